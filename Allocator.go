@@ -0,0 +1,77 @@
+package mmcmap
+
+import "errors"
+import "sync"
+
+
+//============================================= MMCMap Allocator
+
+
+// allocators is the registry of allocation strategies by id
+var allocators = map[AllocatorID]Allocator{ AllocAppend: AppendAllocator{}, AllocFreeList: FreeListAllocator{} }
+var allocatorsLock sync.RWMutex
+
+
+// RegisterAllocator
+//	Register an allocation strategy so files created with it can be opened. Ids must be unique.
+func RegisterAllocator(allocator Allocator) error {
+	allocatorsLock.Lock()
+	defer allocatorsLock.Unlock()
+
+	_, exists := allocators[allocator.ID()]
+	if exists { return errors.New("allocator id already registered") }
+
+	allocators[allocator.ID()] = allocator
+	return nil
+}
+
+// ID
+//	The append allocator is the default strategy.
+func (AppendAllocator) ID() AllocatorID {
+	return AllocAppend
+}
+
+// Place
+//	Place the path one byte past the current end of the memory map.
+func (AppendAllocator) Place(endOffset uint64) uint64 {
+	return endOffset + 1
+}
+
+// End
+//	The memory map always ends after the appended path.
+func (AppendAllocator) End(endOffset, offset, size uint64) uint64 {
+	return offset + size
+}
+
+// ID
+//	The id of the free list allocator.
+func (FreeListAllocator) ID() AllocatorID {
+	return AllocFreeList
+}
+
+// Place
+//	Paths that do not fit in a reclaimed region are appended one byte past the current end of the memory map.
+func (FreeListAllocator) Place(endOffset uint64) uint64 {
+	return endOffset + 1
+}
+
+// End
+//	The memory map only ends after the path if it was appended.
+func (FreeListAllocator) End(endOffset, offset, size uint64) uint64 {
+	if offset < endOffset { return endOffset }
+	return offset + size
+}
+
+// resolveAllocator
+//	Determine the allocation strategy from the file header, checking it against the strategy in the options.
+func (mmcMap *MMCMap) resolveAllocator() error {
+	allocatorsLock.RLock()
+	allocator, ok := allocators[mmcMap.Header.AllocatorID]
+	allocatorsLock.RUnlock()
+
+	if ! ok { return ErrUnknownAllocator }
+	if mmcMap.Opts.Allocator != nil && mmcMap.Opts.Allocator.ID() != allocator.ID() { return ErrAllocatorMismatch }
+
+	mmcMap.Allocator = allocator
+	return nil
+}
@@ -0,0 +1,126 @@
+package mmcmap
+
+import "math/bits"
+
+
+//============================================= MMCMap Approximate Size
+
+
+// ApproximateSize
+//	Estimate the bytes used by the leaves of every key where startKey <= key <= endKey at the latest version, where nil bounds are unbounded.
+//	Keys are placed by hash, so a key range is spread across the whole trie. Instead of visiting every leaf, only the subtrees holding keys whose
+//	hash starts with enough zero bits to leave roughly ApproximateSampleLeaves keys are traversed, and their size is scaled back up by the fraction
+//	of the hash space they cover. Maps with fewer keys than that are measured exactly. Leaves are counted by their serialized size along with any
+//	overflow extent, while internal nodes are not counted.
+//	In files using KeyModeOrdered, a key range is a contiguous part of the trie, so the leaves in range are measured exactly, visiting only the
+//	subtrees overlapping it.
+func (mmcMap *MMCMap) ApproximateSize(startKey, endKey []byte) (uint64, error) {
+	keyCount, lenErr := mmcMap.Len()
+	if lenErr != nil { return 0, lenErr }
+
+	mmcMap.RelocateLock.RLock()
+	defer mmcMap.RelocateLock.RUnlock()
+
+	rootOffset, loadROffErr := mmcMap.loadRootOffsetForRead()
+	if loadROffErr != nil { return 0, loadROffErr }
+
+	var sampleBits uint64
+	if ! mmcMap.isOrderedKeys() { sampleBits = approximateSampleBits(keyCount, uint64(mmcMap.BitChunkSize * mmcMap.HashChunks)) }
+
+	sampled, sampleErr := mmcMap.sampleRecursive(rootOffset, nil, startKey, endKey, sampleBits, 0)
+	if sampleErr != nil { return 0, sampleErr }
+
+	return sampled << sampleBits, nil
+}
+
+// DeadBytes
+//	The bytes of serialized data only reachable from prior versions, which Compact would reclaim. See Stats.
+func (mmcMap *MMCMap) DeadBytes() (uint64, error) {
+	_, deadBytes, deadErr := mmcMap.deadBytes()
+	return deadBytes, deadErr
+}
+
+// sampleRecursive
+//	Sum the size of the leaves in range beneath the node at offset whose hash starts with sampleBits zero bits.
+//	Internal nodes only descend into the slots that can hold such keys, so the traversal is pruned at every level the sampled prefix covers.
+//	In files using KeyModeOrdered, path holds the sparse indexes leading to the node, and slots outside of the range are pruned instead.
+func (mmcMap *MMCMap) sampleRecursive(offset uint64, path []byte, startKey, endKey []byte, sampleBits uint64, level int) (uint64, error) {
+	node, size, readErr := mmcMap.readNodeSize(offset)
+	if readErr != nil { return 0, readErr }
+
+	if node.IsLeaf {
+		if ! isKeyInRange(node.Key, startKey, endKey) || node.isExpired() { return 0, nil }
+
+		hash := mmcMap.calculateHashForCurrentLevel(node.Key, 0)
+		if sampleBits > 0 && hash >> (uint64(mmcMap.Header.HashBits) - sampleBits) != 0 { return 0, nil }
+
+		return size, nil
+	}
+
+	remaining := int(sampleBits) - level * mmcMap.BitChunkSize
+
+	var total uint64
+	slot := 0
+	for _, child := range node.Children {
+		for ! IsBitSet(node.Bitmap, slot) { slot++ }
+
+		var childPath []byte
+		if mmcMap.isOrderedKeys() { childPath = append(path, byte(slot)) }
+
+		isSampled := remaining <= 0 || slot >> uint(max0(mmcMap.BitChunkSize - remaining)) == 0
+		if isSampled && isOrderedPathInRange(childPath, startKey, endKey) {
+			childSize, sampleErr := mmcMap.sampleRecursive(child.StartOffset, childPath, startKey, endKey, sampleBits, level + 1)
+			if sampleErr != nil { return 0, sampleErr }
+
+			total += childSize
+		}
+
+		slot++
+	}
+
+	return total, nil
+}
+
+// readNodeSize
+//	Read a node under the read lock along with its size, without copying its value. The key of a leaf is copied out of the memory map.
+func (mmcMap *MMCMap) readNodeSize(offset uint64) (*MMCMapNode, uint64, error) {
+	mmcMap.waitForRemap()
+
+	mmcMap.RWResizeLock.RLock()
+	defer mmcMap.RWResizeLock.RUnlock()
+
+	handleErr := mmcMap.checkHandle()
+	if handleErr != nil { return nil, 0, handleErr }
+
+	node, readErr := mmcMap.ReadNodeFromMemMap(offset)
+	if readErr != nil { return nil, 0, readErr }
+
+	size := node.EndOffset - node.StartOffset + 1
+	if node.IsLeaf {
+		if node.IsOverflow { size += uint64(len(node.Value)) }
+
+		node.Key = append([]byte{}, node.Key...)
+		node.Value = nil
+	}
+
+	return node, size, nil
+}
+
+// approximateSampleBits
+//	The number of leading zero hash bits that leaves roughly ApproximateSampleLeaves of keyCount keys, capped at the bits of the hash used by the
+//	levels before it is reseeded.
+func approximateSampleBits(keyCount, maxBits uint64) uint64 {
+	if keyCount <= ApproximateSampleLeaves { return 0 }
+
+	sampleBits := uint64(bits.Len64(keyCount / ApproximateSampleLeaves)) - 1
+	if sampleBits > maxBits { return maxBits }
+
+	return sampleBits
+}
+
+// max0
+//	n, or 0 if n is negative.
+func max0(n int) int {
+	if n < 0 { return 0 }
+	return n
+}
@@ -0,0 +1,118 @@
+package mmcmap
+
+import "sync/atomic"
+import "time"
+
+
+//============================================= MMCMap Auto Compaction
+
+
+// PauseCompaction
+//	Stop the compaction go routine from compacting the map until ResumeCompaction is called. A compaction already running is allowed to finish.
+func (mmcMap *MMCMap) PauseCompaction() {
+	atomic.StoreUint32(&mmcMap.IsCompactionPaused, 1)
+}
+
+// ResumeCompaction
+//	Allow the compaction go routine to compact the map again.
+func (mmcMap *MMCMap) ResumeCompaction() {
+	atomic.StoreUint32(&mmcMap.IsCompactionPaused, 0)
+}
+
+// CompactionStats
+//	How many times the map has been compacted, either by Compact or automatically, and how much space was reclaimed.
+func (mmcMap *MMCMap) CompactionStats() CompactionStats {
+	return CompactionStats{
+		Count: atomic.LoadUint64(&mmcMap.CompactionCount),
+		Checks: atomic.LoadUint64(&mmcMap.CompactionChecks),
+		Errors: atomic.LoadUint64(&mmcMap.CompactionErrors),
+		ReclaimedBytes: atomic.LoadUint64(&mmcMap.CompactionReclaimedBytes),
+		Total: time.Duration(atomic.LoadInt64(&mmcMap.CompactionNanos)),
+		IsPaused: atomic.LoadUint32(&mmcMap.IsCompactionPaused) == 1,
+	}
+}
+
+// handleCompaction
+//	Check the compaction threshold on every interval until stop is closed, compacting the map when it is exceeded.
+func (mmcMap *MMCMap) handleCompaction(stop chan struct{}) {
+	interval := mmcMap.Opts.CompactionThreshold.Interval
+	if interval <= 0 { interval = DefaultCompactionInterval }
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var lastVersion uint64
+	for {
+		select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if atomic.LoadUint32(&mmcMap.IsCompactionPaused) == 1 { continue }
+
+				atomic.AddUint64(&mmcMap.CompactionChecks, 1)
+
+				isExceeded, version, checkErr := mmcMap.isCompactionThresholdExceeded(lastVersion)
+				if checkErr != nil { continue }
+
+				lastVersion = version
+				if ! isExceeded { continue }
+
+				report, compactErr := mmcMap.Compact()
+				if compactErr != nil {
+					atomic.AddUint64(&mmcMap.CompactionErrors, 1)
+					mmcMap.log(LogError, "automatic compaction failed", "err", compactErr)
+				} else { mmcMap.log(LogInfo, "compacted automatically", "reclaimedBytes", report.ReclaimedBytes) }
+		}
+	}
+}
+
+// isCompactionThresholdExceeded
+//	Determine if the serialized data is larger than MinSize and the fraction of it reachable from the latest root is below LiveRatio.
+//	The live data is only measured if the version changed since lastVersion, so an idle map is not traversed on every interval.
+func (mmcMap *MMCMap) isCompactionThresholdExceeded(lastVersion uint64) (bool, uint64, error) {
+	mmcMap.waitForRemap()
+
+	mmcMap.RWResizeLock.RLock()
+	defer mmcMap.RWResizeLock.RUnlock()
+
+	handleErr := mmcMap.checkHandle()
+	if handleErr != nil { return false, 0, handleErr }
+
+	meta, readMetaErr := mmcMap.ReadMetaFromMemMap()
+	if readMetaErr != nil { return false, 0, readMetaErr }
+
+	threshold := mmcMap.Opts.CompactionThreshold
+	dataSize := meta.EndMmapOffset - mmcMap.HeaderSize
+	if meta.Version == lastVersion || dataSize < threshold.MinSize { return false, meta.Version, nil }
+
+	liveRatio := threshold.LiveRatio
+	if liveRatio <= 0 { liveRatio = DefaultCompactionLiveRatio }
+
+	liveSize, liveErr := mmcMap.liveBytes(meta.RootOffset)
+	if liveErr != nil { return false, 0, liveErr }
+
+	return float64(liveSize) < liveRatio * float64(dataSize), meta.Version, nil
+}
+
+// liveBytes
+//	The size of the serialized nodes reachable from the node at offset.
+func (mmcMap *MMCMap) liveBytes(offset uint64) (uint64, error) {
+	node, readNodeErr := mmcMap.ReadNodeFromMemMap(offset)
+	if readNodeErr != nil { return 0, readNodeErr }
+
+	size := node.EndOffset - node.StartOffset + 1
+	for _, child := range node.Children {
+		childSize, liveErr := mmcMap.liveBytes(child.StartOffset)
+		if liveErr != nil { return 0, liveErr }
+
+		size += childSize
+	}
+
+	return size, nil
+}
+
+// isAutoCompactionEnabled
+//	Automatic compaction is enabled by setting CompactionThreshold.MinSize in the options.
+func (mmcMap *MMCMap) isAutoCompactionEnabled() bool {
+	return mmcMap.Opts.CompactionThreshold.MinSize > 0
+}
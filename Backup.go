@@ -0,0 +1,258 @@
+package mmcmap
+
+import "bufio"
+import "bytes"
+import "errors"
+import "io"
+
+
+//============================================= MMCMap Backup
+
+
+// backupMagic identifies a serialized backup stream
+var backupMagic = []byte("MMCB")
+
+// backupFormatVersion is the version of the serialized backup stream format
+const backupFormatVersion = 1
+
+const (
+	// backupEnd: the record kind terminating the records of a backup stream
+	backupEnd byte = iota
+	// backupPut: the record kind of a key put since the from version
+	backupPut
+	// backupDelete: the record kind of a key deleted since the from version
+	backupDelete
+)
+
+
+// BackupSince
+//	Write an incremental backup to w containing every key put or deleted after sinceVersion, up to the latest version.
+//	Every node is tagged with the version it was written at and path copying never modifies a node in place, so only the subtrees written after
+//	sinceVersion are traversed: puts are found by descending only into nodes newer than sinceVersion, and deletes by walking the root at sinceVersion
+//	alongside the latest root, skipping subtrees both share. A sinceVersion of 0 writes a full backup.
+//	The root at sinceVersion is located by scanning the file, so incremental backups are unavailable for versions before a Compact, and for files
+//	using the FreeListAllocator. Commits are not blocked while the backup is written, and the backup holds the latest version at the time of the call.
+//	The serialized stream is the 4 byte magic "MMCB", a 1 byte format version, the 8 byte from version and to version, and then a record for each key.
+//	Each record is a 1 byte kind, a 2 byte key length, and the key, followed by an 8 byte value length and the value for puts. The records are
+//	terminated by an end record followed by the 8 byte number of puts and deletes.
+func (mmcMap *MMCMap) BackupSince(w io.Writer, sinceVersion uint64) (*BackupReport, error) {
+	mmcMap.RelocateLock.RLock()
+	defer mmcMap.RelocateLock.RUnlock()
+
+	rootOffset, loadROffErr := mmcMap.loadRootOffsetForRead()
+	if loadROffErr != nil { return nil, loadROffErr }
+
+	root, readRootErr := mmcMap.readNodeCopy(rootOffset, true)
+	if readRootErr != nil { return nil, readRootErr }
+
+	if sinceVersion > root.Version { return nil, ErrVersionUnavailable }
+
+	report := &BackupReport{ FromVersion: sinceVersion, ToVersion: root.Version }
+	writer := bufio.NewWriter(w)
+
+	var header bytes.Buffer
+	header.Write(backupMagic)
+	header.WriteByte(backupFormatVersion)
+	header.Write(serializeUint64(report.FromVersion))
+	header.Write(serializeUint64(report.ToVersion))
+
+	_, writeErr := writer.Write(header.Bytes())
+	if writeErr != nil { return nil, writeErr }
+
+	if sinceVersion < root.Version {
+		putErr := mmcMap.backupPuts(rootOffset, sinceVersion, func(pair *KeyValuePair) error {
+			report.Puts++
+			return writeBackupRecord(writer, backupPut, pair.Key, pair.Value)
+		})
+
+		if putErr != nil { return nil, putErr }
+
+		if sinceVersion > 0 {
+			sinceRootOffset, findErr := mmcMap.findRootAtVersion(sinceVersion)
+			if findErr != nil { return nil, findErr }
+
+			deleteErr := mmcMap.backupDeletes(sinceRootOffset, rootOffset, rootOffset, func(key []byte) error {
+				report.Deletes++
+				return writeBackupRecord(writer, backupDelete, key, nil)
+			})
+
+			if deleteErr != nil { return nil, deleteErr }
+		}
+	}
+
+	writer.WriteByte(backupEnd)
+	writer.Write(serializeUint64(report.Puts))
+	writer.Write(serializeUint64(report.Deletes))
+
+	flushErr := writer.Flush()
+	if flushErr != nil { return nil, flushErr }
+
+	return report, nil
+}
+
+// ReadBackup
+//	Read a backup stream, as written by BackupSince.
+func ReadBackup(r io.Reader) (*Backup, error) {
+	reader := bufio.NewReader(r)
+
+	header := make([]byte, len(backupMagic) + 1 + 2 * OffsetSize)
+	_, readErr := io.ReadFull(reader, header)
+	if readErr != nil { return nil, readErr }
+
+	if ! bytes.Equal(header[:len(backupMagic)], backupMagic) { return nil, errors.New("invalid backup stream magic") }
+	if header[len(backupMagic)] != backupFormatVersion { return nil, errors.New("unsupported backup stream format version") }
+
+	offset := len(backupMagic) + 1
+	backup := &Backup{}
+	backup.FromVersion, _ = deserializeUint64(header[offset:offset + OffsetSize])
+	backup.ToVersion, _ = deserializeUint64(header[offset + OffsetSize:offset + 2 * OffsetSize])
+
+	var puts, deletes uint64
+	for {
+		kind, readKindErr := reader.ReadByte()
+		if readKindErr != nil { return nil, readKindErr }
+
+		if kind == backupEnd { break }
+		if kind != backupPut && kind != backupDelete { return nil, errors.New("invalid backup record kind") }
+
+		key, readKeyErr := readBackupBytes(reader, 2)
+		if readKeyErr != nil { return nil, readKeyErr }
+
+		if kind == backupDelete {
+			backup.Ops = append(backup.Ops, &BatchOp{ Key: key, IsDelete: true })
+			deletes++
+			continue
+		}
+
+		value, readValErr := readBackupBytes(reader, OffsetSize)
+		if readValErr != nil { return nil, readValErr }
+
+		backup.Ops = append(backup.Ops, &BatchOp{ Key: key, Value: value })
+		puts++
+	}
+
+	trailer := make([]byte, 2 * OffsetSize)
+	_, readErr = io.ReadFull(reader, trailer)
+	if readErr != nil { return nil, readErr }
+
+	expectedPuts, _ := deserializeUint64(trailer[:OffsetSize])
+	expectedDeletes, _ := deserializeUint64(trailer[OffsetSize:])
+	if puts != expectedPuts || deletes != expectedDeletes { return nil, errors.New("backup stream record count does not match its trailer") }
+
+	return backup, nil
+}
+
+// RestoreBackup
+//	Apply a backup stream read from r to the map as a single new version.
+//	Incremental backups only hold the keys changed after their from version, so a map is restored by applying a full backup followed by each incremental
+//	backup in order, where every backup starts at the to version of the one before it.
+func (mmcMap *MMCMap) RestoreBackup(r io.Reader) error {
+	backup, readErr := ReadBackup(r)
+	if readErr != nil { return readErr }
+
+	_, commitErr := mmcMap.commitOps(backup.Ops)
+	return commitErr
+}
+
+// backupPuts
+//	Emit every leaf reachable from the node at offset that was written after sinceVersion.
+//	A node is never tagged with a version newer than its parent, so a subtree is skipped once its root is found to be at or before sinceVersion.
+func (mmcMap *MMCMap) backupPuts(offset uint64, sinceVersion uint64, emit func(*KeyValuePair) error) error {
+	node, readErr := mmcMap.readNodeCopy(offset, false)
+	if readErr != nil { return readErr }
+
+	if node.Version <= sinceVersion { return nil }
+	if node.IsLeaf { return emit(&KeyValuePair{ Version: node.Version, Key: node.Key, Value: node.Value }) }
+
+	for _, child := range node.Children {
+		putErr := mmcMap.backupPuts(child.StartOffset, sinceVersion, emit)
+		if putErr != nil { return putErr }
+	}
+
+	return nil
+}
+
+// backupDeletes
+//	Emit every key reachable from the node at prevOffset that is no longer present in the latest root.
+//	The previous node is walked alongside the node at the same position in the latest root, and subtrees shared by both are skipped. A currOffset of 0
+//	means there is no node at the same position, so every leaf below the previous node is checked against the latest root.
+func (mmcMap *MMCMap) backupDeletes(prevOffset, currOffset, rootOffset uint64, emit func([]byte) error) error {
+	if prevOffset == currOffset { return nil }
+
+	prev, readPrevErr := mmcMap.readNodeCopy(prevOffset, true)
+	if readPrevErr != nil { return readPrevErr }
+
+	if prev.IsLeaf {
+		value, getErr := mmcMap.getAtRootOffset(rootOffset, mmcMap.relocateGeneration(), prev.Key)
+		if getErr != nil { return getErr }
+
+		if value == nil { return emit(prev.Key) }
+		return nil
+	}
+
+	var curr *MMCMapNode
+	if currOffset > 0 {
+		var readCurrErr error
+		curr, readCurrErr = mmcMap.readNodeCopy(currOffset, true)
+		if readCurrErr != nil { return readCurrErr }
+
+		if curr.IsLeaf { curr = nil }
+	}
+
+	for index := 0; index < 32; index++ {
+		if ! IsBitSet(prev.Bitmap, index) { continue }
+
+		prevChild := prev.Children[childPosition(prev.Bitmap, index)]
+
+		var currChildOffset uint64
+		if curr != nil && IsBitSet(curr.Bitmap, index) { currChildOffset = curr.Children[childPosition(curr.Bitmap, index)].StartOffset }
+
+		deleteErr := mmcMap.backupDeletes(prevChild.StartOffset, currChildOffset, rootOffset, emit)
+		if deleteErr != nil { return deleteErr }
+	}
+
+	return nil
+}
+
+// findRootAtVersion
+//	Locate the root of the trie as of the given version, which is the latest root committed at or before it.
+func (mmcMap *MMCMap) findRootAtVersion(version uint64) (uint64, error) {
+	roots, scanErr := mmcMap.scanRoots()
+	if scanErr != nil { return 0, scanErr }
+
+	return rootAtVersion(roots, version)
+}
+
+// writeBackupRecord
+//	Serialize a single record of a backup stream. Deletes have no value.
+func writeBackupRecord(w *bufio.Writer, kind byte, key, value []byte) error {
+	w.WriteByte(kind)
+	w.Write(serializeUint16(uint16(len(key))))
+	_, writeErr := w.Write(key)
+	if writeErr != nil { return writeErr }
+	if kind == backupDelete { return nil }
+
+	w.Write(serializeUint64(uint64(len(value))))
+	_, writeErr = w.Write(value)
+	return writeErr
+}
+
+// readBackupBytes
+//	Read a length prefixed byte slice from a backup stream, where the length is either a 2 byte or an 8 byte integer.
+func readBackupBytes(reader *bufio.Reader, lengthSize int) ([]byte, error) {
+	sLength := make([]byte, lengthSize)
+	_, readErr := io.ReadFull(reader, sLength)
+	if readErr != nil { return nil, readErr }
+
+	var length uint64
+	if lengthSize == 2 {
+		length16, _ := deserializeUint16(sLength)
+		length = uint64(length16)
+	} else { length, _ = deserializeUint64(sLength) }
+
+	data := make([]byte, length)
+	_, readErr = io.ReadFull(reader, data)
+	if readErr != nil { return nil, readErr }
+
+	return data, nil
+}
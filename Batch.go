@@ -0,0 +1,91 @@
+package mmcmap
+
+
+//============================================= MMCMap Write Batch
+
+
+// PutBatch
+//	Put every key-value pair into the mmcmap as a single new version.
+//	All pairs are applied to one path copy, so the path is serialized, appended, and the version compare and swap performed once for the whole batch
+//	instead of once per key. Later pairs overwrite earlier pairs with the same key.
+func (mmcMap *MMCMap) PutBatch(pairs []KeyValuePair) (bool, error) {
+	if len(pairs) == 0 { return true, nil }
+
+	ops := make([]*BatchOp, len(pairs))
+	for idx := range pairs {
+		ops[idx] = &BatchOp{ Key: pairs[idx].Key, Value: pairs[idx].Value }
+	}
+
+	return mmcMap.commitOps(ops)
+}
+
+// NewWriteBatch
+//	Creates an empty write batch for the mmcmap. Mutations are buffered in memory until Commit is called.
+func (mmcMap *MMCMap) NewWriteBatch() *WriteBatch {
+	return &WriteBatch{
+		Map: mmcMap,
+		Ops: []*BatchOp{},
+		Overlay: make(map[string]*BatchOp),
+	}
+}
+
+// Put
+//	Buffer a put of the key-value pair in the batch. The key and value are copied, so the caller may reuse them before Commit.
+func (batch *WriteBatch) Put(key, value []byte) {
+	batch.addOp(&BatchOp{ Key: append([]byte{}, key...), Value: append([]byte{}, value...) })
+}
+
+// Delete
+//	Buffer a delete of the key in the batch. The key is copied, so the caller may reuse it before Commit.
+func (batch *WriteBatch) Delete(key []byte) {
+	batch.addOp(&BatchOp{ Key: append([]byte{}, key...), IsDelete: true })
+}
+
+// Get
+//	Read the value for a key as the batch would see it once committed.
+//	The overlay of buffered mutations is checked first, so a put or delete earlier in the batch is observed, and only keys the batch has not
+//	touched fall through to the latest version of the trie. Keys deleted in the batch are missing the same as keys absent from the trie, returning nil,
+//	or ErrKeyNotFound with StrictGet.
+func (batch *WriteBatch) Get(key []byte) ([]byte, error) {
+	op, ok := batch.Overlay[string(key)]
+	if ok {
+		if op.IsDelete && batch.Map.Opts.StrictGet { return nil, ErrKeyNotFound }
+		if op.IsDelete { return nil, nil }
+		return op.Value, nil
+	}
+
+	return batch.Map.Get(key)
+}
+
+// Len
+//	The total number of mutations buffered in the batch.
+func (batch *WriteBatch) Len() int {
+	return len(batch.Ops)
+}
+
+// Commit
+//	Apply every buffered mutation, in order, to a single path copy and commit it as one new version of the mmcmap.
+//	On success the batch is reset so it can be reused.
+func (batch *WriteBatch) Commit() (bool, error) {
+	if len(batch.Ops) == 0 { return true, nil }
+
+	ok, commitErr := batch.Map.commitOps(batch.Ops)
+	if commitErr != nil { return false, commitErr }
+
+	batch.Reset()
+	return ok, nil
+}
+
+// Reset
+//	Discard all buffered mutations.
+func (batch *WriteBatch) Reset() {
+	batch.Ops = []*BatchOp{}
+	batch.Overlay = make(map[string]*BatchOp)
+}
+
+// addOp
+//	Append a mutation to the batch and record it as the latest mutation for its key in the overlay.
+func (batch *WriteBatch) addOp(op *BatchOp) {
+	batch.Ops = append(batch.Ops, op)
+	batch.Overlay[string(op.Key)] = op
+}
@@ -0,0 +1,97 @@
+package mmcmap
+
+import "bytes"
+
+
+//============================================= MMCMap Bounds
+
+
+// First
+//	Returns the key-value pair with the smallest key, or nil if the map is empty.
+//	In files using KeyModeOrdered, children are in key order, so the leftmost branch is walked down to the first leaf, backtracking past expired
+//	leaves, and First costs the depth of the trie. Otherwise keys are placed in the trie by hash, so the leftmost branch does not hold the smallest
+//	key. Instead every leaf of the trie at the latest version is scanned without copying values, so First costs O(n) in the number of keys, and only
+//	the value of the smallest key is read.
+func (mmcMap *MMCMap) First() (*KeyValuePair, error) {
+	return mmcMap.bound(false)
+}
+
+// Last
+//	Returns the key-value pair with the largest key, or nil if the map is empty. Like First, it walks down the rightmost branch in files using
+//	KeyModeOrdered, and otherwise scans every leaf, costing O(n) in the number of keys.
+func (mmcMap *MMCMap) Last() (*KeyValuePair, error) {
+	return mmcMap.bound(true)
+}
+
+// bound
+//	Find the leaf with the smallest key, or the largest if reverse is set, pinned to the root at the time of the call.
+//	Nodes in the memory map are never modified, so the value read from the offset of the leaf matches the key it was found by.
+func (mmcMap *MMCMap) bound(reverse bool) (*KeyValuePair, error) {
+	mmcMap.RelocateLock.RLock()
+	defer mmcMap.RelocateLock.RUnlock()
+
+	rootOffset, loadROffErr := mmcMap.loadRootOffsetForRead()
+	if loadROffErr != nil { return nil, loadROffErr }
+
+	var best *MMCMapNode
+	var boundErr error
+
+	if mmcMap.isOrderedKeys() {
+		best, boundErr = mmcMap.boundOrdered(rootOffset, reverse)
+	} else {
+		boundErr = mmcMap.boundRecursive(rootOffset, func(leaf *MMCMapNode) {
+			if best == nil || (bytes.Compare(leaf.Key, best.Key) < 0) != reverse { best = leaf }
+		})
+	}
+
+	if boundErr != nil { return nil, boundErr }
+	if best == nil { return nil, nil }
+
+	leaf, readErr := mmcMap.readNodeCopy(best.StartOffset, false)
+	if readErr != nil { return nil, readErr }
+
+	return &KeyValuePair{ Version: leaf.Version, Key: leaf.Key, Value: leaf.Value }, nil
+}
+
+// boundRecursive
+//	Depth first traversal from the node at the given offset, visiting each leaf without its value. Expired leaves are skipped.
+func (mmcMap *MMCMap) boundRecursive(offset uint64, visit func(leaf *MMCMapNode)) error {
+	node, readErr := mmcMap.readNodeCopy(offset, true)
+	if readErr != nil { return readErr }
+
+	if node.IsLeaf {
+		if ! node.isExpired() { visit(node) }
+		return nil
+	}
+
+	for _, child := range node.Children {
+		boundErr := mmcMap.boundRecursive(child.StartOffset, visit)
+		if boundErr != nil { return boundErr }
+	}
+
+	return nil
+}
+
+// boundOrdered
+//	Depth first traversal from the node at the given offset of an ordered trie, visiting children in key order, or in reverse key order if reverse is
+//	set, and returning the first leaf that has not expired without its value. Children are stored in the order of their sparse index, and index 0,
+//	where a key ends, sorts before the longer keys it is a prefix of. Returns nil if every leaf beneath the node has expired.
+func (mmcMap *MMCMap) boundOrdered(offset uint64, reverse bool) (*MMCMapNode, error) {
+	node, readErr := mmcMap.readNodeCopy(offset, true)
+	if readErr != nil { return nil, readErr }
+
+	if node.IsLeaf {
+		if node.isExpired() { return nil, nil }
+		return node, nil
+	}
+
+	for idx := range node.Children {
+		child := node.Children[idx]
+		if reverse { child = node.Children[len(node.Children) - 1 - idx] }
+
+		leaf, boundErr := mmcMap.boundOrdered(child.StartOffset, reverse)
+		if boundErr != nil || leaf != nil { return leaf, boundErr }
+	}
+
+	return nil, nil
+}
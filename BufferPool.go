@@ -0,0 +1,31 @@
+package mmcmap
+
+import "sync"
+
+
+//============================================= MMCMap Serialize Buffer Pool
+
+
+// serializeBuffers holds buffers that path copies were serialized into, so each commit can reuse one instead of allocating its own
+var serializeBuffers = sync.Pool{ New: func() interface{} { return new([]byte) } }
+
+// getSerializeBuffer
+//	Get a buffer of size bytes from the pool. If the pooled buffer is too small, a new one is allocated. The contents are not zeroed, so every byte
+//	has to be written before the buffer is used.
+func getSerializeBuffer(size uint64) []byte {
+	bufPtr := serializeBuffers.Get().(*[]byte)
+	if uint64(cap(*bufPtr)) < size {
+		serializeBuffers.Put(bufPtr)
+		return make([]byte, size)
+	}
+
+	return (*bufPtr)[:size]
+}
+
+// releaseSerializeBuffer
+//	Return a buffer to the pool once its contents have been copied into the memory map.
+//	Buffers larger than MaxPooledBufferSize, like those for bulk loads, are dropped so the pool does not pin large allocations.
+func releaseSerializeBuffer(buf []byte) {
+	if cap(buf) > MaxPooledBufferSize { return }
+	serializeBuffers.Put(&buf)
+}
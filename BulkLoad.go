@@ -0,0 +1,178 @@
+package mmcmap
+
+import "bytes"
+import "errors"
+import "sort"
+import "sync/atomic"
+
+
+//============================================= MMCMap Bulk Load
+
+
+// BulkLoad
+//	Load pairs into an empty map as a single new version, building the trie bottom up and serializing it in one pass instead of copying a path per key.
+//	At each level, pairs are grouped by the sparse index of their hash, which produces the same trie as putting them one at a time, so pairs do not need
+//	to be sorted. If a key appears more than once, the last pair wins. The whole trie is built and serialized in memory before it is written, and
+//	commits are blocked while it is written. Returns ErrMapNotEmpty if the map already holds keys, use PutBatch to add pairs to an existing map.
+func (mmcMap *MMCMap) BulkLoad(pairs []KeyValuePair) error {
+	if mmcMap.isFollower() { return ErrFollowerReadOnly }
+
+	if len(pairs) == 0 { return nil }
+
+	lastIdx := make(map[string]int, len(pairs))
+	for idx := range pairs {
+		sizeErr := mmcMap.checkPutSize(pairs[idx].Key, int64(len(pairs[idx].Value)))
+		if sizeErr != nil { return sizeErr }
+
+		lastIdx[string(pairs[idx].Key)] = idx
+	}
+
+	unique := make([]*KeyValuePair, 0, len(lastIdx))
+	for idx := range pairs {
+		if lastIdx[string(pairs[idx].Key)] == idx { unique = append(unique, &pairs[idx]) }
+	}
+
+	stallErr := mmcMap.awaitFlush()
+	if stallErr != nil { return stallErr }
+
+	mmcMap.CommitGate.Lock()
+	defer mmcMap.CommitGate.Unlock()
+
+	meta, readMetaErr := mmcMap.readEmptyMeta()
+	if readMetaErr != nil { return readMetaErr }
+
+	root, buildErr := mmcMap.bulkLoadRecursive(unique, 0, meta.Version + 1)
+	if buildErr != nil { return buildErr }
+
+	rootOffset := mmcMap.Allocator.Place(meta.EndMmapOffset)
+
+	serializedTrie, serializeErr := mmcMap.SerializePathToMemMap(root, rootOffset)
+	if serializeErr != nil { return serializeErr }
+	defer releaseSerializeBuffer(serializedTrie)
+
+	newMeta := &MMCMapMetaData{
+		Version: meta.Version + 1,
+		RootOffset: rootOffset,
+		EndMmapOffset: mmcMap.Allocator.End(meta.EndMmapOffset, rootOffset, uint64(len(serializedTrie))),
+	}
+
+	for {
+		written, writeErr := mmcMap.tryWriteBulkLoad(serializedTrie, newMeta, uint64(len(unique)))
+		if writeErr != nil { return writeErr }
+		if written { return nil }
+	}
+}
+
+// readEmptyMeta
+//	Read the metadata of the map, returning ErrMapNotEmpty if the latest root has any children.
+func (mmcMap *MMCMap) readEmptyMeta() (*MMCMapMetaData, error) {
+	mmcMap.waitForRemap()
+
+	mmcMap.RWResizeLock.RLock()
+	defer mmcMap.RWResizeLock.RUnlock()
+
+	handleErr := mmcMap.checkHandle()
+	if handleErr != nil { return nil, handleErr }
+
+	meta, readMetaErr := mmcMap.ReadMetaFromMemMap()
+	if readMetaErr != nil { return nil, readMetaErr }
+
+	root, readRootErr := mmcMap.ReadNodeFromMemMap(meta.RootOffset)
+	if readRootErr != nil { return nil, readRootErr }
+
+	if len(root.Children) > 0 { return nil, ErrMapNotEmpty }
+	return meta, nil
+}
+
+// bulkLoadRecursive
+//	Build the internal node at level for pairs, which all share the same path to it.
+//	Each slot holding a single pair becomes a leaf, and each slot holding more than one becomes an internal node on the next level, mirroring how
+//	putRecursive splits a leaf when a second key hashes to the same slot. At the collision level, pairs become the sorted leaves of a collision bucket.
+func (mmcMap *MMCMap) bulkLoadRecursive(pairs []*KeyValuePair, level int, version uint64) (*MMCMapNode, error) {
+	node := mmcMap.newInternalNode(version)
+
+	if mmcMap.isCollisionLevel(level) {
+		if len(pairs) > MaxCollisionBucketKeys { return nil, ErrCollisionBucketFull }
+
+		sorted := append([]*KeyValuePair{}, pairs...)
+		sort.Slice(sorted, func(i, j int) bool { return bytes.Compare(sorted[i].Key, sorted[j].Key) < 0 })
+
+		for _, pair := range sorted { node.Children = append(node.Children, mmcMap.newLeafNode(pair.Key, pair.Value, 0, version)) }
+		node.Bitmap = collisionBitmap(len(sorted))
+		return node, nil
+	}
+
+	slots := make([][]*KeyValuePair, 1 << mmcMap.BitChunkSize)
+	for _, pair := range pairs {
+		index := mmcMap.getKeyIndex(pair.Key, level)
+		slots[index] = append(slots[index], pair)
+	}
+
+	for index, slot := range slots {
+		if len(slot) == 0 { continue }
+
+		var child *MMCMapNode
+		if len(slot) == 1 {
+			child = mmcMap.newLeafNode(slot[0].Key, slot[0].Value, 0, version)
+		} else {
+			var buildErr error
+			child, buildErr = mmcMap.bulkLoadRecursive(slot, level + 1, version)
+			if buildErr != nil { return nil, buildErr }
+		}
+
+		node.Bitmap = SetBit(node.Bitmap, index)
+		node.Children = append(node.Children, child)
+	}
+
+	return node, nil
+}
+
+// tryWriteBulkLoad
+//	A single attempt at writing the serialized trie and publishing it as the next version. Returns false if the memory map had to be resized first.
+//	The commit gate must be held exclusively, so the metadata can not change between serializing the trie and writing it.
+func (mmcMap *MMCMap) tryWriteBulkLoad(serializedTrie []byte, newMeta *MMCMapMetaData, keyCount uint64) (bool, error) {
+	mmcMap.waitForResize()
+
+	mmcMap.RWResizeLock.RLock()
+	defer mmcMap.RWResizeLock.RUnlock()
+
+	handleErr := mmcMap.checkHandle()
+	if handleErr != nil { return false, handleErr }
+
+	fileSizeErr := mmcMap.checkFileSize(newMeta.EndMmapOffset)
+	if fileSizeErr != nil { return false, fileSizeErr }
+
+	if mmcMap.determineIfResize(newMeta.EndMmapOffset) { return false, nil }
+
+	versionPtr, version, loadVErr := mmcMap.loadMetaVersion()
+	if loadVErr != nil { return false, loadVErr }
+
+	rootOffsetPtr, prevRootOffset, loadROffErr := mmcMap.loadMetaRootOffset()
+	if loadROffErr != nil { return false, loadROffErr }
+
+	endOffsetPtr, prevEndOffset, loadSOffErr := mmcMap.loadMetaEndSerialized()
+	if loadSOffErr != nil { return false, loadSOffErr }
+
+	if version != newMeta.Version - 1 || ! compareAndSwapMetaPointer(versionPtr, version, newMeta.Version) {
+		return false, errors.New("map was modified during bulk load")
+	}
+
+	mmcMap.storeMetaPointer(endOffsetPtr, newMeta.EndMmapOffset)
+
+	_, writeNodesToMmapErr := mmcMap.writeNodesToMemMap(serializedTrie, newMeta.RootOffset)
+	if writeNodesToMmapErr != nil {
+		mmcMap.storeMetaPointer(endOffsetPtr, prevEndOffset)
+		mmcMap.storeMetaPointer(versionPtr, version)
+		mmcMap.storeMetaPointer(rootOffsetPtr, prevRootOffset)
+
+		return false, writeNodesToMmapErr
+	}
+
+	publishErr := mmcMap.publishRootWithCount(rootOffsetPtr, newMeta.RootOffset, keyCount)
+	if publishErr != nil { return false, publishErr }
+
+	atomic.AddUint64(&mmcMap.UnflushedBytes, uint64(len(serializedTrie)))
+	mmcMap.signalFlush()
+
+	return true, nil
+}
@@ -0,0 +1,174 @@
+package mmcmap
+
+import "container/list"
+import "sync/atomic"
+import "unsafe"
+
+
+//============================================= MMCMap Node Cache
+
+
+// nodeCacheEntry is a node held by the node cache, along with the bytes it is charged against the budget
+type nodeCacheEntry struct {
+	offset uint64
+	node *MMCMapNode
+	cost uint64
+}
+
+
+// NewNodeCache
+//	Creates a node cache holding roughly maxBytes of deserialized nodes. Returns nil if maxBytes is 0, which disables caching.
+//	Nodes are never rewritten in place, so a cached node stays valid as versions advance. The cache only has to be purged when the memory map is
+//	remapped or bytes that were already committed are overwritten, by Compact, Clear, Repair, a replica snapshot, or the free list allocator.
+func NewNodeCache(maxBytes uint64) *NodeCache {
+	if maxBytes == 0 { return nil }
+	return &NodeCache{ MaxBytes: maxBytes, Entries: make(map[uint64]*list.Element), Order: list.New() }
+}
+
+// Get
+//	Look up the node at offset, marking it as most recently used. Returns a copy the caller is free to modify.
+func (cache *NodeCache) Get(offset uint64) (*MMCMapNode, bool) {
+	node, ok := cache.peek(offset)
+	if ! ok {
+		if cache != nil { atomic.AddUint64(&cache.Misses, 1) }
+		return nil, false
+	}
+
+	return node.cacheCopy(), true
+}
+
+// peek
+//	Look up the node at offset in the same way as Get, but return the cached node itself, which the caller must not modify.
+//	Misses are left for the caller to count, since a lookup that reads a missed node from the memory map without caching it is not a cache miss.
+func (cache *NodeCache) peek(offset uint64) (*MMCMapNode, bool) {
+	if cache == nil { return nil, false }
+
+	cache.Lock.Lock()
+	elem, ok := cache.Entries[offset]
+	if ok { cache.Order.MoveToFront(elem) }
+	cache.Lock.Unlock()
+
+	if ! ok { return nil, false }
+
+	atomic.AddUint64(&cache.Hits, 1)
+	return elem.Value.(*nodeCacheEntry).node, true
+}
+
+// Generation
+//	The number of purges so far. Read before a node is read from the memory map and passed to Put, so a node read before a purge is not cached.
+func (cache *NodeCache) Generation() uint64 {
+	if cache == nil { return 0 }
+	return atomic.LoadUint64(&cache.Purges)
+}
+
+// Put
+//	Cache a copy of the node read at offset, evicting the least recently used nodes until the cache is within its budget.
+//	Nodes larger than the whole budget, or read before the cache was last purged, are not cached.
+func (cache *NodeCache) Put(offset uint64, node *MMCMapNode, generation uint64) {
+	if cache == nil { return }
+
+	cost := node.cacheCost()
+	if cost > cache.MaxBytes { return }
+
+	entry := &nodeCacheEntry{ offset: offset, node: node.cacheCopy(), cost: cost }
+
+	cache.Lock.Lock()
+	defer cache.Lock.Unlock()
+
+	if atomic.LoadUint64(&cache.Purges) != generation { return }
+
+	if elem, ok := cache.Entries[offset]; ok {
+		cache.Bytes -= elem.Value.(*nodeCacheEntry).cost
+		cache.Order.Remove(elem)
+	}
+
+	cache.Entries[offset] = cache.Order.PushFront(entry)
+	cache.Bytes += cost
+
+	for cache.Bytes > cache.MaxBytes {
+		oldest := cache.Order.Back()
+		evicted := cache.Order.Remove(oldest).(*nodeCacheEntry)
+
+		delete(cache.Entries, evicted.offset)
+		cache.Bytes -= evicted.cost
+		atomic.AddUint64(&cache.Evictions, 1)
+	}
+}
+
+// Purge
+//	Drop every cached node. Called once the overwritten bytes are in the memory map, so a node read while the cache is purged is not cached.
+func (cache *NodeCache) Purge() {
+	if cache == nil { return }
+
+	cache.Lock.Lock()
+	defer cache.Lock.Unlock()
+
+	cache.Entries = make(map[uint64]*list.Element)
+	cache.Order.Init()
+	cache.Bytes = 0
+	atomic.AddUint64(&cache.Purges, 1)
+}
+
+// Stats
+//	The hit rate and size of the cache. A nil cache reports zero for everything.
+func (cache *NodeCache) Stats() NodeCacheStats {
+	if cache == nil { return NodeCacheStats{} }
+
+	cache.Lock.Lock()
+	entries, bytes := len(cache.Entries), cache.Bytes
+	cache.Lock.Unlock()
+
+	return NodeCacheStats{
+		Hits: atomic.LoadUint64(&cache.Hits),
+		Misses: atomic.LoadUint64(&cache.Misses),
+		Evictions: atomic.LoadUint64(&cache.Evictions),
+		Purges: atomic.LoadUint64(&cache.Purges),
+		Entries: entries,
+		Bytes: bytes,
+	}
+}
+
+// NodeCacheStats
+//	How many node reads were served from the node cache, and how much memory it holds, along with the number of pinned nodes.
+func (mmcMap *MMCMap) NodeCacheStats() NodeCacheStats {
+	stats := mmcMap.NodeCache.Stats()
+	stats.Pinned = len(mmcMap.loadPinned().nodes)
+
+	return stats
+}
+
+// purgeNodes
+//	Drop the node cache and the pinned levels, once committed bytes have been overwritten or the memory map is about to be unmapped.
+//	The pinned levels are rebuilt by the next commit.
+func (mmcMap *MMCMap) purgeNodes() {
+	mmcMap.NodeCache.Purge()
+
+	mmcMap.PinnedLock.Lock()
+	defer mmcMap.PinnedLock.Unlock()
+
+	mmcMap.Pinned.Store(&pinnedNodes{})
+}
+
+// cacheCopy
+//	Copy the node and the stubs of its children, so neither the cached node nor the copy handed to a caller can be modified through the other.
+//	The key and value are shared, capped to their length so appending to them reallocates.
+func (node *MMCMapNode) cacheCopy() *MMCMapNode {
+	nodeCopy := *node
+	nodeCopy.Key = node.Key[:len(node.Key):len(node.Key)]
+	nodeCopy.Value = node.Value[:len(node.Value):len(node.Value)]
+
+	if node.Children != nil {
+		nodeCopy.Children = newChildStubs(len(node.Children))
+		for idx, child := range node.Children { nodeCopy.Children[idx].StartOffset = child.StartOffset }
+	}
+
+	return &nodeCopy
+}
+
+// cacheCost
+//	The approximate number of bytes the node and its child stubs hold in memory. Values are counted even when they are sliced from the memory map,
+//	which keeps the budget an upper bound.
+func (node *MMCMapNode) cacheCost() uint64 {
+	nodeSize := uint64(unsafe.Sizeof(*node))
+	return nodeSize * uint64(1 + len(node.Children)) + uint64(len(node.Key) + len(node.Value))
+}
@@ -0,0 +1,118 @@
+package mmcmap
+
+import "sync/atomic"
+
+
+//============================================= MMCMap Clear
+
+
+// Clear
+//	Remove every key from the map as a single new version, without closing the handle.
+//	By default an empty root is committed, so readers pinned to an older root are unaffected and the space used by the prior versions is kept.
+//	With ClearOpts.Truncate, the file is instead truncated back to the size of a newly created file and rewritten with a fresh header, metadata, and
+//	empty root, discarding every prior version. The version keeps increasing across a truncate, but replicas and history can not reach versions from
+//	before it, and it waits for pinned readers, such as open ValueReaders and unreleased GetZeroCopy values, like Compact. Both block commits for the
+//	duration of the clear.
+func (mmcMap *MMCMap) Clear(opts ...ClearOpts) error {
+	if mmcMap.isFollower() { return ErrFollowerReadOnly }
+
+	var clearOpts ClearOpts
+	if len(opts) > 0 { clearOpts = opts[0] }
+
+	if clearOpts.Truncate {
+		// a seal in flight would write the .cold file of a segment after it is removed, so wait for it first
+		mmcMap.SealLock.Lock()
+		defer mmcMap.SealLock.Unlock()
+	}
+
+	if clearOpts.Truncate {
+		mmcMap.lockRelocate()
+		defer mmcMap.unlockRelocate()
+
+		return mmcMap.truncateFile()
+	}
+
+	mmcMap.CommitGate.Lock()
+	defer mmcMap.CommitGate.Unlock()
+
+	for {
+		cleared, clearErr := mmcMap.tryClear()
+		if clearErr != nil { return clearErr }
+		if cleared { return nil }
+	}
+}
+
+// tryClear
+//	A single attempt at committing an empty root. Returns false if the attempt should be retried.
+func (mmcMap *MMCMap) tryClear() (bool, error) {
+	mmcMap.waitForResize()
+
+	mmcMap.RWResizeLock.RLock()
+	defer mmcMap.RWResizeLock.RUnlock()
+
+	handleErr := mmcMap.checkHandle()
+	if handleErr != nil { return false, handleErr }
+
+	_, version, loadVErr := mmcMap.loadMetaVersion()
+	if loadVErr != nil { return false, loadVErr }
+
+	_, keyCount, loadKCountErr := mmcMap.loadMetaKeyCount()
+	if loadKCountErr != nil { return false, loadKCountErr }
+
+	emptyRoot := &MMCMapNode{ Version: version + 1, Children: []*MMCMapNode{} }
+	written, writeErr := mmcMap.exclusiveWriteMmap(emptyRoot, -int64(keyCount), nil, allWriteShards, nil)
+	if writeErr == ErrResizeInProgress { return false, nil }
+
+	return written, writeErr
+}
+
+// truncateFile
+//	Truncate the file to its initial size and write a fresh header, empty root, and metadata at the next version. Files using segmented storage
+//	remove every segment after the first.
+//	The resize write lock is held throughout so readers never observe the file while it is being rewritten. The commit gate must be held exclusively.
+func (mmcMap *MMCMap) truncateFile() error {
+	mmcMap.lockResize()
+	defer mmcMap.unlockResize()
+
+	handleErr := mmcMap.checkHandle()
+	if handleErr != nil { return handleErr }
+
+	_, version, loadVErr := mmcMap.loadMetaVersion()
+	if loadVErr != nil { return loadVErr }
+
+	segments := mmcMap.loadSegments()
+	atomic.AddUint64(&mmcMap.RelocateGeneration, 1)
+
+	unmapErr := mmcMap.munmap()
+	if unmapErr != nil { return unmapErr }
+
+	truncateErr := mmcMap.truncateToInitialSize(segments)
+	if truncateErr != nil { return truncateErr }
+
+	mmapErr := mmcMap.mMap()
+	if mmapErr != nil { return mmapErr }
+
+	initHeaderErr := mmcMap.initHeader()
+	if initHeaderErr != nil { return initHeaderErr }
+
+	root := &MMCMapNode{ Version: version + 1, StartOffset: mmcMap.HeaderSize, Children: []*MMCMapNode{} }
+	endOffset, writeNodeErr := mmcMap.WriteNodeToMemMap(root)
+	if writeNodeErr != nil { return writeNodeErr }
+
+	newMeta := &MMCMapMetaData{ Version: version + 1, RootOffset: mmcMap.HeaderSize, EndMmapOffset: endOffset }
+	_, writeMetaErr := mmcMap.WriteMetaToMemMap(newMeta.SerializeMetaData())
+	if writeMetaErr != nil { return writeMetaErr }
+
+	return mmcMap.syncFiles()
+}
+
+// truncateToInitialSize
+//	Truncate the unmapped file back to the size of a newly created file.
+func (mmcMap *MMCMap) truncateToInitialSize(segments *MMCMapSegments) error {
+	if mmcMap.isSegmented() { return mmcMap.truncateSegments(segments) }
+
+	truncateErr := mmcMap.File.Truncate(0)
+	if truncateErr != nil { return truncateErr }
+
+	return mmcMap.File.Truncate(mmcMap.capFileSize(initialFileSize()))
+}
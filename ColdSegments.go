@@ -0,0 +1,353 @@
+package mmcmap
+
+import "bytes"
+import "errors"
+import "fmt"
+import "io"
+import "os"
+
+import "github.com/sirgallo/mmcmap/common/mmap"
+
+
+//============================================= MMCMap Cold Segments
+
+
+// coldSegmentSuffix is appended to the path of a segment for the file holding it once it is compressed
+const coldSegmentSuffix = ".cold"
+
+
+// SealSegments
+//	Seal every segment of a file using segmented storage that is older than the newest HotSegments segments and fully written as cold. Nodes are
+//	never written over, so from then on the segment is mapped read only and left out of LockMemory, and its pages are only faulted in by the reads
+//	that need them. With ColdCompression, the segment is also compressed into a .cold file that replaces the segment file, and is decompressed into
+//	memory instead. Segments are copied and compressed without blocking commits, which are only blocked while the mapping of each is replaced.
+//	Sealing runs on its own after the map grows while HotSegments is set.
+func (mmcMap *MMCMap) SealSegments() (*SegmentReport, error) {
+	if ! mmcMap.isSegmented() { return nil, ErrNotSegmented }
+
+	mmcMap.SealLock.Lock()
+	defer mmcMap.SealLock.Unlock()
+
+	return mmcMap.sealSegments()
+}
+
+// sealAfterResize
+//	Seal the segments that fell out of the hot segments on a new go routine after the map grows, unless a seal is already running.
+func (mmcMap *MMCMap) sealAfterResize() {
+	if mmcMap.Opts.HotSegments == 0 { return }
+
+	mmcMap.goLabeled("seal", func() {
+		if ! mmcMap.SealLock.TryLock() { return }
+		defer mmcMap.SealLock.Unlock()
+
+		_, sealErr := mmcMap.sealSegments()
+		if sealErr != nil && sealErr != ErrMapClosed && sealErr != ErrMapDetached { mmcMap.log(LogError, "sealing segments failed", "err", sealErr) }
+	})
+}
+
+// sealSegments
+//	Seal the oldest segment that can be sealed until none are left. The seal lock must be held.
+func (mmcMap *MMCMap) sealSegments() (*SegmentReport, error) {
+	report := &SegmentReport{}
+	for {
+		id, data, nextErr := mmcMap.nextColdSegment()
+		if nextErr != nil { return nil, nextErr }
+		if id == 0 { break }
+
+		isCompressed, writeErr := mmcMap.writeColdSegment(id, data)
+		if writeErr != nil { return nil, writeErr }
+
+		isSealed, sealErr := mmcMap.sealSegment(id, data, isCompressed)
+		if sealErr != nil { return nil, sealErr }
+		if ! isSealed { break }
+
+		report.SealedSegments++
+		if isCompressed { report.CompressedSegments++ }
+	}
+
+	segments := mmcMap.loadSegments()
+	report.FirstSegment, report.LastSegment, report.MinVersion = segments.first(), segments.LastSegment, segments.MinVersion
+	if report.SealedSegments > 0 { mmcMap.log(LogInfo, "sealed segments", "sealed", report.SealedSegments, "compressed", report.CompressedSegments) }
+
+	return report, nil
+}
+
+// nextColdSegment
+//	The oldest hot segment that can be sealed, or 0 if there is none. With ColdCompression, a copy of the segment is returned to be compressed.
+func (mmcMap *MMCMap) nextColdSegment() (uint64, []byte, error) {
+	mmcMap.waitForRemap()
+
+	mmcMap.RWResizeLock.RLock()
+	defer mmcMap.RWResizeLock.RUnlock()
+
+	handleErr := mmcMap.checkHandle()
+	if handleErr != nil { return 0, nil, handleErr }
+
+	meta, readMetaErr := mmcMap.ReadMetaFromMemMap()
+	if readMetaErr != nil { return 0, nil, readMetaErr }
+
+	segmentSize := mmcMap.Header.SegmentSize
+	segments := mmcMap.loadSegments()
+	files := mmcMap.loadSegmentFiles()
+
+	for id := segments.first(); id < uint64(len(files)); id++ {
+		if files[id].File == nil || files[id].IsCold { continue }
+		if ! mmcMap.isColdEligible(id, segments.LastSegment, meta.EndMmapOffset) { return 0, nil, nil }
+		// compressed segments are decompressed in a single allocation, which is bounded like compressed values
+		if mmcMap.Opts.ColdCompression == CompressionNone || segmentSize > MaxDecompressedValueLength { return id, nil, nil }
+
+		data := make([]byte, segmentSize)
+		copy(data, mmcMap.Reservation[id * segmentSize:(id + 1) * segmentSize])
+
+		return id, data, nil
+	}
+
+	return 0, nil, nil
+}
+
+// writeColdSegment
+//	Compress a copy of segment id into its .cold file, returning false if there is no copy or compressing it did not shrink it. The file is written
+//	to a temporary file and renamed, so a crash leaves either no .cold file or a complete one, and the segment file is preferred while both exist.
+func (mmcMap *MMCMap) writeColdSegment(id uint64, data []byte) (bool, error) {
+	if data == nil { return false, nil }
+
+	encoded := compressValue(mmcMap.Opts.ColdCompression, data)
+	if encoded == nil { return false, nil }
+
+	// the resize lock keeps Close from removing the map while the file is written
+	mmcMap.RWResizeLock.RLock()
+	defer mmcMap.RWResizeLock.RUnlock()
+
+	handleErr := mmcMap.checkHandle()
+	if handleErr != nil { return false, handleErr }
+
+	path := mmcMap.coldSegmentPath(id)
+	tmpPath := path + ".tmp"
+	file, openErr := os.OpenFile(tmpPath, os.O_RDWR | os.O_CREATE | os.O_TRUNC, 0600)
+	if openErr != nil { return false, openErr }
+
+	_, writeErr := file.Write(encoded)
+	if writeErr != nil {
+		file.Close()
+		return false, writeErr
+	}
+
+	syncErr := file.Sync()
+	closeErr := file.Close()
+	if syncErr != nil { return false, syncErr }
+	if closeErr != nil { return false, closeErr }
+
+	renameErr := os.Rename(tmpPath, path)
+	if renameErr != nil { return false, renameErr }
+
+	return true, nil
+}
+
+// sealSegment
+//	Replace the mapping of segment id with a read only one, or with the decompressed copy of it if it was compressed, and remove the segment file of
+//	a compressed segment. Returns false if the segment can no longer be sealed, or no longer matches the copy that was compressed because the map
+//	was cleared or a snapshot was applied in the meantime.
+func (mmcMap *MMCMap) sealSegment(id uint64, data []byte, isCompressed bool) (bool, error) {
+	mmcMap.CommitGate.Lock()
+	defer mmcMap.CommitGate.Unlock()
+
+	mmcMap.lockResize()
+	defer mmcMap.unlockResize()
+
+	handleErr := mmcMap.checkHandle()
+	if handleErr != nil { return false, handleErr }
+
+	meta, readMetaErr := mmcMap.ReadMetaFromMemMap()
+	if readMetaErr != nil { return false, readMetaErr }
+
+	segmentSize := mmcMap.Header.SegmentSize
+	segments := mmcMap.loadSegments()
+	files := append([]MMCMapSegmentFile{}, mmcMap.loadSegmentFiles()...)
+
+	isSealable := id >= segments.first() && id < uint64(len(files)) && files[id].File != nil && ! files[id].IsCold
+	isSealable = isSealable && mmcMap.isColdEligible(id, segments.LastSegment, meta.EndMmapOffset)
+
+	region := mmcMap.Reservation[id * segmentSize:(id + 1) * segmentSize]
+	if isSealable && isCompressed { isSealable = bytes.Equal(region, data) }
+	if ! isSealable {
+		if isCompressed { os.Remove(mmcMap.coldSegmentPath(id)) }
+		return false, nil
+	}
+
+	if isCompressed {
+		coldFile, openErr := os.Open(mmcMap.coldSegmentPath(id))
+		if openErr != nil { return false, openErr }
+
+		_, mapErr := mmap.MapCopyAt(region, data)
+		if mapErr != nil {
+			coldFile.Close()
+			return false, mapErr
+		}
+
+		files[id].File.Close()
+		files[id] = MMCMapSegmentFile{ File: coldFile, IsCold: true, IsCompressed: true }
+		mmcMap.SegmentFiles.Store(files)
+
+		removeErr := os.Remove(mmcMap.segmentPath(id))
+		if removeErr != nil { return false, removeErr }
+	} else {
+		_, mapErr := mmap.MapRegionAt(region, files[id].File, int(segmentSize), mmap.RDONLY, 0)
+		if mapErr != nil { return false, mapErr }
+
+		files[id].IsCold = true
+		mmcMap.SegmentFiles.Store(files)
+	}
+
+	mmcMap.lockMemory(mmcMap.Data.Load().(mmap.MMap))
+	return true, nil
+}
+
+// thawSegments
+//	Map every cold segment writable again, before a snapshot or a repair rewrites the bytes it holds. Compressed segments are written back to a
+//	segment file first. The segments are sealed again by the next seal once they fall out of the hot segments. The resize write lock must be held.
+func (mmcMap *MMCMap) thawSegments() error {
+	segmentSize := mmcMap.Header.SegmentSize
+	files := append([]MMCMapSegmentFile{}, mmcMap.loadSegmentFiles()...)
+
+	var isThawed bool
+	for idx, cold := range files {
+		if ! cold.IsCold { continue }
+
+		id := uint64(idx)
+		region := mmcMap.Reservation[id * segmentSize:(id + 1) * segmentSize]
+
+		file := cold.File
+		if cold.IsCompressed {
+			var createErr error
+			file, createErr = mmcMap.createSegment(id)
+			if createErr != nil { return createErr }
+
+			_, writeErr := file.WriteAt(region, 0)
+			if writeErr == nil { writeErr = file.Sync() }
+			if writeErr != nil {
+				file.Close()
+				return writeErr
+			}
+		}
+
+		_, mapErr := mmap.MapRegionAt(region, file, int(segmentSize), mmap.RDWR, 0)
+		if mapErr != nil {
+			if cold.IsCompressed { file.Close() }
+			return mapErr
+		}
+
+		if cold.IsCompressed {
+			cold.File.Close()
+			os.Remove(mmcMap.coldSegmentPath(id))
+		}
+
+		files[id] = MMCMapSegmentFile{ File: file }
+		mmcMap.SegmentFiles.Store(files)
+		isThawed = true
+	}
+
+	if isThawed { mmcMap.lockMemory(mmcMap.Data.Load().(mmap.MMap)) }
+	return nil
+}
+
+// mapSegment
+//	Map segment id into the reservation, read only if it is cold. A segment without a segment file is mapped from its .cold file.
+func (mmcMap *MMCMap) mapSegment(reservation mmap.MMap, id uint64, isCold bool) (MMCMapSegmentFile, error) {
+	file, openErr := mmcMap.openSegment(id)
+	if errors.Is(openErr, os.ErrNotExist) {
+		cold, coldErr := mmcMap.mapColdSegment(reservation, id)
+		if errors.Is(coldErr, os.ErrNotExist) { return MMCMapSegmentFile{}, openErr }
+
+		return cold, coldErr
+	}
+
+	if openErr != nil { return MMCMapSegmentFile{}, openErr }
+
+	prot := mmap.RDWR
+	if isCold { prot = mmap.RDONLY }
+
+	_, mapErr := mmap.MapRegionAt(reservation[id * mmcMap.Header.SegmentSize:], file, int(mmcMap.Header.SegmentSize), prot, 0)
+	if mapErr != nil {
+		file.Close()
+		return MMCMapSegmentFile{}, mapErr
+	}
+
+	return MMCMapSegmentFile{ File: file, IsCold: isCold }, nil
+}
+
+// mapColdSegment
+//	Decompress the .cold file of segment id into the reservation.
+func (mmcMap *MMCMap) mapColdSegment(reservation mmap.MMap, id uint64) (MMCMapSegmentFile, error) {
+	file, openErr := os.Open(mmcMap.coldSegmentPath(id))
+	if openErr != nil { return MMCMapSegmentFile{}, openErr }
+
+	mapCold := func() error {
+		encoded, readErr := io.ReadAll(file)
+		if readErr != nil { return readErr }
+
+		data, _, decodeErr := decompressValue(encoded)
+		if decodeErr != nil { return decodeErr }
+		if uint64(len(data)) != mmcMap.Header.SegmentSize {
+			return fmt.Errorf("cold segment %d is %d bytes instead of the %d byte segment size", id, len(data), mmcMap.Header.SegmentSize)
+		}
+
+		_, mapErr := mmap.MapCopyAt(reservation[id * mmcMap.Header.SegmentSize:], data)
+		return mapErr
+	}
+
+	mapErr := mapCold()
+	if mapErr != nil {
+		file.Close()
+		return MMCMapSegmentFile{}, mapErr
+	}
+
+	return MMCMapSegmentFile{ File: file, IsCold: true, IsCompressed: true }, nil
+}
+
+// isColdEligible
+//	Determine if segment id is older than the newest HotSegments segments and every byte of it has been written, so it is never written again.
+//	The first segment holds the metadata and is always hot.
+func (mmcMap *MMCMap) isColdEligible(id, lastSegment, endOffset uint64) bool {
+	hotSegments := mmcMap.Opts.HotSegments
+	if hotSegments == 0 || id == 0 || id + hotSegments > lastSegment { return false }
+
+	return (id + 1) * mmcMap.Header.SegmentSize <= endOffset
+}
+
+// hotRegions
+//	The start and end offsets of the regions of the first size bytes of the memory map that are not in cold segments.
+func (mmcMap *MMCMap) hotRegions(size uint64) [][2]uint64 {
+	segmentSize := mmcMap.Header.SegmentSize
+
+	var regions [][2]uint64
+	var start uint64
+	for id, file := range mmcMap.loadSegmentFiles() {
+		if ! file.IsCold { continue }
+
+		coldStart := uint64(id) * segmentSize
+		if coldStart >= size { break }
+		if coldStart > start { regions = append(regions, [2]uint64{ start, coldStart }) }
+
+		start = coldStart + segmentSize
+	}
+
+	if start < size { regions = append(regions, [2]uint64{ start, size }) }
+	return regions
+}
+
+// coldSegmentPath
+//	The path of the compressed file of segment id.
+func (mmcMap *MMCMap) coldSegmentPath(id uint64) string {
+	return mmcMap.segmentPath(id) + coldSegmentSuffix
+}
+
+// removeSegment
+//	Remove the segment file of segment id, along with its .cold file.
+func (mmcMap *MMCMap) removeSegment(id uint64) error {
+	for _, path := range []string{ mmcMap.segmentPath(id), mmcMap.coldSegmentPath(id) } {
+		removeErr := os.Remove(path)
+		if removeErr != nil && ! errors.Is(removeErr, os.ErrNotExist) { return removeErr }
+	}
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package mmcmap
+
+import "bytes"
+import "unsafe"
+
+
+//============================================= MMCMap Collision Buckets
+
+
+// isCollisionLevel
+//	Determine if internal nodes at level are collision buckets. Keys still sharing a path once CollisionHashSeeds seeds of the hash are exhausted
+//	collide for every seed, so rather than reseeding forever, the internal node at that level holds them as a list of leaves sorted by key, with the
+//	low bits of its bitmap set for each. Keys in a bucket are found by comparing the full key instead of by their hash.
+//	Files using KeyModeOrdered place distinct keys on distinct paths, so they have no collision buckets.
+func (mmcMap *MMCMap) isCollisionLevel(level int) bool {
+	return ! mmcMap.isOrderedKeys() && level >= mmcMap.HashChunks * CollisionHashSeeds
+}
+
+// collisionBitmap
+//	The bitmap of a collision bucket holding count leaves.
+func collisionBitmap(count int) uint32 {
+	return uint32(uint64(1) << count - 1)
+}
+
+// putCollision
+//	Put the key-value pair in the collision bucket at node, updating the leaf if the key is already in the bucket and otherwise inserting a new leaf at
+//	its sorted position. Returns ErrCollisionBucketFull if the bucket already holds MaxCollisionBucketKeys keys.
+func (mmcMap *MMCMap) putCollision(node *unsafe.Pointer, key, value []byte, expiresAt int64) (bool, error) {
+	currNode := loadNodeFromPointer(node)
+	nodeCopy := mmcMap.copyNode(currNode)
+
+	pos, childNode, findErr := mmcMap.findCollision(nodeCopy, key)
+	if findErr != nil { return false, findErr }
+
+	if childNode != nil {
+		nodeCopy.Children[pos] = mmcMap.updateLeafNode(childNode, value, expiresAt, nodeCopy.Version)
+
+		mmcMap.compareAndSwap(node, currNode, nodeCopy)
+		return false, nil
+	}
+
+	if len(nodeCopy.Children) >= MaxCollisionBucketKeys { return false, ErrCollisionBucketFull }
+
+	newLeaf := mmcMap.newLeafNode(key, value, expiresAt, nodeCopy.Version)
+	nodeCopy.Bitmap = collisionBitmap(len(nodeCopy.Children) + 1)
+	nodeCopy.Children = extendTable(nodeCopy.Children, nodeCopy.Bitmap, pos, newLeaf)
+
+	mmcMap.compareAndSwap(node, currNode, nodeCopy)
+	return true, nil
+}
+
+// deleteCollision
+//	Remove the leaf for key from the collision bucket at node, shifting the leaves after it down one position.
+func (mmcMap *MMCMap) deleteCollision(node *unsafe.Pointer, key []byte) (bool, error) {
+	currNode := loadNodeFromPointer(node)
+
+	pos, childNode, findErr := mmcMap.findCollision(currNode, key)
+	if findErr != nil { return false, findErr }
+	if childNode == nil { return false, nil }
+
+	nodeCopy := mmcMap.copyNode(currNode)
+	nodeCopy.Bitmap = collisionBitmap(len(nodeCopy.Children) - 1)
+	nodeCopy.Children = shrinkTable(nodeCopy.Children, nodeCopy.Bitmap, pos)
+
+	mmcMap.compareAndSwap(node, currNode, nodeCopy)
+	return true, nil
+}
+
+// findCollision
+//	Scan the sorted leaves of the collision bucket for key, returning its position and leaf if it is present, or the position it would be inserted at
+//	and a nil leaf if it is not.
+func (mmcMap *MMCMap) findCollision(bucket *MMCMapNode, key []byte) (int, *MMCMapNode, error) {
+	for pos, child := range bucket.Children {
+		childNode, getChildErr := mmcMap.getChildNode(child, bucket.Version)
+		if getChildErr != nil { return 0, nil, getChildErr }
+
+		switch cmp := bytes.Compare(key, childNode.Key); {
+			case cmp == 0:
+				return pos, childNode, nil
+			case cmp < 0:
+				return pos, nil, nil
+		}
+	}
+
+	return len(bucket.Children), nil, nil
+}
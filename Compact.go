@@ -0,0 +1,352 @@
+package mmcmap
+
+import "os"
+import "sync/atomic"
+import "time"
+
+import "github.com/sirgallo/mmcmap/common/mmap"
+
+
+//============================================= MMCMap Compaction
+
+
+// Compact
+//	Reclaim the space used by prior versions by copying only the nodes reachable from the latest root into a new file and swapping it in.
+//	The header and live trie are written to a file next to the map, which is synced and then renamed over the original, so a crash during compaction
+//	leaves the original file intact. The copied trie is committed as the next version with the same contents as the latest version, but like a
+//	truncating Clear, replicas, history, and transactions begun before the compaction can not reach versions from before it.
+//	The compaction first waits for pinned traversals, such as undrained RangeChans and unreleased GetZeroCopy values, without blocking commits or reads.
+//	Commits and reads are then blocked for the duration of the compaction. The compaction hooks are called once they resume.
+//	Files using segmented storage return ErrSegmentedUnsupported, and reclaim space with ReclaimSegments instead.
+func (mmcMap *MMCMap) Compact() (*CompactReport, error) {
+	report, compactErr := mmcMap.compact()
+	if compactErr != nil { return nil, compactErr }
+
+	mmcMap.fireCompact(report)
+	return report, nil
+}
+
+// compact
+//	The body of Compact, run while commits and reads are blocked.
+func (mmcMap *MMCMap) compact() (*CompactReport, error) {
+	if mmcMap.isFollower() { return nil, ErrFollowerReadOnly }
+	if mmcMap.isSegmented() { return nil, ErrSegmentedUnsupported }
+
+	startTime := time.Now()
+
+	mmcMap.lockRelocate()
+	defer mmcMap.unlockRelocate()
+
+	mmcMap.lockResize()
+	defer mmcMap.unlockResize()
+
+	handleErr := mmcMap.checkHandle()
+	if handleErr != nil { return nil, handleErr }
+
+	meta, readMetaErr := mmcMap.ReadMetaFromMemMap()
+	if readMetaErr != nil { return nil, readMetaErr }
+
+	prevSize, fSizeErr := mmcMap.FileSize()
+	if fSizeErr != nil { return nil, fSizeErr }
+
+	compactPath := mmcMap.Filepath + ".compact"
+
+	var nodesCopied uint64
+	newMeta, size, compactErr := mmcMap.writeCompactedImage(compactPath, meta, meta.Version + 1, &nodesCopied, nil)
+	if compactErr != nil { return nil, compactErr }
+
+	swapErr := mmcMap.swapFile(compactPath)
+	if swapErr != nil { return nil, swapErr }
+
+	atomic.AddUint64(&mmcMap.RelocateGeneration, 1)
+
+	report := &CompactReport{
+		PrevVersion: meta.Version,
+		Version: newMeta.Version,
+		PrevEndMmapOffset: meta.EndMmapOffset,
+		EndMmapOffset: newMeta.EndMmapOffset,
+		PrevFileSize: uint64(prevSize),
+		FileSize: uint64(size),
+		ReclaimedBytes: meta.EndMmapOffset - newMeta.EndMmapOffset,
+		NodesCopied: nodesCopied,
+		Duration: time.Since(startTime),
+	}
+
+	atomic.AddUint64(&mmcMap.CompactionCount, 1)
+	atomic.AddUint64(&mmcMap.CompactionReclaimedBytes, report.ReclaimedBytes)
+	atomic.AddInt64(&mmcMap.CompactionNanos, int64(report.Duration))
+
+	return report, nil
+}
+
+// CloneTo
+//	Write an independent, compacted copy of the latest version of the map to a new file at path, which can then be opened as a separate map.
+//	Only the nodes reachable from the latest root are copied, so the clone holds no prior versions, and it keeps the version number, key count, and
+//	allocator of the map. The copy is written next to path and renamed into place once synced, and an existing file at path is never overwritten.
+//	Files using segmented storage return ErrSegmentedUnsupported.
+//	Like ExportSnapshot, commits are blocked while the copy is made so the key count matches the copied root, but reads continue.
+func (mmcMap *MMCMap) CloneTo(path string) error {
+	if mmcMap.isSegmented() { return ErrSegmentedUnsupported }
+
+	_, statErr := os.Lstat(path)
+	if statErr == nil { return &os.PathError{ Op: "clone", Path: path, Err: os.ErrExist } }
+	if ! os.IsNotExist(statErr) { return statErr }
+
+	mmcMap.CommitGate.Lock()
+	defer mmcMap.CommitGate.Unlock()
+
+	clonePath := path + ".clone"
+	compactErr := func() error {
+		mmcMap.waitForRemap()
+
+		mmcMap.RWResizeLock.RLock()
+		defer mmcMap.RWResizeLock.RUnlock()
+
+		handleErr := mmcMap.checkHandle()
+		if handleErr != nil { return handleErr }
+
+		meta, readMetaErr := mmcMap.ReadMetaFromMemMap()
+		if readMetaErr != nil { return readMetaErr }
+
+		var nodesCopied uint64
+		_, _, writeErr := mmcMap.writeCompactedImage(clonePath, meta, meta.Version, &nodesCopied, nil)
+		return writeErr
+	}()
+
+	if compactErr != nil { return compactErr }
+
+	renameErr := os.Rename(clonePath, path)
+	if renameErr != nil {
+		os.Remove(clonePath)
+		return renameErr
+	}
+
+	return nil
+}
+
+// ShrinkToFit
+//	Truncate the file to the end of the serialized data, rounded up to the page size, and remap it, returning the space past the end to the OS.
+//	Compact already sizes the new file to the live data, so this is mostly useful after a truncating Clear or a Repair, or to release the space the
+//	memory map was grown by ahead of writes. The memory map grows again on the next write that does not fit. Returns the new size of the file.
+//	Files using segmented storage only grow by whole segments, and return ErrSegmentedUnsupported.
+func (mmcMap *MMCMap) ShrinkToFit() (int, error) {
+	if mmcMap.isSegmented() { return 0, ErrSegmentedUnsupported }
+
+	mmcMap.lockResize()
+	defer mmcMap.unlockResize()
+
+	handleErr := mmcMap.checkHandle()
+	if handleErr != nil { return 0, handleErr }
+
+	_, endOffset, loadSOffErr := mmcMap.loadMetaEndSerialized()
+	if loadSOffErr != nil { return 0, loadSOffErr }
+
+	size, fSizeErr := mmcMap.FileSize()
+	if fSizeErr != nil { return 0, fSizeErr }
+
+	pageSize := uint64(DefaultPageSize)
+	shrunkSize := int((endOffset + pageSize) / pageSize * pageSize)
+	if shrunkSize >= size { return size, nil }
+
+	flushErr := mmcMap.syncFiles()
+	if flushErr != nil { return 0, flushErr }
+
+	unmapErr := mmcMap.munmap()
+	if unmapErr != nil { return 0, unmapErr }
+
+	truncateErr := mmcMap.File.Truncate(int64(shrunkSize))
+	if truncateErr != nil { return 0, truncateErr }
+
+	mmapErr := mmcMap.mMap()
+	if mmapErr != nil { return 0, mmapErr }
+
+	return shrunkSize, nil
+}
+
+// writeCompactedImage
+//	Write a file at path holding the header and a copy of the trie reachable from the root in meta, with the copied root committed at version.
+//	The new file is mapped and the nodes are copied straight into the mapping one at a time, so the copy is never built up in memory. Files with the
+//	compact encoding load the trie first to size the single path it is written as at version. patch, if set, is called with the mapping once the copy
+//	is written. The resize lock must be held. Returns the metadata of the copy and the size of the file.
+func (mmcMap *MMCMap) writeCompactedImage(path string, meta *MMCMapMetaData, version uint64, nodesCopied *uint64, patch func(mmap.MMap)) (*MMCMapMetaData, int64, error) {
+	newMeta := &MMCMapMetaData{ Version: version, RootOffset: mmcMap.HeaderSize }
+
+	var root *MMCMapNode
+	var length uint64
+
+	if mmcMap.isCompactEncoding() {
+		var loadErr error
+		root, loadErr = mmcMap.loadTrieRecursive(meta.RootOffset, version, nodesCopied)
+		if loadErr != nil { return nil, 0, loadErr }
+
+		length = mmcMap.HeaderSize + compactPathSize(root, version)
+	} else {
+		// every reachable node is copied once at the size it was serialized at, so the copy is no longer than the data it is copied from
+		length = meta.EndMmapOffset
+	}
+
+	size, writeErr := writeMappedFile(path, length, func(compacted mmap.MMap) (int64, error) {
+		var endOffset uint64
+
+		if root != nil {
+			written, writeErr := mmcMap.writeCompactRecursive(compacted[mmcMap.HeaderSize:length], root, version)
+			if writeErr != nil { return 0, writeErr }
+
+			endOffset = mmcMap.HeaderSize + written
+		} else {
+			var copyErr error
+			endOffset, copyErr = mmcMap.copyRecursive(compacted, meta.RootOffset, mmcMap.HeaderSize, nodesCopied)
+			if copyErr != nil { return 0, copyErr }
+
+			// the root is the first node of the copied trie
+			copy(compacted[mmcMap.HeaderSize + NodeVersionIdx:mmcMap.HeaderSize + NodeStartOffsetIdx], serializeUint64(version))
+		}
+
+		newMeta.EndMmapOffset = endOffset
+
+		mMap := mmcMap.Data.Load().(mmap.MMap)
+		copy(compacted[HeaderIdx:mmcMap.HeaderSize], mMap[HeaderIdx:mmcMap.HeaderSize])
+		copy(compacted[MetaVersionIdx:HeaderIdx], newMeta.SerializeMetaData())
+
+		// every unreachable region is discarded, so the free list starts empty
+		if mmcMap.isFreeListEnabled() { copy(compacted[FreeListIdx:FreeListRegionsIdx], serializeUint64(0)) }
+		// the shard roots point into the old layout, so the table starts empty and the shards are rebuilt by the next commits
+		if mmcMap.Header.WriteShards > 0 {
+			tableIdx := mmcMap.Header.shardTableIdx()
+			copy(compacted[tableIdx:tableIdx + ShardTableSize], make([]byte, ShardTableSize))
+		}
+
+		if patch != nil { patch(compacted) }
+
+		return mmcMap.capFileSize(compactedFileSize(endOffset)), nil
+	})
+
+	if writeErr != nil { return nil, 0, writeErr }
+	return newMeta, size, nil
+}
+
+// copyRecursive
+//	Copy the node at offset and every node reachable from it into dst one node at a time, laid out depth first starting at newOffset in the same order
+//	a path copy is serialized. The children are copied first so the node can be written with the offsets of its copied children, into the space left
+//	for it ahead of them, and overflow extents are copied directly after their leaf. Returns the offset following the copy.
+func (mmcMap *MMCMap) copyRecursive(dst mmap.MMap, offset, newOffset uint64, nodesCopied *uint64) (uint64, error) {
+	node, readNodeErr := mmcMap.ReadNodeFromMemMap(offset)
+	if readNodeErr != nil { return 0, readNodeErr }
+
+	node.StartOffset = newOffset
+	*nodesCopied++
+
+	var nextStartOffset uint64
+	if node.IsLeaf {
+		node.OverflowOffset = 0
+	} else {
+		nextStartOffset = node.determineEndOffset() + 1
+
+		for _, child := range node.Children {
+			childEndOffset, copyErr := mmcMap.copyRecursive(dst, child.StartOffset, nextStartOffset, nodesCopied)
+			if copyErr != nil { return 0, copyErr }
+
+			child.StartOffset = nextStartOffset
+			nextStartOffset = childEndOffset
+		}
+	}
+
+	sNode, serializeErr := node.SerializeNode(newOffset)
+	if serializeErr != nil { return 0, serializeErr }
+
+	rangeErr := checkRange(dst, "copy node", newOffset, uint64(len(sNode)), nil)
+	if rangeErr != nil { return 0, rangeErr }
+
+	copy(dst[newOffset:], sNode)
+	if node.IsLeaf { return newOffset + uint64(len(sNode)), nil }
+
+	return nextStartOffset, nil
+}
+
+// swapFile
+//	Rename the file written at compactPath over the original and remap it.
+//	The resize write lock must be held. If the new file can not be renamed, the original file is remapped and the new file is removed.
+func (mmcMap *MMCMap) swapFile(compactPath string) error {
+	pending := atomic.LoadUint64(&mmcMap.UnflushedBytes)
+
+	unmapErr := mmcMap.munmap()
+	if unmapErr != nil { return unmapErr }
+
+	closeErr := mmcMap.File.Close()
+	if closeErr != nil { return closeErr }
+
+	renameErr := os.Rename(compactPath, mmcMap.Filepath)
+
+	file, openFileErr := os.OpenFile(mmcMap.Filepath, os.O_RDWR | os.O_APPEND, 0600)
+	if openFileErr != nil { return openFileErr }
+
+	mmcMap.File = file
+
+	mmapErr := mmcMap.mMap()
+	if mmapErr != nil { return mmapErr }
+
+	if renameErr != nil {
+		os.Remove(compactPath)
+		return renameErr
+	}
+
+	mmcMap.markFlushed(pending, mmcMap.publishedVersion())
+	return nil
+}
+
+// writeMappedFile
+//	Create the file at path with room for length bytes, map it, and call write to fill in the mapping, which returns the size of the file.
+//	The mapping is flushed and released, and the file is truncated to the size and synced to disk. The file is removed on failure.
+func writeMappedFile(path string, length uint64, write func(mmap.MMap) (int64, error)) (int64, error) {
+	size, writeErr := func() (int64, error) {
+		file, openFileErr := os.OpenFile(path, os.O_RDWR | os.O_CREATE | os.O_TRUNC, 0600)
+		if openFileErr != nil { return 0, openFileErr }
+		defer file.Close()
+
+		truncateErr := file.Truncate(int64(length))
+		if truncateErr != nil { return 0, truncateErr }
+
+		mapped, mmapErr := mmap.MapRegion(file, int(length), mmap.RDWR, 0, 0)
+		if mmapErr != nil { return 0, mmapErr }
+
+		size, writeErr := write(mapped)
+		if writeErr != nil {
+			mapped.Unmap()
+			return 0, writeErr
+		}
+
+		flushErr := mapped.Flush()
+		if flushErr != nil {
+			mapped.Unmap()
+			return 0, flushErr
+		}
+
+		unmapErr := mapped.Unmap()
+		if unmapErr != nil { return 0, unmapErr }
+
+		truncateErr = file.Truncate(size)
+		if truncateErr != nil { return 0, truncateErr }
+
+		return size, file.Sync()
+	}()
+
+	if writeErr != nil {
+		os.Remove(path)
+		return 0, writeErr
+	}
+
+	return size, nil
+}
+
+// compactedFileSize
+//	The size of the compacted file, grown from the initial file size the same way the memory map is resized until the data fits.
+func compactedFileSize(endOffset uint64) int64 {
+	size := initialFileSize()
+	for uint64(size) <= endOffset {
+		if size >= MaxResize {
+			size += MaxResize
+		} else { size *= 2 }
+	}
+
+	return size
+}
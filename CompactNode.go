@@ -0,0 +1,287 @@
+package mmcmap
+
+import "encoding/binary"
+import "fmt"
+
+import "github.com/sirgallo/mmcmap/common/mmap"
+
+
+//============================================= MMCMap Compact Node Encoding
+
+
+// isCompactEncoding
+//	Determine if nodes in the file are serialized with the compact encoding.
+func (mmcMap *MMCMap) isCompactEncoding() bool {
+	return mmcMap.Header.NodeEncoding == NodeEncodingCompact
+}
+
+// serializedPathSize
+//	The number of bytes a path copy serializes to in the node encoding of the file.
+func (mmcMap *MMCMap) serializedPathSize(path *MMCMapNode) uint64 {
+	if mmcMap.isCompactEncoding() { return compactPathSize(path, path.Version) }
+	return pathSize(path, path.Version)
+}
+
+// writePath
+//	Serialize a path copy into buf, which starts at offset in the memory map, in the node encoding of the file.
+func (mmcMap *MMCMap) writePath(buf []byte, path *MMCMapNode, offset uint64) error {
+	if mmcMap.isCompactEncoding() {
+		_, writeErr := mmcMap.writeCompactRecursive(buf, path, path.Version)
+		return writeErr
+	}
+
+	_, serializeErr := mmcMap.serializeRecursive(buf, path, path.Version, 0, offset)
+	return serializeErr
+}
+
+// encodeNode
+//	Serialize a single node into a new buffer in the node encoding of the file. Internal nodes write the current offsets of their children.
+func (mmcMap *MMCMap) encodeNode(node *MMCMapNode) ([]byte, error) {
+	if ! mmcMap.isCompactEncoding() { return node.SerializeNode(node.StartOffset) }
+
+	ptrs, _ := compactChildPtrs(node, 0)
+	sNode := make([]byte, node.compactSize(ptrs))
+
+	_, writeErr := node.writeCompactNode(sNode, ptrs)
+	if writeErr != nil { return nil, writeErr }
+
+	return sNode, nil
+}
+
+// compactPathSize
+//	The number of bytes a path copy serializes to in the compact encoding. Only nodes at the version of the path are serialized.
+func compactPathSize(node *MMCMapNode, version uint64) uint64 {
+	ptrs, childrenSize := compactChildPtrs(node, version)
+	return node.compactSize(ptrs) + childrenSize
+}
+
+// compactChildPtrs
+//	The encoded pointers to the children of an internal node. Children at version are serialized directly after the node, one after another, so
+//	their pointer is their distance from the end of the node. Children of older versions point to their offset, tagged in the low bit.
+//	Encoding the path relative to itself keeps the pointers short and the serialized path the same wherever it is placed in the memory map.
+//	Version 0 is never committed, so passing it references every child by offset, as for a node written on its own.
+//	Also returns the number of bytes the children at version serialize to.
+func compactChildPtrs(node *MMCMapNode, version uint64) ([]uint64, uint64) {
+	if node.IsLeaf { return nil, 0 }
+
+	var distance uint64
+	ptrs := make([]uint64, len(node.Children))
+	for idx, child := range node.Children {
+		if version != 0 && child.Version == version {
+			ptrs[idx] = distance << 1
+			distance += compactPathSize(child, version)
+		} else { ptrs[idx] = child.StartOffset << 1 | 1 }
+	}
+
+	return ptrs, distance
+}
+
+// writeCompactRecursive
+//	Traverses the path copy down to the end of the path, writing each node into buf in the compact encoding followed by its children at version.
+//	Returns the number of bytes written.
+func (mmcMap *MMCMap) writeCompactRecursive(buf []byte, node *MMCMapNode, version uint64) (uint64, error) {
+	ptrs, _ := compactChildPtrs(node, version)
+	written, writeErr := node.writeCompactNode(buf, ptrs)
+	if writeErr != nil { return 0, writeErr }
+
+	for _, child := range node.Children {
+		if child.Version != version { continue }
+
+		childSize, writeErr := mmcMap.writeCompactRecursive(buf[written:], child, version)
+		if writeErr != nil { return 0, writeErr }
+
+		written += childSize
+	}
+
+	mmcMap.NodePool.Put(node)
+	return written, nil
+}
+
+// compactSize
+//	The number of bytes the node serializes to in the compact encoding, given the encoded pointers to its children.
+func (node *MMCMapNode) compactSize(ptrs []uint64) uint64 {
+	bodySize := node.compactBodySize(ptrs)
+	return 1 + uvarintSize(node.Version) + uvarintSize(bodySize) + bodySize
+}
+
+// compactBodySize
+//	The number of bytes after the length of a compact node.
+func (node *MMCMapNode) compactBodySize(ptrs []uint64) uint64 {
+	if node.IsLeaf {
+		size := uvarintSize(uint64(len(node.Key))) + uint64(len(node.Key)) + uint64(len(node.storedValue()))
+		if node.ExpiresAt != 0 { size += uvarintSize(uint64(node.ExpiresAt)) }
+
+		return size
+	}
+
+	size := uint64(BitmapSize)
+	for _, ptr := range ptrs { size += uvarintSize(ptr) }
+
+	return size
+}
+
+// writeCompactNode
+//	Write the node into the start of buf in the compact encoding. Returns the number of bytes written.
+//	Keys longer than MaxKeyLength return ErrKeyTooLarge, matching the fixed encoding.
+func (node *MMCMapNode) writeCompactNode(buf []byte, ptrs []uint64) (uint64, error) {
+	if node.IsLeaf && len(node.Key) > MaxKeyLength { return 0, ErrKeyTooLarge }
+
+	tag := byte(CompactNodeTag)
+	if node.IsLeaf { tag |= CompactLeafTag | byte(node.leafFlags()) }
+
+	buf[0] = tag
+	idx := 1 + binary.PutUvarint(buf[1:], node.Version)
+	idx += binary.PutUvarint(buf[idx:], node.compactBodySize(ptrs))
+
+	if node.IsLeaf {
+		idx += binary.PutUvarint(buf[idx:], uint64(len(node.Key)))
+		idx += copy(buf[idx:], node.Key)
+		if node.ExpiresAt != 0 { idx += binary.PutUvarint(buf[idx:], uint64(node.ExpiresAt)) }
+		idx += copy(buf[idx:], node.storedValue())
+
+		return uint64(idx), nil
+	}
+
+	binary.LittleEndian.PutUint32(buf[idx:idx + BitmapSize], node.Bitmap)
+	idx += BitmapSize
+
+	for _, ptr := range ptrs { idx += binary.PutUvarint(buf[idx:], ptr) }
+	return uint64(idx), nil
+}
+
+// readCompactNode
+//	Read the compact node at startOffset in the memory map. The start and end offsets, which are not stored in the compact encoding, are derived
+//	from the position of the node, and the offsets of children serialized in the same path are resolved from the end of the node.
+func (mmcMap *MMCMap) readCompactNode(mMap mmap.MMap, startOffset uint64) (*MMCMapNode, error) {
+	corruptErr := fmt.Errorf("%w at offset %d", ErrCorruptNode, startOffset)
+
+	tag, version, endOffset, bodyOffset, ok := readCompactHeader(mMap, startOffset)
+	if ! ok { return nil, corruptErr }
+
+	body := mMap[bodyOffset:endOffset + 1]
+	node := &MMCMapNode{ Version: version, StartOffset: startOffset, EndOffset: endOffset, IsLeaf: tag & CompactLeafTag != 0 }
+
+	if node.IsLeaf {
+		keyLength, n := binary.Uvarint(body)
+		if n <= 0 || keyLength > MaxKeyLength || uint64(n) + keyLength > uint64(len(body)) { return nil, corruptErr }
+
+		node.KeyLength = uint16(keyLength)
+		node.Key = body[n:uint64(n) + keyLength]
+		body = body[uint64(n) + keyLength:]
+
+		if tag & LeafExpiresFlag != 0 {
+			expiresAt, n := binary.Uvarint(body)
+			if n <= 0 { return nil, corruptErr }
+
+			node.ExpiresAt = int64(expiresAt)
+			body = body[n:]
+		}
+
+		node.Value = body
+		if tag & LeafCompressedFlag != 0 {
+			value, codec, decompressErr := decompressValue(node.Value)
+			if decompressErr != nil { return nil, decompressErr }
+
+			node.Compression = codec
+			node.EncodedValue = node.Value
+			node.Value = value
+		}
+
+		return node, nil
+	}
+
+	if len(body) < BitmapSize { return nil, corruptErr }
+
+	node.Bitmap = binary.LittleEndian.Uint32(body[:BitmapSize])
+	body = body[BitmapSize:]
+
+	node.Children = newChildStubs(calculateHammingWeight(node.Bitmap))
+	for _, child := range node.Children {
+		ptr, n := binary.Uvarint(body)
+		if n <= 0 { return nil, corruptErr }
+
+		child.StartOffset = ptr >> 1
+		if ptr & 1 == 0 { child.StartOffset += endOffset + 1 }
+
+		body = body[n:]
+	}
+
+	return node, nil
+}
+
+// readCompactHeader
+//	Read the tag, version, and length of the compact node at offset, returning the offset of the last byte of the node and the offset its body
+//	starts at. ok is false if the byte at offset is not the tag of a node or the node does not fit in the memory map.
+func readCompactHeader(mMap mmap.MMap, offset uint64) (tag byte, version, endOffset, bodyOffset uint64, ok bool) {
+	if offset >= uint64(len(mMap)) || mMap[offset] & CompactNodeTag == 0 { return 0, 0, 0, 0, false }
+
+	tag = mMap[offset]
+	bodyOffset = offset + 1
+
+	version, n := binary.Uvarint(mMap[bodyOffset:])
+	if n <= 0 { return 0, 0, 0, 0, false }
+	bodyOffset += uint64(n)
+
+	bodySize, n := binary.Uvarint(mMap[bodyOffset:])
+	if n <= 0 || bodySize == 0 { return 0, 0, 0, 0, false }
+	bodyOffset += uint64(n)
+	if bodySize > uint64(len(mMap)) - bodyOffset { return 0, 0, 0, 0, false }
+
+	return tag, version, bodyOffset + bodySize - 1, bodyOffset, true
+}
+
+// scanCompactNodes
+//	Walk the compact node headers from the scan offset through limit, calling visit with the offset, version, end offset, and type of each node.
+//	A single zeroed byte is skipped between paths, matching the gap left by the append allocator. Stops at the first byte that is neither, or when
+//	visit returns false. Returns the offset one past the last node visited, and whether the walk reached limit.
+func (mmcMap *MMCMap) scanCompactNodes(mMap mmap.MMap, limit uint64, visit func(offset, version, endOffset uint64, isLeaf bool) bool) (uint64, bool) {
+	offset := mmcMap.scanOffset()
+	scanEnd := offset
+
+	for offset <= limit {
+		tag, version, endOffset, _, ok := readCompactHeader(mMap, offset)
+		if ! ok {
+			offset++
+			tag, version, endOffset, _, ok = readCompactHeader(mMap, offset)
+			if ! ok { return scanEnd, offset > limit }
+		}
+
+		if endOffset >= uint64(len(mMap)) || ! visit(offset, version, endOffset, tag & CompactLeafTag != 0) { return scanEnd, false }
+
+		scanEnd = endOffset + 1
+		offset = endOffset + 1
+	}
+
+	return scanEnd, true
+}
+
+// loadTrieRecursive
+//	Read the node at offset and every node reachable from it, setting each to version.
+func (mmcMap *MMCMap) loadTrieRecursive(offset, version uint64, nodesCopied *uint64) (*MMCMapNode, error) {
+	node, readNodeErr := mmcMap.ReadNodeFromMemMap(offset)
+	if readNodeErr != nil { return nil, readNodeErr }
+
+	node.Version = version
+	*nodesCopied++
+
+	for idx, child := range node.Children {
+		loaded, loadErr := mmcMap.loadTrieRecursive(child.StartOffset, version, nodesCopied)
+		if loadErr != nil { return nil, loadErr }
+
+		node.Children[idx] = loaded
+	}
+
+	return node, nil
+}
+
+// uvarintSize
+//	The number of bytes val is encoded to as a uvarint.
+func uvarintSize(val uint64) uint64 {
+	size := uint64(1)
+	for val >= 0x80 {
+		val >>= 7
+		size++
+	}
+
+	return size
+}
@@ -0,0 +1,83 @@
+package mmcmap
+
+import "sync"
+
+import "github.com/golang/snappy"
+import "github.com/klauspost/compress/zstd"
+
+
+//============================================= MMCMap Value Compression
+
+
+var zstdEncoder *zstd.Encoder
+var zstdDecoder *zstd.Decoder
+var zstdOnce sync.Once
+
+// storedValue
+//	The value of a leaf as it is serialized, either inline or in its overflow extent.
+//	Leaves with a codec are compressed the first time they are serialized, and the result is cached in EncodedValue. If compressing does not shrink
+//	the value, the codec of the leaf is dropped and the value is stored as is.
+func (node *MMCMapNode) storedValue() []byte {
+	if node.Compression != CompressionNone && node.EncodedValue == nil {
+		node.EncodedValue = compressValue(node.Compression, node.Value)
+		if node.EncodedValue == nil { node.Compression = CompressionNone }
+	}
+
+	if node.Compression == CompressionNone { return node.Value }
+	return node.EncodedValue
+}
+
+// compressValue
+//	Compress a value with codec, prefixed by the codec. Returns nil if the result is not smaller than the value.
+func compressValue(codec Compression, value []byte) []byte {
+	if len(value) == 0 { return nil }
+
+	encoded := []byte{ byte(codec) }
+	switch codec {
+		case CompressionSnappy:
+			encoded = append(encoded, snappy.Encode(nil, value)...)
+		case CompressionZstd:
+			initZstd()
+			encoded = zstdEncoder.EncodeAll(value, encoded)
+		default:
+			return nil
+	}
+
+	if len(encoded) >= len(value) { return nil }
+	return encoded
+}
+
+// decompressValue
+//	Decompress a value serialized by compressValue, returning the codec it was compressed with.
+func decompressValue(encoded []byte) ([]byte, Compression, error) {
+	if len(encoded) == 0 { return nil, CompressionNone, ErrUnknownCompression }
+
+	codec := Compression(encoded[0])
+	switch codec {
+		case CompressionSnappy:
+			// the decoded length is allocated up front, so reject lengths no snappy stream of this size could expand to
+			decodedLen, decLenErr := snappy.DecodedLen(encoded[1:])
+			if decLenErr != nil { return nil, CompressionNone, decLenErr }
+			if decodedLen > MaxDecompressedValueLength || decodedLen > len(encoded) * snappyMaxExpansion {
+				return nil, CompressionNone, corruptNodeErr("snappy value claims %d decoded bytes from %d", decodedLen, len(encoded) - 1)
+			}
+
+			value, decodeErr := snappy.Decode(nil, encoded[1:])
+			return value, codec, decodeErr
+		case CompressionZstd:
+			initZstd()
+			value, decodeErr := zstdDecoder.DecodeAll(encoded[1:], nil)
+			return value, codec, decodeErr
+		default:
+			return nil, CompressionNone, ErrUnknownCompression
+	}
+}
+
+// initZstd
+//	Create the zstd encoder and decoder shared by every map on first use. Both are safe for concurrent use with EncodeAll and DecodeAll.
+func initZstd() {
+	zstdOnce.Do(func() {
+		zstdEncoder, _ = zstd.NewWriter(nil)
+		zstdDecoder, _ = zstd.NewReader(nil, zstd.WithDecoderMaxMemory(MaxDecompressedValueLength))
+	})
+}
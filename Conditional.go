@@ -0,0 +1,50 @@
+package mmcmap
+
+import "bytes"
+
+
+//============================================= MMCMap Conditional Operations
+
+
+// CompareAndSwap
+//	Atomically replace the value of a key with newValue, only if the current value of the key is expectedValue.
+//	The current value is validated against the root the path copy is made from, so if another writer commits first the check is repeated against
+//	the new root. A nil expectedValue requires the key to be absent. If the value does not match, no new version is written and false is returned.
+func (mmcMap *MMCMap) CompareAndSwap(key, expectedValue, newValue []byte) (bool, error) {
+	return mmcMap.commitWith(func(root *MMCMapNode) ([]*BatchOp, error) {
+		currValue, getErr := mmcMap.getFromRoot(root, key)
+		if getErr != nil { return nil, getErr }
+
+		if ! valuesMatch(currValue, expectedValue) { return nil, errCommitAborted }
+		return []*BatchOp{{ Key: key, Value: newValue }}, nil
+	})
+}
+
+// PutIfAbsent
+//	Put the key-value pair only if the key does not exist at the latest version.
+//	If the key is present, no new version is written and false is returned.
+func (mmcMap *MMCMap) PutIfAbsent(key, value []byte) (bool, error) {
+	return mmcMap.CompareAndSwap(key, nil, value)
+}
+
+// DeleteIfEquals
+//	Delete the key only if its current value is expected.
+//	If the key is absent or holds a different value, no new version is written and false is returned.
+func (mmcMap *MMCMap) DeleteIfEquals(key, expected []byte) (bool, error) {
+	if expected == nil { return false, nil }
+
+	return mmcMap.commitWith(func(root *MMCMapNode) ([]*BatchOp, error) {
+		currValue, getErr := mmcMap.getFromRoot(root, key)
+		if getErr != nil { return nil, getErr }
+
+		if ! valuesMatch(currValue, expected) { return nil, errCommitAborted }
+		return []*BatchOp{{ Key: key, IsDelete: true }}, nil
+	})
+}
+
+// valuesMatch
+//	Compare a value read from the trie with an expected value, where a nil expected value only matches an absent key.
+func valuesMatch(currValue, expectedValue []byte) bool {
+	if expectedValue == nil || currValue == nil { return expectedValue == nil && currValue == nil }
+	return bytes.Equal(currValue, expectedValue)
+}
@@ -0,0 +1,265 @@
+package mmcmap
+
+import "bytes"
+import "errors"
+import "os"
+import "path/filepath"
+import "sort"
+
+
+//============================================= MMCMap Multi-Map Commit
+
+
+// intentMagic identifies a serialized commit intent
+var intentMagic = []byte("MMCI")
+
+
+// NewCoordinator
+//	Creates a coordinator for committing write batches across multiple mmcmaps, using intentPath for the shared intent record.
+//	Recover should be called with every participating map on startup, before the maps are written to.
+func NewCoordinator(intentPath string) *Coordinator {
+	return &Coordinator{ IntentPath: intentPath }
+}
+
+// Commit
+//	Commit a write batch to each of its maps so that either every batch is applied or none are.
+//	The commit runs in two phases:
+//		1.) prepare: block new commits on every map, flush each map, and durably write an intent record holding the current root of each map.
+//		2.) commit: apply each batch as a single new version of its map and flush the map to disk.
+//	Removing the intent record is the commit point. If a batch fails to apply, the maps already committed are restored to their prior roots.
+//	If the process crashes before the record is removed, Recover restores every map to the root in the record.
+func (coord *Coordinator) Commit(batches ...*WriteBatch) error {
+	if len(batches) == 0 { return nil }
+
+	sorted := make([]*WriteBatch, len(batches))
+	copy(sorted, batches)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Map.Opts.Filepath < sorted[j].Map.Opts.Filepath })
+
+	for idx := 1; idx < len(sorted); idx++ {
+		if sorted[idx].Map == sorted[idx - 1].Map { return errors.New("multiple batches for the same map in commit") }
+	}
+
+	for _, batch := range sorted {
+		batch.Map.CommitGate.Lock()
+		defer batch.Map.CommitGate.Unlock()
+	}
+
+	intent := &CommitIntent{}
+	for _, batch := range sorted {
+		entry, prepareErr := batch.Map.prepareIntentEntry()
+		if prepareErr != nil { return prepareErr }
+
+		intent.Entries = append(intent.Entries, entry)
+	}
+
+	writeIntentErr := coord.writeIntent(intent)
+	if writeIntentErr != nil { return writeIntentErr }
+
+	for idx, batch := range sorted {
+		commitErr := batch.Map.commitPrepared(batch.Ops)
+		if commitErr != nil {
+			for restoreIdx := 0; restoreIdx <= idx; restoreIdx++ {
+				restoreErr := sorted[restoreIdx].Map.restoreRoot(intent.Entries[restoreIdx].RootOffset, intent.Entries[restoreIdx].KeyCount)
+				if restoreErr != nil { return restoreErr }
+			}
+
+			os.Remove(coord.IntentPath)
+			return commitErr
+		}
+	}
+
+	removeErr := os.Remove(coord.IntentPath)
+	if removeErr != nil { return removeErr }
+
+	for _, batch := range sorted { batch.Reset() }
+	return nil
+}
+
+// Recover
+//	Complete recovery of an interrupted multi-map commit. If no intent record exists there is nothing to recover.
+//	Otherwise the commit never reached its commit point, so every map whose root has moved since the intent was written is restored to the root
+//	in the record, and the record is removed. Every map named in the record must be provided.
+func (coord *Coordinator) Recover(maps ...*MMCMap) error {
+	data, readErr := os.ReadFile(coord.IntentPath)
+	if errors.Is(readErr, os.ErrNotExist) { return nil }
+	if readErr != nil { return readErr }
+
+	intent, desErr := DeserializeCommitIntent(data)
+	if desErr != nil { return desErr }
+
+	byPath := make(map[string]*MMCMap)
+	for _, mmcMap := range maps {
+		absPath, absErr := filepath.Abs(mmcMap.Opts.Filepath)
+		if absErr != nil { return absErr }
+
+		byPath[absPath] = mmcMap
+	}
+
+	for _, entry := range intent.Entries {
+		if byPath[entry.Filepath] == nil { return ErrIntentMapMissing }
+	}
+
+	for _, entry := range intent.Entries {
+		mmcMap := byPath[entry.Filepath]
+
+		mmcMap.CommitGate.Lock()
+		restoreErr := mmcMap.restoreRoot(entry.RootOffset, entry.KeyCount)
+		mmcMap.CommitGate.Unlock()
+
+		if restoreErr != nil { return restoreErr }
+	}
+
+	return os.Remove(coord.IntentPath)
+}
+
+// Serialize
+//	The serialized intent is the 4 byte magic "MMCI", a 2 byte entry count, and then for each entry a 2 byte path length, the path, the 8 byte version,
+//	the 8 byte root offset, and the 8 byte key count.
+func (intent *CommitIntent) Serialize() []byte {
+	var buf bytes.Buffer
+	buf.Write(intentMagic)
+	buf.Write(serializeUint16(uint16(len(intent.Entries))))
+
+	for _, entry := range intent.Entries {
+		buf.Write(serializeUint16(uint16(len(entry.Filepath))))
+		buf.WriteString(entry.Filepath)
+		buf.Write(serializeUint64(entry.Version))
+		buf.Write(serializeUint64(entry.RootOffset))
+		buf.Write(serializeUint64(entry.KeyCount))
+	}
+
+	return buf.Bytes()
+}
+
+// DeserializeCommitIntent
+//	Deserialize an intent record written by CommitIntent.Serialize.
+func DeserializeCommitIntent(data []byte) (*CommitIntent, error) {
+	if len(data) < len(intentMagic) + 2 || ! bytes.Equal(data[:len(intentMagic)], intentMagic) { return nil, errors.New("invalid commit intent header") }
+
+	offset := len(intentMagic)
+	count, _ := deserializeUint16(data[offset:offset + 2])
+	offset += 2
+
+	intent := &CommitIntent{}
+	for idx := 0; idx < int(count); idx++ {
+		if len(data) < offset + 2 { return nil, errors.New("commit intent truncated") }
+		pathLen, _ := deserializeUint16(data[offset:offset + 2])
+		offset += 2
+
+		if len(data) < offset + int(pathLen) + 24 { return nil, errors.New("commit intent truncated") }
+		entry := &IntentEntry{ Filepath: string(data[offset:offset + int(pathLen)]) }
+		offset += int(pathLen)
+
+		entry.Version, _ = deserializeUint64(data[offset:offset + 8])
+		entry.RootOffset, _ = deserializeUint64(data[offset + 8:offset + 16])
+		entry.KeyCount, _ = deserializeUint64(data[offset + 16:offset + 24])
+		offset += 24
+
+		intent.Entries = append(intent.Entries, entry)
+	}
+
+	return intent, nil
+}
+
+// writeIntent
+//	Durably write the intent record, replacing any existing record.
+func (coord *Coordinator) writeIntent(intent *CommitIntent) error {
+	file, openErr := os.OpenFile(coord.IntentPath, os.O_RDWR | os.O_CREATE | os.O_TRUNC, 0600)
+	if openErr != nil { return openErr }
+	defer file.Close()
+
+	_, writeErr := file.Write(intent.Serialize())
+	if writeErr != nil { return writeErr }
+
+	return file.Sync()
+}
+
+// prepareIntentEntry
+//	Flush the map and capture its current version, root, and key count for the intent record. The commit gate must be held exclusively.
+func (mmcMap *MMCMap) prepareIntentEntry() (*IntentEntry, error) {
+	mmcMap.RWResizeLock.RLock()
+	defer mmcMap.RWResizeLock.RUnlock()
+
+	handleErr := mmcMap.checkHandle()
+	if handleErr != nil { return nil, handleErr }
+
+	absPath, absErr := filepath.Abs(mmcMap.Opts.Filepath)
+	if absErr != nil { return nil, absErr }
+
+	_, version, loadVErr := mmcMap.loadMetaVersion()
+	if loadVErr != nil { return nil, loadVErr }
+
+	_, rootOffset, loadROffErr := mmcMap.loadMetaRootOffset()
+	if loadROffErr != nil { return nil, loadROffErr }
+
+	_, keyCount, loadKCountErr := mmcMap.loadMetaKeyCount()
+	if loadKCountErr != nil { return nil, loadKCountErr }
+
+	syncErr := mmcMap.syncFiles()
+	if syncErr != nil { return nil, syncErr }
+
+	return &IntentEntry{ Filepath: absPath, Version: version, RootOffset: rootOffset, KeyCount: keyCount }, nil
+}
+
+// commitPrepared
+//	Apply the ops as a single new version and flush the map to disk. The commit gate must be held exclusively.
+func (mmcMap *MMCMap) commitPrepared(ops []*BatchOp) error {
+	if len(ops) > 0 {
+		_, commitErr := mmcMap.commitWithGateHeld(func(root *MMCMapNode) ([]*BatchOp, error) { return ops, nil })
+		if commitErr != nil { return commitErr }
+	}
+
+	mmcMap.RWResizeLock.RLock()
+	defer mmcMap.RWResizeLock.RUnlock()
+
+	return mmcMap.syncFiles()
+}
+
+// restoreRoot
+//	Make the root at rootOffset the latest version again. The root is still present in the append-only memory map, so a copy of it is committed
+//	as a new version, which keeps versions increasing while pointing back at the prior trie. The key count is restored to keyCount along with it.
+//	The commit gate must be held exclusively.
+func (mmcMap *MMCMap) restoreRoot(rootOffset, keyCount uint64) error {
+	for {
+		restored, restoreErr := mmcMap.tryRestoreRoot(rootOffset, keyCount)
+		if restoreErr != nil { return restoreErr }
+		if restored { return nil }
+	}
+}
+
+// tryRestoreRoot
+//	A single attempt at committing a copy of the root at rootOffset. Returns false if the attempt should be retried.
+func (mmcMap *MMCMap) tryRestoreRoot(rootOffset, keyCount uint64) (bool, error) {
+	mmcMap.waitForResize()
+
+	mmcMap.RWResizeLock.RLock()
+	defer mmcMap.RWResizeLock.RUnlock()
+
+	handleErr := mmcMap.checkHandle()
+	if handleErr != nil { return false, handleErr }
+
+	_, currRootOffset, loadROffErr := mmcMap.loadMetaRootOffset()
+	if loadROffErr != nil { return false, loadROffErr }
+	if currRootOffset == rootOffset { return true, nil }
+
+	_, version, loadVErr := mmcMap.loadMetaVersion()
+	if loadVErr != nil { return false, loadVErr }
+
+	prevRoot, readRootErr := mmcMap.ReadNodeFromMemMap(rootOffset)
+	if readRootErr != nil { return false, readRootErr }
+
+	_, currKeyCount, loadKCountErr := mmcMap.loadMetaKeyCount()
+	if loadKCountErr != nil { return false, loadKCountErr }
+
+	prevRoot.Version = version + 1
+
+	written, writeErr := mmcMap.exclusiveWriteMmap(prevRoot, int64(keyCount - currKeyCount), nil, allWriteShards, nil)
+	if writeErr == ErrResizeInProgress { return false, nil }
+	if writeErr != nil { return false, writeErr }
+	if ! written { return false, nil }
+
+	syncErr := mmcMap.syncFiles()
+	if syncErr != nil { return false, syncErr }
+
+	return true, nil
+}
@@ -0,0 +1,36 @@
+package mmcmap
+
+import "encoding/binary"
+
+
+//============================================= MMCMap Counters
+
+
+// IncrBy
+//	Add delta to the value of the key, treating the value as a little endian int64, and return the new value.
+//	An absent key starts at 0. The existing value is read from the root the path copy is made from, so concurrent increments are retried against
+//	the latest version by the commit loop and none are lost. Decrement by passing a negative delta. ErrNotInteger is returned if the existing value
+//	is not 8 bytes.
+func (mmcMap *MMCMap) IncrBy(key []byte, delta int64) (int64, error) {
+	var updated int64
+
+	_, commitErr := mmcMap.commitWith(func(root *MMCMapNode) ([]*BatchOp, error) {
+		existing, getErr := mmcMap.getFromRoot(root, key)
+		if getErr != nil { return nil, getErr }
+
+		var curr int64
+		if existing != nil {
+			if len(existing) != 8 { return nil, ErrNotInteger }
+			curr = int64(binary.LittleEndian.Uint64(existing))
+		}
+
+		updated = curr + delta
+
+		value := make([]byte, 8)
+		binary.LittleEndian.PutUint64(value, uint64(updated))
+		return []*BatchOp{{ Key: key, Value: value }}, nil
+	})
+
+	if commitErr != nil { return 0, commitErr }
+	return updated, nil
+}
@@ -0,0 +1,126 @@
+package mmcmap
+
+import "bytes"
+import "sort"
+
+
+//============================================= MMCMap Diff
+
+
+// Diff
+//	Compare the trie at versionA with the trie at versionB, returning a KeyDiff for every key added, removed, or modified between them, sorted by key.
+//	Path copying never modifies a node in place, so a subtree that was not written between the two versions is at the same offset in both tries.
+//	Both roots are walked together and shared subtrees are skipped, so the cost grows with the size of the change rather than the size of the map.
+//	The roots are located by scanning the file, so versions before a Compact, or later than the latest version, return ErrVersionUnavailable, as do
+//	files using the FreeListAllocator. Swapping the versions swaps added and removed keys.
+func (mmcMap *MMCMap) Diff(versionA, versionB uint64) ([]KeyDiff, error) {
+	mmcMap.RelocateLock.RLock()
+	defer mmcMap.RelocateLock.RUnlock()
+
+	roots, scanErr := mmcMap.scanRoots()
+	if scanErr != nil { return nil, scanErr }
+
+	rootOffsetA, findAErr := rootAtVersion(roots, versionA)
+	if findAErr != nil { return nil, findAErr }
+
+	rootOffsetB, findBErr := rootAtVersion(roots, versionB)
+	if findBErr != nil { return nil, findBErr }
+
+	var diffs []KeyDiff
+	diffErr := mmcMap.diffRecursive(rootOffsetA, rootOffsetB, 0, func(diff KeyDiff) { diffs = append(diffs, diff) })
+	if diffErr != nil { return nil, diffErr }
+
+	sort.Slice(diffs, func(i, j int) bool { return bytes.Compare(diffs[i].Key, diffs[j].Key) < 0 })
+	return diffs, nil
+}
+
+// rootAtVersion
+//	The offset of the root visible at version, which is the last root committed at or before it, from roots in version order.
+func rootAtVersion(roots []rootRef, version uint64) (uint64, error) {
+	if len(roots) == 0 || version > roots[len(roots) - 1].version { return 0, ErrVersionUnavailable }
+
+	var rootOffset uint64
+	isFound := false
+
+	for _, root := range roots {
+		if root.version > version { break }
+
+		rootOffset = root.offset
+		isFound = true
+	}
+
+	if ! isFound { return 0, ErrVersionUnavailable }
+	return rootOffset, nil
+}
+
+// diffRecursive
+//	Compare the subtrees at offsetA and offsetB, which occupy the same slot of their tries, where an offset of 0 is an empty slot.
+//	Equal offsets are the same subtree and are skipped. While both are internal nodes, the walk descends into each slot set in either bitmap.
+//	Once either side is a leaf or empty, the keys beneath both sides are collected and compared directly, since every key beneath a slot shares the
+//	hash prefix leading to it. Collision buckets place their leaves by key rather than by hash, so they are always collected.
+func (mmcMap *MMCMap) diffRecursive(offsetA, offsetB uint64, level int, emit func(KeyDiff)) error {
+	if offsetA == offsetB { return nil }
+
+	var nodeA, nodeB *MMCMapNode
+	var readErr error
+
+	if offsetA > 0 {
+		nodeA, readErr = mmcMap.readNodeCopy(offsetA, true)
+		if readErr != nil { return readErr }
+	}
+
+	if offsetB > 0 {
+		nodeB, readErr = mmcMap.readNodeCopy(offsetB, true)
+		if readErr != nil { return readErr }
+	}
+
+	if nodeA != nil && nodeB != nil && ! nodeA.IsLeaf && ! nodeB.IsLeaf && ! mmcMap.isCollisionLevel(level) {
+		for index := 0; index < 32; index++ {
+			var childOffsetA, childOffsetB uint64
+			if IsBitSet(nodeA.Bitmap, index) { childOffsetA = nodeA.Children[childPosition(nodeA.Bitmap, index)].StartOffset }
+			if IsBitSet(nodeB.Bitmap, index) { childOffsetB = nodeB.Children[childPosition(nodeB.Bitmap, index)].StartOffset }
+
+			diffErr := mmcMap.diffRecursive(childOffsetA, childOffsetB, level + 1, emit)
+			if diffErr != nil { return diffErr }
+		}
+
+		return nil
+	}
+
+	pairsA, collectAErr := mmcMap.collectSubtree(offsetA)
+	if collectAErr != nil { return collectAErr }
+
+	pairsB, collectBErr := mmcMap.collectSubtree(offsetB)
+	if collectBErr != nil { return collectBErr }
+
+	for key, valueA := range pairsA {
+		valueB, isPresent := pairsB[key]
+		if ! isPresent {
+			emit(KeyDiff{ Key: []byte(key), OldValue: valueA, Kind: DiffRemoved })
+		} else if ! bytes.Equal(valueA, valueB) {
+			emit(KeyDiff{ Key: []byte(key), OldValue: valueA, NewValue: valueB, Kind: DiffModified })
+		}
+	}
+
+	for key, valueB := range pairsB {
+		_, isPresent := pairsA[key]
+		if ! isPresent { emit(KeyDiff{ Key: []byte(key), NewValue: valueB, Kind: DiffAdded }) }
+	}
+
+	return nil
+}
+
+// collectSubtree
+//	Collect every key-value pair beneath the node at offset, where an offset of 0 is an empty subtree.
+func (mmcMap *MMCMap) collectSubtree(offset uint64) (map[string][]byte, error) {
+	pairs := make(map[string][]byte)
+	if offset == 0 { return pairs, nil }
+
+	rangeErr := mmcMap.rangeRecursive(offset, nil, nil, false, func(pair *KeyValuePair) error {
+		pairs[string(pair.Key)] = pair.Value
+		return nil
+	})
+
+	if rangeErr != nil { return nil, rangeErr }
+	return pairs, nil
+}
@@ -0,0 +1,166 @@
+package mmcmap
+
+import "bufio"
+import "bytes"
+import "encoding/binary"
+import "errors"
+import "io"
+
+import "github.com/sirgallo/mmcmap/common/murmur"
+
+
+//============================================= MMCMap Key Digest
+
+
+// digestMagic identifies a serialized key digest
+var digestMagic = []byte("MMCD")
+
+// KeyDigest
+//	Computes a digest of every key in the mmcmap at the latest version.
+//	Keys are grouped by their leading byte (the empty key is grouped with 0x00) and each bucket records the number of keys along with the wrapping
+//	sum of a 64 bit hash of each key. The hash is two Murmur32 passes over the key with seeds 1 and 2, forming the high and low 32 bits respectively.
+//	Since the sum does not depend on order, any system that can enumerate its keys can produce a comparable digest without sharing the trie layout.
+func (mmcMap *MMCMap) KeyDigest() (*KeyDigest, error) {
+	mmcMap.RelocateLock.RLock()
+	defer mmcMap.RelocateLock.RUnlock()
+
+	rootOffset, loadROffErr := mmcMap.loadRootOffsetForRead()
+	if loadROffErr != nil { return nil, loadROffErr }
+
+	root, readRootErr := mmcMap.readNodeCopy(rootOffset, true)
+	if readRootErr != nil { return nil, readRootErr }
+
+	return mmcMap.keyDigestAt(rootOffset, root.Version)
+}
+
+// KeyDigestAtVersion
+//	Computes the key digest of the keys visible at version, the same as KeyDigest does for the latest version.
+//	The root is located by scanning the file, the same as History and Diff, so versions before a Compact, or later than the latest version, return
+//	ErrVersionUnavailable, as do files using the FreeListAllocator.
+func (mmcMap *MMCMap) KeyDigestAtVersion(version uint64) (*KeyDigest, error) {
+	mmcMap.RelocateLock.RLock()
+	defer mmcMap.RelocateLock.RUnlock()
+
+	roots, scanErr := mmcMap.scanRoots()
+	if scanErr != nil { return nil, scanErr }
+
+	rootOffset, findErr := rootAtVersion(roots, version)
+	if findErr != nil { return nil, findErr }
+
+	return mmcMap.keyDigestAt(rootOffset, version)
+}
+
+// ExportKeyDigest
+//	Computes the key digest at the latest version and writes it to w.
+//	The serialized digest is the 4 byte magic "MMCD", a 1 byte format version, the 8 byte map version, a 2 byte bucket count, and then for each bucket
+//	an 8 byte key count followed by the 8 byte bucket hash. All integers are little endian.
+func (mmcMap *MMCMap) ExportKeyDigest(w io.Writer) error {
+	digest, digestErr := mmcMap.KeyDigest()
+	if digestErr != nil { return digestErr }
+
+	_, writeErr := w.Write(digest.Serialize())
+	return writeErr
+}
+
+// ExportKeyDigestAtVersion
+//	Computes the key digest at version and writes it to w, serialized the same as ExportKeyDigest.
+func (mmcMap *MMCMap) ExportKeyDigestAtVersion(w io.Writer, version uint64) error {
+	digest, digestErr := mmcMap.KeyDigestAtVersion(version)
+	if digestErr != nil { return digestErr }
+
+	_, writeErr := w.Write(digest.Serialize())
+	return writeErr
+}
+
+// keyDigestAt
+//	Add every key reachable from the root at rootOffset to a digest tagged with version. The relocate read lock must be held.
+func (mmcMap *MMCMap) keyDigestAt(rootOffset, version uint64) (*KeyDigest, error) {
+	digest := &KeyDigest{ Version: version }
+	rangeErr := mmcMap.rangeRecursive(rootOffset, nil, nil, true, func(pair *KeyValuePair) error {
+		digest.Add(pair.Key)
+		return nil
+	})
+
+	if rangeErr != nil { return nil, rangeErr }
+	return digest, nil
+}
+
+// ReadKeyDigest
+//	Read a serialized key digest, as written by ExportKeyDigest.
+func ReadKeyDigest(r io.Reader) (*KeyDigest, error) {
+	reader := bufio.NewReader(r)
+
+	header := make([]byte, len(digestMagic) + 1 + OffsetSize + 2)
+	_, readErr := io.ReadFull(reader, header)
+	if readErr != nil { return nil, readErr }
+
+	if ! bytes.Equal(header[:len(digestMagic)], digestMagic) { return nil, errors.New("invalid key digest magic") }
+	if header[len(digestMagic)] != DigestFormatVersion { return nil, errors.New("unsupported key digest format version") }
+
+	versionIdx := len(digestMagic) + 1
+	digest := &KeyDigest{ Version: binary.LittleEndian.Uint64(header[versionIdx:versionIdx + OffsetSize]) }
+
+	totalBuckets := binary.LittleEndian.Uint16(header[versionIdx + OffsetSize:])
+	if totalBuckets != DigestBuckets { return nil, errors.New("key digest bucket count mismatch") }
+
+	sBucket := make([]byte, 2 * OffsetSize)
+	for idx := range digest.Buckets {
+		_, readErr = io.ReadFull(reader, sBucket)
+		if readErr != nil { return nil, readErr }
+
+		digest.Buckets[idx].Count = binary.LittleEndian.Uint64(sBucket[:OffsetSize])
+		digest.Buckets[idx].Hash = binary.LittleEndian.Uint64(sBucket[OffsetSize:])
+	}
+
+	return digest, nil
+}
+
+// Add
+//	Add a key to the digest.
+func (digest *KeyDigest) Add(key []byte) {
+	bucket := &digest.Buckets[digestBucketForKey(key)]
+	bucket.Count++
+	bucket.Hash += digestHashKey(key)
+}
+
+// DivergentPrefixes
+//	Compare two digests and return the leading bytes whose buckets differ.
+//	Only keys starting with the returned prefixes need to be exchanged to reconcile the two key sets.
+func (digest *KeyDigest) DivergentPrefixes(other *KeyDigest) []byte {
+	var prefixes []byte
+	for idx := range digest.Buckets {
+		if digest.Buckets[idx] != other.Buckets[idx] { prefixes = append(prefixes, byte(idx)) }
+	}
+
+	return prefixes
+}
+
+// Serialize
+//	Serialize the digest into its byte representation.
+func (digest *KeyDigest) Serialize() []byte {
+	var sDigest []byte
+	sDigest = append(sDigest, digestMagic...)
+	sDigest = append(sDigest, DigestFormatVersion)
+	sDigest = append(sDigest, serializeUint64(digest.Version)...)
+	sDigest = append(sDigest, serializeUint16(DigestBuckets)...)
+
+	for _, bucket := range digest.Buckets {
+		sDigest = append(sDigest, serializeUint64(bucket.Count)...)
+		sDigest = append(sDigest, serializeUint64(bucket.Hash)...)
+	}
+
+	return sDigest
+}
+
+// digestBucketForKey
+//	The bucket for a key is its leading byte.
+func digestBucketForKey(key []byte) int {
+	if len(key) == 0 { return 0 }
+	return int(key[0])
+}
+
+// digestHashKey
+//	The 64 bit key hash used in digests.
+func digestHashKey(key []byte) uint64 {
+	return uint64(murmur.Murmur32(key, 1)) << 32 | uint64(murmur.Murmur32(key, 2))
+}
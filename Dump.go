@@ -0,0 +1,158 @@
+package mmcmap
+
+import "bufio"
+import "encoding/hex"
+import "encoding/json"
+import "fmt"
+import "io"
+import "strings"
+import "unicode"
+import "unicode/utf8"
+
+
+//============================================= MMCMap Dump
+
+
+// dotLabelEscaper escapes the characters that are special within a DOT record label
+var dotLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `{`, `\{`, `}`, `\}`, `|`, `\|`, `<`, `\<`, `>`, `\>`)
+
+// trieDumper writes the nodes of a trie in a single format
+type trieDumper struct {
+	mmcMap *MMCMap
+	writer *bufio.Writer
+	encoder *json.Encoder
+	opts DumpOpts
+}
+
+
+// Dump
+//	Write the structure of the trie to w, with the offset, version, and bitmap of every node and a preview of the key and value of every leaf,
+//	so the layout of the file can be inspected. DumpJSON writes a DumpNode per line, and DumpDOT a graph that can be rendered with Graphviz.
+//	Nodes are read one at a time in depth first order, so the dump does not grow with the size of the map.
+func (mmcMap *MMCMap) Dump(w io.Writer, opts DumpOpts) error {
+	if opts.Format != DumpJSON && opts.Format != DumpDOT { return ErrUnknownDumpFormat }
+	if opts.PreviewBytes == 0 { opts.PreviewBytes = DefaultDumpPreview }
+
+	mmcMap.RelocateLock.RLock()
+	defer mmcMap.RelocateLock.RUnlock()
+
+	rootOffset, rootErr := mmcMap.dumpRootOffset(opts.Version)
+	if rootErr != nil { return rootErr }
+
+	writer := bufio.NewWriter(w)
+	dumper := &trieDumper{ mmcMap: mmcMap, writer: writer, encoder: json.NewEncoder(writer), opts: opts }
+
+	if opts.Format == DumpDOT { writer.WriteString("digraph mmcmap {\n\tnode [shape=record, fontname=monospace];\n") }
+
+	dumpErr := dumper.dumpRecursive(rootOffset, 0)
+	if dumpErr != nil { return dumpErr }
+
+	if opts.Format == DumpDOT { writer.WriteString("}\n") }
+	return writer.Flush()
+}
+
+// dumpRootOffset
+//	The offset of the root at version, or of the latest root if version is 0. The relocate read lock must be held.
+func (mmcMap *MMCMap) dumpRootOffset(version uint64) (uint64, error) {
+	if version == 0 { return mmcMap.loadRootOffsetForRead() }
+
+	roots, scanErr := mmcMap.scanRoots()
+	if scanErr != nil { return 0, scanErr }
+
+	return rootAtVersion(roots, version)
+}
+
+// dumpRecursive
+//	Write the node at offset and then each of its children, until MaxDepth is reached.
+func (dumper *trieDumper) dumpRecursive(offset uint64, depth int) error {
+	node, readErr := dumper.mmcMap.readNodeCopy(offset, false)
+	if readErr != nil { return readErr }
+
+	var writeErr error
+	if dumper.opts.Format == DumpDOT {
+		writeErr = dumper.writeDOT(node)
+	} else { writeErr = dumper.encoder.Encode(dumper.newDumpNode(node, depth)) }
+
+	if writeErr != nil { return writeErr }
+	if node.IsLeaf || (dumper.opts.MaxDepth > 0 && depth >= dumper.opts.MaxDepth) { return nil }
+
+	for _, child := range node.Children {
+		dumpErr := dumper.dumpRecursive(child.StartOffset, depth + 1)
+		if dumpErr != nil { return dumpErr }
+	}
+
+	return nil
+}
+
+// newDumpNode
+//	The JSON form of the node.
+func (dumper *trieDumper) newDumpNode(node *MMCMapNode, depth int) *DumpNode {
+	dumpNode := &DumpNode{ Offset: node.StartOffset, EndOffset: node.EndOffset, Version: node.Version, Depth: depth, IsLeaf: node.IsLeaf }
+
+	if node.IsLeaf {
+		dumpNode.Key = previewBytes(node.Key, dumper.opts.PreviewBytes)
+		dumpNode.KeyLength = len(node.Key)
+		dumpNode.Value = previewBytes(node.Value, dumper.opts.PreviewBytes)
+		dumpNode.ValueLength = len(node.Value)
+		dumpNode.ExpiresAt = node.ExpiresAt
+		dumpNode.IsOverflow = node.IsOverflow
+
+		return dumpNode
+	}
+
+	dumpNode.Bitmap = fmt.Sprintf("%032b", node.Bitmap)
+	dumpNode.Children = make([]uint64, len(node.Children))
+	for idx, child := range node.Children { dumpNode.Children[idx] = child.StartOffset }
+
+	return dumpNode
+}
+
+// writeDOT
+//	Write the record for the node and an edge to each of its children, labelled with the slot of the child in the bitmap.
+//	Previews are escaped, since keys and values may contain the characters that separate the fields of the record.
+func (dumper *trieDumper) writeDOT(node *MMCMapNode) error {
+	var label string
+	if node.IsLeaf {
+		label = fmt.Sprintf("{leaf @%d-%d|v%d|key %s (%d)|value %s (%d)}",
+			node.StartOffset, node.EndOffset, node.Version,
+			dotLabelEscaper.Replace(previewBytes(node.Key, dumper.opts.PreviewBytes)), len(node.Key),
+			dotLabelEscaper.Replace(previewBytes(node.Value, dumper.opts.PreviewBytes)), len(node.Value),
+		)
+	} else { label = fmt.Sprintf("{internal @%d-%d|v%d|%032b}", node.StartOffset, node.EndOffset, node.Version, node.Bitmap) }
+
+	_, writeErr := fmt.Fprintf(dumper.writer, "\tn%d [label=\"%s\"];\n", node.StartOffset, label)
+	if writeErr != nil { return writeErr }
+
+	if node.IsLeaf { return nil }
+
+	slot := 0
+	for _, child := range node.Children {
+		for ! IsBitSet(node.Bitmap, slot) { slot++ }
+
+		_, writeErr = fmt.Fprintf(dumper.writer, "\tn%d -> n%d [label=\"%d\"];\n", node.StartOffset, child.StartOffset, slot)
+		if writeErr != nil { return writeErr }
+
+		slot++
+	}
+
+	return nil
+}
+
+// previewBytes
+//	The first n bytes of data, as text if every rune is printable and as 0x prefixed hex otherwise, with ... appended if data was truncated.
+//	Returns an empty string if n is negative.
+func previewBytes(data []byte, n int) string {
+	if n < 0 || len(data) == 0 { return "" }
+
+	preview, suffix := data, ""
+	if len(data) > n { preview, suffix = data[:n], "..." }
+
+	isText := utf8.Valid(preview)
+	for _, r := range string(preview) {
+		if ! isText { break }
+		isText = unicode.IsPrint(r)
+	}
+
+	if isText { return string(preview) + suffix }
+	return "0x" + hex.EncodeToString(preview) + suffix
+}
@@ -0,0 +1,85 @@
+package mmcmap
+
+import "runtime"
+import "sync/atomic"
+
+
+//============================================= MMCMap Durability
+
+
+// Flush
+//	Block until every commit published before the call is on disk. See Wait.
+func (mmcMap *MMCMap) Flush() error {
+	version, loadVErr := mmcMap.loadPublishedVersion()
+	if loadVErr != nil { return loadVErr }
+
+	return mmcMap.Wait(version)
+}
+
+// Wait
+//	Block until the commit at version, and every commit before it, is on disk. Commits return once their path is in the memory map and their root
+//	is published, and are flushed by the flush go routine afterwards, so a write is visible to readers before it is durable. Workloads that batch
+//	durability themselves can write with Put, PutVersioned, PutAsync, or DeleteAsync without waiting, and then Wait once for the last version of
+//	the batch. Calls to Wait share a single flush when the flush go routine has not caught up, and return as soon as any flush covers version.
+//	Returns ErrVersionUnavailable if version has not been committed, or the error of a failed flush.
+func (mmcMap *MMCMap) Wait(version uint64) error {
+	handleErr := mmcMap.checkHandleForWrite()
+	if handleErr != nil { return handleErr }
+
+	mmcMap.FlushCond.L.Lock()
+	defer mmcMap.FlushCond.L.Unlock()
+
+	for mmcMap.FlushedVersion < version {
+		if atomic.LoadUint32(&mmcMap.IsDetached) == 1 { return ErrMapDetached }
+		if atomic.LoadUint32(&mmcMap.Opened) == 0 { return ErrMapClosed }
+
+		if mmcMap.IsWaitFlushing {
+			mmcMap.FlushCond.Wait()
+			continue
+		}
+
+		flushed, flushErr := mmcMap.flushForWait()
+		if flushErr != nil { return flushErr }
+		if flushed >= version { return nil }
+
+		// the version may be claimed by a commit that has not published its root yet
+		_, claimed, loadVErr := mmcMap.loadMetaVersion()
+		if loadVErr != nil { return loadVErr }
+		if version > claimed { return ErrVersionUnavailable }
+
+		mmcMap.FlushCond.L.Unlock()
+		runtime.Gosched()
+		mmcMap.FlushCond.L.Lock()
+	}
+
+	return nil
+}
+
+// flushForWait
+//	Flush the files on behalf of the calls to Wait, releasing the flush lock while syncing and waking the other calls once the flush completes.
+//	The flush lock must be held.
+func (mmcMap *MMCMap) flushForWait() (uint64, error) {
+	mmcMap.IsWaitFlushing = true
+	mmcMap.FlushCond.L.Unlock()
+
+	version, flushErr := mmcMap.flushFiles()
+	if flushErr == nil { mmcMap.fireFlush(version) }
+
+	mmcMap.FlushCond.L.Lock()
+	mmcMap.IsWaitFlushing = false
+	mmcMap.FlushCond.Broadcast()
+
+	return version, flushErr
+}
+
+// loadPublishedVersion
+//	The version of the latest published root, read under the resize read lock.
+func (mmcMap *MMCMap) loadPublishedVersion() (uint64, error) {
+	mmcMap.RWResizeLock.RLock()
+	defer mmcMap.RWResizeLock.RUnlock()
+
+	handleErr := mmcMap.checkHandle()
+	if handleErr != nil { return 0, handleErr }
+
+	return mmcMap.publishedVersion(), nil
+}
@@ -0,0 +1,16 @@
+//go:build mips || mips64 || ppc64 || s390x
+
+package mmcmap
+
+import "math/bits"
+
+
+//============================================= MMCMap Byte Order (Big Endian Hosts)
+
+
+// metaWord
+//	Convert between a uint64 in host byte order and the little endian word stored in the memory map. Big endian hosts reverse the bytes, so
+//	words updated atomically in place match the layout written by SerializeMetaData.
+func metaWord(val uint64) uint64 {
+	return bits.ReverseBytes64(val)
+}
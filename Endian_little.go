@@ -0,0 +1,13 @@
+//go:build !(mips || mips64 || ppc64 || s390x)
+
+package mmcmap
+
+
+//============================================= MMCMap Byte Order (Little Endian Hosts)
+
+
+// metaWord
+//	Convert between a uint64 in host byte order and the little endian word stored in the memory map. Little endian hosts store words as is.
+func metaWord(val uint64) uint64 {
+	return val
+}
@@ -0,0 +1,158 @@
+package mmcmap
+
+import "errors"
+import "os"
+import "sync/atomic"
+import "time"
+
+
+//============================================= MMCMap Expiring Keys
+
+
+// PutWithTTL
+//	Put the key-value pair with an expiration ttl from now. The expiration is stored in the leaf, so once it passes, Get, Range, and every other read
+//	treat the key as missing, even before it is physically removed. The key is scheduled on the expiry wheel, and the sweeper go routine deletes it in
+//	a batch commit on the first sweep after it expires. Putting the key again, with or without a ttl, replaces the expiration.
+//	Expired keys are counted by Len until they are swept. The wheel is saved next to the file on Close, so keys that expire while the map is closed
+//	are swept once it is reopened.
+//	The first expiring key upgrades the file format to ExpiryFormatVersion, so older versions of the library refuse to open it.
+func (mmcMap *MMCMap) PutWithTTL(key, value []byte, ttl time.Duration) (bool, error) {
+	if ttl <= 0 { return false, ErrInvalidTTL }
+	if mmcMap.isFollower() { return false, ErrFollowerReadOnly }
+
+	upgradeErr := mmcMap.upgradeToExpiryFormat()
+	if upgradeErr != nil { return false, upgradeErr }
+
+	expiresAt := time.Now().Add(ttl)
+	ok, putErr := mmcMap.commitOps([]*BatchOp{{ Key: key, Value: value, ExpiresAt: expiresAt.UnixNano() }})
+	if putErr != nil { return false, putErr }
+
+	mmcMap.Expiry.Schedule(key, expiresAt)
+	return ok, nil
+}
+
+// isExpired
+//	Determine if a leaf has an expiration that has passed.
+func (node *MMCMapNode) isExpired() bool {
+	return node.ExpiresAt != 0 && node.ExpiresAt <= time.Now().UnixNano()
+}
+
+// upgradeToExpiryFormat
+//	Raise the format version in the header to ExpiryFormatVersion, if it is not already, before the first leaf with an expiration is written.
+//	Files before KeyCountFormatVersion lay out the header differently and return ErrExpiryUnsupported.
+func (mmcMap *MMCMap) upgradeToExpiryFormat() error {
+	if atomic.LoadUint32(&mmcMap.IsExpiryFormat) == 1 { return nil }
+
+	mmcMap.waitForRemap()
+
+	mmcMap.lockResize()
+	defer mmcMap.unlockResize()
+
+	if atomic.LoadUint32(&mmcMap.Opened) == 0 { return ErrMapClosed }
+	if atomic.LoadUint32(&mmcMap.IsDetached) == 1 { return ErrMapDetached }
+	if mmcMap.OwnerPID != os.Getpid() { return ErrForkedHandle }
+
+	if mmcMap.Header.FormatVersion >= ExpiryFormatVersion {
+		atomic.StoreUint32(&mmcMap.IsExpiryFormat, 1)
+		return nil
+	}
+
+	if ! mmcMap.isKeyCountTracked() { return ErrExpiryUnsupported }
+
+	raiseErr := mmcMap.raiseFormatVersion(ExpiryFormatVersion)
+	if raiseErr != nil { return raiseErr }
+
+	atomic.StoreUint32(&mmcMap.IsExpiryFormat, 1)
+
+	return nil
+}
+
+// handleSweep
+//	The sweeper go routine. Removes expired keys on every ExpirySweepInterval until stop is closed.
+func (mmcMap *MMCMap) handleSweep(stop chan struct{}) {
+	interval := mmcMap.Opts.ExpirySweepInterval
+	if interval <= 0 { interval = DefaultExpirySweepInterval }
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				sweepErr := mmcMap.sweepExpired(time.Now())
+				if sweepErr != nil {
+					atomic.AddUint64(&mmcMap.SweepErrors, 1)
+					mmcMap.log(LogWarn, "sweeping expired keys failed", "err", sweepErr)
+				}
+		}
+	}
+}
+
+// sweepExpired
+//	Advance the expiry wheel to now and delete the keys it returns, ExpirySweepBatchSize keys per version.
+//	Each key is only deleted if its leaf in the root being committed against still has an expiration that has passed, so keys put again since they
+//	were scheduled are left alone. If a batch fails to commit, its keys are scheduled again so the next sweep retries them.
+func (mmcMap *MMCMap) sweepExpired(now time.Time) error {
+	expired := mmcMap.Expiry.Advance(now)
+
+	for len(expired) > 0 {
+		batchSize := len(expired)
+		if batchSize > ExpirySweepBatchSize { batchSize = ExpirySweepBatchSize }
+
+		batch := expired[:batchSize]
+		expired = expired[batchSize:]
+
+		var removed int
+		_, commitErr := mmcMap.commitWith(deleteExpired(mmcMap, batch, &removed))
+		if commitErr != nil {
+			for _, key := range append(batch, expired...) { mmcMap.Expiry.Schedule(key, now) }
+			return commitErr
+		}
+
+		atomic.AddUint64(&mmcMap.ExpiredKeys, uint64(removed))
+	}
+
+	return nil
+}
+
+// deleteExpired
+//	A commit precondition that deletes each key whose leaf has expired in the root the path copy is made from, aborting the commit if none have.
+//	The number of keys deleted by the latest attempt is stored in removed.
+func deleteExpired(mmcMap *MMCMap, keys [][]byte, removed *int) func(root *MMCMapNode) ([]*BatchOp, error) {
+	return func(root *MMCMapNode) ([]*BatchOp, error) {
+		var ops []*BatchOp
+		for _, key := range keys {
+			leaf, getErr := mmcMap.lookupLeaf(root.StartOffset, key)
+			if getErr != nil { return nil, getErr }
+
+			if leaf != nil && leaf.isExpired() { ops = append(ops, &BatchOp{ Key: key, IsDelete: true }) }
+		}
+
+		*removed = len(ops)
+		if len(ops) == 0 { return nil, errCommitAborted }
+		return ops, nil
+	}
+}
+
+// loadExpiry
+//	Load the expiry wheel saved next to the file, or start an empty wheel if none was saved.
+func (mmcMap *MMCMap) loadExpiry() error {
+	wheel, loadErr := LoadExpiryWheel(mmcMap.ExpiryWheelPath())
+	if loadErr != nil { return loadErr }
+
+	mmcMap.Expiry = wheel
+	return nil
+}
+
+// saveExpiry
+//	Save the expiry wheel next to the file if any keys are scheduled, otherwise remove any wheel saved previously.
+func (mmcMap *MMCMap) saveExpiry() error {
+	if mmcMap.Expiry.Len() > 0 { return mmcMap.Expiry.Save(mmcMap.ExpiryWheelPath()) }
+
+	removeErr := os.Remove(mmcMap.ExpiryWheelPath())
+	if removeErr != nil && ! errors.Is(removeErr, os.ErrNotExist) { return removeErr }
+
+	return nil
+}
@@ -0,0 +1,309 @@
+package mmcmap
+
+import "bufio"
+import "bytes"
+import "errors"
+import "io"
+import "os"
+import "time"
+
+
+//============================================= MMCMap Expiry Wheel
+
+
+// wheelMagic identifies a serialized expiry wheel
+var wheelMagic = []byte("MMCW")
+
+// wheelFormatVersion is the version of the serialized expiry wheel format
+const wheelFormatVersion = 1
+
+
+// NewExpiryWheel
+//	Creates an empty expiry wheel with the given tick resolution, starting at start.
+func NewExpiryWheel(tick time.Duration, start time.Time) *ExpiryWheel {
+	if tick <= 0 { tick = DefaultWheelTick }
+
+	wheel := &ExpiryWheel{ Tick: tick, Index: make(map[string]uint64) }
+	wheel.Current = wheel.tickOf(start)
+
+	return wheel
+}
+
+// ExpiryWheelPath
+//	The path of the expiry wheel persisted alongside the mmcmap.
+func (mmcMap *MMCMap) ExpiryWheelPath() string {
+	return mmcMap.Opts.Filepath + ".wheel"
+}
+
+// Schedule
+//	Schedule key to expire at expireAt, replacing any existing schedule for the key.
+//	Keys scheduled in the past expire on the next call to Advance.
+func (wheel *ExpiryWheel) Schedule(key []byte, expireAt time.Time) {
+	wheel.Lock.Lock()
+	defer wheel.Lock.Unlock()
+
+	expire := wheel.expireTickOf(expireAt)
+	if expire <= wheel.Current { expire = wheel.Current + 1 }
+
+	wheel.Index[string(key)] = expire
+	wheel.insert(WheelEntry{ Key: string(key), Expire: expire })
+}
+
+// Cancel
+//	Remove the schedule for key. The entry is left in its slot and discarded when the slot is reached.
+func (wheel *ExpiryWheel) Cancel(key []byte) {
+	wheel.Lock.Lock()
+	defer wheel.Lock.Unlock()
+
+	delete(wheel.Index, string(key))
+}
+
+// Len
+//	The number of keys currently scheduled.
+func (wheel *ExpiryWheel) Len() int {
+	wheel.Lock.Lock()
+	defer wheel.Lock.Unlock()
+
+	return len(wheel.Index)
+}
+
+// Advance
+//	Move the wheel forward to now and return every key that expired, removing them from the wheel.
+//	Each tick visits a single slot on the bottom level. When the bottom level completes a rotation, the next slot of the level above is cascaded down,
+//	so an entry is touched once per level at most and the cost of a sweep is proportional to the number of expiring keys rather than the number of
+//	scheduled keys. If the wheel falls behind by more than a full rotation of the top level, the remaining entries are redistributed in a single pass.
+func (wheel *ExpiryWheel) Advance(now time.Time) [][]byte {
+	wheel.Lock.Lock()
+	defer wheel.Lock.Unlock()
+
+	target := wheel.tickOf(now)
+	if target <= wheel.Current { return nil }
+
+	var expired [][]byte
+	if target - wheel.Current >= wheelSpan(WheelLevels) {
+		entries := wheel.drain()
+		wheel.Current = target
+
+		for _, entry := range entries {
+			if entry.Expire <= target {
+				expired = append(expired, []byte(entry.Key))
+				delete(wheel.Index, entry.Key)
+			} else { wheel.insert(entry) }
+		}
+
+		return expired
+	}
+
+	for wheel.Current < target {
+		wheel.Current++
+		wheel.cascade()
+
+		slot := wheel.Current & (WheelSlots - 1)
+		entries := wheel.Levels[0][slot]
+		wheel.Levels[0][slot] = nil
+
+		for _, entry := range entries {
+			if ! wheel.isLive(entry) { continue }
+
+			if entry.Expire <= wheel.Current {
+				expired = append(expired, []byte(entry.Key))
+				delete(wheel.Index, entry.Key)
+			} else { wheel.insert(entry) }
+		}
+	}
+
+	return expired
+}
+
+// Save
+//	Durably persist the wheel to path. The wheel is written to a temporary file and renamed over path, so a crash leaves either the previous or the
+//	new wheel. Only live entries are written.
+//	The serialized wheel is the 4 byte magic "MMCW", a 1 byte format version, the 8 byte tick in nanoseconds, the 8 byte current tick, an 8 byte
+//	entry count, and then for each entry a 2 byte key length, the key, and the 8 byte expiration tick.
+func (wheel *ExpiryWheel) Save(path string) error {
+	wheel.Lock.Lock()
+	serialized := wheel.serialize()
+	wheel.Lock.Unlock()
+
+	tmpPath := path + ".tmp"
+	file, openErr := os.OpenFile(tmpPath, os.O_RDWR | os.O_CREATE | os.O_TRUNC, 0600)
+	if openErr != nil { return openErr }
+
+	_, writeErr := file.Write(serialized)
+	if writeErr != nil {
+		file.Close()
+		return writeErr
+	}
+
+	syncErr := file.Sync()
+	closeErr := file.Close()
+	if syncErr != nil { return syncErr }
+	if closeErr != nil { return closeErr }
+
+	return os.Rename(tmpPath, path)
+}
+
+// LoadExpiryWheel
+//	Load a wheel persisted by Save. If no wheel exists at path, an empty wheel with the default tick starting now is returned.
+func LoadExpiryWheel(path string) (*ExpiryWheel, error) {
+	file, openErr := os.Open(path)
+	if errors.Is(openErr, os.ErrNotExist) { return NewExpiryWheel(DefaultWheelTick, time.Now()), nil }
+	if openErr != nil { return nil, openErr }
+	defer file.Close()
+
+	return ReadExpiryWheel(file)
+}
+
+// ReadExpiryWheel
+//	Read a serialized wheel, as written by Save.
+func ReadExpiryWheel(r io.Reader) (*ExpiryWheel, error) {
+	reader := bufio.NewReader(r)
+
+	header := make([]byte, len(wheelMagic) + 1 + 3 * OffsetSize)
+	_, readErr := io.ReadFull(reader, header)
+	if readErr != nil { return nil, readErr }
+
+	if ! bytes.Equal(header[:len(wheelMagic)], wheelMagic) { return nil, errors.New("invalid expiry wheel magic") }
+	if header[len(wheelMagic)] != wheelFormatVersion { return nil, errors.New("unsupported expiry wheel format version") }
+
+	offset := len(wheelMagic) + 1
+	tick, _ := deserializeUint64(header[offset:offset + OffsetSize])
+	current, _ := deserializeUint64(header[offset + OffsetSize:offset + 2 * OffsetSize])
+	count, _ := deserializeUint64(header[offset + 2 * OffsetSize:offset + 3 * OffsetSize])
+
+	wheel := &ExpiryWheel{ Tick: time.Duration(tick), Current: current, Index: make(map[string]uint64) }
+	if wheel.Tick <= 0 { return nil, errors.New("invalid expiry wheel tick") }
+
+	for idx := uint64(0); idx < count; idx++ {
+		lenBytes := make([]byte, 2)
+		_, readErr = io.ReadFull(reader, lenBytes)
+		if readErr != nil { return nil, readErr }
+
+		keyLen, _ := deserializeUint16(lenBytes)
+		entryBytes := make([]byte, int(keyLen) + OffsetSize)
+		_, readErr = io.ReadFull(reader, entryBytes)
+		if readErr != nil { return nil, readErr }
+
+		expire, _ := deserializeUint64(entryBytes[keyLen:])
+		if expire <= wheel.Current { expire = wheel.Current + 1 }
+		entry := WheelEntry{ Key: string(entryBytes[:keyLen]), Expire: expire }
+
+		wheel.Index[entry.Key] = entry.Expire
+		wheel.insert(entry)
+	}
+
+	return wheel, nil
+}
+
+// insert
+//	Place an entry in the lowest level whose span covers the time until it expires.
+//	Entries expiring on the current tick go in the current bottom slot, which is only valid while that slot is being processed by Advance.
+func (wheel *ExpiryWheel) insert(entry WheelEntry) {
+	delta := uint64(0)
+	if entry.Expire > wheel.Current { delta = entry.Expire - wheel.Current }
+
+	for level := 0; level < WheelLevels; level++ {
+		if delta < wheelSpan(level + 1) {
+			slot := (entry.Expire >> (WheelSlotBits * level)) & (WheelSlots - 1)
+			wheel.Levels[level][slot] = append(wheel.Levels[level][slot], entry)
+			return
+		}
+	}
+
+	wheel.Overflow = append(wheel.Overflow, entry)
+}
+
+// cascade
+//	When the current tick completes a rotation of a level, redistribute the next slot of the level above into the lower levels.
+func (wheel *ExpiryWheel) cascade() {
+	for level := 1; level <= WheelLevels; level++ {
+		if wheel.Current & (wheelSpan(level) - 1) != 0 { return }
+
+		var entries []WheelEntry
+		if level == WheelLevels {
+			entries = wheel.Overflow
+			wheel.Overflow = nil
+		} else {
+			slot := (wheel.Current >> (WheelSlotBits * level)) & (WheelSlots - 1)
+			entries = wheel.Levels[level][slot]
+			wheel.Levels[level][slot] = nil
+		}
+
+		for _, entry := range entries {
+			if wheel.isLive(entry) { wheel.insert(entry) }
+		}
+	}
+}
+
+// drain
+//	Remove and return every live entry in the wheel.
+func (wheel *ExpiryWheel) drain() []WheelEntry {
+	var entries []WheelEntry
+	collect := func(slot []WheelEntry) {
+		for _, entry := range slot {
+			if wheel.isLive(entry) { entries = append(entries, entry) }
+		}
+	}
+
+	for level := range wheel.Levels {
+		for slot := range wheel.Levels[level] {
+			collect(wheel.Levels[level][slot])
+			wheel.Levels[level][slot] = nil
+		}
+	}
+
+	collect(wheel.Overflow)
+	wheel.Overflow = nil
+
+	return entries
+}
+
+// isLive
+//	An entry is live if the index still schedules its key for the same tick.
+func (wheel *ExpiryWheel) isLive(entry WheelEntry) bool {
+	expire, ok := wheel.Index[entry.Key]
+	return ok && expire == entry.Expire
+}
+
+// serialize
+//	Serialize the live entries of the wheel. The lock must be held.
+func (wheel *ExpiryWheel) serialize() []byte {
+	var buf bytes.Buffer
+	buf.Write(wheelMagic)
+	buf.WriteByte(wheelFormatVersion)
+	buf.Write(serializeUint64(uint64(wheel.Tick)))
+	buf.Write(serializeUint64(wheel.Current))
+	buf.Write(serializeUint64(uint64(len(wheel.Index))))
+
+	for key, expire := range wheel.Index {
+		buf.Write(serializeUint16(uint16(len(key))))
+		buf.WriteString(key)
+		buf.Write(serializeUint64(expire))
+	}
+
+	return buf.Bytes()
+}
+
+// tickOf
+//	The number of whole ticks elapsed at t.
+func (wheel *ExpiryWheel) tickOf(t time.Time) uint64 {
+	nanos := t.UnixNano()
+	if nanos <= 0 { return 0 }
+
+	return uint64(nanos / int64(wheel.Tick))
+}
+
+// expireTickOf
+//	The first tick at or after t, so keys never expire early.
+func (wheel *ExpiryWheel) expireTickOf(t time.Time) uint64 {
+	nanos := t.UnixNano()
+	if nanos <= 0 { return 0 }
+
+	return uint64((nanos + int64(wheel.Tick) - 1) / int64(wheel.Tick))
+}
+
+// wheelSpan
+//	The number of ticks covered by the given number of levels.
+func wheelSpan(levels int) uint64 {
+	return uint64(1) << (WheelSlotBits * levels)
+}
@@ -0,0 +1,182 @@
+package mmcmap
+
+import "sort"
+import "sync/atomic"
+
+import "github.com/sirgallo/mmcmap/common/mmap"
+
+
+//============================================= MMCMap Free List
+
+
+// Reclaim
+//	Rebuild the free list from the regions of the memory map that are not reachable from the latest root, so later commits can write their paths
+//	into them instead of appending. Only files created with the FreeListAllocator keep a free list.
+//	The largest unreachable regions are kept, up to MaxFreeRegions. Like Compact, prior versions are no longer readable once their regions are reused,
+//	so transactions begun before the reclaim can not read from or commit against their snapshot after it. Like Compact, pinned traversals are waited
+//	for first, and commits and reads are then blocked while the free list is rebuilt.
+func (mmcMap *MMCMap) Reclaim() (*ReclaimReport, error) {
+	mmcMap.lockRelocate()
+	defer mmcMap.unlockRelocate()
+
+	mmcMap.lockResize()
+	defer mmcMap.unlockResize()
+
+	handleErr := mmcMap.checkHandle()
+	if handleErr != nil { return nil, handleErr }
+	if ! mmcMap.isFreeListEnabled() { return nil, ErrAllocatorUnsupported }
+
+	meta, readMetaErr := mmcMap.ReadMetaFromMemMap()
+	if readMetaErr != nil { return nil, readMetaErr }
+
+	var live []FreeRegion
+	liveErr := mmcMap.collectLiveRegions(meta.RootOffset, &live)
+	if liveErr != nil { return nil, liveErr }
+
+	sort.Slice(live, func(i, j int) bool { return live[i].Offset < live[j].Offset })
+
+	var unreachable []FreeRegion
+	addUnreachable := func(start, end uint64) {
+		if end > start { unreachable = append(unreachable, FreeRegion{ Offset: start, Size: end - start }) }
+	}
+
+	nextOffset := mmcMap.HeaderSize
+	for _, region := range live {
+		addUnreachable(nextOffset, region.Offset)
+		if region.Offset + region.Size > nextOffset { nextOffset = region.Offset + region.Size }
+	}
+
+	addUnreachable(nextOffset, meta.EndMmapOffset)
+	sort.Slice(unreachable, func(i, j int) bool { return unreachable[i].Size > unreachable[j].Size })
+
+	report := &ReclaimReport{ Version: meta.Version }
+	var regions []FreeRegion
+
+	for _, region := range unreachable {
+		if region.Size < MinFreeRegionSize || len(regions) == MaxFreeRegions {
+			report.DroppedBytes += region.Size
+			continue
+		}
+
+		regions = append(regions, region)
+		report.FreeBytes += region.Size
+	}
+
+	mmcMap.writeFreeList(regions)
+	atomic.AddUint64(&mmcMap.RelocateGeneration, 1)
+	report.Regions = len(regions)
+
+	flushErr := mmcMap.flushRegionToDisk(FreeListIdx, mmcMap.HeaderSize)
+	if flushErr != nil { return nil, flushErr }
+
+	return report, nil
+}
+
+// FreeRegions
+//	The regions currently in the free list. Empty for files that do not use the FreeListAllocator.
+func (mmcMap *MMCMap) FreeRegions() ([]FreeRegion, error) {
+	mmcMap.waitForRemap()
+
+	mmcMap.RWResizeLock.RLock()
+	defer mmcMap.RWResizeLock.RUnlock()
+
+	handleErr := mmcMap.checkHandle()
+	if handleErr != nil { return nil, handleErr }
+	if ! mmcMap.isFreeListEnabled() { return nil, nil }
+
+	return mmcMap.readFreeList(), nil
+}
+
+// collectLiveRegions
+//	Record the region of every node reachable from the node at offset, along with the overflow extent of every leaf.
+func (mmcMap *MMCMap) collectLiveRegions(offset uint64, live *[]FreeRegion) error {
+	node, readNodeErr := mmcMap.ReadNodeFromMemMap(offset)
+	if readNodeErr != nil { return readNodeErr }
+
+	*live = append(*live, FreeRegion{ Offset: node.StartOffset, Size: node.EndOffset - node.StartOffset + 1 })
+	if node.IsOverflow { *live = append(*live, FreeRegion{ Offset: node.OverflowOffset, Size: node.extentSize() }) }
+
+	for _, child := range node.Children {
+		collectErr := mmcMap.collectLiveRegions(child.StartOffset, live)
+		if collectErr != nil { return collectErr }
+	}
+
+	return nil
+}
+
+// findFreeRegion
+//	Find the first region in the free list that can fit size bytes. Returns the index of the region, or -1 if none fit.
+func (mmcMap *MMCMap) findFreeRegion(size uint64) (int, uint64) {
+	for idx, region := range mmcMap.readFreeList() {
+		if region.Size >= size { return idx, region.Offset }
+	}
+
+	return -1, 0
+}
+
+// consumeFreeRegion
+//	Remove size bytes from the start of the region at idx in the free list. Regions left smaller than MinFreeRegionSize are dropped.
+//	Only called by the writer that won the version compare and swap, before the new root is published.
+func (mmcMap *MMCMap) consumeFreeRegion(idx int, size uint64) {
+	regions := mmcMap.readFreeList()
+	region := regions[idx]
+
+	if region.Size - size < MinFreeRegionSize {
+		regions = append(regions[:idx], regions[idx + 1:]...)
+	} else { regions[idx] = FreeRegion{ Offset: region.Offset + size, Size: region.Size - size } }
+
+	mmcMap.writeFreeList(regions)
+}
+
+// readFreeList
+//	Deserialize the free list from the memory map.
+func (mmcMap *MMCMap) readFreeList() []FreeRegion {
+	mMap := mmcMap.Data.Load().(mmap.MMap)
+
+	count, _ := deserializeUint64(mMap[FreeListIdx:FreeListRegionsIdx])
+	if count > MaxFreeRegions { count = MaxFreeRegions }
+
+	regions := make([]FreeRegion, count)
+	for idx := range regions {
+		regionIdx := FreeListRegionsIdx + idx * FreeRegionSize
+		offset, _ := deserializeUint64(mMap[regionIdx:regionIdx + OffsetSize])
+		size, _ := deserializeUint64(mMap[regionIdx + OffsetSize:regionIdx + FreeRegionSize])
+
+		regions[idx] = FreeRegion{ Offset: offset, Size: size }
+	}
+
+	return regions
+}
+
+// writeFreeList
+//	Serialize the free list to the memory map. The regions are written before the count, so a reader never sees a count covering unwritten regions.
+func (mmcMap *MMCMap) writeFreeList(regions []FreeRegion) {
+	mMap := mmcMap.Data.Load().(mmap.MMap)
+
+	for idx, region := range regions {
+		regionIdx := FreeListRegionsIdx + idx * FreeRegionSize
+		copy(mMap[regionIdx:regionIdx + OffsetSize], serializeUint64(region.Offset))
+		copy(mMap[regionIdx + OffsetSize:regionIdx + FreeRegionSize], serializeUint64(region.Size))
+	}
+
+	copy(mMap[FreeListIdx:FreeListRegionsIdx], serializeUint64(uint64(len(regions))))
+}
+
+// pathSize
+//	The number of bytes a path copy serializes to. Only nodes at the version of the path are serialized, mirroring serializeRecursive.
+func pathSize(node *MMCMapNode, version uint64) uint64 {
+	size := node.serializedSize()
+	if node.IsLeaf { return size }
+
+	for _, child := range node.Children {
+		if child.Version == version { size += pathSize(child, version) }
+	}
+
+	return size
+}
+
+// isFreeListEnabled
+//	Only files created with the FreeListAllocator reserve space for the free list.
+func (mmcMap *MMCMap) isFreeListEnabled() bool {
+	return mmcMap.Header.AllocatorID == AllocFreeList
+}
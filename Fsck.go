@@ -0,0 +1,141 @@
+package mmcmap
+
+import "encoding/binary"
+import "os"
+import "time"
+
+import "github.com/sirgallo/mmcmap/common/mmap"
+
+
+//============================================= MMCMap Fsck
+
+
+// FsckFile
+//	Check every version of the map at opts.Filepath without opening it for writing. The file is opened and mapped read only, so it is never modified,
+//	and files too damaged for Open to accept can still be checked. Every commit is found by scanning the node headers, as in Repair, and the trie of
+//	each is validated with the same checks as Verify. Versions share every subtree not written since, so each intact subtree is only checked once.
+//	With FsckOpts.RecoverTo, a compacted copy of the newest intact version is written to a new file, which can be opened in place of the original.
+//	An existing file at RecoverTo is never overwritten. The map should not be written to while it is checked.
+func FsckFile(opts MMCMapOpts, fsckOpts FsckOpts) (*FsckReport, error) {
+	start := time.Now()
+
+	mmcMap, openFileErr := openFile(opts, os.O_RDONLY)
+	if openFileErr != nil { return nil, openFileErr }
+	defer mmcMap.File.Close()
+
+	mMap, mmapErr := mmap.Map(mmcMap.File, mmap.RDONLY, 0)
+	if mmapErr != nil { return nil, mmapErr }
+	defer mMap.Unmap()
+
+	mmcMap.Data.Store(mMap)
+
+	loadHeaderErr := mmcMap.loadHeader()
+	if loadHeaderErr != nil { return nil, loadHeaderErr }
+	if mmcMap.isFreeListEnabled() { return nil, ErrAllocatorUnsupported }
+	if mmcMap.isSegmented() { return nil, ErrSegmentedUnsupported }
+
+	report, fsckErr := mmcMap.fsck(mMap)
+	if fsckErr != nil { return nil, fsckErr }
+
+	if fsckOpts.RecoverTo != "" {
+		recoverErr := mmcMap.recoverTo(fsckOpts.RecoverTo, report)
+		if recoverErr != nil { return nil, recoverErr }
+
+		report.RecoveredPath = fsckOpts.RecoverTo
+	}
+
+	report.Duration = time.Since(start)
+	return report, nil
+}
+
+// fsck
+//	Check the header, the metadata, and the trie of every commit found in the memory map.
+func (mmcMap *MMCMap) fsck(mMap mmap.MMap) (*FsckReport, error) {
+	report := &FsckReport{}
+	mmcMap.checkHeader(&report.HeaderFindings)
+
+	meta, readMetaErr := mmcMap.ReadMetaFromMemMap()
+	if readMetaErr != nil {
+		addFinding(&report.HeaderFindings, "metadata is unreadable: %s", readMetaErr.Error())
+		meta = &MMCMapMetaData{}
+	}
+
+	report.MetaVersion = meta.Version
+	report.MetaRootOffset = meta.RootOffset
+
+	commits, scanEnd := mmcMap.scanCommits(mMap)
+	if scanEnd > mmcMap.HeaderSize { report.ScannedBytes = scanEnd - mmcMap.HeaderSize }
+	if meta.EndMmapOffset > scanEnd { report.UnreadableBytes = meta.EndMmapOffset - scanEnd }
+
+	verified := make(map[uint64]verifiedSubtree)
+	var latestValid *FsckVersion
+
+	for _, commit := range commits {
+		commitMeta := &MMCMapMetaData{ Version: commit.version, RootOffset: commit.offset, EndMmapOffset: commit.end }
+		version := FsckVersion{ Version: commit.version, RootOffset: commit.offset, EndOffset: commit.end }
+
+		mmcMap.checkRoot(&version.Findings, commitMeta, mmcMap.ReadNodeFromMemMap)
+		if len(version.Findings) == 0 {
+			checker := &nodeChecker{ mmcMap: mmcMap, meta: commitMeta, read: mmcMap.ReadNodeFromMemMap, verified: verified }
+			checker.checkNode(commitMeta.RootOffset, commitMeta.Version, 0, -1)
+
+			version.Findings = checker.findings
+			version.KeysVisited = checker.keysVisited
+			version.ReachableBytes = checker.bytesVisited
+			report.NodesVisited += checker.nodesVisited
+		}
+
+		version.IsValid = len(version.Findings) == 0
+		if version.IsValid {
+			report.ValidVersions++
+		} else { report.CorruptVersions++ }
+
+		report.Versions = append(report.Versions, version)
+		if version.IsValid { latestValid = &report.Versions[len(report.Versions) - 1] }
+	}
+
+	if latestValid != nil {
+		report.LatestValidVersion = latestValid.Version
+		report.ReachableBytes = latestValid.ReachableBytes
+	}
+
+	report.IsClean = len(report.HeaderFindings) == 0 && report.CorruptVersions == 0 && report.UnreadableBytes == 0 &&
+		latestValid != nil && latestValid.Version == meta.Version && latestValid.RootOffset == meta.RootOffset
+
+	return report, nil
+}
+
+// recoverTo
+//	Write a compacted copy of the newest intact version in the report to a new file at path, with the key count rebuilt from its trie.
+//	The copy is written next to path and renamed into place once synced.
+func (mmcMap *MMCMap) recoverTo(path string, report *FsckReport) error {
+	_, statErr := os.Lstat(path)
+	if statErr == nil { return &os.PathError{ Op: "recover", Path: path, Err: os.ErrExist } }
+	if ! os.IsNotExist(statErr) { return statErr }
+
+	var latestValid *FsckVersion
+	for idx := range report.Versions {
+		if report.Versions[idx].IsValid { latestValid = &report.Versions[idx] }
+	}
+
+	if latestValid == nil { return ErrNoIntactRoot }
+
+	meta := &MMCMapMetaData{ Version: latestValid.Version, RootOffset: latestValid.RootOffset, EndMmapOffset: latestValid.EndOffset }
+
+	recoverPath := path + ".recover"
+	patchKeyCount := func(recovered mmap.MMap) {
+		if mmcMap.isKeyCountTracked() { binary.LittleEndian.PutUint64(recovered[HeaderKeyCountIdx:HeaderKeyCountIdx + OffsetSize], latestValid.KeysVisited) }
+	}
+
+	var nodesCopied uint64
+	_, _, writeErr := mmcMap.writeCompactedImage(recoverPath, meta, meta.Version, &nodesCopied, patchKeyCount)
+	if writeErr != nil { return writeErr }
+
+	renameErr := os.Rename(recoverPath, path)
+	if renameErr != nil {
+		os.Remove(recoverPath)
+		return renameErr
+	}
+
+	return nil
+}
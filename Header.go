@@ -0,0 +1,262 @@
+package mmcmap
+
+import "crypto/rand"
+import "errors"
+import "fmt"
+
+import "github.com/sirgallo/mmcmap/common/mmap"
+
+
+//============================================= MMCMap Header
+
+
+// SerializeHeader
+//	Serialize the header, which is written at bytes 24-63 of the memory map, directly after the metadata.
+func (header *MMCMapHeader) SerializeHeader() []byte {
+	sHeader := make([]byte, InitRootOffset - HeaderIdx)
+	copy(sHeader[HeaderMagicIdx - HeaderIdx:], serializeUint32(HeaderMagic))
+	copy(sHeader[HeaderFormatVersionIdx - HeaderIdx:], serializeUint16(header.FormatVersion))
+	sHeader[HeaderAllocatorIdx - HeaderIdx] = byte(header.AllocatorID)
+	sHeader[HeaderNodeEncodingIdx - HeaderIdx] = byte(header.NodeEncoding)
+	sHeader[HeaderHashBitsIdx - HeaderIdx] = header.HashBits
+	sHeader[HeaderKeyModeIdx - HeaderIdx] = byte(header.KeyMode)
+	copy(sHeader[HeaderHashSeedIdx - HeaderIdx:], serializeUint64(header.HashSeed))
+	copy(sHeader[HeaderSegmentSizeIdx - HeaderIdx:], serializeUint64(header.SegmentSize))
+	sHeader[HeaderWriteShardsIdx - HeaderIdx] = header.WriteShards
+
+	return sHeader
+}
+
+// initHeader
+//	Write the header for a new file, using the allocator, node encoding, hash bits, key mode, segment size, and write shards from the options, and a
+//	new random hash seed unless DeterministicHash is set.
+//	A file that is rewritten by a truncating Clear keeps the node encoding, hash bits, hash seed, key mode, segment size, and write shards it was
+//	created with.
+//	The header is flushed by the caller, along with the root and metadata written after it.
+func (mmcMap *MMCMap) initHeader() error {
+	allocID := AllocAppend
+	if mmcMap.Opts.Allocator != nil { allocID = mmcMap.Opts.Allocator.ID() }
+
+	encoding := mmcMap.Opts.NodeEncoding
+	if mmcMap.Header.FormatVersion >= CompactNodeFormatVersion { encoding = mmcMap.Header.NodeEncoding }
+
+	hashBits := mmcMap.Opts.HashBits
+	if hashBits == 0 { hashBits = HashBits32 }
+	if mmcMap.Header.FormatVersion >= HashBitsFormatVersion { hashBits = mmcMap.Header.HashBits }
+
+	hashSeed := mmcMap.Header.HashSeed
+	if mmcMap.Header.FormatVersion < HashSeedFormatVersion && ! mmcMap.Opts.DeterministicHash {
+		var seedErr error
+		hashSeed, seedErr = newHashSeed()
+		if seedErr != nil { return seedErr }
+	}
+
+	keyMode := mmcMap.Opts.KeyMode
+	if mmcMap.Header.FormatVersion >= KeyModeFormatVersion { keyMode = mmcMap.Header.KeyMode }
+
+	segmentSize := mmcMap.Opts.SegmentSize
+	if mmcMap.Header.FormatVersion >= SegmentFormatVersion { segmentSize = mmcMap.Header.SegmentSize }
+
+	writeShards := uint8(mmcMap.Opts.WriteShards)
+	if mmcMap.Header.FormatVersion >= ShardFormatVersion { writeShards = mmcMap.Header.WriteShards }
+
+	mmcMap.Header = MMCMapHeader{
+		FormatVersion: HeaderFormatVersion,
+		AllocatorID: allocID,
+		NodeEncoding: encoding,
+		HashBits: hashBits,
+		HashSeed: hashSeed,
+		KeyMode: keyMode,
+		SegmentSize: segmentSize,
+		WriteShards: writeShards,
+	}
+
+	mmcMap.HeaderSize = mmcMap.Header.dataOffset()
+
+	mMap := mmcMap.Data.Load().(mmap.MMap)
+	copy(mMap[HeaderIdx:InitRootOffset], mmcMap.Header.SerializeHeader())
+
+	allocErr := mmcMap.resolveAllocator()
+	if allocErr != nil { return allocErr }
+
+	hashBitsErr := mmcMap.resolveHashBits()
+	if hashBitsErr != nil { return hashBitsErr }
+
+	keyModeErr := mmcMap.resolveKeyMode()
+	if keyModeErr != nil { return keyModeErr }
+
+	segmentsErr := mmcMap.resolveSegments()
+	if segmentsErr != nil { return segmentsErr }
+
+	shardsErr := mmcMap.resolveWriteShards()
+	if shardsErr != nil { return shardsErr }
+
+	return mmcMap.resolveNodeEncoding()
+}
+
+// loadHeader
+//	Read the header of an existing file. If the header magic is missing, the file predates the header, so the initial root is at
+//	LegacyInitRootOffset and the append allocator is used. Files at a newer format version than HeaderFormatVersion return
+//	ErrUnsupportedFormatVersion, since they may hold nodes this version of the library can not read.
+func (mmcMap *MMCMap) loadHeader() error {
+	mMap := mmcMap.Data.Load().(mmap.MMap)
+	if len(mMap) < InitRootOffset { return errors.New("file too small to contain mmcmap header") }
+
+	magic, _ := deserializeUint32(mMap[HeaderMagicIdx:HeaderFormatVersionIdx])
+	if magic != HeaderMagic {
+		mmcMap.Header = MMCMapHeader{ AllocatorID: AllocAppend, HashBits: HashBits32 }
+		mmcMap.HeaderSize = LegacyInitRootOffset
+		if mmcMap.Opts.NodeEncoding == NodeEncodingCompact { return ErrNodeEncodingMismatch }
+
+		hashBitsErr := mmcMap.resolveHashBits()
+		if hashBitsErr != nil { return hashBitsErr }
+
+		keyModeErr := mmcMap.resolveKeyMode()
+		if keyModeErr != nil { return keyModeErr }
+
+		segmentsErr := mmcMap.resolveSegments()
+		if segmentsErr != nil { return segmentsErr }
+
+		shardsErr := mmcMap.resolveWriteShards()
+		if shardsErr != nil { return shardsErr }
+
+		return mmcMap.resolveAllocator()
+	}
+
+	formatVersion, _ := deserializeUint16(mMap[HeaderFormatVersionIdx:HeaderAllocatorIdx])
+	if formatVersion > HeaderFormatVersion {
+		return fmt.Errorf("%w: file is at format version %d, the newest supported is %d", ErrUnsupportedFormatVersion, formatVersion, HeaderFormatVersion)
+	}
+
+	mmcMap.Header = MMCMapHeader{ FormatVersion: formatVersion, AllocatorID: AllocatorID(mMap[HeaderAllocatorIdx]), HashBits: HashBits32 }
+	if formatVersion >= CompactNodeFormatVersion { mmcMap.Header.NodeEncoding = NodeEncoding(mMap[HeaderNodeEncodingIdx]) }
+	// files migrated from an earlier format version have the reserved byte zeroed, and use 32 bit hashes
+	if formatVersion >= HashBitsFormatVersion && mMap[HeaderHashBitsIdx] != 0 { mmcMap.Header.HashBits = mMap[HeaderHashBitsIdx] }
+	// the hash seed is in bytes that are reserved, and zeroed, before HashSeedFormatVersion
+	mmcMap.Header.HashSeed, _ = deserializeUint64(mMap[HeaderHashSeedIdx:HeaderHashSeedIdx + OffsetSize])
+	if formatVersion >= KeyModeFormatVersion { mmcMap.Header.KeyMode = KeyMode(mMap[HeaderKeyModeIdx]) }
+	if formatVersion >= SegmentFormatVersion { mmcMap.Header.SegmentSize, _ = deserializeUint64(mMap[HeaderSegmentSizeIdx:HeaderSegmentSizeIdx + OffsetSize]) }
+	if formatVersion >= ShardFormatVersion { mmcMap.Header.WriteShards = mMap[HeaderWriteShardsIdx] }
+	mmcMap.HeaderSize = mmcMap.Header.dataOffset()
+
+	allocErr := mmcMap.resolveAllocator()
+	if allocErr != nil { return allocErr }
+
+	hashBitsErr := mmcMap.resolveHashBits()
+	if hashBitsErr != nil { return hashBitsErr }
+
+	keyModeErr := mmcMap.resolveKeyMode()
+	if keyModeErr != nil { return keyModeErr }
+
+	segmentsErr := mmcMap.resolveSegments()
+	if segmentsErr != nil { return segmentsErr }
+
+	shardsErr := mmcMap.resolveWriteShards()
+	if shardsErr != nil { return shardsErr }
+
+	return mmcMap.resolveNodeEncoding()
+}
+
+// resolveNodeEncoding
+//	Check the node encoding from the file header against the options. The fixed encoding is the default, so compact files can be opened without
+//	setting NodeEncoding, but opening a fixed file with NodeEncodingCompact is an error.
+//	The compact encoding has no room for overflow references and does not record node offsets, so it can not be combined with the free list
+//	allocator or an OverflowThreshold.
+func (mmcMap *MMCMap) resolveNodeEncoding() error {
+	if mmcMap.Header.NodeEncoding > NodeEncodingCompact { return ErrUnknownNodeEncoding }
+	if ! mmcMap.isCompactEncoding() {
+		if mmcMap.Opts.NodeEncoding == NodeEncodingCompact { return ErrNodeEncodingMismatch }
+		return nil
+	}
+
+	if mmcMap.isFreeListEnabled() || mmcMap.Opts.OverflowThreshold > 0 { return ErrNodeEncodingUnsupported }
+	return nil
+}
+
+// resolveHashBits
+//	Check the hash bits from the file header against the options, and size the levels of the trie covered by each seed of the hash to match.
+//	HashBits32 is the default, so files with 64 bit hashes can be opened without setting HashBits.
+func (mmcMap *MMCMap) resolveHashBits() error {
+	if mmcMap.Header.HashBits != HashBits32 && mmcMap.Header.HashBits != HashBits64 { return ErrUnknownHashBits }
+	if mmcMap.Opts.HashBits != 0 && mmcMap.Opts.HashBits != mmcMap.Header.HashBits { return ErrHashBitsMismatch }
+
+	mmcMap.HashChunks = int(mmcMap.Header.HashBits) / mmcMap.BitChunkSize
+	return nil
+}
+
+// resolveKeyMode
+//	Check the key mode from the file header against the options. KeyModeHashed is the default, so ordered files can be opened without setting
+//	KeyMode, but opening a hashed file with KeyModeOrdered is an error.
+func (mmcMap *MMCMap) resolveKeyMode() error {
+	if mmcMap.Header.KeyMode > KeyModeOrdered { return ErrUnknownKeyMode }
+	if mmcMap.Opts.KeyMode != KeyModeHashed && mmcMap.Opts.KeyMode != mmcMap.Header.KeyMode { return ErrKeyModeMismatch }
+
+	return nil
+}
+
+// newHashSeed
+//	Generate the random hash seed for a new file.
+func newHashSeed() (uint64, error) {
+	sSeed := make([]byte, OffsetSize)
+	_, readErr := rand.Read(sSeed)
+	if readErr != nil { return 0, readErr }
+
+	return deserializeUint64(sSeed)
+}
+
+// raiseFormatVersion
+//	Write a newer format version to the header of an existing file and flush it, so older versions of the library refuse to open the file.
+//	The caller is responsible for excluding concurrent header writes.
+func (mmcMap *MMCMap) raiseFormatVersion(formatVersion uint16) error {
+	mMap := mmcMap.Data.Load().(mmap.MMap)
+	copy(mMap[HeaderFormatVersionIdx:HeaderAllocatorIdx], serializeUint16(formatVersion))
+
+	flushErr := mmcMap.flushRegionToDisk(HeaderIdx, InitRootOffset)
+	if flushErr != nil { return flushErr }
+
+	mmcMap.Header.FormatVersion = formatVersion
+	return nil
+}
+
+// upgradeFormatForOpts
+//	Raise the format version in the header of an existing file for the options it is opened with, before any leaf using them can be written.
+func (mmcMap *MMCMap) upgradeFormatForOpts() error {
+	if mmcMap.Opts.OverflowThreshold > 0 {
+		upgradeErr := mmcMap.upgradeFormat(OverflowFormatVersion, ErrOverflowUnsupported)
+		if upgradeErr != nil { return upgradeErr }
+	}
+
+	if mmcMap.Opts.Compression != CompressionNone { return mmcMap.upgradeFormat(CompressionFormatVersion, ErrCompressionUnsupported) }
+	return nil
+}
+
+// upgradeFormat
+//	Raise the format version in the header of an existing file to formatVersion if it is older. Followers are left at the format of the primary.
+//	Files before KeyCountFormatVersion lay out the header differently and return unsupportedErr.
+func (mmcMap *MMCMap) upgradeFormat(formatVersion uint16, unsupportedErr error) error {
+	if mmcMap.isFollower() || mmcMap.Header.FormatVersion >= formatVersion { return nil }
+	if ! mmcMap.isKeyCountTracked() { return unsupportedErr }
+
+	return mmcMap.raiseFormatVersion(formatVersion)
+}
+
+// dataOffset
+//	The offset of the initial root. Files using the free list allocator reserve space for the free list between the header and the initial root,
+//	files using segmented storage for the segment directory, and files with write shards for the shard table after either.
+func (header *MMCMapHeader) dataOffset() uint64 {
+	if header.WriteShards > 0 { return header.shardTableIdx() + ShardTableSize }
+	return header.shardTableIdx()
+}
+
+// shardTableIdx
+//	The offset of the shard table, directly after the free list or segment directory if the file has one.
+func (header *MMCMapHeader) shardTableIdx() uint64 {
+	switch {
+		case header.AllocatorID == AllocFreeList:
+			return InitRootOffset + FreeListSize
+		case header.SegmentSize > 0:
+			return InitRootOffset + SegmentDirectorySize
+		default:
+			return InitRootOffset
+	}
+}
@@ -0,0 +1,56 @@
+package mmcmap
+
+import "context"
+import "fmt"
+
+
+//============================================= MMCMap Health
+
+
+// OpenWithContext
+//	Open the mmcmap as Open does, giving up once ctx is done. Opening an existing file maps it and runs the open check, which can take a while on
+//	a large file or slow disk, so readiness probes and startup deadlines bound it with a context. ctx is checked between the steps of the open and
+//	while the open check traverses the trie, so the open itself stops: the partially opened map is released before ctx.Err() is returned, and
+//	nothing keeps running against the file afterwards.
+func OpenWithContext(ctx context.Context, opts MMCMapOpts) (*MMCMap, error) {
+	return openContext(ctx, opts)
+}
+
+// Ping
+//	Check the map is healthy, for use in the readiness probe of a service. The handle must be open and attached, the metadata and latest root must
+//	be readable and consistent, as in the quick open check, and every background go routine started for the map must still be running. A map
+//	opened with OpenLazy starts its go routines on the first Ping. Returns ErrMapClosed or ErrMapDetached for an unusable handle, and an error
+//	wrapping ErrUnhealthy for anything else.
+func (mmcMap *MMCMap) Ping() error {
+	mmcMap.RWResizeLock.RLock()
+	defer mmcMap.RWResizeLock.RUnlock()
+
+	handleErr := mmcMap.checkHandle()
+	if handleErr != nil { return handleErr }
+
+	meta, readMetaErr := mmcMap.ReadMetaFromMemMap()
+	if readMetaErr != nil { return fmt.Errorf("%w: metadata is unreadable: %s", ErrUnhealthy, readMetaErr.Error()) }
+
+	var findings []string
+	mmcMap.checkRoot(&findings, meta, mmcMap.ReadNodeFromMemMap)
+	if len(findings) > 0 { return fmt.Errorf("%w: %s", ErrUnhealthy, findings[0]) }
+
+	mmcMap.RoutinesLock.Lock()
+	defer mmcMap.RoutinesLock.Unlock()
+
+	for _, task := range mmcMap.BackgroundTasks {
+		if mmcMap.Routines[task] == 0 { return fmt.Errorf("%w: %s go routine is not running", ErrUnhealthy, task) }
+	}
+
+	return nil
+}
+
+// trackRoutine
+//	Add delta to the number of running go routines performing task.
+func (mmcMap *MMCMap) trackRoutine(task string, delta int) {
+	mmcMap.RoutinesLock.Lock()
+	defer mmcMap.RoutinesLock.Unlock()
+
+	if mmcMap.Routines == nil { mmcMap.Routines = make(map[string]int) }
+	mmcMap.Routines[task] += delta
+}
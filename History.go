@@ -0,0 +1,123 @@
+package mmcmap
+
+import "bytes"
+import "errors"
+
+import "github.com/sirgallo/mmcmap/common/mmap"
+
+
+//============================================= MMCMap History
+
+
+// rootRef is the location of a committed root in the memory map
+type rootRef struct {
+	version uint64
+	offset uint64
+}
+
+// History
+//	Returns the sequence of values a key has held, newest first, up to maxVersions entries (0 returns the full history).
+//	Since every commit appends a new root and its path copy, every prior version of the trie is still present in the memory map.
+//	The roots are located by scanning the file and the key is looked up at each of them. An entry is recorded for each version where the value changed,
+//	with the Version of the entry being the first version the value was visible at. Deletions end a run of values but are not returned as entries.
+func (mmcMap *MMCMap) History(key []byte, maxVersions int) ([]*KeyValuePair, error) {
+	mmcMap.RelocateLock.RLock()
+	defer mmcMap.RelocateLock.RUnlock()
+
+	roots, scanErr := mmcMap.scanRoots()
+	if scanErr != nil { return nil, scanErr }
+
+	var history []*KeyValuePair
+	var prev []byte
+	isPresent := false
+
+	for _, root := range roots {
+		value, getErr := mmcMap.getAtRootOffset(root.offset, mmcMap.relocateGeneration(), key)
+		if getErr != nil { return nil, getErr }
+
+		if value == nil {
+			isPresent = false
+			continue
+		}
+
+		if ! isPresent || ! bytes.Equal(value, prev) {
+			history = append(history, &KeyValuePair{ Version: root.version, Key: key, Value: value })
+		}
+
+		prev = value
+		isPresent = true
+	}
+
+	for i, j := 0, len(history) - 1; i < j; i, j = i + 1, j - 1 {
+		history[i], history[j] = history[j], history[i]
+	}
+
+	if maxVersions > 0 && len(history) > maxVersions { history = history[:maxVersions] }
+	return history, nil
+}
+
+// scanRoots
+//	Walk the serialized nodes in the memory map from the initial root up to the latest root, collecting the root of every committed version.
+//	Each commit appends its root followed by the rest of its path copy, all tagged with the new version, so a root is any internal node whose
+//	version is greater than every version seen before it. Nodes are walked using the end offset stored in each node header, and since
+//	each commit is written one byte past the previous end of the memory map, a node is confirmed by the start offset in its own header.
+//	Compact nodes do not store their start offset, so they are confirmed by their tag byte instead.
+//	Files using the FreeListAllocator write commits into reclaimed regions out of order, so they can not be scanned. Files using segmented storage
+//	are scanned from the oldest node left by ReclaimSegments, and skip the roots of versions that may reference the segments it removed.
+func (mmcMap *MMCMap) scanRoots() ([]rootRef, error) {
+	mmcMap.waitForRemap()
+
+	mmcMap.RWResizeLock.RLock()
+	defer mmcMap.RWResizeLock.RUnlock()
+
+	handleErr := mmcMap.checkHandle()
+	if handleErr != nil { return nil, handleErr }
+	if mmcMap.isFreeListEnabled() { return nil, ErrAllocatorUnsupported }
+
+	_, latestRootOffset, loadROffErr := mmcMap.loadMetaRootOffset()
+	if loadROffErr != nil { return nil, loadROffErr }
+
+	mMap := mmcMap.Data.Load().(mmap.MMap)
+	minVersion := mmcMap.minVersion()
+
+	var roots []rootRef
+	if mmcMap.isCompactEncoding() {
+		_, isScanned := mmcMap.scanCompactNodes(mMap, latestRootOffset, func(offset, version, endOffset uint64, isLeaf bool) bool {
+			if ! isLeaf && version >= minVersion && (len(roots) == 0 || version > roots[len(roots) - 1].version) {
+				roots = append(roots, rootRef{ version: version, offset: offset })
+			}
+
+			return true
+		})
+
+		if ! isScanned { return nil, errors.New("unable to locate node header while scanning roots") }
+		return roots, nil
+	}
+
+	offset := mmcMap.scanOffset()
+
+	for offset <= latestRootOffset {
+		if offset + NodeKeyIdx > uint64(len(mMap)) { return nil, errors.New("node header exceeds memory map while scanning roots") }
+
+		startOffset, _ := deserializeUint64(mMap[offset + NodeStartOffsetIdx:offset + NodeEndOffsetIdx])
+		if startOffset != offset {
+			offset++
+			startOffset, _ = deserializeUint64(mMap[offset + NodeStartOffsetIdx:offset + NodeEndOffsetIdx])
+			if startOffset != offset { return nil, errors.New("unable to locate node header while scanning roots") }
+		}
+
+		version, _ := deserializeUint64(mMap[offset + NodeVersionIdx:offset + NodeStartOffsetIdx])
+		endOffset, _ := deserializeUint64(mMap[offset + NodeEndOffsetIdx:offset + NodeBitmapIdx])
+		isLeaf := deserializeBoolean(mMap[offset + NodeIsLeafIdx])
+
+		if endOffset < offset { return nil, errors.New("invalid end offset while scanning roots") }
+
+		if ! isLeaf && version >= minVersion && (len(roots) == 0 || version > roots[len(roots) - 1].version) {
+			roots = append(roots, rootRef{ version: version, offset: offset })
+		}
+
+		offset = endOffset + 1
+	}
+
+	return roots, nil
+}
@@ -0,0 +1,118 @@
+package mmcmap
+
+import "sort"
+import "sync/atomic"
+
+
+//============================================= MMCMap Hooks
+
+
+// OnCommit
+//	Call fn with the version and root offset of every commit, once the new root is published. Calls are made in version order, from the committing
+//	go routine while it holds the locks of the map, so fn must return quickly and must not call into the map. Hand the work off to another go
+//	routine instead. Commits by BulkLoad and Compact, and applied replication deltas or snapshots, do not call fn. Call the CancelFunc to remove fn.
+func (mmcMap *MMCMap) OnCommit(fn func(version, rootOffset uint64)) CancelFunc {
+	registry := &mmcMap.Hooks
+	cancel := addHook(registry, &registry.commit, fn)
+	atomic.AddInt64(&registry.commitCount, 1)
+
+	var once uint32
+	return func() {
+		if ! atomic.CompareAndSwapUint32(&once, 0, 1) { return }
+
+		cancel()
+		atomic.AddInt64(&registry.commitCount, -1)
+	}
+}
+
+// OnResize
+//	Call fn with the new size of the file each time the memory map is grown. fn is called from the resize go routine once the resize completes.
+func (mmcMap *MMCMap) OnResize(fn func(newSize uint64)) CancelFunc {
+	return addHook(&mmcMap.Hooks, &mmcMap.Hooks.resize, fn)
+}
+
+// OnFlush
+//	Call fn each time the flush go routine syncs the memory map to disk, with a version every commit up to which is now durable.
+func (mmcMap *MMCMap) OnFlush(fn func(version uint64)) CancelFunc {
+	return addHook(&mmcMap.Hooks, &mmcMap.Hooks.flush, fn)
+}
+
+// OnCompact
+//	Call fn with the report of each completed compaction, whether started by Compact or automatically, once the compacted file is in place.
+func (mmcMap *MMCMap) OnCompact(fn func(report *CompactReport)) CancelFunc {
+	return addHook(&mmcMap.Hooks, &mmcMap.Hooks.compact, fn)
+}
+
+// hasCommitHooks
+//	Determine if any commit hooks are registered, so commits only take the watch lock to call them in order when needed.
+func (mmcMap *MMCMap) hasCommitHooks() bool {
+	return atomic.LoadInt64(&mmcMap.Hooks.commitCount) > 0
+}
+
+// fireCommit
+//	Call the commit hooks. The watch lock must be held, so hooks are called in version order.
+func (mmcMap *MMCMap) fireCommit(version, rootOffset uint64) {
+	for _, fn := range loadHooks(&mmcMap.Hooks, &mmcMap.Hooks.commit) { fn(version, rootOffset) }
+}
+
+// fireResize
+//	Call the resize hooks with the size of the file after a resize.
+func (mmcMap *MMCMap) fireResize() {
+	fns := loadHooks(&mmcMap.Hooks, &mmcMap.Hooks.resize)
+	if len(fns) == 0 { return }
+
+	size, sizeErr := mmcMap.FileSize()
+	if sizeErr != nil { return }
+
+	for _, fn := range fns { fn(uint64(size)) }
+}
+
+// fireFlush
+//	Call the flush hooks with the version that was durable once the flush completed.
+func (mmcMap *MMCMap) fireFlush(version uint64) {
+	for _, fn := range loadHooks(&mmcMap.Hooks, &mmcMap.Hooks.flush) { fn(version) }
+}
+
+// fireCompact
+//	Call the compaction hooks with the report of a completed compaction.
+func (mmcMap *MMCMap) fireCompact(report *CompactReport) {
+	for _, fn := range loadHooks(&mmcMap.Hooks, &mmcMap.Hooks.compact) { fn(report) }
+}
+
+// addHook
+//	Register fn in hooks under a new id, returning a CancelFunc that removes it.
+func addHook[F any](registry *hookRegistry, hooks *map[uint64]F, fn F) CancelFunc {
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+
+	if *hooks == nil { *hooks = make(map[uint64]F) }
+
+	id := registry.nextID
+	registry.nextID++
+	(*hooks)[id] = fn
+
+	return func() {
+		registry.lock.Lock()
+		defer registry.lock.Unlock()
+
+		delete(*hooks, id)
+	}
+}
+
+// loadHooks
+//	Copy the registered hooks, in the order they were registered, so they are called without holding the registry lock.
+func loadHooks[F any](registry *hookRegistry, hooks *map[uint64]F) []F {
+	registry.lock.RLock()
+	defer registry.lock.RUnlock()
+
+	if len(*hooks) == 0 { return nil }
+
+	ids := make([]uint64, 0, len(*hooks))
+	for id := range *hooks { ids = append(ids, id) }
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	fns := make([]F, len(ids))
+	for idx, id := range ids { fns[idx] = (*hooks)[id] }
+
+	return fns
+}
@@ -1,5 +1,6 @@
 package mmcmap
 
+import "fmt"
 import "runtime"
 import "sync/atomic"
 
@@ -25,10 +26,45 @@ func (mmcMap *MMCMap) determineIfResize(offset uint64) bool {
 	}
 }
 
+// checkRange
+//	Check that length bytes from offset fall within the memory map before it is sliced. A closed map has an empty buffer, so ErrMapClosed is
+//	returned for it instead of an OutOfRangeError.
+func checkRange(mMap mmap.MMap, op string, offset, length uint64, err error) error {
+	size := uint64(len(mMap))
+	if size == 0 { return ErrMapClosed }
+	if offset > size || length > size - offset { return &OutOfRangeError{ Op: op, Offset: offset, Length: length, Size: size, Err: err } }
+
+	return nil
+}
+
+// Error
+//	Describe the access and the size of the memory map it fell outside of.
+func (rangeErr *OutOfRangeError) Error() string {
+	msg := fmt.Sprintf("%s: %d bytes at offset %d are out of range of the %d byte memory map", rangeErr.Op, rangeErr.Length, rangeErr.Offset, rangeErr.Size)
+	if rangeErr.Err != nil { msg += ": " + rangeErr.Err.Error() }
+
+	return msg
+}
+
+// Unwrap
+//	The underlying error, so a node read out of range still matches ErrCorruptNode.
+func (rangeErr *OutOfRangeError) Unwrap() error {
+	return rangeErr.Err
+}
+
+// Is
+//	Every OutOfRangeError matches ErrOutOfRange.
+func (rangeErr *OutOfRangeError) Is(target error) bool {
+	return target == ErrOutOfRange
+}
+
 // flushRegionToDisk
 //	Flushes a region of the memory map to disk instead of flushing the entire map. 
 //	When a startoffset is provided, if it is not aligned with the start of the last page, the offset needs to be normalized.
+//	Ephemeral maps are never flushed.
 func (mmcMap *MMCMap) flushRegionToDisk(startOffset, endOffset uint64) error {
+	if mmcMap.Opts.Ephemeral { return nil }
+
 	startOffsetOfPage := startOffset & ^(uint64(DefaultPageSize) - 1)
 
 	mMap := mmcMap.Data.Load().(mmap.MMap)
@@ -43,86 +79,366 @@ func (mmcMap *MMCMap) flushRegionToDisk(startOffset, endOffset uint64) error {
 // handleFlush
 //	This is "optimistic" flushing. 
 //	A separate go routine is spawned and signalled to flush changes to the mmap to disk.
-func (mmcMap *MMCMap) handleFlush() {
-	for range mmcMap.SignalFlush {
-		func() {
-			for atomic.LoadUint32(&mmcMap.IsResizing) == 1 { runtime.Gosched() }
-			
-			mmcMap.RWResizeLock.RLock()
-			defer mmcMap.RWResizeLock.RUnlock()
+//	After each flush, the bytes written before it started are no longer unflushed, and any writes stalled on MaxUnflushedBytes are woken.
+//	The flush hooks are called once the resize read lock is released, with the version of the root published before the flush started.
+func (mmcMap *MMCMap) handleFlush(signal chan bool) {
+	for range signal {
+		version, flushErr := mmcMap.flushFiles()
+		if flushErr != nil {
+			if flushErr != ErrMapClosed { mmcMap.log(LogError, "flushing memory map failed", "err", flushErr) }
+			continue
+		}
+
+		mmcMap.fireFlush(version)
+	}
+}
+
+// flushFiles
+//	Sync the files to disk under the resize read lock, returning the version of the root published before the sync started, which is on disk once
+//	it returns. Performed by the flush go routine, and by Wait when the flush go routine has not caught up with the version it waits for.
+//	Flushes are serialized by the flush lock, since each removes the unflushed bytes it read before syncing.
+func (mmcMap *MMCMap) flushFiles() (uint64, error) {
+	mmcMap.FlushLock.Lock()
+	defer mmcMap.FlushLock.Unlock()
+
+	mmcMap.waitForRemap()
+
+	mmcMap.RWResizeLock.RLock()
+	defer mmcMap.RWResizeLock.RUnlock()
+
+	if atomic.LoadUint32(&mmcMap.Opened) == 0 { return 0, ErrMapClosed }
+
+	pending := atomic.LoadUint64(&mmcMap.UnflushedBytes)
+	version := mmcMap.publishedVersion()
+
+	span := mmcMap.startSpan(nil, SpanFlush)
+	span.SetAttribute("bytes", pending)
+
+	flushErr := mmcMap.syncFiles()
+	span.End(flushErr)
+
+	if flushErr != nil { return 0, flushErr }
+
+	mmcMap.markFlushed(pending, version)
+	return version, nil
+}
+
+// publishedVersion
+//	The version of the latest published root. The version in the metadata is claimed before the path is written, so it can be ahead of the root.
+//	The resize read lock must be held.
+func (mmcMap *MMCMap) publishedVersion() uint64 {
+	_, rootOffset, loadROffErr := mmcMap.loadMetaRootOffset()
+	if loadROffErr != nil { return 0 }
+
+	root, readRootErr := mmcMap.ReadNodeFromMemMap(rootOffset)
+	if readRootErr != nil { return 0 }
+
+	return root.Version
+}
+
+// markFlushed
+//	Remove the bytes covered by a completed flush from the unflushed bytes, advance the flushed version to the version of the root published before
+//	it started, and wake any stalled writes and calls to Wait.
+func (mmcMap *MMCMap) markFlushed(flushed, version uint64) {
+	atomic.AddUint64(&mmcMap.Flushes, 1)
+	if flushed > 0 { atomic.AddUint64(&mmcMap.UnflushedBytes, ^(flushed - 1)) }
+
+	mmcMap.FlushCond.L.Lock()
+	if version > mmcMap.FlushedVersion { mmcMap.FlushedVersion = version }
+	mmcMap.FlushCond.Broadcast()
+	mmcMap.FlushCond.L.Unlock()
+}
+
+// awaitFlush
+//	Called before every commit. If MaxUnflushedBytes has been exceeded, the write either fails with ErrWriteStall or waits for the flush
+//	go routine to bring the unflushed bytes back under the limit, depending on the stall policy.
+func (mmcMap *MMCMap) awaitFlush() error {
+	limit := mmcMap.Opts.MaxUnflushedBytes
+	if limit == 0 || atomic.LoadUint64(&mmcMap.UnflushedBytes) < limit { return nil }
+
+	atomic.AddUint64(&mmcMap.WriteStalls, 1)
+	if mmcMap.Opts.StallPolicy == StallError {
+		mmcMap.signalFlush()
+		return ErrWriteStall
+	}
+
+	mmcMap.FlushCond.L.Lock()
+	defer mmcMap.FlushCond.L.Unlock()
 
-			mmcMap.File.Sync()
-		}()
+	for atomic.LoadUint64(&mmcMap.UnflushedBytes) >= limit {
+		if atomic.LoadUint32(&mmcMap.IsDetached) == 1 { return ErrMapDetached }
+
+		mmcMap.signalFlush()
+		mmcMap.FlushCond.Wait()
 	}
+
+	return nil
 }
 
 // handleResize
 //	A separate go routine is spawned to handle resizing the memory map.
-//	When the mmap reaches its size limit, the go routine is signalled.
-func (mmcMap *MMCMap) handleResize() {
-	for range mmcMap.SignalResize { mmcMap.resizeMmap() }
+//	When the mmap reaches its size limit, the go routine is signalled. Failed resizes are logged, and retried by the next write that does not fit.
+func (mmcMap *MMCMap) handleResize(signal chan bool) {
+	for range signal {
+		resized, resizeErr := mmcMap.resizeMmap()
+		if resized {
+			atomic.AddUint64(&mmcMap.Resizes, 1)
+			mmcMap.fireResize()
+			mmcMap.sealAfterResize()
+		}
+
+		if resizeErr != nil && resizeErr != ErrMapClosed { mmcMap.log(LogError, "resizing memory map failed", "err", resizeErr) }
+	}
+}
+
+// waitForRemap
+//	Yield while a resize is remapping the memory map, so reads do not contend for the resize lock it is waiting on.
+//	Resizes that grow the memory map in place never block reads, so reads do not wait for them.
+func (mmcMap *MMCMap) waitForRemap() {
+	for atomic.LoadUint32(&mmcMap.IsRemapping) == 1 { runtime.Gosched() }
+}
+
+// waitForResize
+//	Yield while the memory map is being resized, before an operation that appends to it, since the append would likely not fit until it completes.
+func (mmcMap *MMCMap) waitForResize() {
+	for atomic.LoadUint32(&mmcMap.IsResizing) == 1 { runtime.Gosched() }
 }
 
 // mmap
-//	Helper to memory map the mmcMap File in to buffer.
+//	Helper to memory map the mmcMap File in to buffer, locking it into RAM according to LockMemory. Files using segmented storage map every segment.
+//	The file is mapped at the start of a reservation of address space several times its size, so resizes can grow the memory map in place.
+//	If the address space can not be reserved, the file is mapped on its own and every resize remaps it, unless ReserveAddressSpace was requested.
 func (mmcMap *MMCMap) mMap() error {
-	mMap, mmapErr := mmap.Map(mmcMap.File, mmap.RDWR, 0)
-	if mmapErr != nil { return mmapErr }
+	if mmcMap.isSegmented() { return mmcMap.mapSegments() }
+
+	mMap, mmapErr := mmcMap.mapReserved()
+	if mmapErr != nil {
+		if mmcMap.Opts.ReserveAddressSpace > 0 { return mmapErr }
+
+		mMap, mmapErr = mmap.Map(mmcMap.File, mmap.RDWR, 0)
+		if mmapErr != nil { return mmapErr }
+	}
 
 	mmcMap.Data.Store(mMap)
+	mmcMap.lockMemory(mMap)
+
 	return nil
 }
 
+// mapReserved
+//	Reserve address space for the file and map the file at the start of it.
+func (mmcMap *MMCMap) mapReserved() (mmap.MMap, error) {
+	size, sizeErr := mmcMap.FileSize()
+	if sizeErr != nil { return nil, sizeErr }
+
+	reservation, reserveErr := mmap.Reserve(int(mmcMap.reservationSize(int64(size))))
+	if reserveErr != nil { return nil, reserveErr }
+
+	mMap, mapErr := mmap.MapRegionAt(reservation, mmcMap.File, size, mmap.RDWR, 0)
+	if mapErr != nil {
+		reservation.Unmap()
+		return nil, mapErr
+	}
+
+	mmcMap.Reservation = reservation
+	return mMap, nil
+}
+
+// reservationSize
+//	The address space to reserve for a memory map of size bytes, which is ReserveAddressSpace or MinMapReservation, or 4x the size once it outgrows them.
+func (mmcMap *MMCMap) reservationSize(size int64) int64 {
+	reservationSize := int64(MinMapReservation)
+	if mmcMap.Opts.ReserveAddressSpace > 0 { reservationSize = roundToPage(int64(mmcMap.Opts.ReserveAddressSpace)) }
+	if size * 4 > reservationSize { reservationSize = roundToPage(size * 4) }
+
+	return reservationSize
+}
+
 // munmap
-//	Unmaps the memory map from RAM.
+//	Unmaps the memory map from RAM, purging the node cache and pinned levels since their keys and values may be sliced from it. The files of any
+//	segments after the first are closed, since the memory map keeps them open until it is unmapped.
+//	If values returned by GetZeroCopy are still pinned, the memory map is retired instead, and unmapped once the
+//	last of them is released.
 func (mmcMap *MMCMap) munmap() error {
+	mmcMap.purgeNodes()
+	mmcMap.closeSegmentFiles()
+
 	mMap := mmcMap.Data.Load().(mmap.MMap)
-	unmapErr := mMap.Unmap()
+	if mmcMap.Reservation != nil {
+		mMap = mmcMap.Reservation
+		mmcMap.Reservation = nil
+	}
+
+	unmapErr := mmcMap.releaseMap(mMap)
 	if unmapErr != nil { return unmapErr }
 
 	mmcMap.Data.Store(mmap.MMap{})
 	return nil
 }
 
+// releaseMap
+//	Unmap a memory map that is no longer in use, unless zero copy values are pinned to it, in which case it is retired until they are released.
+func (mmcMap *MMCMap) releaseMap(mMap mmap.MMap) error {
+	if len(mMap) == 0 || mmcMap.retireMap(mMap) { return nil }
+	return mMap.Unmap()
+}
+
 // resizeMmap
 //	Dynamically resizes the underlying memory mapped file.
 //	When a file is first created, default size is 64MB and doubles the mem map on each resize until 1GB.
+//	The memory map is grown in place when it fits in the reservation, so readers and writers are not blocked. Otherwise the file is remapped.
 func (mmcMap *MMCMap) resizeMmap() (bool, error) {
+	defer atomic.StoreUint32(&mmcMap.IsResizing, 0)
+
+	span := mmcMap.startSpan(nil, SpanResize)
+
+	grown, growErr := mmcMap.growMmap()
+	if grown || growErr != nil {
+		span.SetAttribute("inPlace", true)
+		span.End(growErr)
+		return grown, growErr
+	}
+
+	remapped, remapErr := mmcMap.remapMmap()
+	span.SetAttribute("inPlace", false)
+	span.End(remapErr)
+
+	return remapped, remapErr
+}
+
+// growMmap
+//	Grow the file and map the new region directly after the memory map, within the reservation, while holding only the resize read lock.
+//	The memory map never moves, so slices of it taken before the resize stay valid. Returns false if the new size does not fit in the reservation,
+//	or the memory map does not end on a page boundary, in which case the file has to be remapped. Files using segmented storage grow by a segment.
+func (mmcMap *MMCMap) growMmap() (bool, error) {
+	mmcMap.RWResizeLock.RLock()
+	defer mmcMap.RWResizeLock.RUnlock()
+
+	if atomic.LoadUint32(&mmcMap.Opened) == 0 { return false, ErrMapClosed }
+
+	mMap := mmcMap.Data.Load().(mmap.MMap)
+	if mmcMap.isMapFull(len(mMap)) { return false, ErrMapFull }
+	if mmcMap.isSegmented() { return mmcMap.growSegments(mMap) }
+
+	allocateSize := mmcMap.capFileSize(nextFileSize(len(mMap)))
+
+	if len(mMap) == 0 || len(mMap) % DefaultPageSize != 0 || allocateSize > int64(len(mmcMap.Reservation)) { return false, nil }
+
+	truncateErr := mmcMap.File.Truncate(allocateSize)
+	if truncateErr != nil { return false, truncateErr }
+
+	_, mapErr := mmap.MapRegionAt(mmcMap.Reservation[len(mMap):], mmcMap.File, int(allocateSize) - len(mMap), mmap.RDWR, int64(len(mMap)))
+	if mapErr != nil { return false, mapErr }
+
+	grownMap := mmcMap.Reservation[:allocateSize]
+	mmcMap.Data.Store(grownMap)
+	mmcMap.lockMemory(grownMap)
+
+	mmcMap.log(LogDebug, "grew memory map in place", "from", len(mMap), "to", allocateSize)
+	return true, nil
+}
+
+// remapMmap
+//	Grow the file and replace the memory map with a new mapping of the whole file, blocking writers and reads under the resize lock while it is
+//	replaced. Reads started by beginRead keep reading the previous mapping, which is only unmapped once they finish. Files using segmented storage
+//	add a segment and map every segment again, which also blocks reads started by beginRead.
+func (mmcMap *MMCMap) remapMmap() (bool, error) {
+	atomic.StoreUint32(&mmcMap.IsRemapping, 1)
+	defer atomic.StoreUint32(&mmcMap.IsRemapping, 0)
+
 	mmcMap.RWResizeLock.Lock()
-	
 	defer mmcMap.RWResizeLock.Unlock()
-	defer atomic.StoreUint32(&mmcMap.IsResizing, 0)
+
+	if atomic.LoadUint32(&mmcMap.Opened) == 0 { return false, ErrMapClosed }
 
 	mMap := mmcMap.Data.Load().(mmap.MMap)
+	if mmcMap.isMapFull(len(mMap)) { return false, ErrMapFull }
+	if mmcMap.isSegmented() {
+		atomic.AddUint32(&mmcMap.ExclusiveHolders, 1)
+		defer atomic.AddUint32(&mmcMap.ExclusiveHolders, ^uint32(0))
+		mmcMap.advanceEpoch()
 
-	allocateSize := func() int64 {
-		switch {
-			case len(mMap) == 0:
-				return int64(DefaultPageSize) * 16 * 1000 // 64MB
-			case len(mMap) >= MaxResize:
-				return int64(len(mMap) + MaxResize)
-			default:
-				return int64(len(mMap) * 2)
-		}
-	}()
+		remapErr := mmcMap.remapSegments(mMap)
+		if remapErr != nil { return false, remapErr }
+
+		return true, nil
+	}
+
+	allocateSize := mmcMap.capFileSize(nextFileSize(len(mMap)))
 
 	if len(mMap) > 0 {
-		flushErr := mmcMap.File.Sync()
+		flushErr := mmcMap.syncFiles()
 		if flushErr != nil { return false, flushErr }
-		
-		unmapErr := mmcMap.munmap()
-		if unmapErr != nil { return false, unmapErr }
 	}
 
 	truncateErr := mmcMap.File.Truncate(allocateSize)
 	if truncateErr != nil { return false, truncateErr }
 
+	replaced, reservation := mMap, mmcMap.Reservation
+	if reservation != nil { replaced = reservation }
+
+	mmcMap.Reservation = nil
 	mmapErr := mmcMap.mMap()
-	if mmapErr != nil { return false, mmapErr }
+	if mmapErr != nil {
+		mmcMap.Reservation = reservation
+		return false, mmapErr
+	}
 
+	mmcMap.purgeNodes()
+	mmcMap.advanceEpoch()
+
+	unmapErr := mmcMap.releaseMap(replaced)
+	if unmapErr != nil { return false, unmapErr }
+
+	mmcMap.log(LogDebug, "remapped memory map", "from", len(mMap), "to", allocateSize)
 	return true, nil
 }
 
+// nextFileSize
+//	The size of the file once a memory map of size bytes is resized, rounded up to a whole page so the next region can be mapped after it.
+func nextFileSize(size int) int64 {
+	switch {
+		case size == 0:
+			return initialFileSize()
+		case size >= MaxResize:
+			return roundToPage(int64(size + MaxResize))
+		default:
+			return int64(size * 2)
+	}
+}
+
+// initialFileSize
+//	The size of a newly created file, which is 64MB with the default page size.
+func initialFileSize() int64 {
+	return int64(DefaultPageSize) * 16 * 1000
+}
+
+// roundToPage
+//	Round size up to a multiple of the page size.
+func roundToPage(size int64) int64 {
+	pageSize := int64(DefaultPageSize)
+	return (size + pageSize - 1) / pageSize * pageSize
+}
+
+// syncFiles
+//	Sync the file to disk, along with the file of every segment after the first in files using segmented storage.
+//	Ephemeral files are removed on Close, so they are never synced.
+func (mmcMap *MMCMap) syncFiles() error {
+	if mmcMap.Opts.Ephemeral { return nil }
+
+	syncErr := mmcMap.File.Sync()
+	if syncErr != nil { return syncErr }
+
+	for _, file := range mmcMap.loadSegmentFiles() {
+		if file.File == nil || file.IsCompressed { continue }
+
+		syncErr = file.File.Sync()
+		if syncErr != nil { return syncErr }
+	}
+
+	return nil
+}
+
 // signalFlush
 //	Called by all writes to "optimistically" handle flushing changes to the mmap to disk.
 func (mmcMap *MMCMap) signalFlush() {
@@ -134,8 +450,16 @@ func (mmcMap *MMCMap) signalFlush() {
 
 // exclusiveWriteMmap
 //	Takes a path copy and writes the nodes to the memory map, then updates the metadata.
-func (mmcMap *MMCMap) exclusiveWriteMmap(path *MMCMapNode) (bool, error) {
-	if atomic.LoadUint32(&mmcMap.IsResizing) == 1 { return false, nil }
+//	keyDelta is the change in the number of keys made by the path copy, which is added to the key count before the new root is published.
+//	With the FreeListAllocator, the path is written into the first region of the free list it fits in, and only appended if none fit.
+//	With DirectSerialize, the path is serialized straight into the memory map once the version has been claimed, instead of into a buffer first.
+//	ErrResizeInProgress is returned if the path does not fit in the memory map, in which case the caller should retry once the resize completes.
+//	ErrMapFull is returned instead if the memory map can not grow enough for the path without exceeding MaxFileSize.
+//	Any change events are published to watchers once the new root is stored. The watch lock is held across both, so a commit can not publish before
+//	the commit whose root it was copied from. Serialization is traced as a child of parent.
+//	shards is the mask of the write shards the path may modify, whose slots in the shard table are given the new root before it is published.
+func (mmcMap *MMCMap) exclusiveWriteMmap(path *MMCMapNode, keyDelta int64, events []ChangeEvent, shards uint32, parent Span) (bool, error) {
+	if atomic.LoadUint32(&mmcMap.IsResizing) == 1 { return false, ErrResizeInProgress }
 
 	versionPtr, version, loadVErr := mmcMap.loadMetaVersion()
 	if loadVErr != nil { return false, nil }
@@ -147,25 +471,58 @@ func (mmcMap *MMCMap) exclusiveWriteMmap(path *MMCMapNode) (bool, error) {
 	if loadSOffErr != nil { return false, nil }
 
 	newVersion := path.Version
-	newOffsetInMMap := endOffset + 1
+	newOffsetInMMap := mmcMap.Allocator.Place(endOffset)
+
+	freeRegionIdx := -1
+	size := mmcMap.serializedPathSize(path)
+	if mmcMap.isFreeListEnabled() {
+		var regionOffset uint64
+		freeRegionIdx, regionOffset = mmcMap.findFreeRegion(size)
+		if freeRegionIdx >= 0 { newOffsetInMMap = regionOffset }
+	}
 	
-	serializedPath, serializeErr := mmcMap.SerializePathToMemMap(path, newOffsetInMMap)
-	if serializeErr != nil { return false, serializeErr }
+	var serializedPath []byte
+	if ! mmcMap.Opts.DirectSerialize {
+		span := mmcMap.startSpan(parent, SpanSerialize)
+		span.SetAttribute("bytes", size)
+
+		var serializeErr error
+		serializedPath, serializeErr = mmcMap.serializePath(path, newOffsetInMMap, size)
+		span.End(serializeErr)
+
+		if serializeErr != nil { return false, serializeErr }
+		defer releaseSerializeBuffer(serializedPath)
+	}
 
 	updatedMeta := &MMCMapMetaData{
 		Version: newVersion,
 		RootOffset: newOffsetInMMap,
-		EndMmapOffset: newOffsetInMMap + uint64(len(serializedPath)),
+		EndMmapOffset: mmcMap.Allocator.End(endOffset, newOffsetInMMap, size),
 	}
 
+	fileSizeErr := mmcMap.checkFileSize(updatedMeta.EndMmapOffset)
+	if fileSizeErr != nil { return false, fileSizeErr }
+
 	isResize := mmcMap.determineIfResize(updatedMeta.EndMmapOffset)
-	if isResize { return false, nil }
+	if isResize { return false, ErrResizeInProgress }
 
 	if atomic.LoadUint32(&mmcMap.IsResizing) == 0 {
-		if version == updatedMeta.Version - 1 && atomic.CompareAndSwapUint64(versionPtr, version, updatedMeta.Version) {
+		if version == updatedMeta.Version - 1 && compareAndSwapMetaPointer(versionPtr, version, updatedMeta.Version) {
 			mmcMap.storeMetaPointer(endOffsetPtr, updatedMeta.EndMmapOffset)
+			if freeRegionIdx >= 0 { mmcMap.consumeFreeRegion(freeRegionIdx, size) }
+
+			var writeNodesToMmapErr error
+			if mmcMap.Opts.DirectSerialize {
+				span := mmcMap.startSpan(parent, SpanSerialize)
+				span.SetAttribute("bytes", size)
+				span.SetAttribute("direct", true)
+
+				writeNodesToMmapErr = mmcMap.writePathToMemMap(path, newOffsetInMMap, size)
+				span.End(writeNodesToMmapErr)
+			} else { _, writeNodesToMmapErr = mmcMap.writeNodesToMemMap(serializedPath, newOffsetInMMap) }
+
+			if writeNodesToMmapErr == nil { writeNodesToMmapErr = mmcMap.publishShards(shards, version, updatedMeta.Version, updatedMeta.RootOffset) }
 
-			_, writeNodesToMmapErr := mmcMap.writeNodesToMemMap(serializedPath, newOffsetInMMap)
 			if writeNodesToMmapErr != nil {
 				mmcMap.storeMetaPointer(endOffsetPtr, updatedMeta.EndMmapOffset)
 				mmcMap.storeMetaPointer(versionPtr, version)
@@ -173,9 +530,20 @@ func (mmcMap *MMCMap) exclusiveWriteMmap(path *MMCMapNode) (bool, error) {
 
 				return false, writeNodesToMmapErr
 			}
-			
-			mmcMap.storeMetaPointer(rootOffsetPtr, updatedMeta.RootOffset)
+
+			if freeRegionIdx >= 0 { mmcMap.purgeNodes() }
+
+			if len(events) > 0 || mmcMap.hasCommitHooks() {
+				mmcMap.WatchLock.Lock()
+				mmcMap.publishRoot(rootOffsetPtr, updatedMeta.RootOffset, keyDelta)
+				if len(events) > 0 { mmcMap.publishChanges(events) }
+				mmcMap.fireCommit(updatedMeta.Version, updatedMeta.RootOffset)
+				mmcMap.WatchLock.Unlock()
+			} else { mmcMap.publishRoot(rootOffsetPtr, updatedMeta.RootOffset, keyDelta) }
+
+			atomic.AddUint64(&mmcMap.UnflushedBytes, size)
 			mmcMap.signalFlush()
+			mmcMap.refreshPinned(updatedMeta.Version, updatedMeta.RootOffset)
 			
 			return true, nil
 		}
@@ -0,0 +1,52 @@
+package mmcmap
+
+
+//============================================= MMCMap Import
+
+
+// importer batches pairs read from another source into commits of batchSize pairs, or a single commit if batchSize is 0
+type importer struct {
+	mmcMap *MMCMap
+	batchSize int
+	ops []*BatchOp
+	imported uint64
+}
+
+
+// newImporter
+//	Creates an importer committing to the map batchSize pairs at a time.
+func (mmcMap *MMCMap) newImporter(batchSize int) *importer {
+	return &importer{ mmcMap: mmcMap, batchSize: batchSize }
+}
+
+// put
+//	Buffer a put of the key-value pair, committing the buffered pairs once the batch is full.
+//	The key and value are retained until the batch is committed, so sources that reuse their buffers must pass copies.
+func (imp *importer) put(key, value []byte) error {
+	sizeErr := imp.mmcMap.checkPutSize(key, int64(len(value)))
+	if sizeErr != nil { return sizeErr }
+
+	imp.ops = append(imp.ops, &BatchOp{ Key: key, Value: value })
+	if imp.batchSize > 0 && len(imp.ops) >= imp.batchSize { return imp.flush() }
+
+	return nil
+}
+
+// flush
+//	Commit the buffered pairs as a single version.
+func (imp *importer) flush() error {
+	if len(imp.ops) == 0 { return nil }
+
+	_, commitErr := imp.mmcMap.commitOps(imp.ops)
+	if commitErr != nil { return commitErr }
+
+	imp.imported += uint64(len(imp.ops))
+	imp.ops = nil
+	return nil
+}
+
+// pending
+//	The number of pairs imported so far, including those buffered but not yet committed.
+func (imp *importer) pending() uint64 {
+	return imp.imported + uint64(len(imp.ops))
+}
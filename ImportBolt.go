@@ -0,0 +1,41 @@
+//go:build bolt
+
+package mmcmap
+
+import "fmt"
+
+import bolt "go.etcd.io/bbolt"
+
+
+//============================================= MMCMap Import Bolt
+
+
+// ImportFromBolt
+//	Put every key-value pair in a bbolt bucket, streamed from a read transaction on db with a cursor.
+//	Nested buckets are skipped. Pairs are committed DefaultImportBatchSize at a time, so the bucket does not need to fit in memory, and the pairs from
+//	earlier batches remain committed if a later batch fails. Returns the number of pairs imported. Requires building with the bolt tag.
+func (mmcMap *MMCMap) ImportFromBolt(db *bolt.DB, bucket []byte) (uint64, error) {
+	imp := mmcMap.newImporter(DefaultImportBatchSize)
+
+	viewErr := db.View(func(tx *bolt.Tx) error {
+		boltBucket := tx.Bucket(bucket)
+		if boltBucket == nil { return fmt.Errorf("bolt bucket %q not found", bucket) }
+
+		cursor := boltBucket.Cursor()
+		for key, value := cursor.First(); key != nil; key, value = cursor.Next() {
+			if value == nil { continue }
+
+			putErr := imp.put(append([]byte{}, key...), append([]byte{}, value...))
+			if putErr != nil { return putErr }
+		}
+
+		return nil
+	})
+
+	if viewErr != nil { return imp.imported, viewErr }
+
+	flushErr := imp.flush()
+	if flushErr != nil { return imp.imported, flushErr }
+
+	return imp.imported, nil
+}
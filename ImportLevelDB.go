@@ -0,0 +1,38 @@
+//go:build leveldb
+
+package mmcmap
+
+import "github.com/syndtr/goleveldb/leveldb"
+import "github.com/syndtr/goleveldb/leveldb/opt"
+
+
+//============================================= MMCMap Import LevelDB
+
+
+// ImportFromLevelDB
+//	Put every key-value pair in the LevelDB database at path, which is opened read only and streamed with an iterator.
+//	Pairs are committed DefaultImportBatchSize at a time, so the database does not need to fit in memory, and the pairs from earlier batches remain
+//	committed if a later batch fails. Returns the number of pairs imported. Requires building with the leveldb tag.
+func (mmcMap *MMCMap) ImportFromLevelDB(path string) (uint64, error) {
+	db, openErr := leveldb.OpenFile(path, &opt.Options{ ReadOnly: true, ErrorIfMissing: true })
+	if openErr != nil { return 0, openErr }
+	defer db.Close()
+
+	imp := mmcMap.newImporter(DefaultImportBatchSize)
+
+	iter := db.NewIterator(nil, nil)
+	defer iter.Release()
+
+	for iter.Next() {
+		putErr := imp.put(append([]byte{}, iter.Key()...), append([]byte{}, iter.Value()...))
+		if putErr != nil { return imp.imported, putErr }
+	}
+
+	iterErr := iter.Error()
+	if iterErr != nil { return imp.imported, iterErr }
+
+	flushErr := imp.flush()
+	if flushErr != nil { return imp.imported, flushErr }
+
+	return imp.imported, nil
+}
@@ -0,0 +1,127 @@
+package mmcmap
+
+import "bufio"
+import "encoding/base64"
+import "encoding/hex"
+import "encoding/json"
+import "fmt"
+import "io"
+
+
+//============================================= MMCMap JSON
+
+
+// jsonPair is the serialized form of a key-value pair in an export
+type jsonPair struct {
+	Key string `json:"key"`
+	Value string `json:"value"`
+}
+
+
+// ExportJSON
+//	Write every key-value pair at the latest version to w as newline delimited JSON, one {"key": ..., "value": ...} object per line, with keys and
+//	values encoded by JSONOpts.Encoding. Pairs are streamed in trie order as they are read from the memory map, so the export does not grow with the
+//	size of the map. Use Range for sorted pairs. Returns the number of pairs written.
+func (mmcMap *MMCMap) ExportJSON(w io.Writer, opts ...JSONOpts) (uint64, error) {
+	var jsonOpts JSONOpts
+	if len(opts) > 0 { jsonOpts = opts[0] }
+
+	mmcMap.RelocateLock.RLock()
+	defer mmcMap.RelocateLock.RUnlock()
+
+	rootOffset, loadROffErr := mmcMap.loadRootOffsetForRead()
+	if loadROffErr != nil { return 0, loadROffErr }
+
+	writer := bufio.NewWriter(w)
+	encoder := json.NewEncoder(writer)
+
+	var exported uint64
+	rangeErr := mmcMap.rangeRecursive(rootOffset, nil, nil, false, func(pair *KeyValuePair) error {
+		exported++
+		return encoder.Encode(jsonPair{ Key: jsonOpts.Encoding.encode(pair.Key), Value: jsonOpts.Encoding.encode(pair.Value) })
+	})
+
+	if rangeErr != nil { return 0, rangeErr }
+
+	flushErr := writer.Flush()
+	if flushErr != nil { return 0, flushErr }
+
+	return exported, nil
+}
+
+// ImportJSON
+//	Put every key-value pair read from r, which is either newline delimited JSON as written by ExportJSON or a JSON array of the same objects.
+//	Keys and values are decoded with JSONOpts.Encoding. Pairs are committed JSONOpts.BatchSize at a time, or all as a single version if BatchSize is 0.
+//	If a pair can not be decoded, the pairs from earlier batches remain committed. Returns the number of pairs imported.
+func (mmcMap *MMCMap) ImportJSON(r io.Reader, opts ...JSONOpts) (uint64, error) {
+	var jsonOpts JSONOpts
+	if len(opts) > 0 { jsonOpts = opts[0] }
+
+	reader := bufio.NewReader(r)
+	isArray, peekErr := isJSONArray(reader)
+	if peekErr != nil { return 0, peekErr }
+
+	decoder := json.NewDecoder(reader)
+	if isArray {
+		_, tokenErr := decoder.Token()
+		if tokenErr != nil { return 0, tokenErr }
+	}
+
+	imp := mmcMap.newImporter(jsonOpts.BatchSize)
+
+	for decoder.More() {
+		var pair jsonPair
+		decodeErr := decoder.Decode(&pair)
+		if decodeErr != nil { return imp.imported, decodeErr }
+
+		key, decKeyErr := jsonOpts.Encoding.decode(pair.Key)
+		if decKeyErr != nil { return imp.imported, fmt.Errorf("invalid key in pair %d: %w", imp.pending(), decKeyErr) }
+
+		value, decValErr := jsonOpts.Encoding.decode(pair.Value)
+		if decValErr != nil { return imp.imported, fmt.Errorf("invalid value in pair %d: %w", imp.pending(), decValErr) }
+
+		putErr := imp.put(key, value)
+		if putErr != nil { return imp.imported, putErr }
+	}
+
+	if isArray {
+		_, tokenErr := decoder.Token()
+		if tokenErr != nil { return imp.imported, tokenErr }
+	}
+
+	flushErr := imp.flush()
+	if flushErr != nil { return imp.imported, flushErr }
+
+	return imp.imported, nil
+}
+
+// isJSONArray
+//	Determine if the JSON read from reader is an array, by peeking past any leading whitespace for the opening bracket.
+func isJSONArray(reader *bufio.Reader) (bool, error) {
+	for {
+		next, peekErr := reader.Peek(1)
+		if peekErr == io.EOF { return false, nil }
+		if peekErr != nil { return false, peekErr }
+
+		switch next[0] {
+			case ' ', '\t', '\r', '\n':
+				reader.ReadByte()
+			default:
+				return next[0] == '[', nil
+		}
+	}
+}
+
+// encode
+//	Encode a key or value as a string using the encoding.
+func (encoding KeyEncoding) encode(data []byte) string {
+	if encoding == EncodingHex { return hex.EncodeToString(data) }
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+// decode
+//	Decode a key or value encoded as a string using the encoding.
+func (encoding KeyEncoding) decode(data string) ([]byte, error) {
+	if encoding == EncodingHex { return hex.DecodeString(data) }
+	return base64.StdEncoding.DecodeString(data)
+}
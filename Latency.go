@@ -0,0 +1,114 @@
+package mmcmap
+
+import "math/bits"
+import "sync/atomic"
+import "time"
+
+
+//============================================= MMCMap Latency
+
+
+// LatencyStats
+//	Summaries of the latencies recorded for each operation. Every summary is empty unless the map was opened with RecordLatency.
+func (mmcMap *MMCMap) LatencyStats() LatencyStats {
+	return LatencyStats{
+		Put: mmcMap.PutLatency.Summary(),
+		Get: mmcMap.GetLatency.Summary(),
+		Delete: mmcMap.DeleteLatency.Summary(),
+		Range: mmcMap.RangeLatency.Summary(),
+	}
+}
+
+// Record
+//	Add a single operation, along with the number of commit attempts it retried.
+func (histogram *LatencyHistogram) Record(latency time.Duration, retries uint64) {
+	nanos := uint64(0)
+	if latency > 0 { nanos = uint64(latency) }
+
+	bucket := bits.Len64(nanos)
+	if bucket >= LatencyBuckets { bucket = LatencyBuckets - 1 }
+
+	atomic.AddUint64(&histogram.Buckets[bucket], 1)
+	atomic.AddUint64(&histogram.Count, 1)
+	atomic.AddUint64(&histogram.TotalNanos, nanos)
+	if retries > 0 { atomic.AddUint64(&histogram.Retries, retries) }
+
+	for {
+		maxNanos := atomic.LoadUint64(&histogram.MaxNanos)
+		if nanos <= maxNanos || atomic.CompareAndSwapUint64(&histogram.MaxNanos, maxNanos, nanos) { return }
+	}
+}
+
+// Summary
+//	The count, total, mean, estimated median and 99th percentile, and max of the recorded latencies.
+func (histogram *LatencyHistogram) Summary() OpLatency {
+	var buckets [LatencyBuckets]uint64
+	var count uint64
+	for idx := range buckets {
+		buckets[idx] = atomic.LoadUint64(&histogram.Buckets[idx])
+		count += buckets[idx]
+	}
+
+	if count == 0 { return OpLatency{} }
+
+	maxNanos := atomic.LoadUint64(&histogram.MaxNanos)
+	totalNanos := atomic.LoadUint64(&histogram.TotalNanos)
+	return OpLatency{
+		Count: count,
+		Retries: atomic.LoadUint64(&histogram.Retries),
+		Total: time.Duration(totalNanos),
+		Mean: time.Duration(totalNanos / count),
+		P50: quantile(buckets, count, maxNanos, 0.5),
+		P99: quantile(buckets, count, maxNanos, 0.99),
+		Max: time.Duration(maxNanos),
+	}
+}
+
+// startLatency
+//	The start time of an operation, or the zero time if latencies are not recorded, so the clock is only read when it is needed.
+func (mmcMap *MMCMap) startLatency() time.Time {
+	if ! mmcMap.Opts.RecordLatency { return time.Time{} }
+	return time.Now()
+}
+
+// recordLatency
+//	Record the time since start in the histogram, if latencies are recorded.
+func (mmcMap *MMCMap) recordLatency(histogram *LatencyHistogram, start time.Time, retries uint64) {
+	if ! mmcMap.Opts.RecordLatency { return }
+	histogram.Record(time.Since(start), retries)
+}
+
+// commitTimed
+//	Same as commitVersioned, but records the latency and retries of the commit in the histogram.
+func (mmcMap *MMCMap) commitTimed(histogram *LatencyHistogram, prepare func(root *MMCMapNode) ([]*BatchOp, error)) (bool, uint64, error) {
+	start := mmcMap.startLatency()
+	ok, version, retries, commitErr := mmcMap.commitCounted(prepare)
+	mmcMap.recordLatency(histogram, start, retries)
+
+	return ok, version, commitErr
+}
+
+// quantile
+//	Estimate the latency below which q of the operations fall, by interpolating linearly within the bucket the quantile lands in.
+//	The estimate is capped at the longest recorded latency, which bounds the open ended last bucket.
+func quantile(buckets [LatencyBuckets]uint64, count, maxNanos uint64, q float64) time.Duration {
+	target := q * float64(count)
+
+	var seen uint64
+	for idx, bucketCount := range buckets {
+		if bucketCount == 0 || float64(seen + bucketCount) < target {
+			seen += bucketCount
+			continue
+		}
+
+		lower, upper := uint64(0), uint64(1)
+		if idx > 0 { lower, upper = uint64(1) << (idx - 1), uint64(1) << idx }
+
+		estimate := lower + uint64((target - float64(seen)) / float64(bucketCount) * float64(upper - lower))
+		if estimate > maxNanos { estimate = maxNanos }
+
+		return time.Duration(estimate)
+	}
+
+	return time.Duration(maxNanos)
+}
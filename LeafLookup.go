@@ -0,0 +1,95 @@
+package mmcmap
+
+import "bytes"
+import "encoding/binary"
+import "fmt"
+import "unsafe"
+
+import "github.com/sirgallo/mmcmap/common/mmap"
+
+
+//============================================= MMCMap Leaf Lookup
+
+
+// lookupLeaf
+//	Locate the leaf for a key in the committed trie rooted at rootOffset, returning nil if the key does not exist. Expired leaves are returned.
+//	Internal nodes are not deserialized on the way down. Pinned and cached internal nodes are used as they are, and for any other node only the bitmap
+//	and the child slot for the sparse index of the key are read straight from the memory map. A full node is only built for the leaf at the end of
+//	the path, or for the collision bucket holding it, which are read through the node cache as usual. The root must be committed, since a path copy
+//	has children that are not in the memory map yet. A path deeper than the key can reach returns ErrCorruptNode, so a cyclic child offset can not
+//	loop forever. Files using the compact encoding have no fixed width child slots, so they are traversed by getLeafRecursive instead.
+func (mmcMap *MMCMap) lookupLeaf(rootOffset uint64, key []byte) (*MMCMapNode, error) {
+	if mmcMap.isCompactEncoding() { return mmcMap.lookupLeafFrom(rootOffset, key, 0) }
+
+	mMap := mmcMap.Data.Load().(mmap.MMap)
+	offset := rootOffset
+	maxLevel := mmcMap.maxKeyLevel(key)
+
+	for level := 0; level <= maxLevel + 1; level++ {
+		node := mmcMap.loadInMemory(offset)
+		if node != nil && ! node.IsLeaf && ! mmcMap.isCollisionLevel(level) {
+			index := mmcMap.getKeyIndex(key, level)
+			if ! IsBitSet(node.Bitmap, index) { return nil, nil }
+
+			offset = node.Children[childPosition(node.Bitmap, index)].StartOffset
+			continue
+		}
+
+		rangeErr := checkRange(mMap, "lookup leaf", offset, NodeKeyIdx, ErrCorruptNode)
+		if rangeErr != nil { return nil, rangeErr }
+
+		if deserializeBoolean(mMap[offset + NodeIsLeafIdx]) {
+			leaf, readLeafErr := mmcMap.ReadNodeFromMemMap(offset)
+			if readLeafErr != nil || ! bytes.Equal(key, leaf.Key) { return nil, readLeafErr }
+
+			return leaf, nil
+		}
+
+		if mmcMap.isCollisionLevel(level) { return mmcMap.lookupLeafFrom(offset, key, level) }
+
+		bitmap := binary.LittleEndian.Uint32(mMap[offset + NodeBitmapIdx:offset + NodeIsLeafIdx])
+		index := mmcMap.getKeyIndex(key, level)
+		if ! IsBitSet(bitmap, index) { return nil, nil }
+
+		endOffset := binary.LittleEndian.Uint64(mMap[offset + NodeEndOffsetIdx:offset + NodeBitmapIdx])
+		slot := offset + NodeChildrenIdx + uint64(childPosition(bitmap, index) * NodeChildPtrSize)
+		if endOffset < slot || endOffset - slot < OffsetSize - 1 {
+			return nil, fmt.Errorf("%w at offset %d: child slot %d is outside of the node ending at %d", ErrCorruptNode, offset, slot, endOffset)
+		}
+
+		rangeErr = checkRange(mMap, "lookup leaf", slot, OffsetSize, ErrCorruptNode)
+		if rangeErr != nil { return nil, rangeErr }
+
+		offset = binary.LittleEndian.Uint64(mMap[slot:slot + OffsetSize])
+	}
+
+	return nil, fmt.Errorf("%w at offset %d: no leaf within %d levels of the root", ErrCorruptNode, offset, maxLevel + 1)
+}
+
+// loadInMemory
+//	The node at offset if it is pinned or cached, without copying it, or nil if it has to be read from the memory map. The node must not be modified.
+func (mmcMap *MMCMap) loadInMemory(offset uint64) *MMCMapNode {
+	if node, ok := mmcMap.loadPinned().nodes[offset]; ok { return node }
+	if node, ok := mmcMap.NodeCache.peek(offset); ok { return node }
+
+	return nil
+}
+
+// lookupLeafFrom
+//	Deserialize the node at offset and locate the leaf for the key from it with getLeafRecursive, starting at level.
+func (mmcMap *MMCMap) lookupLeafFrom(offset uint64, key []byte, level int) (*MMCMapNode, error) {
+	node, readNodeErr := mmcMap.ReadNodeFromMemMap(offset)
+	if readNodeErr != nil { return nil, readNodeErr }
+
+	nodePtr := unsafe.Pointer(node)
+	return mmcMap.getLeafRecursive(&nodePtr, key, level)
+}
+
+// lookupValue
+//	The value for a key in the committed trie rooted at rootOffset, located with lookupLeaf. Returns nil if the key does not exist or has expired.
+func (mmcMap *MMCMap) lookupValue(rootOffset uint64, key []byte) ([]byte, error) {
+	leaf, lookupErr := mmcMap.lookupLeaf(rootOffset, key)
+	if lookupErr != nil || leaf == nil || leaf.isExpired() { return nil, lookupErr }
+
+	return leaf.Value, nil
+}
@@ -0,0 +1,35 @@
+package mmcmap
+
+
+//============================================= MMCMap Key Count
+
+
+// Len
+//	The number of keys in the latest version of the map.
+//	The count is maintained in the header and updated by every commit, so it is read in constant time. It is published together with the root under
+//	the meta lock, so it always matches the version Get and Range read at the same moment. Keys put with a ttl are counted until the sweeper deletes
+//	them, so the count can include keys that have expired but not yet been swept. Files at a format version without the key count are counted by
+//	traversing the latest root instead.
+func (mmcMap *MMCMap) Len() (uint64, error) {
+	if ! mmcMap.isKeyCountTracked() { return mmcMap.countKeys() }
+
+	mmcMap.RWResizeLock.RLock()
+	defer mmcMap.RWResizeLock.RUnlock()
+
+	handleErr := mmcMap.checkHandle()
+	if handleErr != nil { return 0, handleErr }
+
+	return mmcMap.loadCommittedKeyCount()
+}
+
+// countKeys
+//	Count the keys in the latest version by traversing every leaf.
+func (mmcMap *MMCMap) countKeys() (uint64, error) {
+	mmcMap.RelocateLock.RLock()
+	defer mmcMap.RelocateLock.RUnlock()
+
+	rootOffset, loadROffErr := mmcMap.loadRootOffsetForRead()
+	if loadROffErr != nil { return 0, loadROffErr }
+
+	return mmcMap.countLeaves(rootOffset)
+}
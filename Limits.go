@@ -0,0 +1,72 @@
+package mmcmap
+
+
+//============================================= MMCMap Size Limits
+
+
+// checkPutSize
+//	Check that a key-value pair being put fits within MaxKeySize and MaxValueSize, returning ErrKeyTooLarge or ErrValueTooLarge if it does not.
+func (mmcMap *MMCMap) checkPutSize(key []byte, valueSize int64) error {
+	if len(key) > mmcMap.maxKeySize() { return ErrKeyTooLarge }
+	if mmcMap.Opts.MaxValueSize > 0 && valueSize > mmcMap.Opts.MaxValueSize { return ErrValueTooLarge }
+
+	return nil
+}
+
+// maxKeySize
+//	The longest key that can be put, which is MaxKeyLength unless MaxKeySize is set.
+func (mmcMap *MMCMap) maxKeySize() int {
+	if mmcMap.Opts.MaxKeySize > 0 { return mmcMap.Opts.MaxKeySize }
+	return MaxKeyLength
+}
+
+// checkFileSize
+//	Check that the memory map does not have to grow past MaxFileSize to hold offset, returning ErrMapFull if it does.
+//	Called before determineIfResize, so a commit that can never fit fails instead of waiting on a resize that will not happen.
+func (mmcMap *MMCMap) checkFileSize(offset uint64) error {
+	maxSize := mmcMap.maxFileSize()
+	if maxSize > 0 && offset >= maxSize { return ErrMapFull }
+
+	return nil
+}
+
+// maxFileSize
+//	The largest the memory map can grow, which is MaxFileSize rounded down to a whole page, or a whole segment with segmented storage.
+//	Ephemeral maps default to DefaultEphemeralMaxSize. 0 does not limit the size.
+func (mmcMap *MMCMap) maxFileSize() uint64 {
+	maxSize := mmcMap.Opts.MaxFileSize
+	if maxSize == 0 && mmcMap.Opts.Ephemeral { maxSize = DefaultEphemeralMaxSize }
+	if maxSize == 0 { return 0 }
+
+	unit := uint64(DefaultPageSize)
+	if mmcMap.isSegmented() { unit = mmcMap.Header.SegmentSize }
+
+	return maxSize / unit * unit
+}
+
+// capFileSize
+//	Cap the size a file is grown or truncated to at the maximum file size.
+func (mmcMap *MMCMap) capFileSize(size int64) int64 {
+	maxSize := int64(mmcMap.maxFileSize())
+	if maxSize > 0 && size > maxSize { return maxSize }
+
+	return size
+}
+
+// isMapFull
+//	Determine if a memory map of size bytes has reached the maximum file size, so resizing can not grow it.
+func (mmcMap *MMCMap) isMapFull(size int) bool {
+	maxSize := mmcMap.maxFileSize()
+	return maxSize > 0 && uint64(size) >= maxSize
+}
+
+// validateSizeLimits
+//	Check that the size limits in the options can be enforced. MaxKeySize can not exceed MaxKeyLength, since longer keys can not be serialized,
+//	and MaxFileSize has to hold at least a page, or a segment with segmented storage. Node pool sizes can not be negative.
+func validateSizeLimits(opts MMCMapOpts) error {
+	if opts.MaxKeySize < 0 || opts.MaxKeySize > MaxKeyLength || opts.MaxValueSize < 0 { return ErrInvalidSizeLimit }
+	if opts.MaxFileSize > 0 && (opts.MaxFileSize < uint64(DefaultPageSize) || opts.MaxFileSize < opts.SegmentSize) { return ErrInvalidSizeLimit }
+	if opts.NodePoolSize < 0 || opts.NodePoolMaxSize < 0 { return ErrInvalidSizeLimit }
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package mmcmap
+
+import "fmt"
+import "io"
+import "strings"
+import "sync"
+import "time"
+
+
+//============================================= MMCMap Logging
+
+
+// writerLogger writes each log event as a single line
+type writerLogger struct {
+	lock sync.Mutex
+	w io.Writer
+}
+
+
+// NewWriterLogger
+//	Creates a Logger that writes each event to w as a line of the time, level, message, and key=value pairs.
+func NewWriterLogger(w io.Writer) Logger {
+	return &writerLogger{ w: w }
+}
+
+// Log
+//	Write the event as a single line. Lines are not interleaved when the map logs from several go routines.
+func (logger *writerLogger) Log(level LogLevel, msg string, keyvals ...interface{}) {
+	var line strings.Builder
+	fmt.Fprintf(&line, "%s %s %s", time.Now().Format(time.RFC3339), level, msg)
+
+	for idx := 0; idx < len(keyvals); idx += 2 {
+		if idx + 1 < len(keyvals) {
+			fmt.Fprintf(&line, " %v=%v", keyvals[idx], keyvals[idx + 1])
+		} else { fmt.Fprintf(&line, " %v", keyvals[idx]) }
+	}
+
+	line.WriteByte('\n')
+
+	logger.lock.Lock()
+	defer logger.lock.Unlock()
+
+	io.WriteString(logger.w, line.String())
+}
+
+// String
+//	The name of the level.
+func (level LogLevel) String() string {
+	switch level {
+		case LogDebug:
+			return "DEBUG"
+		case LogInfo:
+			return "INFO"
+		case LogWarn:
+			return "WARN"
+		case LogError:
+			return "ERROR"
+		default:
+			return fmt.Sprintf("LEVEL(%d)", int(level))
+	}
+}
+
+// log
+//	Pass the event to the logger of the map if it is at least as severe as LogLevel, tagged with the name of the map.
+func (mmcMap *MMCMap) log(level LogLevel, msg string, keyvals ...interface{}) {
+	if mmcMap.Opts.Logger == nil || level < mmcMap.Opts.LogLevel { return }
+	mmcMap.Opts.Logger.Log(level, msg, append([]interface{}{ "map", mmcMap.Opts.Name }, keyvals...)...)
+}
@@ -1,7 +1,11 @@
 package mmcmap
 
+import "context"
+import "errors"
 import "math"
 import "os"
+import "path/filepath"
+import "sync"
 import "sync/atomic"
 
 import "github.com/sirgallo/utils"
@@ -9,28 +13,95 @@ import "github.com/sirgallo/utils"
 import "github.com/sirgallo/mmcmap/common/mmap"
 
 
+var sidecarSuffixes = []string{ ".wheel", ".wheel.tmp", ".compact", ".clone", ".recover" }
+
+
 //============================================= MMCMap
 
 
 // Open initializes a new mmcmap
 //	This will create the memory mapped file or read it in if it already exists.
-//	Then, the meta data is initialized and written to the first 0-23 bytes in the memory map.
+//	Then, the meta data is initialized and written to the first 0-23 bytes in the memory map, followed by the header at bytes 24-63.
 //	An initial root MMCMapNode will also be written to the memory map as well.
+//	For existing files the metadata is validated, along with the header, root, and a sample of the trie depending on OpenCheck. With OpenLazy, allocating the node pool and starting the background go routines is deferred
+//	until the first operation, so short lived processes against large files open immediately.
+//	Ephemeral maps are created in a new file under EphemeralDir, which is removed if the map can not be opened.
 func Open(opts MMCMapOpts) (*MMCMap, error) {
+	return openContext(context.Background(), opts)
+}
+
+// openContext
+//	Open the mmcmap, checking ctx between the steps of opening an existing file and while the open check traverses the trie. Once ctx is done, the
+//	partially opened map is released before ctx.Err() is returned, so nothing is left mapping, checking, or writing the file.
+func openContext(ctx context.Context, opts MMCMapOpts) (*MMCMap, error) {
+	ctxErr := ctx.Err()
+	if ctxErr != nil { return nil, ctxErr }
+
+	if opts.Ephemeral {
+		var createErr error
+		opts.Filepath, createErr = createEphemeralFile(opts)
+		if createErr != nil { return nil, createErr }
+	}
+
+	mmcMap, openFileErr := openFile(opts, os.O_RDWR | os.O_CREATE | os.O_APPEND)
+	if openFileErr != nil {
+		if opts.Ephemeral { Destroy(opts.Filepath) }
+		return nil, openFileErr
+	}
+
+	initFileErr := mmcMap.initializeFile(ctx)
+	if initFileErr != nil {
+		if opts.Ephemeral {
+			mmcMap.Remove()
+		} else if initFileErr == ctx.Err() { mmcMap.releaseHandle() }
+
+		return nil, initFileErr
+	}
+
+	mmcMap.pinLatest()
+	if opts.OpenMode == OpenEager { mmcMap.ensureStarted() }
+
+	registerMap(mmcMap)
+	return mmcMap, nil
+}
+
+// openFile
+//	Create the mmcmap handle and open the underlying file with the given flags, without mapping it into memory.
+func openFile(opts MMCMapOpts, flag int) (*MMCMap, error) {
 	bitChunkSize := 5
 	hashChunks := int(math.Pow(float64(2), float64(bitChunkSize))) / bitChunkSize
-	np := NewMMCMapNodePool(100000)	// let's initialize with 100,000 pre-allocated nodes
+
+	if opts.Name == "" { opts.Name = filepath.Base(opts.Filepath) }
+	if opts.FollowAddr != "" && opts.ReplicaSource == nil { opts.ReplicaSource = NewTCPReplicaSource(opts.FollowAddr) }
+	if opts.Compression > CompressionZstd || opts.ColdCompression > CompressionZstd { return nil, ErrUnknownCompression }
+	if opts.NodeEncoding > NodeEncodingCompact { return nil, ErrUnknownNodeEncoding }
+	if opts.HashBits != 0 && opts.HashBits != HashBits32 && opts.HashBits != HashBits64 { return nil, ErrUnknownHashBits }
+	if opts.KeyMode > KeyModeOrdered { return nil, ErrUnknownKeyMode }
+	if opts.SegmentSize != 0 && ! isValidSegmentSize(opts.SegmentSize) { return nil, ErrInvalidSegmentSize }
+	if opts.WriteShards < 0 || opts.WriteShards > MaxWriteShards { return nil, ErrInvalidWriteShards }
+	if opts.LockMemory < LockMemoryOff || opts.LockMemory > LockMemoryPrefix { return nil, ErrUnknownLockMemoryMode }
+
+	limitErr := validateSizeLimits(opts)
+	if limitErr != nil { return nil, limitErr }
 
 	mmcMap := &MMCMap{
+		Opts: opts,
 		BitChunkSize: bitChunkSize,
 		HashChunks: hashChunks,
-		Opened: true,
+		Header: MMCMapHeader{ HashBits: HashBits32 },
+		Opened: 1,
+		OwnerPID: os.Getpid(),
 		SignalResize: make(chan bool),
 		SignalFlush: make(chan bool),
-		NodePool: np,
+		WriteQueue: newWriteQueue(opts),
+		StopCompaction: make(chan struct{}),
+		StopFollow: make(chan struct{}),
+		StopSweep: make(chan struct{}),
+		FlushCond: sync.NewCond(&sync.Mutex{}),
+		RelocateCond: sync.NewCond(&sync.Mutex{}),
+		NodeCache: NewNodeCache(opts.NodeCacheSize),
 	}
 
-	flag := os.O_RDWR | os.O_CREATE | os.O_APPEND
 	var openFileErr error
 
 	mmcMap.File, openFileErr = os.OpenFile(opts.Filepath, flag, 0600)
@@ -39,25 +110,83 @@ func Open(opts MMCMapOpts) (*MMCMap, error) {
 	mmcMap.Filepath = mmcMap.File.Name()
 	atomic.StoreUint32(&mmcMap.IsResizing, 0)
 	mmcMap.Data.Store(mmap.MMap{})
+	mmcMap.Pinned.Store(&pinnedNodes{})
 
-	initFileErr := mmcMap.initializeFile()
-	if initFileErr != nil { return nil, initFileErr	}
-
-	go mmcMap.handleFlush()
-	go mmcMap.handleResize()
+	loadExpiryErr := mmcMap.loadExpiry()
+	if loadExpiryErr != nil {
+		mmcMap.File.Close()
+		return nil, loadExpiryErr
+	}
 
 	return mmcMap, nil
 }
 
+// createEphemeralFile
+//	Create the file of an ephemeral map with a unique name in EphemeralDir, prefixed by the base name of Filepath, and return its path.
+//	Without EphemeralDir, the file is created in DefaultEphemeralDir where it exists, and the temp directory otherwise.
+func createEphemeralFile(opts MMCMapOpts) (string, error) {
+	dir := opts.EphemeralDir
+	if dir == "" {
+		stat, statErr := os.Stat(DefaultEphemeralDir)
+		if statErr == nil && stat.IsDir() {
+			dir = DefaultEphemeralDir
+		} else { dir = os.TempDir() }
+	}
+
+	prefix := "mmcmap"
+	if opts.Filepath != "" { prefix = filepath.Base(opts.Filepath) }
+
+	file, createErr := os.CreateTemp(dir, prefix + "-*")
+	if createErr != nil { return "", createErr }
+
+	closeErr := file.Close()
+	if closeErr != nil { return "", closeErr }
+
+	return file.Name(), nil
+}
+
 // Close
-//	Close the mmcmap, unmapping the file from memory and closing the file.
+//	Close the mmcmap, unmapping the file from memory and closing the file. The expiry wheel is saved next to the file.
+//	The handle is marked closed first, and the memory map is only released once in-flight operations holding the resize read lock finish, so
+//	every operation afterwards returns ErrMapClosed instead of reading from an unmapped buffer. Ephemeral maps are removed once closed.
 func (mmcMap *MMCMap) Close() error {
-	if ! mmcMap.Opened { return nil }
-	mmcMap.Opened = false
+	closeErr := mmcMap.closeHandle()
+	if closeErr != nil || ! mmcMap.Opts.Ephemeral { return closeErr }
+
+	return Destroy(mmcMap.Opts.Filepath)
+}
+
+// closeHandle
+//	Mark the handle closed, stop the background go routines, and release the memory map and file, saving the expiry wheel unless the map is
+//	ephemeral.
+func (mmcMap *MMCMap) closeHandle() error {
+	if ! atomic.CompareAndSwapUint32(&mmcMap.Opened, 1, 0) { return nil }
+	unregisterMap(mmcMap)
+	mmcMap.closeWatchers()
+
+	if atomic.LoadUint32(&mmcMap.IsDetached) == 0 {
+		close(mmcMap.StopCompaction)
+		close(mmcMap.StopFollow)
+		close(mmcMap.StopSweep)
+	}
+
+	var saveExpiryErr error
+	if ! mmcMap.Opts.Ephemeral { saveExpiryErr = mmcMap.saveExpiry() }
 
-	flushErr := mmcMap.File.Sync()
+	mmcMap.lockResize()
+	defer mmcMap.unlockResize()
+
+	if atomic.LoadUint32(&mmcMap.IsDetached) == 1 {
+		mmcMap.Filepath = utils.GetZero[string]()
+		return saveExpiryErr
+	}
+
+	pending := atomic.LoadUint64(&mmcMap.UnflushedBytes)
+	flushErr := mmcMap.syncFiles()
 	if flushErr != nil { return flushErr }
 
+	mmcMap.markFlushed(pending, mmcMap.publishedVersion())
+
 	unmapErr := mmcMap.munmap()
 	if unmapErr != nil { return unmapErr }
 
@@ -67,27 +196,181 @@ func (mmcMap *MMCMap) Close() error {
 	}
 
 	mmcMap.Filepath = utils.GetZero[string]()
+	return saveExpiryErr
+}
+
+// Detach
+//	Release the file, the memory map, and the flush/resize go routines while keeping the handle itself valid.
+//	Neither the mapping nor the background go routines survive a fork, so process managers that hand a map off to a child process should
+//	detach before forking and call Reattach in whichever process continues to use the handle.
+//	All operations on a detached handle return ErrMapDetached. Detaching a closed map returns ErrMapClosed.
+func (mmcMap *MMCMap) Detach() error {
+	mmcMap.lockResize()
+	defer mmcMap.unlockResize()
+
+	if atomic.LoadUint32(&mmcMap.Opened) == 0 { return ErrMapClosed }
+	if atomic.LoadUint32(&mmcMap.IsDetached) == 1 { return nil }
+
+	if mmcMap.OwnerPID == os.Getpid() {
+		flushErr := mmcMap.syncFiles()
+		if flushErr != nil { return flushErr }
+	}
+
+	releaseErr := mmcMap.releaseHandle()
+	if releaseErr != nil { return releaseErr }
+
+	atomic.StoreUint32(&mmcMap.IsDetached, 1)
+	return nil
+}
+
+// Reattach
+//	Reopen and remap the file backing a detached handle. The background go routines are restarted on the next operation.
+//	If the handle was inherited from another process without being detached first, the inherited mapping and file descriptor are released
+//	and the current process becomes the owner of the handle. Reattaching an attached handle owned by the current process is a no-op, and reattaching
+//	a closed map returns ErrMapClosed.
+func (mmcMap *MMCMap) Reattach() error {
+	mmcMap.lockResize()
+	defer mmcMap.unlockResize()
+
+	if atomic.LoadUint32(&mmcMap.Opened) == 0 { return ErrMapClosed }
+
+	isDetached := atomic.LoadUint32(&mmcMap.IsDetached) == 1
+	if ! isDetached && mmcMap.OwnerPID == os.Getpid() { return nil }
+
+	if ! isDetached {
+		releaseErr := mmcMap.releaseHandle()
+		if releaseErr != nil { return releaseErr }
+	}
+
+	flag := os.O_RDWR | os.O_APPEND
+	file, openFileErr := os.OpenFile(mmcMap.Filepath, flag, 0600)
+	if openFileErr != nil { return openFileErr }
+
+	mmcMap.File = file
+	mmcMap.OwnerPID = os.Getpid()
+
+	mmapErr := mmcMap.mMap()
+	if mmapErr != nil { return mmapErr }
+
+	atomic.StoreUint32(&mmcMap.IsResizing, 0)
+	atomic.StoreUint32(&mmcMap.IsDetached, 0)
+
+	mmcMap.SignalResize = make(chan bool)
+	mmcMap.SignalFlush = make(chan bool)
+	mmcMap.WriteQueue = newWriteQueue(mmcMap.Opts)
+	mmcMap.StopCompaction = make(chan struct{})
+	mmcMap.StopFollow = make(chan struct{})
+	mmcMap.StopSweep = make(chan struct{})
+	mmcMap.StartOnce = sync.Once{}
+
 	return nil
 }
 
 // FileSize
-//	Determine the memory mapped file size.
+//	Determine the memory mapped file size. For files using segmented storage, this is the size of every segment on disk.
 func (mmcMap *MMCMap) FileSize() (int, error) {
 	stat, statErr := mmcMap.File.Stat()
 	if statErr != nil { return 0, statErr }
 
-	size := int(stat.Size())
+	segmentsSize, segmentsErr := mmcMap.segmentFilesSize()
+	if segmentsErr != nil { return 0, segmentsErr }
+
+	size := int(stat.Size()) + segmentsSize
 	return size, nil
 }
 
 // Remove
-//	Close the MMCMap and remove the source file.
+//	Close the MMCMap if it is still open and then Destroy its file. Since the path is taken from the options the map was opened with, Remove can also
+//	be called after Close or Detach.
 func (mmcMap *MMCMap) Remove() error {
 	closeErr := mmcMap.Close()
 	if closeErr != nil { return closeErr }
 
-	removeErr := os.Remove(mmcMap.File.Name())
-	if removeErr != nil { return removeErr }
+	return Destroy(mmcMap.Opts.Filepath)
+}
+
+// Destroy
+//	Remove the mmcmap file at path along with every sidecar file belonging to it, which are the saved expiry wheel, any temporary file left behind
+//	by an interrupted save, compaction, clone, or recovery, and the segment and cold segment files of a segmented map. Files that do not exist are
+//	skipped, so Destroy can be repeated. The map must not be open. Backups and commit intent logs are written where the caller chooses and are not
+//	removed.
+func Destroy(path string) error {
+	paths := []string{ path }
+	for _, suffix := range sidecarSuffixes { paths = append(paths, path + suffix) }
+
+	for _, suffix := range []string{ "", coldSegmentSuffix, coldSegmentSuffix + ".tmp" } {
+		segmentPaths, globErr := filepath.Glob(path + ".[0-9][0-9][0-9][0-9][0-9][0-9]" + suffix)
+		if globErr != nil { return globErr }
+
+		paths = append(paths, segmentPaths...)
+	}
+
+	for _, sidecarPath := range paths {
+		removeErr := os.Remove(sidecarPath)
+		if removeErr != nil && ! errors.Is(removeErr, os.ErrNotExist) { return removeErr }
+	}
+
+	return nil
+}
+
+// checkHandle
+//	Determine if the handle can be used by the calling process, starting any work deferred by a lazy open.
+//	Called by operations while holding the resize read lock.
+func (mmcMap *MMCMap) checkHandle() error {
+	if atomic.LoadUint32(&mmcMap.Opened) == 0 { return ErrMapClosed }
+	if atomic.LoadUint32(&mmcMap.IsDetached) == 1 { return ErrMapDetached }
+	if mmcMap.OwnerPID != os.Getpid() { return ErrForkedHandle }
+
+	mmcMap.ensureStarted()
+	return nil
+}
+
+// ensureStarted
+//	Allocate the node pool and spawn the flush and resize go routines, if they have not been started already.
+//	Each go routine is handed its channel, since Reattach replaces the channels on the handle once the previous go routines have exited.
+//	The go routines are labelled for pprof with the task they perform, and the tasks are recorded in BackgroundTasks for Ping.
+func (mmcMap *MMCMap) ensureStarted() {
+	mmcMap.StartOnce.Do(func() {
+		if mmcMap.NodePool == nil { mmcMap.NodePool = mmcMap.newNodePool() }
+
+		signalFlush, signalResize, writeQueue := mmcMap.SignalFlush, mmcMap.SignalResize, mmcMap.WriteQueue
+		stopCompaction, stopFollow, stopSweep := mmcMap.StopCompaction, mmcMap.StopFollow, mmcMap.StopSweep
+
+		var backgroundTasks []string
+		start := func(task string, fn func()) {
+			backgroundTasks = append(backgroundTasks, task)
+			mmcMap.goLabeled(task, fn)
+		}
+
+		start("flush", func() { mmcMap.handleFlush(signalFlush) })
+		start("resize", func() { mmcMap.handleResize(signalResize) })
+		if mmcMap.Opts.SingleWriter { start("writer", func() { mmcMap.handleWrites(writeQueue) }) }
+		if mmcMap.isAutoCompactionEnabled() { start("compaction", func() { mmcMap.handleCompaction(stopCompaction) }) }
+		if mmcMap.isFollower() {
+			start("follow", func() { mmcMap.handleFollow(stopFollow) })
+		} else { start("sweep", func() { mmcMap.handleSweep(stopSweep) }) }
+
+		mmcMap.RoutinesLock.Lock()
+		mmcMap.BackgroundTasks = backgroundTasks
+		mmcMap.RoutinesLock.Unlock()
+	})
+}
+
+// releaseHandle
+//	Stop the background go routines, unmap the file, and close the file descriptor. The resize write lock must be held.
+func (mmcMap *MMCMap) releaseHandle() error {
+	close(mmcMap.SignalFlush)
+	close(mmcMap.SignalResize)
+	close(mmcMap.WriteQueue)
+	close(mmcMap.StopCompaction)
+	close(mmcMap.StopFollow)
+	close(mmcMap.StopSweep)
+
+	unmapErr := mmcMap.munmap()
+	if unmapErr != nil { return unmapErr }
+
+	closeErr := mmcMap.File.Close()
+	if closeErr != nil { return closeErr }
 
 	return nil
 }
@@ -95,24 +378,76 @@ func (mmcMap *MMCMap) Remove() error {
 // InitializeFile
 //	Initialize the memory mapped file to persist the hamt.
 //	If file size is 0, initiliaze the file size to 64MB and set the initial metadata and root values into the map.
-//	Otherwise, just map the already initialized file into the memory map.
-func (mmcMap *MMCMap) initializeFile() error {
+//	Otherwise, just map the already initialized file into the memory map. ctx is checked before each step that reads or writes a large part of it.
+func (mmcMap *MMCMap) initializeFile(ctx context.Context) error {
 	fSize, fSizeErr := mmcMap.FileSize()
 	if fSizeErr != nil { return fSizeErr }
 
 	if fSize == 0 {
+		mmcMap.Header.SegmentSize = mmcMap.Opts.SegmentSize
+
 		_, resizeErr := mmcMap.resizeMmap()
 		if resizeErr != nil { return resizeErr }
 
+		initHeaderErr := mmcMap.initHeader()
+		if initHeaderErr != nil { return initHeaderErr }
+
 		endOffset, initRootErr := mmcMap.initRoot()
 		if initRootErr != nil { return initRootErr }
 
 		initMetaErr := mmcMap.initMeta(endOffset)
 		if initMetaErr != nil { return initMetaErr }
+
+		// the metadata, header, and root are contiguous, so a single flush covers all three
+		flushErr := mmcMap.flushRegionToDisk(MetaVersionIdx, endOffset)
+		if flushErr != nil { return flushErr }
 	} else {
 		mmapErr := mmcMap.mMap()
 		if mmapErr != nil { return mmapErr }
+
+		loadHeaderErr := mmcMap.loadHeader()
+		if loadHeaderErr != nil { return loadHeaderErr }
+
+		ctxErr := ctx.Err()
+		if ctxErr != nil { return ctxErr }
+
+		mapSegmentsErr := mmcMap.mapAfterHeader()
+		if mapSegmentsErr != nil { return mapSegmentsErr }
+
+		validateErr := mmcMap.validateMeta()
+		if validateErr != nil { return validateErr }
+
+		// the format is upgraded in place, so nothing is written once ctx is done
+		ctxErr = ctx.Err()
+		if ctxErr != nil { return ctxErr }
+
+		upgradeErr := mmcMap.upgradeFormatForOpts()
+		if upgradeErr != nil { return upgradeErr }
 	}
 
-	return nil
+	return mmcMap.runOpenCheck(ctx)
+}
+
+// validateMeta
+//	Check that the metadata of an existing file is consistent with the size of the memory map.
+//	Only the metadata block is read, so opening a large file does not depend on the size of the trie. Metadata that is only consistent with its
+//	bytes reversed was written in the byte order of a big endian host instead of little endian, and returns ErrByteOrderMismatch.
+func (mmcMap *MMCMap) validateMeta() error {
+	mMap := mmcMap.Data.Load().(mmap.MMap)
+	if uint64(len(mMap)) < mmcMap.HeaderSize + NewINodeSize { return errors.New("file too small to contain mmcmap metadata") }
+
+	meta, readMetaErr := mmcMap.ReadMetaFromMemMap()
+	if readMetaErr != nil { return readMetaErr }
+
+	size := uint64(len(mMap))
+	if ! meta.isInRange(mmcMap.HeaderSize, size) && meta.byteSwapped().isInRange(mmcMap.HeaderSize, size) { return ErrByteOrderMismatch }
+
+	switch {
+		case meta.RootOffset < mmcMap.HeaderSize || meta.RootOffset > meta.EndMmapOffset:
+			return errors.New("metadata root offset out of range")
+		case meta.EndMmapOffset >= uint64(len(mMap)):
+			return errors.New("metadata end offset exceeds file size")
+		default:
+			return nil
+	}
 }
\ No newline at end of file
@@ -1,14 +1,517 @@
 package mmcmap
 
+import "container/list"
+import "errors"
+import "io"
 import "os"
 import "sync"
 import "sync/atomic"
+import "time"
+
+import "github.com/sirgallo/mmcmap/common/mmap"
+import "google.golang.org/protobuf/proto"
 
 
 // MMCMapOpts initialize the MMCMap
 type MMCMapOpts struct {
 	// Filepath: the path to the memory mapped file
 	Filepath string
+	// OpenMode: whether the node pool and background go routines are started on Open or deferred until the first operation
+	OpenMode OpenMode
+	// Name: identifies the map in the open map registry, metrics, and logs. Defaults to the base name of the file
+	Name string
+	// Labels: arbitrary key-value labels attached to the map for metrics and diagnostics
+	Labels map[string]string
+	// ReplicaSource: for read replicas, where CatchUp requests missing deltas or a full snapshot when a gap is detected
+	ReplicaSource ReplicaSource
+	// FollowAddr: the address of a primary serving replication with ServeReplication. The map is opened as a read only follower that applies every
+	// version committed on the primary. Defaults ReplicaSource to a TCP source for the same address
+	FollowAddr string
+	// MergeFunc: combines the existing value of a key with an operand passed to Merge
+	MergeFunc MergeFunc
+	// OpenCheck: how thoroughly an existing file is validated on Open
+	OpenCheck OpenCheck
+	// OpenCheckBudget: the time limit for the sampled traversal of OpenCheckDeep. Defaults to DefaultOpenCheckBudget
+	OpenCheckBudget time.Duration
+	// Allocator: the allocation strategy for new files. Existing files use the strategy persisted in their header, and opening with a different
+	// strategy is an error
+	Allocator Allocator
+	// SingleWriter: route every commit through a single writer go routine instead of letting callers race to commit
+	SingleWriter bool
+	// WriteShards: partition the keyspace by the index of each key at the top level of the trie into this many write shards, each with its own
+	// version and root in the shard table of the file. Commits to the same shard copy their paths one at a time, while a commit to one shard copies
+	// its path from the root of the shard and is placed on whatever root other shards published meanwhile, so commits to different shards never
+	// retry against each other. At most MaxWriteShards, the fanout of the root. Existing files use the write shards persisted in their header, and
+	// opening one with a different nonzero number is an error. 0 disables sharding for new files
+	WriteShards int
+	// WriteQueueSize: the number of commits that can be queued for the writer go routine before callers block. Defaults to DefaultWriteQueueSize
+	WriteQueueSize int
+	// MaxUnflushedBytes: the number of bytes that can be written to the memory map ahead of the last flush before writes stall. 0 never stalls
+	MaxUnflushedBytes uint64
+	// StallPolicy: whether stalled writes wait for the flush go routine to catch up or fail with ErrWriteStall
+	StallPolicy StallPolicy
+	// StrictGet: return ErrKeyNotFound from Get for missing keys instead of a nil value
+	StrictGet bool
+	// CopyOnRead: whether Get and GetMulti copy values out of the memory map. Defaults to CopyAlways
+	CopyOnRead CopyPolicy
+	// CompactionThreshold: when the map is compacted automatically by the compaction go routine. Disabled unless MinSize is set
+	CompactionThreshold CompactionThreshold
+	// ExpirySweepInterval: how often the sweeper go routine removes expired keys. Defaults to DefaultExpirySweepInterval
+	ExpirySweepInterval time.Duration
+	// OverflowThreshold: values longer than this many bytes are stored in an overflow extent referenced from the leaf. 0 stores every value inline
+	OverflowThreshold int
+	// Compression: the codec values are compressed with when their leaves are written. Values that do not shrink are stored uncompressed
+	Compression Compression
+	// DirectSerialize: serialize path copies straight into the memory map once the commit has claimed its region, instead of into a buffer that is
+	// then copied. Halves the bytes moved for large paths, but the commit holds its claim on the version while the path is serialized
+	DirectSerialize bool
+	// MaxKeySize: the longest key that can be put, in bytes. Defaults to MaxKeyLength, the longest key the serialized format can hold
+	MaxKeySize int
+	// MaxValueSize: the longest value that can be put, in bytes. 0 does not limit values
+	MaxValueSize int64
+	// MaxFileSize: a hard cap on the size of the memory map, in bytes, rounded down to a whole page, or segment with segmented storage. Commits that
+	// would grow the map past it fail with ErrMapFull. 0 does not limit the size, unless the map is Ephemeral
+	MaxFileSize uint64
+	// Ephemeral: create the file with a unique name in EphemeralDir instead of at Filepath, and remove it on Close. Meant for caches that do not
+	// need durability, so the memory map is never flushed to disk and MaxFileSize defaults to DefaultEphemeralMaxSize. The base name of Filepath,
+	// if set, prefixes the name of the file
+	Ephemeral bool
+	// EphemeralDir: the directory ephemeral files are created in. Defaults to /dev/shm where it exists, so the file is backed by memory on tmpfs,
+	// and the temp directory otherwise
+	EphemeralDir string
+	// NodeEncoding: how nodes are serialized in new files. Existing files use the encoding persisted in their header, and opening a file with the
+	// fixed encoding as NodeEncodingCompact is an error
+	NodeEncoding NodeEncoding
+	// HashBits: the width of the hash keys are placed in the trie by in new files, HashBits32 or HashBits64. A 64 bit hash covers twice the
+	// levels before it is reseeded, so full hash collisions are rarer in maps with hundreds of millions of keys. Existing files use the width
+	// persisted in their header, and opening a file with a different width is an error. Defaults to HashBits32
+	HashBits uint8
+	// DeterministicHash: create new files with a hash seed of 0 instead of a random one, so the same keys are laid out identically in every file,
+	// as in files from before HashSeedFormatVersion. This gives up the protection of the seed against adversarial key sets
+	DeterministicHash bool
+	// KeyMode: how keys are placed in the trie in new files. KeyModeOrdered places keys by their raw bytes, so the trie is sorted and ranges are
+	// read without scanning the whole trie, at the cost of depth growing with key length. Existing files use the mode persisted in their header,
+	// and opening a file with a different mode is an error. Defaults to KeyModeHashed
+	KeyMode KeyMode
+	// NodeCacheSize: the approximate number of bytes of deserialized nodes kept in memory, keyed by offset, so hot nodes are not read from the
+	// memory map on every traversal. Get only uses internal nodes cached by other traversals, and does not cache the internal nodes it reads.
+	// 0 disables the node cache
+	NodeCacheSize uint64
+	// NodePoolSize: the number of nodes pre-allocated in the node pool, and the most it holds unless NodePoolAdaptive is set. Defaults to
+	// DefaultNodePoolSize
+	NodePoolSize int64
+	// NodePoolAdaptive: let the node pool grow, up to NodePoolMaxSize, while most node requests miss under contention, and shrink, down to
+	// MinNodePoolSize, while the map is idle
+	NodePoolAdaptive bool
+	// NodePoolMaxSize: with NodePoolAdaptive, the most nodes the node pool grows to hold. Defaults to DefaultNodePoolMaxSize
+	NodePoolMaxSize int64
+	// PinnedLevels: the number of levels of internal nodes, from the root of the latest version, kept deserialized in memory and refreshed on
+	// every commit, and used by every traversal including Get. 0 disables pinning
+	PinnedLevels int
+	// PrefetchScans: advise the kernel to read ahead the children of each internal node visited by Range, RangePage, Keys, and RangeChan, so page
+	// faults on files larger than memory overlap with the scan instead of stalling it
+	PrefetchScans bool
+	// LockMemory: whether the memory map is locked into RAM with mlock, so reads never wait on a page fault. Defaults to LockMemoryOff
+	LockMemory LockMemoryMode
+	// LockMemoryPrefix: with LockMemoryPrefix, the number of bytes from the start of the file to lock. Defaults to DefaultLockMemoryPrefix
+	LockMemoryPrefix uint64
+	// ReserveAddressSpace: the bytes of address space reserved for the memory map on Open, so it grows in place without being remapped until the
+	// file outgrows it. Open fails if it can not be reserved. Defaults to MinMapReservation, reserved on a best effort basis
+	ReserveAddressSpace uint64
+	// SegmentSize: store new files as segments of this many bytes instead of a single growing file. The file at Filepath is the first segment, and
+	// later segments are files next to it numbered from 1, like data.000001 for data. The memory map grows by adding a segment instead of truncating
+	// and remapping the file, and ReclaimSegments removes the oldest segments once they only hold prior versions. Must be a multiple of the page
+	// size of at least MinSegmentSize, and requires mapping at a fixed address, which is only implemented on Linux. Existing files use the size
+	// persisted in their header, and opening a file with a different size is an error. 0 stores the map in a single file
+	SegmentSize uint64
+	// HotSegments: with segmented storage, the number of newest segments kept writable. Older segments that are fully written are sealed as cold,
+	// mapped read only and left out of LockMemory, so resident memory follows the hot segments and cold pages are only faulted in by the reads
+	// that need them. Segments are sealed after the map grows and by SealSegments. 0 keeps every segment hot
+	HotSegments uint64
+	// ColdCompression: the codec cold segments are compressed with on disk, in a .cold file that replaces the segment file. Compressed segments are
+	// decompressed into memory when they are mapped, trading resident memory for disk space. Defaults to CompressionNone
+	ColdCompression Compression
+	// Logger: receives resizes, background failures, and other events worth surfacing. nil discards them
+	Logger Logger
+	// LogLevel: the least severe level passed to Logger. Defaults to LogInfo
+	LogLevel LogLevel
+	// RecordLatency: record the latency and commit retries of every Put, Get, Delete and Range in histograms reported by Stats
+	RecordLatency bool
+	// Tracer: starts spans around the commit, serialization, flush, and resize phases. nil disables tracing
+	Tracer Tracer
+}
+
+// CompactionThreshold determines when the compaction go routine compacts the map
+type CompactionThreshold struct {
+	// MinSize: the number of bytes of serialized data required before the map is considered for compaction. 0 disables automatic compaction
+	MinSize uint64
+	// LiveRatio: compact once the fraction of the serialized data reachable from the latest root drops below this ratio. Defaults to DefaultCompactionLiveRatio
+	LiveRatio float64
+	// Interval: how often the threshold is checked. Defaults to DefaultCompactionInterval
+	Interval time.Duration
+}
+
+// CompactionStats reports how often the map was compacted and how much space was reclaimed
+type CompactionStats struct {
+	// Count: the number of completed compactions
+	Count uint64
+	// Checks: the number of times the compaction go routine checked the threshold
+	Checks uint64
+	// Errors: the number of automatic compactions that failed
+	Errors uint64
+	// ReclaimedBytes: the total bytes of serialized data reclaimed
+	ReclaimedBytes uint64
+	// Total: the cumulative time spent compacting, during which commits were blocked
+	Total time.Duration
+	// IsPaused: flag indicating automatic compaction is paused
+	IsPaused bool
+}
+
+// StallPolicy determines how writes behave when MaxUnflushedBytes is exceeded
+type StallPolicy int
+
+// Compression identifies the codec a leaf value is compressed with
+type Compression uint8
+
+// NodeEncoding identifies how nodes are serialized in the memory map
+type NodeEncoding uint8
+
+// KeyMode identifies how keys are placed in the trie
+type KeyMode uint8
+
+// WriteFuture is the pending result of a commit submitted to the writer go routine
+type WriteFuture struct {
+	done chan struct{}
+	ok bool
+	version uint64
+	retries uint64
+	err error
+}
+
+// writeRequest is a commit queued for the writer go routine
+type writeRequest struct {
+	prepare func(root *MMCMapNode) ([]*BatchOp, error)
+	future *WriteFuture
+}
+
+// MergeFunc combines the existing value of a key, nil if the key is absent, with a merge operand to produce the new value
+type MergeFunc func(existing, operand []byte) []byte
+
+// OpenMode determines how much work is performed when the mmcmap is opened
+type OpenMode int
+
+// LogLevel is the severity of a log event
+type LogLevel int
+
+// Logger receives log events from the map, so output can be routed into the logging stack of the application
+type Logger interface {
+	// Log: record msg at level, along with alternating keys and values describing the event
+	Log(level LogLevel, msg string, keyvals ...interface{})
+}
+
+// Tracer starts spans around the internal phases of the map, so it can be adapted to OpenTelemetry or any other tracing stack
+type Tracer interface {
+	// Start: begin a span named name as a child of parent, or as a root span if parent is nil
+	Start(parent Span, name string) Span
+}
+
+// Span is a single traced phase, started by a Tracer
+type Span interface {
+	// SetAttribute: attach a key and value describing the phase
+	SetAttribute(key string, value interface{})
+	// End: finish the span, recording err if the phase failed
+	End(err error)
+}
+
+// hookRegistry holds the callbacks registered for each state transition, keyed by id so they can be removed
+type hookRegistry struct {
+	lock sync.RWMutex
+	nextID uint64
+	commitCount int64
+	commit map[uint64]func(version, rootOffset uint64)
+	resize map[uint64]func(newSize uint64)
+	flush map[uint64]func(version uint64)
+	compact map[uint64]func(report *CompactReport)
+}
+
+// noopSpan is the span started when no Tracer is set
+type noopSpan struct {}
+
+// LockMemoryMode determines how much of the memory map is locked into RAM
+type LockMemoryMode int
+
+// CopyPolicy determines whether values returned by reads are copied out of the memory map
+type CopyPolicy int
+
+// OpenCheck determines how thoroughly an existing file is validated on Open
+type OpenCheck int
+
+// OpenCheckReport holds the findings of the open check
+type OpenCheckReport struct {
+	// Check: the level of the check that was run
+	Check OpenCheck
+	// Findings: descriptions of every inconsistency found, capped at MaxOpenCheckFindings
+	Findings []string
+	// NodesVisited: the number of nodes validated by the deep traversal
+	NodesVisited uint64
+	// IsComplete: flag indicating the deep traversal reached every node before the budget ran out
+	IsComplete bool
+	// Duration: how long the check took
+	Duration time.Duration
+}
+
+// VerifyReport is the result of verifying the trie reachable from the latest root
+type VerifyReport struct {
+	// Version: the version that was verified
+	Version uint64
+	// Findings: descriptions of every inconsistency found, capped at MaxOpenCheckFindings
+	Findings []string
+	// NodesVisited: the number of nodes validated
+	NodesVisited uint64
+	// KeysVisited: the number of leaves found
+	KeysVisited uint64
+	// KeyCount: the key count in the header at the verified version
+	KeyCount uint64
+	// IsValid: flag indicating no inconsistencies were found
+	IsValid bool
+	// Duration: how long the verification took
+	Duration time.Duration
+}
+
+// MigrateReport is the result of migrating a file to the current format
+type MigrateReport struct {
+	// PrevFormatVersion: the format version of the file before the migration, 0 for files created before the header existed
+	PrevFormatVersion uint16
+	// FormatVersion: the format version of the file after the migration
+	FormatVersion uint16
+	// IsRelocated: flag indicating the trie was copied behind a new header, dropping prior versions
+	IsRelocated bool
+}
+
+// RepairReport is the result of repairing a map
+type RepairReport struct {
+	// PrevVersion: the latest version before the repair
+	PrevVersion uint64
+	// PrevRootOffset: the root offset of the latest version before the repair
+	PrevRootOffset uint64
+	// Version: the latest version after the repair
+	Version uint64
+	// RootOffset: the root offset of the latest version after the repair
+	RootOffset uint64
+	// EndMmapOffset: the end of the serialized data after the repair
+	EndMmapOffset uint64
+	// KeyCount: the number of keys in the latest version after the repair
+	KeyCount uint64
+	// DiscardedVersions: the number of damaged commits rolled back
+	DiscardedVersions uint64
+	// DiscardedBytes: the number of bytes zeroed after the end of the serialized data
+	DiscardedBytes uint64
+	// IsRepaired: flag indicating the metadata was rewritten. False if the latest version was already intact
+	IsRepaired bool
+}
+
+// OutOfRangeError is returned when an offset read from the file, or a write, falls outside of the memory map. It matches ErrOutOfRange with
+// errors.Is, and unwraps to Err
+type OutOfRangeError struct {
+	// Op: the access that was attempted
+	Op string
+	// Offset: the offset the access started at
+	Offset uint64
+	// Length: the number of bytes accessed
+	Length uint64
+	// Size: the size of the memory map at the time of the access
+	Size uint64
+	// Err: the underlying error, ErrCorruptNode for nodes read from the file, or nil
+	Err error
+}
+
+// FsckOpts are the optional parameters for FsckFile
+type FsckOpts struct {
+	// RecoverTo: if set, a compacted copy of the newest intact version is written to a new file at this path
+	RecoverTo string
+}
+
+// FsckReport is the result of checking every version of a file offline
+type FsckReport struct {
+	// MetaVersion: the latest version recorded in the metadata
+	MetaVersion uint64
+	// MetaRootOffset: the root offset recorded in the metadata
+	MetaRootOffset uint64
+	// HeaderFindings: inconsistencies found in the header and metadata
+	HeaderFindings []string
+	// Versions: the result of checking each version found in the file, oldest first
+	Versions []FsckVersion
+	// ValidVersions: the number of versions whose trie is intact
+	ValidVersions uint64
+	// CorruptVersions: the number of versions with at least one finding
+	CorruptVersions uint64
+	// LatestValidVersion: the newest version whose trie is intact, 0 if none are
+	LatestValidVersion uint64
+	// NodesVisited: the number of distinct nodes checked across every version
+	NodesVisited uint64
+	// ScannedBytes: the bytes of serialized data from the header to the last readable node
+	ScannedBytes uint64
+	// ReachableBytes: the bytes of serialized data reachable from the newest intact version
+	ReachableBytes uint64
+	// UnreadableBytes: the bytes between the last readable node and the end of the serialized data recorded in the metadata
+	UnreadableBytes uint64
+	// RecoveredPath: the path the newest intact version was copied to, empty if no copy was written
+	RecoveredPath string
+	// IsClean: flag indicating there are no findings, every version is intact, and the newest intact version is the one in the metadata
+	IsClean bool
+	// Duration: how long the check took
+	Duration time.Duration
+}
+
+// FsckVersion is the result of checking a single version of the trie
+type FsckVersion struct {
+	// Version: the version of the root
+	Version uint64
+	// RootOffset: the offset of the root of the version
+	RootOffset uint64
+	// EndOffset: the end of the serialized data once the version was committed
+	EndOffset uint64
+	// KeysVisited: the number of leaves reachable from the root
+	KeysVisited uint64
+	// ReachableBytes: the bytes of serialized data reachable from the root
+	ReachableBytes uint64
+	// Findings: descriptions of every inconsistency found, capped at MaxOpenCheckFindings
+	Findings []string
+	// IsValid: flag indicating no inconsistencies were found
+	IsValid bool
+}
+
+// CompactReport is the result of compacting a map
+type CompactReport struct {
+	// PrevVersion: the latest version before the compaction
+	PrevVersion uint64
+	// Version: the version committed by the compaction
+	Version uint64
+	// PrevEndMmapOffset: the end of the serialized data before the compaction
+	PrevEndMmapOffset uint64
+	// EndMmapOffset: the end of the serialized data after the compaction
+	EndMmapOffset uint64
+	// PrevFileSize: the size of the file before the compaction
+	PrevFileSize uint64
+	// FileSize: the size of the file after the compaction
+	FileSize uint64
+	// ReclaimedBytes: the number of bytes of serialized data used only by prior versions
+	ReclaimedBytes uint64
+	// NodesCopied: the number of nodes reachable from the latest root that were copied
+	NodesCopied uint64
+	// Duration: the time taken to compact the map
+	Duration time.Duration
+}
+
+// MMCMapHeader is the header written directly after the metadata. Files created before the header existed have the initial root at offset 24 instead
+type MMCMapHeader struct {
+	// FormatVersion: the version of the file format
+	FormatVersion uint16
+	// AllocatorID: the id of the allocation strategy the file was created with
+	AllocatorID AllocatorID
+	// NodeEncoding: how nodes are serialized in the file. Files before CompactNodeFormatVersion always use the fixed encoding
+	NodeEncoding NodeEncoding
+	// HashBits: the width of the hash keys are placed in the trie by. Files before HashBitsFormatVersion always use HashBits32
+	HashBits uint8
+	// HashSeed: the random seed generated when the file was created, added to the seed of every level of the hash, so a set of keys that
+	// degenerates the trie of one file does not degenerate any other. Files before HashSeedFormatVersion have a seed of 0
+	HashSeed uint64
+	// KeyMode: how keys are placed in the trie. Files before KeyModeFormatVersion always use KeyModeHashed
+	KeyMode KeyMode
+	// SegmentSize: the size of each segment of a file using segmented storage, 0 for a single file. Files before SegmentFormatVersion always use a
+	// single file
+	SegmentSize uint64
+	// WriteShards: the number of write shards, each with a slot in the shard table. Files before ShardFormatVersion have none
+	WriteShards uint8
+}
+
+// MMCMapSegments is the segment directory of a file using segmented storage, written directly after the header
+type MMCMapSegments struct {
+	// FirstSegment: the oldest segment after the first that has not been removed. Segments before it are mapped as zeroed pages
+	FirstSegment uint64
+	// LastSegment: the newest segment, which the memory map ends with
+	LastSegment uint64
+	// ScanOffset: the offset of the oldest node that was not removed, where scans for roots start instead of the initial root. 0 if no segment
+	// has been removed
+	ScanOffset uint64
+	// MinVersion: the oldest version that can be read, since earlier versions may reference nodes in removed segments
+	MinVersion uint64
+}
+
+// MMCMapSegmentFile is the file backing a segment after the first
+type MMCMapSegmentFile struct {
+	// File: the segment file, or the .cold file of a compressed segment. nil for removed segments
+	File *os.File
+	// IsCold: the segment is sealed and mapped read only, or decompressed into memory if it is compressed
+	IsCold bool
+	// IsCompressed: the segment is stored compressed in its .cold file
+	IsCompressed bool
+}
+
+// SegmentReport is the result of ReclaimSegments and SealSegments
+type SegmentReport struct {
+	// FirstSegment: the oldest segment after the first that is still on disk
+	FirstSegment uint64
+	// LastSegment: the newest segment
+	LastSegment uint64
+	// RemovedSegments: the number of segments removed
+	RemovedSegments uint64
+	// ReclaimedBytes: the disk space returned by the removed segments
+	ReclaimedBytes uint64
+	// MinVersion: the oldest version that can still be read
+	MinVersion uint64
+	// SealedSegments: the number of segments sealed as cold
+	SealedSegments uint64
+	// CompressedSegments: the number of sealed segments compressed with ColdCompression
+	CompressedSegments uint64
+}
+
+// AllocatorID identifies an allocation strategy in the file header
+type AllocatorID uint8
+
+// Allocator decides where serialized paths are placed in the memory map
+type Allocator interface {
+	// ID: the id of the strategy, persisted in the file header
+	ID() AllocatorID
+	// Place: the offset to write the next serialized path at, given the current end offset of the memory map
+	Place(endOffset uint64) uint64
+	// End: the end offset of the memory map once size bytes have been written at offset
+	End(endOffset, offset, size uint64) uint64
+}
+
+// AppendAllocator places every serialized path after the end of the memory map
+type AppendAllocator struct {}
+
+// FreeListAllocator places serialized paths in regions reclaimed by Reclaim when one is large enough, and otherwise appends them like the
+// AppendAllocator. Files created with it reserve space for the free list directly after the header
+type FreeListAllocator struct {}
+
+// FreeRegion is a reclaimed region of the memory map that paths can be written into
+type FreeRegion struct {
+	// Offset: the start of the region
+	Offset uint64
+	// Size: the number of bytes in the region
+	Size uint64
+}
+
+// PunchReport is the result of punching holes over the regions in the free list
+type PunchReport struct {
+	// Regions: the number of free regions large enough to contain at least one whole page
+	Regions int
+	// PunchedBytes: the number of bytes released to the filesystem
+	PunchedBytes uint64
+}
+
+// ReclaimReport is the result of reclaiming the regions of a map not reachable from the latest root
+type ReclaimReport struct {
+	// Version: the latest version at the time of the reclaim
+	Version uint64
+	// Regions: the number of regions in the free list after the reclaim
+	Regions int
+	// FreeBytes: the number of bytes in the free list after the reclaim
+	FreeBytes uint64
+	// DroppedBytes: the number of unreachable bytes not added to the free list, because the regions were too small or the free list was full
+	DroppedBytes uint64
 }
 
 // MMCMapMetaData contains information related to where the root is located in the mem map and the version.
@@ -39,46 +542,1112 @@ type MMCMapNode struct {
 	Key []byte
 	// Value: The value associated with a key, in byte array representation. Values are only stored within leaf nodes
 	Value []byte
+	// ExpiresAt: the unix time in nanoseconds a leaf expires at, after which reads treat the key as missing. 0 never expires
+	ExpiresAt int64
+	// IsOverflow: flag indicating the value of a leaf is stored in an overflow extent instead of inline
+	IsOverflow bool
+	// OverflowOffset: the offset of the overflow extent holding the value of the leaf, or 0 if the extent has not been written yet
+	OverflowOffset uint64
+	// Compression: the codec the value of the leaf is compressed with when serialized, or CompressionNone if it is stored as is
+	Compression Compression
+	// EncodedValue: the value as serialized, prefixed by the codec, once it has been compressed. Cached so the value is only compressed once
+	EncodedValue []byte
 	// Children: an array of child nodes, which are MMCMapNodes. Location in the array is determined by the sparse index
 	Children []*MMCMapNode
 }
 
 // MMCMap contains the memory mapped buffer for the mmcmap, as well as all metadata for operations to occur
 type MMCMap struct {
-	// HashChunks: the total chunks of the 32 bit hash determining the levels within the hash array mapped trie
+	// HashChunks: the total chunks of the hash determining the levels within the hash array mapped trie before it is reseeded
 	HashChunks int
-	// BitChunkSize: the size of each chunk in the 32 bit hash. Since a 32 bit hash is 2^5, each chunk will be 5 bits long
+	// BitChunkSize: the size of each chunk in the hash. Since a bitmap holds 32 = 2^5 children, each chunk will be 5 bits long
 	BitChunkSize int
+	// Opts: the options the map was opened with
+	Opts MMCMapOpts
+	// Header: the file header. Legacy files are treated as using the append allocator
+	Header MMCMapHeader
+	// HeaderSize: the offset of the initial root, which is InitRootOffset or LegacyInitRootOffset for files created before the header existed
+	HeaderSize uint64
+	// Allocator: the allocation strategy resolved from the file header
+	Allocator Allocator
+	// OpenReport: the findings of the open check
+	OpenReport *OpenCheckReport
 	// Filepath: path to the MMCMap file
 	Filepath string
 	// File: the MMCMap file
 	File *os.File
-	// Opened: flag indicating if the file has been opened
-	Opened bool
+	// Opened: atomic flag indicating if the file is open. Cleared by Close before the memory map is released
+	Opened uint32
+	// OwnerPID: the id of the process that mapped the file. Used to detect handles inherited across a fork
+	OwnerPID int
+	// IsDetached: atomic flag indicating the handle has released its file, memory map, and go routines
+	IsDetached uint32
+	// StartOnce: ensures the node pool and background go routines are started exactly once, either on Open or on first use
+	StartOnce sync.Once
+	// RoutinesLock: guards Routines and BackgroundTasks
+	RoutinesLock sync.Mutex
+	// Routines: the number of running go routines started for the map, keyed by the task they perform
+	Routines map[string]int
+	// BackgroundTasks: the tasks of the long lived go routines started with the handle, which Ping expects to be running
+	BackgroundTasks []string
 	// Data: the memory mapped file as a byte slice
 	Data atomic.Value
+	// Reservation: the address space the file is mapped at the start of, so the memory map grows in place on resize. nil if the platform can not
+	// map at a fixed address. Guarded by RWResizeLock
+	Reservation mmap.MMap
+	// Segments: the *MMCMapSegments directory of a file using segmented storage, replaced on every change. Guarded by RWResizeLock
+	Segments atomic.Value
+	// SegmentFiles: the []MMCMapSegmentFile of every segment after the first, indexed by segment number. Replaced on every change. Guarded by
+	// RWResizeLock
+	SegmentFiles atomic.Value
+	// SealLock: held while cold segments are sealed, so only one seal runs at a time, and by snapshots and truncating clears, which rewrite the segments
+	SealLock sync.Mutex
 	// IsResizing: atomic flag to determine if the mem map is being resized or not
 	IsResizing uint32
+	// IsRemapping: atomic flag indicating a resize is replacing the memory map instead of growing it in place, so operations wait for it to finish
+	IsRemapping uint32
 	// SignalResize: send a signal to the resize go routine with the offset for resizing
 	SignalResize chan bool
 	// SignalFlush: send a signal to flush to disk on writes to avoid contention
 	SignalFlush chan bool
+	// WriteQueue: commits waiting for the writer go routine when SingleWriter is set
+	WriteQueue chan *writeRequest
+	// UnflushedBytes: the number of bytes committed to the memory map since the last flush
+	UnflushedBytes uint64
+	// FlushLock: held for the whole of each flush, so the flush go routine and calls to Wait never remove the same unflushed bytes twice
+	FlushLock sync.Mutex
+	// FlushCond: broadcast by the flush go routine after each flush, waking writes stalled on MaxUnflushedBytes and calls to Wait
+	FlushCond *sync.Cond
+	// FlushedVersion: the latest version known to be on disk, advanced after each flush. Guarded by FlushCond.L
+	FlushedVersion uint64
+	// IsWaitFlushing: whether a call to Wait is flushing the files, so other calls wait for its flush instead of starting their own. Guarded by
+	// FlushCond.L
+	IsWaitFlushing bool
+	// WriteStalls: the number of writes that were stalled by MaxUnflushedBytes
+	WriteStalls uint64
 	// ReadResizeLock: A Read-Write mutex for locking reads on resize operations
 	RWResizeLock sync.RWMutex
+	// ShardLanes: the lane of each write shard, held by commits whose ops all fall in the shard. Empty unless the file has write shards
+	ShardLanes []sync.Mutex
+	// ShardWaits: the number of commits that waited for the lane of their write shard
+	ShardWaits uint64
+	// ReadEpoch: the epoch new reads register in. Advanced before a replaced memory map is unmapped, once the reads of the previous epoch finish
+	ReadEpoch uint64
+	// EpochReaders: the number of reads in flight without the resize lock, indexed by the parity of the epoch they registered in
+	EpochReaders [2]int64
+	// ExclusiveHolders: the number of callers holding or waiting for the resize lock exclusively, during which reads take the resize read lock
+	ExclusiveHolders uint32
+	// LockedReads: the number of reads that took the resize read lock because the resize lock was held exclusively
+	LockedReads uint64
+	// CommitGate: held for reading by every commit attempt and for writing while the map is quiesced
+	CommitGate sync.RWMutex
+	// RelocateLock: held for reading by traversals pinned to a root across several reads within a single call, and for writing by Compact and Reclaim,
+	// which move or reuse nodes. Traversals held open by the caller count a relocate pin instead
+	RelocateLock sync.RWMutex
+	// RelocateCond: broadcast once the last relocate pin is released, waking relocations waiting to take the relocate lock
+	RelocateCond *sync.Cond
+	// RelocatePins: the number of traversals held open by callers, such as streamed ranges, that Compact and Reclaim wait for before taking the
+	// relocate lock. Guarded by RelocateCond.L
+	RelocatePins int
+	// RelocateGeneration: advanced by Compact, Reclaim, ReclaimSegments, and truncating Clears while the relocate lock is held, so snapshots taken at
+	// an earlier generation know their root offset may no longer point at their root
+	RelocateGeneration uint64
+	// PinLock: protects ZeroCopyPins and RetiredMaps
+	PinLock sync.Mutex
+	// ZeroCopyPins: the number of values returned by GetZeroCopy that have not been released
+	ZeroCopyPins int
+	// RetiredMaps: memory maps replaced while zero copy values were pinned, kept mapped until the last pin is released
+	RetiredMaps []mmap.MMap
+	// StopCompaction: closed to stop the compaction go routine
+	StopCompaction chan struct{}
+	// StopFollow: closed to stop the follower go routine
+	StopFollow chan struct{}
+	// StopSweep: closed to stop the sweeper go routine
+	StopSweep chan struct{}
+	// Expiry: the expiry wheel scheduling keys put with a ttl, consulted by the sweeper go routine. Persisted next to the file on Close
+	Expiry *ExpiryWheel
+	// IsExpiryFormat: atomic flag indicating the file format allows leaves with an expiration
+	IsExpiryFormat uint32
+	// ExpiredKeys: the number of expired keys removed by the sweeper go routine
+	ExpiredKeys uint64
+	// SweepErrors: the number of sweeps that failed to remove expired keys
+	SweepErrors uint64
+	// IsCompactionPaused: atomic flag indicating the compaction go routine should not compact the map
+	IsCompactionPaused uint32
+	// CompactionCount: the number of completed compactions
+	CompactionCount uint64
+	// CompactionChecks: the number of times the compaction go routine checked the threshold
+	CompactionChecks uint64
+	// CompactionErrors: the number of automatic compactions that failed
+	CompactionErrors uint64
+	// CompactionReclaimedBytes: the total bytes of serialized data reclaimed by compaction
+	CompactionReclaimedBytes uint64
+	// CompactionNanos: the cumulative time, in nanoseconds, spent compacting
+	CompactionNanos int64
+	// MetaLock: held while a commit publishes its root offset and key count, and by Len while reading the count, so the count always matches the root
+	MetaLock sync.RWMutex
+	// WatchLock: guards Watchers, and is held by commits while publishing, so every watcher receives events in version order
+	WatchLock sync.Mutex
+	// Watchers: the active watchers, keyed by id
+	Watchers map[uint64]*watcher
+	// WatcherCount: atomic count of active watchers, so commits only build change events while something is watching
+	WatcherCount int64
+	// NextWatcherID: the id assigned to the next watcher
+	NextWatcherID uint64
+	// QuiesceCount: the total number of times the map has been quiesced
+	QuiesceCount uint64
+	// ReplicaDeltas: the number of replication deltas applied to the map
+	ReplicaDeltas uint64
+	// ReplicaSnapshots: the number of replication snapshots applied to the map
+	ReplicaSnapshots uint64
+	// ReplicaGaps: the number of version gaps detected while catching up
+	ReplicaGaps uint64
+	// FollowErrors: the number of times the follower go routine lost its connection to the primary
+	FollowErrors uint64
+	// QuiescedNanos: the cumulative time, in nanoseconds, that commits have been blocked by Quiesce
+	QuiescedNanos int64
 	// NodePool: the sync.Pool for recycling nodes so nodes are not constantly allocated/deallocated
 	NodePool *MMCMapNodePool
+	// NodeCache: the least recently used deserialized nodes, keyed by offset. nil if NodeCacheSize is not set
+	NodeCache *NodeCache
+	// LockedBytes: the number of bytes of the current memory map locked into RAM
+	LockedBytes uint64
+	// LockFallbacks: the number of times less of the memory map could be locked than LockMemory requested
+	LockFallbacks uint64
+	// PinnedLock: serializes refreshes of the pinned levels
+	PinnedLock sync.Mutex
+	// Pinned: the *pinnedNodes holding the top levels of the trie. Replaced, never modified, so readers load it without locking
+	Pinned atomic.Value
+	// Puts: the number of puts committed, including those in batches and transactions
+	Puts uint64
+	// Gets: the number of keys looked up by Get, GetMulti, GetZeroCopy and GetReader
+	Gets uint64
+	// Deletes: the number of keys removed by committed deletes, including those in batches and transactions
+	Deletes uint64
+	// CommitRetries: the number of commits retried because another commit published a new version, or a resize was in progress, first
+	CommitRetries uint64
+	// CommitRebases: the number of path copies rebased onto a version another commit published while they were being copied, instead of retried
+	CommitRebases uint64
+	// Resizes: the number of times the memory map was grown, in place or by remapping
+	Resizes uint64
+	// Flushes: the number of completed flushes of the memory map to disk
+	Flushes uint64
+	// Prefetches: the number of read ahead hints issued for the children of nodes visited by scans, with PrefetchScans
+	Prefetches uint64
+	// StatsLock: guards StatsVersion and StatsLiveBytes
+	StatsLock sync.Mutex
+	// StatsVersion: the version StatsLiveBytes was measured at
+	StatsVersion uint64
+	// StatsLiveBytes: the bytes of serialized nodes reachable from the root at StatsVersion, so Stats only traverses the trie when it changed
+	StatsLiveBytes uint64
+	// PutLatency: the latencies of Put and PutVersioned, recorded with RecordLatency
+	PutLatency LatencyHistogram
+	// GetLatency: the latencies of Get, recorded with RecordLatency
+	GetLatency LatencyHistogram
+	// DeleteLatency: the latencies of Delete and DeleteVersioned, recorded with RecordLatency
+	DeleteLatency LatencyHistogram
+	// RangeLatency: the latencies of Range, RangePage and Keys, recorded with RecordLatency
+	RangeLatency LatencyHistogram
+	// Hooks: the callbacks registered with OnCommit, OnResize, OnFlush and OnCompact
+	Hooks hookRegistry
+}
+
+// KeyValuePair is a key and its value, as returned by range operations. Both slices are copied out of the memory map
+type KeyValuePair struct {
+	// Version: the version of the mmcmap the pair was written at
+	Version uint64
+	// Key: the key in byte array representation
+	Key []byte
+	// Value: the value associated with the key in byte array representation
+	Value []byte
+}
+
+// RangeOpts bounds and orders the results of a range
+type RangeOpts struct {
+	// Limit: the max number of pairs returned. 0 returns every pair in the range
+	Limit int
+	// Offset: the number of pairs to skip before results are returned
+	Offset int
+	// Reverse: return pairs in descending key order instead of ascending
+	Reverse bool
+	// After: continuation token from a previous page. Only keys strictly after it, in the direction of the range, are returned
+	After []byte
+	// KeysOnly: skip copying values out of the memory map. Returned pairs have a nil value
+	KeysOnly bool
+}
+
+// JSONOpts are the optional parameters for ExportJSON and ImportJSON
+type JSONOpts struct {
+	// Encoding: how keys and values are encoded as JSON strings. Defaults to EncodingBase64
+	Encoding KeyEncoding
+	// BatchSize: the number of pairs ImportJSON commits per version. 0 commits every pair as a single version
+	BatchSize int
+}
+
+// KeyEncoding determines how keys and values are encoded as strings
+type KeyEncoding int
+
+// DumpOpts are the optional parameters for Dump
+type DumpOpts struct {
+	// Format: whether the trie is written as newline delimited JSON or a Graphviz DOT graph. Defaults to DumpJSON
+	Format DumpFormat
+	// Version: the version of the trie to dump. 0 dumps the latest version. Earlier versions are located the same way as Diff
+	Version uint64
+	// MaxDepth: the deepest level of nodes written, where the root is at depth 0. 0 writes every level
+	MaxDepth int
+	// PreviewBytes: the number of leading bytes of each key and value shown. Defaults to DefaultDumpPreview, and a negative number hides them
+	PreviewBytes int
+}
+
+// DumpFormat determines how Dump writes the trie
+type DumpFormat int
+
+// DumpNode is a single node as written by Dump with DumpJSON. Keys and values are previews, written as text if printable and as 0x prefixed hex
+// otherwise, with ... appended if they were truncated
+type DumpNode struct {
+	// Offset: the offset the node is serialized at
+	Offset uint64 `json:"offset"`
+	// EndOffset: the offset of the last byte of the serialized node
+	EndOffset uint64 `json:"endOffset"`
+	// Version: the version the node was written at
+	Version uint64 `json:"version"`
+	// Depth: the level of the node, where the root is at depth 0
+	Depth int `json:"depth"`
+	// IsLeaf: flag indicating the node is a leaf
+	IsLeaf bool `json:"leaf"`
+	// Bitmap: the bitmap of an internal node, as 32 binary digits
+	Bitmap string `json:"bitmap,omitempty"`
+	// Children: the offsets of the children of an internal node, in the order of the bitmap
+	Children []uint64 `json:"children,omitempty"`
+	// Key: a preview of the key of a leaf
+	Key string `json:"key,omitempty"`
+	// KeyLength: the length of the key of a leaf
+	KeyLength int `json:"keyLength,omitempty"`
+	// Value: a preview of the value of a leaf
+	Value string `json:"value,omitempty"`
+	// ValueLength: the length of the value of a leaf
+	ValueLength int `json:"valueLength,omitempty"`
+	// ExpiresAt: the unix time in nanoseconds the leaf expires at, omitted if it never expires
+	ExpiresAt int64 `json:"expiresAt,omitempty"`
+	// IsOverflow: flag indicating the value of the leaf is stored in an overflow extent
+	IsOverflow bool `json:"overflow,omitempty"`
+}
+
+// KeyDiff is the change to a single key between two versions. Values are copied out of the memory map
+type KeyDiff struct {
+	// Key: the key that differs
+	Key []byte
+	// OldValue: the value at the first version, nil if the key was added
+	OldValue []byte
+	// NewValue: the value at the second version, nil if the key was removed
+	NewValue []byte
+	// Kind: whether the key was added, removed, or modified
+	Kind DiffKind
+}
+
+// DiffKind is the kind of change described by a KeyDiff
+type DiffKind int
+
+// ChangeEvent is a committed put or delete published to watchers. Key and Value are copies owned by the receiver
+type ChangeEvent struct {
+	// Key: the key that changed
+	Key []byte
+	// Value: the new value for puts, nil for deletes
+	Value []byte
+	// Version: the version the change was committed at
+	Version uint64
+	// Op: whether the key was put or deleted
+	Op ChangeOp
+}
+
+// ChangeOp is the kind of mutation described by a ChangeEvent
+type ChangeOp int
+
+// CancelFunc stops a watch, closing its channel
+type CancelFunc func()
+
+// watcher queues the change events for a single Watch, handing them to the channel from its own go routine so commits never block on a slow receiver
+type watcher struct {
+	prefix []byte
+	events chan ChangeEvent
+	mutex sync.Mutex
+	cond *sync.Cond
+	pending []ChangeEvent
+	done chan struct{}
+	stopped bool
+}
+
+// ClearOpts are the optional parameters for Clear
+type ClearOpts struct {
+	// Truncate: shrink the file back to the size of a newly created file, discarding every prior version
+	Truncate bool
+}
+
+// DigestBucket summarizes every key sharing a single leading byte
+type DigestBucket struct {
+	// Count: the total keys in the bucket
+	Count uint64
+	// Hash: the wrapping sum of the 64 bit key hashes in the bucket, so the result does not depend on traversal order
+	Hash uint64
+}
+
+// KeyDigest is a compact, order independent summary of every key in the mmcmap at a single version
+type KeyDigest struct {
+	// Version: the version of the mmcmap the digest was computed from
+	Version uint64
+	// Buckets: one bucket per leading key byte
+	Buckets [DigestBuckets]DigestBucket
+}
+
+// OpenMapInfo describes an open mmcmap in the registry, for diagnostics
+type OpenMapInfo struct {
+	// Name: the name of the map
+	Name string
+	// Labels: the labels attached to the map
+	Labels map[string]string
+	// Filepath: the path to the memory mapped file
+	Filepath string
+	// Version: the latest committed version of the map
+	Version uint64
+	// FileSize: the size of the memory mapped file in bytes
+	FileSize int
+	// IsDetached: whether the handle is currently detached
+	IsDetached bool
+}
+
+// QuiesceStats reports how often and for how long commits were blocked by Quiesce
+type QuiesceStats struct {
+	// Count: the total number of completed quiesces
+	Count uint64
+	// Total: the cumulative time commits were blocked
+	Total time.Duration
+}
+
+// BatchOp is a single buffered mutation within a WriteBatch
+type BatchOp struct {
+	// Key: the key to mutate
+	Key []byte
+	// Value: the value to put. Ignored for deletes
+	Value []byte
+	// IsDelete: flag indicating if the mutation removes the key instead of putting the value
+	IsDelete bool
+	// ExpiresAt: the unix time in nanoseconds the put key expires at. 0 never expires
+	ExpiresAt int64
+	// leafOffset: the offset of a leaf for the key already streamed to the memory map, referenced by the put instead of Value. 0 if there is none
+	leafOffset uint64
+}
+
+// WriteBatch buffers puts and deletes so that they can be committed to the mmcmap as a single new version
+type WriteBatch struct {
+	// Map: the mmcmap the batch is committed to
+	Map *MMCMap
+	// Ops: the buffered mutations, in the order they were added to the batch
+	Ops []*BatchOp
+	// Overlay: the latest buffered mutation for each key, consulted by Get before the trie is read
+	Overlay map[string]*BatchOp
+}
+
+// Txn is an atomic multi-key transaction. Reads are served from the version the transaction began at, mutations are buffered,
+// and everything is committed as a single new version only if no key the transaction read has changed
+type Txn struct {
+	// Batch: the buffered mutations of the transaction
+	Batch *WriteBatch
+	// RootOffset: the offset of the root the transaction began at. Reads of untouched keys are served from this snapshot
+	RootOffset uint64
+	// Generation: the relocate generation the transaction began at. Reads from the snapshot fail once it no longer matches the map
+	Generation uint64
+	// ReadSet: the value observed for every key read from the snapshot, validated against the latest version on commit
+	ReadSet map[string][]byte
+	// IsDone: flag indicating the transaction has been committed or rolled back
+	IsDone bool
+}
+
+// Coordinator commits write batches spanning multiple mmcmaps so that either every batch is applied or none are, even across a crash
+type Coordinator struct {
+	// IntentPath: the path to the shared intent record. It only exists while a multi-map commit is in progress
+	IntentPath string
+}
+
+// CommitIntent is the intent record written before a multi-map commit, holding the root of every participating map before the commit
+type CommitIntent struct {
+	// Entries: the state of each participating map prior to the commit
+	Entries []*IntentEntry
+}
+
+// IntentEntry is the state of a single map participating in a multi-map commit
+type IntentEntry struct {
+	// Filepath: the path to the participating mmcmap
+	Filepath string
+	// Version: the version of the map before the commit
+	Version uint64
+	// RootOffset: the offset of the root of the map before the commit. Recovery restores this root if the commit did not complete
+	RootOffset uint64
+	// KeyCount: the number of keys in the map before the commit, restored along with the root
+	KeyCount uint64
+}
+
+// ValueReader streams the value of a key out of the memory map without copying the whole value
+type ValueReader struct {
+	// mmcMap: the map the value is read from
+	mmcMap *MMCMap
+	// offset: the offset in the memory map of the next byte of the value to read
+	offset uint64
+	// remaining: the number of bytes of the value left to read
+	remaining uint64
+	// size: the length of the value
+	size int64
+	// decompressed: the value of a compressed leaf, decompressed when the reader was opened. nil if the value is read from the memory map
+	decompressed []byte
+	// isClosed: flag indicating the reader has been closed and released its pin on the map
+	isClosed bool
+}
+
+// ExpiryWheel is a hierarchical timing wheel indexing keys by expiration time, so expired keys can be found without scanning the keyspace
+type ExpiryWheel struct {
+	// Tick: the resolution of the wheel. Expiration times are rounded up to the next tick
+	Tick time.Duration
+	// Current: the last tick the wheel was advanced to, in ticks since the unix epoch
+	Current uint64
+	// Levels: the slots of each level of the wheel. A slot on level l covers WheelSlots^l ticks
+	Levels [WheelLevels][WheelSlots][]WheelEntry
+	// Overflow: entries expiring further out than the wheel covers, redistributed each full rotation of the top level
+	Overflow []WheelEntry
+	// Index: the scheduled expiration tick of each key. Entries in slots that no longer match the index are stale and skipped
+	Index map[string]uint64
+	// Lock: guards the wheel
+	Lock sync.Mutex
+}
+
+// WheelEntry is a key scheduled in the expiry wheel
+type WheelEntry struct {
+	// Key: the key to expire
+	Key string
+	// Expire: the tick the key expires at
+	Expire uint64
+}
+
+// ReplicaSource provides replication streams from a primary mmcmap to a replica
+type ReplicaSource interface {
+	// DeltaSince returns a delta stream containing every version after the given version
+	DeltaSince(version uint64) (io.Reader, error)
+	// Snapshot returns a snapshot stream of the latest version
+	Snapshot() (io.Reader, error)
+}
+
+// ReplicaDelta is a replication stream, holding the bytes appended to the primary memory map between two versions.
+// Since the memory map is append only and nodes reference each other by absolute offset, a replica that shares the prefix of the primary applies a
+// delta by copying the bytes to the same offsets and adopting the metadata of the primary
+type ReplicaDelta struct {
+	// IsSnapshot: flag indicating the stream holds the whole trie instead of the versions after FromVersion
+	IsSnapshot bool
+	// FromVersion: the version the delta applies on top of
+	FromVersion uint64
+	// ToVersion: the version of the primary once the delta is applied
+	ToVersion uint64
+	// StartOffset: the offset in the memory map of the first byte of Data
+	StartOffset uint64
+	// RootOffset: the root offset of the primary at ToVersion
+	RootOffset uint64
+	// EndMmapOffset: the end offset of the primary at ToVersion
+	EndMmapOffset uint64
+	// KeyCount: the number of keys in the primary at ToVersion
+	KeyCount uint64
+	// HashSeed: the hash seed of the primary. A replica with a different seed only applies snapshots, unless it is still at version 0, in which
+	// case it adopts the seed
+	HashSeed uint64
+	// KeyMode: the key mode of the primary, adopted by a replica at version 0 in the same way as the hash seed
+	KeyMode KeyMode
+	// SegmentSize: the segment size of the primary. Segmented files write their segment directory before the initial root, so a replica only
+	// applies streams from a primary with the same segment size
+	SegmentSize uint64
+	// Data: the serialized nodes
+	Data []byte
+}
+
+// ReplicaStatus is the replication state of a replica
+type ReplicaStatus struct {
+	// Version: the latest version applied to the replica
+	Version uint64
+	// RootOffset: the root offset at the latest version
+	RootOffset uint64
+	// EndMmapOffset: the end offset at the latest version
+	EndMmapOffset uint64
+	// Deltas: the number of deltas applied
+	Deltas uint64
+	// Snapshots: the number of snapshots applied
+	Snapshots uint64
+	// Gaps: the number of version gaps detected
+	Gaps uint64
+	// FollowErrors: the number of times a follower lost its connection to the primary
+	FollowErrors uint64
+}
+
+// ReplicationOpts are the optional parameters for ServeReplication
+type ReplicationOpts struct {
+	// PollInterval: how often the version is checked for new commits to stream to followers. Defaults to DefaultReplicationPollInterval
+	PollInterval time.Duration
+}
+
+// Backup is a backup stream read by ReadBackup, holding the keys put or deleted between two versions
+type Backup struct {
+	// FromVersion: the version the backup applies on top of. 0 for a full backup
+	FromVersion uint64
+	// ToVersion: the latest version of the map when the backup was written
+	ToVersion uint64
+	// Ops: the puts and deletes in the backup
+	Ops []*BatchOp
+}
+
+// BackupReport is the result of writing a backup
+type BackupReport struct {
+	// FromVersion: the version the backup applies on top of. 0 for a full backup
+	FromVersion uint64
+	// ToVersion: the latest version of the map when the backup was written, which the next incremental backup starts from
+	ToVersion uint64
+	// Puts: the number of keys put after FromVersion
+	Puts uint64
+	// Deletes: the number of keys deleted after FromVersion
+	Deletes uint64
+}
+
+// NodeCache is a bounded least recently used cache of deserialized nodes, keyed by their offset in the memory map
+type NodeCache struct {
+	// MaxBytes: the approximate number of bytes of nodes the cache holds before evicting the least recently used
+	MaxBytes uint64
+	// Bytes: the approximate number of bytes of nodes in the cache
+	Bytes uint64
+	// Lock: guards Entries and Order
+	Lock sync.Mutex
+	// Entries: the elements of Order, keyed by node offset
+	Entries map[uint64]*list.Element
+	// Order: the cached nodes, most recently used first
+	Order *list.List
+	// Hits: the number of reads served from the cache
+	Hits uint64
+	// Misses: the number of reads deserialized from the memory map
+	Misses uint64
+	// Evictions: the number of nodes evicted to stay within MaxBytes
+	Evictions uint64
+	// Purges: the number of times the cache was emptied because the memory map was rewritten or remapped
+	Purges uint64
+}
+
+// MemoryLockStats reports how much of the memory map is locked into RAM
+type MemoryLockStats struct {
+	// Mode: the lock memory mode the map was opened with
+	Mode LockMemoryMode
+	// LockedBytes: the number of bytes of the current memory map locked into RAM
+	LockedBytes uint64
+	// Fallbacks: the number of times less of the memory map could be locked than requested, usually because of RLIMIT_MEMLOCK
+	Fallbacks uint64
+}
+
+// NodeCacheStats reports how effective the node cache is
+type NodeCacheStats struct {
+	// Hits: the number of reads served from the cache
+	Hits uint64
+	// Misses: the number of reads deserialized from the memory map
+	Misses uint64
+	// Evictions: the number of nodes evicted to stay within the memory budget
+	Evictions uint64
+	// Purges: the number of times the cache was emptied
+	Purges uint64
+	// Entries: the number of nodes in the cache
+	Entries int
+	// Bytes: the approximate number of bytes of nodes in the cache
+	Bytes uint64
+	// Pinned: the number of nodes in the pinned levels
+	Pinned int
+}
+
+// MMCMapStats is a snapshot of the counters and gauges of an mmcmap, for monitoring. Counters are cumulative since the map was opened
+type MMCMapStats struct {
+	// Puts: the number of puts committed
+	Puts uint64
+	// Gets: the number of keys looked up
+	Gets uint64
+	// Deletes: the number of keys removed by committed deletes
+	Deletes uint64
+	// CommitRetries: the number of commit attempts that had to be retried
+	CommitRetries uint64
+	// CommitRebases: the number of commit attempts rebased onto a newer version instead of retried
+	CommitRebases uint64
+	// ShardWaits: the number of commits that waited for the lane of their write shard
+	ShardWaits uint64
+	// LockedReads: the number of reads that took the resize read lock because the resize lock was held exclusively
+	LockedReads uint64
+	// Resizes: the number of times the memory map was grown
+	Resizes uint64
+	// Flushes: the number of completed flushes to disk
+	Flushes uint64
+	// Prefetches: the number of read ahead hints issued by scans with PrefetchScans
+	Prefetches uint64
+	// FileSize: the size of the file in bytes
+	FileSize uint64
+	// LiveKeys: the number of keys in the latest version
+	LiveKeys uint64
+	// DataBytes: the bytes of serialized data written after the header
+	DataBytes uint64
+	// DeadBytes: the bytes of serialized data only reachable from prior versions, which Compact would reclaim
+	DeadBytes uint64
+	// NodePoolHits: the number of nodes taken from the node pool
+	NodePoolHits uint64
+	// NodePoolMisses: the number of nodes requested while the node pool was empty
+	NodePoolMisses uint64
+	// NodePoolHitRate: the fraction of node requests served by the node pool, 0 if none were made
+	NodePoolHitRate float64
+	// NodePoolSize: the most nodes the node pool currently holds
+	NodePoolSize int64
+	// NodePoolGrows: the number of times the adaptive node pool grew
+	NodePoolGrows uint64
+	// NodePoolShrinks: the number of times the adaptive node pool shrank
+	NodePoolShrinks uint64
+	// NodeCache: the hits, misses and size of the node cache
+	NodeCache NodeCacheStats
+	// NodeCacheHitRate: the fraction of node reads served by the node cache, 0 if none were made
+	NodeCacheHitRate float64
+	// Latency: the latency summaries of each operation, empty unless the map was opened with RecordLatency
+	Latency LatencyStats
+}
+
+// LatencyHistogram counts operation latencies in buckets that double in width, updated atomically so recording never blocks
+type LatencyHistogram struct {
+	// Buckets: the number of operations by latency. Bucket 0 holds latencies under 1ns, bucket i those from 2^(i-1)ns up to 2^i ns, and the last
+	// bucket everything longer
+	Buckets [LatencyBuckets]uint64
+	// Count: the number of operations recorded
+	Count uint64
+	// TotalNanos: the sum of the recorded latencies, in nanoseconds
+	TotalNanos uint64
+	// MaxNanos: the longest recorded latency, in nanoseconds
+	MaxNanos uint64
+	// Retries: the number of commit attempts the recorded operations retried
+	Retries uint64
+}
+
+// OpLatency summarizes the latencies recorded for a single operation. Quantiles are interpolated within their bucket, so they are estimates
+type OpLatency struct {
+	// Count: the number of operations recorded
+	Count uint64
+	// Retries: the number of commit attempts the operations retried, always 0 for reads
+	Retries uint64
+	// Total: the sum of the latencies
+	Total time.Duration
+	// Mean: the mean latency
+	Mean time.Duration
+	// P50: the estimated median latency
+	P50 time.Duration
+	// P99: the estimated 99th percentile latency
+	P99 time.Duration
+	// Max: the longest latency
+	Max time.Duration
+}
+
+// LatencyStats holds the latency summary of each recorded operation
+type LatencyStats struct {
+	// Put: Put and PutVersioned
+	Put OpLatency
+	// Get: Get
+	Get OpLatency
+	// Delete: Delete and DeleteVersioned
+	Delete OpLatency
+	// Range: Range, RangePage and Keys
+	Range OpLatency
 }
 
 // MMCMapNodePool contains pre-allocated mmcmap nodes to improve performance so go garbage collection doesn't handle allocating/deallocating nodes on every op
 type MMCMapNodePool struct {
-	// MaxSize: the max size for the node pool
+	// MaxSize: the max size for the node pool. Adjusted atomically while the pool is adaptive
 	MaxSize int64
 	// Size: the current number of allocated nodes in the node pool
 	Size int64
 	// Pool: the node pool that contains pre-allocated nodes
 	Pool *sync.Pool
+	// Hits: the number of nodes taken from the pre-allocated nodes in the pool
+	Hits uint64
+	// Misses: the number of nodes requested while the pool was empty
+	Misses uint64
+	// IsAdaptive: whether MaxSize is adjusted to the workload, between MinNodePoolSize and Limit
+	IsAdaptive bool
+	// Limit: the most MaxSize grows to while the pool is adaptive
+	Limit int64
+	// WindowRequests: the node requests since MaxSize was last evaluated
+	WindowRequests uint64
+	// WindowMisses: the node requests that missed since MaxSize was last evaluated
+	WindowMisses uint64
+	// WindowStart: the unix time in nanoseconds MaxSize was last evaluated
+	WindowStart int64
+	// IsAdapting: atomic flag held while MaxSize is evaluated, so only one request evaluates each window
+	IsAdapting uint32
+	// Grows: the number of times MaxSize was doubled
+	Grows uint64
+	// Shrinks: the number of times MaxSize was halved
+	Shrinks uint64
+}
+
+// Codec converts keys or values of type T to and from the bytes stored in the mmcmap
+type Codec[T any] interface {
+	// Encode returns the bytes stored for value
+	Encode(value T) ([]byte, error)
+	// Decode returns the value stored as data
+	Decode(data []byte) (T, error)
+}
+
+// Typed wraps a mmcmap with codecs for its keys and values, so keys of type K and values of type V are put and read without converting them to
+// bytes around every call
+type Typed[K, V any] struct {
+	// Map: the wrapped mmcmap
+	Map *MMCMap
+	// KeyCodec: encodes and decodes keys
+	KeyCodec Codec[K]
+	// ValueCodec: encodes and decodes values
+	ValueCodec Codec[V]
+}
+
+// TypedPair is a decoded key-value pair returned by a range on a Typed map
+type TypedPair[K, V any] struct {
+	// Version: the version of the mmcmap the pair was written at
+	Version uint64
+	// Key: the decoded key
+	Key K
+	// Value: the decoded value
+	Value V
+}
+
+// MMCMapCounters are the operation counters of a mmcmap, published for every open map under ExpvarName by PublishCounters
+type MMCMapCounters struct {
+	// Name: the name the map was opened with
+	Name string
+	// Filepath: the path to the memory mapped file
+	Filepath string
+	// Puts: the number of puts committed
+	Puts uint64
+	// Gets: the number of keys looked up
+	Gets uint64
+	// Deletes: the number of keys removed by committed deletes
+	Deletes uint64
+	// CommitRetries: the number of commit attempts that had to be retried
+	CommitRetries uint64
+	// CommitRebases: the number of commit attempts rebased onto a newer version instead of retried
+	CommitRebases uint64
+	// Resizes: the number of times the memory map was grown
+	Resizes uint64
+	// Flushes: the number of completed flushes to disk
+	Flushes uint64
+	// WriteStalls: the number of writes that were stalled by MaxUnflushedBytes
+	WriteStalls uint64
+	// Compactions: the number of completed compactions
+	Compactions uint64
+	// ExpiredKeys: the number of expired keys removed by the sweeper
+	ExpiredKeys uint64
+	// ReplicaDeltas: the number of replication deltas applied
+	ReplicaDeltas uint64
+	// Watchers: the number of active watchers
+	Watchers int64
 }
 
+// StringCodec stores strings as their bytes
+type StringCodec struct {}
+
+// Uint64Codec stores integers as 8 big endian bytes, so their byte order is their numeric order in files using KeyModeOrdered
+type Uint64Codec struct {}
+
+// JSONCodec stores values of type T encoded as JSON
+type JSONCodec[T any] struct {}
+
+// ProtoCodec stores protobuf messages of type T in their wire format
+type ProtoCodec[T proto.Message] struct {
+	// New: returns an empty message to decode into
+	New func() T
+}
+
+const (
+	// AllocAppend: the append allocator, and the strategy of legacy files
+	AllocAppend AllocatorID = iota
+	// AllocFreeList: the free list allocator, which reuses regions reclaimed by Reclaim before appending
+	AllocFreeList
+)
+
+const (
+	// CompressionNone: values are stored as is
+	CompressionNone Compression = iota
+	// CompressionSnappy: values are compressed with snappy, which is fast with a moderate ratio
+	CompressionSnappy
+	// CompressionZstd: values are compressed with zstd, which is slower with a higher ratio
+	CompressionZstd
+)
+
+const (
+	// NodeEncodingFixed: every node has a 31 byte header of fixed width fields, and internal nodes store 8 byte child offsets
+	NodeEncodingFixed NodeEncoding = iota
+	// NodeEncodingCompact: nodes have a tag byte followed by a varint version and length, and internal nodes store varint child offsets relative
+	// to the end of the node. Not supported with the free list allocator, overflow values, or PutReader
+	NodeEncodingCompact
+)
+
+const (
+	// KeyModeHashed: keys are placed by chunks of their hash, which spreads any set of keys uniformly across the trie
+	KeyModeHashed KeyMode = iota
+	// KeyModeOrdered: keys are placed by 4 bit chunks of their raw bytes, followed by an end of key slot, so the trie is sorted by key
+	KeyModeOrdered
+)
+
+const (
+	// OpenCheckNone: only check that the metadata offsets fit within the file
+	OpenCheckNone OpenCheck = iota
+	// OpenCheckQuick: also validate the header and the latest root. Findings fail the open
+	OpenCheckQuick
+	// OpenCheckDeep: also traverse a random sample of the trie within OpenCheckBudget, validating every node visited. Findings are reported in
+	// OpenReport without failing the open
+	OpenCheckDeep
+)
+
+const (
+	// DumpJSON: one JSON DumpNode per line, in depth first order from the root
+	DumpJSON DumpFormat = iota
+	// DumpDOT: a Graphviz DOT digraph with a record per node and an edge per child, labelled with its slot in the bitmap
+	DumpDOT
+)
+
+const (
+	// EncodingBase64: keys and values are encoded as standard base64
+	EncodingBase64 KeyEncoding = iota
+	// EncodingHex: keys and values are encoded as lowercase hex
+	EncodingHex
+)
+
+const (
+	// DiffAdded: the key exists only at the second version
+	DiffAdded DiffKind = iota
+	// DiffRemoved: the key exists only at the first version
+	DiffRemoved
+	// DiffModified: the key exists at both versions with different values
+	DiffModified
+)
+
+const (
+	// ChangePut: the key was inserted or updated
+	ChangePut ChangeOp = iota
+	// ChangeDelete: the key was deleted
+	ChangeDelete
+)
+
+const (
+	// StallBlock: stalled writes block until the flush go routine catches up
+	StallBlock StallPolicy = iota
+	// StallError: stalled writes return ErrWriteStall immediately
+	StallError
+)
+
+const (
+	// OpenEager: the node pool is pre-allocated and the flush/resize go routines are started before Open returns
+	OpenEager OpenMode = iota
+	// OpenLazy: Open only maps the file and validates the metadata. Everything else is deferred until the first operation
+	OpenLazy
+)
+
+const (
+	// CopyAlways: values are copied out of the memory map, so they stay valid after the memory map is resized, compacted, or closed
+	CopyAlways CopyPolicy = iota
+	// CopyNever: Get and GetMulti return slices of the memory map, which are only valid until it is next remapped. Use GetZeroCopy to pin them
+	CopyNever
+)
+
+const (
+	// LockMemoryOff: pages of the memory map are faulted in and evicted by the OS as usual
+	LockMemoryOff LockMemoryMode = iota
+	// LockMemoryAll: the whole memory map is locked each time the file is mapped, including the space preallocated for future commits
+	LockMemoryAll
+	// LockMemoryPrefix: only the first LockMemoryPrefix bytes of the file are locked, which hold the header and, after Compact, the live trie
+	LockMemoryPrefix
+)
+
+const (
+	// SpanCommit: a single attempt at committing a new version, from reading the root to publishing it
+	SpanCommit = "mmcmap.commit"
+	// SpanSerialize: serializing the copied path of a commit into the memory map
+	SpanSerialize = "mmcmap.serialize"
+	// SpanFlush: flushing the memory map to disk
+	SpanFlush = "mmcmap.flush"
+	// SpanResize: growing the memory map, in place or by remapping
+	SpanResize = "mmcmap.resize"
+)
+
+const (
+	// LogDebug: routine events, like resizes
+	LogDebug LogLevel = iota - 1
+	// LogInfo: events that change the file, like automatic compactions
+	LogInfo
+	// LogWarn: degraded operation the map recovers from, like a lost connection to the primary
+	LogWarn
+	// LogError: failures of background work that nothing else reports
+	LogError
+)
+
+var (
+	// ErrKeyNotFound is returned when a key does not exist at the version read
+	ErrKeyNotFound = errors.New("key not found")
+	// ErrCorruptNode is returned when a node can not be read from the memory map at the expected offset
+	ErrCorruptNode = errors.New("corrupt node")
+	// ErrOutOfRange is matched by every OutOfRangeError, returned when an access to the memory map falls outside of it
+	ErrOutOfRange = errors.New("out of range of the memory map")
+	// ErrMapClosed is returned when an operation is attempted on a map that has been closed
+	ErrMapClosed = errors.New("mmcmap is closed")
+	// ErrResizeInProgress is returned when a write can not complete until the memory map finishes resizing. Commits retry on it internally
+	ErrResizeInProgress = errors.New("memory map resize in progress")
+	// ErrKeyTooLarge is returned when a key is longer than MaxKeySize, or MaxKeyLength if it is not set
+	ErrKeyTooLarge = errors.New("key exceeds maximum key length")
+	// ErrValueTooLarge is returned when a value is longer than MaxValueSize
+	ErrValueTooLarge = errors.New("value exceeds maximum value size")
+	// ErrInvalidSizeLimit is returned by Open when MaxKeySize or MaxValueSize is negative, MaxKeySize is longer than MaxKeyLength, or MaxFileSize
+	// is smaller than a page, or a segment with segmented storage
+	ErrInvalidSizeLimit = errors.New("invalid size limit")
+	// ErrMapFull is returned when a commit would grow the memory map past MaxFileSize
+	ErrMapFull = errors.New("memory map would exceed maximum file size")
+	// ErrMapDetached is returned when an operation is attempted on a handle that has been detached
+	ErrMapDetached = errors.New("mmcmap is detached, call Reattach before use")
+	// ErrForkedHandle is returned when a handle is used from a process other than the one that opened it
+	ErrForkedHandle = errors.New("mmcmap handle was inherited from another process, call Reattach before use")
+	// ErrTxnConflict is returned when a key read by a transaction was modified before the transaction committed
+	ErrTxnConflict = errors.New("transaction conflict, a key read by the transaction was modified")
+	// ErrTxnDone is returned when a transaction is used after it has been committed or rolled back
+	ErrTxnDone = errors.New("transaction has already been committed or rolled back")
+	// ErrIntentMapMissing is returned when recovering a multi-map commit without one of the maps named in the intent record
+	ErrIntentMapMissing = errors.New("map in commit intent was not provided for recovery")
+	// ErrAllocatorMismatch is returned when a file is opened with a different allocation strategy than it was created with
+	ErrAllocatorMismatch = errors.New("allocator does not match the allocator persisted in the file header")
+	// ErrUnknownAllocator is returned when the allocation strategy in the file header has not been registered
+	ErrUnknownAllocator = errors.New("allocator persisted in the file header is not registered")
+	// ErrOpenCheckFailed is returned by Open when the quick open check finds an inconsistency
+	ErrOpenCheckFailed = errors.New("open check failed")
+	// ErrNoIntactRoot is returned by Repair when no version of the trie is intact
+	ErrNoIntactRoot = errors.New("no intact root found")
+	// ErrWriteStall is returned when MaxUnflushedBytes is exceeded and the stall policy is StallError
+	ErrWriteStall = errors.New("write stalled waiting for flush")
+	// ErrNotInteger is returned by IncrBy when the existing value is not an 8 byte integer
+	ErrNotInteger = errors.New("value is not an 8 byte little endian integer")
+	// ErrNoMergeFunc is returned by Merge when no MergeFunc was set in the options
+	ErrNoMergeFunc = errors.New("no merge function registered in mmcmap options")
+	// ErrAllocatorUnsupported is returned by operations that scan the file in commit order, which files using the free list allocator do not preserve
+	ErrAllocatorUnsupported = errors.New("operation is not supported by the allocator of this file")
+	// ErrPunchHoleUnsupported is returned by PunchHoles on platforms without hole punching
+	ErrPunchHoleUnsupported = errors.New("hole punching is not supported on this platform")
+	// ErrReplicaGap is returned when a replication delta does not start at the version of the replica and no replica source is available
+	ErrReplicaGap = errors.New("replication delta does not follow the replica version")
+	// ErrMapNotEmpty is returned by BulkLoad when the map already holds keys
+	ErrMapNotEmpty = errors.New("map is not empty")
+	// ErrFollowerReadOnly is returned when writing to a map opened as a follower of a primary
+	ErrFollowerReadOnly = errors.New("map is a read only follower")
+	// ErrInvalidTTL is returned by PutWithTTL when the ttl is not positive
+	ErrInvalidTTL = errors.New("ttl must be positive")
+	// ErrExpiryUnsupported is returned by PutWithTTL for files created before the header tracked the key count, which can not be upgraded in place
+	ErrExpiryUnsupported = errors.New("file format does not support expiring keys")
+	// ErrInvalidValueSize is returned by PutReader when the size of the value is negative
+	ErrInvalidValueSize = errors.New("value size must not be negative")
+	// ErrReaderClosed is returned when reading from a ValueReader that has been closed
+	ErrReaderClosed = errors.New("value reader is closed")
+	// ErrOverflowUnsupported is returned by Open when an OverflowThreshold is set for a file created before the header tracked the key count
+	ErrOverflowUnsupported = errors.New("file format does not support overflow values")
+	// ErrCompressionUnsupported is returned by Open when Compression is set for a file created before the header tracked the key count
+	ErrCompressionUnsupported = errors.New("file format does not support compressed values")
+	// ErrUnknownCompression is returned when a leaf is compressed with a codec this version of the library does not know
+	ErrUnknownCompression = errors.New("unknown compression codec")
+	// ErrByteOrderMismatch is returned by Open when the metadata of a file was written in big endian byte order instead of the little endian of the format
+	ErrByteOrderMismatch = errors.New("file metadata is not in little endian byte order")
+	// ErrUnsupportedFormatVersion is returned by Open when a file was written by a newer version of the library at a format version it does not know
+	ErrUnsupportedFormatVersion = errors.New("unsupported mmcmap file format version")
+	// ErrNodeEncodingMismatch is returned by Open when NodeEncodingCompact is set for a file created with the fixed encoding
+	ErrNodeEncodingMismatch = errors.New("node encoding does not match the encoding persisted in the file header")
+	// ErrNodeEncodingUnsupported is returned when an option or operation requires the fixed node encoding
+	ErrNodeEncodingUnsupported = errors.New("operation is not supported by the node encoding of this file")
+	// ErrUnknownLockMemoryMode is returned by Open when LockMemory is not one of the lock memory modes
+	ErrUnknownLockMemoryMode = errors.New("unknown lock memory mode")
+	// ErrUnknownDumpFormat is returned by Dump when DumpOpts.Format is not one of the dump formats
+	ErrUnknownDumpFormat = errors.New("unknown dump format")
+	// ErrUnknownNodeEncoding is returned when the header or options name a node encoding this version of the library does not know
+	ErrUnknownNodeEncoding = errors.New("unknown node encoding")
+	// ErrHashBitsMismatch is returned by Open when HashBits is set to a width other than the one persisted in the file header
+	ErrHashBitsMismatch = errors.New("hash bits do not match the hash bits persisted in the file header")
+	// ErrUnknownHashBits is returned when the header or options name a hash width other than HashBits32 or HashBits64
+	ErrUnknownHashBits = errors.New("unknown hash bits")
+	// ErrKeyModeMismatch is returned by Open when KeyMode is set to a mode other than the one persisted in the file header
+	ErrKeyModeMismatch = errors.New("key mode does not match the key mode persisted in the file header")
+	// ErrUnknownKeyMode is returned when the header or options name a key mode this version of the library does not know
+	ErrUnknownKeyMode = errors.New("unknown key mode")
+	// ErrInvalidEncoding is returned by a codec when the stored bytes are not a valid encoding of its type
+	ErrInvalidEncoding = errors.New("stored bytes are not a valid encoding for the codec")
+	// ErrCollisionBucketFull is returned when a key would be added to a collision bucket already holding MaxCollisionBucketKeys keys
+	ErrCollisionBucketFull = errors.New("collision bucket is full")
+	// ErrInvalidSegmentSize is returned by Open when SegmentSize is not a multiple of the page size of at least MinSegmentSize
+	ErrInvalidSegmentSize = errors.New("segment size must be a multiple of the page size of at least MinSegmentSize")
+	// ErrSegmentSizeMismatch is returned by Open when SegmentSize does not match the segment size persisted in the file header, and when a replica
+	// is sent a stream from a primary with a different segment size
+	ErrSegmentSizeMismatch = errors.New("segment size does not match the segment size persisted in the file header")
+	// ErrSegmentedUnsupported is returned by operations that rewrite the file as a whole, and by options that require it, on files using segmented
+	// storage
+	ErrSegmentedUnsupported = errors.New("operation is not supported by segmented storage")
+	// ErrNotSegmented is returned by ReclaimSegments and SealSegments on files stored as a single file, and by Open when HotSegments or
+	// ColdCompression is set for one
+	ErrNotSegmented = errors.New("map does not use segmented storage")
+	// ErrVersionUnavailable is returned by BackupSince when the root at the requested version is no longer in the file, or the version is newer than the
+	// map, by Wait for a version newer than the map, and by Txn.Get once the snapshot of the transaction was relocated
+	ErrVersionUnavailable = errors.New("requested version is not available")
+	// ErrInvalidWriteShards is returned by Open when WriteShards is negative or more than the fanout of the root
+	ErrInvalidWriteShards = errors.New("write shards must be between 0 and 32")
+	// ErrWriteShardsMismatch is returned by Open when WriteShards does not match the write shards persisted in the file header
+	ErrWriteShardsMismatch = errors.New("write shards do not match the write shards persisted in the file header")
+	// ErrUnhealthy is returned by Ping when the map is unreadable or one of its background go routines has stopped
+	ErrUnhealthy = errors.New("mmcmap health check failed")
+	// ErrExpvarNameTaken is returned by PublishCounters when a variable is already published under ExpvarName
+	ErrExpvarNameTaken = errors.New("expvar name is already published")
+
+	// errCommitAborted is returned by a commit precondition to abandon the commit without writing a new version
+	errCommitAborted = errors.New("commit aborted")
+	// errRangeComplete stops a traversal of an ordered trie once every pair a page of a range can return has been collected
+	errRangeComplete = errors.New("range complete")
+)
+
+const (
+	// WheelSlotBits: the number of bits of the tick used to select a slot on each level of the expiry wheel
+	WheelSlotBits = 6
+	// WheelSlots: the number of slots on each level of the expiry wheel
+	WheelSlots = 1 << WheelSlotBits
+	// WheelLevels: the number of levels in the expiry wheel. With 1 second ticks the wheel spans ~194 days before overflowing
+	WheelLevels = 4
+	// DefaultWheelTick: the default resolution of the expiry wheel
+	DefaultWheelTick = time.Second
+	// DefaultExpirySweepInterval: the default interval the sweeper go routine removes expired keys at
+	DefaultExpirySweepInterval = time.Second
+	// ExpirySweepBatchSize: the max number of expired keys removed in each version committed by the sweeper
+	ExpirySweepBatchSize = 1000
+	// DefaultOpenCheckBudget: the default time limit for the deep open check
+	DefaultOpenCheckBudget = 5 * time.Second
+	// MaxOpenCheckFindings: the maximum number of findings recorded by the open check
+	MaxOpenCheckFindings = 100
+	// DefaultWriteQueueSize: the default number of commits queued for the writer go routine
+	DefaultWriteQueueSize = 1024
+	// DefaultEphemeralMaxSize: the default MaxFileSize of ephemeral maps, 256MB
+	DefaultEphemeralMaxSize = 1 << 28
+	// DefaultEphemeralDir: where ephemeral files are created by default, when it exists
+	DefaultEphemeralDir = "/dev/shm"
+	// LatencyBuckets: the number of buckets in a LatencyHistogram, where the last starts at 2^(LatencyBuckets-2)ns, about 4.6 minutes
+	LatencyBuckets = 40
+	// DefaultCompactionInterval: the default interval the compaction threshold is checked at
+	DefaultCompactionInterval = time.Minute
+	// DefaultReplicationPollInterval: the default interval the primary checks for new commits to stream to followers
+	DefaultReplicationPollInterval = 10 * time.Millisecond
+	// FollowRetryInterval: how long a follower waits before reconnecting to the primary
+	FollowRetryInterval = time.Second
+	// DefaultCompactionLiveRatio: by default, maps are compacted once less than half of the serialized data is reachable from the latest root
+	DefaultCompactionLiveRatio = 0.5
+	// DefaultLockMemoryPrefix: by default, LockMemoryPrefix locks the first 64MB of the file
+	DefaultLockMemoryPrefix = 64 << 20
+	// DefaultDumpPreview: by default, Dump shows the first 16 bytes of each key and value
+	DefaultDumpPreview = 16
+	// ApproximateSampleLeaves: the number of keys ApproximateSize aims to sample, below which it measures every key
+	ApproximateSampleLeaves = 4096
+)
+
 // DefaultPageSize is the default page size set by the underlying OS. Usually will be 4KiB
 var DefaultPageSize = os.Getpagesize()
 
@@ -105,6 +1674,14 @@ const (
 	NodeKeyIdx = 31
 	// Index of Children in serialized internal node
 	NodeChildrenIdx = 31
+	// Flag in the bitmap of a serialized leaf node indicating an 8 byte expiration follows the key
+	LeafExpiresFlag = 1
+	// Flag in the bitmap of a serialized leaf node indicating the value is replaced by the offset and length of an overflow extent
+	LeafOverflowFlag = 2
+	// Size of the reference to an overflow extent in a leaf, the 8 byte offset followed by the 8 byte length of the value
+	OverflowRefSize = 16
+	// Flag in the bitmap of a serialized leaf node indicating the value is compressed, with the codec in its first byte
+	LeafCompressedFlag = 4
 	// OffsetSize for uint64 in serialized node
 	OffsetSize = 8
 	// Bitmap size in bytes since bitmap sis uint32
@@ -113,10 +1690,133 @@ const (
 	NodeChildPtrSize = 8
 	// Size of a new empty internal not
 	NewINodeSize = 29
-	// Offset for the first version of root on mmcmap initialization
-	InitRootOffset = 24
+	// Index of the header, directly after the metadata
+	HeaderIdx = 24
+	// Index of the header magic in the serialized header
+	HeaderMagicIdx = 24
+	// Index of the file format version in the serialized header
+	HeaderFormatVersionIdx = 28
+	// Index of the allocator id in the serialized header
+	HeaderAllocatorIdx = 30
+	// Index of the node encoding in the serialized header
+	HeaderNodeEncodingIdx = 31
+	// Index of the key count in the serialized header
+	HeaderKeyCountIdx = 32
+	// Index of the hash bits in the serialized header
+	HeaderHashBitsIdx = 40
+	// Index of the key mode in the serialized header
+	HeaderKeyModeIdx = 41
+	// Index of the number of write shards in the serialized header
+	HeaderWriteShardsIdx = 42
+	// Index of the hash seed in the serialized header
+	HeaderHashSeedIdx = 48
+	// Index of the segment size in the serialized header
+	HeaderSegmentSizeIdx = 56
+	// Magic identifying a file with a header ("MMCH")
+	HeaderMagic = 0x48434d4d
+	// The current file format version
+	HeaderFormatVersion = 11
+	// The first file format version that maintains the key count in the header
+	KeyCountFormatVersion = 2
+	// The first file format version that allows leaf nodes with an expiration
+	ExpiryFormatVersion = 3
+	// The first file format version that allows leaf nodes with their value in an overflow extent
+	OverflowFormatVersion = 4
+	// The first file format version that allows leaf nodes with a compressed value
+	CompressionFormatVersion = 5
+	// The first file format version that records the node encoding in the header
+	CompactNodeFormatVersion = 6
+	// The first file format version that records the hash bits in the header
+	HashBitsFormatVersion = 7
+	// The first file format version that records a random hash seed in the header
+	HashSeedFormatVersion = 8
+	// The first file format version that records the key mode in the header
+	KeyModeFormatVersion = 9
+	// The first file format version that records the segment size in the header
+	SegmentFormatVersion = 10
+	// The first file format version that records the write shards in the header
+	ShardFormatVersion = 11
+	// The number of seeds of the hash keys are placed by before the internal node keys still share a path to becomes a collision bucket
+	CollisionHashSeeds = 4
+	// The most keys a collision bucket holds, one per bit of its bitmap
+	MaxCollisionBucketKeys = 32
+	// The name PublishCounters publishes the counters of every open mmcmap under with expvar
+	ExpvarName = "mmcmap"
+	// The pprof label holding the name of the mmcmap a background go routine belongs to
+	ProfileLabelMap = "mmcmap"
+	// The pprof label holding the task a background go routine of a mmcmap performs
+	ProfileLabelTask = "mmcmap_task"
+	// Keys are placed in the trie by the 32 bit Murmur32 hash
+	HashBits32 = 32
+	// Keys are placed in the trie by the 64 bit Murmur64 hash
+	HashBits64 = 64
+	// The bits of the key each level of the trie is placed by in files using KeyModeOrdered
+	OrderedChunkSize = 4
+	// Bit set in the tag byte of every node in the compact encoding, so the zeroed gap between paths is never read as a node
+	CompactNodeTag = 0x80
+	// Bit in the tag byte of a compact node indicating the node is a leaf. The low bits of a leaf tag hold the leaf flags
+	CompactLeafTag = 0x40
+	// Offset for the first version of root on mmcmap initialization. Bytes between the header fields and the root are reserved
+	InitRootOffset = 64
+	// Offset of the first root in files created before the header existed
+	LegacyInitRootOffset = 24
+	// Index of the free list, directly after the header, in files using the free list allocator
+	FreeListIdx = 64
+	// Index of the first region in the free list
+	FreeListRegionsIdx = 72
+	// Size of a serialized free region, which is an 8 byte offset and an 8 byte size
+	FreeRegionSize = 16
+	// Bytes reserved for the free list. The initial root of files using the free list allocator is written after it
+	FreeListSize = 4096
+	// The maximum number of regions in the free list
+	MaxFreeRegions = (FreeListSize - (FreeListRegionsIdx - FreeListIdx)) / FreeRegionSize
+	// Index of the segment directory, directly after the header, in files using segmented storage
+	SegmentDirectoryIdx = 64
+	// Bytes reserved for the segment directory. The initial root of files using segmented storage is written after it
+	SegmentDirectorySize = 32
+	// The smallest segment size of a file using segmented storage
+	MinSegmentSize = 1 << 20
+	// The most write shards a file can have, one per child of the root
+	MaxWriteShards = 32
+	// Size of a serialized shard slot, which is the 8 byte version and 8 byte root offset of the latest commit to the shard
+	ShardSlotSize = 16
+	// Bytes reserved for the shard table, which is the version the table was last published with followed by a slot per write shard. The initial
+	// root of files with write shards is written after it
+	ShardTableSize = OffsetSize + MaxWriteShards * ShardSlotSize
+	// The shard mask of commits that may modify any write shard
+	allWriteShards = ^uint32(0)
+	// Unreachable regions smaller than this are not added to the free list
+	MinFreeRegionSize = 64
 	// 1 GB MaxResize
 	MaxResize = 1000000000
+	// MinMapReservation is the least address space reserved for the memory map, so it can grow in place. Reservations are 4x the file otherwise
+	MinMapReservation = 1 << 36
+	// MaxKeyLength is the longest key that can be stored, since the key length is serialized as a uint16
+	MaxKeyLength = 65535
+	// MaxDecompressedValueLength is the longest value a compressed value can decompress to, so corrupt values can not exhaust memory
+	MaxDecompressedValueLength = 1 << 30
+	// snappyMaxExpansion bounds how many bytes a snappy stream expands to per encoded byte, since its longest copy is 64 bytes from a 3 byte tag
+	snappyMaxExpansion = 22
+	// Total pre-allocated nodes in the node pool
+	DefaultNodePoolSize = 100000
+	// Most nodes an adaptive node pool grows to hold
+	DefaultNodePoolMaxSize = 1 << 20
+	// Fewest nodes an adaptive node pool shrinks to hold
+	MinNodePoolSize = 1024
+	// Node requests between evaluations of the size of an adaptive node pool
+	NodePoolWindow = 4096
+	// Fraction of node requests in a window that must miss for an adaptive node pool to grow
+	NodePoolGrowMissRate = 0.125
+	// An adaptive node pool shrinks after a window that took longer than this, since the map is mostly idle
+	NodePoolIdleInterval = time.Second
+	// Largest serialize buffer kept in the pool once a path copy has been written
+	MaxPooledBufferSize = 1 << 20
+	// Total pairs committed per version when importing from another store
+	DefaultImportBatchSize = 10000
+	// Total prefix buckets in a key digest, one per possible leading byte
+	DigestBuckets = 256
+	// Version of the serialized key digest format
+	DigestFormatVersion = 1
 )
 
 /*
@@ -127,6 +1827,27 @@ const (
 		8 RootOffset - 8 bytes
 		16 EndMmapOffset - 8 bytes
 
+	Header:
+		24 Magic - 4 bytes
+		28 FormatVersion - 2 bytes
+		30 AllocatorID - 1 byte
+		31 NodeEncoding - 1 byte
+		32 KeyCount - 8 bytes
+		40 HashBits - 1 byte
+		41 KeyMode - 1 byte
+		42 WriteShards - 1 byte
+		43-47 Reserved
+		48 HashSeed - 8 bytes
+		56 SegmentSize - 8 bytes
+
+	Free List (free list allocator only):
+		64 RegionCount - 8 bytes
+		72 Regions - 16 bytes each, an 8 byte offset and an 8 byte size, up to MaxFreeRegions
+
+	Shard Table (write shards only, after the free list or segment directory):
+		0 Version - 8 bytes, the version the table was last published with
+		8 Slots - 16 bytes each, the 8 byte version and 8 byte root offset of the latest commit to the shard, one per write shard
+
 	[0-7, 8-15, 16-23, 24-27, 28, 29-92, 93+]
 	Node (Leaf):
 		0 Version - 8 bytes
@@ -147,4 +1868,22 @@ const (
 		29 KeyLength - 2 bytes
 		31 Children -->
 			every child will then be 8 bytes, up to 32 * 8 = 256 bytes
+
+	Node (Compact Leaf):
+		0 Tag - 1 byte, CompactNodeTag | CompactLeafTag | leaf flags
+		Version - uvarint
+		BodyLength - uvarint
+		KeyLength - uvarint
+		Key - variable length
+		ExpiresAt - uvarint, if LeafExpiresFlag is set
+		Value - the rest of the body
+
+	Node (Compact Internal):
+		0 Tag - 1 byte, CompactNodeTag
+		Version - uvarint
+		BodyLength - uvarint
+		Bitmap - 4 bytes
+		Children -->
+			every child is a uvarint. Children serialized in the same path are the distance from the end of the node shifted left by 1,
+			and children of older versions are their offset shifted left by 1 with the low bit set
 */
\ No newline at end of file
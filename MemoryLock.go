@@ -0,0 +1,83 @@
+package mmcmap
+
+import "sync/atomic"
+
+import "github.com/sirgallo/mmcmap/common/mmap"
+
+
+//============================================= MMCMap Memory Locking
+
+
+// MemoryLockStats
+//	How much of the memory map is locked into RAM, and how often LockMemory could not be satisfied in full.
+func (mmcMap *MMCMap) MemoryLockStats() MemoryLockStats {
+	return MemoryLockStats{
+		Mode: mmcMap.Opts.LockMemory,
+		LockedBytes: atomic.LoadUint64(&mmcMap.LockedBytes),
+		Fallbacks: atomic.LoadUint64(&mmcMap.LockFallbacks),
+	}
+}
+
+// lockMemory
+//	Lock the region of a new memory map requested by LockMemory into RAM, leaving out cold segments. Locking is best effort: if a region can not be
+//	locked, usually because it exceeds RLIMIT_MEMLOCK, the largest prefix of it that can be is locked instead, halving the length on each attempt
+//	down to a single page, and the fallback is counted. Failing to lock never fails the operation that remapped the file. The lock is released when
+//	the map is unmapped.
+func (mmcMap *MMCMap) lockMemory(mMap mmap.MMap) {
+	atomic.StoreUint64(&mmcMap.LockedBytes, 0)
+
+	requested := mmcMap.lockLength(uint64(len(mMap)))
+	if requested == 0 { return }
+
+	var hotBytes, lockedBytes uint64
+	var isShort bool
+	for _, region := range mmcMap.hotRegions(requested) {
+		length := region[1] - region[0]
+		hotBytes += length
+		// once a region could not be locked in full, later regions would not fit either
+		if isShort { continue }
+
+		locked := lockPrefix(mMap[region[0]:region[1]])
+		lockedBytes += locked
+		isShort = locked < length
+	}
+
+	atomic.StoreUint64(&mmcMap.LockedBytes, lockedBytes)
+
+	if lockedBytes < hotBytes {
+		atomic.AddUint64(&mmcMap.LockFallbacks, 1)
+		mmcMap.log(LogWarn, "memory map could not be locked in full", "requested", hotBytes, "locked", lockedBytes)
+	}
+}
+
+// lockPrefix
+//	Lock the largest prefix of region that can be locked, halving the length on each attempt down to a single page, returning its length.
+func lockPrefix(region mmap.MMap) uint64 {
+	length := uint64(len(region))
+	pageSize := uint64(DefaultPageSize)
+	for length > 0 {
+		lockErr := region[:length].Lock()
+		if lockErr == nil { return length }
+
+		length = length / 2 / pageSize * pageSize
+	}
+
+	return 0
+}
+
+// lockLength
+//	The number of bytes from the start of a memory map of size bytes that LockMemory requests to be locked.
+func (mmcMap *MMCMap) lockLength(size uint64) uint64 {
+	switch mmcMap.Opts.LockMemory {
+		case LockMemoryAll:
+			return size
+		case LockMemoryPrefix:
+			prefix := mmcMap.Opts.LockMemoryPrefix
+			if prefix == 0 { prefix = DefaultLockMemoryPrefix }
+			if prefix > size { return size }
+
+			return prefix
+		default:
+			return 0
+	}
+}
@@ -0,0 +1,23 @@
+package mmcmap
+
+
+//============================================= MMCMap Merge
+
+
+// Merge
+//	Combine operand with the current value of the key using the MergeFunc registered in the options, and commit the result as a new version.
+//	The existing value is read from the same root the path copy is made from, so the read and the write happen in one traversal of the commit loop.
+//	If another writer commits first, the MergeFunc is called again with the new value, so it must be free of side effects.
+//	The existing value aliases the memory map, so it is passed with its capacity capped at its length, and appending to it copies instead of writing
+//	over the nodes that follow it. It must not be modified in place.
+func (mmcMap *MMCMap) Merge(key, operand []byte) (bool, error) {
+	mergeFunc := mmcMap.Opts.MergeFunc
+	if mergeFunc == nil { return false, ErrNoMergeFunc }
+
+	return mmcMap.commitWith(func(root *MMCMapNode) ([]*BatchOp, error) {
+		existing, getErr := mmcMap.getFromRoot(root, key)
+		if getErr != nil { return nil, getErr }
+
+		return []*BatchOp{{ Key: key, Value: mergeFunc(existing[:len(existing):len(existing)], operand) }}, nil
+	})
+}
@@ -1,6 +1,6 @@
 package mmcmap
 
-import "errors"
+import "math/bits"
 import "sync/atomic"
 import "unsafe"
 
@@ -12,128 +12,169 @@ import "github.com/sirgallo/mmcmap/common/mmap"
 
 // ReadMetaFromMemMap
 //	Read and deserialize the current metadata object from the memory map.
-func (mmcMap *MMCMap) ReadMetaFromMemMap() (meta *MMCMapMetaData, err error) {
-	defer func() {
-		r := recover()
-		if r != nil {
-			meta = nil
-			err = errors.New("error reading metadata from mmap")
-		}
-	}()
-	
+func (mmcMap *MMCMap) ReadMetaFromMemMap() (*MMCMapMetaData, error) {
 	mMap := mmcMap.Data.Load().(mmap.MMap)
-	currMeta := mMap[MetaVersionIdx:MetaEndSerializedOffset + OffsetSize]
-	
-	meta, readMetaErr := DeserializeMetaData(currMeta)
+	rangeErr := checkRange(mMap, "read metadata", MetaVersionIdx, MetaEndSerializedOffset + OffsetSize, nil)
+	if rangeErr != nil { return nil, rangeErr }
+
+	meta, readMetaErr := DeserializeMetaData(mMap[MetaVersionIdx:MetaEndSerializedOffset + OffsetSize])
 	if readMetaErr != nil { return nil, readMetaErr }
 
 	return meta, nil
 }
 
 // WriteMetaToMemMap
-//	Copy the serialized metadata into the memory map.
-func (mmcMap *MMCMap) WriteMetaToMemMap(sMeta []byte) (ok bool, err error) {
-	defer func() {
-		r := recover()
-		if r != nil { 
-			ok = false
-			err = errors.New("error writing metadata to mmap")
-		}
-	}()
-
+//	Copy the serialized metadata into the memory map. Like WriteNodeToMemMap, the metadata is not flushed on its own.
+func (mmcMap *MMCMap) WriteMetaToMemMap(sMeta []byte) (bool, error) {
 	mMap := mmcMap.Data.Load().(mmap.MMap)
-	copy(mMap[MetaVersionIdx:MetaEndSerializedOffset + OffsetSize], sMeta)
-
-	flushErr := mmcMap.flushRegionToDisk(MetaVersionIdx, MetaEndSerializedOffset + OffsetSize)
-	if flushErr != nil { return false, flushErr }
+	rangeErr := checkRange(mMap, "write metadata", MetaVersionIdx, MetaEndSerializedOffset + OffsetSize, nil)
+	if rangeErr != nil { return false, rangeErr }
 
+	copy(mMap[MetaVersionIdx:MetaEndSerializedOffset + OffsetSize], sMeta)
 	return true, nil
 }
 
 // initMeta
 //	Initialize and serialize the metadata in a new MMCMap.
-//	Version starts at 0 and increments, and root offset starts at 24.
+//	Version starts at 0 and increments, and root offset starts directly after the header.
 func (mmcMap *MMCMap) initMeta(endRoot uint64) error {
 	newMeta := &MMCMapMetaData{
 		Version: 0,
-		RootOffset: uint64(InitRootOffset),
+		RootOffset: mmcMap.HeaderSize,
 		EndMmapOffset: endRoot,
 	}
 
 	serializedMeta := newMeta.SerializeMetaData()
-	_, flushErr := mmcMap.WriteMetaToMemMap(serializedMeta)
-	if flushErr != nil { return flushErr }
+	_, writeMetaErr := mmcMap.WriteMetaToMemMap(serializedMeta)
+	if writeMetaErr != nil { return writeMetaErr }
 	
 	return nil
 }
 
 // loadMetaRootOffsetPointer
 //	Get the uint64 pointer from the memory map.
-func (mmcMap *MMCMap) loadMetaRootOffset() (ptr *uint64, rOff uint64, err error) {
-	defer func() {
-		r := recover()
-		if r != nil { 
-			ptr = nil
-			rOff = 0
-			err = errors.New("error getting root offset from mmap")
-		}
-	}()
-
-	mMap := mmcMap.Data.Load().(mmap.MMap)
-	rootOffsetPtr := (*uint64)(unsafe.Pointer(&mMap[MetaRootOffsetIdx]))
-	rootOffset := atomic.LoadUint64(rootOffsetPtr)
-	
-	return rootOffsetPtr, rootOffset, nil
+func (mmcMap *MMCMap) loadMetaRootOffset() (*uint64, uint64, error) {
+	return mmcMap.loadMetaWord("load root offset", MetaRootOffsetIdx)
 }
 
 // loadMetaEndMmapPointer
 //	Get the uint64 pointer from the memory map.
-func (mmcMap *MMCMap) loadMetaEndSerialized() (ptr *uint64, sOff uint64, err error) {
-	defer func() {
-		r := recover()
-		if r != nil { 
-			ptr = nil
-			sOff = 0
-			err = errors.New("error getting end of serialized data from mmap")
-		}
-	}()
-
-	mMap := mmcMap.Data.Load().(mmap.MMap)
-	endSerializedPtr := (*uint64)(unsafe.Pointer(&mMap[MetaEndSerializedOffset]))
-	endSerialized := atomic.LoadUint64(endSerializedPtr)
-	
-	return endSerializedPtr, endSerialized, nil
+func (mmcMap *MMCMap) loadMetaEndSerialized() (*uint64, uint64, error) {
+	return mmcMap.loadMetaWord("load end of serialized data", MetaEndSerializedOffset)
 }
 
 // loadMetaVersionPointer
 //	Get the uint64 pointer from the memory map.
-func (mmcMap *MMCMap) loadMetaVersion() (ptr *uint64, v uint64, err error) {
-	defer func() {
-		r := recover()
-		if r != nil { 
-			ptr = nil
-			v = 0
-			err = errors.New("error getting version from mmap")
-		}
-	}()
+func (mmcMap *MMCMap) loadMetaVersion() (*uint64, uint64, error) {
+	return mmcMap.loadMetaWord("load version", MetaVersionIdx)
+}
+
+// loadMetaKeyCount
+//	Get the uint64 pointer from the memory map.
+func (mmcMap *MMCMap) loadMetaKeyCount() (*uint64, uint64, error) {
+	return mmcMap.loadMetaWord("load key count", HeaderKeyCountIdx)
+}
 
+// loadMetaWord
+//	Get the pointer to the metadata or header word at idx in the memory map, and atomically load it.
+func (mmcMap *MMCMap) loadMetaWord(op string, idx uint64) (*uint64, uint64, error) {
 	mMap := mmcMap.Data.Load().(mmap.MMap)
-	versionPtr := (*uint64)(unsafe.Pointer(&mMap[MetaVersionIdx]))
-	version := atomic.LoadUint64(versionPtr)
+	rangeErr := checkRange(mMap, op, idx, OffsetSize, nil)
+	if rangeErr != nil { return nil, 0, rangeErr }
+
+	ptr := (*uint64)(unsafe.Pointer(&mMap[idx]))
+	return ptr, loadMetaPointer(ptr), nil
+}
+
+// publishRoot
+//	Publish the root offset of a commit along with its change to the key count. The root offset and the key count are the committed metadata of the
+//	version, so both are written under the meta lock, and a reader of the count never sees one that does not match the published root.
+func (mmcMap *MMCMap) publishRoot(rootOffsetPtr *uint64, rootOffset uint64, keyDelta int64) {
+	mmcMap.MetaLock.Lock()
+	defer mmcMap.MetaLock.Unlock()
+
+	if keyDelta != 0 && mmcMap.isKeyCountTracked() {
+		keyCountPtr, _, loadKCountErr := mmcMap.loadMetaKeyCount()
+		if loadKCountErr == nil { addMetaPointer(keyCountPtr, uint64(keyDelta)) }
+	}
 
-	return versionPtr, version, nil
+	mmcMap.storeMetaPointer(rootOffsetPtr, rootOffset)
+}
+
+// publishRootWithCount
+//	Same as publishRoot, but replaces the key count instead of adjusting it, for commits that write a whole trie or adopt the root of another map.
+func (mmcMap *MMCMap) publishRootWithCount(rootOffsetPtr *uint64, rootOffset, keyCount uint64) error {
+	mmcMap.MetaLock.Lock()
+	defer mmcMap.MetaLock.Unlock()
+
+	if mmcMap.isKeyCountTracked() {
+		keyCountPtr, _, loadKCountErr := mmcMap.loadMetaKeyCount()
+		if loadKCountErr != nil { return loadKCountErr }
+
+		mmcMap.storeMetaPointer(keyCountPtr, keyCount)
+	}
+
+	mmcMap.storeMetaPointer(rootOffsetPtr, rootOffset)
+	return nil
+}
+
+// loadCommittedKeyCount
+//	Load the key count of the published root under the meta lock.
+func (mmcMap *MMCMap) loadCommittedKeyCount() (uint64, error) {
+	mmcMap.MetaLock.RLock()
+	defer mmcMap.MetaLock.RUnlock()
+
+	_, keyCount, loadKCountErr := mmcMap.loadMetaKeyCount()
+	return keyCount, loadKCountErr
+}
+
+// isInRange
+//	Determine if the root and end offsets fall within a memory map of size bytes with the initial root at headerSize.
+func (meta *MMCMapMetaData) isInRange(headerSize, size uint64) bool {
+	return meta.RootOffset >= headerSize && meta.RootOffset <= meta.EndMmapOffset && meta.EndMmapOffset < size
+}
+
+// byteSwapped
+//	The metadata with the bytes of every field reversed, as it would be read if it had been written in the opposite byte order.
+func (meta *MMCMapMetaData) byteSwapped() *MMCMapMetaData {
+	return &MMCMapMetaData{
+		Version: bits.ReverseBytes64(meta.Version),
+		RootOffset: bits.ReverseBytes64(meta.RootOffset),
+		EndMmapOffset: bits.ReverseBytes64(meta.EndMmapOffset),
+	}
+}
+
+// isKeyCountTracked
+//	The key count is kept in the header, so it is only maintained for files with a header at a format version that includes it.
+func (mmcMap *MMCMap) isKeyCountTracked() bool {
+	return mmcMap.HeaderSize != LegacyInitRootOffset && mmcMap.Header.FormatVersion >= KeyCountFormatVersion
 }
 
 // storeMetaPointer
 //	Store the pointer associated with the particular metadata (root offset, end serialized, version) back in the memory map.
-func (mmcMap *MMCMap) storeMetaPointer(ptr *uint64, val uint64) (err error) {
-	defer func() {
-		r := recover()
-		if r != nil { 
-			err = errors.New("error storing meta value in mmap")
-		}
-	}()
-
-	atomic.StoreUint64(ptr, val)
-	return nil
+func (mmcMap *MMCMap) storeMetaPointer(ptr *uint64, val uint64) {
+	atomic.StoreUint64(ptr, metaWord(val))
+}
+
+// loadMetaPointer
+//	Atomically load a little endian word of the metadata or header from the memory map.
+//	Every word accessed in place goes through loadMetaPointer, storeMetaPointer, compareAndSwapMetaPointer, or addMetaPointer, so the file has the same
+//	layout whichever byte order the host uses.
+func loadMetaPointer(ptr *uint64) uint64 {
+	return metaWord(atomic.LoadUint64(ptr))
+}
+
+// compareAndSwapMetaPointer
+//	Atomically swap a little endian word of the metadata in the memory map from old to new.
+func compareAndSwapMetaPointer(ptr *uint64, old, new uint64) bool {
+	return atomic.CompareAndSwapUint64(ptr, metaWord(old), metaWord(new))
+}
+
+// addMetaPointer
+//	Atomically add delta to a little endian word of the header in the memory map. A negative delta is passed as its two's complement.
+func addMetaPointer(ptr *uint64, delta uint64) {
+	for {
+		stored := atomic.LoadUint64(ptr)
+		if atomic.CompareAndSwapUint64(ptr, stored, metaWord(metaWord(stored) + delta)) { return }
+	}
 }
\ No newline at end of file
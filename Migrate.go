@@ -0,0 +1,119 @@
+package mmcmap
+
+import "os"
+
+import "github.com/sirgallo/mmcmap/common/mmap"
+
+
+//============================================= MMCMap Format Migration
+
+
+// Migrate
+//	Upgrade the map at path to HeaderFormatVersion in place, without opening it. The file must not be open in any process.
+//	Files created before the header existed have their root at LegacyInitRootOffset, where the header now sits, so the trie reachable from the
+//	latest root is copied behind a new header, like Compact, and the copy is renamed over the original. Prior versions are not carried over.
+//	Files with a header but no key count have their keys counted into the header. Every other format only differs by the features it allows, so
+//	its format version is raised. Files from a newer version of the library return ErrUnsupportedFormatVersion and are left untouched.
+func Migrate(path string) (*MigrateReport, error) {
+	mmcMap, openFileErr := openFile(MMCMapOpts{ Filepath: path }, os.O_RDWR)
+	if openFileErr != nil { return nil, openFileErr }
+
+	migrate := func() (*MigrateReport, error) {
+		mmapErr := mmcMap.mMap()
+		if mmapErr != nil { return nil, mmapErr }
+
+		loadHeaderErr := mmcMap.loadHeader()
+		if loadHeaderErr != nil { return nil, loadHeaderErr }
+
+		validateErr := mmcMap.validateMeta()
+		if validateErr != nil { return nil, validateErr }
+
+		return mmcMap.migrate()
+	}
+
+	report, migrateErr := migrate()
+	closeErr := mmcMap.Close()
+
+	if migrateErr != nil { return nil, migrateErr }
+	if closeErr != nil { return nil, closeErr }
+
+	return report, nil
+}
+
+// migrate
+//	Upgrade the layout of the file to HeaderFormatVersion. Nothing else may be using the handle.
+func (mmcMap *MMCMap) migrate() (*MigrateReport, error) {
+	report := &MigrateReport{ PrevFormatVersion: mmcMap.Header.FormatVersion, FormatVersion: HeaderFormatVersion }
+	if mmcMap.HeaderSize != LegacyInitRootOffset && mmcMap.Header.FormatVersion == HeaderFormatVersion { return report, nil }
+
+	meta, readMetaErr := mmcMap.ReadMetaFromMemMap()
+	if readMetaErr != nil { return nil, readMetaErr }
+
+	if mmcMap.HeaderSize == LegacyInitRootOffset {
+		report.IsRelocated = true
+		return report, mmcMap.migrateLegacy(meta)
+	}
+
+	if ! mmcMap.isKeyCountTracked() {
+		keyCount, countErr := mmcMap.countLeaves(meta.RootOffset)
+		if countErr != nil { return nil, countErr }
+
+		mMap := mmcMap.Data.Load().(mmap.MMap)
+		copy(mMap[HeaderKeyCountIdx:HeaderKeyCountIdx + OffsetSize], serializeUint64(keyCount))
+	}
+
+	return report, mmcMap.raiseFormatVersion(HeaderFormatVersion)
+}
+
+// migrateLegacy
+//	Copy the trie reachable from the latest root of a file created before the header existed behind a new header, and swap the copy in.
+//	The copied root keeps the latest version.
+func (mmcMap *MMCMap) migrateLegacy(meta *MMCMapMetaData) error {
+	keyCount, countErr := mmcMap.countLeaves(meta.RootOffset)
+	if countErr != nil { return countErr }
+
+	mmcMap.lockResize()
+	defer mmcMap.unlockResize()
+
+	header := MMCMapHeader{ FormatVersion: HeaderFormatVersion, AllocatorID: AllocAppend, HashBits: HashBits32 }
+	newMeta := &MMCMapMetaData{ Version: meta.Version, RootOffset: InitRootOffset }
+	compactPath := mmcMap.Filepath + ".compact"
+
+	// the trie moves up by the size of the header, and is otherwise copied at the size it was serialized at
+	length := InitRootOffset + meta.EndMmapOffset - mmcMap.HeaderSize
+	_, writeErr := writeMappedFile(compactPath, length, func(migrated mmap.MMap) (int64, error) {
+		var nodesCopied uint64
+		endOffset, copyErr := mmcMap.copyRecursive(migrated, meta.RootOffset, InitRootOffset, &nodesCopied)
+		if copyErr != nil { return 0, copyErr }
+
+		newMeta.EndMmapOffset = endOffset
+		copy(migrated[MetaVersionIdx:HeaderIdx], newMeta.SerializeMetaData())
+		copy(migrated[HeaderIdx:InitRootOffset], header.SerializeHeader())
+		copy(migrated[HeaderKeyCountIdx:HeaderKeyCountIdx + OffsetSize], serializeUint64(keyCount))
+
+		return compactedFileSize(endOffset), nil
+	})
+
+	if writeErr != nil { return writeErr }
+
+	swapErr := mmcMap.swapFile(compactPath)
+	if swapErr != nil { return swapErr }
+
+	mmcMap.Header = header
+	mmcMap.HeaderSize = header.dataOffset()
+
+	return nil
+}
+
+// countLeaves
+//	Count the leaves reachable from the root at offset.
+func (mmcMap *MMCMap) countLeaves(offset uint64) (uint64, error) {
+	var keyCount uint64
+	rangeErr := mmcMap.rangeRecursive(offset, nil, nil, true, func(kvPair *KeyValuePair) error {
+		keyCount++
+		return nil
+	})
+
+	if rangeErr != nil { return 0, rangeErr }
+	return keyCount, nil
+}
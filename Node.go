@@ -1,6 +1,6 @@
 package mmcmap
 
-import "errors"
+import "fmt"
 import "sync/atomic"
 import "unsafe"
 
@@ -11,27 +11,45 @@ import "github.com/sirgallo/mmcmap/common/mmap"
 
 
 // ReadNodeFromMemMap
-//	Reads a node in the mmcmap from the serialized memory map.
-func (mmcMap *MMCMap) ReadNodeFromMemMap(startOffset uint64) (node *MMCMapNode, err error) {
-	defer func() {
-		r := recover()
-		if r != nil {
-			node = nil
-			err = errors.New("error reading node from mem map")
-		}
-	}()
-	
-	endOffsetIdx := startOffset + NodeEndOffsetIdx
-	
+//	Reads a node in the mmcmap from the serialized memory map, in the node encoding of the file.
+//	Nodes are served from the pinned levels or the node cache when they are enabled, and nodes read from the memory map are added to the cache.
+func (mmcMap *MMCMap) ReadNodeFromMemMap(startOffset uint64) (*MMCMapNode, error) {
+	if node, ok := mmcMap.loadPinned().nodes[startOffset]; ok { return node.cacheCopy(), nil }
+	if node, ok := mmcMap.NodeCache.Get(startOffset); ok { return node, nil }
+
+	generation := mmcMap.NodeCache.Generation()
+	node, readNodeErr := mmcMap.readNode(startOffset)
+	if readNodeErr != nil { return nil, readNodeErr }
+
+	mmcMap.NodeCache.Put(startOffset, node, generation)
+	return node, nil
+}
+
+// readNode
+//	Deserialize the node at startOffset in the memory map, bypassing the node cache.
+func (mmcMap *MMCMap) readNode(startOffset uint64) (*MMCMapNode, error) {
 	mMap := mmcMap.Data.Load().(mmap.MMap)
-	sEndOffset := mMap[endOffsetIdx:endOffsetIdx + OffsetSize]
+	corruptErr := fmt.Errorf("%w at offset %d", ErrCorruptNode, startOffset)
+
+	if mmcMap.isCompactEncoding() {
+		rangeErr := checkRange(mMap, "read node", startOffset, 1, corruptErr)
+		if rangeErr != nil { return nil, rangeErr }
 
-	endOffset, decEndOffErr := deserializeUint64(sEndOffset)
+		return mmcMap.readCompactNode(mMap, startOffset)
+	}
+
+	rangeErr := checkRange(mMap, "read node", startOffset, NodeEndOffsetIdx + OffsetSize, corruptErr)
+	if rangeErr != nil { return nil, rangeErr }
+
+	endOffsetIdx := startOffset + NodeEndOffsetIdx
+	endOffset, decEndOffErr := deserializeUint64(mMap[endOffsetIdx:endOffsetIdx + OffsetSize])
 	if decEndOffErr != nil { return nil, decEndOffErr }
+	if endOffset < endOffsetIdx + OffsetSize - 1 { return nil, corruptErr }
 
-	sNode := mMap[startOffset:endOffset + 1]
-	
-	node, decNodeErr := mmcMap.DeserializeNode(sNode)
+	rangeErr = checkRange(mMap, "read node", startOffset, endOffset - startOffset + 1, corruptErr)
+	if rangeErr != nil { return nil, rangeErr }
+
+	node, decNodeErr := mmcMap.DeserializeNode(mMap[startOffset:endOffset + 1])
 	if decNodeErr != nil { return nil, decNodeErr }
 
 	return node, nil
@@ -39,27 +57,19 @@ func (mmcMap *MMCMap) ReadNodeFromMemMap(startOffset uint64) (node *MMCMapNode,
 
 // WriteNodeToMemMap
 //	Serializes and writes a MMCMapNode instance to the memory map.
-func (mmcMap *MMCMap) WriteNodeToMemMap(node *MMCMapNode) (offset uint64, err error) {
-	defer func() {
-		r := recover()
-		if r != nil {
-			offset = 0
-			err = errors.New("error writing new path to mmap")
-		}
-	}()
-
-	sNode, serializeErr := node.SerializeNode(node.StartOffset)
+//	The node is not flushed on its own. Callers flush it along with the other regions they write, so writing a file takes a single msync.
+func (mmcMap *MMCMap) WriteNodeToMemMap(node *MMCMapNode) (uint64, error) {
+	sNode, serializeErr := mmcMap.encodeNode(node)
 	if serializeErr != nil { return 0, serializeErr	}
 
 	sNodeLen := uint64(len(sNode))
 	endOffset := node.StartOffset + sNodeLen
 
 	mMap := mmcMap.Data.Load().(mmap.MMap)
-	copy(mMap[node.StartOffset:endOffset], sNode)
+	rangeErr := checkRange(mMap, "write node", node.StartOffset, sNodeLen, nil)
+	if rangeErr != nil { return 0, rangeErr }
 
-	flushErr := mmcMap.flushRegionToDisk(node.StartOffset, endOffset)
-	if flushErr != nil { return 0, flushErr } 
-	
+	copy(mMap[node.StartOffset:endOffset], sNode)
 	return endOffset, nil
 }
 
@@ -77,6 +87,11 @@ func (mmcMap *MMCMap) copyNode(node *MMCMapNode) *MMCMapNode {
 	nodeCopy.KeyLength = node.KeyLength
 	nodeCopy.Key = node.Key
 	nodeCopy.Value = node.Value
+	nodeCopy.ExpiresAt = node.ExpiresAt
+	nodeCopy.IsOverflow = node.IsOverflow
+	nodeCopy.OverflowOffset = node.OverflowOffset
+	nodeCopy.Compression = node.Compression
+	nodeCopy.EncodedValue = node.EncodedValue
 	nodeCopy.Children = make([]*MMCMapNode, len(node.Children))
 
 	copy(nodeCopy.Children, node.Children)
@@ -86,13 +101,18 @@ func (mmcMap *MMCMap) copyNode(node *MMCMapNode) *MMCMapNode {
 
 // determineEndOffset
 //	Determine the end offset of a serialized MMCMapNode.
-//	For Leaf Nodes, this will be the start offset through the key index, plus the length of the key and the length of the value.
+//	For Leaf Nodes, this will be the start offset through the key index, plus the length of the key, the expiration if set, and the length of the stored value.
 //	For Internal Nodes, this will be the start offset through the children index, plus (number of children * 8 bytes).
 func (node *MMCMapNode) determineEndOffset() uint64 {
 	nodeEndOffset := node.StartOffset
 
 	if node.IsLeaf {
-		nodeEndOffset += uint64(NodeKeyIdx + int(node.KeyLength) + len(node.Value))
+		nodeEndOffset += uint64(NodeKeyIdx + int(node.KeyLength))
+		if node.ExpiresAt != 0 { nodeEndOffset += OffsetSize }
+
+		if node.IsOverflow {
+			nodeEndOffset += OverflowRefSize
+		} else { nodeEndOffset += uint64(len(node.storedValue())) }
 	} else {
 		encodedChildrenLength := func() int {
 			totalChildren := calculateHammingWeight(node.Bitmap)
@@ -118,7 +138,7 @@ func getSerializedNodeSize(data []byte) uint64 {
 func (mmcMap *MMCMap) initRoot() (uint64, error) {
 	root := &MMCMapNode{
 		Version: 0,
-		StartOffset: uint64(InitRootOffset),
+		StartOffset: mmcMap.HeaderSize,
 		Bitmap: 0,
 		IsLeaf: false,
 		KeyLength: uint16(0),
@@ -151,10 +171,22 @@ func (mmcMap *MMCMap) newInternalNode(version uint64) *MMCMapNode {
 	return iNode
 }
 
+// newChildStubs
+//	Allocate the children of a deserialized internal node, as stubs holding only the offset of each child in the memory map. The stubs share a single
+//	backing array, so an internal node costs the same few allocations regardless of how many children it has, instead of one per child.
+func newChildStubs(totalChildren int) []*MMCMapNode {
+	stubs := make([]MMCMapNode, totalChildren)
+	children := make([]*MMCMapNode, totalChildren)
+	for idx := range children { children[idx] = &stubs[idx] }
+
+	return children
+}
+
 // newLeafNode
 //	Creates a new leaf node when path copying the mmcmap, which stores a key value pair.
-//	It will also include the version of the mmcmap.
-func (mmcMap *MMCMap) newLeafNode(key, value []byte, version uint64) *MMCMapNode {
+//	It will also include the version of the mmcmap, and the unix time in nanoseconds the leaf expires at, where 0 never expires.
+//	Values longer than the OverflowThreshold are flagged to be written to an overflow extent, and values are compressed with the codec of the map.
+func (mmcMap *MMCMap) newLeafNode(key, value []byte, expiresAt int64, version uint64) *MMCMapNode {
 	lNode := mmcMap.NodePool.Get()
 
 	lNode.Version = version
@@ -163,6 +195,26 @@ func (mmcMap *MMCMap) newLeafNode(key, value []byte, version uint64) *MMCMapNode
 	lNode.KeyLength = uint16(len(key))
 	lNode.Key = key
 	lNode.Value = value
+	lNode.ExpiresAt = expiresAt
+	lNode.IsOverflow = mmcMap.isOverflowValue(value)
+	lNode.OverflowOffset = 0
+	lNode.Compression = mmcMap.Opts.Compression
+	lNode.EncodedValue = nil
+
+	return lNode
+}
+
+// updateLeafNode
+//	Update a leaf in place with a new value and expiration at version, when a put replaces the value of a key already in the mmcmap.
+//	The leaf is a copy on the path being modified, so it is written again with the new value.
+func (mmcMap *MMCMap) updateLeafNode(lNode *MMCMapNode, value []byte, expiresAt int64, version uint64) *MMCMapNode {
+	lNode.Version = version
+	lNode.Value = value
+	lNode.ExpiresAt = expiresAt
+	lNode.IsOverflow = mmcMap.isOverflowValue(value)
+	lNode.OverflowOffset = 0
+	lNode.Compression = mmcMap.Opts.Compression
+	lNode.EncodedValue = nil
 
 	return lNode
 }
@@ -174,22 +226,28 @@ func storeNodeAsPointer(node *MMCMapNode) *unsafe.Pointer {
 	return &ptr
 }
 
+// writePathToMemMap
+//	Serialize a path copy of size bytes straight into the memory map at offset, without building it in a buffer first.
+//	The commit must have already claimed the region, since racing commits serialized to the same offset would overwrite each other.
+func (mmcMap *MMCMap) writePathToMemMap(path *MMCMapNode, offset, size uint64) error {
+	mMap := mmcMap.Data.Load().(mmap.MMap)
+	rangeErr := checkRange(mMap, "write path", offset, size, nil)
+	if rangeErr != nil { return rangeErr }
+
+	return mmcMap.writePath(mMap[offset:offset + size], path, offset)
+}
+
 // writeNodesToMemMap
 //	Write a list of serialized nodes to the memory map. If the mem map is too small for the incoming nodes, dynamically resize.
-func (mmcMap *MMCMap) writeNodesToMemMap(snodes []byte, offset uint64) (ok bool, err error) {
-	defer func() {
-		r := recover()
-		if r != nil {
-			ok = false
-			err = errors.New("error writing new path to mmap")
-		}
-	}()
-
+func (mmcMap *MMCMap) writeNodesToMemMap(snodes []byte, offset uint64) (bool, error) {
 	lenSNodes := uint64(len(snodes))
 	endOffset := offset + lenSNodes
 
 	mMap := mmcMap.Data.Load().(mmap.MMap)
+	rangeErr := checkRange(mMap, "write nodes", offset, lenSNodes, nil)
+	if rangeErr != nil { return false, rangeErr }
+
 	copy(mMap[offset:endOffset], snodes)
 
 	return true, nil
-}
\ No newline at end of file
+}
@@ -2,6 +2,7 @@ package mmcmap
 
 import "sync"
 import "sync/atomic"
+import "time"
 
 
 //============================================= MMCMap Node Pool
@@ -18,19 +19,54 @@ func NewMMCMapNodePool(maxSize int64) *MMCMapNodePool {
 	}
 
 	size := int64(0)
-	np := &MMCMapNodePool{ maxSize, size, pool }
+	np := &MMCMapNodePool{ MaxSize: maxSize, Size: size, Pool: pool }
 	np.initializePool()
 
 	return np
 }
 
+// NewAdaptiveNodePool
+//	Creates a node pool pre-allocated with size nodes that adapts how many nodes it holds to the workload.
+//	Every NodePoolWindow requests, the pool doubles its max size, up to limit, if more than NodePoolGrowMissRate of the requests missed, since
+//	more go routines are copying paths than the pool holds nodes for. If the window took longer than NodePoolIdleInterval instead, the map is
+//	mostly idle and the max size is halved, down to MinNodePoolSize, so nodes returned to the pool are dropped until it drains below it.
+func NewAdaptiveNodePool(size, limit int64) *MMCMapNodePool {
+	if limit < size { limit = size }
+
+	np := NewMMCMapNodePool(size)
+	np.IsAdaptive = true
+	np.Limit = limit
+	np.WindowStart = time.Now().UnixNano()
+
+	return np
+}
+
+// newNodePool
+//	Create the node pool sized by the options.
+func (mmcMap *MMCMap) newNodePool() *MMCMapNodePool {
+	size := mmcMap.Opts.NodePoolSize
+	if size == 0 { size = DefaultNodePoolSize }
+	if ! mmcMap.Opts.NodePoolAdaptive { return NewMMCMapNodePool(size) }
+
+	limit := mmcMap.Opts.NodePoolMaxSize
+	if limit == 0 { limit = DefaultNodePoolMaxSize }
+
+	return NewAdaptiveNodePool(size, limit)
+}
+
 // Get
 //	Attempt to get a pre-allocated node from the node pool and decrement the total allocated nodes.
 //	If the pool is empty, a new node is allocated
 func (np *MMCMapNodePool) Get() *MMCMapNode {
 	node := np.Pool.Get().(*MMCMapNode)
-	if atomic.LoadInt64(&np.Size) > 0 { atomic.AddInt64(&np.Size, -1) }
 
+	isHit := atomic.LoadInt64(&np.Size) > 0
+	if isHit {
+		atomic.AddInt64(&np.Size, -1)
+		atomic.AddUint64(&np.Hits, 1)
+	} else { atomic.AddUint64(&np.Misses, 1) }
+
+	if np.IsAdaptive { np.observe(isHit) }
 	return node
 }
 
@@ -38,12 +74,43 @@ func (np *MMCMapNodePool) Get() *MMCMapNode {
 //	Attempt to put a node back into the pool once a path has been copied + serialized.
 //	If the pool is at max capacity, drop the node and let the garbage collector take care of it.
 func (np *MMCMapNodePool) Put(node *MMCMapNode) {
-	if atomic.LoadInt64(&np.Size) < np.MaxSize { 
+	if atomic.LoadInt64(&np.Size) < atomic.LoadInt64(&np.MaxSize) {
 		np.Pool.Put(np.resetNode(node))
 		atomic.AddInt64(&np.Size, 1)
 	}
 }
 
+// observe
+//	Count a request in the current window, and resize the pool once the window is full.
+func (np *MMCMapNodePool) observe(isHit bool) {
+	if ! isHit { atomic.AddUint64(&np.WindowMisses, 1) }
+	if atomic.AddUint64(&np.WindowRequests, 1) < NodePoolWindow { return }
+	if ! atomic.CompareAndSwapUint32(&np.IsAdapting, 0, 1) { return }
+	defer atomic.StoreUint32(&np.IsAdapting, 0)
+
+	requests := atomic.SwapUint64(&np.WindowRequests, 0)
+	misses := atomic.SwapUint64(&np.WindowMisses, 0)
+	now := time.Now().UnixNano()
+	elapsed := time.Duration(now - atomic.SwapInt64(&np.WindowStart, now))
+
+	maxSize := atomic.LoadInt64(&np.MaxSize)
+	switch {
+		case float64(misses) > NodePoolGrowMissRate * float64(requests) && maxSize < np.Limit:
+			grown := maxSize * 2
+			if grown < MinNodePoolSize { grown = MinNodePoolSize }
+			if grown > np.Limit { grown = np.Limit }
+
+			atomic.StoreInt64(&np.MaxSize, grown)
+			atomic.AddUint64(&np.Grows, 1)
+		case elapsed > NodePoolIdleInterval && maxSize > MinNodePoolSize:
+			shrunk := maxSize / 2
+			if shrunk < MinNodePoolSize { shrunk = MinNodePoolSize }
+
+			atomic.StoreInt64(&np.MaxSize, shrunk)
+			atomic.AddUint64(&np.Shrinks, 1)
+	}
+}
+
 // initializePool
 //	When the mmcmap is opened, initialize the pool with the max size of nodes.
 func (np *MMCMapNodePool) initializePool() {
@@ -63,6 +130,11 @@ func (np *MMCMapNodePool) resetNode(node *MMCMapNode) *MMCMapNode{
 	node.KeyLength = 0
 	node.Key = nil
 	node.Value = nil
+	node.ExpiresAt = 0
+	node.IsOverflow = false
+	node.OverflowOffset = 0
+	node.Compression = CompressionNone
+	node.EncodedValue = nil
 	node.Children = nil
 
 	return node
@@ -0,0 +1,191 @@
+package mmcmap
+
+import "context"
+import "fmt"
+import "math/rand"
+import "time"
+
+
+//============================================= MMCMap Open Check
+
+
+// runOpenCheck
+//	Validate an existing file at the level set in the options and record the findings in OpenReport.
+//	The metadata offsets are always checked before this runs. Quick validates the header and the latest root, and any finding fails the open since
+//	operations would start from a corrupt root. Deep also validates a sample of the trie, descending into children in random order until every node
+//	has been visited or the budget runs out, so repeated opens cover different parts of a trie too large to validate in full. The traversal stops
+//	once ctx is done, and ctx.Err() is returned.
+func (mmcMap *MMCMap) runOpenCheck(ctx context.Context) error {
+	start := time.Now()
+	report := &OpenCheckReport{ Check: mmcMap.Opts.OpenCheck }
+	mmcMap.OpenReport = report
+
+	defer func() { report.Duration = time.Since(start) }()
+
+	if mmcMap.Opts.OpenCheck == OpenCheckNone { return nil }
+
+	meta, readMetaErr := mmcMap.ReadMetaFromMemMap()
+	if readMetaErr != nil { return readMetaErr }
+
+	mmcMap.checkHeader(&report.Findings)
+	mmcMap.checkRoot(&report.Findings, meta, mmcMap.ReadNodeFromMemMap)
+
+	if len(report.Findings) > 0 { return fmt.Errorf("%w: %s", ErrOpenCheckFailed, report.Findings[0]) }
+	if mmcMap.Opts.OpenCheck != OpenCheckDeep { return nil }
+
+	ctxErr := ctx.Err()
+	if ctxErr != nil { return ctxErr }
+
+	budget := mmcMap.Opts.OpenCheckBudget
+	if budget <= 0 { budget = DefaultOpenCheckBudget }
+
+	checker := &nodeChecker{ mmcMap: mmcMap, meta: meta, read: mmcMap.ReadNodeFromMemMap, ctx: ctx, deadline: start.Add(budget), findings: report.Findings }
+	report.IsComplete = checker.checkNode(meta.RootOffset, meta.Version, 0, -1)
+	report.Findings = checker.findings
+	report.NodesVisited = checker.nodesVisited
+
+	ctxErr = ctx.Err()
+	if ctxErr != nil { return ctxErr }
+
+	if len(report.Findings) > 0 { mmcMap.log(LogWarn, "open check found inconsistencies", "findings", len(report.Findings), "first", report.Findings[0]) }
+	return nil
+}
+
+// checkHeader
+//	The header fields must be consistent with the header size resolved on load.
+func (mmcMap *MMCMap) checkHeader(findings *[]string) {
+	switch {
+		case mmcMap.HeaderSize != mmcMap.Header.dataOffset() && mmcMap.HeaderSize != LegacyInitRootOffset:
+			addFinding(findings, "invalid header size %d", mmcMap.HeaderSize)
+		case mmcMap.HeaderSize != LegacyInitRootOffset && mmcMap.Header.FormatVersion == 0:
+			addFinding(findings, "header format version is 0")
+	}
+}
+
+// checkRoot
+//	The latest root must be an internal node at the root offset, written at or before the end of the memory map, and no newer than the metadata.
+func (mmcMap *MMCMap) checkRoot(findings *[]string, meta *MMCMapMetaData, read func(offset uint64) (*MMCMapNode, error)) {
+	root, readErr := read(meta.RootOffset)
+	switch {
+		case readErr != nil:
+			addFinding(findings, "root at offset %d is unreadable: %s", meta.RootOffset, readErr.Error())
+		case root.StartOffset != meta.RootOffset:
+			addFinding(findings, "root start offset %d does not match metadata root offset %d", root.StartOffset, meta.RootOffset)
+		case root.IsLeaf:
+			addFinding(findings, "root at offset %d is a leaf node", meta.RootOffset)
+		case root.EndOffset > meta.EndMmapOffset:
+			addFinding(findings, "root end offset %d is past the end of the memory map %d", root.EndOffset, meta.EndMmapOffset)
+		case root.Version > meta.Version:
+			addFinding(findings, "root version %d is newer than metadata version %d", root.Version, meta.Version)
+	}
+}
+
+// nodeChecker holds the state of a traversal validating the trie
+type nodeChecker struct {
+	mmcMap *MMCMap
+	meta *MMCMapMetaData
+	read func(offset uint64) (*MMCMapNode, error)
+	// ctx: stops the traversal once done, like the deadline. A nil ctx never stops it
+	ctx context.Context
+	deadline time.Time
+	findings []string
+	nodesVisited uint64
+	keysVisited uint64
+	bytesVisited uint64
+	// verified: the keys and bytes of subtrees already found intact, keyed by offset. Set when checking many versions, which share subtrees
+	verified map[uint64]verifiedSubtree
+}
+
+// verifiedSubtree is a subtree found intact by a nodeChecker
+type verifiedSubtree struct {
+	keys uint64
+	bytes uint64
+}
+
+// checkNode
+//	Validate the node at offset and recurse into its children in random order. index is the sparse index the node occupies in its parent, or -1
+//	for the root. Returns false if the deadline passed or the context was done before the subtree was fully visited.
+func (checker *nodeChecker) checkNode(offset, parentVersion uint64, level int, index int) (complete bool) {
+	findings := &checker.findings
+	if checker.nodesVisited & 0xff == 0 && checker.isInterrupted() { return false }
+
+	if checker.verified != nil {
+		if subtree, ok := checker.verified[offset]; ok {
+			checker.keysVisited += subtree.keys
+			checker.bytesVisited += subtree.bytes
+			return true
+		}
+
+		keys, bytes, found := checker.keysVisited, checker.bytesVisited, len(checker.findings)
+		defer func() {
+			if complete && len(checker.findings) == found && found < MaxOpenCheckFindings {
+				checker.verified[offset] = verifiedSubtree{ keys: checker.keysVisited - keys, bytes: checker.bytesVisited - bytes }
+			}
+		}()
+	}
+
+	checker.nodesVisited++
+
+	if offset < checker.mmcMap.HeaderSize || offset >= checker.meta.EndMmapOffset {
+		addFinding(findings, "node offset %d is outside of the serialized data", offset)
+		return true
+	}
+
+	node, readErr := checker.read(offset)
+	if readErr != nil {
+		addFinding(findings, "node at offset %d is unreadable: %s", offset, readErr.Error())
+		return true
+	}
+
+	switch {
+		case node.StartOffset != offset:
+			addFinding(findings, "node at offset %d has start offset %d", offset, node.StartOffset)
+			return true
+		case node.EndOffset > checker.meta.EndMmapOffset:
+			addFinding(findings, "node at offset %d ends past the end of the memory map", offset)
+		case node.Version > parentVersion:
+			addFinding(findings, "node at offset %d has version %d newer than its parent %d", offset, node.Version, parentVersion)
+	}
+
+	if node.EndOffset >= node.StartOffset { checker.bytesVisited += node.EndOffset - node.StartOffset + 1 }
+	if node.IsLeaf {
+		checker.keysVisited++
+		if node.IsOverflow { checker.bytesVisited += uint64(len(node.Value)) }
+		if index >= 0 && level > 0 && ! checker.mmcMap.isCollisionLevel(level - 1) {
+			if checker.mmcMap.getKeyIndex(node.Key, level - 1) != index { addFinding(findings, "leaf at offset %d is misplaced for its key", offset) }
+		}
+
+		return true
+	}
+
+	if len(node.Children) != calculateHammingWeight(node.Bitmap) {
+		addFinding(findings, "node at offset %d has %d children for bitmap population %d", offset, len(node.Children), calculateHammingWeight(node.Bitmap))
+		return true
+	}
+
+	var indexes []int
+	for idx := 0; idx < 32; idx++ {
+		if IsBitSet(node.Bitmap, idx) { indexes = append(indexes, idx) }
+	}
+
+	for _, pos := range rand.Perm(len(node.Children)) {
+		complete := checker.checkNode(node.Children[pos].StartOffset, node.Version, level + 1, indexes[pos])
+		if ! complete { return false }
+	}
+
+	return true
+}
+
+// isInterrupted
+//	Determine if the traversal should stop. A zero deadline never passes, and a nil context is never done.
+func (checker *nodeChecker) isInterrupted() bool {
+	if ! checker.deadline.IsZero() && time.Now().After(checker.deadline) { return true }
+	return checker.ctx != nil && checker.ctx.Err() != nil
+}
+
+// addFinding
+//	Record a finding in the report, up to MaxOpenCheckFindings.
+func addFinding(findings *[]string, format string, args ...interface{}) {
+	if len(*findings) >= MaxOpenCheckFindings { return }
+	*findings = append(*findings, fmt.Sprintf(format, args...))
+}
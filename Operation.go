@@ -1,7 +1,7 @@
 package mmcmap
 
 import "bytes"
-import "runtime"
+import "fmt"
 import "sync/atomic"
 import "unsafe"
 
@@ -11,118 +11,101 @@ import "unsafe"
 
 // Put inserts or updates key-value pair into the hash array mapped trie.
 //	The operation begins at the root of the trie and traverses through the tree until the correct location is found, copying the entire path.
-//	If the operation fails, the copied and modified path is discarded and the operation retries back at the root until completed.
+//	If another commit published a new version while the path was being copied, the copy is rebased onto it when the two commits modified different
+//	subtrees, and otherwise discarded so the operation retries back at the root until completed.
 //	The operation begins at the latest known version of root, read from the metadata in the memory map. The version of the copy is incremented
 //	and if the metadata is the same after the path copying has occured, the path is serialized and appended to the memory-map, with the metadata
 //	also being updated to reflect the new version and the new root offset.
 func (mmcMap *MMCMap) Put(key, value []byte) (bool, error) {
-	for {
-		for atomic.LoadUint32(&mmcMap.IsResizing) == 1 { runtime.Gosched() }
-		mmcMap.RWResizeLock.RLock()
-
-		versionPtr, version, loadVErr := mmcMap.loadMetaVersion()
-		if loadVErr != nil { return false, loadVErr }
-
-		if version == atomic.LoadUint64(versionPtr) {
-			_, rootOffset, loadROffErr := mmcMap.loadMetaRootOffset()
-			if loadROffErr != nil { return false, loadROffErr }
-	
-			currRoot, readRootErr := mmcMap.ReadNodeFromMemMap(rootOffset)
-			if readRootErr != nil {
-				mmcMap.RWResizeLock.RUnlock()
-				return false, readRootErr
-			}
-	
-			currRoot.Version = currRoot.Version + 1
-			rootPtr := storeNodeAsPointer(currRoot)
-	
-			_, putErr := mmcMap.putRecursive(rootPtr, key, value, 0)
-			if putErr != nil {
-				mmcMap.RWResizeLock.RUnlock()
-				return false, putErr
-			}
-
-			updatedRootCopy := loadNodeFromPointer(rootPtr)
-			ok, writeErr := mmcMap.exclusiveWriteMmap(updatedRootCopy)
-			if writeErr != nil {
-				mmcMap.RWResizeLock.RUnlock()
-				return false, writeErr
-			}
-
-			if ok {
-				mmcMap.RWResizeLock.RUnlock() 
-				return true, nil 
-			}
-		}
+	ops := []*BatchOp{{ Key: key, Value: value }}
+	ok, _, putErr := mmcMap.commitTimed(&mmcMap.PutLatency, func(root *MMCMapNode) ([]*BatchOp, error) { return ops, nil })
+	return ok, putErr
+}
 
-		mmcMap.RWResizeLock.RUnlock()
-	}
+// PutVersioned
+//	Same as Put, but returns the version the key-value pair was committed at, so the write can be correlated with snapshots, history, and watch events.
+func (mmcMap *MMCMap) PutVersioned(key, value []byte) (uint64, error) {
+	ops := []*BatchOp{{ Key: key, Value: value }}
+	_, version, putErr := mmcMap.commitTimed(&mmcMap.PutLatency, func(root *MMCMapNode) ([]*BatchOp, error) { return ops, nil })
+	return version, putErr
 }
 
 // putRecursive
 //	Attempts to traverse through the trie, locating the node at a given level to modify for the key-value pair.
-//	It first hashes the key, determines the sparse index in the bitmap to modify, and creates a copy of the current node to be modified.
+//	It first determines the sparse index of the key in the bitmap to modify, and creates a copy of the current node to be modified.
 //	If the bit in the bitmap of the node is not set, a new leaf node is created, the bitmap of the copy is modified to reflect the position of the new leaf node, and the child node array is extended to include the new leaf node.
 //	Then, an atomic compare and swap operation is performed where the operation attempts to replace the current node with the modified copy.
 //	If the operation succeeds the response is returned by moving back up the tree. If it fails, the copy is discarded and the operation returns to the root to be reattempted.
 //	If the current bit is set in the bitmap, the operation checks if the node at the location in the child node array is a leaf node or an internal node.
 //	If it is a leaf node and the key is the same as the incoming key, the copy is modified with the new value and we attempt to compare and swap the current child leaf node with the new copy.
 //	If the leaf node does not contain the same key, the operation creates a new internal node, and inserts the new leaf node for the incoming key and value as well as the existing child node into the new internal node.
+//	The existing child node is moved into the new internal node as is, so a leaf read from the memory map is referenced by its offset instead of being written again.
 //	Attempts to compare and swap the current leaf node with the new internal node containing the existing child node and the new leaf node for the incoming key and value.
 //	If the node is an internal node, the operation traverses down the tree to the internal node and the above steps are repeated until the key-value pair is inserted.
-func (mmcMap *MMCMap) putRecursive(node *unsafe.Pointer, key, value []byte, level int) (bool, error) {
+//	Since the path being modified is a private copy, the compare and swap always succeeds, and the returned flag instead reports if the key was newly inserted.
+//	The leaf is written with expiresAt, so putting a key without an expiration clears any expiration it had.
+//	At the collision level, the node is a collision bucket and the key is placed by comparing it to the keys already in the bucket.
+func (mmcMap *MMCMap) putRecursive(node *unsafe.Pointer, key, value []byte, expiresAt int64, level int) (bool, error) {
 	var putErr error
 
-	hash := mmcMap.calculateHashForCurrentLevel(key, level)
-	index := mmcMap.getSparseIndex(hash, level)
+	if mmcMap.isCollisionLevel(level) { return mmcMap.putCollision(node, key, value, expiresAt) }
+
+	index := mmcMap.getKeyIndex(key, level)
 
 	currNode := loadNodeFromPointer(node)
 	nodeCopy := mmcMap.copyNode(currNode)
 
 	if ! IsBitSet(nodeCopy.Bitmap, index) {
-		newLeaf := mmcMap.newLeafNode(key, value, nodeCopy.Version)
+		newLeaf := mmcMap.newLeafNode(key, value, expiresAt, nodeCopy.Version)
 		nodeCopy.Bitmap = SetBit(nodeCopy.Bitmap, index)
 
-		pos := mmcMap.getPosition(nodeCopy.Bitmap, hash, level)
+		pos := childPosition(nodeCopy.Bitmap, index)
 		nodeCopy.Children = extendTable(nodeCopy.Children, nodeCopy.Bitmap, pos, newLeaf)
 
-		return mmcMap.compareAndSwap(node, currNode, nodeCopy), nil
+		mmcMap.compareAndSwap(node, currNode, nodeCopy)
+		return true, nil
 	} else {
-		pos := mmcMap.getPosition(nodeCopy.Bitmap, hash, level)
+		pos := childPosition(nodeCopy.Bitmap, index)
 		childOffset := nodeCopy.Children[pos]
 
 		childNode, getChildErr := mmcMap.getChildNode(childOffset, nodeCopy.Version)
 		if getChildErr != nil { return false, getChildErr }
 
-		childNode.Version = nodeCopy.Version
-
 		if childNode.IsLeaf {
 			if bytes.Equal(key, childNode.Key) {
-				childNode.Value = value
-				nodeCopy.Children[pos] = childNode
+				nodeCopy.Children[pos] = mmcMap.updateLeafNode(childNode, value, expiresAt, nodeCopy.Version)
 
-				return mmcMap.compareAndSwap(node, currNode, nodeCopy), nil
+				mmcMap.compareAndSwap(node, currNode, nodeCopy)
+				return false, nil
 			} else {
 				newINode := mmcMap.newInternalNode(nodeCopy.Version)
 				iNodePtr := storeNodeAsPointer(newINode)
 
-				_, putErr = mmcMap.putRecursive(iNodePtr, childNode.Key, childNode.Value, level + 1)
-				if putErr != nil { return false, putErr }
+				if mmcMap.isCollisionLevel(level + 1) {
+					newINode.Bitmap = collisionBitmap(1)
+				} else {
+					newINode.Bitmap = SetBit(newINode.Bitmap, mmcMap.getKeyIndex(childNode.Key, level + 1))
+				}
+
+				newINode.Children = []*MMCMapNode{ childNode }
 
-				_, putErr = mmcMap.putRecursive(iNodePtr, key, value, level + 1)
+				_, putErr = mmcMap.putRecursive(iNodePtr, key, value, expiresAt, level + 1)
 				if putErr != nil { return false, putErr }
 
 				nodeCopy.Children[pos] = loadNodeFromPointer(iNodePtr)
-				return mmcMap.compareAndSwap(node, currNode, nodeCopy), nil
+				mmcMap.compareAndSwap(node, currNode, nodeCopy)
+				return true, nil
 			}
 		} else {
+			childNode.Version = nodeCopy.Version
 			unsafeChildPtr := storeNodeAsPointer(childNode)
 
-			_, putErr = mmcMap.putRecursive(unsafeChildPtr, key, value, level + 1)
+			isNewKey, putErr := mmcMap.putRecursive(unsafeChildPtr, key, value, expiresAt, level + 1)
 			if putErr != nil { return false, putErr }
 
 			nodeCopy.Children[pos] = loadNodeFromPointer(unsafeChildPtr)
-			return mmcMap.compareAndSwap(node, currNode, nodeCopy), nil
+			mmcMap.compareAndSwap(node, currNode, nodeCopy)
+			return isNewKey, nil
 		}
 	}
 }
@@ -132,50 +115,98 @@ func (mmcMap *MMCMap) putRecursive(node *unsafe.Pointer, key, value []byte, leve
 //	It gets the latest version of the hash array mapped trie and starts from that offset in the mem-map.
 //	The operation begins at the root of the trie and traverses down the path to the key.
 //	Get is concurrent since it will perform the operation on an existing path, so new paths can be written at the same time with new versions.
+//	The resize lock is not taken unless it is held exclusively, so reads do not wait on resizes, or on a remap beyond the time to map the new file.
+//	Internal nodes on the way down are not deserialized, only the child slot for the key is read from each, as described in lookupLeaf.
+//	The value is copied out of the memory map, so it is owned by the caller, unless the map was opened with CopyNever.
 func (mmcMap *MMCMap) Get(key []byte) ([]byte, error) {
-	for atomic.LoadUint32(&mmcMap.IsResizing) == 1 { runtime.Gosched() }
+	atomic.AddUint64(&mmcMap.Gets, 1)
+	defer mmcMap.recordLatency(&mmcMap.GetLatency, mmcMap.startLatency(), 0)
 
-	mmcMap.RWResizeLock.RLock()
-	defer mmcMap.RWResizeLock.RUnlock()
+	epoch, isLockFree := mmcMap.beginRead()
+	defer mmcMap.endRead(epoch, isLockFree)
+
+	handleErr := mmcMap.checkHandle()
+	if handleErr != nil { return nil, handleErr }
 
 	_, rootOffset, loadROffErr := mmcMap.loadMetaRootOffset()
 	if loadROffErr != nil { return nil, loadROffErr }
 
-	currRoot, readRootErr := mmcMap.ReadNodeFromMemMap(rootOffset)
-	if readRootErr != nil { return nil, readRootErr }
+	value, getErr := mmcMap.lookupValue(rootOffset, key)
+	if getErr != nil { return nil, getErr }
+
+	if value == nil && mmcMap.Opts.StrictGet { return nil, ErrKeyNotFound }
+	return mmcMap.readValue(value), nil
+}
+
+// GetMulti
+//	Retrieve the values for many keys against a single root.
+//	The metadata is loaded once, so every key is resolved against the same consistent version of the trie.
+//	A pair is returned for each key, in the same order as keys, with a nil Value for keys that do not exist.
+func (mmcMap *MMCMap) GetMulti(keys [][]byte) ([]*KeyValuePair, error) {
+	atomic.AddUint64(&mmcMap.Gets, uint64(len(keys)))
+
+	epoch, isLockFree := mmcMap.beginRead()
+	defer mmcMap.endRead(epoch, isLockFree)
+
+	handleErr := mmcMap.checkHandle()
+	if handleErr != nil { return nil, handleErr }
+
+	_, rootOffset, loadROffErr := mmcMap.loadMetaRootOffset()
+	if loadROffErr != nil { return nil, loadROffErr }
 
-	rootPtr := unsafe.Pointer(currRoot)
-	return mmcMap.getRecursive(&rootPtr, key, 0)
+	pairs := make([]*KeyValuePair, len(keys))
+	for idx, key := range keys {
+		value, getErr := mmcMap.lookupValue(rootOffset, key)
+		if getErr != nil { return nil, getErr }
+
+		pairs[idx] = &KeyValuePair{ Key: key, Value: mmcMap.readValue(value) }
+	}
+
+	return pairs, nil
+}
+
+// readValue
+//	The value returned by Get and GetMulti. Slices of the memory map become invalid once it is unmapped on a resize, Compact, or Close, so values
+//	are copied out of it unless CopyOnRead is CopyNever.
+func (mmcMap *MMCMap) readValue(value []byte) []byte {
+	if value == nil || mmcMap.Opts.CopyOnRead == CopyNever { return value }
+	return append([]byte{}, value...)
 }
 
-// getRecursive
-//	Attempts to recursively retrieve a value for a given key within the hash array mapped trie.
+// getLeafRecursive
+//	Attempts to recursively locate the leaf for a given key within the hash array mapped trie, returning nil if the key does not exist.
 //	For each node traversed to at each level the operation travels to, the sparse index is calculated for the hashed key.
 //	If the bit is not set in the bitmap, return nil since the key has not been inserted yet into the trie.
 //	Otherwise, determine the position in the child node array for the sparse index.
-//	If the child node is a leaf node and the key to be searched for is the same as the key of the child node, the value has been found.
-//	Since the trie utilizes path copying, any threads modifying the trie are modifying copies so it the get operation returns the value at the point in time of the get operation.
+//	If the child node is a leaf node and the key to be searched for is the same as the key of the child node, the leaf has been found.
+//	Since the trie utilizes path copying, any threads modifying the trie are modifying copies so it the get operation returns the leaf at the point in time of the get operation.
 //	If the node is node a leaf node, but instead an internal node, recurse down the path to the next level to the child node in the position of the child node array and repeat the above.
-func (mmcMap *MMCMap) getRecursive(node *unsafe.Pointer, key []byte, level int) ([]byte, error) {
+//	Expired leaves are returned. Used for roots whose children are deserialized, and committed roots are looked up with lookupLeaf instead.
+//	An internal node below the deepest level the key can reach returns ErrCorruptNode.
+func (mmcMap *MMCMap) getLeafRecursive(node *unsafe.Pointer, key []byte, level int) (*MMCMapNode, error) {
 	currNode := loadNodeFromPointer(node)
 
 	if currNode.IsLeaf && bytes.Equal(key, currNode.Key) {
-		return currNode.Value, nil
+		return currNode, nil
+	} else if mmcMap.isCollisionLevel(level) {
+		_, leaf, findErr := mmcMap.findCollision(currNode, key)
+		return leaf, findErr
 	} else {
-		hash := mmcMap.calculateHashForCurrentLevel(key, level)
-		index := mmcMap.getSparseIndex(hash, level)
+		if level > mmcMap.maxKeyLevel(key) { return nil, fmt.Errorf("%w at offset %d: internal node below the deepest level of the key", ErrCorruptNode, currNode.StartOffset) }
+
+		index := mmcMap.getKeyIndex(key, level)
 
 		if ! IsBitSet(currNode.Bitmap, index) {
 			return nil, nil
 		} else {
-			pos := mmcMap.getPosition(currNode.Bitmap, hash, level)
+			pos := childPosition(currNode.Bitmap, index)
 			childPtr := currNode.Children[pos]
 
 			childNode, desErr := mmcMap.ReadNodeFromMemMap(childPtr.StartOffset)
 			if desErr != nil { return nil, desErr }
 
 			unsafeChildPtr := storeNodeAsPointer(childNode)
-			return mmcMap.getRecursive(unsafeChildPtr, key, level + 1)
+			return mmcMap.getLeafRecursive(unsafeChildPtr, key, level + 1)
 		}
 	}
 }
@@ -186,104 +217,327 @@ func (mmcMap *MMCMap) getRecursive(node *unsafe.Pointer, key []byte, level int)
 //	The operation creates an entire, in-memory copy of the path down to the key, where if the metadata hasn't changed during the copy, will get exclusive
 //	write access to the memory-map, where the new path is serialized and appened to the end of the mem-map.
 //	If the operation succeeds truthy value is returned, otherwise the operation returns to the root to retry the operation.
+//	If the key does not exist at the latest version, no new version is written and false is returned, so deletes of missing keys can be told apart
+//	from real deletions.
 func (mmcMap *MMCMap) Delete(key []byte) (bool, error) {
-	for {		
-		for atomic.LoadUint32(&mmcMap.IsResizing) == 1 { runtime.Gosched() }
-		mmcMap.RWResizeLock.RLock()
-
-		versionPtr, version, loadROffErr := mmcMap.loadMetaVersion()
-		if loadROffErr != nil { return false, loadROffErr }
-
-		if version == atomic.LoadUint64(versionPtr) {
-			_, rootOffset, loadROffErr := mmcMap.loadMetaRootOffset()
-			if loadROffErr != nil { return false, loadROffErr }
-
-			currRoot, readRootErr := mmcMap.ReadNodeFromMemMap(rootOffset)
-			if readRootErr != nil {
-				mmcMap.RWResizeLock.RUnlock()
-				return false, readRootErr
-			}
-
-			currRoot.Version = currRoot.Version + 1
-			rootPtr := storeNodeAsPointer(currRoot)
-
-			_, delErr := mmcMap.deleteRecursive(rootPtr, key, 0)
-			if delErr != nil {
-				mmcMap.RWResizeLock.RUnlock()
-				return false, delErr
-			}
+	ok, _, delErr := mmcMap.commitTimed(&mmcMap.DeleteLatency, deleteExisting(mmcMap, key))
+	return ok, delErr
+}
 
-			updatedRootCopy := loadNodeFromPointer(rootPtr)
-			ok, writeErr := mmcMap.exclusiveWriteMmap(updatedRootCopy)
-			if writeErr != nil {
-				mmcMap.RWResizeLock.RUnlock()
-				return false, writeErr
-			}
+// DeleteVersioned
+//	Same as Delete, but returns the version the deletion was committed at. If the key does not exist, no new version is written and 0 is returned.
+func (mmcMap *MMCMap) DeleteVersioned(key []byte) (uint64, error) {
+	_, version, delErr := mmcMap.commitTimed(&mmcMap.DeleteLatency, deleteExisting(mmcMap, key))
+	return version, delErr
+}
 
-			if ok { 
-				mmcMap.RWResizeLock.RUnlock()
-				return true, nil 
-			}
-		}
+// deleteExisting
+//	A commit precondition that deletes the key only if it is present in the root the path copy is made from, aborting the commit otherwise.
+func deleteExisting(mmcMap *MMCMap, key []byte) func(root *MMCMapNode) ([]*BatchOp, error) {
+	return func(root *MMCMapNode) ([]*BatchOp, error) {
+		currValue, getErr := mmcMap.getFromRoot(root, key)
+		if getErr != nil { return nil, getErr }
 
-		mmcMap.RWResizeLock.RUnlock()
+		if currValue == nil { return nil, errCommitAborted }
+		return []*BatchOp{{ Key: key, IsDelete: true }}, nil
 	}
 }
 
 // deleteRecursive
 //	Attempts to recursively move down the path of the trie to the key-value pair to be deleted.
 //	The hash for the key is calculated, the sparse index in the bitmap is determined for the given level, and a copy of the current node is created to be modifed.
-//	If the bit in the bitmap is not set, the key doesn't exist so truthy is returned since there is nothing to delete and the operation completes.
+//	If the bit in the bitmap is not set, the key doesn't exist so falsey is returned since there is nothing to delete and the operation completes.
 //	If the bit is set, the child node for the position within the child node array is found.
-//	If the child node is a leaf node and the key of the child node is equal to the key of the key to delete, the copy is modified to update the bitmap and shrink the table and remove the given node.
-//	A compare and swap operation is performed, and if successful traverse back up the trie and complete, otherwise the operation is returned to the root to retry.
+//	If the child node is a leaf node and the key of the child node is equal to the key of the key to delete, the copy is modified to unset the bit in the bitmap and shrink the table to remove the given node.
 //	If the child node is an internal node, the operation recurses down the trie to the next level.
-//	On return, if the internal node is empty, the copy modified so the bitmap is updated and table is shrunk.
-//	A compare and swap operation is performed on the current node with the new copy.
+//	On return, if the key was removed, the child is collapsed: an empty child is removed from the copy, and a child left holding a single leaf is replaced
+//	by that leaf, which is where a put would have placed the key had it been the only key under the slot. Collapsing on the way back up removes chains of
+//	single child internal nodes, so the trie after a delete is the same trie the remaining keys would have produced, and the path written is no longer
+//	than necessary. The leaf is moved by reference, so it is not copied unless it was already on the path.
+//	If the key was not found, the path is left untouched.
+//	Since the path being modified is a private copy, the compare and swap always succeeds, and the returned flag instead reports if the key was removed.
+//	At the collision level, the leaf for the key is removed from the collision bucket.
 func (mmcMap *MMCMap) deleteRecursive(node *unsafe.Pointer, key []byte, level int) (bool, error) {
-	hash := mmcMap.calculateHashForCurrentLevel(key, level)
-	index := mmcMap.getSparseIndex(hash, level)
+	if mmcMap.isCollisionLevel(level) { return mmcMap.deleteCollision(node, key) }
+
+	index := mmcMap.getKeyIndex(key, level)
 	
 	currNode := loadNodeFromPointer(node)
-	nodeCopy := mmcMap.copyNode(currNode)
 
-	if ! IsBitSet(nodeCopy.Bitmap, index) {
-		return true, nil
+	if ! IsBitSet(currNode.Bitmap, index) {
+		return false, nil
 	} else {
-		pos := mmcMap.getPosition(nodeCopy.Bitmap, hash, level)
-		childOffset := nodeCopy.Children[pos]
+		pos := childPosition(currNode.Bitmap, index)
+		childOffset := currNode.Children[pos]
 
-		childNode, getChildErr := mmcMap.getChildNode(childOffset, nodeCopy.Version)
+		childNode, getChildErr := mmcMap.getChildNode(childOffset, currNode.Version)
 		if getChildErr != nil { return false, getChildErr }
 
-		childNode.Version = nodeCopy.Version
-
 		if childNode.IsLeaf {
-			if bytes.Equal(key, childNode.Key) {
-				nodeCopy.Bitmap = SetBit(nodeCopy.Bitmap, index)
-				nodeCopy.Children = shrinkTable(nodeCopy.Children, nodeCopy.Bitmap, pos)
+			if ! bytes.Equal(key, childNode.Key) { return false, nil }
 
-				return mmcMap.compareAndSwap(node, currNode, nodeCopy), nil
-			}
+			nodeCopy := mmcMap.copyNode(currNode)
+			nodeCopy.Bitmap = ClearBit(nodeCopy.Bitmap, index)
+			nodeCopy.Children = shrinkTable(nodeCopy.Children, nodeCopy.Bitmap, pos)
 
-			return false, nil
+			mmcMap.compareAndSwap(node, currNode, nodeCopy)
+			return true, nil
 		} else {
+			childNode.Version = currNode.Version
 			childPtr := storeNodeAsPointer(childNode)
 
-			_, delErr := mmcMap.deleteRecursive(childPtr, key, level + 1)
+			isRemoved, delErr := mmcMap.deleteRecursive(childPtr, key, level + 1)
 			if delErr != nil { return false, delErr }
-
-			popCount := calculateHammingWeight(nodeCopy.Bitmap)
-			if popCount == 0 {
-				nodeCopy.Bitmap = SetBit(nodeCopy.Bitmap, index)
-				nodeCopy.Children = shrinkTable(nodeCopy.Children, nodeCopy.Bitmap, pos)
+			if ! isRemoved { return false, nil }
+
+			nodeCopy := mmcMap.copyNode(currNode)
+			updatedChild := loadNodeFromPointer(childPtr)
+
+			switch calculateHammingWeight(updatedChild.Bitmap) {
+				case 0:
+					nodeCopy.Bitmap = ClearBit(nodeCopy.Bitmap, index)
+					nodeCopy.Children = shrinkTable(nodeCopy.Children, nodeCopy.Bitmap, pos)
+				case 1:
+					onlyChild, getOnlyErr := mmcMap.getChildNode(updatedChild.Children[0], updatedChild.Version)
+					if getOnlyErr != nil { return false, getOnlyErr }
+
+					if onlyChild.IsLeaf {
+						nodeCopy.Children[pos] = onlyChild
+					} else { nodeCopy.Children[pos] = updatedChild }
+				default:
+					nodeCopy.Children[pos] = updatedChild
 			}
 
-			return mmcMap.compareAndSwap(node, currNode, nodeCopy), nil
+			mmcMap.compareAndSwap(node, currNode, nodeCopy)
+			return true, nil
 		}
 	}
 }
 
+// commitOps
+//	Applies a list of puts and deletes to a single path copy of the latest root and commits them as one new version.
+func (mmcMap *MMCMap) commitOps(ops []*BatchOp) (bool, error) {
+	return mmcMap.commitWith(func(root *MMCMapNode) ([]*BatchOp, error) { return ops, nil })
+}
+
+// commitWith
+//	Commits the mutations returned by prepare as one new version of the mmcmap.
+//	prepare is called with the root of the latest version before anything is copied, so it can inspect the current state of the trie and decide which
+//	mutations to make. Every mutation is then applied to the same path copy, so nodes created earlier in the list are reused by later mutations instead
+//	of being re-read from the memory map. If the metadata changed while the path was being copied, the copy is discarded and prepare is called again
+//	against the new root. If prepare returns errCommitAborted, nothing is written and false is returned.
+//	With SingleWriter, the commit is handed to the writer go routine and the caller waits for the result. Followers return ErrFollowerReadOnly.
+func (mmcMap *MMCMap) commitWith(prepare func(root *MMCMapNode) ([]*BatchOp, error)) (bool, error) {
+	ok, _, commitErr := mmcMap.commitVersioned(prepare)
+	return ok, commitErr
+}
+
+// commitVersioned
+//	Same as commitWith, but also returns the version the mutations were committed at, or 0 if no new version was written.
+func (mmcMap *MMCMap) commitVersioned(prepare func(root *MMCMapNode) ([]*BatchOp, error)) (bool, uint64, error) {
+	ok, version, _, commitErr := mmcMap.commitCounted(prepare)
+	return ok, version, commitErr
+}
+
+// commitCounted
+//	Same as commitVersioned, but also returns the number of attempts that had to be retried.
+func (mmcMap *MMCMap) commitCounted(prepare func(root *MMCMapNode) ([]*BatchOp, error)) (bool, uint64, uint64, error) {
+	stallErr := mmcMap.awaitFlush()
+	if stallErr != nil { return false, 0, 0, stallErr }
+
+	if mmcMap.Opts.SingleWriter {
+		future := mmcMap.submitWrite(prepare)
+		ok, waitErr := future.Wait()
+		return ok, future.version, future.retries, waitErr
+	}
+
+	var retries uint64
+	ok, version, commitErr := mmcMap.commitLoop(prepare, &retries)
+	return ok, version, retries, commitErr
+}
+
+// commitLoop
+//	Attempt the commit until it either succeeds or fails without needing a retry, counting each retry in retries.
+//	With write shards, the lane of the shard is held across attempts, and an attempt abandoned to wait for the lane is not counted as a retry.
+func (mmcMap *MMCMap) commitLoop(prepare func(root *MMCMapNode) ([]*BatchOp, error), retries *uint64) (bool, uint64, error) {
+	lane := mmcMap.newWriteLane()
+	defer lane.release()
+
+	for {
+		ok, version, retry, commitErr := mmcMap.attemptCommit(prepare, lane)
+		if commitErr == errCommitAborted { return false, 0, nil }
+		if ! retry { return ok, version, commitErr }
+
+		if lane.isWaiting() {
+			lane.wait()
+			continue
+		}
+
+		atomic.AddUint64(&mmcMap.CommitRetries, 1)
+		*retries++
+	}
+}
+
+// commitWithGateHeld
+//	Same as commitWith, but for callers that already hold the commit gate exclusively, like the multi-map Coordinator.
+func (mmcMap *MMCMap) commitWithGateHeld(prepare func(root *MMCMapNode) ([]*BatchOp, error)) (bool, error) {
+	for {
+		ok, _, retry, commitErr := mmcMap.tryCommit(prepare, nil)
+		if commitErr == errCommitAborted { return false, nil }
+		if ! retry { return ok, commitErr }
+
+		atomic.AddUint64(&mmcMap.CommitRetries, 1)
+	}
+}
+
+// attemptCommit
+//	A single attempt at copying the path from the latest root and committing it. Returns retry if the metadata changed during the attempt.
+//	The commit gate is held for the duration of the attempt, so Quiesce waits for in-flight attempts and blocks new ones.
+func (mmcMap *MMCMap) attemptCommit(prepare func(root *MMCMapNode) ([]*BatchOp, error), lane *writeLane) (ok bool, committedVersion uint64, retry bool, err error) {
+	mmcMap.CommitGate.RLock()
+	defer mmcMap.CommitGate.RUnlock()
+
+	return mmcMap.tryCommit(prepare, lane)
+}
+
+// tryCommit
+//	The body of a commit attempt. The caller is responsible for the commit gate, and for waiting on the lane of the write shard if the attempt could
+//	not claim it. lane is nil when the map has no write shards, or the caller holds the commit gate exclusively.
+//	A commit whose ops all fall in one write shard copies its path from the root of the latest commit to the shard, and is placed on the latest root
+//	by commitToShard, so it only retries against commits to the same shard.
+//	The version is the one the path copy was written at, or 0 if nothing was written.
+//	Every commit path ends here, so followers are rejected with ErrFollowerReadOnly before anything is prepared.
+func (mmcMap *MMCMap) tryCommit(prepare func(root *MMCMapNode) ([]*BatchOp, error), lane *writeLane) (ok bool, committedVersion uint64, retry bool, err error) {
+	span := mmcMap.startSpan(nil, SpanCommit)
+	defer func() {
+		span.SetAttribute("version", committedVersion)
+		span.SetAttribute("retry", retry)
+		if err == errCommitAborted {
+			span.SetAttribute("aborted", true)
+			span.End(nil)
+		} else { span.End(err) }
+	}()
+
+	mmcMap.waitForResize()
+
+	mmcMap.RWResizeLock.RLock()
+	defer mmcMap.RWResizeLock.RUnlock()
+
+	handleErr := mmcMap.checkHandle()
+	if handleErr != nil { return false, 0, false, handleErr }
+	if mmcMap.isFollower() { return false, 0, false, ErrFollowerReadOnly }
+
+	versionPtr, version, loadVErr := mmcMap.loadMetaVersion()
+	if loadVErr != nil { return false, 0, false, loadVErr }
+
+	if version != loadMetaPointer(versionPtr) { return false, 0, true, nil }
+
+	rootOffset, currRoot, ops, prepareErr := mmcMap.prepareFromLatest(prepare)
+	if prepareErr != nil { return false, 0, false, prepareErr }
+	if len(ops) == 0 { return true, 0, false, nil }
+
+	// the commit holding the shard most likely modifies the same paths, so wait for it and start again from the root it publishes
+	if ! lane.claim(ops) { return false, 0, true, nil }
+
+	span.SetAttribute("ops", len(ops))
+
+	baseVersion := currRoot.Version
+	shard := mmcMap.opsShard(ops)
+
+	var slot *shardSlot
+	if shard >= 0 {
+		var shardBase *MMCMapNode
+		var loadBaseErr error
+		slot, shardBase, loadBaseErr = mmcMap.loadShardBase(shard, baseVersion)
+		if loadBaseErr != nil { return false, 0, false, loadBaseErr }
+		if slot != nil { currRoot = shardBase }
+	}
+
+	currRoot.Version = currRoot.Version + 1
+	rootPtr := storeNodeAsPointer(currRoot)
+
+	isWatched := mmcMap.isWatched()
+	rebaseable := true
+
+	var keyDelta int64
+	var puts, deletes uint64
+	var events []ChangeEvent
+	for _, op := range ops {
+		if len(op.Key) > MaxKeyLength { return false, 0, false, ErrKeyTooLarge }
+		if ! op.IsDelete && op.leafOffset == 0 {
+			sizeErr := mmcMap.checkPutSize(op.Key, int64(len(op.Value)))
+			if sizeErr != nil { return false, 0, false, sizeErr }
+		}
+
+		var changed bool
+		var opErr error
+		if op.IsDelete {
+			changed, opErr = mmcMap.deleteRecursive(rootPtr, op.Key, 0)
+			if changed {
+				keyDelta--
+				deletes++
+			} else { rebaseable = false }
+		} else {
+			changed, opErr = mmcMap.putRecursive(rootPtr, op.Key, op.Value, op.ExpiresAt, 0)
+			if changed { keyDelta++ }
+			puts++
+			if opErr == nil && op.leafOffset != 0 { opErr = mmcMap.referenceLeaf(rootPtr, op.Key, op.leafOffset) }
+		}
+
+		if opErr != nil { return false, 0, false, opErr }
+		if isWatched && (changed || ! op.IsDelete) { events = append(events, newChangeEvent(op, currRoot.Version)) }
+	}
+
+	if slot != nil {
+		newVersion, retry, shardErr := mmcMap.commitToShard(loadNodeFromPointer(rootPtr), shard, slot, baseVersion, ops, prepare, keyDelta, events, span)
+		if shardErr != nil { return false, 0, false, shardErr }
+		if retry { return false, 0, true, nil }
+
+		atomic.AddUint64(&mmcMap.Puts, puts)
+		atomic.AddUint64(&mmcMap.Deletes, deletes)
+		return true, newVersion, false, nil
+	}
+
+	updatedRootCopy, retry, rebaseErr := mmcMap.rebaseCommit(loadNodeFromPointer(rootPtr), rootOffset, version, ops, rebaseable, prepare)
+	if rebaseErr != nil { return false, 0, false, rebaseErr }
+	if retry { return false, 0, true, nil }
+
+	newVersion := updatedRootCopy.Version
+	for idx := range events { events[idx].Version = newVersion }
+
+	written, writeErr := mmcMap.exclusiveWriteMmap(updatedRootCopy, keyDelta, events, mmcMap.opsShards(ops), span)
+	if writeErr == ErrResizeInProgress { return false, 0, true, nil }
+	if writeErr != nil { return false, 0, false, writeErr }
+
+	if ! written { return false, 0, true, nil }
+
+	atomic.AddUint64(&mmcMap.Puts, puts)
+	atomic.AddUint64(&mmcMap.Deletes, deletes)
+	return true, newVersion, false, nil
+}
+
+// prepareFromLatest
+//	Read the latest root from the memory map and call prepare with it, returning the offset of the root, the root, and the ops to apply to it.
+func (mmcMap *MMCMap) prepareFromLatest(prepare func(root *MMCMapNode) ([]*BatchOp, error)) (uint64, *MMCMapNode, []*BatchOp, error) {
+	_, rootOffset, loadROffErr := mmcMap.loadMetaRootOffset()
+	if loadROffErr != nil { return 0, nil, nil, loadROffErr }
+
+	currRoot, readRootErr := mmcMap.ReadNodeFromMemMap(rootOffset)
+	if readRootErr != nil { return 0, nil, nil, readRootErr }
+
+	ops, prepareErr := prepare(currRoot)
+	if prepareErr != nil { return 0, nil, nil, prepareErr }
+
+	return rootOffset, currRoot, ops, nil
+}
+
+// getFromRoot
+//	Retrieve the value for a key starting from a root that has been read from the memory map. The root is committed, so the key is located with
+//	lookupValue, which only reads the child slot for the key from each internal node below it. The resize read lock must be held.
+func (mmcMap *MMCMap) getFromRoot(root *MMCMapNode, key []byte) ([]byte, error) {
+	return mmcMap.lookupValue(root.StartOffset, key)
+}
+
 // compareAndSwap
 //	Performs CAS opertion.
 func (mmcMap *MMCMap) compareAndSwap(node *unsafe.Pointer, currNode, nodeCopy *MMCMapNode) bool {
@@ -0,0 +1,52 @@
+package mmcmap
+
+
+//============================================= MMCMap Ordered Keys
+
+
+// isOrderedKeys
+//	Determine if keys are placed in the trie by their raw bytes instead of their hash.
+func (mmcMap *MMCMap) isOrderedKeys() bool {
+	return mmcMap.Header.KeyMode == KeyModeOrdered
+}
+
+// getOrderedIndex
+//	Gets the sparse index of a key at a particular level in a file using KeyModeOrdered.
+//	Each level covers the next OrderedChunkSize bits of the key, most significant first, placed at 1 plus their value. Once the key is exhausted it
+//	is placed at 0, which sorts before every chunk, so a key sorts before the longer keys it is a prefix of. Children are then in key order.
+func getOrderedIndex(key []byte, level int) int {
+	chunksPerByte := 8 / OrderedChunkSize
+	if level >= len(key) * chunksPerByte { return 0 }
+
+	shiftSize := 8 - OrderedChunkSize * (level % chunksPerByte + 1)
+	mask := byte(1 << OrderedChunkSize - 1)
+	return int(key[level / chunksPerByte] >> shiftSize & mask) + 1
+}
+
+// compareOrderedPath
+//	Compare the sparse indexes of path, leading from the root to a node of an ordered trie, with the indexes of bound at the same levels.
+//	Returns -1 if every key beneath the node sorts before bound, 1 if every key sorts after it, and 0 if bound could be beneath the node.
+func compareOrderedPath(path []byte, bound []byte) int {
+	for level, index := range path {
+		boundIndex := getOrderedIndex(bound, level)
+
+		switch {
+			case int(index) < boundIndex:
+				return -1
+			case int(index) > boundIndex:
+				return 1
+			case boundIndex == 0:
+				return 0
+		}
+	}
+
+	return 0
+}
+
+// isOrderedPathInRange
+//	Determine if the node of an ordered trie at path could hold keys where startKey <= key <= endKey, where nil bounds are unbounded.
+func isOrderedPathInRange(path []byte, startKey, endKey []byte) bool {
+	if startKey != nil && compareOrderedPath(path, startKey) < 0 { return false }
+	if endKey != nil && compareOrderedPath(path, endKey) > 0 { return false }
+	return true
+}
@@ -0,0 +1,24 @@
+package mmcmap
+
+
+//============================================= MMCMap Overflow Values
+
+
+// isOverflowValue
+//	Determine if a value is long enough to be stored in an overflow extent instead of inline in its leaf.
+func (mmcMap *MMCMap) isOverflowValue(value []byte) bool {
+	return mmcMap.Opts.OverflowThreshold > 0 && len(value) > mmcMap.Opts.OverflowThreshold
+}
+
+// valueOffset
+//	The offset of the value of a leaf read from the memory map, either in its overflow extent or at the end of the leaf.
+func (node *MMCMapNode) valueOffset() uint64 {
+	if node.IsOverflow { return node.OverflowOffset + NodeKeyIdx }
+	return node.EndOffset + 1 - uint64(len(node.Value))
+}
+
+// extentSize
+//	The size of the overflow extent of a leaf.
+func (node *MMCMapNode) extentSize() uint64 {
+	return NodeKeyIdx + uint64(len(node.storedValue()))
+}
@@ -0,0 +1,75 @@
+package mmcmap
+
+
+//============================================= MMCMap Pinned Levels
+
+
+// pinnedNodes are the internal nodes in the top levels of the trie at version, keyed by offset, along with the offsets of the leaves among them
+type pinnedNodes struct {
+	version uint64
+	nodes map[uint64]*MMCMapNode
+	leaves map[uint64]bool
+}
+
+
+// loadPinned
+//	The pinned levels. Nodes in it must not be modified.
+func (mmcMap *MMCMap) loadPinned() *pinnedNodes {
+	return mmcMap.Pinned.Load().(*pinnedNodes)
+}
+
+// pinLatest
+//	Pin the top levels of the latest version, once the file has been opened.
+func (mmcMap *MMCMap) pinLatest() {
+	if mmcMap.Opts.PinnedLevels <= 0 { return }
+
+	meta, readMetaErr := mmcMap.ReadMetaFromMemMap()
+	if readMetaErr != nil { return }
+
+	mmcMap.refreshPinned(meta.Version, meta.RootOffset)
+}
+
+// refreshPinned
+//	Replace the pinned levels with the top PinnedLevels levels of internal nodes of the trie rooted at rootOffset, unless a newer version is pinned.
+//	Called once the root is published, with the resize read lock held. Nodes that are already pinned are reused, so only the nodes copied by the
+//	commit are read from the memory map. Nodes that can not be read are left unpinned and read as usual.
+func (mmcMap *MMCMap) refreshPinned(version, rootOffset uint64) {
+	if mmcMap.Opts.PinnedLevels <= 0 { return }
+
+	mmcMap.PinnedLock.Lock()
+	defer mmcMap.PinnedLock.Unlock()
+
+	prev := mmcMap.loadPinned()
+	if prev.nodes != nil && prev.version >= version { return }
+
+	pinned := &pinnedNodes{ version: version, nodes: make(map[uint64]*MMCMapNode), leaves: make(map[uint64]bool) }
+	mmcMap.pinRecursive(pinned, prev, rootOffset, 1)
+
+	mmcMap.Pinned.Store(pinned)
+}
+
+// pinRecursive
+//	Pin the internal node at offset, then its children while level is within PinnedLevels.
+func (mmcMap *MMCMap) pinRecursive(pinned, prev *pinnedNodes, offset uint64, level int) {
+	if prev.leaves[offset] {
+		pinned.leaves[offset] = true
+		return
+	}
+
+	node, ok := prev.nodes[offset]
+	if ! ok {
+		var readNodeErr error
+		node, readNodeErr = mmcMap.readNode(offset)
+		if readNodeErr != nil { return }
+	}
+
+	if node.IsLeaf {
+		pinned.leaves[offset] = true
+		return
+	}
+
+	pinned.nodes[offset] = node
+	if level >= mmcMap.Opts.PinnedLevels { return }
+
+	for _, child := range node.Children { mmcMap.pinRecursive(pinned, prev, child.StartOffset, level + 1) }
+}
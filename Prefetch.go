@@ -0,0 +1,52 @@
+package mmcmap
+
+import "sync/atomic"
+
+import "github.com/sirgallo/mmcmap/common/mmap"
+
+
+//============================================= MMCMap Prefetch
+
+
+// prefetchChildren
+//	With PrefetchScans, advise the kernel to read ahead the pages holding the children of an internal node visited by a scan, before the scan
+//	descends into them. On a file larger than memory, the page faults for the next nodes then overlap with processing the current one, instead
+//	of each stalling the scan in turn. Children on the same or adjacent pages, like the children written by a single commit, are covered by one hint.
+//	Hints are best effort, so errors are ignored.
+func (mmcMap *MMCMap) prefetchChildren(node *MMCMapNode) {
+	if ! mmcMap.Opts.PrefetchScans || node.IsLeaf || len(node.Children) == 0 { return }
+
+	mmcMap.waitForRemap()
+
+	mmcMap.RWResizeLock.RLock()
+	defer mmcMap.RWResizeLock.RUnlock()
+
+	if atomic.LoadUint32(&mmcMap.Opened) == 0 { return }
+	mMap := mmcMap.Data.Load().(mmap.MMap)
+
+	var start, end uint64
+	for _, child := range node.Children {
+		page := child.StartOffset / uint64(DefaultPageSize) * uint64(DefaultPageSize)
+		pageEnd := uint64(roundToPage(int64(child.StartOffset + NodeKeyIdx)))
+
+		if end > start && page >= start && page <= end {
+			if pageEnd > end { end = pageEnd }
+			continue
+		}
+
+		mmcMap.prefetchPages(mMap, start, end)
+		start, end = page, pageEnd
+	}
+
+	mmcMap.prefetchPages(mMap, start, end)
+}
+
+// prefetchPages
+//	Advise the kernel to read ahead the pages of the memory map from start to end, which must start on a page boundary.
+func (mmcMap *MMCMap) prefetchPages(mMap mmap.MMap, start, end uint64) {
+	if end > uint64(len(mMap)) { end = uint64(len(mMap)) }
+	if start >= end { return }
+
+	mMap[start:end].Prefetch()
+	atomic.AddUint64(&mmcMap.Prefetches, 1)
+}
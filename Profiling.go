@@ -0,0 +1,86 @@
+package mmcmap
+
+import "context"
+import "expvar"
+import "runtime/pprof"
+import "sort"
+import "sync"
+import "sync/atomic"
+
+
+//============================================= MMCMap Profiling
+
+
+// publishCounters guards PublishCounters, so the counters are published at most once per process
+var publishCounters sync.Once
+
+// PublishCounters
+//	Publish the counters of every open mmcmap under ExpvarName, so they are served by the expvar handler of the embedding application.
+//	Nothing is published on import. Calling it again is a no op, and ErrExpvarNameTaken is returned if another package already published ExpvarName.
+func PublishCounters() error {
+	var publishErr error
+	publishCounters.Do(func() {
+		if expvar.Get(ExpvarName) != nil {
+			publishErr = ErrExpvarNameTaken
+			return
+		}
+
+		expvar.Publish(ExpvarName, expvar.Func(func() interface{} { return OpenMapCounters() }))
+	})
+
+	return publishErr
+}
+
+// OpenMapCounters
+//	The counters of every mmcmap currently open in the process, sorted by name.
+func OpenMapCounters() []MMCMapCounters {
+	openMaps.RLock()
+	maps := make([]*MMCMap, 0, len(openMaps.maps))
+	for mmcMap := range openMaps.maps { maps = append(maps, mmcMap) }
+	openMaps.RUnlock()
+
+	counters := make([]MMCMapCounters, len(maps))
+	for idx, mmcMap := range maps { counters[idx] = mmcMap.Counters() }
+
+	sort.Slice(counters, func(i, j int) bool {
+		if counters[i].Name == counters[j].Name { return counters[i].Filepath < counters[j].Filepath }
+		return counters[i].Name < counters[j].Name
+	})
+
+	return counters
+}
+
+// Counters
+//	Snapshot the operation counters of the mmcmap. Unlike Stats, the size of the map is not measured, so reading them never touches the memory map.
+func (mmcMap *MMCMap) Counters() MMCMapCounters {
+	return MMCMapCounters{
+		Name: mmcMap.Opts.Name,
+		Filepath: mmcMap.Opts.Filepath,
+		Puts: atomic.LoadUint64(&mmcMap.Puts),
+		Gets: atomic.LoadUint64(&mmcMap.Gets),
+		Deletes: atomic.LoadUint64(&mmcMap.Deletes),
+		CommitRetries: atomic.LoadUint64(&mmcMap.CommitRetries),
+		CommitRebases: atomic.LoadUint64(&mmcMap.CommitRebases),
+		Resizes: atomic.LoadUint64(&mmcMap.Resizes),
+		Flushes: atomic.LoadUint64(&mmcMap.Flushes),
+		WriteStalls: atomic.LoadUint64(&mmcMap.WriteStalls),
+		Compactions: atomic.LoadUint64(&mmcMap.CompactionCount),
+		ExpiredKeys: atomic.LoadUint64(&mmcMap.ExpiredKeys),
+		ReplicaDeltas: atomic.LoadUint64(&mmcMap.ReplicaDeltas),
+		Watchers: atomic.LoadInt64(&mmcMap.WatcherCount),
+	}
+}
+
+// goLabeled
+//	Start fn on a new go routine labelled for pprof with the name of the mmcmap under ProfileLabelMap and the task the go routine performs under
+//	ProfileLabelTask, so CPU profiles and go routine dumps of the embedding application attribute its work to the mmcmap. Go routines started by fn
+//	inherit the labels. The go routine is counted under its task until fn returns, so Ping can tell which go routines are running.
+func (mmcMap *MMCMap) goLabeled(task string, fn func()) {
+	labels := pprof.Labels(ProfileLabelMap, mmcMap.Opts.Name, ProfileLabelTask, task)
+
+	mmcMap.trackRoutine(task, 1)
+	go pprof.Do(context.Background(), labels, func(context.Context) {
+		defer mmcMap.trackRoutine(task, -1)
+		fn()
+	})
+}
@@ -0,0 +1,36 @@
+package mmcmap
+
+
+//============================================= MMCMap Hole Punching
+
+
+// PunchHoles
+//	Release the filesystem blocks backing the regions in the free list, so the space used by unreachable versions is returned to the OS without
+//	rewriting the file like Compact. Only the whole pages within each region are punched, and the file keeps its size, so later commits can still
+//	write into the regions. Only files created with the FreeListAllocator keep a free list, and hole punching is only supported on Linux.
+//	Commits and reads are blocked while holes are punched.
+func (mmcMap *MMCMap) PunchHoles() (*PunchReport, error) {
+	mmcMap.lockResize()
+	defer mmcMap.unlockResize()
+
+	handleErr := mmcMap.checkHandle()
+	if handleErr != nil { return nil, handleErr }
+	if ! mmcMap.isFreeListEnabled() { return nil, ErrAllocatorUnsupported }
+
+	pageSize := uint64(DefaultPageSize)
+	report := &PunchReport{}
+
+	for _, region := range mmcMap.readFreeList() {
+		start := (region.Offset + pageSize - 1) / pageSize * pageSize
+		end := (region.Offset + region.Size) / pageSize * pageSize
+		if end <= start { continue }
+
+		punchErr := punchHole(mmcMap.File, int64(start), int64(end - start))
+		if punchErr != nil { return nil, punchErr }
+
+		report.Regions++
+		report.PunchedBytes += end - start
+	}
+
+	return report, nil
+}
@@ -0,0 +1,17 @@
+//go:build linux
+
+package mmcmap
+
+import "os"
+
+import "golang.org/x/sys/unix"
+
+
+//============================================= MMCMap Hole Punching (Linux)
+
+
+// punchHole
+//	Deallocate the blocks backing length bytes of the file at offset, keeping the size of the file. Reads of the range return zeros afterwards.
+func punchHole(file *os.File, offset, length int64) error {
+	return unix.Fallocate(int(file.Fd()), unix.FALLOC_FL_PUNCH_HOLE | unix.FALLOC_FL_KEEP_SIZE, offset, length)
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+
+package mmcmap
+
+import "os"
+
+
+//============================================= MMCMap Hole Punching (Unsupported)
+
+
+// punchHole
+//	Hole punching is only implemented on Linux.
+func punchHole(file *os.File, offset, length int64) error {
+	return ErrPunchHoleUnsupported
+}
@@ -0,0 +1,74 @@
+package mmcmap
+
+import "context"
+import "sync"
+import "sync/atomic"
+import "time"
+
+
+//============================================= MMCMap Quiesce
+
+
+// Quiesce
+//	Temporarily block new commits so external tooling (LVM/ZFS snapshots, file copies) can capture a consistent image of the file.
+//	Commits already in flight are allowed to finish, the file is then flushed to disk, and a release function is returned.
+//	Reads continue while the map is quiesced, while writers block until release is called. If ctx is done before in-flight commits finish,
+//	the quiesce is abandoned and the context error is returned. Release is safe to call more than once.
+func (mmcMap *MMCMap) Quiesce(ctx context.Context) (func(), error) {
+	acquired := make(chan struct{})
+	go func() {
+		mmcMap.CommitGate.Lock()
+		close(acquired)
+	}()
+
+	select {
+		case <-acquired:
+		case <-ctx.Done():
+			go func() {
+				<-acquired
+				mmcMap.CommitGate.Unlock()
+			}()
+
+			return nil, ctx.Err()
+	}
+
+	quiescedAt := time.Now()
+
+	flushErr := mmcMap.flushForQuiesce()
+	if flushErr != nil {
+		mmcMap.CommitGate.Unlock()
+		return nil, flushErr
+	}
+
+	var releaseOnce sync.Once
+	release := func() {
+		releaseOnce.Do(func() {
+			atomic.AddInt64(&mmcMap.QuiescedNanos, int64(time.Since(quiescedAt)))
+			atomic.AddUint64(&mmcMap.QuiesceCount, 1)
+			mmcMap.CommitGate.Unlock()
+		})
+	}
+
+	return release, nil
+}
+
+// QuiesceStats
+//	How many times the map has been quiesced and the cumulative time commits were blocked.
+func (mmcMap *MMCMap) QuiesceStats() QuiesceStats {
+	return QuiesceStats{
+		Count: atomic.LoadUint64(&mmcMap.QuiesceCount),
+		Total: time.Duration(atomic.LoadInt64(&mmcMap.QuiescedNanos)),
+	}
+}
+
+// flushForQuiesce
+//	Durably flush the memory map to disk once no commits are in flight.
+func (mmcMap *MMCMap) flushForQuiesce() error {
+	mmcMap.RWResizeLock.RLock()
+	defer mmcMap.RWResizeLock.RUnlock()
+
+	handleErr := mmcMap.checkHandle()
+	if handleErr != nil { return handleErr }
+
+	return mmcMap.syncFiles()
+}
@@ -0,0 +1,238 @@
+package mmcmap
+
+import "bytes"
+import "context"
+import "sort"
+
+
+//============================================= MMCMap Range
+
+
+// Range
+//	Collects every key-value pair where startKey <= key <= endKey, sorted by key.
+//	A nil startKey or endKey leaves that side of the range unbounded. Optional RangeOpts limit, offset, and reverse the results.
+//	In files using KeyModeHashed, keys are placed in the trie by hash, so the entire trie at the latest version is scanned and matching pairs are
+//	accumulated before sorting. In files using KeyModeOrdered, only the subtrees overlapping the range are visited, in key order. For large ranges,
+//	use RangeChan to stream results instead. Set RangeOpts.KeysOnly to skip copying values out of the memory map.
+func (mmcMap *MMCMap) Range(startKey, endKey []byte, opts ...RangeOpts) ([]*KeyValuePair, error) {
+	var rangeOpts RangeOpts
+	if len(opts) > 0 { rangeOpts = opts[0] }
+
+	pairs, _, rangeErr := mmcMap.RangePage(startKey, endKey, rangeOpts)
+	return pairs, rangeErr
+}
+
+// RangePage
+//	Returns a single page of the sorted range along with a continuation token.
+//	The token is the last key of the page and is nil once the range is exhausted. Pass it as RangeOpts.After, with Offset 0, to fetch the next page.
+//	When a limit is set, only Offset + Limit + 1 pairs are retained while scanning, so paging through a large map does not accumulate the whole range.
+//	In files using KeyModeOrdered, pairs are visited in key order, or in reverse, so the traversal stops once those pairs are collected.
+func (mmcMap *MMCMap) RangePage(startKey, endKey []byte, opts RangeOpts) ([]*KeyValuePair, []byte, error) {
+	defer mmcMap.recordLatency(&mmcMap.RangeLatency, mmcMap.startLatency(), 0)
+
+	mmcMap.RelocateLock.RLock()
+	defer mmcMap.RelocateLock.RUnlock()
+
+	rootOffset, loadROffErr := mmcMap.loadRootOffsetForRead()
+	if loadROffErr != nil { return nil, nil, loadROffErr }
+
+	less := func(a, b []byte) bool {
+		if opts.Reverse { return bytes.Compare(a, b) > 0 }
+		return bytes.Compare(a, b) < 0
+	}
+
+	retain := 0
+	if opts.Limit > 0 { retain = opts.Offset + opts.Limit + 1 }
+
+	sortPairs := func(pairs []*KeyValuePair) {
+		sort.Slice(pairs, func(i, j int) bool { return less(pairs[i].Key, pairs[j].Key) })
+	}
+
+	isOrdered := mmcMap.isOrderedKeys()
+
+	var pairs []*KeyValuePair
+	rangeErr := mmcMap.rangePath(rootOffset, nil, startKey, endKey, opts.KeysOnly, opts.Reverse, func(pair *KeyValuePair) error {
+		if opts.After != nil && ! less(opts.After, pair.Key) { return nil }
+
+		pairs = append(pairs, pair)
+		if isOrdered && retain > 0 && len(pairs) >= retain { return errRangeComplete }
+		if retain > 0 && len(pairs) >= 2 * retain {
+			sortPairs(pairs)
+			pairs = pairs[:retain]
+		}
+
+		return nil
+	})
+
+	if rangeErr != nil && rangeErr != errRangeComplete { return nil, nil, rangeErr }
+
+	sortPairs(pairs)
+
+	if opts.Offset >= len(pairs) { return []*KeyValuePair{}, nil, nil }
+	pairs = pairs[opts.Offset:]
+
+	if opts.Limit > 0 && len(pairs) > opts.Limit {
+		page := pairs[:opts.Limit]
+		return page, page[len(page) - 1].Key, nil
+	}
+
+	return pairs, nil, nil
+}
+
+// Keys
+//	Collects every key where startKey <= key <= endKey, sorted by key. Values are never copied out of the memory map, so index-style scans only pay
+//	for the keys.
+func (mmcMap *MMCMap) Keys(startKey, endKey []byte) ([][]byte, error) {
+	pairs, _, rangeErr := mmcMap.RangePage(startKey, endKey, RangeOpts{ KeysOnly: true })
+	if rangeErr != nil { return nil, rangeErr }
+
+	keys := make([][]byte, len(pairs))
+	for idx, pair := range pairs { keys[idx] = pair.Key }
+
+	return keys, nil
+}
+
+// RangeChan
+//	Streams every key-value pair where startKey <= key <= endKey as it is deserialized from the memory map.
+//	Pairs are emitted in trie order, which is only sorted order in files using KeyModeOrdered, and only a single node is held in memory at a time so the heap does not grow with the size of the range.
+//	The traversal is pinned to the root at the time of the call. Both channels are closed once the traversal completes, and at most one error is sent.
+//	The pairs channel must be drained, otherwise the traversal go routine blocks, along with any Compact or Reclaim waiting for it. Consumers that
+//	may stop early should use RangeChanWithContext.
+func (mmcMap *MMCMap) RangeChan(startKey, endKey []byte) (<-chan *KeyValuePair, <-chan error) {
+	return mmcMap.RangeChanWithContext(context.Background(), startKey, endKey)
+}
+
+// RangeChanWithContext
+//	Stream the pairs as RangeChan does, stopping the traversal once ctx is done. The traversal go routine then releases its pin on the root, so
+//	Compact and Reclaim are no longer held up, closes both channels, and sends ctx.Err(). Pairs already read may still be received before the
+//	channels close, so a consumer that stops reading only has to cancel ctx.
+func (mmcMap *MMCMap) RangeChanWithContext(ctx context.Context, startKey, endKey []byte) (<-chan *KeyValuePair, <-chan error) {
+	pairs := make(chan *KeyValuePair)
+	errs := make(chan error, 1)
+
+	mmcMap.goLabeled("range", func() {
+		defer close(errs)
+		defer close(pairs)
+
+		mmcMap.pinRelocation()
+		defer mmcMap.unpinRelocation()
+
+		rootOffset, loadROffErr := mmcMap.loadRootOffsetForRead()
+		if loadROffErr != nil {
+			errs <- loadROffErr
+			return
+		}
+
+		rangeErr := mmcMap.rangeRecursive(rootOffset, startKey, endKey, false, func(pair *KeyValuePair) error {
+			select {
+				case pairs <- pair:
+					return nil
+				case <-ctx.Done():
+					return ctx.Err()
+			}
+		})
+
+		if rangeErr != nil { errs <- rangeErr }
+	})
+
+	return pairs, errs
+}
+
+// rangeRecursive
+//	Depth first traversal from the node at the given offset, emitting each leaf whose key falls within the range.
+//	Each node is read under its own read lock, so a slow consumer never blocks a resize of the memory map. If keysOnly is set, emitted pairs have a nil value.
+//	Expired leaves are skipped.
+func (mmcMap *MMCMap) rangeRecursive(offset uint64, startKey, endKey []byte, keysOnly bool, emit func(*KeyValuePair) error) error {
+	return mmcMap.rangePath(offset, nil, startKey, endKey, keysOnly, false, emit)
+}
+
+// rangePath
+//	Same as rangeRecursive, where path holds the sparse indexes leading to the node at offset. In files using KeyModeOrdered, children whose path
+//	sorts entirely outside of the range are skipped without being read, and children are visited in reverse key order if reverse is set.
+func (mmcMap *MMCMap) rangePath(offset uint64, path []byte, startKey, endKey []byte, keysOnly, reverse bool, emit func(*KeyValuePair) error) error {
+	node, readErr := mmcMap.readNodeCopy(offset, keysOnly)
+	if readErr != nil { return readErr }
+
+	if node.IsLeaf {
+		if ! isKeyInRange(node.Key, startKey, endKey) || node.isExpired() { return nil }
+		return emit(&KeyValuePair{ Version: node.Version, Key: node.Key, Value: node.Value })
+	}
+
+	mmcMap.prefetchChildren(node)
+
+	if ! mmcMap.isOrderedKeys() {
+		for _, child := range node.Children {
+			rangeErr := mmcMap.rangePath(child.StartOffset, nil, startKey, endKey, keysOnly, reverse, emit)
+			if rangeErr != nil { return rangeErr }
+		}
+
+		return nil
+	}
+
+	for idx := 0; idx < 32; idx++ {
+		index := idx
+		if reverse { index = 31 - idx }
+		if ! IsBitSet(node.Bitmap, index) { continue }
+
+		childPath := append(path, byte(index))
+		if ! isOrderedPathInRange(childPath, startKey, endKey) { continue }
+
+		child := node.Children[childPosition(node.Bitmap, index)]
+		rangeErr := mmcMap.rangePath(child.StartOffset, childPath, startKey, endKey, keysOnly, reverse, emit)
+		if rangeErr != nil { return rangeErr }
+	}
+
+	return nil
+}
+
+// isKeyInRange
+//	Determine if startKey <= key <= endKey, where nil bounds are unbounded.
+func isKeyInRange(key, startKey, endKey []byte) bool {
+	if startKey != nil && bytes.Compare(key, startKey) < 0 { return false }
+	if endKey != nil && bytes.Compare(key, endKey) > 0 { return false }
+	return true
+}
+
+// loadRootOffsetForRead
+//	Load the offset of the latest root from the metadata for operations that traverse the trie outside of a single read lock.
+func (mmcMap *MMCMap) loadRootOffsetForRead() (uint64, error) {
+	mmcMap.waitForRemap()
+
+	mmcMap.RWResizeLock.RLock()
+	defer mmcMap.RWResizeLock.RUnlock()
+
+	handleErr := mmcMap.checkHandle()
+	if handleErr != nil { return 0, handleErr }
+
+	_, rootOffset, loadROffErr := mmcMap.loadMetaRootOffset()
+	if loadROffErr != nil { return 0, loadROffErr }
+
+	return rootOffset, nil
+}
+
+// readNodeCopy
+//	Read a node from the memory map under the read lock, copying the key and value out of the mapped buffer.
+//	Nodes in the memory map are never modified, and callers pinned to a root hold the relocate read lock so Compact and Reclaim can not move or reuse
+//	them, so the offset stays valid even if the map is remapped after the lock is released.
+//	If keysOnly is set, the value of a leaf is dropped instead of copied.
+func (mmcMap *MMCMap) readNodeCopy(offset uint64, keysOnly bool) (*MMCMapNode, error) {
+	mmcMap.waitForRemap()
+
+	mmcMap.RWResizeLock.RLock()
+	defer mmcMap.RWResizeLock.RUnlock()
+
+	handleErr := mmcMap.checkHandle()
+	if handleErr != nil { return nil, handleErr }
+
+	node, readErr := mmcMap.ReadNodeFromMemMap(offset)
+	if readErr != nil { return nil, readErr }
+
+	if node.IsLeaf {
+		node.Key = append([]byte{}, node.Key...)
+		if keysOnly {
+			node.Value = nil
+		} else { node.Value = append([]byte{}, node.Value...) }
+	}
+
+	return node, nil
+}
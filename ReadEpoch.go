@@ -0,0 +1,63 @@
+package mmcmap
+
+import "runtime"
+import "sync/atomic"
+
+
+//============================================= MMCMap Read Epochs
+
+
+// beginRead
+//	Start a read of the memory map, registering it in the current read epoch instead of taking the resize lock, so reads do not contend with each
+//	other or with resizes on a shared lock. The memory map loaded after beginRead stays mapped until endRead is called, since anything replacing it
+//	waits for the reads of the epoch to finish before unmapping it. While the resize lock is held exclusively, the memory map may be unmapped or
+//	rewritten, so the read falls back to waiting for the resize read lock. The result is passed to endRead once the read is done.
+func (mmcMap *MMCMap) beginRead() (epoch uint64, isLockFree bool) {
+	epoch = atomic.LoadUint64(&mmcMap.ReadEpoch)
+	readers := &mmcMap.EpochReaders[epoch & 1]
+
+	atomic.AddInt64(readers, 1)
+	if atomic.LoadUint32(&mmcMap.ExclusiveHolders) == 0 && atomic.LoadUint64(&mmcMap.ReadEpoch) == epoch { return epoch, true }
+
+	atomic.AddInt64(readers, -1)
+	atomic.AddUint64(&mmcMap.LockedReads, 1)
+
+	mmcMap.waitForRemap()
+	mmcMap.RWResizeLock.RLock()
+	return epoch, false
+}
+
+// endRead
+//	Finish a read started by beginRead.
+func (mmcMap *MMCMap) endRead(epoch uint64, isLockFree bool) {
+	if ! isLockFree {
+		mmcMap.RWResizeLock.RUnlock()
+		return
+	}
+
+	atomic.AddInt64(&mmcMap.EpochReaders[epoch & 1], -1)
+}
+
+// advanceEpoch
+//	Move new reads to the next epoch and wait for the reads registered in the current epoch to finish. Reads load the memory map after registering,
+//	so once advanceEpoch returns, no read holds a memory map that was replaced before it was called.
+func (mmcMap *MMCMap) advanceEpoch() {
+	epoch := atomic.AddUint64(&mmcMap.ReadEpoch, 1) - 1
+	for atomic.LoadInt64(&mmcMap.EpochReaders[epoch & 1]) > 0 { runtime.Gosched() }
+}
+
+// lockResize
+//	Take the resize lock exclusively. Reads started by beginRead are turned away to the resize read lock, and those in flight are waited for, so the
+//	memory map can be unmapped or rewritten while the lock is held.
+func (mmcMap *MMCMap) lockResize() {
+	atomic.AddUint32(&mmcMap.ExclusiveHolders, 1)
+	mmcMap.RWResizeLock.Lock()
+	mmcMap.advanceEpoch()
+}
+
+// unlockResize
+//	Release the resize lock taken by lockResize.
+func (mmcMap *MMCMap) unlockResize() {
+	mmcMap.RWResizeLock.Unlock()
+	atomic.AddUint32(&mmcMap.ExclusiveHolders, ^uint32(0))
+}
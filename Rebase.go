@@ -0,0 +1,125 @@
+package mmcmap
+
+import "sync/atomic"
+
+
+//============================================= MMCMap Rebase
+
+
+// rebaseCommit
+//	Rebase a path copy made from the root at baseOffset onto the latest root, if another commit published a new version while it was being copied.
+//	Instead of discarding the copy and starting again from the root, prepare is called against the latest root, and if it returns the same ops the
+//	copy is merged into the latest root. Subtrees only one of the commits modified are taken from that commit as is, and subtrees both modified are
+//	merged child by child, so only the paths the two commits share are read again. The path copy must have applied every op as a modification,
+//	which rebaseable reports, since a delete that found nothing depends on the state of a path it did not copy.
+//	Returns the rebased path, the path as is if the metadata has not changed, or retry if the commit has to start again from the root because both
+//	commits modified the same leaf or collision bucket, or a node would be left with fewer than two children.
+func (mmcMap *MMCMap) rebaseCommit(path *MMCMapNode, baseOffset, baseVersion uint64, ops []*BatchOp, rebaseable bool, prepare func(root *MMCMapNode) ([]*BatchOp, error)) (rebased *MMCMapNode, retry bool, err error) {
+	_, latestVersion, loadVErr := mmcMap.loadMetaVersion()
+	if loadVErr != nil { return nil, false, loadVErr }
+
+	if latestVersion == baseVersion { return path, false, nil }
+	if ! rebaseable { return nil, true, nil }
+
+	_, latestOffset, loadROffErr := mmcMap.loadMetaRootOffset()
+	if loadROffErr != nil { return nil, false, loadROffErr }
+
+	latestRoot, readLatestErr := mmcMap.ReadNodeFromMemMap(latestOffset)
+	if readLatestErr != nil { return nil, false, readLatestErr }
+
+	// the version is published before the root offset, so the root read may still be the previous one
+	if latestRoot.Version != latestVersion { return nil, true, nil }
+
+	latestOps, prepareErr := prepare(latestRoot)
+	if prepareErr != nil { return nil, false, prepareErr }
+	if ! isSameOps(ops, latestOps) { return nil, true, nil }
+
+	baseRoot, readBaseErr := mmcMap.ReadNodeFromMemMap(baseOffset)
+	if readBaseErr != nil { return nil, false, readBaseErr }
+
+	renumberPath(path, path.Version, latestVersion + 1)
+
+	isMerged, mergeErr := mmcMap.mergeNode(path, baseRoot, latestRoot, 0)
+	if mergeErr != nil { return nil, false, mergeErr }
+	if ! isMerged { return nil, true, nil }
+
+	atomic.AddUint64(&mmcMap.CommitRebases, 1)
+	return path, false, nil
+}
+
+// mergeNode
+//	Merge the children of the internal node the latest root has at the same position as node into node, which was copied from base. A child node
+//	did not modify is replaced with the child of latest, a child latest did not modify is kept, and internal children both modified are merged
+//	recursively. Returns false if the nodes can not be merged.
+func (mmcMap *MMCMap) mergeNode(node, base, latest *MMCMapNode, level int) (bool, error) {
+	if mmcMap.isCollisionLevel(level) || base.IsLeaf || latest.IsLeaf { return false, nil }
+
+	var bitmap uint32
+	children := make([]*MMCMapNode, 0, len(node.Children))
+
+	for idx := 0; idx < 32; idx++ {
+		ours, prior, theirs := childAt(node, idx), childAt(base, idx), childAt(latest, idx)
+
+		var merged *MMCMapNode
+		switch {
+			case isSameChild(ours, prior, node.Version):
+				merged = theirs
+			case isSameChild(theirs, prior, node.Version):
+				merged = ours
+			case ours == nil || prior == nil || theirs == nil || ours.Version != node.Version || ours.IsLeaf:
+				return false, nil
+			default:
+				priorNode, readPriorErr := mmcMap.ReadNodeFromMemMap(prior.StartOffset)
+				if readPriorErr != nil { return false, readPriorErr }
+
+				latestNode, readLatestErr := mmcMap.ReadNodeFromMemMap(theirs.StartOffset)
+				if readLatestErr != nil { return false, readLatestErr }
+
+				isMerged, mergeErr := mmcMap.mergeNode(ours, priorNode, latestNode, level + 1)
+				if mergeErr != nil || ! isMerged { return false, mergeErr }
+
+				merged = ours
+		}
+
+		if merged == nil { continue }
+
+		bitmap = SetBit(bitmap, idx)
+		children = append(children, merged)
+	}
+
+	// deletes collapse internal nodes left with a single leaf, which the merged node may need but can not tell without reading the child
+	if level > 0 && len(children) < 2 { return false, nil }
+
+	node.Bitmap = bitmap
+	node.Children = children
+	return true, nil
+}
+
+// childAt
+//	The child of an internal node at the sparse index, or nil if the bit is not set.
+func childAt(node *MMCMapNode, index int) *MMCMapNode {
+	if ! IsBitSet(node.Bitmap, index) { return nil }
+	return node.Children[childPosition(node.Bitmap, index)]
+}
+
+// isSameChild
+//	Determine if child is the committed node prior refers to. Nodes at version belong to the path copy, so they are never the same as a committed node.
+func isSameChild(child, prior *MMCMapNode, version uint64) bool {
+	if child == nil || prior == nil { return child == prior }
+	return child.Version != version && child.StartOffset == prior.StartOffset
+}
+
+// isSameOps
+//	Determine if prepare returned the same slice of ops on both attempts.
+func isSameOps(ops, latestOps []*BatchOp) bool {
+	return len(ops) == len(latestOps) && len(ops) > 0 && &ops[0] == &latestOps[0]
+}
+
+// renumberPath
+//	Move every node of the path copy from version to newVersion, so the nodes are serialized at the version the rebased commit is written at.
+func renumberPath(node *MMCMapNode, version, newVersion uint64) {
+	if node.Version != version { return }
+
+	node.Version = newVersion
+	for _, child := range node.Children { renumberPath(child, version, newVersion) }
+}
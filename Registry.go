@@ -0,0 +1,94 @@
+package mmcmap
+
+import "sort"
+import "sync"
+import "sync/atomic"
+
+
+//============================================= MMCMap Open Map Registry
+
+
+// openMaps tracks every mmcmap opened by the process until it is closed
+var openMaps = struct {
+	sync.RWMutex
+	maps map[*MMCMap]struct{}
+}{ maps: make(map[*MMCMap]struct{}) }
+
+// ListOpenMaps
+//	Describe every mmcmap currently open in the process, sorted by name, for diagnostics and admin endpoints.
+func ListOpenMaps() []OpenMapInfo {
+	openMaps.RLock()
+	maps := make([]*MMCMap, 0, len(openMaps.maps))
+	for mmcMap := range openMaps.maps { maps = append(maps, mmcMap) }
+	openMaps.RUnlock()
+
+	infos := make([]OpenMapInfo, 0, len(maps))
+	for _, mmcMap := range maps { infos = append(infos, mmcMap.info()) }
+
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Name == infos[j].Name { return infos[i].Filepath < infos[j].Filepath }
+		return infos[i].Name < infos[j].Name
+	})
+
+	return infos
+}
+
+// Name
+//	The name the mmcmap was opened with.
+func (mmcMap *MMCMap) Name() string {
+	return mmcMap.Opts.Name
+}
+
+// Labels
+//	The labels attached to the mmcmap, along with its name and filepath, for use as metric and log labels.
+func (mmcMap *MMCMap) Labels() map[string]string {
+	labels := make(map[string]string, len(mmcMap.Opts.Labels) + 2)
+	for key, value := range mmcMap.Opts.Labels { labels[key] = value }
+
+	labels["name"] = mmcMap.Opts.Name
+	labels["filepath"] = mmcMap.Opts.Filepath
+
+	return labels
+}
+
+// info
+//	Snapshot the registry information for the mmcmap.
+func (mmcMap *MMCMap) info() OpenMapInfo {
+	info := OpenMapInfo{
+		Name: mmcMap.Opts.Name,
+		Labels: mmcMap.Labels(),
+		Filepath: mmcMap.Opts.Filepath,
+		IsDetached: atomic.LoadUint32(&mmcMap.IsDetached) == 1,
+	}
+
+	if info.IsDetached { return info }
+
+	mmcMap.RWResizeLock.RLock()
+	defer mmcMap.RWResizeLock.RUnlock()
+
+	_, version, loadVErr := mmcMap.loadMetaVersion()
+	if loadVErr == nil { info.Version = version }
+
+	fSize, fSizeErr := mmcMap.FileSize()
+	if fSizeErr == nil { info.FileSize = fSize }
+
+	return info
+}
+
+// registerMap
+//	Add an opened mmcmap to the registry.
+func registerMap(mmcMap *MMCMap) {
+	openMaps.Lock()
+	defer openMaps.Unlock()
+
+	openMaps.maps[mmcMap] = struct{}{}
+}
+
+// unregisterMap
+//	Remove a closed mmcmap from the registry.
+func unregisterMap(mmcMap *MMCMap) {
+	openMaps.Lock()
+	defer openMaps.Unlock()
+
+	delete(openMaps.maps, mmcMap)
+}
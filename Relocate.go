@@ -0,0 +1,67 @@
+package mmcmap
+
+
+//============================================= MMCMap Relocation
+
+
+// lockRelocate
+//	Take the commit gate and the relocate lock exclusively for Compact, Reclaim, ReclaimSegments, and truncating Clears, which move or reuse nodes,
+//	once no pinned traversals are open. Pins are waited for with no locks held, so commits, reads, and pinned traversals continue while a relocation
+//	is queued behind an unreleased pin. A pin may be added between the wait and the locks, so the count is checked again under them. Pins are only
+//	added under the relocate read lock, so none can be added until unlockRelocate.
+func (mmcMap *MMCMap) lockRelocate() {
+	for {
+		mmcMap.waitForRelocatePins()
+
+		mmcMap.CommitGate.Lock()
+		mmcMap.RelocateLock.Lock()
+
+		mmcMap.RelocateCond.L.Lock()
+		pins := mmcMap.RelocatePins
+		mmcMap.RelocateCond.L.Unlock()
+
+		if pins == 0 { return }
+
+		mmcMap.RelocateLock.Unlock()
+		mmcMap.CommitGate.Unlock()
+	}
+}
+
+// unlockRelocate
+//	Release the locks taken by lockRelocate.
+func (mmcMap *MMCMap) unlockRelocate() {
+	mmcMap.RelocateLock.Unlock()
+	mmcMap.CommitGate.Unlock()
+}
+
+// waitForRelocatePins
+//	Block until every pin added by pinRelocation has been released.
+func (mmcMap *MMCMap) waitForRelocatePins() {
+	mmcMap.RelocateCond.L.Lock()
+	defer mmcMap.RelocateCond.L.Unlock()
+
+	for mmcMap.RelocatePins > 0 { mmcMap.RelocateCond.Wait() }
+}
+
+// pinRelocation
+//	Pin the nodes of the latest version in place for a traversal held open by the caller, such as a streamed range or an unreleased zero copy value.
+//	Unlike the relocate read lock, the pin is counted instead of held, so a relocation waiting for it does not block new reads, and the holder may
+//	start other traversals before unpinning. The relocate read lock is only held while the pin is added, which waits out a relocation in progress.
+func (mmcMap *MMCMap) pinRelocation() {
+	mmcMap.RelocateLock.RLock()
+	defer mmcMap.RelocateLock.RUnlock()
+
+	mmcMap.RelocateCond.L.Lock()
+	mmcMap.RelocatePins++
+	mmcMap.RelocateCond.L.Unlock()
+}
+
+// unpinRelocation
+//	Release a pin added by pinRelocation, waking relocations waiting for the last pin.
+func (mmcMap *MMCMap) unpinRelocation() {
+	mmcMap.RelocateCond.L.Lock()
+	defer mmcMap.RelocateCond.L.Unlock()
+
+	mmcMap.RelocatePins--
+	if mmcMap.RelocatePins == 0 { mmcMap.RelocateCond.Broadcast() }
+}
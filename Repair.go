@@ -0,0 +1,177 @@
+package mmcmap
+
+import "os"
+
+import "github.com/sirgallo/mmcmap/common/mmap"
+
+
+//============================================= MMCMap Repair
+
+
+// Repair
+//	Recover the map from a damaged write by rolling back to the newest version whose entire trie is intact.
+//	Every commit appended to the file is found by scanning the node headers from the initial root, stopping at the first node that can not be read.
+//	Starting from the newest commit, each root is validated with the same checks as Verify, and the first root that passes becomes the latest
+//	version. The metadata and key count are rebuilt from it, and the bytes after it are zeroed so later commits overwrite them cleanly.
+//	Commits are blocked while the map is repaired. If the latest version is already intact, the map is left unchanged.
+func (mmcMap *MMCMap) Repair() (*RepairReport, error) {
+	mmcMap.CommitGate.Lock()
+	defer mmcMap.CommitGate.Unlock()
+
+	mmcMap.lockResize()
+	defer mmcMap.unlockResize()
+
+	handleErr := mmcMap.checkHandle()
+	if handleErr != nil { return nil, handleErr }
+
+	return mmcMap.repair()
+}
+
+// RepairFile
+//	Repair the map at opts.Filepath without opening it, for files with metadata too damaged for Open to accept. See Repair.
+func RepairFile(opts MMCMapOpts) (*RepairReport, error) {
+	mmcMap, openFileErr := openFile(opts, os.O_RDWR)
+	if openFileErr != nil { return nil, openFileErr }
+
+	repair := func() (*RepairReport, error) {
+		mmapErr := mmcMap.mMap()
+		if mmapErr != nil { return nil, mmapErr }
+
+		loadHeaderErr := mmcMap.loadHeader()
+		if loadHeaderErr != nil { return nil, loadHeaderErr }
+
+		mapSegmentsErr := mmcMap.mapAfterHeader()
+		if mapSegmentsErr != nil { return nil, mapSegmentsErr }
+
+		return mmcMap.repair()
+	}
+
+	report, repairErr := repair()
+	closeErr := mmcMap.Close()
+
+	if repairErr != nil { return nil, repairErr }
+	if closeErr != nil { return nil, closeErr }
+
+	return report, nil
+}
+
+// repair
+//	Find the newest intact root and make it the latest version. Commits must be blocked and the resize write lock held.
+func (mmcMap *MMCMap) repair() (*RepairReport, error) {
+	if mmcMap.isFreeListEnabled() { return nil, ErrAllocatorUnsupported }
+
+	mMap := mmcMap.Data.Load().(mmap.MMap)
+
+	prevMeta, readMetaErr := mmcMap.ReadMetaFromMemMap()
+	if readMetaErr != nil { prevMeta = &MMCMapMetaData{} }
+
+	commits, scanEnd := mmcMap.scanCommits(mMap)
+	report := &RepairReport{ PrevVersion: prevMeta.Version, PrevRootOffset: prevMeta.RootOffset }
+
+	for idx := len(commits) - 1; idx >= 0; idx-- {
+		commit := commits[idx]
+		meta := &MMCMapMetaData{ Version: commit.version, RootOffset: commit.offset, EndMmapOffset: commit.end }
+
+		var findings []string
+		mmcMap.checkRoot(&findings, meta, mmcMap.ReadNodeFromMemMap)
+		if len(findings) > 0 { continue }
+
+		checker := &nodeChecker{ mmcMap: mmcMap, meta: meta, read: mmcMap.ReadNodeFromMemMap }
+		checker.checkNode(meta.RootOffset, meta.Version, 0, -1)
+		if len(checker.findings) > 0 { continue }
+
+		report.Version = meta.Version
+		report.RootOffset = meta.RootOffset
+		report.EndMmapOffset = meta.EndMmapOffset
+		report.KeyCount = checker.keysVisited
+		report.DiscardedVersions = uint64(len(commits) - 1 - idx)
+
+		if *prevMeta == *meta { return report, nil }
+
+		discardEnd := scanEnd
+		if prevMeta.EndMmapOffset > discardEnd { discardEnd = prevMeta.EndMmapOffset }
+		if discardEnd > uint64(len(mMap)) { discardEnd = uint64(len(mMap)) }
+
+		if discardEnd > meta.EndMmapOffset {
+			// the discarded bytes may have been sealed in cold segments since they were written
+			thawErr := mmcMap.thawSegments()
+			if thawErr != nil { return nil, thawErr }
+
+			for offset := meta.EndMmapOffset; offset < discardEnd; offset++ { mMap[offset] = 0 }
+			report.DiscardedBytes = discardEnd - meta.EndMmapOffset
+		}
+
+		mmcMap.purgeNodes()
+
+		if mmcMap.isKeyCountTracked() {
+			keyCountPtr, _, loadKCountErr := mmcMap.loadMetaKeyCount()
+			if loadKCountErr != nil { return nil, loadKCountErr }
+
+			mmcMap.storeMetaPointer(keyCountPtr, report.KeyCount)
+		}
+
+		_, writeMetaErr := mmcMap.WriteMetaToMemMap(meta.SerializeMetaData())
+		if writeMetaErr != nil { return nil, writeMetaErr }
+
+		report.IsRepaired = true
+		return report, mmcMap.syncFiles()
+	}
+
+	return nil, ErrNoIntactRoot
+}
+
+// scanCommits
+//	Walk the node headers from the initial root until a node can not be read, collecting the root of every commit along with the end of the commit.
+//	Roots are identified as in scanRoots. Returns the commits and the offset one past the last readable node.
+func (mmcMap *MMCMap) scanCommits(mMap mmap.MMap) ([]repairCommit, uint64) {
+	var commits []repairCommit
+	if mmcMap.isCompactEncoding() {
+		scanEnd, _ := mmcMap.scanCompactNodes(mMap, uint64(len(mMap)), func(offset, version, endOffset uint64, isLeaf bool) bool {
+			if ! isLeaf && (len(commits) == 0 || version > commits[len(commits) - 1].version) {
+				commits = append(commits, repairCommit{ version: version, offset: offset })
+			}
+
+			if len(commits) > 0 { commits[len(commits) - 1].end = endOffset + 1 }
+			return true
+		})
+
+		return commits, scanEnd
+	}
+
+	offset := mmcMap.scanOffset()
+	scanEnd := offset
+
+	readUint64 := func(idx uint64) uint64 {
+		val, _ := deserializeUint64(mMap[idx:idx + OffsetSize])
+		return val
+	}
+
+	for offset + NodeKeyIdx + 1 <= uint64(len(mMap)) {
+		if readUint64(offset + NodeStartOffsetIdx) != offset {
+			offset++
+			if offset + NodeKeyIdx > uint64(len(mMap)) || readUint64(offset + NodeStartOffsetIdx) != offset { break }
+		}
+
+		version := readUint64(offset + NodeVersionIdx)
+		endOffset := readUint64(offset + NodeEndOffsetIdx)
+		if endOffset < offset || endOffset >= uint64(len(mMap)) { break }
+
+		if ! deserializeBoolean(mMap[offset + NodeIsLeafIdx]) && (len(commits) == 0 || version > commits[len(commits) - 1].version) {
+			commits = append(commits, repairCommit{ version: version, offset: offset })
+		}
+
+		if len(commits) > 0 { commits[len(commits) - 1].end = endOffset + 1 }
+
+		scanEnd = endOffset + 1
+		offset = endOffset + 1
+	}
+
+	return commits, scanEnd
+}
+
+// repairCommit is a commit found while scanning for an intact root
+type repairCommit struct {
+	version uint64
+	offset uint64
+	end uint64
+}
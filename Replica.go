@@ -0,0 +1,405 @@
+package mmcmap
+
+import "bufio"
+import "bytes"
+import "errors"
+import "io"
+import "sync/atomic"
+
+import "github.com/sirgallo/mmcmap/common/mmap"
+
+
+//============================================= MMCMap Replication
+
+
+// replicaMagic identifies a serialized replication stream
+var replicaMagic = []byte("MMCR")
+
+// replicaFormatVersion is the version of the serialized replication stream format
+const replicaFormatVersion = 5
+
+
+// ExportDelta
+//	Write a delta stream to w containing every version committed after sinceVersion.
+//	Commits are blocked while the delta is captured so the metadata and the appended bytes are consistent.
+//	The serialized stream is the 4 byte magic "MMCR", a 1 byte format version, a 1 byte snapshot flag, the 8 byte from version, to version, start offset,
+//	root offset, end offset, key count, hash seed, key mode, and segment size, an 8 byte data length, and then the data.
+func (mmcMap *MMCMap) ExportDelta(w io.Writer, sinceVersion uint64) error {
+	delta, exportErr := mmcMap.exportDelta(sinceVersion)
+	if exportErr != nil { return exportErr }
+
+	_, writeErr := w.Write(delta.Serialize())
+	return writeErr
+}
+
+// exportDelta
+//	Capture the delta containing every version committed after sinceVersion.
+func (mmcMap *MMCMap) exportDelta(sinceVersion uint64) (*ReplicaDelta, error) {
+	mmcMap.CommitGate.Lock()
+	defer mmcMap.CommitGate.Unlock()
+
+	roots, scanErr := mmcMap.scanRoots()
+	if scanErr != nil { return nil, scanErr }
+
+	delta, captureErr := mmcMap.captureDelta(func(meta *MMCMapMetaData) (uint64, error) {
+		if sinceVersion > meta.Version { return 0, errors.New("replica version is ahead of the primary") }
+
+		for _, root := range roots {
+			if root.version > sinceVersion { return root.offset, nil }
+		}
+
+		return meta.EndMmapOffset, nil
+	})
+
+	if captureErr != nil { return nil, captureErr }
+
+	delta.FromVersion = sinceVersion
+	return delta, nil
+}
+
+// ExportSnapshot
+//	Write a snapshot stream to w containing the header and the whole trie at the latest version.
+func (mmcMap *MMCMap) ExportSnapshot(w io.Writer) error {
+	mmcMap.CommitGate.Lock()
+	defer mmcMap.CommitGate.Unlock()
+
+	delta, captureErr := mmcMap.captureDelta(func(meta *MMCMapMetaData) (uint64, error) { return uint64(HeaderIdx), nil })
+	if captureErr != nil { return captureErr }
+
+	delta.IsSnapshot = true
+	_, writeErr := w.Write(delta.Serialize())
+	return writeErr
+}
+
+// NewReplicaSource
+//	A replica source that streams directly from a primary mmcmap open in the same process.
+func NewReplicaSource(primary *MMCMap) ReplicaSource {
+	return &mapReplicaSource{ primary: primary }
+}
+
+// ReplicaStatus
+//	The replication state of the map, used by replicas to determine which version to request deltas from.
+func (mmcMap *MMCMap) ReplicaStatus() (*ReplicaStatus, error) {
+	mmcMap.RWResizeLock.RLock()
+	defer mmcMap.RWResizeLock.RUnlock()
+
+	handleErr := mmcMap.checkHandle()
+	if handleErr != nil { return nil, handleErr }
+
+	meta, readMetaErr := mmcMap.ReadMetaFromMemMap()
+	if readMetaErr != nil { return nil, readMetaErr }
+
+	return &ReplicaStatus{
+		Version: meta.Version,
+		RootOffset: meta.RootOffset,
+		EndMmapOffset: meta.EndMmapOffset,
+		Deltas: atomic.LoadUint64(&mmcMap.ReplicaDeltas),
+		Snapshots: atomic.LoadUint64(&mmcMap.ReplicaSnapshots),
+		Gaps: atomic.LoadUint64(&mmcMap.ReplicaGaps),
+		FollowErrors: atomic.LoadUint64(&mmcMap.FollowErrors),
+	}, nil
+}
+
+// CatchUp
+//	Apply a replication stream read from from to the replica.
+//	Deltas the replica has already applied are ignored. If the delta starts after the version of the replica, the versions in between are missing,
+//	so the missing range is requested from the ReplicaSource in the options. If the missing range can not be applied, a full snapshot is requested
+//	instead. Without a ReplicaSource, ErrReplicaGap is returned and the replica is left unchanged.
+func (mmcMap *MMCMap) CatchUp(from io.Reader) error {
+	delta, readErr := ReadReplicaDelta(from)
+	if readErr != nil { return readErr }
+
+	return mmcMap.catchUpDelta(delta)
+}
+
+// catchUpDelta
+//	Apply a delta to the replica, recovering from a gap through the ReplicaSource.
+func (mmcMap *MMCMap) catchUpDelta(delta *ReplicaDelta) error {
+	applyErr := mmcMap.applyDelta(delta)
+	if applyErr != ErrReplicaGap { return applyErr }
+
+	atomic.AddUint64(&mmcMap.ReplicaGaps, 1)
+
+	source := mmcMap.Opts.ReplicaSource
+	if source == nil { return ErrReplicaGap }
+
+	status, statusErr := mmcMap.ReplicaStatus()
+	if statusErr != nil { return statusErr }
+
+	missing, deltaErr := source.DeltaSince(status.Version)
+	if deltaErr == nil {
+		missingDelta, readErr := ReadReplicaDelta(missing)
+		if readErr == nil && mmcMap.applyDelta(missingDelta) == nil { return nil }
+	}
+
+	snapshot, snapshotErr := source.Snapshot()
+	if snapshotErr != nil { return snapshotErr }
+
+	snapshotDelta, readErr := ReadReplicaDelta(snapshot)
+	if readErr != nil { return readErr }
+
+	return mmcMap.applyDelta(snapshotDelta)
+}
+
+// Serialize
+//	Serialize the replication stream.
+func (delta *ReplicaDelta) Serialize() []byte {
+	var buf bytes.Buffer
+	buf.Write(replicaMagic)
+	buf.WriteByte(replicaFormatVersion)
+	buf.WriteByte(serializeBoolean(delta.IsSnapshot))
+
+	for _, val := range []uint64{ delta.FromVersion, delta.ToVersion, delta.StartOffset, delta.RootOffset, delta.EndMmapOffset, delta.KeyCount, delta.HashSeed, uint64(delta.KeyMode), delta.SegmentSize, uint64(len(delta.Data)) } {
+		buf.Write(serializeUint64(val))
+	}
+
+	buf.Write(delta.Data)
+	return buf.Bytes()
+}
+
+// ReadReplicaDelta
+//	Read a replication stream, as written by ExportDelta or ExportSnapshot.
+func ReadReplicaDelta(r io.Reader) (*ReplicaDelta, error) {
+	reader := bufio.NewReader(r)
+
+	header := make([]byte, len(replicaMagic) + 2 + 10 * OffsetSize)
+	_, readErr := io.ReadFull(reader, header)
+	if readErr != nil { return nil, readErr }
+
+	if ! bytes.Equal(header[:len(replicaMagic)], replicaMagic) { return nil, errors.New("invalid replication stream magic") }
+	if header[len(replicaMagic)] != replicaFormatVersion { return nil, errors.New("unsupported replication stream format version") }
+
+	offset := len(replicaMagic) + 1
+	delta := &ReplicaDelta{ IsSnapshot: deserializeBoolean(header[offset]) }
+	offset++
+
+	var keyMode uint64
+	fields := []*uint64{ &delta.FromVersion, &delta.ToVersion, &delta.StartOffset, &delta.RootOffset, &delta.EndMmapOffset, &delta.KeyCount, &delta.HashSeed, &keyMode, &delta.SegmentSize }
+	for _, field := range fields {
+		*field, _ = deserializeUint64(header[offset:offset + OffsetSize])
+		offset += OffsetSize
+	}
+
+	if keyMode > uint64(KeyModeOrdered) { return nil, ErrUnknownKeyMode }
+	delta.KeyMode = KeyMode(keyMode)
+
+	dataLen, _ := deserializeUint64(header[offset:offset + OffsetSize])
+	if delta.StartOffset + dataLen != delta.EndMmapOffset { return nil, errors.New("replication stream length does not match its offsets") }
+
+	delta.Data = make([]byte, dataLen)
+	_, readErr = io.ReadFull(reader, delta.Data)
+	if readErr != nil { return nil, readErr }
+
+	return delta, nil
+}
+
+// captureDelta
+//	Copy the bytes from the offset chosen by startOffset up to the end of the memory map, along with the metadata. The commit gate must be held exclusively.
+func (mmcMap *MMCMap) captureDelta(startOffset func(meta *MMCMapMetaData) (uint64, error)) (*ReplicaDelta, error) {
+	mmcMap.RWResizeLock.RLock()
+	defer mmcMap.RWResizeLock.RUnlock()
+
+	handleErr := mmcMap.checkHandle()
+	if handleErr != nil { return nil, handleErr }
+
+	meta, readMetaErr := mmcMap.ReadMetaFromMemMap()
+	if readMetaErr != nil { return nil, readMetaErr }
+
+	start, startErr := startOffset(meta)
+	if startErr != nil { return nil, startErr }
+
+	_, keyCount, loadKCountErr := mmcMap.loadMetaKeyCount()
+	if loadKCountErr != nil { return nil, loadKCountErr }
+
+	mMap := mmcMap.Data.Load().(mmap.MMap)
+	data := make([]byte, meta.EndMmapOffset - start)
+	copy(data, mMap[start:meta.EndMmapOffset])
+
+	return &ReplicaDelta{
+		FromVersion: meta.Version,
+		ToVersion: meta.Version,
+		StartOffset: start,
+		RootOffset: meta.RootOffset,
+		EndMmapOffset: meta.EndMmapOffset,
+		KeyCount: keyCount,
+		HashSeed: mmcMap.Header.HashSeed,
+		KeyMode: mmcMap.Header.KeyMode,
+		SegmentSize: mmcMap.Header.SegmentSize,
+		Data: data,
+	}, nil
+}
+
+// applyDelta
+//	Copy the bytes of the delta into the memory map at their original offsets and adopt the metadata of the primary.
+//	Snapshots always apply, and the header is reloaded since it is included in the snapshot. A delta applies if the replica is at or past its from
+//	version and behind its to version, otherwise ErrReplicaGap is returned.
+//	A snapshot holds the seal lock, taken before the commit gate like a seal does, so it waits for a seal in flight and no segment is compressed
+//	while the snapshot is copied over the segments.
+func (mmcMap *MMCMap) applyDelta(delta *ReplicaDelta) error {
+	if delta.IsSnapshot {
+		mmcMap.SealLock.Lock()
+		defer mmcMap.SealLock.Unlock()
+	}
+
+	mmcMap.CommitGate.Lock()
+	defer mmcMap.CommitGate.Unlock()
+
+	adoptErr := mmcMap.adoptLayout(delta)
+	if adoptErr != nil { return adoptErr }
+
+	for {
+		if delta.IsSnapshot {
+			thawErr := mmcMap.thawForSnapshot()
+			if thawErr != nil { return thawErr }
+		}
+
+		applied, applyErr := mmcMap.tryApplyDelta(delta)
+		if applyErr != nil { return applyErr }
+		if applied { break }
+	}
+
+	if delta.IsSnapshot {
+		// readers outside the commit gate, like the seal go routine, read the header under the resize lock
+		mmcMap.lockResize()
+		loadHeaderErr := mmcMap.loadHeader()
+		mmcMap.unlockResize()
+
+		if loadHeaderErr != nil { return loadHeaderErr }
+
+		atomic.AddUint64(&mmcMap.ReplicaSnapshots, 1)
+	} else { atomic.AddUint64(&mmcMap.ReplicaDeltas, 1) }
+
+	mmcMap.RWResizeLock.RLock()
+	defer mmcMap.RWResizeLock.RUnlock()
+
+	return mmcMap.syncFiles()
+}
+
+// adoptLayout
+//	Write the hash seed and key mode of the primary to the header of a replica that is still at version 0, before the first delta is applied. Keys
+//	are laid out by both, so a replica that already holds versions of the primary keeps its own, and tryApplyDelta returns ErrReplicaGap for the
+//	delta. Readers are excluded while the header changes.
+func (mmcMap *MMCMap) adoptLayout(delta *ReplicaDelta) error {
+	if delta.IsSnapshot || (delta.HashSeed == mmcMap.Header.HashSeed && delta.KeyMode == mmcMap.Header.KeyMode) { return nil }
+
+	mmcMap.lockResize()
+	defer mmcMap.unlockResize()
+
+	handleErr := mmcMap.checkHandle()
+	if handleErr != nil { return handleErr }
+
+	_, version, loadVErr := mmcMap.loadMetaVersion()
+	if loadVErr != nil { return loadVErr }
+	if version > 0 { return nil }
+
+	mMap := mmcMap.Data.Load().(mmap.MMap)
+	copy(mMap[HeaderHashSeedIdx:HeaderHashSeedIdx + OffsetSize], serializeUint64(delta.HashSeed))
+	mMap[HeaderKeyModeIdx] = byte(delta.KeyMode)
+	mmcMap.Header.HashSeed = delta.HashSeed
+	mmcMap.Header.KeyMode = delta.KeyMode
+
+	return nil
+}
+
+// thawForSnapshot
+//	Map the cold segments of a replica writable again before a snapshot is copied over them, including any a resize for the snapshot mapped read
+//	only. The caller holds the seal lock and blocks commits, so no segment is sealed again until the snapshot is applied.
+func (mmcMap *MMCMap) thawForSnapshot() error {
+	mmcMap.waitForResize()
+
+	mmcMap.lockResize()
+	defer mmcMap.unlockResize()
+
+	handleErr := mmcMap.checkHandle()
+	if handleErr != nil { return handleErr }
+
+	return mmcMap.thawSegments()
+}
+
+// tryApplyDelta
+//	A single attempt at applying the delta. Returns false if the memory map had to be resized first.
+func (mmcMap *MMCMap) tryApplyDelta(delta *ReplicaDelta) (bool, error) {
+	mmcMap.waitForResize()
+
+	mmcMap.RWResizeLock.RLock()
+	defer mmcMap.RWResizeLock.RUnlock()
+
+	handleErr := mmcMap.checkHandle()
+	if handleErr != nil { return false, handleErr }
+	if delta.SegmentSize != mmcMap.Header.SegmentSize { return false, ErrSegmentSizeMismatch }
+
+	meta, readMetaErr := mmcMap.ReadMetaFromMemMap()
+	if readMetaErr != nil { return false, readMetaErr }
+
+	if ! delta.IsSnapshot {
+		if delta.ToVersion <= meta.Version { return true, nil }
+		if delta.FromVersion > meta.Version || delta.StartOffset > meta.EndMmapOffset + 1 { return false, ErrReplicaGap }
+		// keys are laid out by the hash seed and key mode, so a replica can only adopt those of the primary before it holds any of its versions
+		isSameLayout := delta.HashSeed == mmcMap.Header.HashSeed && delta.KeyMode == mmcMap.Header.KeyMode
+		if ! isSameLayout && meta.Version > 0 { return false, ErrReplicaGap }
+	}
+
+	fileSizeErr := mmcMap.checkFileSize(delta.EndMmapOffset)
+	if fileSizeErr != nil { return false, fileSizeErr }
+
+	if mmcMap.determineIfResize(delta.EndMmapOffset) { return false, nil }
+
+	segments := mmcMap.loadSegments()
+
+	mMap := mmcMap.Data.Load().(mmap.MMap)
+	copy(mMap[delta.StartOffset:delta.EndMmapOffset], delta.Data)
+
+	if delta.IsSnapshot {
+		mmcMap.purgeNodes()
+		// the snapshot holds the segment directory of the primary, but the segment files are the replica's own
+		if mmcMap.isSegmented() {
+			restoreErr := mmcMap.restoreSegments(segments)
+			if restoreErr != nil { return false, restoreErr }
+		}
+	}
+
+	versionPtr, _, loadVErr := mmcMap.loadMetaVersion()
+	if loadVErr != nil { return false, loadVErr }
+
+	rootOffsetPtr, _, loadROffErr := mmcMap.loadMetaRootOffset()
+	if loadROffErr != nil { return false, loadROffErr }
+
+	endOffsetPtr, _, loadSOffErr := mmcMap.loadMetaEndSerialized()
+	if loadSOffErr != nil { return false, loadSOffErr }
+
+	mmcMap.storeMetaPointer(endOffsetPtr, delta.EndMmapOffset)
+
+	// a snapshot already copied the key count of the primary along with its header
+	publishErr := mmcMap.publishRootWithCount(rootOffsetPtr, delta.RootOffset, delta.KeyCount)
+	if publishErr != nil { return false, publishErr }
+
+	mmcMap.storeMetaPointer(versionPtr, delta.ToVersion)
+	mmcMap.refreshPinned(delta.ToVersion, delta.RootOffset)
+
+	return true, nil
+}
+
+// mapReplicaSource streams replication deltas from a primary open in the same process
+type mapReplicaSource struct {
+	primary *MMCMap
+}
+
+// DeltaSince
+//	Export a delta from the primary containing every version after version.
+func (source *mapReplicaSource) DeltaSince(version uint64) (io.Reader, error) {
+	var buf bytes.Buffer
+	exportErr := source.primary.ExportDelta(&buf, version)
+	if exportErr != nil { return nil, exportErr }
+
+	return &buf, nil
+}
+
+// Snapshot
+//	Export a snapshot of the primary.
+func (source *mapReplicaSource) Snapshot() (io.Reader, error) {
+	var buf bytes.Buffer
+	exportErr := source.primary.ExportSnapshot(&buf)
+	if exportErr != nil { return nil, exportErr }
+
+	return &buf, nil
+}
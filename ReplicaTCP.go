@@ -0,0 +1,218 @@
+package mmcmap
+
+import "bufio"
+import "bytes"
+import "errors"
+import "fmt"
+import "io"
+import "net"
+import "sync/atomic"
+import "time"
+
+
+//============================================= MMCMap Replication Over TCP
+
+
+const (
+	// replicaRequestDelta: request a single delta containing every version after the requested version
+	replicaRequestDelta byte = iota + 1
+	// replicaRequestSnapshot: request a single snapshot of the latest version
+	replicaRequestSnapshot
+	// replicaRequestFollow: request a delta for every version committed after the requested version, until the connection is closed
+	replicaRequestFollow
+)
+
+// replicaRequestSize is the size of a serialized replication request, which is a 1 byte kind and an 8 byte version
+const replicaRequestSize = 1 + OffsetSize
+
+
+// ServeReplication
+//	Serve replication streams to replicas on other machines, accepting connections from listener until it is closed.
+//	Each connection sends a single request, a 1 byte kind followed by an 8 byte version, and is answered with replication streams in the format
+//	written by ExportDelta. Delta and snapshot requests are answered with one stream, as used by the source returned by NewTCPReplicaSource.
+//	Follow requests, as sent by maps opened with FollowAddr, are answered with a delta each time new versions are committed, which is detected by
+//	checking the version every ReplicationOpts.PollInterval. Returns nil once the listener is closed.
+func (mmcMap *MMCMap) ServeReplication(listener net.Listener, opts ...ReplicationOpts) error {
+	var replicationOpts ReplicationOpts
+	if len(opts) > 0 { replicationOpts = opts[0] }
+	if replicationOpts.PollInterval <= 0 { replicationOpts.PollInterval = DefaultReplicationPollInterval }
+
+	for {
+		conn, acceptErr := listener.Accept()
+		if errors.Is(acceptErr, net.ErrClosed) { return nil }
+		if acceptErr != nil { return acceptErr }
+
+		mmcMap.goLabeled("replicate", func() { mmcMap.serveReplica(conn, replicationOpts.PollInterval) })
+	}
+}
+
+// NewTCPReplicaSource
+//	A replica source that requests replication streams from a primary serving replication at addr, dialing a new connection for each request.
+func NewTCPReplicaSource(addr string) ReplicaSource {
+	return &tcpReplicaSource{ addr: addr }
+}
+
+// serveReplica
+//	Answer the request read from a single replica connection, closing the connection once it is answered or the replica disconnects.
+func (mmcMap *MMCMap) serveReplica(conn net.Conn, pollInterval time.Duration) error {
+	defer conn.Close()
+
+	request := make([]byte, replicaRequestSize)
+	_, readErr := io.ReadFull(conn, request)
+	if readErr != nil { return readErr }
+
+	version, _ := deserializeUint64(request[1:])
+	writer := bufio.NewWriter(conn)
+
+	switch request[0] {
+		case replicaRequestDelta:
+			exportErr := mmcMap.ExportDelta(writer, version)
+			if exportErr != nil { return exportErr }
+		case replicaRequestSnapshot:
+			exportErr := mmcMap.ExportSnapshot(writer)
+			if exportErr != nil { return exportErr }
+		case replicaRequestFollow:
+			return mmcMap.streamDeltas(conn, writer, version, pollInterval)
+		default:
+			return fmt.Errorf("unknown replication request kind %d", request[0])
+	}
+
+	return writer.Flush()
+}
+
+// streamDeltas
+//	Write a delta to the follower each time the version moves past the last version sent, until the follower disconnects or the map is closed.
+//	Followers never write to the connection after their request, so a read returning signals the follower has gone.
+func (mmcMap *MMCMap) streamDeltas(conn net.Conn, writer *bufio.Writer, sinceVersion uint64, pollInterval time.Duration) error {
+	disconnected := make(chan struct{})
+	go func() {
+		io.Copy(io.Discard, conn)
+		close(disconnected)
+	}()
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		status, statusErr := mmcMap.ReplicaStatus()
+		if statusErr != nil { return statusErr }
+
+		if status.Version != sinceVersion {
+			delta, exportErr := mmcMap.exportDelta(sinceVersion)
+			if exportErr != nil { return exportErr }
+
+			_, writeErr := writer.Write(delta.Serialize())
+			if writeErr != nil { return writeErr }
+
+			flushErr := writer.Flush()
+			if flushErr != nil { return flushErr }
+
+			sinceVersion = delta.ToVersion
+		}
+
+		select {
+			case <-disconnected:
+				return nil
+			case <-ticker.C:
+		}
+	}
+}
+
+// isFollower
+//	Determine if the map was opened as a follower of a primary, in which case writes are rejected.
+func (mmcMap *MMCMap) isFollower() bool {
+	return mmcMap.Opts.FollowAddr != ""
+}
+
+// handleFollow
+//	The follower go routine. Follows the primary, reconnecting after FollowRetryInterval whenever the connection is lost, until stop is closed.
+func (mmcMap *MMCMap) handleFollow(stop chan struct{}) {
+	for {
+		followErr := mmcMap.followPrimary(stop)
+		if followErr != nil {
+			atomic.AddUint64(&mmcMap.FollowErrors, 1)
+			mmcMap.log(LogWarn, "lost connection to primary", "addr", mmcMap.Opts.FollowAddr, "err", followErr)
+		}
+
+		select {
+			case <-stop:
+				return
+			case <-time.After(FollowRetryInterval):
+		}
+	}
+}
+
+// followPrimary
+//	Request every version after the latest version of the map from the primary, and apply each delta as it arrives.
+//	A gap is recovered through the ReplicaSource, as with CatchUp. The connection is closed when stop is closed, which ends the follow.
+func (mmcMap *MMCMap) followPrimary(stop chan struct{}) error {
+	status, statusErr := mmcMap.ReplicaStatus()
+	if statusErr != nil { return statusErr }
+
+	conn, dialErr := net.DialTimeout("tcp", mmcMap.Opts.FollowAddr, FollowRetryInterval)
+	if dialErr != nil { return dialErr }
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+			case <-stop:
+			case <-done:
+		}
+
+		conn.Close()
+	}()
+
+	_, writeErr := conn.Write(serializeReplicaRequest(replicaRequestFollow, status.Version))
+	if writeErr != nil { return writeErr }
+
+	// ReadReplicaDelta reuses a *bufio.Reader of the default size, so bytes buffered past one delta are kept for the next
+	reader := bufio.NewReader(conn)
+	for {
+		delta, readErr := ReadReplicaDelta(reader)
+		if readErr != nil { return readErr }
+
+		applyErr := mmcMap.catchUpDelta(delta)
+		if applyErr != nil { return applyErr }
+	}
+}
+
+// serializeReplicaRequest
+//	Serialize a replication request of the given kind for the version of the replica.
+func serializeReplicaRequest(kind byte, version uint64) []byte {
+	return append([]byte{ kind }, serializeUint64(version)...)
+}
+
+// tcpReplicaSource requests replication streams from a primary serving replication over TCP
+type tcpReplicaSource struct {
+	addr string
+}
+
+// DeltaSince
+//	Request a delta from the primary containing every version after version.
+func (source *tcpReplicaSource) DeltaSince(version uint64) (io.Reader, error) {
+	return source.request(replicaRequestDelta, version)
+}
+
+// Snapshot
+//	Request a snapshot of the primary.
+func (source *tcpReplicaSource) Snapshot() (io.Reader, error) {
+	return source.request(replicaRequestSnapshot, 0)
+}
+
+// request
+//	Send a single request to the primary and read back the replication stream it answers with.
+func (source *tcpReplicaSource) request(kind byte, version uint64) (io.Reader, error) {
+	conn, dialErr := net.DialTimeout("tcp", source.addr, FollowRetryInterval)
+	if dialErr != nil { return nil, dialErr }
+	defer conn.Close()
+
+	_, writeErr := conn.Write(serializeReplicaRequest(kind, version))
+	if writeErr != nil { return nil, writeErr }
+
+	delta, readErr := ReadReplicaDelta(conn)
+	if readErr != nil { return nil, readErr }
+
+	return bytes.NewReader(delta.Serialize()), nil
+}
@@ -0,0 +1,426 @@
+package mmcmap
+
+import "fmt"
+import "os"
+import "sync/atomic"
+
+import "github.com/sirgallo/mmcmap/common/mmap"
+
+
+//============================================= MMCMap Segments
+
+
+// ReclaimSegments
+//	Remove the oldest segments of a file using segmented storage once nothing reachable from the latest root is in them, returning the disk space of
+//	the prior versions they hold without rewriting the trie. The latest trie is walked to find the lowest offset it references, and every segment
+//	before the one holding it is replaced in the memory map by zeroed pages and its file removed. The first segment holds the metadata and is never
+//	removed. Versions before the latest may reference the removed segments, so like Compact, history, diffs, backups, and replicas can not reach
+//	them afterwards, and like Compact it waits for pinned traversals without blocking commits or reads. Commits and reads are then blocked while the trie
+//	is walked.
+func (mmcMap *MMCMap) ReclaimSegments() (*SegmentReport, error) {
+	if ! mmcMap.isSegmented() { return nil, ErrNotSegmented }
+
+	mmcMap.lockRelocate()
+	defer mmcMap.unlockRelocate()
+
+	mmcMap.lockResize()
+	defer mmcMap.unlockResize()
+
+	handleErr := mmcMap.checkHandle()
+	if handleErr != nil { return nil, handleErr }
+
+	meta, readMetaErr := mmcMap.ReadMetaFromMemMap()
+	if readMetaErr != nil { return nil, readMetaErr }
+
+	lowestNode, lowestOffset := meta.RootOffset, meta.RootOffset
+	walkErr := mmcMap.lowestLiveOffset(meta.RootOffset, &lowestNode, &lowestOffset)
+	if walkErr != nil { return nil, walkErr }
+
+	segmentSize := mmcMap.Header.SegmentSize
+	segments := *mmcMap.loadSegments()
+	firstSegment := segments.first()
+	liveSegment := lowestOffset / segmentSize
+
+	report := &SegmentReport{ FirstSegment: firstSegment, LastSegment: segments.LastSegment, MinVersion: segments.MinVersion }
+	if liveSegment <= firstSegment { return report, nil }
+
+	atomic.AddUint64(&mmcMap.RelocateGeneration, 1)
+
+	files := append([]MMCMapSegmentFile{}, mmcMap.loadSegmentFiles()...)
+	for id := firstSegment; id < liveSegment; id++ {
+		_, zeroErr := mmap.MapZeroAt(mmcMap.Reservation[id * segmentSize:], int(segmentSize))
+		if zeroErr != nil { return nil, zeroErr }
+
+		if files[id].File != nil { files[id].File.Close() }
+		files[id] = MMCMapSegmentFile{}
+	}
+
+	mmcMap.SegmentFiles.Store(files)
+	mmcMap.purgeNodes()
+
+	segments.FirstSegment, segments.ScanOffset, segments.MinVersion = liveSegment, lowestNode, meta.Version
+	writeErr := mmcMap.writeSegments(&segments)
+	if writeErr != nil { return nil, writeErr }
+
+	for id := firstSegment; id < liveSegment; id++ {
+		removeErr := mmcMap.removeSegment(id)
+		if removeErr != nil { return nil, removeErr }
+	}
+
+	mmcMap.lockMemory(mmcMap.Data.Load().(mmap.MMap))
+
+	report.FirstSegment, report.MinVersion = liveSegment, meta.Version
+	report.RemovedSegments = liveSegment - firstSegment
+	report.ReclaimedBytes = report.RemovedSegments * segmentSize
+
+	mmcMap.log(LogInfo, "reclaimed segments", "removed", report.RemovedSegments, "first", liveSegment)
+	return report, nil
+}
+
+// lowestLiveOffset
+//	Lower lowestNode to the offset of the lowest node beneath offset, and lowestOffset to the lowest byte referenced beneath it, which is an
+//	overflow extent kept by a leaf that was copied since the extent was written.
+func (mmcMap *MMCMap) lowestLiveOffset(offset uint64, lowestNode, lowestOffset *uint64) error {
+	node, readNodeErr := mmcMap.ReadNodeFromMemMap(offset)
+	if readNodeErr != nil { return readNodeErr }
+
+	if offset < *lowestNode { *lowestNode = offset }
+	if offset < *lowestOffset { *lowestOffset = offset }
+	if node.IsLeaf && node.IsOverflow && node.OverflowOffset < *lowestOffset { *lowestOffset = node.OverflowOffset }
+
+	for _, child := range node.Children {
+		walkErr := mmcMap.lowestLiveOffset(child.StartOffset, lowestNode, lowestOffset)
+		if walkErr != nil { return walkErr }
+	}
+
+	return nil
+}
+
+// isSegmented
+//	Determine if the file is stored as segments instead of a single file.
+func (mmcMap *MMCMap) isSegmented() bool {
+	return mmcMap.Header.SegmentSize > 0
+}
+
+// isValidSegmentSize
+//	Segments are mapped at page boundaries, and must be large enough that paths rarely cross them.
+func isValidSegmentSize(size uint64) bool {
+	return size >= MinSegmentSize && size % uint64(DefaultPageSize) == 0
+}
+
+// resolveSegments
+//	Check the segment size from the file header against the options. Single files are the default, so segmented files can be opened without
+//	setting SegmentSize, but opening a file with a different size is an error. Removing segments relies on nodes never being written over, so
+//	segmented storage can not be combined with the free list allocator, and rewriting the file as a whole is left to Compact on single files.
+//	Only segments can be sealed as cold.
+func (mmcMap *MMCMap) resolveSegments() error {
+	if mmcMap.Opts.SegmentSize != 0 && mmcMap.Opts.SegmentSize != mmcMap.Header.SegmentSize { return ErrSegmentSizeMismatch }
+	if ! mmcMap.isSegmented() {
+		if mmcMap.Opts.HotSegments > 0 || mmcMap.Opts.ColdCompression != CompressionNone { return ErrNotSegmented }
+		return nil
+	}
+
+	if ! isValidSegmentSize(mmcMap.Header.SegmentSize) { return ErrInvalidSegmentSize }
+	if mmcMap.Opts.MaxFileSize > 0 && mmcMap.Opts.MaxFileSize < mmcMap.Header.SegmentSize { return ErrInvalidSizeLimit }
+	if mmcMap.Header.AllocatorID == AllocFreeList || mmcMap.isAutoCompactionEnabled() { return ErrSegmentedUnsupported }
+
+	return nil
+}
+
+// segmentPath
+//	The path of the file holding segment id, numbered from 1 after the file at Filepath.
+func (mmcMap *MMCMap) segmentPath(id uint64) string {
+	return fmt.Sprintf("%s.%06d", mmcMap.Opts.Filepath, id)
+}
+
+// loadSegments
+//	The segment directory, which is empty before the file is mapped.
+func (mmcMap *MMCMap) loadSegments() *MMCMapSegments {
+	segments, ok := mmcMap.Segments.Load().(*MMCMapSegments)
+	if ! ok { return &MMCMapSegments{} }
+
+	return segments
+}
+
+// loadSegmentFiles
+//	The files of the segments after the first, indexed by segment number.
+func (mmcMap *MMCMap) loadSegmentFiles() []MMCMapSegmentFile {
+	files, _ := mmcMap.SegmentFiles.Load().([]MMCMapSegmentFile)
+	return files
+}
+
+// scanOffset
+//	The offset scans for roots start at, which is past the removed segments of a file using segmented storage.
+func (mmcMap *MMCMap) scanOffset() uint64 {
+	scanOffset := mmcMap.loadSegments().ScanOffset
+	if ! mmcMap.isSegmented() || scanOffset < mmcMap.HeaderSize { return mmcMap.HeaderSize }
+
+	return scanOffset
+}
+
+// minVersion
+//	The oldest version whose root can be read, since versions before the last ReclaimSegments may reference removed segments.
+func (mmcMap *MMCMap) minVersion() uint64 {
+	if ! mmcMap.isSegmented() { return 0 }
+	return mmcMap.loadSegments().MinVersion
+}
+
+// mapAfterHeader
+//	Map every segment of an existing file using segmented storage once its header is read, since only the first segment is mapped before then.
+func (mmcMap *MMCMap) mapAfterHeader() error {
+	if ! mmcMap.isSegmented() { return nil }
+
+	unmapErr := mmcMap.munmap()
+	if unmapErr != nil { return unmapErr }
+
+	return mmcMap.mMap()
+}
+
+// mapSegments
+//	Map the first segment, which is the file at Filepath, and every later segment in the segment directory one after another at the start of a
+//	reservation, so offsets address the segments as one contiguous memory map. Removed segments are mapped as zeroed pages, and cold segments
+//	are mapped read only.
+func (mmcMap *MMCMap) mapSegments() error {
+	segmentSize := mmcMap.Header.SegmentSize
+
+	stat, statErr := mmcMap.File.Stat()
+	if statErr != nil { return statErr }
+	if uint64(stat.Size()) != segmentSize { return fmt.Errorf("first segment is %d bytes instead of the %d byte segment size", stat.Size(), segmentSize) }
+
+	sSegments := make([]byte, SegmentDirectorySize)
+	_, readErr := mmcMap.File.ReadAt(sSegments, SegmentDirectoryIdx)
+	if readErr != nil { return readErr }
+
+	segments := deserializeSegments(sSegments)
+	size := (segments.LastSegment + 1) * segmentSize
+
+	reservation, reserveErr := mmap.Reserve(int(mmcMap.reservationSize(int64(size))))
+	if reserveErr != nil { return reserveErr }
+
+	files := make([]MMCMapSegmentFile, segments.LastSegment + 1)
+	closeFiles := func() {
+		for _, file := range files {
+			if file.File != nil { file.File.Close() }
+		}
+	}
+
+	var endOffset uint64
+	_, mapErr := mmap.MapRegionAt(reservation, mmcMap.File, int(segmentSize), mmap.RDWR, 0)
+	if mapErr == nil {
+		meta, readMetaErr := DeserializeMetaData(reservation[MetaVersionIdx:MetaEndSerializedOffset + OffsetSize])
+		if readMetaErr == nil { endOffset = meta.EndMmapOffset }
+	}
+
+	for id := uint64(1); mapErr == nil && id <= segments.LastSegment; id++ {
+		if id < segments.FirstSegment {
+			_, mapErr = mmap.MapZeroAt(reservation[id * segmentSize:], int(segmentSize))
+			continue
+		}
+
+		files[id], mapErr = mmcMap.mapSegment(reservation, id, mmcMap.isColdEligible(id, segments.LastSegment, endOffset))
+	}
+
+	if mapErr != nil {
+		reservation.Unmap()
+		closeFiles()
+		return mapErr
+	}
+
+	mMap := reservation[:size]
+	mmcMap.Reservation = reservation
+	mmcMap.Segments.Store(segments)
+	mmcMap.SegmentFiles.Store(files)
+	mmcMap.Data.Store(mMap)
+	mmcMap.lockMemory(mMap)
+
+	return nil
+}
+
+// openSegment
+//	Open the file of segment id, checking that it holds a whole segment so the memory map never extends past the end of the file.
+func (mmcMap *MMCMap) openSegment(id uint64) (*os.File, error) {
+	file, openErr := os.OpenFile(mmcMap.segmentPath(id), os.O_RDWR, 0600)
+	if openErr != nil { return nil, openErr }
+
+	stat, statErr := file.Stat()
+	if statErr == nil && uint64(stat.Size()) != mmcMap.Header.SegmentSize { statErr = fmt.Errorf("segment %d is %d bytes instead of the %d byte segment size", id, stat.Size(), mmcMap.Header.SegmentSize) }
+	if statErr != nil {
+		file.Close()
+		return nil, statErr
+	}
+
+	return file, nil
+}
+
+// createSegment
+//	Create the file of segment id, sized to a whole segment. A file left behind by a resize that failed before the segment was recorded is reused.
+func (mmcMap *MMCMap) createSegment(id uint64) (*os.File, error) {
+	file, openErr := os.OpenFile(mmcMap.segmentPath(id), os.O_RDWR | os.O_CREATE | os.O_TRUNC, 0600)
+	if openErr != nil { return nil, openErr }
+
+	truncateErr := file.Truncate(int64(mmcMap.Header.SegmentSize))
+	if truncateErr != nil {
+		file.Close()
+		return nil, truncateErr
+	}
+
+	return file, nil
+}
+
+// growSegments
+//	Add a segment and map it directly after the memory map, within the reservation, while holding only the resize read lock. The segment is only
+//	recorded in the segment directory once it is mapped. Returns false if it does not fit in the reservation, in which case the segments have to
+//	be remapped.
+func (mmcMap *MMCMap) growSegments(mMap mmap.MMap) (bool, error) {
+	segmentSize := mmcMap.Header.SegmentSize
+	if len(mMap) == 0 || uint64(len(mMap)) + segmentSize > uint64(len(mmcMap.Reservation)) { return false, nil }
+
+	id := uint64(len(mMap)) / segmentSize
+	file, createErr := mmcMap.createSegment(id)
+	if createErr != nil { return false, createErr }
+
+	_, mapErr := mmap.MapRegionAt(mmcMap.Reservation[len(mMap):], file, int(segmentSize), mmap.RDWR, 0)
+	if mapErr != nil {
+		file.Close()
+		return false, mapErr
+	}
+
+	mmcMap.SegmentFiles.Store(append(append([]MMCMapSegmentFile{}, mmcMap.loadSegmentFiles()...), MMCMapSegmentFile{ File: file }))
+
+	segments := *mmcMap.loadSegments()
+	segments.LastSegment = id
+	writeErr := mmcMap.writeSegments(&segments)
+	if writeErr != nil { return false, writeErr }
+
+	grownMap := mmcMap.Reservation[:uint64(len(mMap)) + segmentSize]
+	mmcMap.Data.Store(grownMap)
+	mmcMap.lockMemory(grownMap)
+
+	mmcMap.log(LogDebug, "added segment", "segment", id)
+	return true, nil
+}
+
+// remapSegments
+//	Add a segment and map every segment again at the start of a new reservation, when the next segment does not fit in the current one. A new file
+//	is instead sized to its first segment. The resize write lock must be held.
+func (mmcMap *MMCMap) remapSegments(mMap mmap.MMap) error {
+	segmentSize := mmcMap.Header.SegmentSize
+	if len(mMap) == 0 {
+		truncateErr := mmcMap.File.Truncate(int64(segmentSize))
+		if truncateErr != nil { return truncateErr }
+
+		return mmcMap.mMap()
+	}
+
+	id := uint64(len(mMap)) / segmentSize
+	file, createErr := mmcMap.createSegment(id)
+	if createErr != nil { return createErr }
+	file.Close()
+
+	segments := *mmcMap.loadSegments()
+	segments.LastSegment = id
+	writeErr := mmcMap.writeSegments(&segments)
+	if writeErr != nil { return writeErr }
+
+	flushErr := mmcMap.syncFiles()
+	if flushErr != nil { return flushErr }
+
+	unmapErr := mmcMap.munmap()
+	if unmapErr != nil { return unmapErr }
+
+	mmcMap.log(LogDebug, "remapped segments", "segment", id)
+	return mmcMap.mMap()
+}
+
+// truncateSegments
+//	Remove every segment after the first and truncate the first back to a single empty segment, for a truncating Clear. The memory map must be
+//	unmapped.
+func (mmcMap *MMCMap) truncateSegments(segments *MMCMapSegments) error {
+	for id := segments.first(); id <= segments.LastSegment; id++ {
+		removeErr := mmcMap.removeSegment(id)
+		if removeErr != nil { return removeErr }
+	}
+
+	truncateErr := mmcMap.File.Truncate(0)
+	if truncateErr != nil { return truncateErr }
+
+	return mmcMap.File.Truncate(int64(mmcMap.Header.SegmentSize))
+}
+
+// writeSegments
+//	Write the segment directory after the header and flush it. The resize lock must be held, and only one writer may change the directory at a time.
+func (mmcMap *MMCMap) writeSegments(segments *MMCMapSegments) error {
+	mMap := mmcMap.Data.Load().(mmap.MMap)
+	copy(mMap[SegmentDirectoryIdx:SegmentDirectoryIdx + SegmentDirectorySize], segments.serialize())
+	mmcMap.Segments.Store(segments)
+
+	return mmcMap.flushRegionToDisk(SegmentDirectoryIdx, SegmentDirectoryIdx + SegmentDirectorySize)
+}
+
+// restoreSegments
+//	Restore the segments of a replica after a snapshot is copied over its segment directory. The replica keeps its own segment files, and adopts
+//	where the primary starts scanning and the oldest version it can read, since the snapshot holds zeroes in place of the segments it removed.
+func (mmcMap *MMCMap) restoreSegments(segments *MMCMapSegments) error {
+	mMap := mmcMap.Data.Load().(mmap.MMap)
+	primary := deserializeSegments(mMap[SegmentDirectoryIdx:SegmentDirectoryIdx + SegmentDirectorySize])
+
+	restored := *segments
+	restored.ScanOffset, restored.MinVersion = primary.ScanOffset, primary.MinVersion
+
+	return mmcMap.writeSegments(&restored)
+}
+
+// closeSegmentFiles
+//	Close the files of every segment after the first once the memory map no longer needs them.
+func (mmcMap *MMCMap) closeSegmentFiles() {
+	for _, file := range mmcMap.loadSegmentFiles() {
+		if file.File != nil { file.File.Close() }
+	}
+
+	mmcMap.SegmentFiles.Store([]MMCMapSegmentFile{})
+}
+
+// segmentFilesSize
+//	The bytes on disk of every segment after the first, counting compressed segments at their compressed size.
+func (mmcMap *MMCMap) segmentFilesSize() (int, error) {
+	var size int
+	for _, file := range mmcMap.loadSegmentFiles() {
+		if file.File == nil { continue }
+
+		stat, statErr := file.File.Stat()
+		if statErr != nil { return 0, statErr }
+
+		size += int(stat.Size())
+	}
+
+	return size, nil
+}
+
+// first
+//	The oldest segment after the first that has not been removed.
+func (segments *MMCMapSegments) first() uint64 {
+	if segments.FirstSegment == 0 { return 1 }
+	return segments.FirstSegment
+}
+
+// serialize
+//	The segment directory is the 8 byte first segment, last segment, scan offset, and min version.
+func (segments *MMCMapSegments) serialize() []byte {
+	sSegments := make([]byte, 0, SegmentDirectorySize)
+	for _, val := range []uint64{ segments.FirstSegment, segments.LastSegment, segments.ScanOffset, segments.MinVersion } {
+		sSegments = append(sSegments, serializeUint64(val)...)
+	}
+
+	return sSegments
+}
+
+// deserializeSegments
+//	Read a segment directory. The directory of a new file is zeroed, which is a file with only its first segment.
+func deserializeSegments(sSegments []byte) *MMCMapSegments {
+	segments := &MMCMapSegments{}
+	for idx, val := range []*uint64{ &segments.FirstSegment, &segments.LastSegment, &segments.ScanOffset, &segments.MinVersion } {
+		*val, _ = deserializeUint64(sSegments[idx * OffsetSize:(idx + 1) * OffsetSize])
+	}
+
+	return segments
+}
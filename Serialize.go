@@ -2,6 +2,9 @@ package mmcmap
 
 import "encoding/binary"
 import "errors"
+import "fmt"
+
+import "github.com/sirgallo/mmcmap/common/mmap"
 
 
 //============================================= MMCMap Serialization
@@ -46,174 +49,310 @@ func DeserializeMetaData(smeta []byte) (*MMCMapMetaData, error) {
 
 // DeserializeNode
 //	Deserialize a node in the memory memory map. Version, StartOffset, EndOffset, Bitmap, IsLeaf, and KeyLength are at fixed offsets in the nodes.
-//	For Leaf Node, key is found from the start of the key index (31) up to the key index + key length. Value is the key index + key length up to the end of the node.
-//	For Internal Node, the population count is found from the bitmap, and then children offsets are determined from (pop count * 8 bytes for offset).
+//	Leaves are deserialized with DeserializeLNode and internal nodes with DeserializeINode, so snode is bounds checked before it is sliced.
+//	If the LeafOverflowFlag is set, the value is sliced from the overflow extent, which must lie within the memory map.
+//	If the LeafCompressedFlag is set, the value is decompressed, keeping the compressed value so the leaf can be written again as is.
 func (mmcMap *MMCMap) DeserializeNode(snode []byte) (*MMCMapNode, error) {
-	version, decVersionErr := deserializeUint64(snode[NodeVersionIdx:NodeStartOffsetIdx])
-	if decVersionErr != nil { return nil, decVersionErr }
+	if len(snode) <= NodeIsLeafIdx || ! deserializeBoolean(snode[NodeIsLeafIdx]) { return DeserializeINode(snode) }
 
-	startOffset, decStartOffErr := deserializeUint64(snode[NodeStartOffsetIdx:NodeEndOffsetIdx])
-	if decStartOffErr != nil { return nil, decStartOffErr	}
+	node, flags, valueLength, decLeafErr := deserializeLNode(snode)
+	if decLeafErr != nil { return nil, decLeafErr }
 
-	endOffset, decEndOffsetErr := deserializeUint64(snode[NodeEndOffsetIdx:NodeBitmapIdx])
-	if decEndOffsetErr != nil { return nil, decEndOffsetErr }
+	if node.IsOverflow {
+		mMap := mmcMap.Data.Load().(mmap.MMap)
 
-	bitmap, decBitmapErr := deserializeUint32(snode[NodeBitmapIdx:NodeIsLeafIdx])
-	if decBitmapErr != nil { return nil, decBitmapErr }
+		valueStart := node.OverflowOffset + NodeKeyIdx
+		if valueStart < node.OverflowOffset || valueLength > uint64(len(mMap)) || valueStart > uint64(len(mMap)) - valueLength {
+			return nil, corruptNodeErr("overflow extent at %d of %d bytes is outside of the memory map", node.OverflowOffset, valueLength)
+		}
 
-	isLeaf := deserializeBoolean(snode[NodeIsLeafIdx])
+		node.Value = mMap[valueStart:valueStart + valueLength]
+	}
 
-	keyLength, decKeyLenErr := deserializeUint16(snode[NodeKeyLength:NodeKeyIdx])
-	if decKeyLenErr != nil { return nil, decKeyLenErr }
+	decodeErr := node.decodeLeafValue(flags)
+	if decodeErr != nil { return nil, decodeErr }
 
-	node := &MMCMapNode{
-		Version: version,
-		StartOffset: startOffset,
-		EndOffset: endOffset,
-		Bitmap: bitmap,
-		IsLeaf: isLeaf,
-		KeyLength: keyLength,
+	return node, nil
+}
+
+// DeserializeINode
+//	Deserialize a serialized internal node. The population count is found from the bitmap, and then the child offsets are read from the
+//	(pop count * 8 bytes) following the header, into child stubs allocated together by newChildStubs.
+//	snode must hold at least the node, as given by its start and end offsets, and every length is checked before it is sliced, so arbitrary input
+//	returns ErrCorruptNode instead of panicking. Lookups against committed roots do not deserialize internal nodes at all, see lookupLeaf.
+func DeserializeINode(snode []byte) (*MMCMapNode, error) {
+	node, snode, decHeaderErr := deserializeNodeHeader(snode)
+	if decHeaderErr != nil { return nil, decHeaderErr }
+	if node.IsLeaf { return nil, corruptNodeErr("expected an internal node, found a leaf") }
+
+	totalChildren := calculateHammingWeight(node.Bitmap)
+	if len(snode) != NodeChildrenIdx + totalChildren * NodeChildPtrSize {
+		return nil, corruptNodeErr("internal node of %d bytes does not hold the %d children of its bitmap", len(snode), totalChildren)
 	}
 
-	if node.IsLeaf {
-		key := snode[NodeKeyIdx:NodeKeyIdx + node.KeyLength]
-		value := snode[NodeKeyIdx + node.KeyLength:]
-
-		node.Key = key
-		node.Value = value
-	} else {
-		totalChildren := calculateHammingWeight(node.Bitmap)
-		currOffset := NodeChildrenIdx
-
-		for range make([]int, totalChildren) {
-			offset, decChildErr := deserializeUint64(snode[currOffset:currOffset + OffsetSize])
-			if decChildErr != nil { return nil, decChildErr }
-
-			nodePtr := &MMCMapNode{ StartOffset: offset }
-			node.Children = append(node.Children, nodePtr)
-			currOffset += NodeChildPtrSize
-		}
+	node.Children = newChildStubs(totalChildren)
+	for idx, child := range node.Children {
+		childIdx := NodeChildrenIdx + idx * NodeChildPtrSize
+		child.StartOffset = binary.LittleEndian.Uint64(snode[childIdx:childIdx + OffsetSize])
 	}
 
 	return node, nil
 }
 
+// DeserializeLNode
+//	Deserialize a serialized leaf. The key is found from the start of the key index (31) up to the key index + key length, and the value from the
+//	key index + key length up to the end of the node. If the LeafExpiresFlag is set in the bitmap of a leaf, an 8 byte expiration sits between the
+//	key and the value. Compressed values are decompressed. The value of an overflow leaf is stored outside of the node, so only IsOverflow and
+//	OverflowOffset are set and the value is left nil. Like DeserializeINode, arbitrary input returns ErrCorruptNode instead of panicking.
+func DeserializeLNode(snode []byte) (*MMCMapNode, error) {
+	node, flags, _, decLeafErr := deserializeLNode(snode)
+	if decLeafErr != nil { return nil, decLeafErr }
+	if node.IsOverflow { return node, nil }
+
+	decodeErr := node.decodeLeafValue(flags)
+	if decodeErr != nil { return nil, decodeErr }
+
+	return node, nil
+}
+
+// deserializeLNode
+//	Deserialize the key, expiration, and inline value or overflow reference of a leaf, returning the flags stored in its bitmap and the length of its
+//	overflow value.
+func deserializeLNode(snode []byte) (*MMCMapNode, uint32, uint64, error) {
+	node, snode, decHeaderErr := deserializeNodeHeader(snode)
+	if decHeaderErr != nil { return nil, 0, 0, decHeaderErr }
+	if ! node.IsLeaf { return nil, 0, 0, corruptNodeErr("expected a leaf, found an internal node") }
+
+	flags := node.Bitmap
+	if flags &^ (LeafExpiresFlag | LeafOverflowFlag | LeafCompressedFlag) != 0 { return nil, 0, 0, corruptNodeErr("leaf has unknown flags %#x", flags) }
+
+	valueIdx := NodeKeyIdx + int(node.KeyLength)
+	if flags & LeafExpiresFlag != 0 { valueIdx += OffsetSize }
+	if len(snode) < valueIdx { return nil, 0, 0, corruptNodeErr("leaf of %d bytes is too short for its key of %d bytes", len(snode), node.KeyLength) }
+
+	node.Bitmap = 0
+	node.Key = snode[NodeKeyIdx:NodeKeyIdx + int(node.KeyLength)]
+	if flags & LeafExpiresFlag != 0 { node.ExpiresAt = int64(binary.LittleEndian.Uint64(snode[valueIdx - OffsetSize:valueIdx])) }
+
+	if flags & LeafOverflowFlag == 0 {
+		node.Value = snode[valueIdx:]
+		return node, flags, 0, nil
+	}
+
+	if len(snode) != valueIdx + OverflowRefSize { return nil, 0, 0, corruptNodeErr("overflow leaf of %d bytes does not end with its reference", len(snode)) }
+
+	node.IsOverflow = true
+	node.OverflowOffset = binary.LittleEndian.Uint64(snode[valueIdx:valueIdx + OffsetSize])
+	return node, flags, binary.LittleEndian.Uint64(snode[valueIdx + OffsetSize:valueIdx + OverflowRefSize]), nil
+}
+
+// deserializeNodeHeader
+//	Deserialize the fixed width fields at the start of a node, returning the node along with snode trimmed to the bytes between its start and end
+//	offsets. The node must fit within snode, and anything after it is ignored.
+func deserializeNodeHeader(snode []byte) (*MMCMapNode, []byte, error) {
+	if len(snode) < NodeKeyIdx { return nil, nil, corruptNodeErr("node of %d bytes is shorter than its header", len(snode)) }
+
+	node := &MMCMapNode{
+		Version: binary.LittleEndian.Uint64(snode[NodeVersionIdx:NodeStartOffsetIdx]),
+		StartOffset: binary.LittleEndian.Uint64(snode[NodeStartOffsetIdx:NodeEndOffsetIdx]),
+		EndOffset: binary.LittleEndian.Uint64(snode[NodeEndOffsetIdx:NodeBitmapIdx]),
+		Bitmap: binary.LittleEndian.Uint32(snode[NodeBitmapIdx:NodeIsLeafIdx]),
+		KeyLength: binary.LittleEndian.Uint16(snode[NodeKeyLength:NodeKeyIdx]),
+	}
+
+	switch snode[NodeIsLeafIdx] {
+		case serializeBoolean(true):
+			node.IsLeaf = true
+		case serializeBoolean(false):
+		default:
+			return nil, nil, corruptNodeErr("invalid leaf flag %#x", snode[NodeIsLeafIdx])
+	}
+
+	if node.EndOffset < node.StartOffset || node.EndOffset - node.StartOffset >= uint64(len(snode)) {
+		return nil, nil, corruptNodeErr("node from %d to %d does not fit in %d bytes", node.StartOffset, node.EndOffset, len(snode))
+	}
+
+	size := node.EndOffset - node.StartOffset + 1
+	if size < NodeKeyIdx { return nil, nil, corruptNodeErr("node of %d bytes is shorter than its header", size) }
+
+	return node, snode[:size], nil
+}
+
+// decodeLeafValue
+//	Decompress the value of the leaf if the LeafCompressedFlag is set in its flags, keeping the compressed value as the encoded value.
+func (node *MMCMapNode) decodeLeafValue(flags uint32) error {
+	if flags & LeafCompressedFlag == 0 { return nil }
+
+	value, codec, decompressErr := decompressValue(node.Value)
+	if decompressErr != nil { return decompressErr }
+
+	node.Compression = codec
+	node.EncodedValue = node.Value
+	node.Value = value
+
+	return nil
+}
+
+// corruptNodeErr
+//	An ErrCorruptNode describing why a serialized node was rejected.
+func corruptNodeErr(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: " + format, append([]interface{}{ ErrCorruptNode }, args...)...)
+}
+
 // SerializePathToMemMap
 //	Serializes a path copy by starting at the root, getting the latest available offset in the memory map, and recursively serializing.
+//	The path is written into a single pooled buffer sized up front, which should be handed back with releaseSerializeBuffer once it has been copied
+//	into the memory map.
 func (mmcMap *MMCMap) SerializePathToMemMap(root *MMCMapNode, nextOffsetInMMap uint64) ([]byte, error) {
-	serializedPath, serializeErr := mmcMap.serializeRecursive(root, root.Version, 0, nextOffsetInMMap)
-	if serializeErr != nil { return nil, serializeErr }
+	return mmcMap.serializePath(root, nextOffsetInMMap, mmcMap.serializedPathSize(root))
+}
+
+// serializePath
+//	Serialize a path copy of size bytes, as computed by serializedPathSize, starting at offset.
+func (mmcMap *MMCMap) serializePath(root *MMCMapNode, offset, size uint64) ([]byte, error) {
+	serializedPath := getSerializeBuffer(size)
+
+	serializeErr := mmcMap.writePath(serializedPath, root, offset)
+	if serializeErr != nil {
+		releaseSerializeBuffer(serializedPath)
+		return nil, serializeErr
+	}
 
 	return serializedPath, nil
 }
 
 // SerializeRecursive
-//	Traverses the path copy down to the end of the path.
+//	Traverses the path copy down to the end of the path, writing each node into buf, which starts at offset in the memory map.
 //	If the node is a leaf, serialize it and return. If the node is a internal node, serialize each of the children recursively if
 //	the version matches the version of the root. If it is an older version, just serialize the existing offset in the memory map.
-func (mmcMap *MMCMap) serializeRecursive(node *MMCMapNode, version uint64, level int, offset uint64) ([]byte, error) {
+//	Returns the number of bytes written.
+func (mmcMap *MMCMap) serializeRecursive(buf []byte, node *MMCMapNode, version uint64, level int, offset uint64) (uint64, error) {
 	node.StartOffset = offset
 
-	sNode, serializeErr := node.serializeNodeMeta(node.StartOffset)
-	if serializeErr != nil { return nil, serializeErr }
+	if node.IsLeaf {
+		size := node.serializedSize()
+		writeErr := node.writeNode(buf[:size])
+		if writeErr != nil { return 0, writeErr }
 
-	endOffSet, desErr := deserializeUint64(sNode[NodeEndOffsetIdx:NodeBitmapIdx])
-	if desErr != nil { return nil, desErr }
+		mmcMap.NodePool.Put(node)
+		return size, nil
+	}
 
-	switch {
-		case node.IsLeaf:
-			serializedKeyVal, sLeafErr := node.serializeLNode()
-			if sLeafErr != nil { return nil, sLeafErr }
+	endOffset := node.determineEndOffset()
+	node.writeNodeMeta(buf, endOffset)
 
-			mmcMap.NodePool.Put(node)
-			return append(sNode, serializedKeyVal...), nil
-		default:
-			var childrenOnPaths []byte
-			nextStartOffset := endOffSet + 1
-
-			for _, child := range node.Children {
-				if child.Version != version {
-					sNode = append(sNode, serializeUint64(child.StartOffset)...)
-				} else {
-					sNode = append(sNode, serializeUint64(nextStartOffset)...)
-					childrenOnPath, serializeErr := mmcMap.serializeRecursive(child, node.Version, level + 1, nextStartOffset)
-					if serializeErr != nil { return nil, serializeErr }
-
-					nextStartOffset += getSerializedNodeSize(childrenOnPath)
-					childrenOnPaths = append(childrenOnPaths, childrenOnPath...)
-				}
-			}
-
-			mmcMap.NodePool.Put(node)
-			return append(sNode, childrenOnPaths...), nil
+	written := endOffset - offset + 1
+	for idx, child := range node.Children {
+		childOffset := child.StartOffset
+		if child.Version == version {
+			childOffset = offset + written
+
+			childSize, serializeErr := mmcMap.serializeRecursive(buf[written:], child, node.Version, level + 1, childOffset)
+			if serializeErr != nil { return 0, serializeErr }
+
+			written += childSize
+		}
+
+		childIdx := NodeChildrenIdx + idx * NodeChildPtrSize
+		binary.LittleEndian.PutUint64(buf[childIdx:childIdx + OffsetSize], childOffset)
 	}
+
+	mmcMap.NodePool.Put(node)
+	return written, nil
 }
 
 // SerializeNode
-//	First serialize the node metadata. If the node is a leaf node, serialize the key and value.
+//	Serialize the node into a new buffer. If the node is a leaf node, serialize the key and value.
 //	Otherwise, serialize the child offsets within the internal node.
 func (node *MMCMapNode) SerializeNode(offset uint64) ([]byte, error) {
-	sNode, serializeErr := node.serializeNodeMeta(offset)
-	if serializeErr != nil { return nil, serializeErr }
+	sNode := make([]byte, node.serializedSize())
 
-	switch {
-		case node.IsLeaf:
-			serializedKeyVal, sLeafErr := node.serializeLNode()
-			if sLeafErr != nil { return nil, sLeafErr }
+	writeErr := node.writeNode(sNode)
+	if writeErr != nil { return nil, writeErr }
 
-			return append(sNode, serializedKeyVal...), nil
-		default:
-			serializedChildren, sInternalErr := node.serializeINode()
-			if sInternalErr != nil { return nil, sInternalErr }
+	return sNode, nil
+}
 
-			return append(sNode, serializedChildren...), nil
-	}
+// serializedSize
+//	The number of bytes the node serializes to, including the overflow extent of a leaf if it has not been written yet.
+func (node *MMCMapNode) serializedSize() uint64 {
+	size := node.determineEndOffset() - node.StartOffset + 1
+	if node.IsLeaf && node.IsOverflow && node.OverflowOffset == 0 { size += node.extentSize() }
+
+	return size
 }
 
-// SerializeNodeMeta
-//	Serialize the meta data for the node. These are values at fixed offsets within the MMCMapNode.
-func (node *MMCMapNode) serializeNodeMeta(offset uint64) ([]byte, error) {
-	var baseNode []byte
+// writeNode
+//	Serialize the node into buf, which must hold serializedSize bytes. Internal nodes write the current offsets of their children.
+//	Keys longer than MaxKeyLength return ErrKeyTooLarge, since their length would be truncated to the 2 bytes of KeyLength.
+func (node *MMCMapNode) writeNode(buf []byte) error {
+	if node.IsLeaf && len(node.Key) > MaxKeyLength { return ErrKeyTooLarge }
 
-	endOffset := node.determineEndOffset()
+	node.writeNodeMeta(buf, node.determineEndOffset())
+	if node.IsLeaf { return node.writeLNode(buf[NodeKeyIdx:]) }
 
-	sVersion := serializeUint64(node.Version)
-	sStartOffset := serializeUint64(node.StartOffset)
-	sEndOffset := serializeUint64(endOffset)
-	sBitmap := serializeUint32(node.Bitmap)
-	sIsLeaf := serializeBoolean(node.IsLeaf)
-	sKeyLength := serializeUint16(node.KeyLength)
-
-	baseNode = append(baseNode, sVersion...)
-	baseNode = append(baseNode, sStartOffset...)
-	baseNode = append(baseNode, sEndOffset...)
-	baseNode = append(baseNode, sBitmap...)
-	baseNode = append(baseNode, sIsLeaf)
-	baseNode = append(baseNode, sKeyLength...)
-
-	return baseNode, nil
-}
+	for idx, child := range node.Children {
+		childIdx := NodeChildrenIdx + idx * NodeChildPtrSize
+		binary.LittleEndian.PutUint64(buf[childIdx:childIdx + OffsetSize], child.StartOffset)
+	}
 
-// SerializeLNode
-//	Serialize a leaf node in the mmcmap. Append the key and value together since both are already byte slices.
-func (node *MMCMapNode) serializeLNode() ([]byte, error) {
-	var sLNode []byte
-	sLNode = append(sLNode, node.Key...)
-	sLNode = append(sLNode, node.Value...)
+	return nil
+}
 
-	return sLNode, nil
+// writeNodeMeta
+//	Write the meta data for the node into the start of buf. These are values at fixed offsets within the MMCMapNode.
+//	Leaves store their flags in place of the bitmap.
+func (node *MMCMapNode) writeNodeMeta(buf []byte, endOffset uint64) {
+	bitmap := node.Bitmap
+	if node.IsLeaf { bitmap = node.leafFlags() }
+
+	binary.LittleEndian.PutUint64(buf[NodeVersionIdx:NodeStartOffsetIdx], node.Version)
+	binary.LittleEndian.PutUint64(buf[NodeStartOffsetIdx:NodeEndOffsetIdx], node.StartOffset)
+	binary.LittleEndian.PutUint64(buf[NodeEndOffsetIdx:NodeBitmapIdx], endOffset)
+	binary.LittleEndian.PutUint32(buf[NodeBitmapIdx:NodeIsLeafIdx], bitmap)
+	buf[NodeIsLeafIdx] = serializeBoolean(node.IsLeaf)
+	binary.LittleEndian.PutUint16(buf[NodeKeyLength:NodeKeyIdx], node.KeyLength)
 }
 
-// SerializeINode
-//	Serialize an internal node in the mmcmap. This involves scanning the children nodes and serializing the offset in the memory map for each one.
-func (node *MMCMapNode) serializeINode() ([]byte, error) {
-	var sINode []byte
-	for _, cnode := range node.Children {
-		snode := serializeUint64(cnode.StartOffset)
-		sINode = append(sINode, snode...)
+// writeLNode
+//	Write the key and value of a leaf into buf, which starts at the key index of the leaf.
+//	Leaves with an expiration place it between the key and the value. Compressed leaves store the compressed value in place of the value.
+//	Overflow leaves store the offset and length of their extent in place of the value. If the extent has not been written yet, it is serialized
+//	directly after the leaf, so the leaf and its extent are always written together. The extent is laid out as a leaf with an empty key, tagged with
+//	the version of the leaf, so scans over the node headers in the memory map step over it like any other leaf.
+func (node *MMCMapNode) writeLNode(buf []byte) error {
+	idx := copy(buf, node.Key)
+	if node.ExpiresAt != 0 {
+		binary.LittleEndian.PutUint64(buf[idx:idx + OffsetSize], uint64(node.ExpiresAt))
+		idx += OffsetSize
+	}
+
+	storedValue := node.storedValue()
+	if ! node.IsOverflow {
+		copy(buf[idx:], storedValue)
+		return nil
 	}
 
-	return sINode, nil
+	isExtentPending := node.OverflowOffset == 0
+	if isExtentPending { node.OverflowOffset = node.determineEndOffset() + 1 }
+
+	binary.LittleEndian.PutUint64(buf[idx:idx + OffsetSize], node.OverflowOffset)
+	binary.LittleEndian.PutUint64(buf[idx + OffsetSize:idx + OverflowRefSize], uint64(len(storedValue)))
+	if ! isExtentPending { return nil }
+
+	extent := &MMCMapNode{ Version: node.Version, StartOffset: node.OverflowOffset, IsLeaf: true, Value: storedValue }
+	return extent.writeNode(buf[idx + OverflowRefSize:])
+}
+
+// leafFlags
+//	The flags stored in the otherwise unused bitmap of a serialized leaf.
+func (node *MMCMapNode) leafFlags() uint32 {
+	var flags uint32
+	if node.ExpiresAt != 0 { flags |= LeafExpiresFlag }
+	if node.IsOverflow { flags |= LeafOverflowFlag }
+	if len(node.storedValue()) > 0 && node.Compression != CompressionNone { flags |= LeafCompressedFlag }
+
+	return flags
 }
 
 
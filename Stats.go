@@ -0,0 +1,195 @@
+package mmcmap
+
+import "expvar"
+import "fmt"
+import "io"
+import "strings"
+import "sync/atomic"
+
+
+//============================================= MMCMap Stats
+
+
+// prometheusLabelEscaper escapes a label value for the Prometheus text format
+var prometheusLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// statsMetric is a single value written by WritePrometheus
+type statsMetric struct {
+	name string
+	kind string
+	help string
+	value float64
+	labels string
+}
+
+
+// Stats
+//	A snapshot of the operation counters and the size of the map, for monitoring.
+//	The dead bytes are measured by traversing the latest version, which is only repeated once a new version has been committed.
+func (mmcMap *MMCMap) Stats() (MMCMapStats, error) {
+	liveKeys, lenErr := mmcMap.Len()
+	if lenErr != nil { return MMCMapStats{}, lenErr }
+
+	dataBytes, deadBytes, deadErr := mmcMap.deadBytes()
+	if deadErr != nil { return MMCMapStats{}, deadErr }
+
+	fileSize, sizeErr := mmcMap.FileSize()
+	if sizeErr != nil { return MMCMapStats{}, sizeErr }
+
+	poolHits := atomic.LoadUint64(&mmcMap.NodePool.Hits)
+	poolMisses := atomic.LoadUint64(&mmcMap.NodePool.Misses)
+	cacheStats := mmcMap.NodeCacheStats()
+
+	return MMCMapStats{
+		Puts: atomic.LoadUint64(&mmcMap.Puts),
+		Gets: atomic.LoadUint64(&mmcMap.Gets),
+		Deletes: atomic.LoadUint64(&mmcMap.Deletes),
+		CommitRetries: atomic.LoadUint64(&mmcMap.CommitRetries),
+		CommitRebases: atomic.LoadUint64(&mmcMap.CommitRebases),
+		ShardWaits: atomic.LoadUint64(&mmcMap.ShardWaits),
+		LockedReads: atomic.LoadUint64(&mmcMap.LockedReads),
+		Resizes: atomic.LoadUint64(&mmcMap.Resizes),
+		Flushes: atomic.LoadUint64(&mmcMap.Flushes),
+		Prefetches: atomic.LoadUint64(&mmcMap.Prefetches),
+		FileSize: uint64(fileSize),
+		LiveKeys: liveKeys,
+		DataBytes: dataBytes,
+		DeadBytes: deadBytes,
+		NodePoolHits: poolHits,
+		NodePoolMisses: poolMisses,
+		NodePoolHitRate: hitRate(poolHits, poolMisses),
+		NodePoolSize: atomic.LoadInt64(&mmcMap.NodePool.MaxSize),
+		NodePoolGrows: atomic.LoadUint64(&mmcMap.NodePool.Grows),
+		NodePoolShrinks: atomic.LoadUint64(&mmcMap.NodePool.Shrinks),
+		NodeCache: cacheStats,
+		NodeCacheHitRate: hitRate(cacheStats.Hits, cacheStats.Misses),
+		Latency: mmcMap.LatencyStats(),
+	}, nil
+}
+
+// Expvar
+//	An expvar.Var that reports Stats as JSON each time it is read, to be published with expvar.Publish under a name unique to the map.
+func (mmcMap *MMCMap) Expvar() expvar.Var {
+	return expvar.Func(func() interface{} {
+		stats, statsErr := mmcMap.Stats()
+		if statsErr != nil { return map[string]string{ "error": statsErr.Error() } }
+		return stats
+	})
+}
+
+// WritePrometheus
+//	Write Stats to w in the Prometheus text exposition format, labelled with the name of the map, so it can be served to a Prometheus scraper
+//	without depending on the Prometheus client.
+func (mmcMap *MMCMap) WritePrometheus(w io.Writer) error {
+	stats, statsErr := mmcMap.Stats()
+	if statsErr != nil { return statsErr }
+
+	metrics := []statsMetric{
+		{ name: "mmcmap_puts_total", kind: "counter", help: "The number of puts committed.", value: float64(stats.Puts) },
+		{ name: "mmcmap_gets_total", kind: "counter", help: "The number of keys looked up.", value: float64(stats.Gets) },
+		{ name: "mmcmap_deletes_total", kind: "counter", help: "The number of keys removed by committed deletes.", value: float64(stats.Deletes) },
+		{ name: "mmcmap_commit_retries_total", kind: "counter", help: "The number of commit attempts that had to be retried.", value: float64(stats.CommitRetries) },
+		{ name: "mmcmap_commit_rebases_total", kind: "counter", help: "The number of commit attempts rebased onto a newer version instead of retried.", value: float64(stats.CommitRebases) },
+		{ name: "mmcmap_shard_waits_total", kind: "counter", help: "The number of commits that waited for the lane of their write shard.", value: float64(stats.ShardWaits) },
+		{ name: "mmcmap_locked_reads_total", kind: "counter", help: "The number of reads that took the resize read lock because the resize lock was held exclusively.", value: float64(stats.LockedReads) },
+		{ name: "mmcmap_resizes_total", kind: "counter", help: "The number of times the memory map was grown.", value: float64(stats.Resizes) },
+		{ name: "mmcmap_flushes_total", kind: "counter", help: "The number of completed flushes to disk.", value: float64(stats.Flushes) },
+		{ name: "mmcmap_prefetches_total", kind: "counter", help: "The number of read ahead hints issued by scans.", value: float64(stats.Prefetches) },
+		{ name: "mmcmap_file_size_bytes", kind: "gauge", help: "The size of the file.", value: float64(stats.FileSize) },
+		{ name: "mmcmap_live_keys", kind: "gauge", help: "The number of keys in the latest version.", value: float64(stats.LiveKeys) },
+		{ name: "mmcmap_data_bytes", kind: "gauge", help: "The bytes of serialized data written after the header.", value: float64(stats.DataBytes) },
+		{ name: "mmcmap_dead_bytes", kind: "gauge", help: "The bytes of serialized data only reachable from prior versions.", value: float64(stats.DeadBytes) },
+		{ name: "mmcmap_node_pool_hits_total", kind: "counter", help: "The number of nodes taken from the node pool.", value: float64(stats.NodePoolHits) },
+		{ name: "mmcmap_node_pool_misses_total", kind: "counter", help: "The number of nodes requested while the node pool was empty.", value: float64(stats.NodePoolMisses) },
+		{ name: "mmcmap_node_pool_size", kind: "gauge", help: "The most nodes the node pool currently holds.", value: float64(stats.NodePoolSize) },
+		{ name: "mmcmap_node_pool_grows_total", kind: "counter", help: "The number of times the adaptive node pool grew.", value: float64(stats.NodePoolGrows) },
+		{ name: "mmcmap_node_pool_shrinks_total", kind: "counter", help: "The number of times the adaptive node pool shrank.", value: float64(stats.NodePoolShrinks) },
+		{ name: "mmcmap_node_cache_hits_total", kind: "counter", help: "The number of node reads served from the node cache.", value: float64(stats.NodeCache.Hits) },
+		{ name: "mmcmap_node_cache_misses_total", kind: "counter", help: "The number of node reads deserialized from the memory map.", value: float64(stats.NodeCache.Misses) },
+		{ name: "mmcmap_node_cache_bytes", kind: "gauge", help: "The approximate bytes of nodes in the node cache.", value: float64(stats.NodeCache.Bytes) },
+	}
+
+	if mmcMap.Opts.RecordLatency {
+		ops := []struct { name string; latency OpLatency }{
+			{ "put", stats.Latency.Put }, { "get", stats.Latency.Get }, { "delete", stats.Latency.Delete }, { "range", stats.Latency.Range },
+		}
+
+		latencyHelp := "The estimated latency of the operation, in seconds."
+		for _, op := range ops {
+			opLabel := ",op=\"" + op.name + "\""
+			metrics = append(metrics,
+				statsMetric{ name: "mmcmap_op_latency_seconds", kind: "summary", help: latencyHelp, value: op.latency.P50.Seconds(), labels: opLabel + ",quantile=\"0.5\"" },
+				statsMetric{ name: "mmcmap_op_latency_seconds", kind: "summary", help: latencyHelp, value: op.latency.P99.Seconds(), labels: opLabel + ",quantile=\"0.99\"" },
+			)
+		}
+
+		for _, op := range ops {
+			metrics = append(metrics, statsMetric{ name: "mmcmap_op_latency_seconds_sum", value: op.latency.Total.Seconds(), labels: ",op=\"" + op.name + "\"" })
+		}
+
+		for _, op := range ops {
+			metrics = append(metrics, statsMetric{ name: "mmcmap_op_latency_seconds_count", value: float64(op.latency.Count), labels: ",op=\"" + op.name + "\"" })
+		}
+
+		for _, op := range ops {
+			metrics = append(metrics, statsMetric{
+				name: "mmcmap_op_retries_total", kind: "counter", help: "The number of commit attempts the operation retried.", value: float64(op.latency.Retries), labels: ",op=\"" + op.name + "\"",
+			})
+		}
+	}
+
+	label := prometheusLabelEscaper.Replace(mmcMap.Opts.Name)
+	var lastName string
+	for _, metric := range metrics {
+		if metric.help != "" && metric.name != lastName {
+			_, writeErr := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", metric.name, metric.help, metric.name, metric.kind)
+			if writeErr != nil { return writeErr }
+		}
+
+		lastName = metric.name
+
+		_, writeErr := fmt.Fprintf(w, "%s{map=\"%s\"%s} %v\n", metric.name, label, metric.labels, metric.value)
+		if writeErr != nil { return writeErr }
+	}
+
+	return nil
+}
+
+// deadBytes
+//	The bytes of serialized data after the header, or after the last reclaimed segment, and how many of them are not reachable from the latest root.
+//	The reachable bytes are remembered along with the version they were measured at, so the trie is only traversed again once it changes.
+func (mmcMap *MMCMap) deadBytes() (uint64, uint64, error) {
+	mmcMap.waitForRemap()
+
+	mmcMap.RWResizeLock.RLock()
+	defer mmcMap.RWResizeLock.RUnlock()
+
+	handleErr := mmcMap.checkHandle()
+	if handleErr != nil { return 0, 0, handleErr }
+
+	meta, readMetaErr := mmcMap.ReadMetaFromMemMap()
+	if readMetaErr != nil { return 0, 0, readMetaErr }
+
+	mmcMap.StatsLock.Lock()
+	defer mmcMap.StatsLock.Unlock()
+
+	if mmcMap.StatsVersion != meta.Version || mmcMap.StatsLiveBytes == 0 {
+		liveSize, liveErr := mmcMap.liveBytes(meta.RootOffset)
+		if liveErr != nil { return 0, 0, liveErr }
+
+		mmcMap.StatsVersion, mmcMap.StatsLiveBytes = meta.Version, liveSize
+	}
+
+	dataSize := meta.EndMmapOffset - mmcMap.scanOffset()
+	if mmcMap.StatsLiveBytes > dataSize { return dataSize, 0, nil }
+
+	return dataSize, dataSize - mmcMap.StatsLiveBytes, nil
+}
+
+// hitRate
+//	The fraction of lookups that were hits, 0 if there were none.
+func hitRate(hits, misses uint64) float64 {
+	if hits + misses == 0 { return 0 }
+	return float64(hits) / float64(hits + misses)
+}
+
@@ -0,0 +1,227 @@
+package mmcmap
+
+import "bytes"
+import "fmt"
+import "io"
+import "sync/atomic"
+import "unsafe"
+
+import "github.com/sirgallo/mmcmap/common/mmap"
+
+
+//============================================= MMCMap Value Streaming
+
+
+// GetReader
+//	Open a reader over the value of a key, pinned to the latest version, that copies the value out of the memory map as it is read instead of all
+//	at once. A nil reader is returned if the key does not exist, or ErrKeyNotFound with StrictGet, the same as Get.
+//	The reader holds a relocate pin until it is closed, since Compact and Reclaim would otherwise move or reuse the leaf it reads from. It must be
+//	closed, otherwise any Compact or Reclaim waits for it, though commits and reads, including those of the reader's owner, continue in the meantime.
+func (mmcMap *MMCMap) GetReader(key []byte) (io.ReadCloser, error) {
+	atomic.AddUint64(&mmcMap.Gets, 1)
+	mmcMap.pinRelocation()
+
+	reader, openErr := mmcMap.openValueReader(key)
+	if reader == nil || openErr != nil {
+		mmcMap.unpinRelocation()
+		if openErr != nil { return nil, openErr }
+		if mmcMap.Opts.StrictGet { return nil, ErrKeyNotFound }
+		return nil, nil
+	}
+
+	return reader, nil
+}
+
+// PutReader
+//	Put a value of size bytes read from r, streaming it directly into the memory map instead of building the value in memory first.
+//	The leaf is appended to the end of the memory map and the value is read into the mapped buffer behind it, then the path to the key is committed
+//	referencing the leaf, so the value is never part of the serialized path. If r returns fewer than size bytes, nothing is committed.
+//	Commits are blocked while the value is read, so r should not be slow. Change events for the put are published with a nil value.
+//	Files with the compact node encoding return ErrNodeEncodingUnsupported, since the leaf is written with the fixed layout.
+func (mmcMap *MMCMap) PutReader(key []byte, r io.Reader, size int64) (bool, error) {
+	if size < 0 { return false, ErrInvalidValueSize }
+	sizeErr := mmcMap.checkPutSize(key, size)
+	if sizeErr != nil { return false, sizeErr }
+	if mmcMap.isFollower() { return false, ErrFollowerReadOnly }
+	if mmcMap.isCompactEncoding() { return false, ErrNodeEncodingUnsupported }
+
+	stallErr := mmcMap.awaitFlush()
+	if stallErr != nil { return false, stallErr }
+
+	mmcMap.CommitGate.Lock()
+	defer mmcMap.CommitGate.Unlock()
+
+	offset, streamErr := mmcMap.streamLeaf(key, r, size)
+	if streamErr != nil { return false, streamErr }
+
+	return mmcMap.commitWithGateHeld(func(root *MMCMapNode) ([]*BatchOp, error) {
+		return []*BatchOp{{ Key: key, leafOffset: offset }}, nil
+	})
+}
+
+// Read
+//	Copy the next bytes of the value into p, returning io.EOF once the whole value has been read.
+func (reader *ValueReader) Read(p []byte) (int, error) {
+	if reader.isClosed { return 0, ErrReaderClosed }
+	if reader.remaining == 0 { return 0, io.EOF }
+
+	if reader.decompressed != nil {
+		n := copy(p, reader.decompressed[uint64(reader.size) - reader.remaining:])
+		reader.remaining -= uint64(n)
+		return n, nil
+	}
+
+	mmcMap := reader.mmcMap
+	mmcMap.waitForRemap()
+
+	mmcMap.RWResizeLock.RLock()
+	defer mmcMap.RWResizeLock.RUnlock()
+
+	handleErr := mmcMap.checkHandle()
+	if handleErr != nil { return 0, handleErr }
+
+	length := uint64(len(p))
+	if length > reader.remaining { length = reader.remaining }
+
+	mMap := mmcMap.Data.Load().(mmap.MMap)
+	n := copy(p, mMap[reader.offset:reader.offset + length])
+
+	reader.offset += uint64(n)
+	reader.remaining -= uint64(n)
+
+	return n, nil
+}
+
+// Size
+//	The length of the value.
+func (reader *ValueReader) Size() int64 {
+	return reader.size
+}
+
+// Close
+//	Release the pin on the map. Closing more than once has no effect.
+func (reader *ValueReader) Close() error {
+	if reader.isClosed { return nil }
+
+	reader.isClosed = true
+	reader.mmcMap.unpinRelocation()
+
+	return nil
+}
+
+// openValueReader
+//	Locate the leaf for key at the latest version and create a reader over its value, or nil if the key does not exist or has expired.
+//	Only the offset of the value is kept, so the reader does not need the value to stay mapped at the same address. Compressed values can not be read
+//	from the memory map in place, so the reader keeps the value decompressed when the leaf was read instead. Leaves in the compact encoding do not
+//	place their value at a fixed offset, so the reader keeps a copy of the value.
+func (mmcMap *MMCMap) openValueReader(key []byte) (*ValueReader, error) {
+	mmcMap.waitForRemap()
+
+	mmcMap.RWResizeLock.RLock()
+	defer mmcMap.RWResizeLock.RUnlock()
+
+	handleErr := mmcMap.checkHandle()
+	if handleErr != nil { return nil, handleErr }
+
+	_, rootOffset, loadROffErr := mmcMap.loadMetaRootOffset()
+	if loadROffErr != nil { return nil, loadROffErr }
+
+	leaf, getErr := mmcMap.lookupLeaf(rootOffset, key)
+	if getErr != nil || leaf == nil || leaf.isExpired() { return nil, getErr }
+
+	size := uint64(len(leaf.Value))
+	if mmcMap.isCompactEncoding() && leaf.Compression == CompressionNone { leaf.Value = append([]byte{}, leaf.Value...) }
+	if mmcMap.isCompactEncoding() || leaf.Compression != CompressionNone { return &ValueReader{ mmcMap: mmcMap, remaining: size, size: int64(size), decompressed: leaf.Value }, nil }
+
+	return &ValueReader{ mmcMap: mmcMap, offset: leaf.valueOffset(), remaining: size, size: int64(size) }, nil
+}
+
+// streamLeaf
+//	Append a leaf for key to the memory map with its value read from r, retrying once the memory map has been resized if the leaf does not fit.
+//	Returns the offset of the leaf. The commit gate must be held exclusively.
+func (mmcMap *MMCMap) streamLeaf(key []byte, r io.Reader, size int64) (uint64, error) {
+	for {
+		offset, streamErr := mmcMap.tryStreamLeaf(key, r, size)
+		if streamErr != ErrResizeInProgress { return offset, streamErr }
+	}
+}
+
+// tryStreamLeaf
+//	A single attempt at appending the leaf. The header and key are written one byte past the end of the serialized data, as a path would be, and the
+//	value is read from r directly into the mapped buffer after them. The leaf is tagged with the next version, since with the commit gate held the
+//	commit referencing it is the next one. The end of the serialized data is only moved past the leaf once the whole value has been read, so a failed
+//	read leaves nothing behind for the commit after it to trip over.
+func (mmcMap *MMCMap) tryStreamLeaf(key []byte, r io.Reader, size int64) (uint64, error) {
+	mmcMap.waitForResize()
+
+	mmcMap.RWResizeLock.RLock()
+	defer mmcMap.RWResizeLock.RUnlock()
+
+	handleErr := mmcMap.checkHandle()
+	if handleErr != nil { return 0, handleErr }
+
+	_, version, loadVErr := mmcMap.loadMetaVersion()
+	if loadVErr != nil { return 0, loadVErr }
+
+	endOffsetPtr, endOffset, loadSOffErr := mmcMap.loadMetaEndSerialized()
+	if loadSOffErr != nil { return 0, loadSOffErr }
+
+	leaf := &MMCMapNode{ Version: version + 1, IsLeaf: true, KeyLength: uint16(len(key)), Key: key }
+	leaf.StartOffset = mmcMap.Allocator.Place(endOffset)
+
+	headerSize := uint64(NodeKeyIdx) + uint64(len(key))
+	leafSize := headerSize + uint64(size)
+	newEndOffset := mmcMap.Allocator.End(endOffset, leaf.StartOffset, leafSize)
+
+	fileSizeErr := mmcMap.checkFileSize(newEndOffset)
+	if fileSizeErr != nil { return 0, fileSizeErr }
+
+	isResize := mmcMap.determineIfResize(newEndOffset)
+	if isResize { return 0, ErrResizeInProgress }
+
+	// the leaf is serialized without its value, so the end offset has to account for it
+	mMap := mmcMap.Data.Load().(mmap.MMap)
+	leaf.writeNodeMeta(mMap[leaf.StartOffset:leaf.StartOffset + NodeKeyIdx], leaf.StartOffset + leafSize - 1)
+	copy(mMap[leaf.StartOffset + NodeKeyIdx:leaf.StartOffset + headerSize], key)
+
+	_, readErr := io.ReadFull(r, mMap[leaf.StartOffset + headerSize:leaf.StartOffset + leafSize])
+	if readErr != nil { return 0, readErr }
+
+	mmcMap.storeMetaPointer(endOffsetPtr, newEndOffset)
+	atomic.AddUint64(&mmcMap.UnflushedBytes, leafSize)
+
+	return leaf.StartOffset, nil
+}
+
+// referenceLeaf
+//	Point the leaf for key in a path copy at the leaf streamed to offset. The leaf must have been put in the same path copy.
+//	The leaf is given a version other than the version of the path, so it is serialized as a reference to offset instead of being written again.
+//	Returns ErrCorruptNode if no leaf for key is found within the deepest level the key can reach.
+func (mmcMap *MMCMap) referenceLeaf(node *unsafe.Pointer, key []byte, offset uint64) error {
+	currNode := loadNodeFromPointer(node)
+
+	for level := 0; level <= mmcMap.maxKeyLevel(key); level++ {
+		var child *MMCMapNode
+		if mmcMap.isCollisionLevel(level) {
+			for _, bucketChild := range currNode.Children {
+				if bucketChild.IsLeaf && bytes.Equal(bucketChild.Key, key) { child = bucketChild }
+			}
+		} else {
+			index := mmcMap.getKeyIndex(key, level)
+			if IsBitSet(currNode.Bitmap, index) { child = currNode.Children[childPosition(currNode.Bitmap, index)] }
+		}
+
+		if child == nil { break }
+
+		if child.IsLeaf {
+			child.StartOffset = offset
+			child.Version = 0
+			child.Value = nil
+			return nil
+		}
+
+		currNode = child
+	}
+
+	return fmt.Errorf("%w: no leaf for the streamed key in the path copy", ErrCorruptNode)
+}
@@ -0,0 +1,20 @@
+package mmcmap
+
+
+//============================================= MMCMap Tracing
+
+
+// startSpan
+//	Start a span with the tracer of the map, or a span that does nothing if no Tracer is set, so phases are traced without checking for one.
+func (mmcMap *MMCMap) startSpan(parent Span, name string) Span {
+	if mmcMap.Opts.Tracer == nil { return noopSpan{} }
+	return mmcMap.Opts.Tracer.Start(parent, name)
+}
+
+// SetAttribute
+//	Discard the attribute.
+func (span noopSpan) SetAttribute(key string, value interface{}) {}
+
+// End
+//	Nothing to finish.
+func (span noopSpan) End(err error) {}
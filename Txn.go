@@ -0,0 +1,136 @@
+package mmcmap
+
+import "bytes"
+import "sync/atomic"
+
+
+//============================================= MMCMap Transactions
+
+
+// BeginTxn
+//	Begin a transaction at the latest version of the mmcmap.
+//	The transaction reads from the root at that version by its offset, so once a Compact, Reclaim, or truncating Clear moves or reuses the nodes of the
+//	root, reads from the snapshot return ErrVersionUnavailable instead of whatever is now at the offset.
+func (mmcMap *MMCMap) BeginTxn() (*Txn, error) {
+	mmcMap.RelocateLock.RLock()
+	defer mmcMap.RelocateLock.RUnlock()
+
+	rootOffset, loadROffErr := mmcMap.loadRootOffsetForRead()
+	if loadROffErr != nil { return nil, loadROffErr }
+
+	return &Txn{
+		Batch: mmcMap.NewWriteBatch(),
+		RootOffset: rootOffset,
+		Generation: mmcMap.relocateGeneration(),
+		ReadSet: make(map[string][]byte),
+	}, nil
+}
+
+// Put
+//	Buffer a put of the key-value pair in the transaction.
+func (txn *Txn) Put(key, value []byte) error {
+	if txn.IsDone { return ErrTxnDone }
+
+	txn.Batch.Put(key, value)
+	return nil
+}
+
+// Delete
+//	Buffer a delete of the key in the transaction.
+func (txn *Txn) Delete(key []byte) error {
+	if txn.IsDone { return ErrTxnDone }
+
+	txn.Batch.Delete(key)
+	return nil
+}
+
+// Get
+//	Read the value for a key within the transaction.
+//	Keys mutated earlier in the transaction are served from its overlay. All other keys are read from the snapshot the transaction began at,
+//	and the observed value is recorded so the commit can detect if it changed in the meantime. Returns ErrVersionUnavailable if the snapshot was
+//	relocated since the transaction began.
+func (txn *Txn) Get(key []byte) ([]byte, error) {
+	if txn.IsDone { return nil, ErrTxnDone }
+
+	op, ok := txn.Batch.Overlay[string(key)]
+	if ok {
+		if op.IsDelete { return nil, nil }
+		return op.Value, nil
+	}
+
+	value, getErr := txn.getFromSnapshot(key)
+	if getErr != nil { return nil, getErr }
+
+	_, isRead := txn.ReadSet[string(key)]
+	if ! isRead { txn.ReadSet[string(key)] = value }
+
+	return value, nil
+}
+
+// Commit
+//	Validate the read set and commit every buffered mutation as a single new version with one root swap.
+//	If any key read by the transaction no longer holds the observed value at the latest version, nothing is written and ErrTxnConflict is returned.
+//	The transaction cannot be used after Commit returns.
+func (txn *Txn) Commit() error {
+	if txn.IsDone { return ErrTxnDone }
+	txn.IsDone = true
+
+	mmcMap := txn.Batch.Map
+	_, commitErr := mmcMap.commitWith(func(root *MMCMapNode) ([]*BatchOp, error) {
+		for key, observed := range txn.ReadSet {
+			current, getErr := mmcMap.getFromRoot(root, []byte(key))
+			if getErr != nil { return nil, getErr }
+			if ! bytes.Equal(current, observed) || (current == nil) != (observed == nil) { return nil, ErrTxnConflict }
+		}
+
+		return txn.Batch.Ops, nil
+	})
+
+	txn.Batch.Reset()
+	return commitErr
+}
+
+// Rollback
+//	Discard every buffered mutation. The transaction cannot be used after Rollback returns.
+func (txn *Txn) Rollback() {
+	txn.IsDone = true
+	txn.Batch.Reset()
+}
+
+// getFromSnapshot
+//	Read the value for a key from the root the transaction began at, under the relocate read lock so the root can not be relocated during the read.
+func (txn *Txn) getFromSnapshot(key []byte) ([]byte, error) {
+	mmcMap := txn.Batch.Map
+
+	mmcMap.RelocateLock.RLock()
+	defer mmcMap.RelocateLock.RUnlock()
+
+	return mmcMap.getAtRootOffset(txn.RootOffset, txn.Generation, key)
+}
+
+// getAtRootOffset
+//	Retrieve the value for a key from the root at the given offset, copying the value out of the memory map.
+//	The offset is only valid for the relocate generation it was loaded at, so ErrVersionUnavailable is returned once the generation has moved on
+//	instead of following the offset into whatever now occupies it. Callers hold the relocate read lock, so it can not move on during the read.
+func (mmcMap *MMCMap) getAtRootOffset(rootOffset, generation uint64, key []byte) ([]byte, error) {
+	if mmcMap.relocateGeneration() != generation { return nil, ErrVersionUnavailable }
+
+	mmcMap.waitForRemap()
+
+	mmcMap.RWResizeLock.RLock()
+	defer mmcMap.RWResizeLock.RUnlock()
+
+	handleErr := mmcMap.checkHandle()
+	if handleErr != nil { return nil, handleErr }
+
+	value, getErr := mmcMap.lookupValue(rootOffset, key)
+	if getErr != nil || value == nil { return nil, getErr }
+
+	return append([]byte{}, value...), nil
+}
+
+// relocateGeneration
+//	Load the current relocate generation. Offsets loaded while the relocate read lock is held are valid for the generation loaded under the same lock.
+func (mmcMap *MMCMap) relocateGeneration() uint64 {
+	return atomic.LoadUint64(&mmcMap.RelocateGeneration)
+}
@@ -0,0 +1,153 @@
+package mmcmap
+
+import "encoding/binary"
+import "encoding/json"
+import "errors"
+
+import "google.golang.org/protobuf/proto"
+
+
+//============================================= MMCMap Typed
+
+
+// NewTyped
+//	Wrap the mmcmap with codecs for its keys and values.
+func NewTyped[K, V any](mmcMap *MMCMap, keyCodec Codec[K], valueCodec Codec[V]) *Typed[K, V] {
+	return &Typed[K, V]{ Map: mmcMap, KeyCodec: keyCodec, ValueCodec: valueCodec }
+}
+
+// Put
+//	Encode the key and value and put them in the wrapped mmcmap.
+func (typed *Typed[K, V]) Put(key K, value V) (bool, error) {
+	sKey, encodeKeyErr := typed.KeyCodec.Encode(key)
+	if encodeKeyErr != nil { return false, encodeKeyErr }
+
+	sValue, encodeValueErr := typed.ValueCodec.Encode(value)
+	if encodeValueErr != nil { return false, encodeValueErr }
+
+	return typed.Map.Put(sKey, sValue)
+}
+
+// Get
+//	Get the decoded value for key, along with whether the key exists, since the zero value of V may be a valid value.
+//	Missing keys return the zero value of V and false, whether or not the wrapped mmcmap was opened with StrictGet.
+func (typed *Typed[K, V]) Get(key K) (V, bool, error) {
+	var value V
+
+	sKey, encodeKeyErr := typed.KeyCodec.Encode(key)
+	if encodeKeyErr != nil { return value, false, encodeKeyErr }
+
+	sValue, getErr := typed.Map.Get(sKey)
+	if errors.Is(getErr, ErrKeyNotFound) { return value, false, nil }
+	if getErr != nil { return value, false, getErr }
+	if sValue == nil { return value, false, nil }
+
+	value, decodeErr := typed.ValueCodec.Decode(sValue)
+	if decodeErr != nil { return value, false, decodeErr }
+
+	return value, true, nil
+}
+
+// Delete
+//	Delete the key from the wrapped mmcmap, returning whether it was present.
+func (typed *Typed[K, V]) Delete(key K) (bool, error) {
+	sKey, encodeKeyErr := typed.KeyCodec.Encode(key)
+	if encodeKeyErr != nil { return false, encodeKeyErr }
+
+	return typed.Map.Delete(sKey)
+}
+
+// Range
+//	Collect and decode every pair where startKey <= key <= endKey, in the order of their encoded keys. A nil startKey or endKey leaves that side of
+//	the range unbounded. Bounds and order follow the encoded bytes of the keys, so they match the order of K only for codecs that preserve it, like
+//	StringCodec and Uint64Codec.
+func (typed *Typed[K, V]) Range(startKey, endKey *K, opts ...RangeOpts) ([]*TypedPair[K, V], error) {
+	sStartKey, encodeStartErr := typed.encodeBound(startKey)
+	if encodeStartErr != nil { return nil, encodeStartErr }
+
+	sEndKey, encodeEndErr := typed.encodeBound(endKey)
+	if encodeEndErr != nil { return nil, encodeEndErr }
+
+	sPairs, rangeErr := typed.Map.Range(sStartKey, sEndKey, opts...)
+	if rangeErr != nil { return nil, rangeErr }
+
+	pairs := make([]*TypedPair[K, V], len(sPairs))
+	for idx, sPair := range sPairs {
+		key, decodeKeyErr := typed.KeyCodec.Decode(sPair.Key)
+		if decodeKeyErr != nil { return nil, decodeKeyErr }
+
+		var value V
+		if sPair.Value != nil {
+			var decodeValueErr error
+			value, decodeValueErr = typed.ValueCodec.Decode(sPair.Value)
+			if decodeValueErr != nil { return nil, decodeValueErr }
+		}
+
+		pairs[idx] = &TypedPair[K, V]{ Version: sPair.Version, Key: key, Value: value }
+	}
+
+	return pairs, nil
+}
+
+// encodeBound
+//	Encode a range bound, where a nil bound is unbounded.
+func (typed *Typed[K, V]) encodeBound(bound *K) ([]byte, error) {
+	if bound == nil { return nil, nil }
+	return typed.KeyCodec.Encode(*bound)
+}
+
+// Encode
+//	The bytes of the string.
+func (StringCodec) Encode(value string) ([]byte, error) {
+	return []byte(value), nil
+}
+
+// Decode
+//	The string of the bytes.
+func (StringCodec) Decode(data []byte) (string, error) {
+	return string(data), nil
+}
+
+// Encode
+//	The 8 big endian bytes of the integer.
+func (Uint64Codec) Encode(value uint64) ([]byte, error) {
+	sValue := make([]byte, 8)
+	binary.BigEndian.PutUint64(sValue, value)
+
+	return sValue, nil
+}
+
+// Decode
+//	The integer of 8 big endian bytes. Any other length returns ErrInvalidEncoding.
+func (Uint64Codec) Decode(data []byte) (uint64, error) {
+	if len(data) != 8 { return 0, ErrInvalidEncoding }
+	return binary.BigEndian.Uint64(data), nil
+}
+
+// Encode
+//	The JSON encoding of the value.
+func (JSONCodec[T]) Encode(value T) ([]byte, error) {
+	return json.Marshal(value)
+}
+
+// Decode
+//	The value decoded from JSON.
+func (JSONCodec[T]) Decode(data []byte) (T, error) {
+	var value T
+	unmarshalErr := json.Unmarshal(data, &value)
+	return value, unmarshalErr
+}
+
+// Encode
+//	The wire format of the message.
+func (ProtoCodec[T]) Encode(value T) ([]byte, error) {
+	return proto.Marshal(value)
+}
+
+// Decode
+//	A new message from New, decoded from its wire format.
+func (codec ProtoCodec[T]) Decode(data []byte) (T, error) {
+	value := codec.New()
+	unmarshalErr := proto.Unmarshal(data, value)
+	return value, unmarshalErr
+}
@@ -1,12 +1,8 @@
 package mmcmap
 
-import "fmt"
 import "math"
 import "math/bits"
-import "sync/atomic"
-import "unsafe"
 
-import "github.com/sirgallo/mmcmap/common/mmap"
 import "github.com/sirgallo/mmcmap/common/murmur"
 
 
@@ -34,12 +30,12 @@ func IsBitSet(bitmap uint32, position int) bool {
 }
 
 // Print Children
-//	Debugging function for printing nodes in the hash array mapped trie.
+//	Debugging function for logging every node in the hash array mapped trie at LogDebug.
+//
+// Deprecated: use Dump, which writes the offsets, versions, and bitmaps of the nodes as JSON or DOT.
 func (mmcMap *MMCMap) PrintChildren() error {
-	mMap := mmcMap.Data.Load().(mmap.MMap)
-
-	rootOffsetPtr := (*uint64)(unsafe.Pointer(&mMap[MetaRootOffsetIdx]))
-	rootOffset := atomic.LoadUint64(rootOffsetPtr)
+	_, rootOffset, loadROffErr := mmcMap.loadMetaRootOffset()
+	if loadROffErr != nil { return loadROffErr }
 
 	currRoot, readRootErr := mmcMap.ReadNodeFromMemMap(rootOffset)
 	if readRootErr != nil { return readRootErr }
@@ -58,6 +54,13 @@ func SetBit(bitmap uint32, position int) uint32 {
 	return bitmap ^ (1 << position)
 }
 
+// ClearBit
+//	Unsets the bit at the position of the incoming index, leaving the bitmap unchanged if it is already 0.
+//	Used on delete, where flipping a bit that is not set would mark an empty slot as occupied.
+func ClearBit(bitmap uint32, position int) uint32 {
+	return bitmap &^ (1 << position)
+}
+
 // CalculateHammingWeight
 //	Determines the total number of 1s in the binary representation of a number. 0s are ignored.
 func calculateHammingWeight(bitmap uint32) int {
@@ -66,11 +69,14 @@ func calculateHammingWeight(bitmap uint32) int {
 
 // CalculateHashForCurrentLevel
 //	Calculates the hash for value based on what level of the trie the operation is at.
-//	Hash is reseeded every 6 levels.
-func (mmcMap *MMCMap) calculateHashForCurrentLevel(key []byte, level int) uint32 {
+//	Hash is reseeded every 6 levels with 32 bit hashes, and every 12 levels with 64 bit hashes. The seed of each chunk is offset by the random
+//	seed of the file, and 32 bit hashes use its low 32 bits.
+func (mmcMap *MMCMap) calculateHashForCurrentLevel(key []byte, level int) uint64 {
 	currChunk := level / mmcMap.HashChunks
-	seed := uint32(currChunk + 1)
-	return murmur.Murmur32(key, seed)
+	seed := mmcMap.Header.HashSeed + uint64(currChunk + 1)
+	if mmcMap.Header.HashBits == HashBits64 { return murmur.Murmur64(key, seed) }
+
+	return uint64(murmur.Murmur32(key, uint32(seed)))
 }
 
 // extendTable
@@ -87,32 +93,45 @@ func extendTable(orig []*MMCMapNode, bitMap uint32, pos int, newNode *MMCMapNode
 }
 
 // GetIndexForLevel
-//	Determines the local level for a hash at a particular seed.
-func getIndexForLevel(hash uint32, chunkSize int, level int, hashChunks int) int {
+//	Determines the local level for a hash at a particular seed, and gets the index from the chunk of the hashBits wide hash at that level.
+func getIndexForLevel(hash uint64, chunkSize int, level int, hashChunks int, hashBits int) int {
 	updatedLevel := level % hashChunks
-	return GetIndex(hash, chunkSize, updatedLevel)
+	shiftSize := hashBits - (chunkSize * (updatedLevel + 1))
+
+	mask := uint64(1 << chunkSize - 1)
+	return int(hash >> shiftSize & mask)
 }
 
-// getPosition
+// childPosition
 //	Calculates the position in the child node array based on the sparse index and the current bitmap of internal node.
-//	The sparse index is calculated using the hash and bitchunk size.
 //	A mask is calculated by performing a bitwise left shift operation, which shifts the binary representation of the value 1 the number of positions associated with the sparse index value and then subtracts 1.
 //	This creates a binary number with all 1s to the right sparse index positions.
 //	The mask is then applied the bitmap and the resulting isolated bits are the 1s right of the sparse index. 
 //	The hamming weight, or total bits right of the sparse index, is then calculated.
-func (mmcMap *MMCMap) getPosition(bitMap uint32, hash uint32, level int) int {
-	sparseIdx := getIndexForLevel(hash, mmcMap.BitChunkSize, level, mmcMap.HashChunks)
+func childPosition(bitmap uint32, index int) int {
+	return calculateHammingWeight(bitmap & uint32((1 << index) - 1))
+}
+
+// getKeyIndex
+//	Gets the sparse index of a key at a particular level in the trie, from the hash of the key, or from its raw bytes in files using KeyModeOrdered.
+func (mmcMap *MMCMap) getKeyIndex(key []byte, level int) int {
+	if mmcMap.isOrderedKeys() { return getOrderedIndex(key, level) }
+	return mmcMap.getSparseIndex(mmcMap.calculateHashForCurrentLevel(key, level), level)
+}
 
-	mask := uint32((1 << sparseIdx) - 1)
-	isolatedBits := bitMap & mask
-	return calculateHammingWeight(isolatedBits)
+// maxKeyLevel
+//	The deepest level an internal node on the path to key can be at. Hashed keys reach a collision bucket once the seeds of the hash are exhausted,
+//	and ordered keys are placed at 0 once their chunks are exhausted, so the node below that level must be a leaf.
+func (mmcMap *MMCMap) maxKeyLevel(key []byte) int {
+	if mmcMap.isOrderedKeys() { return len(key) * (8 / OrderedChunkSize) }
+	return mmcMap.HashChunks * CollisionHashSeeds
 }
 
 // getSparseIndex
 //	Gets the index at a particular level in the trie. 
 //	Pass through function.
-func (mmcMap *MMCMap) getSparseIndex(hash uint32, level int) int {
-	return getIndexForLevel(hash, mmcMap.BitChunkSize, level, mmcMap.HashChunks)
+func (mmcMap *MMCMap) getSparseIndex(hash uint64, level int) int {
+	return getIndexForLevel(hash, mmcMap.BitChunkSize, level, mmcMap.HashChunks, int(mmcMap.Header.HashBits))
 }
 
 // shrinkTable
@@ -140,7 +159,7 @@ func (mmcMap *MMCMap) printChildrenRecursive(node *MMCMapNode, level int) error
 		if desErr != nil { return desErr }
 
 		if child != nil {
-			fmt.Printf("Level: %d, Index: %d, Key: %d, Value: %d\n", level, idx, child.Key, child.Value)
+			mmcMap.log(LogDebug, "child", "level", level, "index", idx, "key", child.Key, "value", child.Value)
 			mmcMap.printChildrenRecursive(child, level + 1)
 		}
 	}
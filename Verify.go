@@ -0,0 +1,65 @@
+package mmcmap
+
+import "time"
+
+
+//============================================= MMCMap Verify
+
+
+// Verify
+//	Walk the entire trie reachable from the latest root and report any corruption found.
+//	Every node is checked for a start offset matching where it was read, an end offset within the serialized data, a version no newer than its parent,
+//	and a child count matching its bitmap, and every leaf is checked to be placed where its key hashes to. Nodes do not carry checksums, so
+//	corruption is detected through these structural checks. For files that maintain a key count, the number of leaves must also match the count.
+//	The traversal is pinned to the root at the time of the call, and each node is read under its own read lock so writes and resizes can continue.
+func (mmcMap *MMCMap) Verify() (*VerifyReport, error) {
+	start := time.Now()
+
+	meta, keyCount, loadErr := mmcMap.loadVerifyMeta()
+	if loadErr != nil { return nil, loadErr }
+
+	read := func(offset uint64) (*MMCMapNode, error) { return mmcMap.readNodeCopy(offset, true) }
+	report := &VerifyReport{ Version: meta.Version, KeyCount: keyCount }
+
+	mmcMap.checkHeader(&report.Findings)
+	mmcMap.checkRoot(&report.Findings, meta, read)
+
+	if len(report.Findings) == 0 {
+		checker := &nodeChecker{ mmcMap: mmcMap, meta: meta, read: read }
+		checker.checkNode(meta.RootOffset, meta.Version, 0, -1)
+
+		report.Findings = checker.findings
+		report.NodesVisited = checker.nodesVisited
+		report.KeysVisited = checker.keysVisited
+
+		if mmcMap.isKeyCountTracked() && report.KeysVisited != report.KeyCount {
+			addFinding(&report.Findings, "found %d keys but the header key count is %d", report.KeysVisited, report.KeyCount)
+		}
+	}
+
+	report.IsValid = len(report.Findings) == 0
+	report.Duration = time.Since(start)
+
+	return report, nil
+}
+
+// loadVerifyMeta
+//	Read the metadata and key count together so the count matches the pinned root.
+func (mmcMap *MMCMap) loadVerifyMeta() (*MMCMapMetaData, uint64, error) {
+	mmcMap.CommitGate.Lock()
+	defer mmcMap.CommitGate.Unlock()
+
+	mmcMap.RWResizeLock.RLock()
+	defer mmcMap.RWResizeLock.RUnlock()
+
+	handleErr := mmcMap.checkHandle()
+	if handleErr != nil { return nil, 0, handleErr }
+
+	meta, readMetaErr := mmcMap.ReadMetaFromMemMap()
+	if readMetaErr != nil { return nil, 0, readMetaErr }
+
+	_, keyCount, loadKCountErr := mmcMap.loadMetaKeyCount()
+	if loadKCountErr != nil { return nil, 0, loadKCountErr }
+
+	return meta, keyCount, nil
+}
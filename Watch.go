@@ -0,0 +1,145 @@
+package mmcmap
+
+import "bytes"
+import "sync"
+import "sync/atomic"
+
+
+//============================================= MMCMap Watch
+
+
+// Watch
+//	Receive a ChangeEvent for every put and delete of a key starting with prefix, once the version containing it has been committed and the new root
+//	published, so a Get after receiving an event observes the change or a later one. Events are delivered in version order, and in the order of the
+//	ops within a version. A nil prefix watches every key. Puts publish an event even if the value is unchanged, and deletes only if the key existed.
+//	Events are queued for each watcher without bound, so a slow receiver never blocks commits. Call the CancelFunc to stop watching, which closes
+//	the channel. Closing the map cancels every watch. Keys loaded by BulkLoad, Clear, and applied replication deltas or snapshots do not publish events.
+func (mmcMap *MMCMap) Watch(prefix []byte) (<-chan ChangeEvent, CancelFunc) {
+	watch := &watcher{
+		prefix: append([]byte{}, prefix...),
+		events: make(chan ChangeEvent),
+		done: make(chan struct{}),
+	}
+
+	watch.cond = sync.NewCond(&watch.mutex)
+
+	mmcMap.WatchLock.Lock()
+	if mmcMap.Watchers == nil { mmcMap.Watchers = make(map[uint64]*watcher) }
+
+	id := mmcMap.NextWatcherID
+	mmcMap.NextWatcherID++
+	mmcMap.Watchers[id] = watch
+	atomic.AddInt64(&mmcMap.WatcherCount, 1)
+	mmcMap.WatchLock.Unlock()
+
+	mmcMap.goLabeled("watch", watch.dispatch)
+
+	cancel := func() {
+		mmcMap.WatchLock.Lock()
+		_, isActive := mmcMap.Watchers[id]
+		if isActive {
+			delete(mmcMap.Watchers, id)
+			atomic.AddInt64(&mmcMap.WatcherCount, -1)
+		}
+
+		mmcMap.WatchLock.Unlock()
+		watch.stop()
+	}
+
+	return watch.events, cancel
+}
+
+// isWatched
+//	Determine if any watchers are active, so commits can skip building change events otherwise.
+func (mmcMap *MMCMap) isWatched() bool {
+	return atomic.LoadInt64(&mmcMap.WatcherCount) > 0
+}
+
+// newChangeEvent
+//	Create the change event for an op committed at version, copying the key and value so the event does not share memory with the caller.
+func newChangeEvent(op *BatchOp, version uint64) ChangeEvent {
+	if op.IsDelete { return ChangeEvent{ Key: append([]byte{}, op.Key...), Version: version, Op: ChangeDelete } }
+	return ChangeEvent{ Key: append([]byte{}, op.Key...), Value: append([]byte{}, op.Value...), Version: version, Op: ChangePut }
+}
+
+// publishChanges
+//	Queue the events of a committed version for every watcher with a matching prefix. The watch lock must be held.
+func (mmcMap *MMCMap) publishChanges(events []ChangeEvent) {
+	for _, watch := range mmcMap.Watchers {
+		watch.enqueue(events)
+	}
+}
+
+// closeWatchers
+//	Cancel every watch, closing their channels.
+func (mmcMap *MMCMap) closeWatchers() {
+	mmcMap.WatchLock.Lock()
+	defer mmcMap.WatchLock.Unlock()
+
+	for id, watch := range mmcMap.Watchers {
+		delete(mmcMap.Watchers, id)
+		atomic.AddInt64(&mmcMap.WatcherCount, -1)
+		watch.stop()
+	}
+}
+
+// enqueue
+//	Append the events with keys matching the prefix of the watcher to its queue, waking the dispatch go routine.
+func (watch *watcher) enqueue(events []ChangeEvent) {
+	watch.mutex.Lock()
+	defer watch.mutex.Unlock()
+
+	if watch.stopped { return }
+
+	var matched bool
+	for _, event := range events {
+		if bytes.HasPrefix(event.Key, watch.prefix) {
+			watch.pending = append(watch.pending, event)
+			matched = true
+		}
+	}
+
+	if matched { watch.cond.Signal() }
+}
+
+// stop
+//	Stop the watcher, discarding any queued events. The dispatch go routine closes the events channel on exit.
+func (watch *watcher) stop() {
+	watch.mutex.Lock()
+	defer watch.mutex.Unlock()
+
+	if watch.stopped { return }
+
+	watch.stopped = true
+	watch.pending = nil
+	close(watch.done)
+	watch.cond.Signal()
+}
+
+// dispatch
+//	The watcher go routine. Hands queued events to the events channel in order until the watcher is stopped.
+func (watch *watcher) dispatch() {
+	defer close(watch.events)
+
+	for {
+		watch.mutex.Lock()
+		for len(watch.pending) == 0 && ! watch.stopped { watch.cond.Wait() }
+
+		if watch.stopped {
+			watch.mutex.Unlock()
+			return
+		}
+
+		batch := watch.pending
+		watch.pending = nil
+		watch.mutex.Unlock()
+
+		for _, event := range batch {
+			select {
+				case watch.events <- event:
+				case <-watch.done:
+					return
+			}
+		}
+	}
+}
@@ -0,0 +1,307 @@
+package mmcmap
+
+import "runtime"
+import "sync"
+import "sync/atomic"
+
+
+//============================================= MMCMap Write Shards
+
+
+// writeLane is the lane of a write shard held by a commit across its attempts. The lane is only waited for between attempts, once the commit gate
+// and the resize read lock have been released, so a commit waiting on its shard never holds up a resize, Quiesce, or Compact
+type writeLane struct {
+	mmcMap *MMCMap
+	// held: the shard whose lane is held by the commit, or -1
+	held int
+	// next: the shard whose lane was held by another commit during the last attempt, to be waited for before the next attempt, or -1
+	next int
+}
+
+
+// shardSlot is the slot of a write shard in the shard table, recording the latest commit to the shard. The children of the root of that commit at
+// the indexes of the shard are the sub-root of the shard
+type shardSlot struct {
+	// version: the version of the latest commit to the shard
+	version uint64
+	// rootOffset: the offset of the root of the latest commit to the shard
+	rootOffset uint64
+}
+
+
+// WriteShard
+//	The write shard the key falls in, or -1 if the map has no write shards. Writes to keys in the same shard are committed in place of each other,
+//	so grouping batches by shard keeps commits to different shards from contending.
+func (mmcMap *MMCMap) WriteShard(key []byte) int {
+	if len(mmcMap.ShardLanes) == 0 { return -1 }
+	return mmcMap.writeShard(key)
+}
+
+// resolveWriteShards
+//	Check the write shards from the file header against the options, and create the lane of each shard.
+func (mmcMap *MMCMap) resolveWriteShards() error {
+	if mmcMap.Opts.WriteShards != 0 && mmcMap.Opts.WriteShards != int(mmcMap.Header.WriteShards) { return ErrWriteShardsMismatch }
+	if mmcMap.Header.WriteShards > MaxWriteShards { return ErrInvalidWriteShards }
+
+	mmcMap.ShardLanes = make([]sync.Mutex, mmcMap.Header.WriteShards)
+	return nil
+}
+
+// newWriteLane
+//	A commit holding no lane. Returns nil if the map has no write shards.
+func (mmcMap *MMCMap) newWriteLane() *writeLane {
+	if len(mmcMap.ShardLanes) == 0 { return nil }
+	return &writeLane{ mmcMap: mmcMap, held: -1, next: -1 }
+}
+
+// claim
+//	Take the lane of the write shard every op falls in, so commits to the same shard copy their paths one at a time instead of invalidating each
+//	other. Commits with ops in more than one shard hold no lane and rely on the version check like any other commit. Called once the ops are
+//	prepared, with the commit gate and resize read lock held, so the lane is only tried. Returns false if another commit holds the lane, in which
+//	case the attempt is abandoned and the lane waited for with wait before the next one.
+func (lane *writeLane) claim(ops []*BatchOp) bool {
+	if lane == nil { return true }
+
+	shard := lane.mmcMap.opsShard(ops)
+	if shard == lane.held { return true }
+
+	lane.release()
+	if shard < 0 { return true }
+
+	if ! lane.mmcMap.ShardLanes[shard].TryLock() {
+		lane.next = shard
+		return false
+	}
+
+	lane.held = shard
+	return true
+}
+
+// isWaiting
+//	Determine if the last attempt was abandoned because another commit held the lane of its shard.
+func (lane *writeLane) isWaiting() bool {
+	return lane != nil && lane.next >= 0
+}
+
+// wait
+//	Block until the lane the last attempt could not claim is free, and hold it for the next attempt, which starts again from the root the commit
+//	holding the lane published. Called between attempts, with no locks held.
+func (lane *writeLane) wait() {
+	if ! lane.isWaiting() { return }
+
+	atomic.AddUint64(&lane.mmcMap.ShardWaits, 1)
+	lane.mmcMap.ShardLanes[lane.next].Lock()
+	lane.held, lane.next = lane.next, -1
+}
+
+// release
+//	Release the lane held by the commit, if any.
+func (lane *writeLane) release() {
+	if lane == nil || lane.held < 0 { return }
+
+	lane.mmcMap.ShardLanes[lane.held].Unlock()
+	lane.held = -1
+}
+
+// opsShard
+//	The write shard every op falls in, or -1 if the ops span more than one shard.
+func (mmcMap *MMCMap) opsShard(ops []*BatchOp) int {
+	if len(ops) == 0 || len(mmcMap.ShardLanes) == 0 { return -1 }
+
+	shard := mmcMap.writeShard(ops[0].Key)
+	for _, op := range ops[1:] {
+		if mmcMap.writeShard(op.Key) != shard { return -1 }
+	}
+
+	return shard
+}
+
+// writeShard
+//	The write shard of the key, determined by the index of the key at the top level of the trie, so keys in a shard share no path below the root
+//	with keys in another.
+func (mmcMap *MMCMap) writeShard(key []byte) int {
+	return mmcMap.getKeyIndex(key, 0) % len(mmcMap.ShardLanes)
+}
+
+// opsShards
+//	The mask of the write shards the ops fall in.
+func (mmcMap *MMCMap) opsShards(ops []*BatchOp) uint32 {
+	if len(mmcMap.ShardLanes) == 0 { return allWriteShards }
+
+	var shards uint32
+	for _, op := range ops { shards |= 1 << mmcMap.writeShard(op.Key) }
+
+	return shards
+}
+
+// loadShardSlot
+//	Load the slot of the shard, along with the version the shard table was last published with. The slot is written by the commit that won the
+//	version before its root is published, so a slot read ahead of that root may pair its version with the root of the commit after it. Commits
+//	check the slot again against the published root before they are placed on it, which catches a torn read.
+func (mmcMap *MMCMap) loadShardSlot(shard int) (*shardSlot, uint64, error) {
+	tableIdx := mmcMap.Header.shardTableIdx()
+	slotIdx := tableIdx + OffsetSize + uint64(shard) * ShardSlotSize
+
+	_, tableVersion, loadTableErr := mmcMap.loadMetaWord("load shard table", tableIdx)
+	if loadTableErr != nil { return nil, 0, loadTableErr }
+
+	_, version, loadVErr := mmcMap.loadMetaWord("load shard version", slotIdx)
+	if loadVErr != nil { return nil, 0, loadVErr }
+
+	_, rootOffset, loadROffErr := mmcMap.loadMetaWord("load shard root offset", slotIdx + OffsetSize)
+	if loadROffErr != nil { return nil, 0, loadROffErr }
+
+	return &shardSlot{ version: version, rootOffset: rootOffset }, tableVersion, nil
+}
+
+// publishShards
+//	Record the commit at version, with its root at rootOffset, in the slot of every write shard in shards. Called by the commit that won version
+//	before its root is published. A table last published with a version other than prevVersion was bypassed by a commit that rewrote the root
+//	as a whole, like a Clear, BulkLoad, Compact, or a delta applied by a follower, so every slot is reset to the commit.
+func (mmcMap *MMCMap) publishShards(shards uint32, prevVersion, version, rootOffset uint64) error {
+	if len(mmcMap.ShardLanes) == 0 { return nil }
+
+	tableIdx := mmcMap.Header.shardTableIdx()
+	tablePtr, tableVersion, loadTableErr := mmcMap.loadMetaWord("load shard table", tableIdx)
+	if loadTableErr != nil { return loadTableErr }
+
+	if tableVersion != prevVersion { shards = allWriteShards }
+
+	for shard := range mmcMap.ShardLanes {
+		if shards & (1 << shard) == 0 { continue }
+
+		slotIdx := tableIdx + OffsetSize + uint64(shard) * ShardSlotSize
+		versionPtr, _, loadVErr := mmcMap.loadMetaWord("load shard version", slotIdx)
+		if loadVErr != nil { return loadVErr }
+
+		rootOffsetPtr, _, loadROffErr := mmcMap.loadMetaWord("load shard root offset", slotIdx + OffsetSize)
+		if loadROffErr != nil { return loadROffErr }
+
+		mmcMap.storeMetaPointer(rootOffsetPtr, rootOffset)
+		mmcMap.storeMetaPointer(versionPtr, version)
+	}
+
+	mmcMap.storeMetaPointer(tablePtr, version)
+	return nil
+}
+
+// loadShardBase
+//	The root to copy the path of a commit to shard from, which is the root of the latest commit to the shard. prepare was called with the root at
+//	version, so the slot is only used if the shard has not been committed to since, and the shard table has been published with every commit since
+//	version. A table published with a version newer than the map was left behind by a Repair that rolled the map back. Returns nil if the commit has
+//	to copy its path from the root at version instead.
+func (mmcMap *MMCMap) loadShardBase(shard int, version uint64) (*shardSlot, *MMCMapNode, error) {
+	slot, tableVersion, loadSlotErr := mmcMap.loadShardSlot(shard)
+	if loadSlotErr != nil { return nil, nil, loadSlotErr }
+
+	_, latestVersion, loadVErr := mmcMap.loadMetaVersion()
+	if loadVErr != nil { return nil, nil, loadVErr }
+
+	if tableVersion < version || tableVersion > latestVersion || slot.version > version || slot.rootOffset < mmcMap.HeaderSize { return nil, nil, nil }
+
+	// the slot is checked against the published root before the commit is placed on it, so a slot that can not be read is only passed over
+	base, readBaseErr := mmcMap.ReadNodeFromMemMap(slot.rootOffset)
+	if readBaseErr != nil || base.Version != slot.version { return nil, nil, nil }
+
+	return slot, base, nil
+}
+
+// commitToShard
+//	Place the path copied from the root of the shard on the latest root and write it, until it is written or the shard is committed to by another
+//	commit. The commit gate is held, so the root can only be replaced by commits that publish the shard table. Each attempt takes the children of the latest root outside the shard, so a commit to another shard published meanwhile is kept instead of
+//	invalidating the path, and losing the race for the next version to another shard is not a retry. If a commit other than the one the path was
+//	copied from has been published, prepare is called again with the latest root, like a rebase, and the commit is retried if the ops differ.
+//	Returns retry if the shard was committed to since the path was copied.
+func (mmcMap *MMCMap) commitToShard(path *MMCMapNode, shard int, slot *shardSlot, baseVersion uint64, ops []*BatchOp, prepare func(root *MMCMapNode) ([]*BatchOp, error), keyDelta int64, events []ChangeEvent, span Span) (uint64, bool, error) {
+	for {
+		current, tableVersion, loadSlotErr := mmcMap.loadShardSlot(shard)
+		if loadSlotErr != nil { return 0, false, loadSlotErr }
+		if current.version != slot.version || current.rootOffset != slot.rootOffset { return 0, true, nil }
+
+		latestVersion, latestRoot, loadRootErr := mmcMap.loadPublishedRoot()
+		if loadRootErr != nil { return 0, false, loadRootErr }
+
+		// the table is read first, so it only trails the latest root while commits to other shards land, and is only ahead of it when a commit
+		// failed after publishing it, which the next attempt copies its path from the latest root to repair
+		if tableVersion > latestVersion { return 0, true, nil }
+		if tableVersion < latestVersion {
+			runtime.Gosched()
+			continue
+		}
+
+		if latestVersion != baseVersion {
+			latestOps, prepareErr := prepare(latestRoot)
+			if prepareErr != nil { return 0, false, prepareErr }
+			if ! isSameOps(ops, latestOps) { return 0, true, nil }
+		}
+
+		mmcMap.mergeShard(path, latestRoot, shard, latestVersion + 1)
+		for idx := range events { events[idx].Version = path.Version }
+
+		written, writeErr := mmcMap.exclusiveWriteMmap(mmcMap.clonePath(path, path.Version), keyDelta, events, 1 << shard, span)
+		if writeErr == ErrResizeInProgress { return 0, true, nil }
+		if writeErr != nil { return 0, false, writeErr }
+
+		if written {
+			if latestVersion != baseVersion { atomic.AddUint64(&mmcMap.CommitRebases, 1) }
+			return path.Version, false, nil
+		}
+	}
+}
+
+// mergeShard
+//	Place the path on the latest root at version. The children of the root of the path outside the shard are replaced with the children of the latest
+//	root, so the path only changes the shard, and the nodes of the path below the shard are moved to version. The latest root may hold nodes at the
+//	version the path was at, written by the commit it lost the race to, so only the children of the shard are renumbered.
+func (mmcMap *MMCMap) mergeShard(path, latest *MMCMapNode, shard int, version uint64) {
+	var bitmap uint32
+	children := make([]*MMCMapNode, 0, len(latest.Children) + 1)
+
+	for idx := 0; idx < 32; idx++ {
+		child := childAt(latest, idx)
+		if idx % len(mmcMap.ShardLanes) == shard {
+			child = childAt(path, idx)
+			if child != nil { renumberPath(child, path.Version, version) }
+		}
+
+		if child == nil { continue }
+
+		bitmap = SetBit(bitmap, idx)
+		children = append(children, child)
+	}
+
+	path.Version = version
+	path.Bitmap = bitmap
+	path.Children = children
+}
+
+// clonePath
+//	Copy the nodes of the path at version. The path is serialized before the race for the version is decided, and its nodes are handed back to the
+//	node pool as they are serialized, so each attempt writes a copy and the path stays intact to be placed again if the attempt loses the race.
+func (mmcMap *MMCMap) clonePath(node *MMCMapNode, version uint64) *MMCMapNode {
+	nodeCopy := mmcMap.copyNode(node)
+	for idx, child := range nodeCopy.Children {
+		if child.Version == version { nodeCopy.Children[idx] = mmcMap.clonePath(child, version) }
+	}
+
+	return nodeCopy
+}
+
+// loadPublishedRoot
+//	Load the latest version along with its root. The version is published before the root offset, so the root is read until it is the one the
+//	version was committed with.
+func (mmcMap *MMCMap) loadPublishedRoot() (uint64, *MMCMapNode, error) {
+	for {
+		_, version, loadVErr := mmcMap.loadMetaVersion()
+		if loadVErr != nil { return 0, nil, loadVErr }
+
+		_, rootOffset, loadROffErr := mmcMap.loadMetaRootOffset()
+		if loadROffErr != nil { return 0, nil, loadROffErr }
+
+		root, readRootErr := mmcMap.ReadNodeFromMemMap(rootOffset)
+		if readRootErr != nil { return 0, nil, readRootErr }
+		if root.Version == version { return version, root, nil }
+
+		runtime.Gosched()
+	}
+}
@@ -0,0 +1,120 @@
+package mmcmap
+
+
+//============================================= MMCMap Single Writer
+
+
+// PutAsync
+//	Submit a put and return a future for its result instead of waiting for the commit.
+//	With SingleWriter the put is queued for the writer go routine, otherwise it is committed before returning and the future is already resolved.
+//	The future resolves once the put is committed, which is before it is on disk. Pass the version from WaitVersion to Wait to await durability.
+func (mmcMap *MMCMap) PutAsync(key, value []byte) *WriteFuture {
+	return mmcMap.submitOps([]*BatchOp{{ Key: key, Value: value }})
+}
+
+// DeleteAsync
+//	Submit a delete and return a future for its result. See PutAsync.
+func (mmcMap *MMCMap) DeleteAsync(key []byte) *WriteFuture {
+	return mmcMap.submitPrepare(deleteExisting(mmcMap, key))
+}
+
+// Wait
+//	Block until the commit completes and return its result.
+func (future *WriteFuture) Wait() (bool, error) {
+	<-future.done
+	return future.ok, future.err
+}
+
+// WaitVersion
+//	Block until the commit completes and return the version it was committed at, or 0 if no new version was written.
+func (future *WriteFuture) WaitVersion() (uint64, error) {
+	<-future.done
+	return future.version, future.err
+}
+
+// Done
+//	A channel that is closed once the commit completes.
+func (future *WriteFuture) Done() <-chan struct{} {
+	return future.done
+}
+
+// submitOps
+//	Submit the ops as a single commit, queued for the writer go routine if SingleWriter is set.
+func (mmcMap *MMCMap) submitOps(ops []*BatchOp) *WriteFuture {
+	return mmcMap.submitPrepare(func(root *MMCMapNode) ([]*BatchOp, error) { return ops, nil })
+}
+
+// submitPrepare
+//	Submit the mutations returned by prepare as a single commit. See commitWith.
+func (mmcMap *MMCMap) submitPrepare(prepare func(root *MMCMapNode) ([]*BatchOp, error)) *WriteFuture {
+	future := newWriteFuture()
+
+	stallErr := mmcMap.awaitFlush()
+	if stallErr != nil {
+		future.resolve(false, 0, stallErr)
+		return future
+	}
+
+	if mmcMap.Opts.SingleWriter { return mmcMap.submitWrite(prepare) }
+
+	future.resolve(mmcMap.commitLoop(prepare, &future.retries))
+	return future
+}
+
+// submitWrite
+//	Queue a commit for the writer go routine. Callers block once the queue is full, which applies backpressure instead of piling up retries.
+//	The handle is checked first so a lazily opened map starts its writer go routine before anything is queued.
+func (mmcMap *MMCMap) submitWrite(prepare func(root *MMCMapNode) ([]*BatchOp, error)) *WriteFuture {
+	future := newWriteFuture()
+
+	handleErr := mmcMap.checkHandleForWrite()
+	if handleErr != nil {
+		future.resolve(false, 0, handleErr)
+		return future
+	}
+
+	mmcMap.WriteQueue <- &writeRequest{ prepare: prepare, future: future }
+	return future
+}
+
+// checkHandleForWrite
+//	Check the handle under the resize read lock, starting the background go routines if needed.
+func (mmcMap *MMCMap) checkHandleForWrite() error {
+	mmcMap.RWResizeLock.RLock()
+	defer mmcMap.RWResizeLock.RUnlock()
+
+	return mmcMap.checkHandle()
+}
+
+// handleWrites
+//	The writer go routine. Commits are applied one at a time in the order they were queued, so the writer is the only go routine copying paths and
+//	its commits never have to be retried against a newer root.
+func (mmcMap *MMCMap) handleWrites(queue chan *writeRequest) {
+	for request := range queue {
+		request.future.resolve(mmcMap.commitLoop(request.prepare, &request.future.retries))
+	}
+}
+
+// newWriteFuture
+//	Create an unresolved future.
+func newWriteFuture() *WriteFuture {
+	return &WriteFuture{ done: make(chan struct{}) }
+}
+
+// resolve
+//	Record the result of the commit and release anyone waiting on the future.
+func (future *WriteFuture) resolve(ok bool, version uint64, err error) {
+	future.ok = ok
+	future.version = version
+	future.err = err
+	close(future.done)
+}
+
+// newWriteQueue
+//	Create the queue feeding the writer go routine, sized from the options.
+func newWriteQueue(opts MMCMapOpts) chan *writeRequest {
+	size := opts.WriteQueueSize
+	if size <= 0 { size = DefaultWriteQueueSize }
+
+	return make(chan *writeRequest, size)
+}
@@ -0,0 +1,96 @@
+package mmcmap
+
+import "sync"
+import "sync/atomic"
+
+import "github.com/sirgallo/mmcmap/common/mmap"
+
+
+//============================================= MMCMap Zero Copy Reads
+
+
+// GetZeroCopy
+//	Retrieve the value for a key as a slice aliasing the memory map, without copying it, along with a release func that must be called once the
+//	value is no longer needed. A nil value is returned if the key does not exist, or ErrKeyNotFound with StrictGet, the same as Get.
+//	Until it is released, the value is pinned: a resize or Close retires the memory map instead of unmapping it, and Compact, Reclaim, and truncating
+//	Clears wait for the release, since they would move or overwrite the value. The pin is counted rather than held as a lock, so while a relocation waits
+//	for it, commits continue and the holder may still call Range, GetReader, or other reads before releasing the value. The value must not be modified
+//	or used after it is released.
+//	Compressed values are decompressed into a new slice, so only uncompressed values avoid the copy.
+func (mmcMap *MMCMap) GetZeroCopy(key []byte) ([]byte, func(), error) {
+	atomic.AddUint64(&mmcMap.Gets, 1)
+	mmcMap.pinRelocation()
+
+	value, getErr := mmcMap.getPinned(key)
+	if getErr != nil {
+		mmcMap.unpinRelocation()
+		return nil, nil, getErr
+	}
+
+	if value == nil {
+		mmcMap.unpinRelocation()
+		if mmcMap.Opts.StrictGet { return nil, nil, ErrKeyNotFound }
+		return nil, func() {}, nil
+	}
+
+	var releaseOnce sync.Once
+	return value, func() { releaseOnce.Do(mmcMap.releasePin) }, nil
+}
+
+// getPinned
+//	Retrieve the value for a key at the latest version, adding a pin on the memory map if the key exists. The pin is added while the resize read
+//	lock is held, so the memory map the value is sliced from can not be replaced before it is pinned.
+func (mmcMap *MMCMap) getPinned(key []byte) ([]byte, error) {
+	mmcMap.waitForRemap()
+
+	mmcMap.RWResizeLock.RLock()
+	defer mmcMap.RWResizeLock.RUnlock()
+
+	handleErr := mmcMap.checkHandle()
+	if handleErr != nil { return nil, handleErr }
+
+	_, rootOffset, loadROffErr := mmcMap.loadMetaRootOffset()
+	if loadROffErr != nil { return nil, loadROffErr }
+
+	value, getErr := mmcMap.lookupValue(rootOffset, key)
+	if getErr != nil || value == nil { return nil, getErr }
+
+	mmcMap.PinLock.Lock()
+	mmcMap.ZeroCopyPins++
+	mmcMap.PinLock.Unlock()
+
+	return value, nil
+}
+
+// releasePin
+//	Release a pin added by GetZeroCopy. Once the last pin is released, the memory maps retired while pinned are unmapped, and the relocate pin is
+//	released.
+func (mmcMap *MMCMap) releasePin() {
+	mmcMap.PinLock.Lock()
+
+	mmcMap.ZeroCopyPins--
+	var retired []mmap.MMap
+	if mmcMap.ZeroCopyPins == 0 {
+		retired = mmcMap.RetiredMaps
+		mmcMap.RetiredMaps = nil
+	}
+
+	mmcMap.PinLock.Unlock()
+
+	for _, mMap := range retired { mMap.Unmap() }
+	mmcMap.unpinRelocation()
+}
+
+// retireMap
+//	Keep a memory map being replaced mapped if any values are pinned to it. Returns false if nothing is pinned, in which case it can be unmapped.
+func (mmcMap *MMCMap) retireMap(mMap mmap.MMap) bool {
+	if len(mMap) == 0 { return false }
+
+	mmcMap.PinLock.Lock()
+	defer mmcMap.PinLock.Unlock()
+
+	if mmcMap.ZeroCopyPins == 0 { return false }
+
+	mmcMap.RetiredMaps = append(mmcMap.RetiredMaps, mMap)
+	return true
+}
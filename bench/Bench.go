@@ -0,0 +1,226 @@
+package bench
+
+import "encoding/csv"
+import "fmt"
+import "io"
+import "math/rand"
+import "strconv"
+import "sync"
+import "time"
+
+import "github.com/sirgallo/mmcmap"
+
+
+//============================================= MMCMap Benchmarks
+
+
+// ReadHeavy
+//	95% gets and 5% puts over uniformly chosen keys with small values.
+func ReadHeavy() Workload {
+	return Workload{ Name: "read-heavy", ReadRatio: 0.95, KeyCount: 100000, KeySize: 16, ValueSize: 64 }
+}
+
+// WriteHeavy
+//	5% gets and 95% puts over uniformly chosen keys with small values.
+func WriteHeavy() Workload {
+	return Workload{ Name: "write-heavy", ReadRatio: 0.05, KeyCount: 100000, KeySize: 16, ValueSize: 64 }
+}
+
+// ZipfianKeys
+//	An even mix of gets and puts where a small set of hot keys receives most operations, so concurrent puts contend on the same paths.
+func ZipfianKeys() Workload {
+	return Workload{ Name: "zipfian", ReadRatio: 0.5, KeyCount: 100000, KeySize: 16, ValueSize: 64, Distribution: Zipfian }
+}
+
+// LargeValues
+//	An even mix of gets and puts over uniformly chosen keys with 16KB values.
+func LargeValues() Workload {
+	return Workload{ Name: "large-values", ReadRatio: 0.5, KeyCount: 10000, KeySize: 16, ValueSize: 16384 }
+}
+
+// Workloads
+//	The predefined workloads, in the order they are run by default.
+func Workloads() []Workload {
+	return []Workload{ ReadHeavy(), WriteHeavy(), ZipfianKeys(), LargeValues() }
+}
+
+// NewGenerator
+//	Create a generator for the workload. Generators with the same workload and seed produce the same operations.
+func NewGenerator(workload Workload, seed int64) *Generator {
+	generator := &Generator{ Workload: workload, rand: rand.New(rand.NewSource(seed)) }
+	if workload.Distribution == Zipfian {
+		zipfS := workload.ZipfS
+		if zipfS <= 1 { zipfS = DefaultZipfS }
+
+		generator.zipf = rand.NewZipf(generator.rand, zipfS, 1, uint64(workload.KeyCount - 1))
+	}
+
+	return generator
+}
+
+// Next
+//	Produce the next operation.
+func (generator *Generator) Next() Op {
+	var index int
+	if generator.zipf != nil {
+		index = int(generator.zipf.Uint64())
+	} else { index = generator.rand.Intn(generator.Workload.KeyCount) }
+
+	key := Key(generator.Workload, index)
+	if generator.rand.Float64() < generator.Workload.ReadRatio { return Op{ Kind: OpGet, Key: key } }
+
+	return Op{ Kind: OpPut, Key: key, Value: generator.value() }
+}
+
+// Key
+//	The key at index in the key space of the workload, the index zero padded to the key size.
+func Key(workload Workload, index int) []byte {
+	return []byte(fmt.Sprintf("%0*d", workload.KeySize, index))
+}
+
+// value
+//	A random value of the value size of the workload.
+func (generator *Generator) value() []byte {
+	value := make([]byte, generator.Workload.ValueSize)
+	generator.rand.Read(value)
+	return value
+}
+
+// Load
+//	Put every key of the workload with a random value, committing loadBatchSize keys per version.
+func Load(mmcMap *mmcmap.MMCMap, workload Workload, seed int64) error {
+	generator := NewGenerator(workload, seed)
+
+	for start := 0; start < workload.KeyCount; start += loadBatchSize {
+		batch := mmcMap.NewWriteBatch()
+		for index := start; index < start + loadBatchSize && index < workload.KeyCount; index++ {
+			batch.Put(Key(workload, index), generator.value())
+		}
+
+		_, commitErr := batch.Commit()
+		if commitErr != nil { return commitErr }
+	}
+
+	return nil
+}
+
+// Run
+//	Load the keys of the workload into the map, then run the operations split evenly across the go routines and measure each one.
+//	The map should be empty, or hold only keys of the workload, for results to be comparable across runs.
+func Run(mmcMap *mmcmap.MMCMap, workload Workload, opts RunOpts) (*Result, error) {
+	if opts.Ops <= 0 { opts.Ops = DefaultOps }
+	if opts.Concurrency <= 0 { opts.Concurrency = 1 }
+	if workload.KeyCount <= 0 { return nil, fmt.Errorf("workload %s has no keys", workload.Name) }
+
+	loadErr := Load(mmcMap, workload, opts.Seed)
+	if loadErr != nil { return nil, loadErr }
+
+	prevStats, prevStatsErr := mmcMap.Stats()
+	if prevStatsErr != nil { return nil, prevStatsErr }
+
+	var readHistogram, writeHistogram mmcmap.LatencyHistogram
+	var wg sync.WaitGroup
+	errs := make([]error, opts.Concurrency)
+
+	start := time.Now()
+	for worker := 0; worker < opts.Concurrency; worker++ {
+		ops := opts.Ops / opts.Concurrency
+		if worker < opts.Ops % opts.Concurrency { ops++ }
+
+		wg.Add(1)
+		go func(worker, ops int) {
+			defer wg.Done()
+			errs[worker] = runWorker(mmcMap, NewGenerator(workload, opts.Seed + int64(worker) + 1), ops, &readHistogram, &writeHistogram)
+		}(worker, ops)
+	}
+
+	wg.Wait()
+	duration := time.Since(start)
+
+	for _, err := range errs {
+		if err != nil { return nil, err }
+	}
+
+	stats, statsErr := mmcMap.Stats()
+	if statsErr != nil { return nil, statsErr }
+
+	result := &Result{
+		Workload: workload.Name,
+		Concurrency: opts.Concurrency,
+		Duration: duration,
+		ReadLatency: readHistogram.Summary(),
+		WriteLatency: writeHistogram.Summary(),
+		FileSize: stats.FileSize,
+	}
+
+	result.WriteLatency.Retries = stats.CommitRetries - prevStats.CommitRetries
+	result.Reads = result.ReadLatency.Count
+	result.Writes = result.WriteLatency.Count
+	result.Ops = result.Reads + result.Writes
+	if duration > 0 { result.Throughput = float64(result.Ops) / duration.Seconds() }
+
+	return result, nil
+}
+
+// runWorker
+//	Run ops operations from the generator, recording the latency of each in the histogram for its kind.
+func runWorker(mmcMap *mmcmap.MMCMap, generator *Generator, ops int, readHistogram, writeHistogram *mmcmap.LatencyHistogram) error {
+	for idx := 0; idx < ops; idx++ {
+		op := generator.Next()
+		start := time.Now()
+
+		switch op.Kind {
+			case OpGet:
+				_, getErr := mmcMap.Get(op.Key)
+				if getErr != nil { return getErr }
+
+				readHistogram.Record(time.Since(start), 0)
+			case OpPut:
+				_, putErr := mmcMap.Put(op.Key, op.Value)
+				if putErr != nil { return putErr }
+
+				writeHistogram.Record(time.Since(start), 0)
+		}
+	}
+
+	return nil
+}
+
+// WriteCSV
+//	Write the results as CSV with a header row, one row per result, with latencies in microseconds.
+func WriteCSV(w io.Writer, results []*Result) error {
+	writer := csv.NewWriter(w)
+
+	header := []string{
+		"workload", "concurrency", "ops", "reads", "writes", "duration_ms", "ops_per_sec",
+		"read_mean_us", "read_p50_us", "read_p99_us", "read_max_us",
+		"write_mean_us", "write_p50_us", "write_p99_us", "write_max_us", "write_retries", "file_size",
+	}
+
+	writeErr := writer.Write(header)
+	if writeErr != nil { return writeErr }
+
+	micros := func(d time.Duration) string { return strconv.FormatFloat(float64(d) / float64(time.Microsecond), 'f', 2, 64) }
+
+	for _, result := range results {
+		row := []string{
+			result.Workload,
+			strconv.Itoa(result.Concurrency),
+			strconv.FormatUint(result.Ops, 10),
+			strconv.FormatUint(result.Reads, 10),
+			strconv.FormatUint(result.Writes, 10),
+			strconv.FormatInt(result.Duration.Milliseconds(), 10),
+			strconv.FormatFloat(result.Throughput, 'f', 2, 64),
+			micros(result.ReadLatency.Mean), micros(result.ReadLatency.P50), micros(result.ReadLatency.P99), micros(result.ReadLatency.Max),
+			micros(result.WriteLatency.Mean), micros(result.WriteLatency.P50), micros(result.WriteLatency.P99), micros(result.WriteLatency.Max),
+			strconv.FormatUint(result.WriteLatency.Retries, 10),
+			strconv.FormatUint(result.FileSize, 10),
+		}
+
+		writeErr = writer.Write(row)
+		if writeErr != nil { return writeErr }
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
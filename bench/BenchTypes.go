@@ -0,0 +1,109 @@
+package bench
+
+import "math/rand"
+import "time"
+
+import "github.com/sirgallo/mmcmap"
+
+
+// Workload describes the operations a benchmark runs against a map
+type Workload struct {
+	// Name: identifies the workload in results
+	Name string
+	// ReadRatio: the fraction of operations that are gets, the rest are puts
+	ReadRatio float64
+	// KeyCount: the number of distinct keys, all of which are loaded before the workload runs
+	KeyCount int
+	// KeySize: the length of each key in bytes
+	KeySize int
+	// ValueSize: the length of each value in bytes
+	ValueSize int
+	// Distribution: how keys are chosen for each operation
+	Distribution Distribution
+	// ZipfS: the skew of the zipfian distribution, which must be greater than 1. Defaults to DefaultZipfS
+	ZipfS float64
+}
+
+// Distribution determines how a generator chooses the key of each operation
+type Distribution int
+
+// Op is a single operation produced by a Generator
+type Op struct {
+	// Kind: whether the operation is a get or a put
+	Kind OpKind
+	// Key: the key operated on
+	Key []byte
+	// Value: the value put, nil for gets
+	Value []byte
+}
+
+// OpKind is the kind of an Op
+type OpKind int
+
+// Generator produces a reproducible sequence of operations for a workload. A Generator is not safe for concurrent use
+type Generator struct {
+	// Workload: the workload the operations are drawn from
+	Workload Workload
+	// rand: the source of every choice, seeded so runs with the same seed produce the same operations
+	rand *rand.Rand
+	// zipf: draws key indexes for the zipfian distribution, nil otherwise
+	zipf *rand.Zipf
+}
+
+// RunOpts are the parameters of a benchmark run
+type RunOpts struct {
+	// Ops: the total number of operations run after the keys are loaded. Defaults to DefaultOps
+	Ops int
+	// Concurrency: the number of go routines the operations are split across. Defaults to 1
+	Concurrency int
+	// Seed: seeds the generators, so runs with the same seed and workload produce the same operations. Each go routine uses Seed plus its index
+	Seed int64
+}
+
+// Result is the outcome of running a workload
+type Result struct {
+	// Workload: the name of the workload
+	Workload string
+	// Concurrency: the number of go routines the operations were split across
+	Concurrency int
+	// Ops: the number of operations run
+	Ops uint64
+	// Reads: the number of gets run
+	Reads uint64
+	// Writes: the number of puts run
+	Writes uint64
+	// Duration: the wall clock time taken by the operations, excluding loading the keys
+	Duration time.Duration
+	// Throughput: operations per second
+	Throughput float64
+	// ReadLatency: the latency of the gets
+	ReadLatency mmcmap.OpLatency
+	// WriteLatency: the latency of the puts, along with the commit attempts they retried
+	WriteLatency mmcmap.OpLatency
+	// FileSize: the size of the file once the workload finished
+	FileSize uint64
+}
+
+
+const (
+	// Uniform: every key is equally likely
+	Uniform Distribution = iota
+	// Zipfian: a small set of hot keys receives most operations
+	Zipfian
+)
+
+const (
+	// OpGet: the operation reads the key
+	OpGet OpKind = iota
+	// OpPut: the operation writes the value to the key
+	OpPut
+)
+
+const (
+	// DefaultOps: the number of operations run when RunOpts.Ops is not set
+	DefaultOps = 100000
+	// DefaultZipfS: the skew of the zipfian distribution when Workload.ZipfS is not set
+	DefaultZipfS = 1.1
+	// loadBatchSize: the number of keys committed per version while loading
+	loadBatchSize = 1000
+)
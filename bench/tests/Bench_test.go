@@ -0,0 +1,87 @@
+package benchtests
+
+import "bytes"
+import "encoding/csv"
+import "fmt"
+import "os"
+import "path/filepath"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+import "github.com/sirgallo/mmcmap/bench"
+
+
+var benchTestPath = filepath.Join(os.TempDir(), "testbench")
+var benchTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initBenchMapErr error
+	os.Remove(benchTestPath)
+
+	benchTestMap, initBenchMapErr = mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: benchTestPath })
+	if initBenchMapErr != nil { panic(initBenchMapErr.Error()) }
+
+	fmt.Println("bench test mmcmap initialized")
+}
+
+
+func TestBench(t *testing.T) {
+	defer benchTestMap.Remove()
+
+	t.Run("Test Reproducible Generator", func(t *testing.T) {
+		for _, workload := range bench.Workloads() {
+			genA, genB := bench.NewGenerator(workload, 42), bench.NewGenerator(workload, 42)
+			for idx := 0; idx < 1000; idx++ {
+				opA, opB := genA.Next(), genB.Next()
+				if opA.Kind != opB.Kind || ! bytes.Equal(opA.Key, opB.Key) || ! bytes.Equal(opA.Value, opB.Value) {
+					t.Fatalf("%s op %d differs for the same seed", workload.Name, idx)
+				}
+
+				if len(opA.Key) != workload.KeySize { t.Fatalf("key size mismatch: actual(%d), expected(%d)", len(opA.Key), workload.KeySize) }
+				if opA.Kind == bench.OpPut && len(opA.Value) != workload.ValueSize {
+					t.Fatalf("value size mismatch: actual(%d), expected(%d)", len(opA.Value), workload.ValueSize)
+				}
+			}
+		}
+	})
+
+	t.Run("Test Zipfian Skew", func(t *testing.T) {
+		generator := bench.NewGenerator(bench.ZipfianKeys(), 1)
+		hottest := string(bench.Key(bench.ZipfianKeys(), 0))
+
+		hits := 0
+		for idx := 0; idx < 10000; idx++ {
+			if string(generator.Next().Key) == hottest { hits++ }
+		}
+
+		if hits < 1000 { t.Errorf("hottest key not skewed: actual(%d), expected(>= 1000)", hits) }
+	})
+
+	t.Run("Test Run", func(t *testing.T) {
+		workload := bench.WriteHeavy()
+		workload.KeyCount = 2000
+
+		result, runErr := bench.Run(benchTestMap, workload, bench.RunOpts{ Ops: 5000, Concurrency: 4, Seed: 1 })
+		if runErr != nil { t.Fatalf("error on run: %s", runErr.Error()) }
+
+		if result.Ops != 5000 { t.Errorf("ops mismatch: actual(%d), expected(5000)", result.Ops) }
+		if result.Reads + result.Writes != result.Ops { t.Errorf("reads and writes do not sum to ops: %d + %d", result.Reads, result.Writes) }
+		if result.Writes < result.Reads { t.Errorf("write heavy workload ran more reads: actual(%d), writes(%d)", result.Reads, result.Writes) }
+		if result.Throughput <= 0 { t.Errorf("throughput not measured: actual(%f)", result.Throughput) }
+
+		count, lenErr := benchTestMap.Len()
+		if lenErr != nil { t.Fatalf("error on len: %s", lenErr.Error()) }
+		if count != 2000 { t.Errorf("key count mismatch: actual(%d), expected(2000)", count) }
+
+		var buf bytes.Buffer
+		writeErr := bench.WriteCSV(&buf, []*bench.Result{ result })
+		if writeErr != nil { t.Fatalf("error writing csv: %s", writeErr.Error()) }
+
+		records, readErr := csv.NewReader(&buf).ReadAll()
+		if readErr != nil { t.Fatalf("error reading csv: %s", readErr.Error()) }
+		if len(records) != 2 || records[1][0] != workload.Name || records[1][2] != "5000" { t.Errorf("csv mismatch: actual(%v)", records) }
+	})
+
+	t.Log("Done")
+}
@@ -0,0 +1,207 @@
+package main
+
+import "encoding/base64"
+import "encoding/hex"
+import "encoding/json"
+import "fmt"
+import "os"
+
+import "github.com/sirgallo/mmcmap"
+
+
+//============================================= MMCMap CLI Commands
+
+
+// runGet
+//	Print the value of the key, failing if it does not exist.
+func runGet(mmcMap *mmcmap.MMCMap, env *cliEnv, args []string) error {
+	if len(args) != 1 { return errUsage }
+
+	key, decKeyErr := decode(env.Encoding, args[0])
+	if decKeyErr != nil { return decKeyErr }
+
+	value, getErr := mmcMap.Get(key)
+	if getErr != nil { return getErr }
+
+	_, writeErr := fmt.Fprintln(env.Stdout, encode(env.Encoding, value))
+	return writeErr
+}
+
+// runPut
+//	Insert or update the key.
+func runPut(mmcMap *mmcmap.MMCMap, env *cliEnv, args []string) error {
+	if len(args) != 2 { return errUsage }
+
+	key, decKeyErr := decode(env.Encoding, args[0])
+	if decKeyErr != nil { return decKeyErr }
+
+	value, decValErr := decode(env.Encoding, args[1])
+	if decValErr != nil { return decValErr }
+
+	_, putErr := mmcMap.Put(key, value)
+	return putErr
+}
+
+// runDel
+//	Delete the key, failing if it does not exist.
+func runDel(mmcMap *mmcmap.MMCMap, env *cliEnv, args []string) error {
+	if len(args) != 1 { return errUsage }
+
+	key, decKeyErr := decode(env.Encoding, args[0])
+	if decKeyErr != nil { return decKeyErr }
+
+	deleted, delErr := mmcMap.Delete(key)
+	if delErr != nil { return delErr }
+	if ! deleted { return mmcmap.ErrKeyNotFound }
+
+	return nil
+}
+
+// runScan
+//	Print the pairs between the bounds in sorted order, a page at a time so large ranges are not collected in memory.
+func runScan(mmcMap *mmcmap.MMCMap, env *cliEnv, args []string) error {
+	if len(args) != 0 { return errUsage }
+
+	startKey, decStartErr := decodeBound(env.Encoding, env.Start)
+	if decStartErr != nil { return decStartErr }
+
+	endKey, decEndErr := decodeBound(env.Encoding, env.End)
+	if decEndErr != nil { return decEndErr }
+
+	opts := mmcmap.RangeOpts{ KeysOnly: env.KeysOnly }
+	written := 0
+
+	for {
+		opts.Limit = scanPageSize
+		if env.Limit > 0 && env.Limit - written < scanPageSize { opts.Limit = env.Limit - written }
+
+		pairs, next, rangeErr := mmcMap.RangePage(startKey, endKey, opts)
+		if rangeErr != nil { return rangeErr }
+
+		for _, pair := range pairs {
+			var writeErr error
+			if env.KeysOnly {
+				_, writeErr = fmt.Fprintln(env.Stdout, encode(env.Encoding, pair.Key))
+			} else { _, writeErr = fmt.Fprintf(env.Stdout, "%s\t%s\n", encode(env.Encoding, pair.Key), encode(env.Encoding, pair.Value)) }
+
+			if writeErr != nil { return writeErr }
+		}
+
+		written += len(pairs)
+		if next == nil || (env.Limit > 0 && written >= env.Limit) { return nil }
+
+		opts.After = next
+	}
+}
+
+// runStats
+//	Print the stats of the map as indented JSON.
+func runStats(mmcMap *mmcmap.MMCMap, env *cliEnv, args []string) error {
+	if len(args) != 0 { return errUsage }
+
+	stats, statsErr := mmcMap.Stats()
+	if statsErr != nil { return statsErr }
+
+	return writeJSON(env, stats)
+}
+
+// runVerify
+//	Print the verification report of the latest version as indented JSON, failing if the trie is invalid.
+func runVerify(mmcMap *mmcmap.MMCMap, env *cliEnv, args []string) error {
+	if len(args) != 0 { return errUsage }
+
+	report, verifyErr := mmcMap.Verify()
+	if verifyErr != nil { return verifyErr }
+
+	writeErr := writeJSON(env, report)
+	if writeErr != nil { return writeErr }
+	if ! report.IsValid { return fmt.Errorf("found %d inconsistencies", len(report.Findings)) }
+
+	return nil
+}
+
+// runCompact
+//	Compact the map and print the report as indented JSON.
+func runCompact(mmcMap *mmcmap.MMCMap, env *cliEnv, args []string) error {
+	if len(args) != 0 { return errUsage }
+
+	report, compactErr := mmcMap.Compact()
+	if compactErr != nil { return compactErr }
+
+	return writeJSON(env, report)
+}
+
+// runBackup
+//	Write a backup of the changes since the version to the output file, or stdout. The report is only printed when writing to a file,
+//	so the backup can be piped.
+func runBackup(mmcMap *mmcmap.MMCMap, env *cliEnv, args []string) error {
+	if len(args) != 0 { return errUsage }
+	if env.File == "" {
+		_, backupErr := mmcMap.BackupSince(env.Stdout, env.Since)
+		return backupErr
+	}
+
+	file, createErr := os.Create(env.File)
+	if createErr != nil { return createErr }
+
+	report, backupErr := mmcMap.BackupSince(file, env.Since)
+	closeErr := file.Close()
+	if backupErr != nil { return backupErr }
+	if closeErr != nil { return closeErr }
+
+	return writeJSON(env, report)
+}
+
+// runRestore
+//	Apply a backup from the input file, or stdin.
+func runRestore(mmcMap *mmcmap.MMCMap, env *cliEnv, args []string) error {
+	if len(args) != 0 { return errUsage }
+	if env.File == "" { return mmcMap.RestoreBackup(env.Stdin) }
+
+	file, openErr := os.Open(env.File)
+	if openErr != nil { return openErr }
+	defer file.Close()
+
+	return mmcMap.RestoreBackup(file)
+}
+
+// writeJSON
+//	Write the value as indented JSON.
+func writeJSON(env *cliEnv, value interface{}) error {
+	encoder := json.NewEncoder(env.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// decodeBound
+//	Decode a scan bound, where an empty bound is unbounded.
+func decodeBound(encoding string, bound string) ([]byte, error) {
+	if bound == "" { return nil, nil }
+	return decode(encoding, bound)
+}
+
+// encode
+//	Encode a key or value for output using the encoding.
+func encode(encoding string, data []byte) string {
+	switch encoding {
+		case EncodingBase64:
+			return base64.StdEncoding.EncodeToString(data)
+		case EncodingHex:
+			return hex.EncodeToString(data)
+		default:
+			return string(data)
+	}
+}
+
+// decode
+//	Decode a key or value from an argument using the encoding.
+func decode(encoding string, data string) ([]byte, error) {
+	switch encoding {
+		case EncodingBase64:
+			return base64.StdEncoding.DecodeString(data)
+		case EncodingHex:
+			return hex.DecodeString(data)
+		default:
+			return []byte(data), nil
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import "errors"
+import "flag"
+import "fmt"
+import "io"
+import "os"
+import "sort"
+
+import "github.com/sirgallo/mmcmap"
+
+
+//============================================= MMCMap CLI
+
+
+// errUsage is returned by commands given the wrong arguments, so the usage of the command is printed
+var errUsage = errors.New("invalid arguments")
+
+// commands are the subcommands of the cli, keyed by name
+var commands = map[string]*command{
+	"get": {
+		Usage: "<file> <key>",
+		Description: "print the value of a key",
+		MustExist: true,
+		Run: runGet,
+	},
+	"put": {
+		Usage: "<file> <key> <value>",
+		Description: "insert or update a key",
+		Run: runPut,
+	},
+	"del": {
+		Usage: "<file> <key>",
+		Description: "delete a key",
+		Run: runDel,
+	},
+	"scan": {
+		Usage: "<file>",
+		Description: "print the pairs in a key range, one tab separated pair per line",
+		MustExist: true,
+		Run: runScan,
+		Flags: func(flags *flag.FlagSet, env *cliEnv) {
+			flags.StringVar(&env.Start, "start", "", "inclusive lower bound of the range")
+			flags.StringVar(&env.End, "end", "", "inclusive upper bound of the range")
+			flags.IntVar(&env.Limit, "limit", 0, "max number of pairs, 0 for every pair")
+			flags.BoolVar(&env.KeysOnly, "keys-only", false, "only print keys")
+		},
+	},
+	"stats": {
+		Usage: "<file>",
+		Description: "print the stats of the map as JSON",
+		MustExist: true,
+		Run: runStats,
+	},
+	"verify": {
+		Usage: "<file>",
+		Description: "check the latest version of the trie, exiting with 1 if it is invalid",
+		MustExist: true,
+		Run: runVerify,
+	},
+	"compact": {
+		Usage: "<file>",
+		Description: "copy the latest version into a new file, reclaiming space used by prior versions",
+		MustExist: true,
+		Run: runCompact,
+	},
+	"backup": {
+		Usage: "<file>",
+		Description: "write a full or incremental backup",
+		MustExist: true,
+		Run: runBackup,
+		Flags: func(flags *flag.FlagSet, env *cliEnv) {
+			flags.Uint64Var(&env.Since, "since", 0, "version the backup starts from, 0 for a full backup")
+			flags.StringVar(&env.File, "o", "", "file to write the backup to, stdout when empty")
+		},
+	},
+	"restore": {
+		Usage: "<file>",
+		Description: "apply a backup as a single new version",
+		Run: runRestore,
+		Flags: func(flags *flag.FlagSet, env *cliEnv) {
+			flags.StringVar(&env.File, "i", "", "file to read the backup from, stdin when empty")
+		},
+	},
+}
+
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run
+//	Parse the subcommand and its flags, open the map file, and run the command, returning the exit code.
+//	Every command opens the map lazily, so the background go routines are only started for commands that write.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		printUsage(stderr)
+		return exitUsage
+	}
+
+	name := args[0]
+	if name == "help" || name == "-h" || name == "--help" {
+		printUsage(stdout)
+		return exitOK
+	}
+
+	cmd, ok := commands[name]
+	if ! ok {
+		fmt.Fprintf(stderr, "mmcmap: unknown command %q\n\n", name)
+		printUsage(stderr)
+		return exitUsage
+	}
+
+	env := &cliEnv{ Stdin: stdin, Stdout: stdout }
+
+	flags := flag.NewFlagSet("mmcmap " + name, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&env.Encoding, "encoding", EncodingText, "encoding of keys and values: text, base64, or hex")
+	if cmd.Flags != nil { cmd.Flags(flags, env) }
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "usage: mmcmap %s [flags] %s\n", name, cmd.Usage)
+		flags.PrintDefaults()
+	}
+
+	parseErr := flags.Parse(args[1:])
+	if errors.Is(parseErr, flag.ErrHelp) { return exitOK }
+	if parseErr != nil { return exitUsage }
+
+	if env.Encoding != EncodingText && env.Encoding != EncodingBase64 && env.Encoding != EncodingHex {
+		fmt.Fprintf(stderr, "mmcmap: unknown encoding %q\n", env.Encoding)
+		return exitUsage
+	}
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	runErr := runCommand(cmd, env, flags.Arg(0), flags.Args()[1:])
+	if errors.Is(runErr, errUsage) {
+		flags.Usage()
+		return exitUsage
+	}
+
+	if runErr != nil {
+		fmt.Fprintf(stderr, "mmcmap %s: %s\n", name, runErr.Error())
+		return exitFailed
+	}
+
+	return exitOK
+}
+
+// runCommand
+//	Open the map file and run the command against it. Commands that read or rewrite an existing map refuse to create a missing file.
+func runCommand(cmd *command, env *cliEnv, path string, args []string) error {
+	if cmd.MustExist {
+		_, statErr := os.Stat(path)
+		if statErr != nil { return statErr }
+	}
+
+	mmcMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: path, OpenMode: mmcmap.OpenLazy, StrictGet: true })
+	if openErr != nil { return openErr }
+
+	runErr := cmd.Run(mmcMap, env, args)
+	closeErr := mmcMap.Close()
+	if runErr != nil { return runErr }
+
+	return closeErr
+}
+
+// printUsage
+//	Write the list of commands, sorted by name.
+func printUsage(w io.Writer) {
+	names := make([]string, 0, len(commands))
+	for name := range commands { names = append(names, name) }
+	sort.Strings(names)
+
+	fmt.Fprintln(w, "usage: mmcmap <command> [flags] <file> [args]")
+	fmt.Fprintln(w, "\ncommands:")
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-8s %-22s %s\n", name, commands[name].Usage, commands[name].Description)
+	}
+
+	fmt.Fprintln(w, "\nrun mmcmap <command> -h for the flags of a command")
+}
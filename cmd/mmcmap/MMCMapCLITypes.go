@@ -0,0 +1,67 @@
+package main
+
+import "flag"
+import "io"
+
+import "github.com/sirgallo/mmcmap"
+
+
+// command is a subcommand of the cli, run against a single map file
+type command struct {
+	// Usage: the arguments the command takes after its flags
+	Usage string
+	// Description: a one line summary shown in the help output
+	Description string
+	// MustExist: the command reads or rewrites an existing map, so a missing file is an error instead of being created
+	MustExist bool
+	// Run: executes the command against the opened map with the arguments remaining after the flags and the file
+	Run func(mmcMap *mmcmap.MMCMap, env *cliEnv, args []string) error
+	// Flags: registers the flags of the command on its flag set
+	Flags func(flags *flag.FlagSet, env *cliEnv)
+}
+
+// cliEnv holds the streams and the parsed flags shared by every command
+type cliEnv struct {
+	// Stdin: where restore reads a backup from when no input file is given
+	Stdin io.Reader
+	// Stdout: where results are written
+	Stdout io.Writer
+	// Encoding: how keys and values are read from arguments and written to output: text, base64, or hex
+	Encoding string
+	// Start: the inclusive lower bound of a scan, empty for unbounded
+	Start string
+	// End: the inclusive upper bound of a scan, empty for unbounded
+	End string
+	// Limit: the max number of pairs a scan writes, 0 for every pair
+	Limit int
+	// KeysOnly: scan only writes keys
+	KeysOnly bool
+	// Since: the version an incremental backup starts from, 0 for a full backup
+	Since uint64
+	// File: the file a backup is written to or restored from, stdout or stdin when empty
+	File string
+}
+
+
+const (
+	// EncodingText: keys and values are used as is
+	EncodingText = "text"
+	// EncodingBase64: keys and values are standard base64
+	EncodingBase64 = "base64"
+	// EncodingHex: keys and values are lowercase hex
+	EncodingHex = "hex"
+)
+
+const (
+	// scanPageSize: the number of pairs scan reads from the map at a time
+	scanPageSize = 1024
+)
+
+const (
+	// exitOK: the command succeeded
+	exitOK = 0
+	// exitFailed: the command failed, or found a problem, like a missing key or an invalid trie
+	exitFailed = 1
+	// exitUsage: the arguments were invalid
+	exitUsage = 2
+)
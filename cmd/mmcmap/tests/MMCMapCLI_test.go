@@ -0,0 +1,125 @@
+package mmcmapclitests
+
+import "bytes"
+import "fmt"
+import "os"
+import "os/exec"
+import "path/filepath"
+import "strings"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var cliTestDir = filepath.Join(os.TempDir(), "testcli")
+var cliTestBinary = filepath.Join(cliTestDir, "mmcmap")
+var cliTestPath = filepath.Join(cliTestDir, "testcli")
+
+
+func init() {
+	os.RemoveAll(cliTestDir)
+	os.MkdirAll(cliTestDir, 0700)
+
+	output, buildErr := exec.Command("go", "build", "-o", cliTestBinary, "..").CombinedOutput()
+	if buildErr != nil { panic(fmt.Sprintf("%s: %s", buildErr.Error(), output)) }
+
+	fmt.Println("cli test binary built")
+}
+
+
+func TestMMCMapCLI(t *testing.T) {
+	defer os.RemoveAll(cliTestDir)
+
+	runCLI := func(t *testing.T, stdin string, args ...string) (string, int) {
+		var stdout, stderr bytes.Buffer
+		cmd := exec.Command(cliTestBinary, args...)
+		cmd.Stdin = strings.NewReader(stdin)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+
+		runErr := cmd.Run()
+		if exitErr, ok := runErr.(*exec.ExitError); ok { return stderr.String(), exitErr.ExitCode() }
+		if runErr != nil { t.Fatalf("error running cli: %s", runErr.Error()) }
+
+		return stdout.String(), 0
+	}
+
+	expectOK := func(t *testing.T, stdin string, args ...string) string {
+		output, code := runCLI(t, stdin, args...)
+		if code != 0 { t.Fatalf("%v exited with %d: %s", args, code, output) }
+		return output
+	}
+
+	t.Run("Test Put Get Del", func(t *testing.T) {
+		expectOK(t, "", "put", cliTestPath, "hello", "world")
+		expectOK(t, "", "put", "-encoding", "hex", cliTestPath, "0001", "ff")
+
+		value := expectOK(t, "", "get", cliTestPath, "hello")
+		if value != "world\n" { t.Errorf("value mismatch: actual(%q), expected(%q)", value, "world\n") }
+
+		value = expectOK(t, "", "get", "-encoding", "hex", cliTestPath, "0001")
+		if value != "ff\n" { t.Errorf("hex value mismatch: actual(%q), expected(%q)", value, "ff\n") }
+
+		expectOK(t, "", "del", cliTestPath, "hello")
+
+		output, code := runCLI(t, "", "get", cliTestPath, "hello")
+		if code != 1 || ! strings.Contains(output, mmcmap.ErrKeyNotFound.Error()) {
+			t.Errorf("get of deleted key: actual(%d, %q), expected(1, %q)", code, output, mmcmap.ErrKeyNotFound.Error())
+		}
+	})
+
+	t.Run("Test Scan", func(t *testing.T) {
+		for idx := 0; idx < 5; idx++ {
+			expectOK(t, "", "put", cliTestPath, fmt.Sprintf("scan%d", idx), fmt.Sprintf("value%d", idx))
+		}
+
+		output := expectOK(t, "", "scan", "-start", "scan1", "-end", "scan3", cliTestPath)
+		expected := "scan1\tvalue1\nscan2\tvalue2\nscan3\tvalue3\n"
+		if output != expected { t.Errorf("scan mismatch: actual(%q), expected(%q)", output, expected) }
+
+		output = expectOK(t, "", "scan", "-start", "scan", "-limit", "2", "-keys-only", cliTestPath)
+		if output != "scan0\nscan1\n" { t.Errorf("limited scan mismatch: actual(%q), expected(%q)", output, "scan0\nscan1\n") }
+	})
+
+	t.Run("Test Stats Verify Compact", func(t *testing.T) {
+		output := expectOK(t, "", "stats", cliTestPath)
+		if ! strings.Contains(output, "\"LiveKeys\": 6") { t.Errorf("stats missing key count: actual(%s)", output) }
+
+		output = expectOK(t, "", "verify", cliTestPath)
+		if ! strings.Contains(output, "\"IsValid\": true") { t.Errorf("verify report invalid: actual(%s)", output) }
+
+		output = expectOK(t, "", "compact", cliTestPath)
+		if ! strings.Contains(output, "\"ReclaimedBytes\"") { t.Errorf("compact report missing: actual(%s)", output) }
+	})
+
+	t.Run("Test Backup Restore", func(t *testing.T) {
+		backupPath := filepath.Join(cliTestDir, "backup")
+		expectOK(t, "", "backup", "-o", backupPath, cliTestPath)
+
+		backup, readErr := os.ReadFile(backupPath)
+		if readErr != nil { t.Fatalf("error reading backup: %s", readErr.Error()) }
+
+		restoredPath := filepath.Join(cliTestDir, "restored")
+		expectOK(t, string(backup), "restore", restoredPath)
+
+		expected := expectOK(t, "", "scan", cliTestPath)
+		actual := expectOK(t, "", "scan", restoredPath)
+		if actual != expected { t.Errorf("restored scan mismatch: actual(%q), expected(%q)", actual, expected) }
+	})
+
+	t.Run("Test Usage", func(t *testing.T) {
+		_, code := runCLI(t, "", "frobnicate", cliTestPath)
+		if code != 2 { t.Errorf("unknown command exit code: actual(%d), expected(2)", code) }
+
+		_, code = runCLI(t, "", "get", cliTestPath)
+		if code != 2 { t.Errorf("missing key exit code: actual(%d), expected(2)", code) }
+
+		_, code = runCLI(t, "", "get", filepath.Join(cliTestDir, "missing"), "key")
+		if code != 1 { t.Errorf("missing file exit code: actual(%d), expected(1)", code) }
+
+		_, statErr := os.Stat(filepath.Join(cliTestDir, "missing"))
+		if ! os.IsNotExist(statErr) { t.Errorf("get created the missing file") }
+	})
+
+	t.Log("Done")
+}
@@ -0,0 +1,127 @@
+package main
+
+import "errors"
+import "flag"
+import "fmt"
+import "io"
+import "os"
+import "path/filepath"
+import "strings"
+
+import "github.com/sirgallo/mmcmap"
+import "github.com/sirgallo/mmcmap/bench"
+
+
+//============================================= MMCMap Bench CLI
+
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run
+//	Run each selected workload against a fresh map and write the results as CSV, returning the exit code.
+func run(args []string, stdout, stderr io.Writer) int {
+	var workloadNames, dir, output string
+	var ops, concurrency, keyCount, valueSize int
+	var seed int64
+
+	flags := flag.NewFlagSet("mmcmapbench", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&workloadNames, "workloads", "", "comma separated workloads to run, every workload when empty: " + strings.Join(workloadList(), ", "))
+	flags.IntVar(&ops, "ops", bench.DefaultOps, "operations per workload")
+	flags.IntVar(&concurrency, "concurrency", 1, "go routines the operations are split across")
+	flags.Int64Var(&seed, "seed", 1, "seed for the generated operations")
+	flags.IntVar(&keyCount, "keys", 0, "override the number of keys of every workload")
+	flags.IntVar(&valueSize, "value-size", 0, "override the value size of every workload")
+	flags.StringVar(&dir, "dir", os.TempDir(), "directory the map files are created in")
+	flags.StringVar(&output, "o", "", "file to write the CSV to, stdout when empty")
+
+	parseErr := flags.Parse(args)
+	if errors.Is(parseErr, flag.ErrHelp) { return 0 }
+	if parseErr != nil { return 2 }
+
+	workloads, selectErr := selectWorkloads(workloadNames)
+	if selectErr != nil {
+		fmt.Fprintf(stderr, "mmcmapbench: %s\n", selectErr.Error())
+		return 2
+	}
+
+	var results []*bench.Result
+	for _, workload := range workloads {
+		if keyCount > 0 { workload.KeyCount = keyCount }
+		if valueSize > 0 { workload.ValueSize = valueSize }
+
+		result, runErr := runWorkload(workload, dir, bench.RunOpts{ Ops: ops, Concurrency: concurrency, Seed: seed })
+		if runErr != nil {
+			fmt.Fprintf(stderr, "mmcmapbench: %s: %s\n", workload.Name, runErr.Error())
+			return 1
+		}
+
+		fmt.Fprintf(stderr, "%s: %.0f ops/sec\n", workload.Name, result.Throughput)
+		results = append(results, result)
+	}
+
+	writeErr := writeResults(output, stdout, results)
+	if writeErr != nil {
+		fmt.Fprintf(stderr, "mmcmapbench: %s\n", writeErr.Error())
+		return 1
+	}
+
+	return 0
+}
+
+// runWorkload
+//	Run the workload against a new map in dir, removing the map afterwards.
+func runWorkload(workload bench.Workload, dir string, opts bench.RunOpts) (*bench.Result, error) {
+	path := filepath.Join(dir, "mmcmapbench-" + workload.Name)
+	os.Remove(path)
+
+	mmcMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: path })
+	if openErr != nil { return nil, openErr }
+	defer mmcMap.Remove()
+
+	return bench.Run(mmcMap, workload, opts)
+}
+
+// selectWorkloads
+//	The workloads named in the comma separated list, or every workload for an empty list.
+func selectWorkloads(names string) ([]bench.Workload, error) {
+	if names == "" { return bench.Workloads(), nil }
+
+	byName := make(map[string]bench.Workload)
+	for _, workload := range bench.Workloads() { byName[workload.Name] = workload }
+
+	var workloads []bench.Workload
+	for _, name := range strings.Split(names, ",") {
+		workload, ok := byName[strings.TrimSpace(name)]
+		if ! ok { return nil, fmt.Errorf("unknown workload %q", name) }
+
+		workloads = append(workloads, workload)
+	}
+
+	return workloads, nil
+}
+
+// workloadList
+//	The names of the predefined workloads.
+func workloadList() []string {
+	var names []string
+	for _, workload := range bench.Workloads() { names = append(names, workload.Name) }
+	return names
+}
+
+// writeResults
+//	Write the results as CSV to the output file, or stdout when no file is given.
+func writeResults(output string, stdout io.Writer, results []*bench.Result) error {
+	if output == "" { return bench.WriteCSV(stdout, results) }
+
+	file, createErr := os.Create(output)
+	if createErr != nil { return createErr }
+
+	writeErr := bench.WriteCSV(file, results)
+	closeErr := file.Close()
+	if writeErr != nil { return writeErr }
+
+	return closeErr
+}
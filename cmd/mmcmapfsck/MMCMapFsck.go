@@ -0,0 +1,113 @@
+package main
+
+import "encoding/json"
+import "errors"
+import "flag"
+import "fmt"
+import "io"
+import "os"
+
+import "github.com/sirgallo/mmcmap"
+
+
+//============================================= MMCMap Fsck CLI
+
+
+const (
+	// exitClean: every version is intact and the metadata points at the newest one
+	exitClean = 0
+	// exitCorrupt: the check found inconsistencies, or the newest intact version is not the one in the metadata
+	exitCorrupt = 1
+	// exitFailed: the arguments were invalid or the file could not be checked
+	exitFailed = 2
+)
+
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run
+//	Check the file named by the arguments and write the report, returning the exit code.
+func run(args []string, stdout, stderr io.Writer) int {
+	var recoverTo string
+	var asJSON, verbose bool
+
+	flags := flag.NewFlagSet("mmcmapfsck", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&recoverTo, "recover", "", "write a compacted copy of the newest intact version to this new file")
+	flags.BoolVar(&asJSON, "json", false, "write the full report as JSON")
+	flags.BoolVar(&verbose, "v", false, "list every version, not just the corrupt ones")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: mmcmapfsck [flags] <file>")
+		flags.PrintDefaults()
+	}
+
+	parseErr := flags.Parse(args)
+	if errors.Is(parseErr, flag.ErrHelp) { return exitClean }
+	if parseErr != nil { return exitFailed }
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return exitFailed
+	}
+
+	report, fsckErr := mmcmap.FsckFile(mmcmap.MMCMapOpts{ Filepath: flags.Arg(0) }, mmcmap.FsckOpts{ RecoverTo: recoverTo })
+	if fsckErr != nil {
+		fmt.Fprintf(stderr, "mmcmapfsck: %s\n", fsckErr.Error())
+		return exitFailed
+	}
+
+	var writeErr error
+	if asJSON {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		writeErr = encoder.Encode(report)
+	} else { writeErr = writeReport(stdout, report, verbose) }
+
+	if writeErr != nil {
+		fmt.Fprintf(stderr, "mmcmapfsck: %s\n", writeErr.Error())
+		return exitFailed
+	}
+
+	if ! report.IsClean { return exitCorrupt }
+	return exitClean
+}
+
+// writeReport
+//	Write a summary of the report, followed by the findings of each corrupt version, or of every version when verbose.
+func writeReport(w io.Writer, report *mmcmap.FsckReport, verbose bool) error {
+	var writeErr error
+	printf := func(format string, args ...interface{}) {
+		if writeErr != nil { return }
+		_, writeErr = fmt.Fprintf(w, format, args...)
+	}
+
+	printf("metadata version:      %d (root at %d)\n", report.MetaVersion, report.MetaRootOffset)
+	printf("versions found:        %d valid, %d corrupt\n", report.ValidVersions, report.CorruptVersions)
+	printf("latest valid version:  %d\n", report.LatestValidVersion)
+	printf("nodes checked:         %d\n", report.NodesVisited)
+	printf("scanned bytes:         %d\n", report.ScannedBytes)
+	printf("reachable bytes:       %d\n", report.ReachableBytes)
+	printf("unreadable bytes:      %d\n", report.UnreadableBytes)
+
+	for _, finding := range report.HeaderFindings { printf("header: %s\n", finding) }
+
+	for _, version := range report.Versions {
+		if version.IsValid && ! verbose { continue }
+
+		status := "ok"
+		if ! version.IsValid { status = "corrupt" }
+
+		printf("version %d at %d: %s, %d keys, %d bytes\n", version.Version, version.RootOffset, status, version.KeysVisited, version.ReachableBytes)
+		for _, finding := range version.Findings { printf("  %s\n", finding) }
+	}
+
+	if report.RecoveredPath != "" { printf("recovered version %d to %s\n", report.LatestValidVersion, report.RecoveredPath) }
+
+	if report.IsClean {
+		printf("clean\n")
+	} else { printf("not clean\n") }
+
+	return writeErr
+}
@@ -0,0 +1,93 @@
+package mmcmapfscktests
+
+import "fmt"
+import "os"
+import "os/exec"
+import "path/filepath"
+import "strings"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var fsckCLITestDir = filepath.Join(os.TempDir(), "testfsckcli")
+var fsckCLITestBinary = filepath.Join(fsckCLITestDir, "mmcmapfsck")
+var fsckCLITestPath = filepath.Join(fsckCLITestDir, "testfsckcli")
+
+
+func init() {
+	os.RemoveAll(fsckCLITestDir)
+	os.MkdirAll(fsckCLITestDir, 0700)
+
+	output, buildErr := exec.Command("go", "build", "-o", fsckCLITestBinary, "..").CombinedOutput()
+	if buildErr != nil { panic(fmt.Sprintf("%s: %s", buildErr.Error(), output)) }
+
+	fmt.Println("fsck cli test binary built")
+}
+
+
+func TestMMCMapFsckCLI(t *testing.T) {
+	defer os.RemoveAll(fsckCLITestDir)
+
+	runFsck := func(t *testing.T, args ...string) (string, int) {
+		output, runErr := exec.Command(fsckCLITestBinary, args...).CombinedOutput()
+		if exitErr, ok := runErr.(*exec.ExitError); ok { return string(output), exitErr.ExitCode() }
+		if runErr != nil { t.Fatalf("error running fsck: %s", runErr.Error()) }
+
+		return string(output), 0
+	}
+
+	mmcMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: fsckCLITestPath })
+	if openErr != nil { t.Fatalf("error opening map: %s", openErr.Error()) }
+
+	for idx := 0; idx < 50; idx++ {
+		_, putErr := mmcMap.Put([]byte(fmt.Sprintf("key%d", idx)), []byte("value"))
+		if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+	}
+
+	meta, readMetaErr := mmcMap.ReadMetaFromMemMap()
+	if readMetaErr != nil { t.Fatalf("error reading metadata: %s", readMetaErr.Error()) }
+
+	closeErr := mmcMap.Close()
+	if closeErr != nil { t.Fatalf("error on close: %s", closeErr.Error()) }
+
+	t.Run("Test Clean", func(t *testing.T) {
+		output, code := runFsck(t, fsckCLITestPath)
+		if code != 0 { t.Errorf("clean exit code: actual(%d), expected(0): %s", code, output) }
+		if ! strings.Contains(output, fmt.Sprintf("latest valid version:  %d", meta.Version)) { t.Errorf("latest version missing: %s", output) }
+
+		output, code = runFsck(t, "-json", fsckCLITestPath)
+		if code != 0 || ! strings.Contains(output, "\"IsClean\": true") { t.Errorf("json report mismatch: actual(%d, %s)", code, output) }
+	})
+
+	t.Run("Test Corrupt", func(t *testing.T) {
+		file, fileErr := os.OpenFile(fsckCLITestPath, os.O_RDWR, 0600)
+		if fileErr != nil { t.Fatalf("error opening file: %s", fileErr.Error()) }
+
+		_, writeErr := file.WriteAt([]byte{ 1, 2, 3, 4, 5, 6, 7, 8 }, int64(meta.RootOffset) + mmcmap.NodeStartOffsetIdx)
+		file.Close()
+		if writeErr != nil { t.Fatalf("error corrupting root: %s", writeErr.Error()) }
+
+		recoverPath := filepath.Join(fsckCLITestDir, "recovered")
+		output, code := runFsck(t, "-recover", recoverPath, fsckCLITestPath)
+		if code != 1 { t.Errorf("corrupt exit code: actual(%d), expected(1): %s", code, output) }
+
+		recovered, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: recoverPath })
+		if openErr != nil { t.Fatalf("error opening recovered file: %s", openErr.Error()) }
+		defer recovered.Close()
+
+		count, lenErr := recovered.Len()
+		if lenErr != nil { t.Fatalf("error on len: %s", lenErr.Error()) }
+		if count != 49 { t.Errorf("recovered key count mismatch: actual(%d), expected(49)", count) }
+	})
+
+	t.Run("Test Usage", func(t *testing.T) {
+		_, code := runFsck(t)
+		if code != 2 { t.Errorf("usage exit code: actual(%d), expected(2)", code) }
+
+		_, code = runFsck(t, filepath.Join(fsckCLITestDir, "missing"))
+		if code != 2 { t.Errorf("missing file exit code: actual(%d), expected(2)", code) }
+	})
+
+	t.Log("Done")
+}
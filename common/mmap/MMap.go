@@ -43,6 +43,17 @@ func MapRegion(file *os.File, length int, prot, flags int, offset int64) (MMap,
 // mmapHelper 
 //	Utility function for mmap.
 func mmapHelper(length int, inprot, inflags, fileDescriptor uintptr, offset int64) ([]byte, error) {
+	prot, flags := protAndFlags(inprot, inflags)
+
+	bytes, mmapErr := unix.Mmap(int(fileDescriptor), offset, length, prot, flags)
+	if mmapErr != nil { return nil, mmapErr }
+	
+	return bytes, nil
+}
+
+// protAndFlags
+//	Translates the protection and flags of this package into those of mmap.
+func protAndFlags(inprot, inflags uintptr) (int, int) {
 	flags := unix.MAP_SHARED
 	prot := unix.PROT_READ
 	
@@ -57,10 +68,7 @@ func mmapHelper(length int, inprot, inflags, fileDescriptor uintptr, offset int6
 	if inprot & EXEC != 0 { prot |= unix.PROT_EXEC }
 	if inflags & ANON != 0 { flags |= unix.MAP_ANON }
 
-	bytes, mmapErr := unix.Mmap(int(fileDescriptor), offset, length, prot, flags)
-	if mmapErr != nil { return nil, mmapErr }
-	
-	return bytes, nil
+	return prot, flags
 }
 
 // Flush
@@ -69,6 +77,25 @@ func (mapped MMap) Flush() error {
 	return unix.Msync(mapped, unix.MS_SYNC)
 }
 
+// Lock
+//	Locks the pages of the byte slice into RAM, faulting in any that are not resident. Pages stay locked until they are unlocked or unmapped.
+func (mapped MMap) Lock() error {
+	return unix.Mlock(mapped)
+}
+
+// Unlock
+//	Allows the pages of the byte slice to be paged out again.
+func (mapped MMap) Unlock() error {
+	return unix.Munlock(mapped)
+}
+
+// Prefetch
+//	Advises the kernel that the pages of the byte slice will be read soon, so they are read in ahead of the accesses instead of faulting in one at a
+//	time. The byte slice must start on a page boundary.
+func (mapped MMap) Prefetch() error {
+	return unix.Madvise(mapped, unix.MADV_WILLNEED)
+}
+
 // Unmap 
 //	Unmaps the byte slice from the memory mapped file.
 func (mapped MMap) Unmap() error {
@@ -0,0 +1,82 @@
+//go:build linux
+
+package mmap
+
+import "errors"
+import "os"
+import "unsafe"
+
+import "golang.org/x/sys/unix"
+
+
+//============================================= MMap Fixed Address Mapping (Linux)
+
+
+// Reserve
+//	Reserves length bytes of address space without backing them with memory, so a file can be mapped into it region by region with MapRegionAt.
+//	Accessing the reservation outside of a mapped region faults. Unmapping the reservation also unmaps every region mapped into it.
+func Reserve(length int) (MMap, error) {
+	if length <= 0 { return nil, errors.New("reservation requires non-zero length") }
+
+	bytes, mmapErr := unix.Mmap(-1, 0, length, unix.PROT_NONE, unix.MAP_PRIVATE | unix.MAP_ANON | unix.MAP_NORESERVE)
+	if mmapErr != nil { return nil, mmapErr }
+
+	return bytes, nil
+}
+
+// MapRegionAt
+//	Memory maps length bytes of the file at offset over the start of reserved, which must be a slice of a reservation at least length bytes long.
+//	Existing mappings of the reservation are left where they are, so slices of them stay valid while the mapping grows.
+func MapRegionAt(reserved MMap, file *os.File, length int, prot int, offset int64) (MMap, error) {
+	if offset % int64(os.Getpagesize()) != 0 {
+		return nil, errors.New("offset parameter must be a multiple of the system's page size")
+	}
+
+	if length <= 0 || length > len(reserved) { return nil, errors.New("region must be non-empty and fit within the reservation") }
+
+	mprot, flags := protAndFlags(uintptr(prot), 0)
+	addr := uintptr(unsafe.Pointer(&reserved[0]))
+
+	_, _, errno := unix.Syscall6(unix.SYS_MMAP, addr, uintptr(length), uintptr(mprot), uintptr(flags | unix.MAP_FIXED), file.Fd(), uintptr(offset))
+	if errno != 0 { return nil, errno }
+
+	return reserved[:length], nil
+}
+
+// MapZeroAt
+//	Replace the first length bytes of reserved, which must be a slice of a reservation, with read only pages of zeros that are not backed by a file.
+//	Any region of a file mapped there is unmapped, so the file can be removed while slices of the rest of the reservation stay valid.
+func MapZeroAt(reserved MMap, length int) (MMap, error) {
+	if length <= 0 || length > len(reserved) { return nil, errors.New("region must be non-empty and fit within the reservation") }
+
+	addr := uintptr(unsafe.Pointer(&reserved[0]))
+	flags := unix.MAP_PRIVATE | unix.MAP_ANON | unix.MAP_NORESERVE | unix.MAP_FIXED
+
+	_, _, errno := unix.Syscall6(unix.SYS_MMAP, addr, uintptr(length), uintptr(unix.PROT_READ), uintptr(flags), ^uintptr(0), 0)
+	if errno != 0 { return nil, errno }
+
+	return reserved[:length], nil
+}
+
+// MapCopyAt
+//	Replace the start of reserved, which must be a slice of a reservation, with read only pages holding a copy of data that are not backed by a
+//	file. Any region of a file mapped there is unmapped.
+func MapCopyAt(reserved MMap, data []byte) (MMap, error) {
+	if len(data) == 0 || len(data) > len(reserved) || len(data) % os.Getpagesize() != 0 {
+		return nil, errors.New("data must be a non-empty multiple of the page size that fits within the reservation")
+	}
+
+	addr := uintptr(unsafe.Pointer(&reserved[0]))
+	flags := unix.MAP_PRIVATE | unix.MAP_ANON | unix.MAP_FIXED
+
+	_, _, errno := unix.Syscall6(unix.SYS_MMAP, addr, uintptr(len(data)), uintptr(unix.PROT_READ | unix.PROT_WRITE), uintptr(flags), ^uintptr(0), 0)
+	if errno != 0 { return nil, errno }
+
+	mapped := reserved[:len(data)]
+	copy(mapped, data)
+
+	protectErr := unix.Mprotect(mapped, unix.PROT_READ)
+	if protectErr != nil { return nil, protectErr }
+
+	return mapped, nil
+}
@@ -0,0 +1,33 @@
+//go:build !linux
+
+package mmap
+
+import "os"
+
+
+//============================================= MMap Fixed Address Mapping (Unsupported)
+
+
+// Reserve
+//	Mapping at a fixed address is only implemented on Linux.
+func Reserve(length int) (MMap, error) {
+	return nil, ErrFixedMappingUnsupported
+}
+
+// MapRegionAt
+//	Mapping at a fixed address is only implemented on Linux.
+func MapRegionAt(reserved MMap, file *os.File, length int, prot int, offset int64) (MMap, error) {
+	return nil, ErrFixedMappingUnsupported
+}
+
+// MapZeroAt
+//	Mapping at a fixed address is only implemented on Linux.
+func MapZeroAt(reserved MMap, length int) (MMap, error) {
+	return nil, ErrFixedMappingUnsupported
+}
+
+// MapCopyAt
+//	Mapping at a fixed address is only implemented on Linux.
+func MapCopyAt(reserved MMap, data []byte) (MMap, error) {
+	return nil, ErrFixedMappingUnsupported
+}
@@ -1,10 +1,15 @@
 package mmap
 
+import "errors"
+
 
 // MMap
 //	The byte array representation of the memory mapped file in memory.
 type MMap []byte
 
+// ErrFixedMappingUnsupported is returned by Reserve and MapRegionAt on platforms where a file can not be mapped at a fixed address
+var ErrFixedMappingUnsupported = errors.New("mapping at a fixed address is not supported on this platform")
+
 const (
 	// RDONLY: maps the memory read-only. Attempts to write to the MMap object will result in undefined behavior.
 	RDONLY = 0
@@ -60,4 +60,105 @@ func TestMMap(t *testing.T) {
 		mMap[9] = '9'
 		mMap.Flush()
 	})
+
+	t.Run("Test Lock Unlock", func(t *testing.T) {
+		testFile := openFile(os.O_RDONLY)
+		defer testFile.Close()
+
+		mMap, mmapErr := mmap.Map(testFile, mmap.RDONLY, 0)
+		if mmapErr != nil { t.Fatalf("error mapping: %s", mmapErr) }
+
+		defer mMap.Unmap()
+
+		lockErr := mMap.Lock()
+		if lockErr != nil { t.Skipf("mlock unavailable: %s", lockErr) }
+
+		if ! bytes.Equal(TestData, mMap) { t.Errorf("locked mmap != testData: %q, %q", mMap, TestData) }
+
+		unlockErr := mMap.Unlock()
+		if unlockErr != nil { t.Errorf("error unlocking: %s", unlockErr) }
+	})
+
+	t.Run("Test Prefetch", func(t *testing.T) {
+		testFile := openFile(os.O_RDONLY)
+		defer testFile.Close()
+
+		mMap, mmapErr := mmap.Map(testFile, mmap.RDONLY, 0)
+		if mmapErr != nil { t.Fatalf("error mapping: %s", mmapErr) }
+
+		defer mMap.Unmap()
+
+		prefetchErr := mMap.Prefetch()
+		if prefetchErr != nil { t.Errorf("error prefetching: %s", prefetchErr) }
+		if ! bytes.Equal(TestData, mMap) { t.Errorf("prefetched mmap != testData: %q, %q", mMap, TestData) }
+	})
+
+	t.Run("Test Map Region At", func(t *testing.T) {
+		testFile := openFile(os.O_RDONLY)
+		defer testFile.Close()
+
+		reserved, reserveErr := mmap.Reserve(1 << 20)
+		if reserveErr == mmap.ErrFixedMappingUnsupported { t.Skip("fixed mappings unsupported") }
+		if reserveErr != nil { t.Fatalf("error reserving: %s", reserveErr) }
+
+		defer reserved.Unmap()
+
+		mMap, mmapErr := mmap.MapRegionAt(reserved, testFile, len(TestData), mmap.RDONLY, 0)
+		if mmapErr != nil { t.Fatalf("error mapping: %s", mmapErr) }
+
+		if &mMap[0] != &reserved[0] { t.Errorf("region was not mapped at the start of the reservation") }
+		if ! bytes.Equal(TestData, mMap) { t.Errorf("mmap != testData: %q, %q", mMap, TestData) }
+
+		_, mmapErr = mmap.MapRegionAt(reserved[:8], testFile, len(TestData), mmap.RDONLY, 0)
+		if mmapErr == nil { t.Errorf("region larger than the reservation was mapped") }
+	})
+
+	t.Run("Test Map Zero At", func(t *testing.T) {
+		testFile := openFile(os.O_RDONLY)
+		defer testFile.Close()
+
+		reserved, reserveErr := mmap.Reserve(1 << 20)
+		if reserveErr == mmap.ErrFixedMappingUnsupported { t.Skip("fixed mappings unsupported") }
+		if reserveErr != nil { t.Fatalf("error reserving: %s", reserveErr) }
+
+		defer reserved.Unmap()
+
+		pageSize := os.Getpagesize()
+		_, mmapErr := mmap.MapRegionAt(reserved[pageSize:], testFile, len(TestData), mmap.RDONLY, 0)
+		if mmapErr != nil { t.Fatalf("error mapping: %s", mmapErr) }
+
+		zeroed, zeroErr := mmap.MapZeroAt(reserved, 2 * pageSize)
+		if zeroErr != nil { t.Fatalf("error mapping zeros: %s", zeroErr) }
+
+		if &zeroed[0] != &reserved[0] { t.Errorf("zeros were not mapped at the start of the reservation") }
+		if ! bytes.Equal(zeroed[pageSize:pageSize + len(TestData)], make([]byte, len(TestData))) { t.Errorf("mapped region was not replaced with zeros") }
+	})
+
+	t.Run("Test Map Copy At", func(t *testing.T) {
+		testFile := openFile(os.O_RDONLY)
+		defer testFile.Close()
+
+		reserved, reserveErr := mmap.Reserve(1 << 20)
+		if reserveErr == mmap.ErrFixedMappingUnsupported { t.Skip("fixed mappings unsupported") }
+		if reserveErr != nil { t.Fatalf("error reserving: %s", reserveErr) }
+
+		defer reserved.Unmap()
+
+		pageSize := os.Getpagesize()
+		_, mmapErr := mmap.MapRegionAt(reserved, testFile, len(TestData), mmap.RDONLY, 0)
+		if mmapErr != nil { t.Fatalf("error mapping: %s", mmapErr) }
+
+		data := make([]byte, pageSize)
+		copy(data, TestData)
+		copy(data[len(TestData):], TestData)
+
+		copied, copyErr := mmap.MapCopyAt(reserved, data)
+		if copyErr != nil { t.Fatalf("error mapping copy: %s", copyErr) }
+
+		if &copied[0] != &reserved[0] { t.Errorf("copy was not mapped at the start of the reservation") }
+		if ! bytes.Equal(copied, data) { t.Errorf("mapped region does not hold the copied data") }
+
+		_, copyErr = mmap.MapCopyAt(reserved, data[:len(TestData)])
+		if copyErr == nil { t.Errorf("expected an error copying data that is not a multiple of the page size") }
+	})
 }
\ No newline at end of file
@@ -69,4 +69,75 @@ func handleRemainingBytes32(hash *uint32, dataAsBytes []byte) {
 				*hash ^= chunk
 			}
 	}
-}
\ No newline at end of file
+}
+
+//============================================= Murmur64
+
+
+// Murmur64
+//	The 64 bit Murmur64A non-cryptographic hash function, which processes 8-byte chunks and is faster than Murmur32 on 64 bit processors.
+func Murmur64(data []byte, seed uint64) uint64 {
+	length := uint64(len(data))
+	hash := seed ^ (length * m64)
+
+	total8ByteChunks := len(data) / 8
+
+	for idx := range make([]int, total8ByteChunks) {
+		startIdxOfChunk := idx * 8
+		endIdxOfChunk := (idx + 1) * 8
+		chunk := binary.LittleEndian.Uint64(data[startIdxOfChunk:endIdxOfChunk])
+
+		mix64(&hash, chunk)
+	}
+
+	handleRemainingBytes64(&hash, data)
+
+	hash ^= hash >> r64
+	hash *= m64
+	hash ^= hash >> r64
+
+	return hash
+}
+
+// mix64
+//	For each 8-byte chunk, the chunk is mixed with multiplications and a shifted XOR, then folded into the hash.
+func mix64(hash *uint64, chunk uint64) {
+	chunk *= m64
+	chunk ^= chunk >> r64
+	chunk *= m64
+
+	*hash ^= chunk
+	*hash *= m64
+}
+
+// handleRemainingBytes64
+//	If there are any remaining bytes that are not a chunk of 8, XOR them into the hash and mix it.
+func handleRemainingBytes64(hash *uint64, dataAsBytes []byte) {
+	remaining := dataAsBytes[len(dataAsBytes)-len(dataAsBytes) % 8:]
+
+	if len(remaining) > 0 {
+		switch len(remaining) {
+			case 7:
+				*hash ^= uint64(remaining[6]) << 48
+				fallthrough
+			case 6:
+				*hash ^= uint64(remaining[5]) << 40
+				fallthrough
+			case 5:
+				*hash ^= uint64(remaining[4]) << 32
+				fallthrough
+			case 4:
+				*hash ^= uint64(remaining[3]) << 24
+				fallthrough
+			case 3:
+				*hash ^= uint64(remaining[2]) << 16
+				fallthrough
+			case 2:
+				*hash ^= uint64(remaining[1]) << 8
+				fallthrough
+			case 1:
+				*hash ^= uint64(remaining[0])
+				*hash *= m64
+			}
+	}
+}
@@ -12,4 +12,8 @@ const (
 	c32_4 = 0x1b873593
 	// multiplier in the finalization step. Again, improves hash value distribution
 	c32_5 = 0x5c4bcea9
+	// the multiplier of Murmur64, applied to each chunk and to the hash after each chunk is mixed in
+	m64 = 0xc6a4a7935bd1e995
+	// the shift of Murmur64, mixing the high bits of each chunk and of the final hash into the low bits
+	r64 = 47
 )
\ No newline at end of file
@@ -13,4 +13,24 @@ func TestMurmur(t *testing.T) {
 		hash := murmur.Murmur32(key, seed)
 		t.Log("hash:", hash)
 	})
-}
\ No newline at end of file
+
+	t.Run("Test Hashing 64", func(t *testing.T) {
+		expected := []struct {
+			key string
+			seed uint64
+			hash uint64
+		}{
+			{ "", 0, 0 },
+			{ "", 1, 14313749767032693980 },
+			{ "a", 0, 510903276987443985 },
+			{ "hello", 1, 5983625672228268878 },
+			{ "hello world", 2, 17853290960970811572 },
+			{ "0123456789abcdef0", 1, 15024696713924103108 },
+		}
+
+		for _, vector := range expected {
+			hash := murmur.Murmur64([]byte(vector.key), vector.seed)
+			if hash != vector.hash { t.Errorf("hash mismatch for %q with seed %d: actual(%d), expected(%d)", vector.key, vector.seed, hash, vector.hash) }
+		}
+	})
+}
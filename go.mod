@@ -3,6 +3,28 @@ module github.com/sirgallo/mmcmap
 go 1.20
 
 require (
+	github.com/golang/snappy v0.0.4
+	github.com/hashicorp/raft v1.7.1
+	github.com/klauspost/compress v1.17.4
 	github.com/sirgallo/utils v0.1.8
+	github.com/syndtr/goleveldb v1.0.0
+	go.etcd.io/bbolt v1.3.8
 	golang.org/x/sys v0.13.0
+	google.golang.org/grpc v1.58.3
+	google.golang.org/protobuf v1.31.0
+)
+
+require (
+	github.com/armon/go-metrics v0.4.1 // indirect
+	github.com/fatih/color v1.13.0 // indirect
+	github.com/golang/protobuf v1.5.3 // indirect
+	github.com/hashicorp/go-hclog v1.6.2 // indirect
+	github.com/hashicorp/go-immutable-radix v1.0.0 // indirect
+	github.com/hashicorp/go-msgpack/v2 v2.1.2 // indirect
+	github.com/hashicorp/golang-lru v0.5.0 // indirect
+	github.com/mattn/go-colorable v0.1.12 // indirect
+	github.com/mattn/go-isatty v0.0.14 // indirect
+	golang.org/x/net v0.16.0 // indirect
+	golang.org/x/text v0.13.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20230711160842-782d3b101e98 // indirect
 )
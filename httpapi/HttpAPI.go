@@ -0,0 +1,303 @@
+package httpapi
+
+import "bytes"
+import "encoding/base64"
+import "encoding/hex"
+import "encoding/json"
+import "errors"
+import "fmt"
+import "net/http"
+import "strconv"
+import "strings"
+
+import "github.com/sirgallo/mmcmap"
+
+
+//============================================= MMCMap HTTP API
+
+
+// NewHandler
+//	Create an http.Handler exposing mmcMap as a REST API with JSON bodies, for debugging, ops tooling, and clients without a gRPC stack.
+//	GET, PUT, and DELETE on /keys/{key} get, put, and delete a single key, where the key is the rest of the path after /keys/, so it may contain
+//	slashes and any byte escaped as %XX. GET on /range returns a sorted page of the pairs between the start and end query parameters, holding at most
+//	MaxRangeLimit pairs.
+//	GET on /metrics returns the stats of the map in the Prometheus text format.
+//	The encoding query parameter selects how keys and values are written in bodies: text (the default), base64, or hex.
+//	Mount the handler under a prefix with http.StripPrefix.
+func NewHandler(mmcMap *mmcmap.MMCMap) *Handler {
+	return &Handler{ MMCMap: mmcMap, MaxRangeLimit: DefaultMaxRangeLimit }
+}
+
+// ServeHTTP
+//	Route the request to the endpoint for its path and method.
+func (handler *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+		case strings.HasPrefix(r.URL.Path, KeysPath):
+			key := []byte(strings.TrimPrefix(r.URL.Path, KeysPath))
+			if len(key) == 0 {
+				writeError(w, http.StatusBadRequest, errors.New("missing key"))
+				return
+			}
+
+			switch r.Method {
+				case http.MethodGet, http.MethodHead:
+					handler.get(w, r, key)
+				case http.MethodPut:
+					handler.put(w, r, key)
+				case http.MethodDelete:
+					handler.delete(w, key)
+				default:
+					w.Header().Set("Allow", "GET, HEAD, PUT, DELETE")
+					writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+			}
+		case r.URL.Path == RangePath:
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				w.Header().Set("Allow", "GET, HEAD")
+				writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+				return
+			}
+
+			handler.rangePage(w, r)
+		case r.URL.Path == MetricsPath:
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				w.Header().Set("Allow", "GET, HEAD")
+				writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+				return
+			}
+
+			handler.metrics(w)
+		default:
+			writeError(w, http.StatusNotFound, fmt.Errorf("no endpoint for %s", r.URL.Path))
+	}
+}
+
+// get
+//	Respond with the key and its value, or 404 if the key does not exist.
+func (handler *Handler) get(w http.ResponseWriter, r *http.Request, key []byte) {
+	encoding, encodingErr := requestEncoding(r)
+	if encodingErr != nil {
+		writeError(w, http.StatusBadRequest, encodingErr)
+		return
+	}
+
+	value, getErr := handler.MMCMap.Get(key)
+	if value == nil && (getErr == nil || errors.Is(getErr, mmcmap.ErrKeyNotFound)) {
+		writeError(w, http.StatusNotFound, mmcmap.ErrKeyNotFound)
+		return
+	}
+
+	if getErr != nil {
+		writeError(w, statusFor(getErr), getErr)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newPair(encoding, &mmcmap.KeyValuePair{ Key: key, Value: value }, false))
+}
+
+// put
+//	Put the value decoded from the PutBody of the request.
+func (handler *Handler) put(w http.ResponseWriter, r *http.Request, key []byte) {
+	encoding, encodingErr := requestEncoding(r)
+	if encodingErr != nil {
+		writeError(w, http.StatusBadRequest, encodingErr)
+		return
+	}
+
+	var body PutBody
+	decodeErr := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxBodySize)).Decode(&body)
+	if decodeErr != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid body: %w", decodeErr))
+		return
+	}
+
+	value, decValErr := decode(encoding, body.Value)
+	if decValErr != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid value: %w", decValErr))
+		return
+	}
+
+	ok, putErr := handler.MMCMap.Put(key, value)
+	if putErr != nil {
+		writeError(w, statusFor(putErr), putErr)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, PutResponse{ OK: ok })
+}
+
+// delete
+//	Delete the key, responding with whether it existed.
+func (handler *Handler) delete(w http.ResponseWriter, key []byte) {
+	deleted, delErr := handler.MMCMap.Delete(key)
+	if delErr != nil {
+		writeError(w, statusFor(delErr), delErr)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, DeleteResponse{ Deleted: deleted })
+}
+
+// rangePage
+//	Respond with a page of the sorted range, selected by the start, end, limit, reverse, keys_only, and after query parameters.
+//	The start, end, and after parameters are decoded with the encoding of the request, and empty bounds are unbounded. The after parameter takes the
+//	encoded Next token of the previous page. The limit is capped to MaxRangeLimit, and a request without a limit returns at most MaxRangeLimit pairs,
+//	with Next set whenever more pairs follow the page.
+func (handler *Handler) rangePage(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	encoding, encodingErr := requestEncoding(r)
+	if encodingErr != nil {
+		writeError(w, http.StatusBadRequest, encodingErr)
+		return
+	}
+
+	var opts mmcmap.RangeOpts
+	var parseErr error
+
+	if query.Get("limit") != "" {
+		opts.Limit, parseErr = strconv.Atoi(query.Get("limit"))
+		if parseErr != nil || opts.Limit < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid limit %q", query.Get("limit")))
+			return
+		}
+	}
+
+	for name, flag := range map[string]*bool{ "reverse": &opts.Reverse, "keys_only": &opts.KeysOnly } {
+		if query.Get(name) == "" { continue }
+
+		*flag, parseErr = strconv.ParseBool(query.Get(name))
+		if parseErr != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid %s %q", name, query.Get(name)))
+			return
+		}
+	}
+
+	if handler.MaxRangeLimit > 0 && (opts.Limit == 0 || opts.Limit > handler.MaxRangeLimit) { opts.Limit = handler.MaxRangeLimit }
+
+	var startKey, endKey []byte
+	for name, bound := range map[string]*[]byte{ "start": &startKey, "end": &endKey, "after": &opts.After } {
+		*bound, parseErr = queryBound(encoding, query.Get(name))
+		if parseErr != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid %s: %w", name, parseErr))
+			return
+		}
+	}
+
+	pairs, next, rangeErr := handler.MMCMap.RangePage(startKey, endKey, opts)
+	if rangeErr != nil {
+		writeError(w, statusFor(rangeErr), rangeErr)
+		return
+	}
+
+	response := RangeResponse{ Pairs: make([]Pair, 0, len(pairs)) }
+	for _, pair := range pairs {
+		response.Pairs = append(response.Pairs, newPair(encoding, pair, opts.KeysOnly))
+	}
+
+	if next != nil { response.Next = encode(encoding, next) }
+	writeJSON(w, http.StatusOK, response)
+}
+
+// metrics
+//	Respond with the stats of the map in the Prometheus text format.
+func (handler *Handler) metrics(w http.ResponseWriter) {
+	var body bytes.Buffer
+	writeErr := handler.MMCMap.WritePrometheus(&body)
+	if writeErr != nil {
+		writeError(w, statusFor(writeErr), writeErr)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.Write(body.Bytes())
+}
+
+// newPair
+//	Encode a key-value pair for a response body.
+func newPair(encoding string, pair *mmcmap.KeyValuePair, keysOnly bool) Pair {
+	encoded := Pair{ Key: encode(encoding, pair.Key), Version: pair.Version }
+	if ! keysOnly {
+		value := encode(encoding, pair.Value)
+		encoded.Value = &value
+	}
+
+	return encoded
+}
+
+// queryBound
+//	Decode a range bound from the query using the encoding, where an empty bound is unbounded.
+func queryBound(encoding string, bound string) ([]byte, error) {
+	if bound == "" { return nil, nil }
+	return decode(encoding, bound)
+}
+
+// requestEncoding
+//	The encoding selected by the encoding query parameter, defaulting to EncodingText.
+func requestEncoding(r *http.Request) (string, error) {
+	encoding := r.URL.Query().Get("encoding")
+	switch encoding {
+		case "":
+			return EncodingText, nil
+		case EncodingText, EncodingBase64, EncodingHex:
+			return encoding, nil
+		default:
+			return "", fmt.Errorf("unknown encoding %q", encoding)
+	}
+}
+
+// encode
+//	Encode a key or value for a body using the encoding.
+func encode(encoding string, data []byte) string {
+	switch encoding {
+		case EncodingBase64:
+			return base64.StdEncoding.EncodeToString(data)
+		case EncodingHex:
+			return hex.EncodeToString(data)
+		default:
+			return string(data)
+	}
+}
+
+// decode
+//	Decode a key or value from a body using the encoding.
+func decode(encoding string, data string) ([]byte, error) {
+	switch encoding {
+		case EncodingBase64:
+			return base64.StdEncoding.DecodeString(data)
+		case EncodingHex:
+			return hex.DecodeString(data)
+		default:
+			return []byte(data), nil
+	}
+}
+
+// statusFor
+//	The HTTP status for an error returned by the map, so clients can tell invalid requests apart from unavailable or failed maps.
+func statusFor(err error) int {
+	switch {
+		case errors.Is(err, mmcmap.ErrKeyTooLarge), errors.Is(err, mmcmap.ErrValueTooLarge):
+			return http.StatusBadRequest
+		case errors.Is(err, mmcmap.ErrFollowerReadOnly):
+			return http.StatusConflict
+		case errors.Is(err, mmcmap.ErrMapFull):
+			return http.StatusInsufficientStorage
+		case errors.Is(err, mmcmap.ErrWriteStall), errors.Is(err, mmcmap.ErrMapClosed), errors.Is(err, mmcmap.ErrMapDetached):
+			return http.StatusServiceUnavailable
+		default:
+			return http.StatusInternalServerError
+	}
+}
+
+// writeJSON
+//	Write the body as JSON with the status code.
+func writeJSON(w http.ResponseWriter, statusCode int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(body)
+}
+
+// writeError
+//	Write an ErrorResponse with the status code.
+func writeError(w http.ResponseWriter, statusCode int, err error) {
+	writeJSON(w, statusCode, ErrorResponse{ Error: err.Error() })
+}
@@ -0,0 +1,74 @@
+package httpapi
+
+import "github.com/sirgallo/mmcmap"
+
+
+// Handler serves the REST API for a single mmcmap
+type Handler struct {
+	// MMCMap: the map every request is served from
+	MMCMap *mmcmap.MMCMap
+	// MaxRangeLimit: the most pairs a single range request loads, since the page is held in memory before it is written. Requests without a limit,
+	// or with a larger one, are capped to it. 0 does not cap requests. Defaults to DefaultMaxRangeLimit
+	MaxRangeLimit int
+}
+
+// Pair is a key-value pair in a response body. Keys and values are encoded with the encoding requested by the encoding query parameter
+type Pair struct {
+	// Key: the encoded key
+	Key string `json:"key"`
+	// Value: the encoded value, omitted for keys only ranges
+	Value *string `json:"value,omitempty"`
+	// Version: the version of the map the pair was written at, omitted for gets
+	Version uint64 `json:"version,omitempty"`
+}
+
+// PutBody is the request body of a put
+type PutBody struct {
+	// Value: the encoded value to put
+	Value string `json:"value"`
+}
+
+// PutResponse is the response body of a put
+type PutResponse struct {
+	// OK: whether the put was committed
+	OK bool `json:"ok"`
+}
+
+// DeleteResponse is the response body of a delete
+type DeleteResponse struct {
+	// Deleted: whether the key existed
+	Deleted bool `json:"deleted"`
+}
+
+// RangeResponse is the response body of a range
+type RangeResponse struct {
+	// Pairs: the page of pairs in the range, in sorted order
+	Pairs []Pair `json:"pairs"`
+	// Next: the encoded continuation token to pass as the after query parameter for the next page, omitted once the range is exhausted
+	Next string `json:"next,omitempty"`
+}
+
+// ErrorResponse is the response body of a failed request
+type ErrorResponse struct {
+	// Error: the error message
+	Error string `json:"error"`
+}
+
+const (
+	// KeysPath: the path prefix of the single key endpoints
+	KeysPath = "/keys/"
+	// RangePath: the path of the range endpoint
+	RangePath = "/range"
+	// MetricsPath: the path of the Prometheus metrics endpoint
+	MetricsPath = "/metrics"
+	// MaxBodySize: the largest request body accepted for a put
+	MaxBodySize = 64 << 20
+	// DefaultMaxRangeLimit: the default cap on the pairs returned by a single range request
+	DefaultMaxRangeLimit = 10000
+	// EncodingText: keys and values in bodies are plain strings. The default
+	EncodingText = "text"
+	// EncodingBase64: keys and values in bodies are standard base64
+	EncodingBase64 = "base64"
+	// EncodingHex: keys and values in bodies are lowercase hex
+	EncodingHex = "hex"
+)
@@ -0,0 +1,165 @@
+package httpapitests
+
+import "encoding/hex"
+import "encoding/json"
+import "fmt"
+import "io"
+import "net/http"
+import "net/http/httptest"
+import "net/url"
+import "os"
+import "path/filepath"
+import "strings"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+import "github.com/sirgallo/mmcmap/httpapi"
+
+
+var apiTestPath = filepath.Join(os.TempDir(), "testhttpapi")
+var apiTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initApiMapErr error
+	os.Remove(apiTestPath)
+
+	apiTestMap, initApiMapErr = mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: apiTestPath })
+	if initApiMapErr != nil { panic(initApiMapErr.Error()) }
+
+	fmt.Println("http api test mmcmap initialized")
+}
+
+
+func TestHttpAPI(t *testing.T) {
+	defer apiTestMap.Remove()
+
+	handler := httpapi.NewHandler(apiTestMap)
+	handler.MaxRangeLimit = 20
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	do := func(t *testing.T, method, path, body string, response interface{}) int {
+		req, reqErr := http.NewRequest(method, server.URL + path, strings.NewReader(body))
+		if reqErr != nil { t.Fatalf("error creating request: %s", reqErr.Error()) }
+
+		resp, doErr := http.DefaultClient.Do(req)
+		if doErr != nil { t.Fatalf("error on request: %s", doErr.Error()) }
+		defer resp.Body.Close()
+
+		if response != nil {
+			decodeErr := json.NewDecoder(resp.Body).Decode(response)
+			if decodeErr != nil { t.Fatalf("error decoding response: %s", decodeErr.Error()) }
+		}
+
+		return resp.StatusCode
+	}
+
+	t.Run("Test Put Get Delete", func(t *testing.T) {
+		var putResp httpapi.PutResponse
+		code := do(t, http.MethodPut, "/keys/user/1", `{"value": "alice"}`, &putResp)
+		if code != http.StatusOK || ! putResp.OK { t.Fatalf("put failed: actual(%d %v), expected(200 true)", code, putResp.OK) }
+
+		var pair httpapi.Pair
+		code = do(t, http.MethodGet, "/keys/user/1", "", &pair)
+		if code != http.StatusOK { t.Fatalf("get status mismatch: actual(%d), expected(200)", code) }
+		if pair.Key != "user/1" || pair.Value == nil || *pair.Value != "alice" { t.Errorf("get pair mismatch: actual(%+v)", pair) }
+
+		code = do(t, http.MethodGet, "/keys/user/1?encoding=hex", "", &pair)
+		if code != http.StatusOK || *pair.Value != "616c696365" { t.Errorf("hex value mismatch: actual(%d %s), expected(200 616c696365)", code, *pair.Value) }
+
+		var delResp httpapi.DeleteResponse
+		code = do(t, http.MethodDelete, "/keys/user/1", "", &delResp)
+		if code != http.StatusOK || ! delResp.Deleted { t.Errorf("delete failed: actual(%d %v), expected(200 true)", code, delResp.Deleted) }
+
+		var errResp httpapi.ErrorResponse
+		code = do(t, http.MethodGet, "/keys/user/1", "", &errResp)
+		if code != http.StatusNotFound { t.Errorf("missing key status mismatch: actual(%d), expected(404)", code) }
+	})
+
+	t.Run("Test Binary Values", func(t *testing.T) {
+		code := do(t, http.MethodPut, "/keys/binary?encoding=base64", `{"value": "AAH/"}`, nil)
+		if code != http.StatusOK { t.Fatalf("put status mismatch: actual(%d), expected(200)", code) }
+
+		val, _ := apiTestMap.Get([]byte("binary"))
+		if string(val) != "\x00\x01\xff" { t.Errorf("binary value mismatch: actual(%v)", val) }
+
+		code = do(t, http.MethodPut, "/keys/binary?encoding=base64", `{"value": "not base64!"}`, nil)
+		if code != http.StatusBadRequest { t.Errorf("invalid value status mismatch: actual(%d), expected(400)", code) }
+	})
+
+	t.Run("Test Range Pages", func(t *testing.T) {
+		for idx := 0; idx < 25; idx++ {
+			_, putErr := apiTestMap.Put([]byte(fmt.Sprintf("page%02d", idx)), []byte(fmt.Sprintf("value%d", idx)))
+			if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+		}
+
+		var keys []string
+		after := ""
+		for {
+			var page httpapi.RangeResponse
+			code := do(t, http.MethodGet, "/range?start=page&end=page99&limit=10&after=" + url.QueryEscape(after), "", &page)
+			if code != http.StatusOK { t.Fatalf("range status mismatch: actual(%d), expected(200)", code) }
+
+			for _, pair := range page.Pairs { keys = append(keys, pair.Key) }
+			if page.Next == "" { break }
+			after = page.Next
+		}
+
+		if len(keys) != 25 { t.Fatalf("range count mismatch: actual(%d), expected(25)", len(keys)) }
+		for idx, key := range keys {
+			if key != fmt.Sprintf("page%02d", idx) { t.Errorf("range order mismatch: actual(%s), expected(page%02d)", key, idx) }
+		}
+
+		var page httpapi.RangeResponse
+		do(t, http.MethodGet, "/range?start=page&end=page99&keys_only=true&reverse=true&limit=1", "", &page)
+		if len(page.Pairs) != 1 || page.Pairs[0].Key != "page24" || page.Pairs[0].Value != nil { t.Errorf("keys only reverse range mismatch: actual(%+v)", page.Pairs) }
+
+		for _, path := range []string{ "/range?start=page&end=page99", "/range?start=page&end=page99&limit=100" } {
+			var capped httpapi.RangeResponse
+			do(t, http.MethodGet, path, "", &capped)
+			if len(capped.Pairs) != 20 { t.Errorf("%s was not capped: actual(%d), expected(20)", path, len(capped.Pairs)) }
+			if capped.Next != "page19" { t.Errorf("%s continuation mismatch: actual(%s), expected(page19)", path, capped.Next) }
+		}
+
+		var hexPage httpapi.RangeResponse
+		hexBounds := "start=" + hex.EncodeToString([]byte("page10")) + "&end=" + hex.EncodeToString([]byte("page12"))
+		do(t, http.MethodGet, "/range?encoding=hex&" + hexBounds + "&after=" + hex.EncodeToString([]byte("page10")), "", &hexPage)
+		if len(hexPage.Pairs) != 2 || hexPage.Pairs[0].Key != hex.EncodeToString([]byte("page11")) { t.Errorf("hex bounds mismatch: actual(%+v)", hexPage.Pairs) }
+	})
+
+	t.Run("Test Metrics", func(t *testing.T) {
+		resp, getErr := http.Get(server.URL + "/metrics")
+		if getErr != nil { t.Fatalf("error on request: %s", getErr.Error()) }
+		defer resp.Body.Close()
+
+		body, readErr := io.ReadAll(resp.Body)
+		if readErr != nil { t.Fatalf("error reading response: %s", readErr.Error()) }
+
+		if resp.StatusCode != http.StatusOK { t.Fatalf("status mismatch: actual(%d), expected(200)", resp.StatusCode) }
+		if ! strings.Contains(string(body), "# TYPE mmcmap_puts_total counter") { t.Errorf("puts counter missing from metrics: actual(%s)", body) }
+		if ! strings.Contains(string(body), "mmcmap_live_keys{map=\"testhttpapi\"} ") { t.Errorf("live keys gauge missing from metrics: actual(%s)", body) }
+	})
+
+	t.Run("Test Invalid Requests", func(t *testing.T) {
+		cases := []struct { method, path, body string; code int }{
+			{ http.MethodPost, "/keys/a", "", http.StatusMethodNotAllowed },
+			{ http.MethodGet, "/keys/", "", http.StatusBadRequest },
+			{ http.MethodPut, "/keys/a", "not json", http.StatusBadRequest },
+			{ http.MethodGet, "/range?limit=-1", "", http.StatusBadRequest },
+			{ http.MethodGet, "/range?encoding=hex&start=zz", "", http.StatusBadRequest },
+			{ http.MethodGet, "/keys/a?encoding=rot13", "", http.StatusBadRequest },
+			{ http.MethodGet, "/unknown", "", http.StatusNotFound },
+		}
+
+		for _, c := range cases {
+			var errResp httpapi.ErrorResponse
+			code := do(t, c.method, c.path, c.body, &errResp)
+			if code != c.code { t.Errorf("%s %s status mismatch: actual(%d), expected(%d)", c.method, c.path, code, c.code) }
+			if errResp.Error == "" { t.Errorf("%s %s did not return an error message", c.method, c.path) }
+		}
+	})
+
+	t.Log("Done")
+}
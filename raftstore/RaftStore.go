@@ -0,0 +1,208 @@
+package raftstore
+
+import "encoding/binary"
+import "errors"
+import "time"
+
+import "github.com/hashicorp/raft"
+import "github.com/sirgallo/mmcmap"
+
+
+//============================================= MMCMap Raft Store
+
+
+// NewStore
+//	Create a raft log and stable store on mmcMap, which can be passed to raft.NewRaft as both the LogStore and the StableStore.
+//	Log entries are keyed by their index in big endian, so they sort in index order. The first and last index are each a single page of a range, and
+//	DeleteRange reads the entries to delete a page at a time, which only stops once the page is full in a map using KeyModeOrdered. In hashed key mode
+//	every page walks the whole trie, making each call O(n) in the size of the log, so maps in any other key mode return ErrKeyModeUnsupported.
+//	StoreLogs commits every entry as one version, so a batch of entries is either fully stored or not at all. The map should be dedicated to the store.
+func NewStore(mmcMap *mmcmap.MMCMap) (*Store, error) {
+	if mmcMap.Header.KeyMode != mmcmap.KeyModeOrdered { return nil, ErrKeyModeUnsupported }
+	return &Store{ MMCMap: mmcMap }, nil
+}
+
+// FirstIndex
+//	The index of the first log entry stored, or 0 if there are none.
+func (store *Store) FirstIndex() (uint64, error) {
+	return store.boundIndex(false)
+}
+
+// LastIndex
+//	The index of the last log entry stored, or 0 if there are none.
+func (store *Store) LastIndex() (uint64, error) {
+	return store.boundIndex(true)
+}
+
+// GetLog
+//	Read the log entry at index into log, returning raft.ErrLogNotFound if it does not exist.
+func (store *Store) GetLog(index uint64, log *raft.Log) error {
+	value, getErr := store.get(logKey(index))
+	if getErr != nil { return getErr }
+	if value == nil { return raft.ErrLogNotFound }
+
+	return deserializeLog(value, log)
+}
+
+// StoreLog
+//	Store a single log entry.
+func (store *Store) StoreLog(log *raft.Log) error {
+	return store.StoreLogs([]*raft.Log{ log })
+}
+
+// StoreLogs
+//	Store every log entry as a single version of the map.
+func (store *Store) StoreLogs(logs []*raft.Log) error {
+	batch := store.MMCMap.NewWriteBatch()
+	for _, log := range logs {
+		batch.Put(logKey(log.Index), serializeLog(log))
+	}
+
+	_, commitErr := batch.Commit()
+	return commitErr
+}
+
+// DeleteRange
+//	Delete the log entries from min to max, inclusive. Entries are deleted DeleteBatchSize at a time, each batch as its own version.
+func (store *Store) DeleteRange(min, max uint64) error {
+	if min > max { return nil }
+
+	for {
+		keys, _, rangeErr := store.MMCMap.RangePage(logKey(min), logKey(max), mmcmap.RangeOpts{ Limit: DeleteBatchSize, KeysOnly: true })
+		if rangeErr != nil { return rangeErr }
+		if len(keys) == 0 { return nil }
+
+		batch := store.MMCMap.NewWriteBatch()
+		for _, pair := range keys { batch.Delete(pair.Key) }
+
+		_, commitErr := batch.Commit()
+		if commitErr != nil { return commitErr }
+
+		if len(keys) < DeleteBatchSize { return nil }
+	}
+}
+
+// Set
+//	Put the value for a stable store key.
+func (store *Store) Set(key, val []byte) error {
+	_, putErr := store.MMCMap.Put(stableKey(key), val)
+	return putErr
+}
+
+// Get
+//	Read the value for a stable store key, returning ErrKeyNotFound if it does not exist.
+func (store *Store) Get(key []byte) ([]byte, error) {
+	value, getErr := store.get(stableKey(key))
+	if getErr != nil { return nil, getErr }
+	if value == nil { return nil, ErrKeyNotFound }
+
+	return value, nil
+}
+
+// SetUint64
+//	Put a uint64 for a stable store key, encoded in big endian.
+func (store *Store) SetUint64(key []byte, val uint64) error {
+	return store.Set(key, binary.BigEndian.AppendUint64(nil, val))
+}
+
+// GetUint64
+//	Read a uint64 for a stable store key, returning 0 if it does not exist.
+func (store *Store) GetUint64(key []byte) (uint64, error) {
+	value, getErr := store.get(stableKey(key))
+	if getErr != nil { return 0, getErr }
+	if value == nil { return 0, nil }
+	if len(value) != 8 { return 0, ErrInvalidUint64 }
+
+	return binary.BigEndian.Uint64(value), nil
+}
+
+// get
+//	Read the value for a key, returning nil if it does not exist whether or not the map was opened with StrictGet.
+func (store *Store) get(key []byte) ([]byte, error) {
+	value, getErr := store.MMCMap.Get(key)
+	if errors.Is(getErr, mmcmap.ErrKeyNotFound) { return nil, nil }
+	if getErr != nil { return nil, getErr }
+
+	return value, nil
+}
+
+// boundIndex
+//	The index of the first, or the last if isLast, log entry stored, or 0 if there are none.
+func (store *Store) boundIndex(isLast bool) (uint64, error) {
+	pairs, _, rangeErr := store.MMCMap.RangePage(logKey(0), logKey(^uint64(0)), mmcmap.RangeOpts{ Limit: 1, Reverse: isLast, KeysOnly: true })
+	if rangeErr != nil { return 0, rangeErr }
+	if len(pairs) == 0 { return 0, nil }
+
+	return binary.BigEndian.Uint64(pairs[0].Key[1:]), nil
+}
+
+// logKey
+//	The key of the log entry at index.
+func logKey(index uint64) []byte {
+	return binary.BigEndian.AppendUint64([]byte{ logPrefix }, index)
+}
+
+// stableKey
+//	The key of a stable store key.
+func stableKey(key []byte) []byte {
+	return append([]byte{ stablePrefix }, key...)
+}
+
+// serializeLog
+//	Serialize a log entry as its index, term, type, and append time in unix nanoseconds, followed by its length prefixed data and extensions.
+//	A zero append time is stored as 0.
+func serializeLog(log *raft.Log) []byte {
+	var appendedAt int64
+	if ! log.AppendedAt.IsZero() { appendedAt = log.AppendedAt.UnixNano() }
+
+	buf := make([]byte, 0, logHeaderSize + len(log.Data) + len(log.Extensions))
+	buf = binary.BigEndian.AppendUint64(buf, log.Index)
+	buf = binary.BigEndian.AppendUint64(buf, log.Term)
+	buf = append(buf, byte(log.Type))
+	buf = binary.BigEndian.AppendUint64(buf, uint64(appendedAt))
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(log.Data)))
+	buf = append(buf, log.Data...)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(log.Extensions)))
+	buf = append(buf, log.Extensions...)
+
+	return buf
+}
+
+// deserializeLog
+//	Deserialize a log entry written by serializeLog into log. Data and extensions are copied, so log does not share memory with the map.
+func deserializeLog(data []byte, log *raft.Log) error {
+	if len(data) < logHeaderSize { return ErrCorruptLog }
+
+	log.Index = binary.BigEndian.Uint64(data[0:8])
+	log.Term = binary.BigEndian.Uint64(data[8:16])
+	log.Type = raft.LogType(data[16])
+
+	log.AppendedAt = time.Time{}
+	appendedAt := int64(binary.BigEndian.Uint64(data[17:25]))
+	if appendedAt != 0 { log.AppendedAt = time.Unix(0, appendedAt) }
+
+	rest := data[25:]
+	var readErr error
+
+	log.Data, rest, readErr = readLengthPrefixed(rest)
+	if readErr != nil { return readErr }
+
+	log.Extensions, rest, readErr = readLengthPrefixed(rest)
+	if readErr != nil { return readErr }
+	if len(rest) != 0 { return ErrCorruptLog }
+
+	return nil
+}
+
+// readLengthPrefixed
+//	Read a copy of the bytes prefixed by a 4 byte big endian length, returning the remaining data. An empty field is returned as nil.
+func readLengthPrefixed(data []byte) ([]byte, []byte, error) {
+	if len(data) < 4 { return nil, nil, ErrCorruptLog }
+
+	length := binary.BigEndian.Uint32(data)
+	data = data[4:]
+	if uint64(length) > uint64(len(data)) { return nil, nil, ErrCorruptLog }
+	if length == 0 { return nil, data, nil }
+
+	return append([]byte{}, data[:length]...), data[length:], nil
+}
@@ -0,0 +1,39 @@
+package raftstore
+
+import "errors"
+
+import "github.com/sirgallo/mmcmap"
+
+
+// Store implements the raft LogStore and StableStore interfaces on top of a single mmcmap
+type Store struct {
+	// MMCMap: the map log entries and stable values are stored in
+	MMCMap *mmcmap.MMCMap
+}
+
+const (
+	// logPrefix: the first byte of every log entry key, followed by the 8 byte big endian index so keys sort in index order
+	logPrefix byte = 'l'
+	// stablePrefix: the first byte of every stable store key, followed by the key given to Set
+	stablePrefix byte = 's'
+)
+
+const (
+	// IndexSize: the size of a serialized log index
+	IndexSize = 8
+	// logHeaderSize: the size of a serialized log entry before its data and extensions. The index, term, and append time are 8 bytes each, the type
+	// is 1 byte, and the data and extensions lengths are 4 bytes each
+	logHeaderSize = 8 + 8 + 1 + 8 + 4 + 4
+	// DeleteBatchSize: the number of log entries deleted in each version by DeleteRange
+	DeleteBatchSize = 10000
+)
+
+
+// ErrKeyNotFound is returned by Get for stable store keys that do not exist. raft matches on the message "not found"
+var ErrKeyNotFound = errors.New("not found")
+// ErrCorruptLog is returned when a stored log entry cannot be deserialized
+var ErrCorruptLog = errors.New("raftstore: corrupt log entry")
+// ErrKeyModeUnsupported is returned by NewStore for maps not using KeyModeOrdered
+var ErrKeyModeUnsupported = errors.New("raftstore: map must use the ordered key mode")
+// ErrInvalidUint64 is returned by GetUint64 for stable store values that were not written by SetUint64
+var ErrInvalidUint64 = errors.New("raftstore: stable value is not a uint64")
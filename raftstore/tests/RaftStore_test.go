@@ -0,0 +1,180 @@
+package raftstoretests
+
+import "bytes"
+import "fmt"
+import "io"
+import "os"
+import "path/filepath"
+import "testing"
+import "time"
+
+import "github.com/hashicorp/raft"
+import "github.com/sirgallo/mmcmap"
+import "github.com/sirgallo/mmcmap/raftstore"
+
+
+var raftTestPath = filepath.Join(os.TempDir(), "testraftstore")
+var raftTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initRaftMapErr error
+	os.Remove(raftTestPath)
+
+	raftTestMap, initRaftMapErr = mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: raftTestPath, KeyMode: mmcmap.KeyModeOrdered })
+	if initRaftMapErr != nil { panic(initRaftMapErr.Error()) }
+
+	fmt.Println("raft store test mmcmap initialized")
+}
+
+
+// countFSM counts the commands applied to it
+type countFSM struct {
+	applied [][]byte
+}
+
+func (fsm *countFSM) Apply(log *raft.Log) interface{} {
+	fsm.applied = append(fsm.applied, log.Data)
+	return len(fsm.applied)
+}
+
+func (fsm *countFSM) Snapshot() (raft.FSMSnapshot, error) { return nil, fmt.Errorf("snapshots not supported") }
+
+func (fsm *countFSM) Restore(snapshot io.ReadCloser) error { return snapshot.Close() }
+
+
+func TestRaftStore(t *testing.T) {
+	defer raftTestMap.Remove()
+
+	store, newStoreErr := raftstore.NewStore(raftTestMap)
+	if newStoreErr != nil { t.Fatalf("error creating store: %s", newStoreErr.Error()) }
+
+	t.Run("Test Hashed Map Rejected", func(t *testing.T) {
+		path := filepath.Join(os.TempDir(), "testraftstorehashed")
+		os.Remove(path)
+
+		hashedMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: path })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+		defer hashedMap.Remove()
+
+		_, newStoreErr := raftstore.NewStore(hashedMap)
+		if newStoreErr != raftstore.ErrKeyModeUnsupported { t.Errorf("key mode error mismatch: actual(%v), expected(%v)", newStoreErr, raftstore.ErrKeyModeUnsupported) }
+	})
+
+	t.Run("Test Empty Store", func(t *testing.T) {
+		first, _ := store.FirstIndex()
+		last, _ := store.LastIndex()
+		if first != 0 || last != 0 { t.Errorf("empty indexes mismatch: actual(%d %d), expected(0 0)", first, last) }
+
+		var log raft.Log
+		if getErr := store.GetLog(1, &log); getErr != raft.ErrLogNotFound { t.Errorf("missing log error mismatch: actual(%v), expected(%v)", getErr, raft.ErrLogNotFound) }
+	})
+
+	t.Run("Test Store And Get Logs", func(t *testing.T) {
+		appendedAt := time.Unix(0, time.Now().UnixNano())
+
+		var logs []*raft.Log
+		for idx := uint64(1); idx <= 300; idx++ {
+			logs = append(logs, &raft.Log{ Index: idx, Term: idx / 100 + 1, Type: raft.LogCommand, Data: []byte(fmt.Sprintf("cmd%d", idx)), AppendedAt: appendedAt })
+		}
+
+		logs[9].Extensions = []byte("ext")
+		storeErr := store.StoreLogs(logs)
+		if storeErr != nil { t.Fatalf("error storing logs: %s", storeErr.Error()) }
+
+		first, _ := store.FirstIndex()
+		last, _ := store.LastIndex()
+		if first != 1 || last != 300 { t.Errorf("indexes mismatch: actual(%d %d), expected(1 300)", first, last) }
+
+		var log raft.Log
+		getErr := store.GetLog(10, &log)
+		if getErr != nil { t.Fatalf("error getting log: %s", getErr.Error()) }
+		if log.Index != 10 || log.Term != 1 || log.Type != raft.LogCommand || string(log.Data) != "cmd10" || string(log.Extensions) != "ext" {
+			t.Errorf("log mismatch: actual(%+v)", log)
+		}
+
+		if ! log.AppendedAt.Equal(appendedAt) { t.Errorf("appended at mismatch: actual(%v), expected(%v)", log.AppendedAt, appendedAt) }
+
+		store.GetLog(256, &log)
+		if log.Index != 256 || log.Term != 3 || log.Extensions != nil { t.Errorf("log mismatch: actual(%+v)", log) }
+	})
+
+	t.Run("Test Delete Range", func(t *testing.T) {
+		deleteErr := store.DeleteRange(1, 255)
+		if deleteErr != nil { t.Fatalf("error deleting range: %s", deleteErr.Error()) }
+
+		first, _ := store.FirstIndex()
+		last, _ := store.LastIndex()
+		if first != 256 || last != 300 { t.Errorf("indexes after delete mismatch: actual(%d %d), expected(256 300)", first, last) }
+
+		var log raft.Log
+		if getErr := store.GetLog(255, &log); getErr != raft.ErrLogNotFound { t.Errorf("deleted log error mismatch: actual(%v)", getErr) }
+
+		store.DeleteRange(290, 300)
+		last, _ = store.LastIndex()
+		if last != 289 { t.Errorf("last index after suffix delete mismatch: actual(%d), expected(289)", last) }
+
+		store.DeleteRange(256, 289)
+	})
+
+	t.Run("Test Stable Store", func(t *testing.T) {
+		_, getErr := store.Get([]byte("missing"))
+		if getErr == nil || getErr.Error() != "not found" { t.Errorf("missing key error mismatch: actual(%v), expected(not found)", getErr) }
+
+		missing, getErr := store.GetUint64([]byte("missing"))
+		if getErr != nil || missing != 0 { t.Errorf("missing uint64 mismatch: actual(%d %v), expected(0 nil)", missing, getErr) }
+
+		store.Set([]byte("addr"), []byte("127.0.0.1"))
+		value, _ := store.Get([]byte("addr"))
+		if ! bytes.Equal(value, []byte("127.0.0.1")) { t.Errorf("stable value mismatch: actual(%s), expected(127.0.0.1)", value) }
+
+		store.SetUint64([]byte("term"), 42)
+		term, _ := store.GetUint64([]byte("term"))
+		if term != 42 { t.Errorf("stable uint64 mismatch: actual(%d), expected(42)", term) }
+
+		first, _ := store.FirstIndex()
+		if first != 0 { t.Errorf("stable keys visible as logs: actual(%d), expected(0)", first) }
+	})
+
+	t.Run("Test Single Node Raft", func(t *testing.T) {
+		conf := raft.DefaultConfig()
+		conf.LocalID = "node"
+		conf.HeartbeatTimeout = 50 * time.Millisecond
+		conf.ElectionTimeout = 50 * time.Millisecond
+		conf.LeaderLeaseTimeout = 50 * time.Millisecond
+		conf.CommitTimeout = 5 * time.Millisecond
+		conf.LogOutput = io.Discard
+
+		addr, transport := raft.NewInmemTransport("")
+		configuration := raft.Configuration{ Servers: []raft.Server{ { ID: conf.LocalID, Address: addr } } }
+
+		bootstrapErr := raft.BootstrapCluster(conf, store, store, raft.NewInmemSnapshotStore(), transport, configuration)
+		if bootstrapErr != nil { t.Fatalf("error bootstrapping cluster: %s", bootstrapErr.Error()) }
+
+		fsm := &countFSM{}
+		node, newErr := raft.NewRaft(conf, fsm, store, store, raft.NewInmemSnapshotStore(), transport)
+		if newErr != nil { t.Fatalf("error creating raft: %s", newErr.Error()) }
+		defer func() { node.Shutdown().Error() }()
+
+		select {
+			case <-node.LeaderCh():
+			case <-time.After(5 * time.Second):
+				t.Fatal("node did not become leader")
+		}
+
+		for idx := 0; idx < 10; idx++ {
+			applyErr := node.Apply([]byte(fmt.Sprintf("cmd%d", idx)), time.Second).Error()
+			if applyErr != nil { t.Fatalf("error applying command: %s", applyErr.Error()) }
+		}
+
+		if len(fsm.applied) != 10 { t.Errorf("applied count mismatch: actual(%d), expected(10)", len(fsm.applied)) }
+
+		last, _ := store.LastIndex()
+		if last < 10 { t.Errorf("last index mismatch: actual(%d), expected at least 10", last) }
+
+		currentTerm, _ := store.GetUint64([]byte("CurrentTerm"))
+		if currentTerm == 0 { t.Error("current term was not persisted") }
+	})
+
+	t.Log("Done")
+}
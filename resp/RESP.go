@@ -0,0 +1,484 @@
+package resp
+
+import "bufio"
+import "bytes"
+import "errors"
+import "fmt"
+import "io"
+import "net"
+import "strconv"
+import "strings"
+
+import "github.com/sirgallo/mmcmap"
+
+
+//============================================= MMCMap RESP Server
+
+
+// errProtocol is returned when a client sends a command that is not valid RESP, after which the connection is closed
+var errProtocol = errors.New("Protocol error")
+
+
+// NewServer
+//	Create a server speaking a minimal subset of the Redis protocol (RESP2) over mmcMap, so existing Redis client libraries can be used for simple
+//	key-value workloads. GET, SET (with NX), DEL, and SCAN (with MATCH and COUNT) map onto Get, Put, PutIfAbsent, Delete, and RangePage. PING, ECHO,
+//	SELECT 0, COMMAND, and QUIT are answered so clients can connect. Every other command returns an error.
+func NewServer(mmcMap *mmcmap.MMCMap) *Server {
+	return &Server{ MMCMap: mmcMap, MaxScanCount: DefaultMaxScanCount, conns: make(map[net.Conn]struct{}) }
+}
+
+// Serve
+//	Accept client connections from listener until it is closed, serving each on its own go routine. Returns nil once the listener is closed.
+func (server *Server) Serve(listener net.Listener) error {
+	for {
+		conn, acceptErr := listener.Accept()
+		if errors.Is(acceptErr, net.ErrClosed) { return nil }
+		if acceptErr != nil { return acceptErr }
+
+		if ! server.track(conn) {
+			conn.Close()
+			continue
+		}
+
+		go server.serveConn(conn)
+	}
+}
+
+// Close
+//	Close every client connection and reject new ones. The listener passed to Serve is owned by the caller and is not closed.
+func (server *Server) Close() error {
+	server.connMutex.Lock()
+	defer server.connMutex.Unlock()
+
+	server.closed = true
+	for conn := range server.conns {
+		conn.Close()
+		delete(server.conns, conn)
+	}
+
+	return nil
+}
+
+// track
+//	Add the connection to the open connections, returning false if the server has been closed.
+func (server *Server) track(conn net.Conn) bool {
+	server.connMutex.Lock()
+	defer server.connMutex.Unlock()
+
+	if server.closed { return false }
+
+	server.conns[conn] = struct{}{}
+	return true
+}
+
+// untrack
+//	Remove the connection from the open connections.
+func (server *Server) untrack(conn net.Conn) {
+	server.connMutex.Lock()
+	defer server.connMutex.Unlock()
+
+	delete(server.conns, conn)
+}
+
+// serveConn
+//	Read and execute commands from a single client until it quits, disconnects, or sends a malformed command.
+//	Replies are flushed once every pipelined command that has been received is answered.
+func (server *Server) serveConn(conn net.Conn) {
+	defer server.untrack(conn)
+	defer conn.Close()
+
+	sess := &session{
+		reader: bufio.NewReaderSize(conn, 64 << 10),
+		writer: bufio.NewWriter(conn),
+		cursors: make(map[uint64][]byte),
+		nextCursor: 1,
+	}
+
+	for {
+		args, readErr := readCommand(sess.reader)
+		if readErr != nil {
+			if readErr != io.EOF && ! errors.Is(readErr, net.ErrClosed) {
+				writeError(sess.writer, "ERR " + readErr.Error())
+				sess.writer.Flush()
+			}
+
+			return
+		}
+
+		isQuit := len(args) > 0 && server.execute(sess, args)
+		if isQuit || sess.reader.Buffered() == 0 {
+			flushErr := sess.writer.Flush()
+			if flushErr != nil || isQuit { return }
+		}
+	}
+}
+
+// execute
+//	Execute a single command, writing its reply. Returns true if the client asked to close the connection.
+func (server *Server) execute(sess *session, args [][]byte) bool {
+	w := sess.writer
+	name := strings.ToUpper(string(args[0]))
+	args = args[1:]
+
+	arity := func(min, max int) bool {
+		if len(args) >= min && (max < 0 || len(args) <= max) { return true }
+
+		writeError(w, fmt.Sprintf("ERR wrong number of arguments for '%s' command", strings.ToLower(name)))
+		return false
+	}
+
+	switch name {
+		case "PING":
+			if ! arity(0, 1) { return false }
+			if len(args) == 1 {
+				writeBulk(w, args[0])
+			} else { writeSimple(w, "PONG") }
+		case "ECHO":
+			if arity(1, 1) { writeBulk(w, args[0]) }
+		case "QUIT":
+			writeSimple(w, "OK")
+			return true
+		case "SELECT":
+			if ! arity(1, 1) { return false }
+			if string(args[0]) == "0" {
+				writeSimple(w, "OK")
+			} else { writeError(w, "ERR DB index is out of range") }
+		case "COMMAND":
+			writeArrayHeader(w, 0)
+		case "GET":
+			if arity(1, 1) { server.get(w, args[0]) }
+		case "SET":
+			if arity(2, 3) { server.set(w, args) }
+		case "DEL":
+			if arity(1, -1) { server.del(w, args) }
+		case "SCAN":
+			if arity(1, -1) { server.scan(sess, args) }
+		default:
+			writeError(w, fmt.Sprintf("ERR unknown command '%s'", strings.ToLower(name)))
+	}
+
+	return false
+}
+
+// get
+//	Reply with the value of the key, or a null bulk string if it does not exist.
+func (server *Server) get(w *bufio.Writer, key []byte) {
+	value, getErr := server.MMCMap.Get(key)
+	if errors.Is(getErr, mmcmap.ErrKeyNotFound) || (getErr == nil && value == nil) {
+		writeNull(w)
+		return
+	}
+
+	if getErr != nil {
+		writeMapError(w, getErr)
+		return
+	}
+
+	writeBulk(w, value)
+}
+
+// set
+//	Put the key-value pair. With NX, the pair is only put if the key does not exist, replying with a null bulk string otherwise.
+func (server *Server) set(w *bufio.Writer, args [][]byte) {
+	key, value := args[0], args[1]
+
+	if len(args) == 3 {
+		if strings.ToUpper(string(args[2])) != "NX" {
+			writeError(w, "ERR syntax error")
+			return
+		}
+
+		isPut, putErr := server.MMCMap.PutIfAbsent(key, value)
+		if putErr != nil {
+			writeMapError(w, putErr)
+			return
+		}
+
+		if isPut {
+			writeSimple(w, "OK")
+		} else { writeNull(w) }
+
+		return
+	}
+
+	_, putErr := server.MMCMap.Put(key, value)
+	if putErr != nil {
+		writeMapError(w, putErr)
+		return
+	}
+
+	writeSimple(w, "OK")
+}
+
+// del
+//	Delete every key, replying with the number of keys that existed. Each key is deleted as its own version.
+func (server *Server) del(w *bufio.Writer, keys [][]byte) {
+	var deleted int64
+	for _, key := range keys {
+		isDeleted, delErr := server.MMCMap.Delete(key)
+		if delErr != nil {
+			writeMapError(w, delErr)
+			return
+		}
+
+		if isDeleted { deleted++ }
+	}
+
+	writeInteger(w, deleted)
+}
+
+// scan
+//	Reply with the next page of keys in sorted order and the cursor to continue from, which is 0 once every key has been returned.
+//	Redis cursors are integers, so the continuation key of each page is kept on the session under a new cursor id. Like Redis, MATCH filters keys
+//	after the page is read, so a page may hold fewer keys than COUNT. TYPE is accepted for string, the only type stored, and returns nothing otherwise.
+//	COUNT is capped to MaxScanCount. Pages come from RangePage, so on a map in hashed key mode every page walks the whole trie to find the keys that
+//	follow the cursor, and a full SCAN costs a traversal per page. Maps scanned often should be created with KeyModeOrdered, where a page stops once it is full.
+func (server *Server) scan(sess *session, args [][]byte) {
+	w := sess.writer
+
+	cursor, parseErr := strconv.ParseUint(string(args[0]), 10, 64)
+	if parseErr != nil {
+		writeError(w, "ERR invalid cursor")
+		return
+	}
+
+	opts := mmcmap.RangeOpts{ Limit: DefaultScanCount, KeysOnly: true }
+	var pattern []byte
+	isStringType := true
+
+	for idx := 1; idx < len(args); idx += 2 {
+		if idx + 1 >= len(args) {
+			writeError(w, "ERR syntax error")
+			return
+		}
+
+		option, value := strings.ToUpper(string(args[idx])), args[idx + 1]
+		switch option {
+			case "MATCH":
+				pattern = value
+			case "COUNT":
+				count, countErr := strconv.Atoi(string(value))
+				if countErr != nil || count < 1 {
+					writeError(w, "ERR value is not an integer or out of range")
+					return
+				}
+
+				if server.MaxScanCount > 0 && count > server.MaxScanCount { count = server.MaxScanCount }
+				opts.Limit = count
+			case "TYPE":
+				isStringType = strings.ToLower(string(value)) == "string"
+			default:
+				writeError(w, "ERR syntax error")
+				return
+		}
+	}
+
+	if cursor != 0 {
+		after, isKnown := sess.cursors[cursor]
+		if ! isKnown {
+			writeError(w, "ERR invalid cursor")
+			return
+		}
+
+		delete(sess.cursors, cursor)
+		opts.After = after
+	}
+
+	if ! isStringType {
+		writeArrayHeader(w, 2)
+		writeBulk(w, []byte("0"))
+		writeArrayHeader(w, 0)
+		return
+	}
+
+	pairs, next, rangeErr := server.MMCMap.RangePage(nil, nil, opts)
+	if rangeErr != nil {
+		writeMapError(w, rangeErr)
+		return
+	}
+
+	var nextCursor uint64
+	if next != nil { nextCursor = sess.saveCursor(next) }
+
+	var keys [][]byte
+	for _, pair := range pairs {
+		if pattern == nil || matchGlob(pattern, pair.Key) { keys = append(keys, pair.Key) }
+	}
+
+	writeArrayHeader(w, 2)
+	writeBulk(w, []byte(strconv.FormatUint(nextCursor, 10)))
+	writeArrayHeader(w, len(keys))
+	for _, key := range keys { writeBulk(w, key) }
+}
+
+// saveCursor
+//	Keep the continuation key under a new cursor id, discarding the oldest cursor once MaxCursors are held.
+func (sess *session) saveCursor(after []byte) uint64 {
+	if len(sess.cursors) >= MaxCursors {
+		oldest := sess.nextCursor
+		for cursor := range sess.cursors {
+			if cursor < oldest { oldest = cursor }
+		}
+
+		delete(sess.cursors, oldest)
+	}
+
+	cursor := sess.nextCursor
+	sess.nextCursor++
+	sess.cursors[cursor] = after
+
+	return cursor
+}
+
+// readCommand
+//	Read a single command, either as a RESP array of bulk strings or as an inline command of space separated arguments.
+//	An empty inline command returns no arguments.
+func readCommand(reader *bufio.Reader) ([][]byte, error) {
+	line, readErr := readLine(reader)
+	if readErr != nil { return nil, readErr }
+
+	if len(line) == 0 || line[0] != '*' {
+		var args [][]byte
+		for _, field := range bytes.Fields(line) { args = append(args, append([]byte{}, field...)) }
+		return args, nil
+	}
+
+	count, parseErr := strconv.Atoi(string(line[1:]))
+	if parseErr != nil || count > MaxArgs { return nil, errProtocol }
+
+	var args [][]byte
+	for idx := 0; idx < count; idx++ {
+		header, headerErr := readLine(reader)
+		if headerErr != nil { return nil, headerErr }
+		if len(header) == 0 || header[0] != '$' { return nil, errProtocol }
+
+		length, lenErr := strconv.Atoi(string(header[1:]))
+		if lenErr != nil || length < 0 || length > MaxBulkLength { return nil, errProtocol }
+
+		bulk := make([]byte, length + 2)
+		_, bulkErr := io.ReadFull(reader, bulk)
+		if bulkErr != nil { return nil, bulkErr }
+		if bulk[length] != '\r' || bulk[length + 1] != '\n' { return nil, errProtocol }
+
+		args = append(args, bulk[:length])
+	}
+
+	return args, nil
+}
+
+// readLine
+//	Read a line terminated by CRLF, or LF for inline commands, without the terminator. Lines longer than the read buffer are a protocol error.
+func readLine(reader *bufio.Reader) ([]byte, error) {
+	line, readErr := reader.ReadSlice('\n')
+	if readErr == bufio.ErrBufferFull { return nil, errProtocol }
+	if readErr != nil { return nil, readErr }
+
+	return bytes.TrimSuffix(line[:len(line) - 1], []byte("\r")), nil
+}
+
+// matchGlob
+//	Determine if str matches the Redis glob pattern, which supports *, ?, [...] classes with ranges and ^ negation, and \ escapes.
+func matchGlob(pattern, str []byte) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+			case '*':
+				for len(pattern) > 1 && pattern[1] == '*' { pattern = pattern[1:] }
+				if len(pattern) == 1 { return true }
+
+				for idx := 0; idx <= len(str); idx++ {
+					if matchGlob(pattern[1:], str[idx:]) { return true }
+				}
+
+				return false
+			case '?':
+				if len(str) == 0 { return false }
+
+				pattern, str = pattern[1:], str[1:]
+			case '[':
+				if len(str) == 0 { return false }
+
+				pattern = pattern[1:]
+				isNegated := len(pattern) > 0 && pattern[0] == '^'
+				if isNegated { pattern = pattern[1:] }
+
+				isMatched := false
+				for len(pattern) > 0 && pattern[0] != ']' {
+					if pattern[0] == '\\' && len(pattern) >= 2 {
+						if pattern[1] == str[0] { isMatched = true }
+						pattern = pattern[2:]
+					} else if len(pattern) >= 3 && pattern[1] == '-' {
+						low, high := pattern[0], pattern[2]
+						if low > high { low, high = high, low }
+						if str[0] >= low && str[0] <= high { isMatched = true }
+						pattern = pattern[3:]
+					} else {
+						if pattern[0] == str[0] { isMatched = true }
+						pattern = pattern[1:]
+					}
+				}
+
+				if len(pattern) > 0 { pattern = pattern[1:] }
+				if isMatched == isNegated { return false }
+
+				str = str[1:]
+			case '\\':
+				if len(pattern) >= 2 { pattern = pattern[1:] }
+				fallthrough
+			default:
+				if len(str) == 0 || pattern[0] != str[0] { return false }
+
+				pattern, str = pattern[1:], str[1:]
+		}
+	}
+
+	return len(str) == 0
+}
+
+// writeMapError
+//	Reply with an error returned by the map. Writes to a follower reply with READONLY, as Redis replicas do.
+func writeMapError(w *bufio.Writer, err error) {
+	if errors.Is(err, mmcmap.ErrFollowerReadOnly) {
+		writeError(w, "READONLY " + err.Error())
+		return
+	}
+
+	writeError(w, "ERR " + err.Error())
+}
+
+// writeSimple
+//	Reply with a simple string.
+func writeSimple(w *bufio.Writer, str string) {
+	w.WriteString("+" + str + "\r\n")
+}
+
+// writeError
+//	Reply with an error, where the first word is the error prefix. Line breaks are removed, since they would end the reply.
+func writeError(w *bufio.Writer, msg string) {
+	w.WriteString("-" + strings.NewReplacer("\r", " ", "\n", " ").Replace(msg) + "\r\n")
+}
+
+// writeInteger
+//	Reply with an integer.
+func writeInteger(w *bufio.Writer, val int64) {
+	w.WriteString(":" + strconv.FormatInt(val, 10) + "\r\n")
+}
+
+// writeBulk
+//	Reply with a bulk string.
+func writeBulk(w *bufio.Writer, data []byte) {
+	w.WriteString("$" + strconv.Itoa(len(data)) + "\r\n")
+	w.Write(data)
+	w.WriteString("\r\n")
+}
+
+// writeNull
+//	Reply with a null bulk string.
+func writeNull(w *bufio.Writer) {
+	w.WriteString("$-1\r\n")
+}
+
+// writeArrayHeader
+//	Begin an array reply of length elements.
+func writeArrayHeader(w *bufio.Writer, length int) {
+	w.WriteString("*" + strconv.Itoa(length) + "\r\n")
+}
@@ -0,0 +1,46 @@
+package resp
+
+import "bufio"
+import "net"
+import "sync"
+
+import "github.com/sirgallo/mmcmap"
+
+
+// Server serves a minimal subset of the Redis protocol over a single mmcmap
+type Server struct {
+	// MMCMap: the map every command is applied to
+	MMCMap *mmcmap.MMCMap
+	// MaxScanCount: the most keys a single SCAN page reads, since the page is held in memory before it is written. A larger COUNT is capped to it.
+	// 0 does not cap COUNT. Defaults to DefaultMaxScanCount
+	MaxScanCount int
+	// connMutex: guards conns
+	connMutex sync.Mutex
+	// conns: the open client connections, closed when the server is closed
+	conns map[net.Conn]struct{}
+	// closed: flag indicating Close was called, so new connections are rejected
+	closed bool
+}
+
+// session is the state of a single client connection
+type session struct {
+	reader *bufio.Reader
+	writer *bufio.Writer
+	// cursors: the continuation key of each SCAN in progress, by cursor id
+	cursors map[uint64][]byte
+	// nextCursor: the id assigned to the next SCAN cursor
+	nextCursor uint64
+}
+
+const (
+	// MaxBulkLength: the largest bulk string accepted in a command
+	MaxBulkLength = 512 << 20
+	// MaxArgs: the largest number of arguments accepted in a command
+	MaxArgs = 1 << 20
+	// DefaultScanCount: the number of keys returned by SCAN when COUNT is not given
+	DefaultScanCount = 10
+	// MaxCursors: the number of SCAN cursors kept per connection before the oldest are discarded
+	MaxCursors = 1024
+	// DefaultMaxScanCount: the default cap on the COUNT of a SCAN
+	DefaultMaxScanCount = 1000
+)
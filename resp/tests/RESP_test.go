@@ -0,0 +1,202 @@
+package resptests
+
+import "bufio"
+import "fmt"
+import "net"
+import "os"
+import "path/filepath"
+import "strconv"
+import "strings"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+import "github.com/sirgallo/mmcmap/resp"
+
+
+var respTestPath = filepath.Join(os.TempDir(), "testresp")
+var respTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initRespMapErr error
+	os.Remove(respTestPath)
+
+	respTestMap, initRespMapErr = mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: respTestPath })
+	if initRespMapErr != nil { panic(initRespMapErr.Error()) }
+
+	fmt.Println("resp test mmcmap initialized")
+}
+
+
+// respError is an error reply
+type respError string
+
+// client sends commands as RESP arrays and parses the replies
+type client struct {
+	conn net.Conn
+	reader *bufio.Reader
+}
+
+func (c *client) send(t *testing.T, args ...string) {
+	cmd := "*" + strconv.Itoa(len(args)) + "\r\n"
+	for _, arg := range args { cmd += "$" + strconv.Itoa(len(arg)) + "\r\n" + arg + "\r\n" }
+
+	_, writeErr := c.conn.Write([]byte(cmd))
+	if writeErr != nil { t.Fatalf("error writing command: %s", writeErr.Error()) }
+}
+
+func (c *client) do(t *testing.T, args ...string) interface{} {
+	c.send(t, args...)
+	return c.read(t)
+}
+
+// read parses a reply into a string for simple strings, respError for errors, int64 for integers, []byte or nil for bulk strings,
+// and []interface{} for arrays
+func (c *client) read(t *testing.T) interface{} {
+	line, readErr := c.reader.ReadString('\n')
+	if readErr != nil { t.Fatalf("error reading reply: %s", readErr.Error()) }
+
+	line = strings.TrimSuffix(line, "\r\n")
+	switch line[0] {
+		case '+':
+			return line[1:]
+		case '-':
+			return respError(line[1:])
+		case ':':
+			val, _ := strconv.ParseInt(line[1:], 10, 64)
+			return val
+		case '$':
+			length, _ := strconv.Atoi(line[1:])
+			if length < 0 { return nil }
+
+			bulk := make([]byte, length + 2)
+			for read := 0; read < len(bulk); {
+				n, bulkErr := c.reader.Read(bulk[read:])
+				if bulkErr != nil { t.Fatalf("error reading bulk: %s", bulkErr.Error()) }
+				read += n
+			}
+
+			return bulk[:length]
+		case '*':
+			length, _ := strconv.Atoi(line[1:])
+			elems := make([]interface{}, length)
+			for idx := range elems { elems[idx] = c.read(t) }
+			return elems
+	}
+
+	t.Fatalf("unknown reply: %q", line)
+	return nil
+}
+
+
+func TestRESP(t *testing.T) {
+	defer respTestMap.Remove()
+
+	listener, listenErr := net.Listen("tcp", "127.0.0.1:0")
+	if listenErr != nil { t.Fatalf("error on listen: %s", listenErr.Error()) }
+
+	server := resp.NewServer(respTestMap)
+	server.MaxScanCount = 10
+	go server.Serve(listener)
+	defer server.Close()
+	defer listener.Close()
+
+	connect := func(t *testing.T) *client {
+		conn, dialErr := net.Dial("tcp", listener.Addr().String())
+		if dialErr != nil { t.Fatalf("error on dial: %s", dialErr.Error()) }
+		return &client{ conn: conn, reader: bufio.NewReader(conn) }
+	}
+
+	t.Run("Test Get Set Del", func(t *testing.T) {
+		c := connect(t)
+		defer c.conn.Close()
+
+		if reply := c.do(t, "PING"); reply != "PONG" { t.Errorf("ping mismatch: actual(%v), expected(PONG)", reply) }
+		if reply := c.do(t, "SET", "hello", "world"); reply != "OK" { t.Fatalf("set mismatch: actual(%v), expected(OK)", reply) }
+
+		reply := c.do(t, "GET", "hello")
+		if value, _ := reply.([]byte); string(value) != "world" { t.Errorf("get mismatch: actual(%v), expected(world)", reply) }
+
+		if reply := c.do(t, "SET", "hello", "again", "NX"); reply != nil { t.Errorf("set nx on existing key mismatch: actual(%v), expected(nil)", reply) }
+		if reply := c.do(t, "SET", "fresh", "value", "nx"); reply != "OK" { t.Errorf("set nx on missing key mismatch: actual(%v), expected(OK)", reply) }
+
+		if reply := c.do(t, "DEL", "hello", "fresh", "missing"); reply != int64(2) { t.Errorf("del count mismatch: actual(%v), expected(2)", reply) }
+		if reply := c.do(t, "GET", "hello"); reply != nil { t.Errorf("get deleted key mismatch: actual(%v), expected(nil)", reply) }
+	})
+
+	t.Run("Test Pipelined And Inline Commands", func(t *testing.T) {
+		c := connect(t)
+		defer c.conn.Close()
+
+		for idx := 0; idx < 100; idx++ { c.send(t, "SET", fmt.Sprintf("pipe%d", idx), strconv.Itoa(idx)) }
+		for idx := 0; idx < 100; idx++ {
+			if reply := c.read(t); reply != "OK" { t.Fatalf("pipelined set mismatch: actual(%v), expected(OK)", reply) }
+		}
+
+		_, writeErr := c.conn.Write([]byte("GET pipe42\r\n"))
+		if writeErr != nil { t.Fatalf("error writing inline command: %s", writeErr.Error()) }
+
+		reply := c.read(t)
+		if value, _ := reply.([]byte); string(value) != "42" { t.Errorf("inline get mismatch: actual(%v), expected(42)", reply) }
+	})
+
+	t.Run("Test Scan", func(t *testing.T) {
+		c := connect(t)
+		defer c.conn.Close()
+
+		for idx := 0; idx < 25; idx++ {
+			_, putErr := respTestMap.Put([]byte(fmt.Sprintf("scan:%02d", idx)), []byte("value"))
+			if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+		}
+
+		seen := make(map[string]bool)
+		cursor := "0"
+		for pages := 0; ; pages++ {
+			if pages > 100 { t.Fatal("scan did not terminate") }
+
+			reply, _ := c.do(t, "SCAN", cursor, "MATCH", "scan:[0-1]?", "COUNT", "7").([]interface{})
+			if len(reply) != 2 { t.Fatalf("scan reply mismatch: actual(%v)", reply) }
+
+			for _, key := range reply[1].([]interface{}) { seen[string(key.([]byte))] = true }
+
+			cursor = string(reply[0].([]byte))
+			if cursor == "0" { break }
+		}
+
+		if len(seen) != 20 { t.Errorf("scan match count mismatch: actual(%d), expected(20)", len(seen)) }
+		for idx := 0; idx < 20; idx++ {
+			if ! seen[fmt.Sprintf("scan:%02d", idx)] { t.Errorf("scan missing key: scan:%02d", idx) }
+		}
+
+		if _, isErr := c.do(t, "SCAN", "12345").(respError); ! isErr { t.Error("unknown cursor did not return an error") }
+	})
+
+	t.Run("Test Scan Count Is Capped", func(t *testing.T) {
+		c := connect(t)
+		defer c.conn.Close()
+
+		reply, _ := c.do(t, "SCAN", "0", "COUNT", "1000").([]interface{})
+		if len(reply) != 2 { t.Fatalf("scan reply mismatch: actual(%v)", reply) }
+
+		keys := reply[1].([]interface{})
+		if len(keys) != 10 { t.Errorf("scan page was not capped: actual(%d), expected(10)", len(keys)) }
+		if string(reply[0].([]byte)) == "0" { t.Error("capped scan did not return a cursor to continue from") }
+	})
+
+	t.Run("Test Errors", func(t *testing.T) {
+		c := connect(t)
+		defer c.conn.Close()
+
+		cases := [][]string{ { "FLUSHALL" }, { "GET" }, { "SET", "a", "b", "EX" }, { "SCAN", "x" }, { "SELECT", "1" } }
+		for _, args := range cases {
+			if _, isErr := c.do(t, args...).(respError); ! isErr { t.Errorf("%v did not return an error", args) }
+		}
+
+		if reply := c.do(t, "QUIT"); reply != "OK" { t.Errorf("quit mismatch: actual(%v), expected(OK)", reply) }
+
+		_, readErr := c.reader.ReadByte()
+		if readErr == nil { t.Error("connection was not closed after quit") }
+	})
+
+	t.Log("Done")
+}
@@ -0,0 +1,134 @@
+package server
+
+import "context"
+import "errors"
+
+import "google.golang.org/grpc"
+import "google.golang.org/grpc/codes"
+import "google.golang.org/grpc/status"
+
+import "github.com/sirgallo/mmcmap"
+import "github.com/sirgallo/mmcmap/server/pb"
+
+
+//============================================= MMCMap gRPC Server
+
+
+// NewServer
+//	Create the gRPC service for mmcMap, so remote processes and clients in other languages can use the map.
+//	The service is defined in pb/mmcmap.proto, and clients can be generated from it for any language supported by protoc.
+func NewServer(mmcMap *mmcmap.MMCMap) *Server {
+	return &Server{ MMCMap: mmcMap, MaxRangeLimit: DefaultMaxRangeLimit }
+}
+
+// Register
+//	Register the service on a gRPC server. The map is not closed when the gRPC server stops.
+func (server *Server) Register(grpcServer *grpc.Server) {
+	pb.RegisterMMCMapServer(grpcServer, server)
+}
+
+// Put
+//	Insert or update the key-value pair.
+func (server *Server) Put(ctx context.Context, request *pb.PutRequest) (*pb.PutResponse, error) {
+	ok, putErr := server.MMCMap.Put(request.Key, request.Value)
+	if putErr != nil { return nil, toStatus(putErr) }
+
+	return &pb.PutResponse{ Ok: ok }, nil
+}
+
+// Get
+//	Retrieve the value of the key at the latest version. Missing keys are reported with Found set to false, regardless of StrictGet.
+func (server *Server) Get(ctx context.Context, request *pb.GetRequest) (*pb.GetResponse, error) {
+	value, getErr := server.MMCMap.Get(request.Key)
+	if errors.Is(getErr, mmcmap.ErrKeyNotFound) { return &pb.GetResponse{ Found: false }, nil }
+	if getErr != nil { return nil, toStatus(getErr) }
+
+	return &pb.GetResponse{ Value: value, Found: value != nil }, nil
+}
+
+// Delete
+//	Remove the key, reporting if it existed.
+func (server *Server) Delete(ctx context.Context, request *pb.DeleteRequest) (*pb.DeleteResponse, error) {
+	deleted, delErr := server.MMCMap.Delete(request.Key)
+	if delErr != nil { return nil, toStatus(delErr) }
+
+	return &pb.DeleteResponse{ Deleted: deleted }, nil
+}
+
+// Range
+//	Stream a page of the pairs between the bounds of the request in sorted order. The page is loaded into memory before it is sent, so the limit of the
+//	request is capped to MaxRangeLimit, and a request without a limit returns at most MaxRangeLimit pairs. When the page is cut short by the limit,
+//	the last pair is sent with Truncated set and Next holding the key to pass as After for the next page. Use Iterate to stream a whole range.
+func (server *Server) Range(request *pb.RangeRequest, stream pb.MMCMap_RangeServer) error {
+	opts := mmcmap.RangeOpts{ Limit: server.rangeLimit(request.Limit), Reverse: request.Reverse, After: toBound(request.After), KeysOnly: request.KeysOnly }
+
+	pairs, next, rangeErr := server.MMCMap.RangePage(toBound(request.StartKey), toBound(request.EndKey), opts)
+	if rangeErr != nil { return toStatus(rangeErr) }
+
+	for idx, pair := range pairs {
+		kv := &pb.KeyValue{ Key: pair.Key, Value: pair.Value, Version: pair.Version }
+		if next != nil && idx == len(pairs) - 1 {
+			kv.Truncated = true
+			kv.Next = next
+		}
+
+		sendErr := stream.Send(kv)
+		if sendErr != nil { return sendErr }
+	}
+
+	return nil
+}
+
+// rangeLimit
+//	The limit of a Range request, capped to MaxRangeLimit.
+func (server *Server) rangeLimit(limit uint32) int {
+	if server.MaxRangeLimit > 0 && (limit == 0 || uint64(limit) > uint64(server.MaxRangeLimit)) { return server.MaxRangeLimit }
+	return int(limit)
+}
+
+// Iterate
+//	Stream the pairs between the bounds of the request in trie order as they are read from the memory map, so the range is never held in memory.
+//	The traversal is pinned to the latest root for the duration of the stream, and stops as soon as the client goes away or a send fails, releasing
+//	its pin so Compact and Reclaim are not held up by an abandoned stream.
+func (server *Server) Iterate(request *pb.IterateRequest, stream pb.MMCMap_IterateServer) error {
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	pairs, errs := server.MMCMap.RangeChanWithContext(ctx, toBound(request.StartKey), toBound(request.EndKey))
+
+	for pair := range pairs {
+		sendErr := stream.Send(&pb.KeyValue{ Key: pair.Key, Value: pair.Value, Version: pair.Version })
+		if sendErr != nil { return sendErr }
+	}
+
+	rangeErr := <-errs
+	if rangeErr != nil { return toStatus(rangeErr) }
+
+	return nil
+}
+
+// toBound
+//	Convert a range bound from a request, where an empty bound is unbounded.
+func toBound(key []byte) []byte {
+	if len(key) == 0 { return nil }
+	return key
+}
+
+// toStatus
+//	Convert an error returned by the map to a gRPC status, so clients can tell invalid requests apart from unavailable or failed maps.
+func toStatus(err error) error {
+	switch {
+		case errors.Is(err, mmcmap.ErrKeyTooLarge), errors.Is(err, mmcmap.ErrValueTooLarge):
+			return status.Error(codes.InvalidArgument, err.Error())
+		case errors.Is(err, mmcmap.ErrFollowerReadOnly):
+			return status.Error(codes.FailedPrecondition, err.Error())
+		case errors.Is(err, mmcmap.ErrWriteStall), errors.Is(err, mmcmap.ErrMapFull):
+			return status.Error(codes.ResourceExhausted, err.Error())
+		case errors.Is(err, mmcmap.ErrMapClosed), errors.Is(err, mmcmap.ErrMapDetached):
+			return status.Error(codes.Unavailable, err.Error())
+		case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+			return status.FromContextError(err).Err()
+		default:
+			return status.Error(codes.Internal, err.Error())
+	}
+}
@@ -0,0 +1,19 @@
+package server
+
+import "github.com/sirgallo/mmcmap"
+import "github.com/sirgallo/mmcmap/server/pb"
+
+
+// Server implements the MMCMap gRPC service, backed by a single mmcmap
+type Server struct {
+	pb.UnimplementedMMCMapServer
+	// MMCMap: the map every request is served from
+	MMCMap *mmcmap.MMCMap
+	// MaxRangeLimit: the most pairs a single Range request loads, since the page is held in memory before it is streamed. Requests without a
+	// limit, or with a larger one, are capped to it. 0 does not cap requests. Defaults to DefaultMaxRangeLimit
+	MaxRangeLimit int
+}
+
+
+// DefaultMaxRangeLimit is the default cap on the pairs loaded by a single Range request
+const DefaultMaxRangeLimit = 10000
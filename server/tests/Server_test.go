@@ -0,0 +1,170 @@
+package servertests
+
+import "bytes"
+import "context"
+import "fmt"
+import "io"
+import "net"
+import "os"
+import "path/filepath"
+import "testing"
+
+import "google.golang.org/grpc"
+import "google.golang.org/grpc/codes"
+import "google.golang.org/grpc/credentials/insecure"
+import "google.golang.org/grpc/status"
+
+import "github.com/sirgallo/mmcmap"
+import "github.com/sirgallo/mmcmap/server"
+import "github.com/sirgallo/mmcmap/server/pb"
+
+
+var srvTestPath = filepath.Join(os.TempDir(), "testserver")
+var serverTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initSrvMapErr error
+	os.Remove(srvTestPath)
+
+	serverTestMap, initSrvMapErr = mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: srvTestPath })
+	if initSrvMapErr != nil { panic(initSrvMapErr.Error()) }
+
+	fmt.Println("server test mmcmap initialized")
+}
+
+
+func TestServer(t *testing.T) {
+	defer serverTestMap.Remove()
+
+	listener, listenErr := net.Listen("tcp", "127.0.0.1:0")
+	if listenErr != nil { t.Fatalf("error on listen: %s", listenErr.Error()) }
+
+	grpcServer := grpc.NewServer()
+	srv := server.NewServer(serverTestMap)
+	srv.MaxRangeLimit = 50
+	srv.Register(grpcServer)
+
+	go grpcServer.Serve(listener)
+	defer grpcServer.Stop()
+
+	conn, dialErr := grpc.Dial(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if dialErr != nil { t.Fatalf("error on dial: %s", dialErr.Error()) }
+	defer conn.Close()
+
+	client := pb.NewMMCMapClient(conn)
+	ctx := context.Background()
+
+	receiveAll := func(t *testing.T, recv func() (*pb.KeyValue, error)) []*pb.KeyValue {
+		var pairs []*pb.KeyValue
+		for {
+			pair, recvErr := recv()
+			if recvErr == io.EOF { return pairs }
+			if recvErr != nil { t.Fatalf("error receiving pair: %s", recvErr.Error()) }
+
+			pairs = append(pairs, pair)
+		}
+	}
+
+	t.Run("Test Put Get Delete", func(t *testing.T) {
+		for idx := 0; idx < 100; idx++ {
+			key := []byte(fmt.Sprintf("key%03d", idx))
+			_, putErr := client.Put(ctx, &pb.PutRequest{ Key: key, Value: key })
+			if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+		}
+
+		getResp, getErr := client.Get(ctx, &pb.GetRequest{ Key: []byte("key042") })
+		if getErr != nil { t.Fatalf("error on get: %s", getErr.Error()) }
+		if ! getResp.Found || ! bytes.Equal(getResp.Value, []byte("key042")) { t.Errorf("get mismatch: actual(%v %s), expected(true key042)", getResp.Found, getResp.Value) }
+
+		delResp, delErr := client.Delete(ctx, &pb.DeleteRequest{ Key: []byte("key042") })
+		if delErr != nil { t.Fatalf("error on delete: %s", delErr.Error()) }
+		if ! delResp.Deleted { t.Error("existing key was not reported as deleted") }
+
+		delResp, delErr = client.Delete(ctx, &pb.DeleteRequest{ Key: []byte("key042") })
+		if delErr != nil { t.Fatalf("error on delete: %s", delErr.Error()) }
+		if delResp.Deleted { t.Error("missing key was reported as deleted") }
+
+		getResp, getErr = client.Get(ctx, &pb.GetRequest{ Key: []byte("key042") })
+		if getErr != nil { t.Fatalf("error on get: %s", getErr.Error()) }
+		if getResp.Found { t.Errorf("deleted key was found: actual(%s)", getResp.Value) }
+	})
+
+	t.Run("Test Range", func(t *testing.T) {
+		stream, rangeErr := client.Range(ctx, &pb.RangeRequest{ StartKey: []byte("key010"), EndKey: []byte("key019"), Reverse: true })
+		if rangeErr != nil { t.Fatalf("error on range: %s", rangeErr.Error()) }
+
+		pairs := receiveAll(t, stream.Recv)
+		if len(pairs) != 10 { t.Fatalf("range count mismatch: actual(%d), expected(10)", len(pairs)) }
+
+		for idx, pair := range pairs {
+			expected := []byte(fmt.Sprintf("key%03d", 19 - idx))
+			if ! bytes.Equal(pair.Key, expected) || ! bytes.Equal(pair.Value, expected) { t.Errorf("range pair mismatch: actual(%s), expected(%s)", pair.Key, expected) }
+		}
+
+		stream, rangeErr = client.Range(ctx, &pb.RangeRequest{ Limit: 5, KeysOnly: true })
+		if rangeErr != nil { t.Fatalf("error on range: %s", rangeErr.Error()) }
+
+		pairs = receiveAll(t, stream.Recv)
+		if len(pairs) != 5 { t.Errorf("range limit mismatch: actual(%d), expected(5)", len(pairs)) }
+		if len(pairs) > 0 && pairs[0].Value != nil { t.Errorf("keys only range returned a value: %s", pairs[0].Value) }
+	})
+
+	t.Run("Test Range Limit Capped", func(t *testing.T) {
+		for _, limit := range []uint32{ 0, 80 } {
+			stream, rangeErr := client.Range(ctx, &pb.RangeRequest{ Limit: limit })
+			if rangeErr != nil { t.Fatalf("error on range: %s", rangeErr.Error()) }
+
+			pairs := receiveAll(t, stream.Recv)
+			if len(pairs) != 50 { t.Errorf("range with limit %d not capped: actual(%d), expected(50)", limit, len(pairs)) }
+		}
+	})
+
+	t.Run("Test Range Pages", func(t *testing.T) {
+		var keys [][]byte
+		var after []byte
+
+		for pages := 0; pages < 10; pages++ {
+			stream, rangeErr := client.Range(ctx, &pb.RangeRequest{ Limit: 30, KeysOnly: true, After: after })
+			if rangeErr != nil { t.Fatalf("error on range: %s", rangeErr.Error()) }
+
+			pairs := receiveAll(t, stream.Recv)
+			for idx, pair := range pairs {
+				if pair.Truncated != (idx == len(pairs) - 1 && pair.Next != nil) { t.Errorf("truncated set on pair %d of %d: %s", idx, len(pairs), pair.Key) }
+				keys = append(keys, pair.Key)
+			}
+
+			last := pairs[len(pairs) - 1]
+			if ! last.Truncated { break }
+			if ! bytes.Equal(last.Next, last.Key) { t.Errorf("next mismatch: actual(%s), expected(%s)", last.Next, last.Key) }
+
+			after = last.Next
+		}
+
+		if len(keys) != 99 { t.Fatalf("paged range count mismatch: actual(%d), expected(99)", len(keys)) }
+		for idx := 1; idx < len(keys); idx++ {
+			if bytes.Compare(keys[idx - 1], keys[idx]) >= 0 { t.Errorf("paged range out of order: %s before %s", keys[idx - 1], keys[idx]) }
+		}
+
+		stream, rangeErr := client.Range(ctx, &pb.RangeRequest{ Limit: 80 })
+		if rangeErr != nil { t.Fatalf("error on range: %s", rangeErr.Error()) }
+
+		pairs := receiveAll(t, stream.Recv)
+		if len(pairs) != 50 || ! pairs[49].Truncated { t.Errorf("capped range not truncated: actual(%d)", len(pairs)) }
+	})
+
+	t.Run("Test Iterate", func(t *testing.T) {
+		stream, iterErr := client.Iterate(ctx, &pb.IterateRequest{})
+		if iterErr != nil { t.Fatalf("error on iterate: %s", iterErr.Error()) }
+
+		pairs := receiveAll(t, stream.Recv)
+		if len(pairs) != 99 { t.Errorf("iterate count mismatch: actual(%d), expected(99)", len(pairs)) }
+	})
+
+	t.Run("Test Invalid Key", func(t *testing.T) {
+		_, putErr := client.Put(ctx, &pb.PutRequest{ Key: make([]byte, mmcmap.MaxKeyLength + 1) })
+		if status.Code(putErr) != codes.InvalidArgument { t.Errorf("status code mismatch: actual(%s), expected(%s)", status.Code(putErr), codes.InvalidArgument) }
+	})
+
+	t.Log("Done")
+}
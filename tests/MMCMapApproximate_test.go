@@ -0,0 +1,106 @@
+package mmcmaptests
+
+import "bufio"
+import "bytes"
+import "encoding/json"
+import "fmt"
+import "os"
+import "path/filepath"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var approximateTestPath = filepath.Join(os.TempDir(), "testapproximate")
+var approximateTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initApproximateMapErr error
+	os.Remove(approximateTestPath)
+
+	approximateTestMap, initApproximateMapErr = mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: approximateTestPath })
+	if initApproximateMapErr != nil { panic(initApproximateMapErr.Error()) }
+
+	fmt.Println("approximate test mmcmap initialized")
+}
+
+
+func TestMMCMapApproximate(t *testing.T) {
+	defer approximateTestMap.Remove()
+
+	exactSize := func(t *testing.T, startKey, endKey []byte) uint64 {
+		var buf bytes.Buffer
+		dumpErr := approximateTestMap.Dump(&buf, mmcmap.DumpOpts{ PreviewBytes: 64 })
+		if dumpErr != nil { t.Fatalf("error on dump: %s", dumpErr.Error()) }
+
+		var size uint64
+		scanner := bufio.NewScanner(&buf)
+		for scanner.Scan() {
+			var node mmcmap.DumpNode
+			decodeErr := json.Unmarshal(scanner.Bytes(), &node)
+			if decodeErr != nil { t.Fatalf("error decoding node: %s", decodeErr.Error()) }
+
+			if node.IsLeaf && node.Key >= string(startKey) && (endKey == nil || node.Key <= string(endKey)) { size += node.EndOffset - node.Offset + 1 }
+		}
+
+		return size
+	}
+
+	putKeys := func(t *testing.T, from, to int) {
+		batch := approximateTestMap.NewWriteBatch()
+		for idx := from; idx < to; idx++ {
+			batch.Put([]byte(fmt.Sprintf("key%06d", idx)), bytes.Repeat([]byte("v"), 32))
+		}
+
+		_, commitErr := batch.Commit()
+		if commitErr != nil { t.Fatalf("error on commit: %s", commitErr.Error()) }
+	}
+
+	t.Run("Test Exact Below Sample Size", func(t *testing.T) {
+		putKeys(t, 0, 1000)
+
+		approximate, sizeErr := approximateTestMap.ApproximateSize(nil, nil)
+		if sizeErr != nil { t.Fatalf("error on approximate size: %s", sizeErr.Error()) }
+
+		exact := exactSize(t, nil, nil)
+		if approximate != exact { t.Errorf("size mismatch: actual(%d), expected(%d)", approximate, exact) }
+
+		approximate, sizeErr = approximateTestMap.ApproximateSize([]byte("key000100"), []byte("key000199"))
+		if sizeErr != nil { t.Fatalf("error on approximate size: %s", sizeErr.Error()) }
+
+		exact = exactSize(t, []byte("key000100"), []byte("key000199"))
+		if approximate != exact { t.Errorf("range size mismatch: actual(%d), expected(%d)", approximate, exact) }
+	})
+
+	t.Run("Test Sampled Estimate", func(t *testing.T) {
+		for from := 1000; from < 40000; from += 5000 { putKeys(t, from, from + 5000) }
+
+		for _, bounds := range [][2][]byte{ { nil, nil }, { []byte("key000000"), []byte("key019999") } } {
+			approximate, sizeErr := approximateTestMap.ApproximateSize(bounds[0], bounds[1])
+			if sizeErr != nil { t.Fatalf("error on approximate size: %s", sizeErr.Error()) }
+
+			exact := exactSize(t, bounds[0], bounds[1])
+			if float64(approximate) < 0.8 * float64(exact) || float64(approximate) > 1.2 * float64(exact) {
+				t.Errorf("estimate for [%s, %s] not within 20%%: actual(%d), expected(%d)", bounds[0], bounds[1], approximate, exact)
+			}
+		}
+	})
+
+	t.Run("Test Dead Bytes", func(t *testing.T) {
+		_, compactErr := approximateTestMap.Compact()
+		if compactErr != nil { t.Fatalf("error on compact: %s", compactErr.Error()) }
+
+		compacted, deadErr := approximateTestMap.DeadBytes()
+		if deadErr != nil { t.Fatalf("error on dead bytes: %s", deadErr.Error()) }
+
+		putKeys(t, 0, 1000)
+
+		overwritten, deadErr := approximateTestMap.DeadBytes()
+		if deadErr != nil { t.Fatalf("error on dead bytes: %s", deadErr.Error()) }
+
+		if overwritten <= compacted { t.Errorf("overwriting keys did not add dead bytes: actual(%d), compacted(%d)", overwritten, compacted) }
+	})
+
+	t.Log("Done")
+}
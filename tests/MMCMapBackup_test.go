@@ -0,0 +1,164 @@
+package mmcmaptests
+
+import "bytes"
+import "errors"
+import "fmt"
+import "os"
+import "path/filepath"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var bkSourceTestPath = filepath.Join(os.TempDir(), "testbackupsource")
+var bkRestoreTestPath = filepath.Join(os.TempDir(), "testbackuprestore")
+var backupTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initBkMapErr error
+	os.Remove(bkSourceTestPath)
+
+	backupTestMap, initBkMapErr = mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: bkSourceTestPath })
+	if initBkMapErr != nil { panic(initBkMapErr.Error()) }
+
+	fmt.Println("backup test mmcmap initialized")
+}
+
+
+func TestMMCMapBackup(t *testing.T) {
+	defer backupTestMap.Remove()
+
+	os.Remove(bkRestoreTestPath)
+	restoreTestMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: bkRestoreTestPath })
+	if openErr != nil { t.Fatalf("error opening restore map: %s", openErr.Error()) }
+	defer restoreTestMap.Remove()
+
+	backupSince := func(t *testing.T, version uint64) (*mmcmap.BackupReport, *bytes.Buffer) {
+		var buf bytes.Buffer
+		report, backupErr := backupTestMap.BackupSince(&buf, version)
+		if backupErr != nil { t.Fatalf("error on backup: %s", backupErr.Error()) }
+
+		return report, &buf
+	}
+
+	assertRestored := func(t *testing.T) {
+		expected, expectedErr := backupTestMap.Range(nil, nil)
+		if expectedErr != nil { t.Fatalf("error ranging source: %s", expectedErr.Error()) }
+
+		actual, actualErr := restoreTestMap.Range(nil, nil)
+		if actualErr != nil { t.Fatalf("error ranging restored map: %s", actualErr.Error()) }
+
+		if len(actual) != len(expected) { t.Fatalf("restored key count mismatch: actual(%d), expected(%d)", len(actual), len(expected)) }
+
+		for idx := range expected {
+			if ! bytes.Equal(actual[idx].Key, expected[idx].Key) || ! bytes.Equal(actual[idx].Value, expected[idx].Value) {
+				t.Errorf("restored pair mismatch: actual(%s: %s), expected(%s: %s)", actual[idx].Key, actual[idx].Value, expected[idx].Key, expected[idx].Value)
+			}
+		}
+	}
+
+	var lastVersion uint64
+
+	t.Run("Test Full Backup", func(t *testing.T) {
+		for idx := 0; idx < 1000; idx++ {
+			_, putErr := backupTestMap.Put([]byte(fmt.Sprintf("key%d", idx)), []byte(fmt.Sprintf("value%d", idx)))
+			if putErr != nil { t.Errorf("error on put: %s", putErr.Error()) }
+		}
+
+		report, buf := backupSince(t, 0)
+		version, _ := readVersion(backupTestMap)
+
+		if report.ToVersion != version { t.Errorf("backup to version mismatch: actual(%d), expected(%d)", report.ToVersion, version) }
+		if report.Puts != 1000 || report.Deletes != 0 { t.Errorf("full backup counts mismatch: puts(%d), deletes(%d)", report.Puts, report.Deletes) }
+
+		restoreErr := restoreTestMap.RestoreBackup(buf)
+		if restoreErr != nil { t.Fatalf("error on restore: %s", restoreErr.Error()) }
+
+		assertRestored(t)
+		lastVersion = report.ToVersion
+	})
+
+	t.Run("Test Incremental Backup", func(t *testing.T) {
+		for idx := 0; idx < 100; idx++ {
+			_, putErr := backupTestMap.Put([]byte(fmt.Sprintf("key%d", idx)), []byte(fmt.Sprintf("updated%d", idx)))
+			if putErr != nil { t.Errorf("error on put: %s", putErr.Error()) }
+		}
+
+		for idx := 1000; idx < 1050; idx++ {
+			_, putErr := backupTestMap.Put([]byte(fmt.Sprintf("key%d", idx)), []byte(fmt.Sprintf("value%d", idx)))
+			if putErr != nil { t.Errorf("error on put: %s", putErr.Error()) }
+		}
+
+		for idx := 500; idx < 520; idx++ {
+			_, delErr := backupTestMap.Delete([]byte(fmt.Sprintf("key%d", idx)))
+			if delErr != nil { t.Errorf("error on delete: %s", delErr.Error()) }
+		}
+
+		report, buf := backupSince(t, lastVersion)
+
+		if report.FromVersion != lastVersion { t.Errorf("backup from version mismatch: actual(%d), expected(%d)", report.FromVersion, lastVersion) }
+		if report.Deletes != 20 { t.Errorf("incremental backup deletes mismatch: actual(%d), expected(20)", report.Deletes) }
+		if report.Puts < 150 || report.Puts >= 1000 { t.Errorf("incremental backup was not incremental: puts(%d)", report.Puts) }
+
+		restoreErr := restoreTestMap.RestoreBackup(buf)
+		if restoreErr != nil { t.Fatalf("error on restore: %s", restoreErr.Error()) }
+
+		assertRestored(t)
+		lastVersion = report.ToVersion
+	})
+
+	t.Run("Test Backup At Latest Version", func(t *testing.T) {
+		report, buf := backupSince(t, lastVersion)
+		if report.Puts != 0 || report.Deletes != 0 { t.Errorf("backup at the latest version was not empty: puts(%d), deletes(%d)", report.Puts, report.Deletes) }
+
+		backup, readErr := mmcmap.ReadBackup(buf)
+		if readErr != nil { t.Fatalf("error reading backup: %s", readErr.Error()) }
+		if len(backup.Ops) != 0 { t.Errorf("backup ops mismatch: actual(%d), expected(0)", len(backup.Ops)) }
+
+		var aheadBuf bytes.Buffer
+		_, aheadErr := backupTestMap.BackupSince(&aheadBuf, lastVersion + 1)
+		if ! errors.Is(aheadErr, mmcmap.ErrVersionUnavailable) { t.Errorf("expected ErrVersionUnavailable for a future version, got: %v", aheadErr) }
+	})
+
+	t.Run("Test Corrupt Backup", func(t *testing.T) {
+		for idx := 0; idx < 10; idx++ {
+			_, putErr := backupTestMap.Put([]byte(fmt.Sprintf("key%d", idx)), []byte(fmt.Sprintf("corrupt%d", idx)))
+			if putErr != nil { t.Errorf("error on put: %s", putErr.Error()) }
+		}
+
+		_, buf := backupSince(t, lastVersion)
+		truncated := bytes.NewReader(buf.Bytes()[:buf.Len() - 1])
+
+		_, readErr := mmcmap.ReadBackup(truncated)
+		if readErr == nil { t.Error("expected an error reading a truncated backup") }
+	})
+
+	t.Run("Test Backup After Compact", func(t *testing.T) {
+		_, compactErr := backupTestMap.Compact()
+		if compactErr != nil { t.Fatalf("error on compact: %s", compactErr.Error()) }
+
+		var buf bytes.Buffer
+		_, backupErr := backupTestMap.BackupSince(&buf, lastVersion)
+		if ! errors.Is(backupErr, mmcmap.ErrVersionUnavailable) { t.Errorf("expected ErrVersionUnavailable for a compacted version, got: %v", backupErr) }
+
+		report, fullBuf := backupSince(t, 0)
+		if report.Puts != 1030 { t.Errorf("full backup after compact puts mismatch: actual(%d), expected(1030)", report.Puts) }
+
+		fullBackupTestPath := filepath.Join(os.TempDir(), "testbackupfull")
+		os.Remove(fullBackupTestPath)
+
+		fullTestMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: fullBackupTestPath })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+		defer fullTestMap.Remove()
+
+		restoreErr := fullTestMap.RestoreBackup(fullBuf)
+		if restoreErr != nil { t.Fatalf("error on restore: %s", restoreErr.Error()) }
+
+		val, getErr := fullTestMap.Get([]byte("key0"))
+		if getErr != nil { t.Errorf("error on get: %s", getErr.Error()) }
+		if ! bytes.Equal(val, []byte("corrupt0")) { t.Errorf("value mismatch: actual(%s), expected(corrupt0)", val) }
+	})
+
+	t.Log("Done")
+}
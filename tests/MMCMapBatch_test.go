@@ -0,0 +1,148 @@
+package mmcmaptests
+
+import "errors"
+import "fmt"
+import "os"
+import "path/filepath"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var bTestPath = filepath.Join(os.TempDir(), "testbatch")
+var batchTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initBMapErr error
+	os.Remove(bTestPath)
+
+	opts := mmcmap.MMCMapOpts{ Filepath: bTestPath }
+	batchTestMap, initBMapErr = mmcmap.Open(opts)
+	if initBMapErr != nil { panic(initBMapErr.Error()) }
+
+	fmt.Println("batch test mmcmap initialized")
+}
+
+
+func TestMMCMapWriteBatch(t *testing.T) {
+	defer batchTestMap.Remove()
+
+	_, putErr := batchTestMap.Put([]byte("existing"), []byte("before"))
+	if putErr != nil { t.Errorf("error putting key in mmcmap: %s", putErr.Error()) }
+
+	batch := batchTestMap.NewWriteBatch()
+
+	t.Run("Test Batch Reads Own Writes", func(t *testing.T) {
+		batch.Put([]byte("hello"), []byte("world"))
+		batch.Put([]byte("existing"), []byte("after"))
+		batch.Put([]byte("removed"), []byte("soon"))
+		batch.Delete([]byte("removed"))
+
+		val, getErr := batch.Get([]byte("hello"))
+		if getErr != nil { t.Errorf("error getting from batch: %s", getErr.Error()) }
+		if string(val) != "world" { t.Errorf("batch get does not match: actual(%s), expected(world)", val) }
+
+		val, getErr = batch.Get([]byte("existing"))
+		if getErr != nil { t.Errorf("error getting from batch: %s", getErr.Error()) }
+		if string(val) != "after" { t.Errorf("batch get does not match: actual(%s), expected(after)", val) }
+
+		val, getErr = batch.Get([]byte("removed"))
+		if getErr != nil { t.Errorf("error getting from batch: %s", getErr.Error()) }
+		if val != nil { t.Errorf("expected deleted key to be nil in batch, got: %s", val) }
+
+		val, getErr = batchTestMap.Get([]byte("hello"))
+		if getErr != nil { t.Errorf("error getting from mmcmap: %s", getErr.Error()) }
+		if val != nil { t.Errorf("uncommitted batch write visible in mmcmap: %s", val) }
+	})
+
+	t.Run("Test Batch Commit", func(t *testing.T) {
+		meta, readMetaErr := batchTestMap.ReadMetaFromMemMap()
+		if readMetaErr != nil { t.Errorf("error reading metadata: %s", readMetaErr.Error()) }
+
+		_, commitErr := batch.Commit()
+		if commitErr != nil { t.Errorf("error committing batch: %s", commitErr.Error()) }
+		if batch.Len() != 0 { t.Errorf("batch not reset after commit: %d ops remaining", batch.Len()) }
+
+		updatedMeta, readMetaErr := batchTestMap.ReadMetaFromMemMap()
+		if readMetaErr != nil { t.Errorf("error reading metadata: %s", readMetaErr.Error()) }
+		if updatedMeta.Version != meta.Version + 1 {
+			t.Errorf("batch not committed as a single version: actual(%d), expected(%d)", updatedMeta.Version, meta.Version + 1)
+		}
+
+		expected := map[string]string{ "hello": "world", "existing": "after" }
+		for key, expVal := range expected {
+			val, getErr := batchTestMap.Get([]byte(key))
+			if getErr != nil { t.Errorf("error getting from mmcmap: %s", getErr.Error()) }
+			if string(val) != expVal { t.Errorf("committed value does not match: actual(%s), expected(%s)", val, expVal) }
+		}
+
+		val, getErr := batchTestMap.Get([]byte("removed"))
+		if getErr != nil { t.Errorf("error getting from mmcmap: %s", getErr.Error()) }
+		if val != nil { t.Errorf("deleted key present after commit: %s", val) }
+	})
+
+	t.Run("Test Put Batch", func(t *testing.T) {
+		meta, readMetaErr := batchTestMap.ReadMetaFromMemMap()
+		if readMetaErr != nil { t.Errorf("error reading metadata: %s", readMetaErr.Error()) }
+
+		var pairs []mmcmap.KeyValuePair
+		for idx := range make([]int, 500) {
+			key := []byte(fmt.Sprintf("batchkey%d", idx))
+			pairs = append(pairs, mmcmap.KeyValuePair{ Key: key, Value: key })
+		}
+
+		_, putBatchErr := batchTestMap.PutBatch(pairs)
+		if putBatchErr != nil { t.Errorf("error on put batch: %s", putBatchErr.Error()) }
+
+		updatedMeta, readMetaErr := batchTestMap.ReadMetaFromMemMap()
+		if readMetaErr != nil { t.Errorf("error reading metadata: %s", readMetaErr.Error()) }
+		if updatedMeta.Version != meta.Version + 1 {
+			t.Errorf("put batch not committed as a single version: actual(%d), expected(%d)", updatedMeta.Version, meta.Version + 1)
+		}
+
+		for _, pair := range pairs {
+			val, getErr := batchTestMap.Get(pair.Key)
+			if getErr != nil { t.Errorf("error getting from mmcmap: %s", getErr.Error()) }
+			if string(val) != string(pair.Value) { t.Errorf("put batch value does not match: actual(%s), expected(%s)", val, pair.Value) }
+		}
+	})
+
+	t.Run("Test Batch Copies Buffers", func(t *testing.T) {
+		key, value := []byte("copiedkey"), []byte("copiedval")
+		copyBatch := batchTestMap.NewWriteBatch()
+		copyBatch.Put(key, value)
+
+		copy(key, "clobbered")
+		copy(value, "clobbered")
+
+		_, commitErr := copyBatch.Commit()
+		if commitErr != nil { t.Fatalf("error on commit: %s", commitErr.Error()) }
+
+		val, getErr := batchTestMap.Get([]byte("copiedkey"))
+		if getErr != nil { t.Errorf("error getting from mmcmap: %s", getErr.Error()) }
+		if string(val) != "copiedval" { t.Errorf("reused buffers changed the batch: actual(%s), expected(copiedval)", val) }
+	})
+
+	t.Run("Test Batch Strict Get", func(t *testing.T) {
+		path := filepath.Join(os.TempDir(), "testbatchstrict")
+		os.Remove(path)
+
+		strictMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: path, StrictGet: true })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+		defer strictMap.Remove()
+
+		strictMap.Put([]byte("removed"), []byte("soon"))
+
+		strictBatch := strictMap.NewWriteBatch()
+		strictBatch.Delete([]byte("removed"))
+
+		_, getErr := strictBatch.Get([]byte("removed"))
+		if ! errors.Is(getErr, mmcmap.ErrKeyNotFound) { t.Errorf("deleted key error mismatch: actual(%v), expected(%v)", getErr, mmcmap.ErrKeyNotFound) }
+
+		_, getErr = strictBatch.Get([]byte("absent"))
+		if ! errors.Is(getErr, mmcmap.ErrKeyNotFound) { t.Errorf("absent key error mismatch: actual(%v), expected(%v)", getErr, mmcmap.ErrKeyNotFound) }
+	})
+
+	t.Log("Done")
+}
@@ -0,0 +1,134 @@
+package mmcmaptests
+
+import "bytes"
+import "errors"
+import "fmt"
+import "os"
+import "path/filepath"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var blTestPath = filepath.Join(os.TempDir(), "testbulkload")
+var bulkLoadTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initBlMapErr error
+	os.Remove(blTestPath)
+
+	bulkLoadTestMap, initBlMapErr = mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: blTestPath, DeterministicHash: true })
+	if initBlMapErr != nil { panic(initBlMapErr.Error()) }
+
+	fmt.Println("bulk load test mmcmap initialized")
+}
+
+
+func TestMMCMapBulkLoad(t *testing.T) {
+	defer bulkLoadTestMap.Remove()
+
+	pairs := make([]mmcmap.KeyValuePair, 0, 20000)
+	for idx := 0; idx < 20000; idx++ {
+		pairs = append(pairs, mmcmap.KeyValuePair{ Key: []byte(fmt.Sprintf("key%d", idx)), Value: []byte(fmt.Sprintf("value%d", idx)) })
+	}
+
+	for idx := 0; idx < 100; idx++ {
+		pairs = append(pairs, mmcmap.KeyValuePair{ Key: []byte(fmt.Sprintf("key%d", idx)), Value: []byte(fmt.Sprintf("latest%d", idx)) })
+	}
+
+	expectedValue := func(idx int) []byte {
+		if idx < 100 { return []byte(fmt.Sprintf("latest%d", idx)) }
+		return []byte(fmt.Sprintf("value%d", idx))
+	}
+
+	t.Run("Test Bulk Load", func(t *testing.T) {
+		loadErr := bulkLoadTestMap.BulkLoad(pairs)
+		if loadErr != nil { t.Fatalf("error on bulk load: %s", loadErr.Error()) }
+
+		version, _ := readVersion(bulkLoadTestMap)
+		if version != 1 { t.Errorf("bulk load did not commit a single version: actual(%d), expected(1)", version) }
+
+		keyCount, lenErr := bulkLoadTestMap.Len()
+		if lenErr != nil { t.Errorf("error on len: %s", lenErr.Error()) }
+		if keyCount != 20000 { t.Errorf("key count mismatch: actual(%d), expected(20000)", keyCount) }
+
+		for idx := 0; idx < 20000; idx++ {
+			key := []byte(fmt.Sprintf("key%d", idx))
+			val, getErr := bulkLoadTestMap.Get(key)
+			if getErr != nil { t.Errorf("error on get: %s", getErr.Error()) }
+			if ! bytes.Equal(val, expectedValue(idx)) { t.Errorf("value mismatch for %s: actual(%s), expected(%s)", key, val, expectedValue(idx)) }
+		}
+
+		report, verifyErr := bulkLoadTestMap.Verify()
+		if verifyErr != nil { t.Errorf("error on verify: %s", verifyErr.Error()) }
+		if report != nil && ! report.IsValid { t.Errorf("map invalid after bulk load: %v", report.Findings) }
+	})
+
+	t.Run("Test Bulk Load Matches Puts", func(t *testing.T) {
+		putTestPath := filepath.Join(os.TempDir(), "testbulkloadputs")
+		os.Remove(putTestPath)
+
+		putTestMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: putTestPath, DeterministicHash: true })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+		defer putTestMap.Remove()
+
+		_, putErr := putTestMap.PutBatch(pairs)
+		if putErr != nil { t.Fatalf("error on put batch: %s", putErr.Error()) }
+
+		expectedMeta, _ := putTestMap.ReadMetaFromMemMap()
+		actualMeta, _ := bulkLoadTestMap.ReadMetaFromMemMap()
+
+		expectedSize := expectedMeta.EndMmapOffset - expectedMeta.RootOffset
+		actualSize := actualMeta.EndMmapOffset - actualMeta.RootOffset
+		if actualSize != expectedSize { t.Errorf("bulk loaded trie differs from put trie: actual(%d bytes), expected(%d bytes)", actualSize, expectedSize) }
+	})
+
+	t.Run("Test Writes After Bulk Load", func(t *testing.T) {
+		_, putErr := bulkLoadTestMap.Put([]byte("key20000"), []byte("value20000"))
+		if putErr != nil { t.Errorf("error on put: %s", putErr.Error()) }
+
+		_, delErr := bulkLoadTestMap.Delete([]byte("key0"))
+		if delErr != nil { t.Errorf("error on delete: %s", delErr.Error()) }
+
+		keyCount, _ := bulkLoadTestMap.Len()
+		if keyCount != 20000 { t.Errorf("key count mismatch: actual(%d), expected(20000)", keyCount) }
+
+		val, _ := bulkLoadTestMap.Get([]byte("key20000"))
+		if ! bytes.Equal(val, []byte("value20000")) { t.Errorf("value mismatch: actual(%s), expected(value20000)", val) }
+	})
+
+	t.Run("Test Bulk Load Non Empty", func(t *testing.T) {
+		loadErr := bulkLoadTestMap.BulkLoad(pairs)
+		if ! errors.Is(loadErr, mmcmap.ErrMapNotEmpty) { t.Errorf("expected ErrMapNotEmpty, got: %v", loadErr) }
+	})
+
+	t.Run("Test Bulk Load Resizes", func(t *testing.T) {
+		largeTestPath := filepath.Join(os.TempDir(), "testbulkloadlarge")
+		os.Remove(largeTestPath)
+
+		largeTestMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: largeTestPath })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+		defer largeTestMap.Remove()
+
+		prevSize, _ := largeTestMap.FileSize()
+		value := bytes.Repeat([]byte("v"), 1 << 20)
+
+		largePairs := make([]mmcmap.KeyValuePair, 100)
+		for idx := range largePairs { largePairs[idx] = mmcmap.KeyValuePair{ Key: []byte(fmt.Sprintf("large%d", idx)), Value: value } }
+
+		loadErr := largeTestMap.BulkLoad(largePairs)
+		if loadErr != nil { t.Fatalf("error on bulk load: %s", loadErr.Error()) }
+
+		size, _ := largeTestMap.FileSize()
+		if size <= prevSize { t.Errorf("map was not resized: actual(%d), previous(%d)", size, prevSize) }
+
+		for idx := range largePairs {
+			val, getErr := largeTestMap.Get(largePairs[idx].Key)
+			if getErr != nil { t.Errorf("error on get: %s", getErr.Error()) }
+			if ! bytes.Equal(val, value) { t.Errorf("value mismatch for %s", largePairs[idx].Key) }
+		}
+	})
+
+	t.Log("Done")
+}
@@ -0,0 +1,115 @@
+package mmcmaptests
+
+import "bytes"
+import "fmt"
+import "os"
+import "path/filepath"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var clTestPath = filepath.Join(os.TempDir(), "testclear")
+var clearTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initClMapErr error
+	os.Remove(clTestPath)
+
+	opts := mmcmap.MMCMapOpts{ Filepath: clTestPath }
+	clearTestMap, initClMapErr = mmcmap.Open(opts)
+	if initClMapErr != nil { panic(initClMapErr.Error()) }
+
+	fmt.Println("clear test mmcmap initialized")
+}
+
+
+func TestMMCMapClear(t *testing.T) {
+	defer clearTestMap.Remove()
+
+	putKeys := func(t *testing.T, total int) {
+		for idx := 0; idx < total; idx++ {
+			key := []byte(fmt.Sprintf("key%d", idx))
+			_, putErr := clearTestMap.Put(key, key)
+			if putErr != nil { t.Errorf("error on put: %s", putErr.Error()) }
+		}
+	}
+
+	assertEmpty := func(t *testing.T) {
+		keyCount, lenErr := clearTestMap.Len()
+		if lenErr != nil { t.Errorf("error on len: %s", lenErr.Error()) }
+		if keyCount != 0 { t.Errorf("key count mismatch after clear: actual(%d), expected(0)", keyCount) }
+
+		kvPairs, rangeErr := clearTestMap.Range(nil, nil)
+		if rangeErr != nil { t.Errorf("error on range: %s", rangeErr.Error()) }
+		if len(kvPairs) != 0 { t.Errorf("range returned pairs after clear: actual(%d), expected(0)", len(kvPairs)) }
+
+		val, getErr := clearTestMap.Get([]byte("key1"))
+		if getErr != nil { t.Errorf("error on get: %s", getErr.Error()) }
+		if val != nil { t.Errorf("cleared key still present: %s", val) }
+
+		first, firstErr := clearTestMap.First()
+		if firstErr != nil { t.Errorf("error on first: %s", firstErr.Error()) }
+		if first != nil { t.Errorf("first returned a pair after clear: %s", first.Key) }
+	}
+
+	t.Run("Test Clear", func(t *testing.T) {
+		putKeys(t, 500)
+
+		prevVersion, _ := readVersion(clearTestMap)
+		clearErr := clearTestMap.Clear()
+		if clearErr != nil { t.Errorf("error on clear: %s", clearErr.Error()) }
+
+		version, _ := readVersion(clearTestMap)
+		if version != prevVersion + 1 { t.Errorf("clear did not commit a single version: actual(%d), expected(%d)", version, prevVersion + 1) }
+
+		assertEmpty(t)
+	})
+
+	t.Run("Test Clear With Truncate", func(t *testing.T) {
+		putKeys(t, 500)
+
+		prevVersion, _ := readVersion(clearTestMap)
+		initialSize, _ := clearTestMap.FileSize()
+
+		clearErr := clearTestMap.Clear(mmcmap.ClearOpts{ Truncate: true })
+		if clearErr != nil { t.Errorf("error on clear: %s", clearErr.Error()) }
+
+		version, _ := readVersion(clearTestMap)
+		if version <= prevVersion { t.Errorf("version did not increase across truncate: actual(%d), previous(%d)", version, prevVersion) }
+
+		size, _ := clearTestMap.FileSize()
+		if size > initialSize { t.Errorf("file grew on truncate: actual(%d), previous(%d)", size, initialSize) }
+
+		assertEmpty(t)
+	})
+
+	t.Run("Test Writes After Clear", func(t *testing.T) {
+		putKeys(t, 100)
+
+		closeErr := clearTestMap.Close()
+		if closeErr != nil { t.Errorf("error on close: %s", closeErr.Error()) }
+
+		var openErr error
+		clearTestMap, openErr = mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: clTestPath })
+		if openErr != nil { t.Fatalf("error on reopen: %s", openErr.Error()) }
+
+		keyCount, lenErr := clearTestMap.Len()
+		if lenErr != nil { t.Errorf("error on len: %s", lenErr.Error()) }
+		if keyCount != 100 { t.Errorf("key count mismatch: actual(%d), expected(100)", keyCount) }
+
+		val, getErr := clearTestMap.Get([]byte("key42"))
+		if getErr != nil { t.Errorf("error on get: %s", getErr.Error()) }
+		if ! bytes.Equal(val, []byte("key42")) { t.Errorf("value mismatch: actual(%s), expected(key42)", val) }
+	})
+
+	t.Log("Done")
+}
+
+func readVersion(mmcMap *mmcmap.MMCMap) (uint64, error) {
+	meta, readMetaErr := mmcMap.ReadMetaFromMemMap()
+	if readMetaErr != nil { return 0, readMetaErr }
+
+	return meta.Version, nil
+}
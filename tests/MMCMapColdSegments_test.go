@@ -0,0 +1,192 @@
+package mmcmaptests
+
+import "bytes"
+import "errors"
+import "fmt"
+import "os"
+import "path/filepath"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var coldTestPath = filepath.Join(os.TempDir(), "testcoldsegments")
+var coldTestMap *mmcmap.MMCMap
+var coldTestSize = uint64(mmcmap.MinSegmentSize)
+
+
+func init() {
+	var initColdMapErr error
+	mmcmap.Destroy(coldTestPath)
+
+	opts := mmcmap.MMCMapOpts{ Filepath: coldTestPath, SegmentSize: coldTestSize, HotSegments: 1, ColdCompression: mmcmap.CompressionSnappy }
+	coldTestMap, initColdMapErr = mmcmap.Open(opts)
+	if initColdMapErr != nil { panic(initColdMapErr.Error()) }
+
+	fmt.Println("cold segments test mmcmap initialized")
+}
+
+
+func TestMMCMapColdSegments(t *testing.T) {
+	defer coldTestMap.Remove()
+
+	coldPath := func(path string, id int) string { return fmt.Sprintf("%s.%06d.cold", path, id) }
+	coldValue := func(round, idx int) []byte { return bytes.Repeat([]byte(fmt.Sprintf("%d:%d;", round, idx)), 600) }
+
+	putRound := func(t *testing.T, mmcMap *mmcmap.MMCMap, round int) {
+		for idx := range make([]int, 1000) {
+			_, putErr := mmcMap.Put([]byte(fmt.Sprintf("cold%04d", idx)), coldValue(round, idx))
+			if putErr != nil { t.Fatalf("error putting key in mmcmap: %s", putErr.Error()) }
+		}
+	}
+
+	checkRound := func(t *testing.T, mmcMap *mmcmap.MMCMap, round int) {
+		for idx := range make([]int, 1000) {
+			key := []byte(fmt.Sprintf("cold%04d", idx))
+			value, getErr := mmcMap.Get(key)
+			if getErr != nil { t.Fatalf("error getting key from mmcmap: %s", getErr.Error()) }
+			if ! bytes.Equal(value, coldValue(round, idx)) { t.Fatalf("value mismatch for key %s: actual(%d bytes), expected(%d bytes)", key, len(value), len(coldValue(round, idx))) }
+		}
+	}
+
+	t.Run("Test Seal Compressed Segments", func(t *testing.T) {
+		putRound(t, coldTestMap, 0)
+
+		report, sealErr := coldTestMap.SealSegments()
+		if sealErr != nil { t.Fatalf("error sealing segments: %s", sealErr.Error()) }
+		if report.LastSegment < 3 { t.Fatalf("expected at least 3 segments after the first: %+v", report) }
+
+		_, statErr := os.Stat(coldPath(coldTestPath, 1))
+		if statErr != nil { t.Fatalf("oldest segment was not compressed: %s", statErr.Error()) }
+
+		_, statErr = os.Stat(fmt.Sprintf("%s.%06d", coldTestPath, 1))
+		if ! errors.Is(statErr, os.ErrNotExist) { t.Errorf("segment file of a compressed segment was not removed: stat error(%v)", statErr) }
+
+		_, statErr = os.Stat(fmt.Sprintf("%s.%06d", coldTestPath, report.LastSegment))
+		if statErr != nil { t.Errorf("hot segment file is missing: %s", statErr.Error()) }
+
+		fileSize, sizeErr := coldTestMap.FileSize()
+		if sizeErr != nil { t.Fatalf("error getting file size: %s", sizeErr.Error()) }
+		if uint64(fileSize) >= (report.LastSegment + 1) * coldTestSize { t.Errorf("compressed segments did not shrink the file: actual(%d)", fileSize) }
+
+		checkRound(t, coldTestMap, 0)
+
+		again, sealErr := coldTestMap.SealSegments()
+		if sealErr != nil { t.Fatalf("error on repeated seal: %s", sealErr.Error()) }
+		if again.SealedSegments != 0 { t.Errorf("repeated seal sealed segments: actual(%d), expected(0)", again.SealedSegments) }
+	})
+
+	t.Run("Test Write After Seal", func(t *testing.T) {
+		putRound(t, coldTestMap, 1)
+		checkRound(t, coldTestMap, 1)
+
+		_, sealErr := coldTestMap.SealSegments()
+		if sealErr != nil { t.Fatalf("error sealing segments: %s", sealErr.Error()) }
+		checkRound(t, coldTestMap, 1)
+	})
+
+	t.Run("Test Reopen Cold Segments", func(t *testing.T) {
+		closeErr := coldTestMap.Close()
+		if closeErr != nil { t.Fatalf("error on close: %s", closeErr.Error()) }
+
+		var openErr error
+		coldTestMap, openErr = mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: coldTestPath, HotSegments: 1, ColdCompression: mmcmap.CompressionSnappy })
+		if openErr != nil { t.Fatalf("error reopening with cold segments: %s", openErr.Error()) }
+
+		checkRound(t, coldTestMap, 1)
+		putRound(t, coldTestMap, 2)
+		checkRound(t, coldTestMap, 2)
+	})
+
+	t.Run("Test Reclaim Cold Segments", func(t *testing.T) {
+		_, sealErr := coldTestMap.SealSegments()
+		if sealErr != nil { t.Fatalf("error sealing segments: %s", sealErr.Error()) }
+
+		report, reclaimErr := coldTestMap.ReclaimSegments()
+		if reclaimErr != nil { t.Fatalf("error reclaiming segments: %s", reclaimErr.Error()) }
+		if report.RemovedSegments == 0 { t.Fatalf("no segments were reclaimed after every key was overwritten: %+v", report) }
+
+		_, statErr := os.Stat(coldPath(coldTestPath, 1))
+		if ! errors.Is(statErr, os.ErrNotExist) { t.Errorf("cold segment was not removed: stat error(%v)", statErr) }
+
+		checkRound(t, coldTestMap, 2)
+	})
+
+	t.Run("Test Read Only Cold Segments", func(t *testing.T) {
+		path := filepath.Join(os.TempDir(), "testcoldsegmentsreadonly")
+		mmcmap.Destroy(path)
+
+		opts := mmcmap.MMCMapOpts{ Filepath: path, SegmentSize: coldTestSize, HotSegments: 1, LockMemory: mmcmap.LockMemoryAll }
+		mmcMap, openErr := mmcmap.Open(opts)
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+		defer mmcMap.Remove()
+
+		putRound(t, mmcMap, 0)
+
+		report, sealErr := mmcMap.SealSegments()
+		if sealErr != nil { t.Fatalf("error sealing segments: %s", sealErr.Error()) }
+		if report.CompressedSegments != 0 { t.Errorf("segments were compressed without ColdCompression: actual(%d)", report.CompressedSegments) }
+
+		matches, globErr := filepath.Glob(path + ".*.cold")
+		if globErr != nil { t.Fatalf("error listing cold segments: %s", globErr.Error()) }
+		if len(matches) != 0 { t.Errorf("cold segments were compressed without ColdCompression: %v", matches) }
+
+		lockedBytes := mmcMap.MemoryLockStats().LockedBytes
+		if lockedBytes > 3 * coldTestSize { t.Errorf("cold segments are locked into memory: actual(%d bytes locked)", lockedBytes) }
+
+		checkRound(t, mmcMap, 0)
+		putRound(t, mmcMap, 1)
+		checkRound(t, mmcMap, 1)
+	})
+
+	t.Run("Test Snapshot Over Cold Segments", func(t *testing.T) {
+		var snapshot bytes.Buffer
+		exportErr := coldTestMap.ExportSnapshot(&snapshot)
+		if exportErr != nil { t.Fatalf("error exporting snapshot: %s", exportErr.Error()) }
+
+		replicaPath := filepath.Join(os.TempDir(), "testcoldsegmentsreplica")
+		mmcmap.Destroy(replicaPath)
+
+		opts := mmcmap.MMCMapOpts{ Filepath: replicaPath, SegmentSize: coldTestSize, HotSegments: 1, ColdCompression: mmcmap.CompressionZstd }
+		replicaMap, openErr := mmcmap.Open(opts)
+		if openErr != nil { t.Fatalf("error opening replica: %s", openErr.Error()) }
+		defer replicaMap.Remove()
+
+		putRound(t, replicaMap, 5)
+		putRound(t, replicaMap, 6)
+
+		_, sealErr := replicaMap.SealSegments()
+		if sealErr != nil { t.Fatalf("error sealing replica segments: %s", sealErr.Error()) }
+
+		matches, globErr := filepath.Glob(replicaPath + ".*.cold")
+		if globErr != nil { t.Fatalf("error listing cold segments: %s", globErr.Error()) }
+		if len(matches) == 0 { t.Fatalf("replica has no compressed segments to apply the snapshot over") }
+
+		catchUpErr := replicaMap.CatchUp(bytes.NewReader(snapshot.Bytes()))
+		if catchUpErr != nil { t.Fatalf("error applying snapshot over cold segments: %s", catchUpErr.Error()) }
+		checkRound(t, replicaMap, 2)
+
+		matches, globErr = filepath.Glob(replicaPath + ".*.cold")
+		if globErr != nil { t.Fatalf("error listing cold segments: %s", globErr.Error()) }
+		if len(matches) != 0 { t.Errorf("compressed segments of the replica remain after the snapshot: %v", matches) }
+	})
+
+	t.Run("Test Cold Segments Require Segments", func(t *testing.T) {
+		path := filepath.Join(os.TempDir(), "testcoldsegmentsplain")
+		defer mmcmap.Destroy(path)
+
+		_, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: path, HotSegments: 1 })
+		if ! errors.Is(openErr, mmcmap.ErrNotSegmented) { t.Errorf("expected ErrNotSegmented, got: %v", openErr) }
+	})
+
+	t.Run("Test Destroy Cold Segments", func(t *testing.T) {
+		removeErr := coldTestMap.Remove()
+		if removeErr != nil { t.Fatalf("error on remove: %s", removeErr.Error()) }
+
+		matches, globErr := filepath.Glob(coldTestPath + "*")
+		if globErr != nil { t.Fatalf("error listing segments: %s", globErr.Error()) }
+		if len(matches) != 0 { t.Errorf("files remain after remove: %v", matches) }
+	})
+
+	t.Log("Done")
+}
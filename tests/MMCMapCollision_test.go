@@ -0,0 +1,212 @@
+package mmcmaptests
+
+import "bytes"
+import "encoding/binary"
+import "errors"
+import "fmt"
+import "os"
+import "path/filepath"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+import "github.com/sirgallo/mmcmap/common/murmur"
+
+
+var collisionTestPath = filepath.Join(os.TempDir(), "testcollision")
+var collisionTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initCollisionMapErr error
+	os.Remove(collisionTestPath)
+
+	opts := mmcmap.MMCMapOpts{ Filepath: collisionTestPath, DeterministicHash: true }
+	collisionTestMap, initCollisionMapErr = mmcmap.Open(opts)
+	if initCollisionMapErr != nil { panic(initCollisionMapErr.Error()) }
+
+	fmt.Println("collision test mmcmap initialized")
+}
+
+
+func TestMMCMapCollision(t *testing.T) {
+	defer collisionTestMap.Remove()
+
+	keys := murmur32Multicollision(6)
+	bucketKeys := keys[:mmcmap.MaxCollisionBucketKeys]
+
+	checkValues := func(t *testing.T, mmcMap *mmcmap.MMCMap, keys [][]byte, suffix string) {
+		for _, key := range keys {
+			value, getErr := mmcMap.Get(key)
+			if getErr != nil { t.Fatalf("error on get: %s", getErr.Error()) }
+			if string(value) != fmt.Sprintf("%x", key) + suffix { t.Fatalf("value mismatch for %x: actual(%s), expected(%x%s)", key, value, key, suffix) }
+		}
+	}
+
+	t.Run("Test Put Colliding Keys", func(t *testing.T) {
+		for _, seed := range []uint32{ 1, 2, 0xdeadbeef } {
+			for _, key := range keys[1:] {
+				if murmur.Murmur32(key, seed) != murmur.Murmur32(keys[0], seed) { t.Fatalf("keys %x and %x do not collide for seed %d", key, keys[0], seed) }
+			}
+		}
+
+		for _, key := range bucketKeys {
+			_, putErr := collisionTestMap.Put(key, []byte(fmt.Sprintf("%x", key)))
+			if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+		}
+
+		checkValues(t, collisionTestMap, bucketKeys, "")
+
+		length, lenErr := collisionTestMap.Len()
+		if lenErr != nil { t.Fatalf("error on len: %s", lenErr.Error()) }
+		if length != uint64(len(bucketKeys)) { t.Errorf("length mismatch: actual(%d), expected(%d)", length, len(bucketKeys)) }
+
+		value, _ := collisionTestMap.Get(keys[len(keys) - 1])
+		if value != nil { t.Errorf("colliding key that was never put is present: %s", value) }
+
+		report, verifyErr := collisionTestMap.Verify()
+		if verifyErr != nil { t.Fatalf("error on verify: %s", verifyErr.Error()) }
+		if ! report.IsValid { t.Errorf("verify found inconsistencies: %v", report.Findings) }
+	})
+
+	t.Run("Test Bucket Full", func(t *testing.T) {
+		_, putErr := collisionTestMap.Put(keys[mmcmap.MaxCollisionBucketKeys], []byte("value"))
+		if ! errors.Is(putErr, mmcmap.ErrCollisionBucketFull) { t.Errorf("expected ErrCollisionBucketFull, got: %v", putErr) }
+
+		checkValues(t, collisionTestMap, bucketKeys, "")
+	})
+
+	t.Run("Test Update And Diff", func(t *testing.T) {
+		prevVersion, _ := readVersion(collisionTestMap)
+
+		for _, key := range bucketKeys[:4] {
+			_, putErr := collisionTestMap.Put(key, []byte(fmt.Sprintf("%x", key) + "updated"))
+			if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+		}
+
+		length, lenErr := collisionTestMap.Len()
+		if lenErr != nil { t.Fatalf("error on len: %s", lenErr.Error()) }
+		if length != uint64(len(bucketKeys)) { t.Errorf("length mismatch after update: actual(%d), expected(%d)", length, len(bucketKeys)) }
+
+		checkValues(t, collisionTestMap, bucketKeys[:4], "updated")
+		checkValues(t, collisionTestMap, bucketKeys[4:], "")
+
+		version, _ := readVersion(collisionTestMap)
+		diffs, diffErr := collisionTestMap.Diff(prevVersion, version)
+		if diffErr != nil { t.Fatalf("error on diff: %s", diffErr.Error()) }
+		if len(diffs) != 4 { t.Fatalf("diff length mismatch: actual(%d), expected(4)", len(diffs)) }
+
+		for _, diff := range diffs {
+			if diff.Kind != mmcmap.DiffModified { t.Errorf("diff kind mismatch for %x: actual(%v), expected(%v)", diff.Key, diff.Kind, mmcmap.DiffModified) }
+		}
+
+		for _, key := range bucketKeys[:4] {
+			_, putErr := collisionTestMap.Put(key, []byte(fmt.Sprintf("%x", key)))
+			if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+		}
+	})
+
+	t.Run("Test Delete Colliding Keys", func(t *testing.T) {
+		for _, key := range bucketKeys[1:] {
+			removed, delErr := collisionTestMap.Delete(key)
+			if delErr != nil { t.Fatalf("error on delete: %s", delErr.Error()) }
+			if ! removed { t.Errorf("delete of colliding key %x did not report a removal", key) }
+		}
+
+		for _, key := range bucketKeys[1:] {
+			value, _ := collisionTestMap.Get(key)
+			if value != nil { t.Fatalf("deleted key %x still present", key) }
+		}
+
+		checkValues(t, collisionTestMap, bucketKeys[:1], "")
+
+		for _, key := range bucketKeys[1:] {
+			_, putErr := collisionTestMap.Put(key, []byte(fmt.Sprintf("%x", key)))
+			if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+		}
+
+		checkValues(t, collisionTestMap, bucketKeys, "")
+	})
+
+	t.Run("Test Reopen With Deep Check", func(t *testing.T) {
+		closeErr := collisionTestMap.Close()
+		if closeErr != nil { t.Fatalf("error on close: %s", closeErr.Error()) }
+
+		var openErr error
+		collisionTestMap, openErr = mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: collisionTestPath, OpenCheck: mmcmap.OpenCheckDeep })
+		if openErr != nil { t.Fatalf("error on reopen: %s", openErr.Error()) }
+		if len(collisionTestMap.OpenReport.Findings) != 0 { t.Errorf("open check found inconsistencies: %v", collisionTestMap.OpenReport.Findings) }
+
+		checkValues(t, collisionTestMap, bucketKeys, "")
+	})
+
+	t.Run("Test Bulk Load", func(t *testing.T) {
+		path := filepath.Join(os.TempDir(), "testcollisionbulk")
+		os.Remove(path)
+
+		bulkMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: path, DeterministicHash: true })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+		defer bulkMap.Remove()
+
+		pairs := make([]mmcmap.KeyValuePair, len(keys))
+		for idx, key := range keys { pairs[idx] = mmcmap.KeyValuePair{ Key: key, Value: []byte(fmt.Sprintf("%x", key)) } }
+
+		loadErr := bulkMap.BulkLoad(pairs)
+		if ! errors.Is(loadErr, mmcmap.ErrCollisionBucketFull) { t.Errorf("expected ErrCollisionBucketFull, got: %v", loadErr) }
+
+		loadErr = bulkMap.BulkLoad(pairs[:mmcmap.MaxCollisionBucketKeys])
+		if loadErr != nil { t.Fatalf("error on bulk load: %s", loadErr.Error()) }
+
+		checkValues(t, bulkMap, bucketKeys, "")
+
+		report, verifyErr := bulkMap.Verify()
+		if verifyErr != nil { t.Fatalf("error on verify: %s", verifyErr.Error()) }
+		if ! report.IsValid { t.Errorf("verify found inconsistencies: %v", report.Findings) }
+	})
+
+	t.Log("Done")
+}
+
+// murmur32Multicollision
+//	Build 2^segments keys with the same Murmur32 hash under every seed. Each 8 byte segment is one of two block pairs, where the mixed first blocks
+//	differ only in bit 18, which the rotate and multiply of the hash state carry to bit 31, and the mixed second blocks differ only in bit 31, which
+//	cancels it. Every choice of segments leaves the same hash state.
+func murmur32Multicollision(segments int) [][]byte {
+	const c1, c2 = uint32(0x85ebca6b), uint32(0xc2b2ae35)
+	invC1, invC2 := inverseMod32(c1), inverseMod32(c2)
+
+	unmix := func(mixed uint32) uint32 {
+		mixed *= invC2
+		mixed = (mixed >> 15) | (mixed << 17)
+		return mixed * invC1
+	}
+
+	keys := [][]byte{{}}
+	for segment := 0; segment < segments; segment++ {
+		first := 0x9e3779b9 * uint32(segment + 1)
+		second := 0x7f4a7c15 * uint32(segment + 1)
+
+		choices := [2][]byte{ make([]byte, 8), make([]byte, 8) }
+		binary.LittleEndian.PutUint32(choices[0][:4], unmix(first))
+		binary.LittleEndian.PutUint32(choices[0][4:], unmix(second))
+		binary.LittleEndian.PutUint32(choices[1][:4], unmix(first ^ 1 << 18))
+		binary.LittleEndian.PutUint32(choices[1][4:], unmix(second ^ 1 << 31))
+
+		var extended [][]byte
+		for _, key := range keys {
+			for _, choice := range choices { extended = append(extended, append(bytes.Clone(key), choice...)) }
+		}
+
+		keys = extended
+	}
+
+	return keys
+}
+
+// inverseMod32
+//	The multiplicative inverse of an odd value modulo 2^32, by Newton iteration.
+func inverseMod32(value uint32) uint32 {
+	inverse := value
+	for idx := 0; idx < 5; idx++ { inverse *= 2 - value * inverse }
+
+	return inverse
+}
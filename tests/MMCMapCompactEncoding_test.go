@@ -0,0 +1,192 @@
+package mmcmaptests
+
+import "bytes"
+import "fmt"
+import "os"
+import "path/filepath"
+import "strings"
+import "testing"
+import "time"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var compactEncodingTestPath = filepath.Join(os.TempDir(), "testcompactencoding")
+var compactEncodingTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initCompactEncodingMapErr error
+	os.Remove(compactEncodingTestPath)
+
+	opts := mmcmap.MMCMapOpts{ Filepath: compactEncodingTestPath, NodeEncoding: mmcmap.NodeEncodingCompact }
+	compactEncodingTestMap, initCompactEncodingMapErr = mmcmap.Open(opts)
+	if initCompactEncodingMapErr != nil { panic(initCompactEncodingMapErr.Error()) }
+
+	fmt.Println("compact encoding test mmcmap initialized")
+}
+
+
+func TestMMCMapCompactEncoding(t *testing.T) {
+	defer compactEncodingTestMap.Remove()
+
+	keys := make([]string, 1000)
+	for idx := range keys { keys[idx] = fmt.Sprintf("key%d", idx) }
+
+	putValues := func(t *testing.T, mmcMap *mmcmap.MMCMap) {
+		for _, key := range keys {
+			_, putErr := mmcMap.Put([]byte(key), []byte("v" + key))
+			if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+		}
+	}
+
+	checkValues := func(t *testing.T, mmcMap *mmcmap.MMCMap) {
+		for _, key := range keys {
+			value, getErr := mmcMap.Get([]byte(key))
+			if getErr != nil { t.Fatalf("error on get: %s", getErr.Error()) }
+			if string(value) != "v" + key { t.Fatalf("value mismatch for %s: actual(%s), expected(v%s)", key, value, key) }
+		}
+	}
+
+	putValues(t, compactEncodingTestMap)
+
+	t.Run("Test Reads", func(t *testing.T) {
+		checkValues(t, compactEncodingTestMap)
+
+		if compactEncodingTestMap.Header.FormatVersion != mmcmap.HeaderFormatVersion {
+			t.Errorf("format version mismatch: actual(%d), expected(%d)", compactEncodingTestMap.Header.FormatVersion, mmcmap.HeaderFormatVersion)
+		}
+
+		length, _ := compactEncodingTestMap.Len()
+		if length != uint64(len(keys)) { t.Errorf("length mismatch: actual(%d), expected(%d)", length, len(keys)) }
+
+		pairs, rangeErr := compactEncodingTestMap.Range(nil, nil)
+		if rangeErr != nil { t.Fatalf("error on range: %s", rangeErr.Error()) }
+		if len(pairs) != len(keys) { t.Errorf("range length mismatch: actual(%d), expected(%d)", len(pairs), len(keys)) }
+
+		report, verifyErr := compactEncodingTestMap.Verify()
+		if verifyErr != nil { t.Fatalf("error on verify: %s", verifyErr.Error()) }
+		if ! report.IsValid { t.Errorf("verify found inconsistencies: %v", report.Findings) }
+	})
+
+	t.Run("Test Smaller Than Fixed Encoding", func(t *testing.T) {
+		path := filepath.Join(os.TempDir(), "testcompactencodingfixed")
+		os.Remove(path)
+
+		fixedMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: path })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+		defer fixedMap.Remove()
+
+		putValues(t, fixedMap)
+
+		fixedMeta, _ := fixedMap.ReadMetaFromMemMap()
+		compactMeta, _ := compactEncodingTestMap.ReadMetaFromMemMap()
+		if compactMeta.EndMmapOffset * 2 > fixedMeta.EndMmapOffset {
+			t.Errorf("compact encoding did not halve the file: actual(%d bytes), fixed(%d bytes)", compactMeta.EndMmapOffset, fixedMeta.EndMmapOffset)
+		}
+	})
+
+	t.Run("Test Expiration And Compression", func(t *testing.T) {
+		path := filepath.Join(os.TempDir(), "testcompactencodingcompressed")
+		os.Remove(path)
+
+		compressedMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: path, NodeEncoding: mmcmap.NodeEncodingCompact, Compression: mmcmap.CompressionSnappy })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+		defer compressedMap.Remove()
+
+		value := []byte(strings.Repeat("compressible", 100))
+		compressedMap.Put([]byte("compressed"), value)
+		compressedMap.PutWithTTL([]byte("expiring"), []byte("value"), 100 * time.Millisecond)
+
+		actual, _ := compressedMap.Get([]byte("compressed"))
+		if ! bytes.Equal(actual, value) { t.Errorf("compressed value mismatch: actual(%d bytes), expected(%d bytes)", len(actual), len(value)) }
+
+		actual, _ = compressedMap.Get([]byte("expiring"))
+		if string(actual) != "value" { t.Errorf("value before expiry mismatch: actual(%s), expected(value)", actual) }
+
+		time.Sleep(150 * time.Millisecond)
+
+		actual, _ = compressedMap.Get([]byte("expiring"))
+		if actual != nil { t.Errorf("expired key was returned: actual(%s)", actual) }
+	})
+
+	t.Run("Test History", func(t *testing.T) {
+		compactEncodingTestMap.Put([]byte("key0"), []byte("second"))
+		compactEncodingTestMap.Put([]byte("key0"), []byte("third"))
+
+		history, historyErr := compactEncodingTestMap.History([]byte("key0"), 0)
+		if historyErr != nil { t.Fatalf("error on history: %s", historyErr.Error()) }
+		if len(history) != 3 { t.Fatalf("history length mismatch: actual(%d), expected(3)", len(history)) }
+		if string(history[0].Value) != "third" || string(history[2].Value) != "vkey0" { t.Errorf("history mismatch: actual(%s, %s)", history[0].Value, history[2].Value) }
+
+		compactEncodingTestMap.Put([]byte("key0"), []byte("vkey0"))
+	})
+
+	t.Run("Test Compact And Reopen", func(t *testing.T) {
+		_, compactErr := compactEncodingTestMap.Compact()
+		if compactErr != nil { t.Fatalf("error on compact: %s", compactErr.Error()) }
+
+		checkValues(t, compactEncodingTestMap)
+
+		closeErr := compactEncodingTestMap.Close()
+		if closeErr != nil { t.Fatalf("error on close: %s", closeErr.Error()) }
+
+		var openErr error
+		compactEncodingTestMap, openErr = mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: compactEncodingTestPath, OpenCheck: mmcmap.OpenCheckDeep })
+		if openErr != nil { t.Fatalf("error on reopen: %s", openErr.Error()) }
+
+		if compactEncodingTestMap.Header.NodeEncoding != mmcmap.NodeEncodingCompact { t.Errorf("node encoding was not persisted: actual(%d)", compactEncodingTestMap.Header.NodeEncoding) }
+		if len(compactEncodingTestMap.OpenReport.Findings) != 0 { t.Errorf("open check found inconsistencies: %v", compactEncodingTestMap.OpenReport.Findings) }
+
+		checkValues(t, compactEncodingTestMap)
+	})
+
+	t.Run("Test Bulk Load And Truncate", func(t *testing.T) {
+		path := filepath.Join(os.TempDir(), "testcompactencodingbulk")
+		os.Remove(path)
+
+		bulkMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: path, NodeEncoding: mmcmap.NodeEncodingCompact })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+		defer bulkMap.Remove()
+
+		pairs := make([]mmcmap.KeyValuePair, len(keys))
+		for idx, key := range keys { pairs[idx] = mmcmap.KeyValuePair{ Key: []byte(key), Value: []byte("v" + key) } }
+
+		loadErr := bulkMap.BulkLoad(pairs)
+		if loadErr != nil { t.Fatalf("error on bulk load: %s", loadErr.Error()) }
+
+		checkValues(t, bulkMap)
+
+		clearErr := bulkMap.Clear(mmcmap.ClearOpts{ Truncate: true })
+		if clearErr != nil { t.Fatalf("error on clear: %s", clearErr.Error()) }
+		if bulkMap.Header.NodeEncoding != mmcmap.NodeEncodingCompact { t.Errorf("node encoding was not kept by clear: actual(%d)", bulkMap.Header.NodeEncoding) }
+
+		putValues(t, bulkMap)
+		checkValues(t, bulkMap)
+	})
+
+	t.Run("Test Unsupported Options", func(t *testing.T) {
+		path := filepath.Join(os.TempDir(), "testcompactencodingerrors")
+		os.Remove(path)
+		defer os.Remove(path)
+
+		fixedMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: path })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+		fixedMap.Close()
+
+		_, openErr = mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: path, NodeEncoding: mmcmap.NodeEncodingCompact })
+		if openErr != mmcmap.ErrNodeEncodingMismatch { t.Errorf("mismatch error mismatch: actual(%v), expected(%v)", openErr, mmcmap.ErrNodeEncodingMismatch) }
+
+		_, putErr := compactEncodingTestMap.PutReader([]byte("streamed"), bytes.NewReader([]byte("value")), 5)
+		if putErr != mmcmap.ErrNodeEncodingUnsupported { t.Errorf("put reader error mismatch: actual(%v), expected(%v)", putErr, mmcmap.ErrNodeEncodingUnsupported) }
+
+		freeListPath := filepath.Join(os.TempDir(), "testcompactencodingfreelist")
+		os.Remove(freeListPath)
+		defer os.Remove(freeListPath)
+
+		_, openErr = mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: freeListPath, NodeEncoding: mmcmap.NodeEncodingCompact, Allocator: mmcmap.FreeListAllocator{} })
+		if openErr != mmcmap.ErrNodeEncodingUnsupported { t.Errorf("free list error mismatch: actual(%v), expected(%v)", openErr, mmcmap.ErrNodeEncodingUnsupported) }
+	})
+
+	t.Log("Done")
+}
@@ -0,0 +1,268 @@
+package mmcmaptests
+
+import "bytes"
+import "errors"
+import "fmt"
+import "os"
+import "path/filepath"
+import "testing"
+import "time"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var cmpTestPath = filepath.Join(os.TempDir(), "testcompact")
+var compactTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initCmpMapErr error
+	os.Remove(cmpTestPath)
+
+	opts := mmcmap.MMCMapOpts{ Filepath: cmpTestPath }
+	compactTestMap, initCmpMapErr = mmcmap.Open(opts)
+	if initCmpMapErr != nil { panic(initCmpMapErr.Error()) }
+
+	fmt.Println("compact test mmcmap initialized")
+}
+
+
+func TestMMCMapCompact(t *testing.T) {
+	defer compactTestMap.Remove()
+
+	assertContents := func(t *testing.T, total int) {
+		keyCount, lenErr := compactTestMap.Len()
+		if lenErr != nil { t.Errorf("error on len: %s", lenErr.Error()) }
+		if keyCount != uint64(total) { t.Errorf("key count mismatch: actual(%d), expected(%d)", keyCount, total) }
+
+		for idx := 0; idx < total; idx++ {
+			key := []byte(fmt.Sprintf("key%d", idx))
+			val, getErr := compactTestMap.Get(key)
+			if getErr != nil { t.Errorf("error on get: %s", getErr.Error()) }
+			if ! bytes.Equal(val, []byte(fmt.Sprintf("value%d", idx))) { t.Errorf("value mismatch for %s: actual(%s), expected(value%d)", key, val, idx) }
+		}
+
+		report, verifyErr := compactTestMap.Verify()
+		if verifyErr != nil { t.Errorf("error on verify: %s", verifyErr.Error()) }
+		if report != nil && ! report.IsValid { t.Errorf("map invalid after compaction: %v", report.Findings) }
+	}
+
+	t.Run("Test Compact", func(t *testing.T) {
+		for round := 0; round < 5; round++ {
+			for idx := 0; idx < 1000; idx++ {
+				_, putErr := compactTestMap.Put([]byte(fmt.Sprintf("key%d", idx)), []byte(fmt.Sprintf("value%d", idx)))
+				if putErr != nil { t.Errorf("error on put: %s", putErr.Error()) }
+			}
+		}
+
+		prevVersion, _ := readVersion(compactTestMap)
+
+		report, compactErr := compactTestMap.Compact()
+		if compactErr != nil { t.Fatalf("error on compact: %s", compactErr.Error()) }
+
+		if report.Version != prevVersion + 1 { t.Errorf("compaction did not commit a single version: actual(%d), expected(%d)", report.Version, prevVersion + 1) }
+		if report.EndMmapOffset >= report.PrevEndMmapOffset { t.Errorf("compaction did not reclaim space: actual(%d), previous(%d)", report.EndMmapOffset, report.PrevEndMmapOffset) }
+		if report.ReclaimedBytes != report.PrevEndMmapOffset - report.EndMmapOffset { t.Errorf("reclaimed bytes mismatch: actual(%d)", report.ReclaimedBytes) }
+
+		version, _ := readVersion(compactTestMap)
+		if version != report.Version { t.Errorf("version mismatch: actual(%d), expected(%d)", version, report.Version) }
+
+		assertContents(t, 1000)
+	})
+
+	t.Run("Test Compact Is Stable", func(t *testing.T) {
+		report, compactErr := compactTestMap.Compact()
+		if compactErr != nil { t.Fatalf("error on compact: %s", compactErr.Error()) }
+
+		if report.ReclaimedBytes != 0 { t.Errorf("compacting a compacted map reclaimed space: actual(%d), expected(0)", report.ReclaimedBytes) }
+		assertContents(t, 1000)
+	})
+
+	t.Run("Test Txn Across Compact", func(t *testing.T) {
+		txn, beginErr := compactTestMap.BeginTxn()
+		if beginErr != nil { t.Fatalf("error on begin: %s", beginErr.Error()) }
+
+		val, getErr := txn.Get([]byte("key0"))
+		if getErr != nil { t.Errorf("error on get before compact: %s", getErr.Error()) }
+		if ! bytes.Equal(val, []byte("value0")) { t.Errorf("value mismatch before compact: actual(%s), expected(value0)", val) }
+
+		_, compactErr := compactTestMap.Compact()
+		if compactErr != nil { t.Fatalf("error on compact: %s", compactErr.Error()) }
+
+		for idx := 0; idx < 100; idx++ {
+			_, getErr = txn.Get([]byte(fmt.Sprintf("key%d", idx)))
+			if ! errors.Is(getErr, mmcmap.ErrVersionUnavailable) { t.Fatalf("expected ErrVersionUnavailable reading a relocated snapshot, got: %v", getErr) }
+		}
+
+		txn.Rollback()
+		assertContents(t, 1000)
+	})
+
+	t.Run("Test Compact Waits For Pinned Range", func(t *testing.T) {
+		pairs, errs := compactTestMap.RangeChan(nil, nil)
+		<-pairs
+
+		compacted := make(chan error, 1)
+		go func() {
+			_, compactErr := compactTestMap.Compact()
+			compacted <- compactErr
+		}()
+
+		time.Sleep(50 * time.Millisecond)
+
+		unblocked := make(chan error, 1)
+		go func() {
+			_, putErr := compactTestMap.Put([]byte("key0"), []byte("value0"))
+			if putErr != nil {
+				unblocked <- putErr
+				return
+			}
+
+			_, rangeErr := compactTestMap.Range([]byte("key0"), []byte("key1"))
+			unblocked <- rangeErr
+		}()
+
+		select {
+			case opErr := <-unblocked:
+				if opErr != nil { t.Errorf("error while compaction is queued: %s", opErr.Error()) }
+			case <-time.After(2 * time.Second):
+				t.Fatal("put and range blocked behind a compaction waiting for a pinned range")
+		}
+
+		select {
+			case <-compacted:
+				t.Error("compaction ran while a range was pinned")
+			default:
+		}
+
+		for range pairs {}
+		rangeErr := <-errs
+		if rangeErr != nil { t.Errorf("error on range chan: %s", rangeErr.Error()) }
+
+		compactErr := <-compacted
+		if compactErr != nil { t.Errorf("error on compact: %s", compactErr.Error()) }
+
+		assertContents(t, 1000)
+	})
+
+	t.Run("Test Writes After Compact", func(t *testing.T) {
+		for idx := 1000; idx < 1500; idx++ {
+			_, putErr := compactTestMap.Put([]byte(fmt.Sprintf("key%d", idx)), []byte(fmt.Sprintf("value%d", idx)))
+			if putErr != nil { t.Errorf("error on put: %s", putErr.Error()) }
+		}
+
+		closeErr := compactTestMap.Close()
+		if closeErr != nil { t.Errorf("error on close: %s", closeErr.Error()) }
+
+		var openErr error
+		compactTestMap, openErr = mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: cmpTestPath })
+		if openErr != nil { t.Fatalf("error on reopen: %s", openErr.Error()) }
+
+		assertContents(t, 1500)
+	})
+
+	t.Run("Test Shrink To Fit", func(t *testing.T) {
+		prevSize, _ := compactTestMap.FileSize()
+		meta, _ := compactTestMap.ReadMetaFromMemMap()
+
+		size, shrinkErr := compactTestMap.ShrinkToFit()
+		if shrinkErr != nil { t.Fatalf("error on shrink: %s", shrinkErr.Error()) }
+		if size >= prevSize { t.Errorf("file did not shrink: actual(%d), previous(%d)", size, prevSize) }
+		if uint64(size) <= meta.EndMmapOffset { t.Errorf("file shrunk past the serialized data: actual(%d), end(%d)", size, meta.EndMmapOffset) }
+
+		fileSize, _ := compactTestMap.FileSize()
+		if fileSize != size { t.Errorf("file size mismatch: actual(%d), expected(%d)", fileSize, size) }
+
+		assertContents(t, 1500)
+
+		for idx := 1500; idx < 3000; idx++ {
+			_, putErr := compactTestMap.Put([]byte(fmt.Sprintf("key%d", idx)), []byte(fmt.Sprintf("value%d", idx)))
+			if putErr != nil { t.Errorf("error on put: %s", putErr.Error()) }
+		}
+
+		assertContents(t, 3000)
+	})
+
+	t.Run("Test Clone To", func(t *testing.T) {
+		cloneTestPath := filepath.Join(os.TempDir(), "testcompactclone")
+		os.Remove(cloneTestPath)
+
+		cloneErr := compactTestMap.CloneTo(cloneTestPath)
+		if cloneErr != nil { t.Fatalf("error on clone: %s", cloneErr.Error()) }
+
+		cloneTestMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: cloneTestPath })
+		if openErr != nil { t.Fatalf("error opening clone: %s", openErr.Error()) }
+		defer cloneTestMap.Remove()
+
+		version, _ := readVersion(compactTestMap)
+		cloneVersion, _ := readVersion(cloneTestMap)
+		if cloneVersion != version { t.Errorf("clone version mismatch: actual(%d), expected(%d)", cloneVersion, version) }
+
+		keyCount, lenErr := cloneTestMap.Len()
+		if lenErr != nil { t.Errorf("error on len: %s", lenErr.Error()) }
+		if keyCount != 3000 { t.Errorf("clone key count mismatch: actual(%d), expected(3000)", keyCount) }
+
+		for idx := 0; idx < 3000; idx++ {
+			key := []byte(fmt.Sprintf("key%d", idx))
+			val, getErr := cloneTestMap.Get(key)
+			if getErr != nil { t.Errorf("error on get: %s", getErr.Error()) }
+			if ! bytes.Equal(val, []byte(fmt.Sprintf("value%d", idx))) { t.Errorf("clone value mismatch for %s: actual(%s), expected(value%d)", key, val, idx) }
+		}
+
+		report, verifyErr := cloneTestMap.Verify()
+		if verifyErr != nil { t.Errorf("error on verify: %s", verifyErr.Error()) }
+		if report != nil && ! report.IsValid { t.Errorf("clone invalid: %v", report.Findings) }
+
+		_, putErr := cloneTestMap.Put([]byte("key0"), []byte("cloned"))
+		if putErr != nil { t.Errorf("error on put: %s", putErr.Error()) }
+
+		assertContents(t, 3000)
+
+		cloneErr = compactTestMap.CloneTo(cloneTestPath)
+		if ! errors.Is(cloneErr, os.ErrExist) { t.Errorf("expected os.ErrExist cloning over an existing file, got: %v", cloneErr) }
+	})
+
+	t.Run("Test Auto Compaction", func(t *testing.T) {
+		autoTestPath := filepath.Join(os.TempDir(), "testautocompact")
+		os.Remove(autoTestPath)
+
+		threshold := mmcmap.CompactionThreshold{ MinSize: 1 << 16, Interval: 10 * time.Millisecond }
+		autoTestMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: autoTestPath, CompactionThreshold: threshold })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+		defer autoTestMap.Remove()
+
+		autoTestMap.PauseCompaction()
+
+		for round := 0; round < 5; round++ {
+			for idx := 0; idx < 1000; idx++ {
+				_, putErr := autoTestMap.Put([]byte(fmt.Sprintf("key%d", idx)), []byte(fmt.Sprintf("value%d", idx)))
+				if putErr != nil { t.Errorf("error on put: %s", putErr.Error()) }
+			}
+		}
+
+		time.Sleep(50 * time.Millisecond)
+
+		stats := autoTestMap.CompactionStats()
+		if ! stats.IsPaused { t.Error("compaction stats do not report the pause") }
+		if stats.Count != 0 { t.Errorf("map compacted while paused: actual(%d), expected(0)", stats.Count) }
+
+		autoTestMap.ResumeCompaction()
+
+		deadline := time.Now().Add(5 * time.Second)
+		for autoTestMap.CompactionStats().Count == 0 && time.Now().Before(deadline) { time.Sleep(10 * time.Millisecond) }
+
+		stats = autoTestMap.CompactionStats()
+		if stats.Count == 0 { t.Fatal("map was not compacted automatically") }
+		if stats.ReclaimedBytes == 0 { t.Error("automatic compaction did not reclaim space") }
+		if stats.Errors != 0 { t.Errorf("automatic compaction failed: actual(%d), expected(0)", stats.Errors) }
+
+		for idx := 0; idx < 1000; idx++ {
+			key := []byte(fmt.Sprintf("key%d", idx))
+			val, getErr := autoTestMap.Get(key)
+			if getErr != nil { t.Errorf("error on get: %s", getErr.Error()) }
+			if ! bytes.Equal(val, []byte(fmt.Sprintf("value%d", idx))) { t.Errorf("value mismatch for %s: actual(%s), expected(value%d)", key, val, idx) }
+		}
+	})
+
+	t.Log("Done")
+}
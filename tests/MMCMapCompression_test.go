@@ -0,0 +1,162 @@
+package mmcmaptests
+
+import "bytes"
+import "crypto/rand"
+import "fmt"
+import "io"
+import "os"
+import "path/filepath"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var compressionTestPath = filepath.Join(os.TempDir(), "testcompression")
+var compressionTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initCompressionMapErr error
+	os.Remove(compressionTestPath)
+
+	compressionTestMap, initCompressionMapErr = mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: compressionTestPath, Compression: mmcmap.CompressionSnappy })
+	if initCompressionMapErr != nil { panic(initCompressionMapErr.Error()) }
+
+	fmt.Println("compression test mmcmap initialized")
+}
+
+
+// compressibleValue is a repetitive value for key that shrinks when compressed
+func compressibleValue(key string) []byte {
+	return bytes.Repeat([]byte(key + ":"), 1024 / len(key) + 1)
+}
+
+
+func TestMMCMapCompression(t *testing.T) {
+	defer compressionTestMap.Remove()
+
+	keys := make([]string, 1000)
+	for idx := range keys { keys[idx] = fmt.Sprintf("key%d", idx) }
+
+	putValues := func(t *testing.T, mmcMap *mmcmap.MMCMap) {
+		for _, key := range keys {
+			_, putErr := mmcMap.Put([]byte(key), compressibleValue(key))
+			if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+		}
+	}
+
+	checkValues := func(t *testing.T, mmcMap *mmcmap.MMCMap) {
+		for _, key := range keys {
+			value, getErr := mmcMap.Get([]byte(key))
+			if getErr != nil { t.Fatalf("error on get: %s", getErr.Error()) }
+			if ! bytes.Equal(value, compressibleValue(key)) { t.Fatalf("value mismatch for %s: actual(%d bytes), expected(%d bytes)", key, len(value), len(compressibleValue(key))) }
+		}
+	}
+
+	putValues(t, compressionTestMap)
+
+	t.Run("Test Values Are Compressed", func(t *testing.T) {
+		checkValues(t, compressionTestMap)
+
+		meta, _ := compressionTestMap.ReadMetaFromMemMap()
+		leaf := findLeaf(t, compressionTestMap, meta.RootOffset, []byte("key0"))
+		if leaf == nil || leaf.Compression != mmcmap.CompressionSnappy { t.Fatalf("value is not compressed: %+v", leaf) }
+		if size := leaf.EndOffset - leaf.StartOffset + 1; size >= uint64(len(compressibleValue("key0"))) { t.Errorf("compressed leaf is not smaller than the value: actual(%d bytes)", size) }
+
+		pairs, rangeErr := compressionTestMap.Range([]byte("key0"), []byte("key0"))
+		if rangeErr != nil { t.Fatalf("error on range: %s", rangeErr.Error()) }
+		if len(pairs) != 1 || ! bytes.Equal(pairs[0].Value, compressibleValue("key0")) { t.Error("range did not return the decompressed value") }
+
+		reader, getErr := compressionTestMap.GetReader([]byte("key0"))
+		if getErr != nil { t.Fatalf("error on get reader: %s", getErr.Error()) }
+		defer reader.Close()
+
+		streamed, _ := io.ReadAll(reader)
+		if ! bytes.Equal(streamed, compressibleValue("key0")) { t.Error("get reader did not return the decompressed value") }
+	})
+
+	t.Run("Test File Growth", func(t *testing.T) {
+		path := filepath.Join(os.TempDir(), "testcompressionplain")
+		os.Remove(path)
+
+		plainMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: path })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+		defer plainMap.Remove()
+
+		putValues(t, plainMap)
+
+		plainMeta, _ := plainMap.ReadMetaFromMemMap()
+		compressedMeta, _ := compressionTestMap.ReadMetaFromMemMap()
+		if compressedMeta.EndMmapOffset * 2 > plainMeta.EndMmapOffset {
+			t.Errorf("compressed map did not shrink: actual(%d bytes), uncompressed(%d bytes)", compressedMeta.EndMmapOffset, plainMeta.EndMmapOffset)
+		}
+	})
+
+	t.Run("Test Incompressible Values Stored As Is", func(t *testing.T) {
+		value := make([]byte, 512)
+		rand.Read(value)
+
+		compressionTestMap.Put([]byte("random"), value)
+
+		meta, _ := compressionTestMap.ReadMetaFromMemMap()
+		leaf := findLeaf(t, compressionTestMap, meta.RootOffset, []byte("random"))
+		if leaf == nil || leaf.Compression != mmcmap.CompressionNone { t.Errorf("incompressible value was compressed: %+v", leaf) }
+
+		stored, _ := compressionTestMap.Get([]byte("random"))
+		if ! bytes.Equal(stored, value) { t.Error("incompressible value mismatch") }
+
+		compressionTestMap.Delete([]byte("random"))
+	})
+
+	t.Run("Test Compact And Reopen Without Compression", func(t *testing.T) {
+		_, compactErr := compressionTestMap.Compact()
+		if compactErr != nil { t.Fatalf("error on compact: %s", compactErr.Error()) }
+
+		checkValues(t, compressionTestMap)
+		compressionTestMap.Close()
+
+		var openErr error
+		compressionTestMap, openErr = mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: compressionTestPath })
+		if openErr != nil { t.Fatalf("error on reopen: %s", openErr.Error()) }
+
+		checkValues(t, compressionTestMap)
+
+		compressionTestMap.Put([]byte("key0"), compressibleValue("key0"))
+
+		meta, _ := compressionTestMap.ReadMetaFromMemMap()
+		leaf := findLeaf(t, compressionTestMap, meta.RootOffset, []byte("key0"))
+		if leaf == nil || leaf.Compression != mmcmap.CompressionNone { t.Errorf("value was compressed without compression enabled: %+v", leaf) }
+	})
+
+	t.Run("Test Zstd With Overflow", func(t *testing.T) {
+		path := filepath.Join(os.TempDir(), "testcompressionzstd")
+		os.Remove(path)
+
+		zstdMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: path, Compression: mmcmap.CompressionZstd, OverflowThreshold: 256 })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+		defer zstdMap.Remove()
+
+		putValues(t, zstdMap)
+		checkValues(t, zstdMap)
+
+		meta, _ := zstdMap.ReadMetaFromMemMap()
+		leaf := findLeaf(t, zstdMap, meta.RootOffset, []byte("key0"))
+		if leaf == nil || ! leaf.IsOverflow || leaf.Compression != mmcmap.CompressionZstd { t.Fatalf("value is not compressed in an extent: %+v", leaf) }
+
+		report, verifyErr := zstdMap.Verify()
+		if verifyErr != nil { t.Fatalf("error on verify: %s", verifyErr.Error()) }
+		if ! report.IsValid { t.Errorf("verify found inconsistencies: %v", report.Findings) }
+
+		_, compactErr := zstdMap.Compact()
+		if compactErr != nil { t.Fatalf("error on compact: %s", compactErr.Error()) }
+
+		checkValues(t, zstdMap)
+	})
+
+	t.Run("Test Unknown Codec", func(t *testing.T) {
+		_, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: filepath.Join(os.TempDir(), "testcompressionunknown"), Compression: 9 })
+		if openErr != mmcmap.ErrUnknownCompression { t.Errorf("unknown codec error mismatch: actual(%v), expected(%v)", openErr, mmcmap.ErrUnknownCompression) }
+	})
+
+	t.Log("Done")
+}
@@ -0,0 +1,123 @@
+package mmcmaptests
+
+import "fmt"
+import "os"
+import "path/filepath"
+import "strconv"
+import "sync"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var cdTestPath = filepath.Join(os.TempDir(), "testconditional")
+var conditionalTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initCdMapErr error
+	os.Remove(cdTestPath)
+
+	opts := mmcmap.MMCMapOpts{ Filepath: cdTestPath }
+	conditionalTestMap, initCdMapErr = mmcmap.Open(opts)
+	if initCdMapErr != nil { panic(initCdMapErr.Error()) }
+
+	fmt.Println("conditional test mmcmap initialized")
+}
+
+
+func TestMMCMapConditional(t *testing.T) {
+	defer conditionalTestMap.Remove()
+
+	t.Run("Test Compare And Swap", func(t *testing.T) {
+		swapped, casErr := conditionalTestMap.CompareAndSwap([]byte("cas"), nil, []byte("first"))
+		if casErr != nil { t.Errorf("error on compare and swap: %s", casErr.Error()) }
+		if ! swapped { t.Error("compare and swap on absent key with nil expected value did not swap") }
+
+		meta, readMetaErr := conditionalTestMap.ReadMetaFromMemMap()
+		if readMetaErr != nil { t.Errorf("error reading metadata: %s", readMetaErr.Error()) }
+
+		swapped, casErr = conditionalTestMap.CompareAndSwap([]byte("cas"), []byte("wrong"), []byte("second"))
+		if casErr != nil { t.Errorf("error on compare and swap: %s", casErr.Error()) }
+		if swapped { t.Error("compare and swap with mismatched expected value swapped") }
+
+		updatedMeta, readMetaErr := conditionalTestMap.ReadMetaFromMemMap()
+		if readMetaErr != nil { t.Errorf("error reading metadata: %s", readMetaErr.Error()) }
+		if updatedMeta.Version != meta.Version { t.Errorf("failed compare and swap wrote a version: actual(%d), expected(%d)", updatedMeta.Version, meta.Version) }
+
+		swapped, casErr = conditionalTestMap.CompareAndSwap([]byte("cas"), []byte("first"), []byte("second"))
+		if casErr != nil { t.Errorf("error on compare and swap: %s", casErr.Error()) }
+		if ! swapped { t.Error("compare and swap with matching expected value did not swap") }
+
+		val, getErr := conditionalTestMap.Get([]byte("cas"))
+		if getErr != nil { t.Errorf("error getting from mmcmap: %s", getErr.Error()) }
+		if string(val) != "second" { t.Errorf("swapped value mismatch: actual(%s), expected(second)", val) }
+	})
+
+	t.Run("Test Concurrent Compare And Swap", func(t *testing.T) {
+		_, putErr := conditionalTestMap.Put([]byte("counter"), []byte("0"))
+		if putErr != nil { t.Errorf("error putting key in mmcmap: %s", putErr.Error()) }
+
+		var wg sync.WaitGroup
+		for range make([]int, 8) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for range make([]int, 25) {
+					for {
+						curr, getErr := conditionalTestMap.Get([]byte("counter"))
+						if getErr != nil { t.Errorf("error getting from mmcmap: %s", getErr.Error()); return }
+
+						count, _ := strconv.Atoi(string(curr))
+						swapped, casErr := conditionalTestMap.CompareAndSwap([]byte("counter"), curr, []byte(strconv.Itoa(count + 1)))
+						if casErr != nil { t.Errorf("error on compare and swap: %s", casErr.Error()); return }
+						if swapped { break }
+					}
+				}
+			}()
+		}
+
+		wg.Wait()
+
+		val, getErr := conditionalTestMap.Get([]byte("counter"))
+		if getErr != nil { t.Errorf("error getting from mmcmap: %s", getErr.Error()) }
+		if string(val) != "200" { t.Errorf("lost updates with compare and swap: actual(%s), expected(200)", val) }
+	})
+
+	t.Run("Test Put If Absent", func(t *testing.T) {
+		ok, putErr := conditionalTestMap.PutIfAbsent([]byte("absent"), []byte("first"))
+		if putErr != nil { t.Errorf("error on put if absent: %s", putErr.Error()) }
+		if ! ok { t.Error("put if absent did not put an absent key") }
+
+		ok, putErr = conditionalTestMap.PutIfAbsent([]byte("absent"), []byte("second"))
+		if putErr != nil { t.Errorf("error on put if absent: %s", putErr.Error()) }
+		if ok { t.Error("put if absent overwrote an existing key") }
+
+		val, getErr := conditionalTestMap.Get([]byte("absent"))
+		if getErr != nil { t.Errorf("error getting from mmcmap: %s", getErr.Error()) }
+		if string(val) != "first" { t.Errorf("value mismatch: actual(%s), expected(first)", val) }
+	})
+
+	t.Run("Test Delete If Equals", func(t *testing.T) {
+		meta, readMetaErr := conditionalTestMap.ReadMetaFromMemMap()
+		if readMetaErr != nil { t.Errorf("error reading metadata: %s", readMetaErr.Error()) }
+
+		ok, delErr := conditionalTestMap.DeleteIfEquals([]byte("absent"), []byte("second"))
+		if delErr != nil { t.Errorf("error on delete if equals: %s", delErr.Error()) }
+		if ok { t.Error("delete if equals deleted a key with a different value") }
+
+		updatedMeta, readMetaErr := conditionalTestMap.ReadMetaFromMemMap()
+		if readMetaErr != nil { t.Errorf("error reading metadata: %s", readMetaErr.Error()) }
+		if updatedMeta.Version != meta.Version { t.Errorf("failed delete if equals wrote a version: actual(%d), expected(%d)", updatedMeta.Version, meta.Version) }
+
+		ok, delErr = conditionalTestMap.DeleteIfEquals([]byte("absent"), []byte("first"))
+		if delErr != nil { t.Errorf("error on delete if equals: %s", delErr.Error()) }
+		if ! ok { t.Error("delete if equals did not delete a key with the expected value") }
+
+		val, getErr := conditionalTestMap.Get([]byte("absent"))
+		if getErr != nil { t.Errorf("error getting from mmcmap: %s", getErr.Error()) }
+		if val != nil { t.Errorf("key present after delete if equals: %s", val) }
+	})
+
+	t.Log("Done")
+}
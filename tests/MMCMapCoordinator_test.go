@@ -0,0 +1,101 @@
+package mmcmaptests
+
+import "fmt"
+import "os"
+import "path/filepath"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var coDataTestPath = filepath.Join(os.TempDir(), "testcoorddata")
+var coIndexTestPath = filepath.Join(os.TempDir(), "testcoordindex")
+var coIntentTestPath = filepath.Join(os.TempDir(), "testcoordintent")
+var coDataTestMap *mmcmap.MMCMap
+var coIndexTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initCoMapErr error
+	os.Remove(coDataTestPath)
+	os.Remove(coIndexTestPath)
+	os.Remove(coIntentTestPath)
+
+	coDataTestMap, initCoMapErr = mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: coDataTestPath })
+	if initCoMapErr != nil { panic(initCoMapErr.Error()) }
+
+	coIndexTestMap, initCoMapErr = mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: coIndexTestPath })
+	if initCoMapErr != nil { panic(initCoMapErr.Error()) }
+
+	fmt.Println("coordinator test mmcmaps initialized")
+}
+
+
+func TestMMCMapCoordinator(t *testing.T) {
+	defer coDataTestMap.Remove()
+	defer coIndexTestMap.Remove()
+	defer os.Remove(coIntentTestPath)
+
+	coord := mmcmap.NewCoordinator(coIntentTestPath)
+
+	t.Run("Test Multi Map Commit", func(t *testing.T) {
+		dataBatch := coDataTestMap.NewWriteBatch()
+		dataBatch.Put([]byte("user1"), []byte("alice"))
+
+		indexBatch := coIndexTestMap.NewWriteBatch()
+		indexBatch.Put([]byte("alice"), []byte("user1"))
+
+		commitErr := coord.Commit(dataBatch, indexBatch)
+		if commitErr != nil { t.Errorf("error on multi map commit: %s", commitErr.Error()) }
+
+		val, getErr := coDataTestMap.Get([]byte("user1"))
+		if getErr != nil { t.Errorf("error getting from data map: %s", getErr.Error()) }
+		if string(val) != "alice" { t.Errorf("data map value mismatch: actual(%s), expected(alice)", val) }
+
+		val, getErr = coIndexTestMap.Get([]byte("alice"))
+		if getErr != nil { t.Errorf("error getting from index map: %s", getErr.Error()) }
+		if string(val) != "user1" { t.Errorf("index map value mismatch: actual(%s), expected(user1)", val) }
+
+		_, statErr := os.Stat(coIntentTestPath)
+		if ! os.IsNotExist(statErr) { t.Error("intent record not removed after commit") }
+	})
+
+	t.Run("Test Recover Interrupted Commit", func(t *testing.T) {
+		intent := &mmcmap.CommitIntent{}
+		for _, mmcMap := range []*mmcmap.MMCMap{ coDataTestMap, coIndexTestMap } {
+			meta, readMetaErr := mmcMap.ReadMetaFromMemMap()
+			if readMetaErr != nil { t.Fatalf("error reading metadata: %s", readMetaErr.Error()) }
+
+			absPath, _ := filepath.Abs(mmcMap.Opts.Filepath)
+			intent.Entries = append(intent.Entries, &mmcmap.IntentEntry{ Filepath: absPath, Version: meta.Version, RootOffset: meta.RootOffset })
+		}
+
+		writeErr := os.WriteFile(coIntentTestPath, intent.Serialize(), 0600)
+		if writeErr != nil { t.Fatalf("error writing intent: %s", writeErr.Error()) }
+
+		_, putErr := coDataTestMap.Put([]byte("user2"), []byte("bob"))
+		if putErr != nil { t.Errorf("error putting key in mmcmap: %s", putErr.Error()) }
+
+		recoverErr := coord.Recover(coDataTestMap)
+		if recoverErr != mmcmap.ErrIntentMapMissing { t.Errorf("expected missing map error, got: %v", recoverErr) }
+
+		recoverErr = coord.Recover(coDataTestMap, coIndexTestMap)
+		if recoverErr != nil { t.Errorf("error recovering: %s", recoverErr.Error()) }
+
+		val, getErr := coDataTestMap.Get([]byte("user2"))
+		if getErr != nil { t.Errorf("error getting from data map: %s", getErr.Error()) }
+		if val != nil { t.Errorf("uncommitted write survived recovery: %s", val) }
+
+		val, getErr = coDataTestMap.Get([]byte("user1"))
+		if getErr != nil { t.Errorf("error getting from data map: %s", getErr.Error()) }
+		if string(val) != "alice" { t.Errorf("committed value lost in recovery: actual(%s), expected(alice)", val) }
+
+		_, putErr = coDataTestMap.Put([]byte("user3"), []byte("carol"))
+		if putErr != nil { t.Errorf("error putting key after recovery: %s", putErr.Error()) }
+
+		_, statErr := os.Stat(coIntentTestPath)
+		if ! os.IsNotExist(statErr) { t.Error("intent record not removed after recovery") }
+	})
+
+	t.Log("Done")
+}
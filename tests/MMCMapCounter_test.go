@@ -0,0 +1,75 @@
+package mmcmaptests
+
+import "encoding/binary"
+import "fmt"
+import "os"
+import "path/filepath"
+import "sync"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var ctTestPath = filepath.Join(os.TempDir(), "testcounter")
+var counterTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initCtMapErr error
+	os.Remove(ctTestPath)
+
+	opts := mmcmap.MMCMapOpts{ Filepath: ctTestPath }
+	counterTestMap, initCtMapErr = mmcmap.Open(opts)
+	if initCtMapErr != nil { panic(initCtMapErr.Error()) }
+
+	fmt.Println("counter test mmcmap initialized")
+}
+
+
+func TestMMCMapIncrBy(t *testing.T) {
+	defer counterTestMap.Remove()
+
+	t.Run("Test Incr And Decr", func(t *testing.T) {
+		val, incrErr := counterTestMap.IncrBy([]byte("counter"), 10)
+		if incrErr != nil { t.Errorf("error on incr: %s", incrErr.Error()) }
+		if val != 10 { t.Errorf("incr value mismatch: actual(%d), expected(10)", val) }
+
+		val, incrErr = counterTestMap.IncrBy([]byte("counter"), -25)
+		if incrErr != nil { t.Errorf("error on decr: %s", incrErr.Error()) }
+		if val != -15 { t.Errorf("decr value mismatch: actual(%d), expected(-15)", val) }
+
+		stored, getErr := counterTestMap.Get([]byte("counter"))
+		if getErr != nil { t.Errorf("error getting from mmcmap: %s", getErr.Error()) }
+		if int64(binary.LittleEndian.Uint64(stored)) != -15 { t.Errorf("stored value is not little endian int64: %v", stored) }
+	})
+
+	t.Run("Test Incr Non Integer", func(t *testing.T) {
+		_, putErr := counterTestMap.Put([]byte("text"), []byte("hello"))
+		if putErr != nil { t.Errorf("error putting key in mmcmap: %s", putErr.Error()) }
+
+		_, incrErr := counterTestMap.IncrBy([]byte("text"), 1)
+		if incrErr != mmcmap.ErrNotInteger { t.Errorf("expected not integer error, got: %v", incrErr) }
+	})
+
+	t.Run("Test Concurrent Incr", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for range make([]int, 8) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for range make([]int, 50) {
+					_, incrErr := counterTestMap.IncrBy([]byte("concurrent"), 1)
+					if incrErr != nil { t.Errorf("error on incr: %s", incrErr.Error()) }
+				}
+			}()
+		}
+
+		wg.Wait()
+
+		val, incrErr := counterTestMap.IncrBy([]byte("concurrent"), 0)
+		if incrErr != nil { t.Errorf("error on incr: %s", incrErr.Error()) }
+		if val != 400 { t.Errorf("lost increments: actual(%d), expected(400)", val) }
+	})
+
+	t.Log("Done")
+}
@@ -0,0 +1,112 @@
+package mmcmaptests
+
+import "fmt"
+import "os"
+import "path/filepath"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var delTestPath = filepath.Join(os.TempDir(), "testdelete")
+var delTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initDelMapErr error
+	os.Remove(delTestPath)
+
+	delTestMap, initDelMapErr = mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: delTestPath })
+	if initDelMapErr != nil { panic(initDelMapErr.Error()) }
+
+	fmt.Println("delete test mmcmap initialized")
+}
+
+
+// readRoot reads the root of the latest version
+func readRoot(t *testing.T, mmcMap *mmcmap.MMCMap) *mmcmap.MMCMapNode {
+	meta, readMetaErr := mmcMap.ReadMetaFromMemMap()
+	if readMetaErr != nil { t.Fatalf("error reading meta: %s", readMetaErr.Error()) }
+
+	root, readRootErr := mmcMap.ReadNodeFromMemMap(meta.RootOffset)
+	if readRootErr != nil { t.Fatalf("error reading root: %s", readRootErr.Error()) }
+
+	return root
+}
+
+// checkCollapsed fails if any internal node below the root is empty or holds a single leaf, returning the number of leaves
+func checkCollapsed(t *testing.T, mmcMap *mmcmap.MMCMap, node *mmcmap.MMCMapNode, isRoot bool) int {
+	if node.IsLeaf { return 1 }
+
+	if len(node.Children) == 0 && ! isRoot { t.Errorf("empty internal node at offset %d", node.StartOffset) }
+
+	leaves := 0
+	for _, child := range node.Children {
+		childNode, readErr := mmcMap.ReadNodeFromMemMap(child.StartOffset)
+		if readErr != nil { t.Fatalf("error reading child: %s", readErr.Error()) }
+
+		if ! isRoot && len(node.Children) == 1 && childNode.IsLeaf { t.Errorf("internal node at offset %d holds a single leaf", node.StartOffset) }
+		leaves += checkCollapsed(t, mmcMap, childNode, false)
+	}
+
+	return leaves
+}
+
+
+func TestMMCMapDelete(t *testing.T) {
+	defer delTestMap.Remove()
+
+	keys := make([][]byte, 5000)
+	for idx := range keys {
+		keys[idx] = []byte(fmt.Sprintf("key%d", idx))
+		_, putErr := delTestMap.Put(keys[idx], []byte("value"))
+		if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+	}
+
+	t.Run("Test Delete Collapses Subtrees", func(t *testing.T) {
+		for idx := 0; idx < len(keys); idx += 2 {
+			isDeleted, delErr := delTestMap.Delete(keys[idx])
+			if delErr != nil { t.Fatalf("error on delete: %s", delErr.Error()) }
+			if ! isDeleted { t.Fatalf("key was not deleted: %s", keys[idx]) }
+		}
+
+		leaves := checkCollapsed(t, delTestMap, readRoot(t, delTestMap), true)
+		if leaves != len(keys) / 2 { t.Errorf("leaf count mismatch: actual(%d), expected(%d)", leaves, len(keys) / 2) }
+
+		for idx, key := range keys {
+			value, getErr := delTestMap.Get(key)
+			if getErr != nil { t.Fatalf("error on get: %s", getErr.Error()) }
+
+			isPresent := value != nil
+			if isPresent != (idx % 2 == 1) { t.Errorf("key presence mismatch for %s: actual(%t), expected(%t)", key, isPresent, idx % 2 == 1) }
+		}
+	})
+
+	t.Run("Test Delete Missing Key", func(t *testing.T) {
+		isDeleted, delErr := delTestMap.Delete([]byte("missing"))
+		if delErr != nil { t.Fatalf("error on delete: %s", delErr.Error()) }
+		if isDeleted { t.Error("missing key reported as deleted") }
+	})
+
+	t.Run("Test Delete Every Key Empties Root", func(t *testing.T) {
+		batch := delTestMap.NewWriteBatch()
+		for idx := 1; idx < len(keys); idx += 2 { batch.Delete(keys[idx]) }
+
+		_, commitErr := batch.Commit()
+		if commitErr != nil { t.Fatalf("error on commit: %s", commitErr.Error()) }
+
+		root := readRoot(t, delTestMap)
+		if root.Bitmap != 0 || len(root.Children) != 0 { t.Errorf("root not empty: actual(bitmap %032b, %d children)", root.Bitmap, len(root.Children)) }
+
+		length, _ := delTestMap.Len()
+		if length != 0 { t.Errorf("length mismatch: actual(%d), expected(0)", length) }
+
+		_, putErr := delTestMap.Put(keys[0], []byte("again"))
+		if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+
+		value, _ := delTestMap.Get(keys[0])
+		if string(value) != "again" { t.Errorf("value after reinsert mismatch: actual(%s), expected(again)", value) }
+	})
+
+	t.Log("Done")
+}
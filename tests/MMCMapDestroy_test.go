@@ -0,0 +1,96 @@
+package mmcmaptests
+
+import "errors"
+import "fmt"
+import "os"
+import "path/filepath"
+import "testing"
+import "time"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var destroyTestPath = filepath.Join(os.TempDir(), "testdestroy")
+var destroyTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initDestroyMapErr error
+	mmcmap.Destroy(destroyTestPath)
+
+	opts := mmcmap.MMCMapOpts{ Filepath: destroyTestPath }
+	destroyTestMap, initDestroyMapErr = mmcmap.Open(opts)
+	if initDestroyMapErr != nil { panic(initDestroyMapErr.Error()) }
+
+	fmt.Println("destroy test mmcmap initialized")
+}
+
+
+func TestMMCMapDestroy(t *testing.T) {
+	checkRemoved := func(t *testing.T, paths ...string) {
+		for _, path := range paths {
+			_, statErr := os.Stat(path)
+			if ! errors.Is(statErr, os.ErrNotExist) { t.Errorf("file was not removed: %s, stat error(%v)", path, statErr) }
+		}
+	}
+
+	t.Run("Test Remove After Close", func(t *testing.T) {
+		_, putErr := destroyTestMap.PutWithTTL([]byte("key"), []byte("value"), time.Hour)
+		if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+
+		closeErr := destroyTestMap.Close()
+		if closeErr != nil { t.Fatalf("error on close: %s", closeErr.Error()) }
+
+		_, statErr := os.Stat(destroyTestPath + ".wheel")
+		if statErr != nil { t.Fatalf("expiry wheel was not saved on close: %s", statErr.Error()) }
+
+		removeErr := destroyTestMap.Remove()
+		if removeErr != nil { t.Fatalf("error on remove after close: %s", removeErr.Error()) }
+		checkRemoved(t, destroyTestPath, destroyTestPath + ".wheel")
+
+		removeErr = destroyTestMap.Remove()
+		if removeErr != nil { t.Errorf("error on repeated remove: %s", removeErr.Error()) }
+	})
+
+	t.Run("Test Remove After Detach", func(t *testing.T) {
+		path := filepath.Join(os.TempDir(), "testdestroydetach")
+		mmcmap.Destroy(path)
+
+		mmcMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: path })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+
+		detachErr := mmcMap.Detach()
+		if detachErr != nil { t.Fatalf("error on detach: %s", detachErr.Error()) }
+
+		removeErr := mmcMap.Remove()
+		if removeErr != nil { t.Fatalf("error on remove after detach: %s", removeErr.Error()) }
+		checkRemoved(t, path)
+	})
+
+	t.Run("Test Destroy Sidecars", func(t *testing.T) {
+		path := filepath.Join(os.TempDir(), "testdestroysidecars")
+		sidecars := []string{ path, path + ".wheel", path + ".wheel.tmp", path + ".compact", path + ".clone", path + ".recover" }
+
+		for _, sidecar := range sidecars {
+			writeErr := os.WriteFile(sidecar, []byte("leftover"), 0600)
+			if writeErr != nil { t.Fatalf("error writing %s: %s", sidecar, writeErr.Error()) }
+		}
+
+		unrelated := path + ".backup"
+		writeErr := os.WriteFile(unrelated, []byte("backup"), 0600)
+		if writeErr != nil { t.Fatalf("error writing %s: %s", unrelated, writeErr.Error()) }
+		defer os.Remove(unrelated)
+
+		destroyErr := mmcmap.Destroy(path)
+		if destroyErr != nil { t.Fatalf("error on destroy: %s", destroyErr.Error()) }
+		checkRemoved(t, sidecars...)
+
+		_, statErr := os.Stat(unrelated)
+		if statErr != nil { t.Errorf("file not belonging to the map was removed: %s", statErr.Error()) }
+
+		destroyErr = mmcmap.Destroy(path)
+		if destroyErr != nil { t.Errorf("error on repeated destroy: %s", destroyErr.Error()) }
+	})
+
+	t.Log("Done")
+}
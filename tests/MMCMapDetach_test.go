@@ -0,0 +1,93 @@
+package mmcmaptests
+
+import "errors"
+import "fmt"
+import "os"
+import "path/filepath"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var dTestPath = filepath.Join(os.TempDir(), "testdetach")
+var detachTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initDMapErr error
+	os.Remove(dTestPath)
+
+	opts := mmcmap.MMCMapOpts{ Filepath: dTestPath }
+	detachTestMap, initDMapErr = mmcmap.Open(opts)
+	if initDMapErr != nil { panic(initDMapErr.Error()) }
+
+	fmt.Println("detach test mmcmap initialized")
+}
+
+
+func TestMMCMapDetach(t *testing.T) {
+	defer detachTestMap.Remove()
+
+	t.Run("Test Detach Refuses Operations", func(t *testing.T) {
+		_, putErr := detachTestMap.Put([]byte("hello"), []byte("world"))
+		if putErr != nil { t.Errorf("error putting key in mmcmap: %s", putErr.Error()) }
+
+		detachErr := detachTestMap.Detach()
+		if detachErr != nil { t.Errorf("error detaching mmcmap: %s", detachErr.Error()) }
+
+		_, getErr := detachTestMap.Get([]byte("hello"))
+		if ! errors.Is(getErr, mmcmap.ErrMapDetached) { t.Errorf("expected detached error on get, got: %v", getErr) }
+
+		_, putErr = detachTestMap.Put([]byte("new"), []byte("wow!"))
+		if ! errors.Is(putErr, mmcmap.ErrMapDetached) { t.Errorf("expected detached error on put, got: %v", putErr) }
+
+		_, delErr := detachTestMap.Delete([]byte("hello"))
+		if ! errors.Is(delErr, mmcmap.ErrMapDetached) { t.Errorf("expected detached error on delete, got: %v", delErr) }
+	})
+
+	t.Run("Test Reattach Restores Operations", func(t *testing.T) {
+		reattachErr := detachTestMap.Reattach()
+		if reattachErr != nil { t.Errorf("error reattaching mmcmap: %s", reattachErr.Error()) }
+
+		val, getErr := detachTestMap.Get([]byte("hello"))
+		if getErr != nil { t.Errorf("error getting key after reattach: %s", getErr.Error()) }
+		if string(val) != "world" { t.Errorf("value after reattach does not match: actual(%s), expected(world)", val) }
+
+		_, putErr := detachTestMap.Put([]byte("new"), []byte("wow!"))
+		if putErr != nil { t.Errorf("error putting key after reattach: %s", putErr.Error()) }
+	})
+
+	t.Run("Test Foreign Process Handle", func(t *testing.T) {
+		detachTestMap.OwnerPID = -1
+
+		_, getErr := detachTestMap.Get([]byte("hello"))
+		if ! errors.Is(getErr, mmcmap.ErrForkedHandle) { t.Errorf("expected forked handle error, got: %v", getErr) }
+
+		reattachErr := detachTestMap.Reattach()
+		if reattachErr != nil { t.Errorf("error reattaching inherited handle: %s", reattachErr.Error()) }
+
+		val, getErr := detachTestMap.Get([]byte("new"))
+		if getErr != nil { t.Errorf("error getting key after reattach: %s", getErr.Error()) }
+		if string(val) != "wow!" { t.Errorf("value after reattach does not match: actual(%s), expected(wow!)", val) }
+	})
+
+	t.Run("Test Detach After Close", func(t *testing.T) {
+		path := filepath.Join(os.TempDir(), "testdetachclosed")
+		os.Remove(path)
+		defer os.Remove(path)
+
+		closedMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: path })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+
+		closeErr := closedMap.Close()
+		if closeErr != nil { t.Fatalf("error on close: %s", closeErr.Error()) }
+
+		detachErr := closedMap.Detach()
+		if ! errors.Is(detachErr, mmcmap.ErrMapClosed) { t.Errorf("detach after close error mismatch: actual(%v), expected(%v)", detachErr, mmcmap.ErrMapClosed) }
+
+		reattachErr := closedMap.Reattach()
+		if ! errors.Is(reattachErr, mmcmap.ErrMapClosed) { t.Errorf("reattach after close error mismatch: actual(%v), expected(%v)", reattachErr, mmcmap.ErrMapClosed) }
+	})
+
+	t.Log("Done")
+}
@@ -0,0 +1,115 @@
+package mmcmaptests
+
+import "bytes"
+import "errors"
+import "fmt"
+import "os"
+import "path/filepath"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var dfTestPath = filepath.Join(os.TempDir(), "testdiff")
+var diffTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initDfMapErr error
+	os.Remove(dfTestPath)
+
+	diffTestMap, initDfMapErr = mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: dfTestPath })
+	if initDfMapErr != nil { panic(initDfMapErr.Error()) }
+
+	fmt.Println("diff test mmcmap initialized")
+}
+
+
+func TestMMCMapDiff(t *testing.T) {
+	defer diffTestMap.Remove()
+
+	pairs := make([]mmcmap.KeyValuePair, 0, 1000)
+	for idx := 0; idx < 1000; idx++ {
+		pairs = append(pairs, mmcmap.KeyValuePair{ Key: []byte(fmt.Sprintf("key%04d", idx)), Value: []byte(fmt.Sprintf("value%d", idx)) })
+	}
+
+	_, putErr := diffTestMap.PutBatch(pairs)
+	if putErr != nil { t.Fatalf("error on put batch: %s", putErr.Error()) }
+
+	baseVersion, _ := readVersion(diffTestMap)
+
+	batch := diffTestMap.NewWriteBatch()
+	batch.Put([]byte("key0001"), []byte("updated"))
+	batch.Put([]byte("key0002"), []byte("value2"))
+	batch.Put([]byte("new"), []byte("added"))
+	batch.Delete([]byte("key0003"))
+
+	_, commitErr := batch.Commit()
+	if commitErr != nil { t.Fatalf("error on commit: %s", commitErr.Error()) }
+
+	for idx := 500; idx < 510; idx++ {
+		_, delErr := diffTestMap.Delete([]byte(fmt.Sprintf("key%04d", idx)))
+		if delErr != nil { t.Fatalf("error on delete: %s", delErr.Error()) }
+	}
+
+	latestVersion, _ := readVersion(diffTestMap)
+
+	t.Run("Test Diff Versions", func(t *testing.T) {
+		diffs, diffErr := diffTestMap.Diff(baseVersion, latestVersion)
+		if diffErr != nil { t.Fatalf("error on diff: %s", diffErr.Error()) }
+
+		expected := map[string]mmcmap.DiffKind{ "key0001": mmcmap.DiffModified, "key0003": mmcmap.DiffRemoved, "new": mmcmap.DiffAdded }
+		for idx := 500; idx < 510; idx++ { expected[fmt.Sprintf("key%04d", idx)] = mmcmap.DiffRemoved }
+
+		if len(diffs) != len(expected) { t.Fatalf("diff count mismatch: actual(%d), expected(%d)", len(diffs), len(expected)) }
+
+		for idx, diff := range diffs {
+			kind, isExpected := expected[string(diff.Key)]
+			if ! isExpected || kind != diff.Kind { t.Errorf("unexpected diff: actual(%s %d)", diff.Key, diff.Kind) }
+			if idx > 0 && bytes.Compare(diffs[idx - 1].Key, diff.Key) >= 0 { t.Errorf("diffs not sorted: %s before %s", diffs[idx - 1].Key, diff.Key) }
+
+			switch diff.Kind {
+				case mmcmap.DiffModified:
+					if ! bytes.Equal(diff.OldValue, []byte("value1")) || ! bytes.Equal(diff.NewValue, []byte("updated")) {
+						t.Errorf("modified values mismatch: actual(%s -> %s), expected(value1 -> updated)", diff.OldValue, diff.NewValue)
+					}
+				case mmcmap.DiffAdded:
+					if diff.OldValue != nil || ! bytes.Equal(diff.NewValue, []byte("added")) { t.Errorf("added values mismatch: actual(%v -> %s)", diff.OldValue, diff.NewValue) }
+				case mmcmap.DiffRemoved:
+					if diff.NewValue != nil || diff.OldValue == nil { t.Errorf("removed values mismatch: actual(%s -> %v)", diff.OldValue, diff.NewValue) }
+			}
+		}
+	})
+
+	t.Run("Test Diff Reversed", func(t *testing.T) {
+		diffs, diffErr := diffTestMap.Diff(latestVersion, baseVersion)
+		if diffErr != nil { t.Fatalf("error on diff: %s", diffErr.Error()) }
+
+		var added, removed int
+		for _, diff := range diffs {
+			if diff.Kind == mmcmap.DiffAdded { added++ }
+			if diff.Kind == mmcmap.DiffRemoved { removed++ }
+		}
+
+		if added != 11 || removed != 1 { t.Errorf("reversed diff mismatch: actual(%d added, %d removed), expected(11 added, 1 removed)", added, removed) }
+	})
+
+	t.Run("Test Diff From Empty", func(t *testing.T) {
+		diffs, diffErr := diffTestMap.Diff(0, baseVersion)
+		if diffErr != nil { t.Fatalf("error on diff: %s", diffErr.Error()) }
+		if len(diffs) != 1000 { t.Errorf("diff from empty mismatch: actual(%d), expected(1000)", len(diffs)) }
+	})
+
+	t.Run("Test Diff Same Version", func(t *testing.T) {
+		diffs, diffErr := diffTestMap.Diff(latestVersion, latestVersion)
+		if diffErr != nil { t.Fatalf("error on diff: %s", diffErr.Error()) }
+		if len(diffs) != 0 { t.Errorf("diff of the same version is not empty: actual(%d)", len(diffs)) }
+	})
+
+	t.Run("Test Diff Unavailable Version", func(t *testing.T) {
+		_, diffErr := diffTestMap.Diff(baseVersion, latestVersion + 1)
+		if ! errors.Is(diffErr, mmcmap.ErrVersionUnavailable) { t.Errorf("expected ErrVersionUnavailable, got: %v", diffErr) }
+	})
+
+	t.Log("Done")
+}
@@ -0,0 +1,87 @@
+package mmcmaptests
+
+import "bytes"
+import "errors"
+import "fmt"
+import "os"
+import "path/filepath"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var dgTestPath = filepath.Join(os.TempDir(), "testdigest")
+var digestTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initDgMapErr error
+	os.Remove(dgTestPath)
+
+	opts := mmcmap.MMCMapOpts{ Filepath: dgTestPath }
+	digestTestMap, initDgMapErr = mmcmap.Open(opts)
+	if initDgMapErr != nil { panic(initDgMapErr.Error()) }
+
+	fmt.Println("digest test mmcmap initialized")
+}
+
+
+func TestMMCMapKeyDigest(t *testing.T) {
+	defer digestTestMap.Remove()
+
+	keys := []string{ "alpha", "bravo", "charlie", "delta", "echo" }
+	for _, key := range keys {
+		_, putErr := digestTestMap.Put([]byte(key), []byte(key))
+		if putErr != nil { t.Errorf("error putting key in mmcmap: %s", putErr.Error()) }
+	}
+
+	var exported *mmcmap.KeyDigest
+
+	t.Run("Test Export And Read Digest", func(t *testing.T) {
+		var buf bytes.Buffer
+		exportErr := digestTestMap.ExportKeyDigest(&buf)
+		if exportErr != nil { t.Errorf("error exporting digest: %s", exportErr.Error()) }
+
+		var readErr error
+		exported, readErr = mmcmap.ReadKeyDigest(&buf)
+		if readErr != nil { t.Errorf("error reading digest: %s", readErr.Error()) }
+
+		independent := &mmcmap.KeyDigest{ Version: exported.Version }
+		for idx := len(keys) - 1; idx >= 0; idx-- { independent.Add([]byte(keys[idx])) }
+
+		divergent := exported.DivergentPrefixes(independent)
+		if len(divergent) != 0 { t.Errorf("digest of identical key sets diverged on prefixes: %v", divergent) }
+	})
+
+	t.Run("Test Divergent Prefixes", func(t *testing.T) {
+		_, putErr := digestTestMap.Put([]byte("zulu"), []byte("zulu"))
+		if putErr != nil { t.Errorf("error putting key in mmcmap: %s", putErr.Error()) }
+
+		current, digestErr := digestTestMap.KeyDigest()
+		if digestErr != nil { t.Errorf("error computing digest: %s", digestErr.Error()) }
+
+		divergent := exported.DivergentPrefixes(current)
+		if ! bytes.Equal(divergent, []byte("z")) { t.Errorf("unexpected divergent prefixes: actual(%v), expected(%v)", divergent, []byte("z")) }
+		if current.Version <= exported.Version { t.Errorf("digest version did not advance: %d <= %d", current.Version, exported.Version) }
+	})
+
+	t.Run("Test Digest At Version", func(t *testing.T) {
+		past, digestErr := digestTestMap.KeyDigestAtVersion(exported.Version)
+		if digestErr != nil { t.Fatalf("error computing digest at version: %s", digestErr.Error()) }
+		if past.Version != exported.Version { t.Errorf("digest version mismatch: actual(%d), expected(%d)", past.Version, exported.Version) }
+
+		divergent := exported.DivergentPrefixes(past)
+		if len(divergent) != 0 { t.Errorf("digest at the exported version diverged on prefixes: %v", divergent) }
+
+		var buf bytes.Buffer
+		exportErr := digestTestMap.ExportKeyDigestAtVersion(&buf, exported.Version)
+		if exportErr != nil { t.Fatalf("error exporting digest at version: %s", exportErr.Error()) }
+		if ! bytes.Equal(buf.Bytes(), exported.Serialize()) { t.Error("exported digest at version does not match the digest exported at that version") }
+
+		current, _ := digestTestMap.KeyDigest()
+		_, digestErr = digestTestMap.KeyDigestAtVersion(current.Version + 1)
+		if ! errors.Is(digestErr, mmcmap.ErrVersionUnavailable) { t.Errorf("future version error mismatch: actual(%v), expected(%v)", digestErr, mmcmap.ErrVersionUnavailable) }
+	})
+
+	t.Log("Done")
+}
@@ -0,0 +1,129 @@
+package mmcmaptests
+
+import "bufio"
+import "bytes"
+import "encoding/json"
+import "fmt"
+import "os"
+import "path/filepath"
+import "strings"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var dumpTestPath = filepath.Join(os.TempDir(), "testdump")
+var dumpTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initDumpMapErr error
+	os.Remove(dumpTestPath)
+
+	dumpTestMap, initDumpMapErr = mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: dumpTestPath })
+	if initDumpMapErr != nil { panic(initDumpMapErr.Error()) }
+
+	fmt.Println("dump test mmcmap initialized")
+}
+
+
+func TestMMCMapDump(t *testing.T) {
+	defer dumpTestMap.Remove()
+
+	for idx := 0; idx < 200; idx++ {
+		key := []byte(fmt.Sprintf("key%d", idx))
+		_, putErr := dumpTestMap.Put(key, []byte(fmt.Sprintf("value%d", idx)))
+		if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+	}
+
+	_, putErr := dumpTestMap.Put([]byte{ 0x00, 0xff, '|' }, bytes.Repeat([]byte("x"), 100))
+	if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+
+	readNodes := func(t *testing.T, opts mmcmap.DumpOpts) []mmcmap.DumpNode {
+		var buf bytes.Buffer
+		dumpErr := dumpTestMap.Dump(&buf, opts)
+		if dumpErr != nil { t.Fatalf("error on dump: %s", dumpErr.Error()) }
+
+		var nodes []mmcmap.DumpNode
+		scanner := bufio.NewScanner(&buf)
+		for scanner.Scan() {
+			var node mmcmap.DumpNode
+			decodeErr := json.Unmarshal(scanner.Bytes(), &node)
+			if decodeErr != nil { t.Fatalf("error decoding node: %s", decodeErr.Error()) }
+			nodes = append(nodes, node)
+		}
+
+		return nodes
+	}
+
+	t.Run("Test Dump JSON", func(t *testing.T) {
+		nodes := readNodes(t, mmcmap.DumpOpts{})
+
+		meta, _ := dumpTestMap.ReadMetaFromMemMap()
+		if len(nodes) == 0 || nodes[0].Offset != meta.RootOffset || nodes[0].Depth != 0 { t.Fatalf("dump does not start at the root: actual(%+v), expected offset(%d)", nodes[0], meta.RootOffset) }
+
+		offsets := make(map[uint64]bool)
+		var leaves int
+		var binaryKey *mmcmap.DumpNode
+		for idx, node := range nodes {
+			offsets[node.Offset] = true
+			if ! node.IsLeaf {
+				if len(node.Bitmap) != 32 || strings.Count(node.Bitmap, "1") != len(node.Children) { t.Errorf("bitmap does not match children: %+v", node) }
+				continue
+			}
+
+			leaves++
+			if node.KeyLength == 3 { binaryKey = &nodes[idx] }
+		}
+
+		if leaves != 201 { t.Errorf("leaf count mismatch: actual(%d), expected(201)", leaves) }
+		for _, node := range nodes {
+			for _, child := range node.Children {
+				if ! offsets[child] { t.Errorf("child %d of %d was not dumped", child, node.Offset) }
+			}
+		}
+
+		if binaryKey == nil { t.Fatalf("binary key was not dumped") }
+		if binaryKey.Key != "0x00ff7c" { t.Errorf("binary key preview mismatch: actual(%s), expected(0x00ff7c)", binaryKey.Key) }
+		if binaryKey.Value != strings.Repeat("x", mmcmap.DefaultDumpPreview) + "..." || binaryKey.ValueLength != 100 { t.Errorf("value preview mismatch: actual(%s %d)", binaryKey.Value, binaryKey.ValueLength) }
+	})
+
+	t.Run("Test Max Depth And Hidden Previews", func(t *testing.T) {
+		nodes := readNodes(t, mmcmap.DumpOpts{ MaxDepth: 1, PreviewBytes: -1 })
+		for _, node := range nodes {
+			if node.Depth > 1 { t.Errorf("node deeper than max depth: %+v", node) }
+			if node.Key != "" || node.Value != "" { t.Errorf("preview not hidden: %+v", node) }
+		}
+	})
+
+	t.Run("Test Dump Prior Version", func(t *testing.T) {
+		nodes := readNodes(t, mmcmap.DumpOpts{ Version: 10 })
+
+		var leaves int
+		for _, node := range nodes {
+			if node.Version > 10 { t.Errorf("node newer than dumped version: %+v", node) }
+			if node.IsLeaf { leaves++ }
+		}
+
+		if leaves != 10 { t.Errorf("leaf count at version 10 mismatch: actual(%d), expected(10)", leaves) }
+	})
+
+	t.Run("Test Dump DOT", func(t *testing.T) {
+		var buf bytes.Buffer
+		dumpErr := dumpTestMap.Dump(&buf, mmcmap.DumpOpts{ Format: mmcmap.DumpDOT })
+		if dumpErr != nil { t.Fatalf("error on dump: %s", dumpErr.Error()) }
+
+		output := buf.String()
+		if ! strings.HasPrefix(output, "digraph mmcmap {") || ! strings.HasSuffix(output, "}\n") { t.Errorf("not a DOT digraph: %s", output) }
+		if strings.Count(output, "{leaf ") != 201 { t.Errorf("leaf record count mismatch: actual(%d), expected(201)", strings.Count(output, "{leaf ")) }
+		if ! strings.Contains(output, `key 0x00ff7c (3)`) { t.Errorf("binary key record missing") }
+		if ! strings.Contains(output, " -> ") { t.Errorf("no edges written") }
+	})
+
+	t.Run("Test Unknown Format", func(t *testing.T) {
+		dumpErr := dumpTestMap.Dump(&bytes.Buffer{}, mmcmap.DumpOpts{ Format: mmcmap.DumpFormat(9) })
+		if dumpErr != mmcmap.ErrUnknownDumpFormat { t.Errorf("error mismatch: actual(%v), expected(%v)", dumpErr, mmcmap.ErrUnknownDumpFormat) }
+	})
+
+	t.Log("Done")
+}
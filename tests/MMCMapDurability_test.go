@@ -0,0 +1,183 @@
+package mmcmaptests
+
+import "errors"
+import "fmt"
+import "os"
+import "path/filepath"
+import "sync"
+import "sync/atomic"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var durabilityTestPath = filepath.Join(os.TempDir(), "testdurability")
+var durabilityTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initDurabilityMapErr error
+	os.Remove(durabilityTestPath)
+
+	opts := mmcmap.MMCMapOpts{ Filepath: durabilityTestPath }
+	durabilityTestMap, initDurabilityMapErr = mmcmap.Open(opts)
+	if initDurabilityMapErr != nil { panic(initDurabilityMapErr.Error()) }
+
+	fmt.Println("durability test mmcmap initialized")
+}
+
+
+func TestMMCMapDurability(t *testing.T) {
+	defer durabilityTestMap.Remove()
+
+	t.Run("Test Wait", func(t *testing.T) {
+		version, putErr := durabilityTestMap.PutVersioned([]byte("key"), []byte("value"))
+		if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+
+		waitErr := durabilityTestMap.Wait(version)
+		if waitErr != nil { t.Fatalf("error on wait: %s", waitErr.Error()) }
+		if durabilityTestMap.FlushedVersion < version { t.Errorf("flushed version behind waited version: actual(%d), expected(>= %d)", durabilityTestMap.FlushedVersion, version) }
+	})
+
+	t.Run("Test Async Writes And Flush", func(t *testing.T) {
+		futures := make([]*mmcmap.WriteFuture, 0, 100)
+		for idx := 0; idx < 100; idx++ {
+			key := []byte(fmt.Sprintf("async%d", idx))
+			futures = append(futures, durabilityTestMap.PutAsync(key, key))
+		}
+
+		futures = append(futures, durabilityTestMap.DeleteAsync([]byte("async0")))
+
+		var last uint64
+		for _, future := range futures {
+			version, writeErr := future.WaitVersion()
+			if writeErr != nil { t.Fatalf("error on async write: %s", writeErr.Error()) }
+			if version > last { last = version }
+		}
+
+		flushErr := durabilityTestMap.Flush()
+		if flushErr != nil { t.Fatalf("error on flush: %s", flushErr.Error()) }
+		if durabilityTestMap.FlushedVersion < last { t.Errorf("flush did not cover the async writes: actual(%d), expected(>= %d)", durabilityTestMap.FlushedVersion, last) }
+	})
+
+	t.Run("Test Concurrent Waits", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for writer := 0; writer < 8; writer++ {
+			wg.Add(1)
+			go func(writer int) {
+				defer wg.Done()
+
+				for idx := 0; idx < 20; idx++ {
+					key := []byte(fmt.Sprintf("writer%d-%d", writer, idx))
+					version, putErr := durabilityTestMap.PutVersioned(key, key)
+					if putErr != nil {
+						t.Errorf("error on put: %s", putErr.Error())
+						return
+					}
+
+					waitErr := durabilityTestMap.Wait(version)
+					if waitErr != nil { t.Errorf("error on wait: %s", waitErr.Error()) }
+				}
+			}(writer)
+		}
+
+		wg.Wait()
+	})
+
+	t.Run("Test Flush Races Flush Routine", func(t *testing.T) {
+		path := filepath.Join(os.TempDir(), "testdurabilityrace")
+		os.Remove(path)
+
+		mmcMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: path, MaxUnflushedBytes: 1 << 16, StallPolicy: mmcmap.StallError })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+		defer mmcMap.Remove()
+
+		stop := make(chan struct{})
+
+		var wg, flushers sync.WaitGroup
+		for writer := 0; writer < 4; writer++ {
+			wg.Add(1)
+			go func(writer int) {
+				defer wg.Done()
+
+				for idx := 0; idx < 5000; idx++ {
+					key := []byte(fmt.Sprintf("writer%d-%d", writer, idx))
+					_, putErr := mmcMap.Put(key, key)
+					if putErr != nil && putErr != mmcmap.ErrWriteStall { t.Errorf("error on put: %s", putErr.Error()) }
+				}
+			}(writer)
+		}
+
+		for flusher := 0; flusher < 4; flusher++ {
+			flushers.Add(1)
+			go func() {
+				defer flushers.Done()
+
+				for {
+					select {
+						case <-stop:
+							return
+						default:
+							flushErr := mmcMap.Flush()
+							if flushErr != nil { t.Errorf("error on flush: %s", flushErr.Error()) }
+					}
+				}
+			}()
+		}
+
+		checkUnflushed := func() {
+			stats, statsErr := mmcMap.Stats()
+			if statsErr != nil { t.Fatalf("error on stats: %s", statsErr.Error()) }
+
+			unflushed := atomic.LoadUint64(&mmcMap.UnflushedBytes)
+			if unflushed > stats.FileSize { t.Fatalf("unflushed bytes exceed the bytes written: actual(%d), expected(<= %d)", unflushed, stats.FileSize) }
+		}
+
+		done := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(done)
+		}()
+
+		for isDone := false; ! isDone; {
+			select {
+				case <-done:
+					isDone = true
+				default:
+					checkUnflushed()
+			}
+		}
+
+		close(stop)
+		flushers.Wait()
+
+		flushErr := mmcMap.Flush()
+		if flushErr != nil { t.Fatalf("error on flush: %s", flushErr.Error()) }
+		checkUnflushed()
+	})
+
+	t.Run("Test Wait For Uncommitted Version", func(t *testing.T) {
+		waitErr := durabilityTestMap.Wait(1 << 40)
+		if ! errors.Is(waitErr, mmcmap.ErrVersionUnavailable) { t.Errorf("wait error mismatch: actual(%v), expected(%v)", waitErr, mmcmap.ErrVersionUnavailable) }
+	})
+
+	t.Run("Test Wait Closed", func(t *testing.T) {
+		path := filepath.Join(os.TempDir(), "testdurabilityclosed")
+		os.Remove(path)
+
+		mmcMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: path })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+		defer mmcMap.Remove()
+
+		version, putErr := mmcMap.PutVersioned([]byte("key"), []byte("value"))
+		if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+
+		closeErr := mmcMap.Close()
+		if closeErr != nil { t.Fatalf("error on close: %s", closeErr.Error()) }
+
+		waitErr := mmcMap.Wait(version)
+		if ! errors.Is(waitErr, mmcmap.ErrMapClosed) { t.Errorf("wait error mismatch: actual(%v), expected(%v)", waitErr, mmcmap.ErrMapClosed) }
+	})
+
+	t.Log("Done")
+}
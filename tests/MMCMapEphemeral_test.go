@@ -0,0 +1,156 @@
+package mmcmaptests
+
+import "bytes"
+import "errors"
+import "fmt"
+import "os"
+import "path/filepath"
+import "strings"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var ephemeralTestDir = filepath.Join(os.TempDir(), "testephemeral")
+var ephemeralTestMap *mmcmap.MMCMap
+var ephemeralTestSize = uint64(1 << 20)
+
+
+func init() {
+	var initEphemeralMapErr error
+	os.RemoveAll(ephemeralTestDir)
+
+	mkdirErr := os.MkdirAll(ephemeralTestDir, 0700)
+	if mkdirErr != nil { panic(mkdirErr.Error()) }
+
+	opts := mmcmap.MMCMapOpts{ Filepath: "cache", Ephemeral: true, EphemeralDir: ephemeralTestDir, MaxFileSize: ephemeralTestSize }
+	ephemeralTestMap, initEphemeralMapErr = mmcmap.Open(opts)
+	if initEphemeralMapErr != nil { panic(initEphemeralMapErr.Error()) }
+
+	fmt.Println("ephemeral test mmcmap initialized")
+}
+
+
+func TestMMCMapEphemeral(t *testing.T) {
+	defer os.RemoveAll(ephemeralTestDir)
+	defer ephemeralTestMap.Remove()
+
+	path := ephemeralTestMap.Opts.Filepath
+	ephemeralValue := func(idx int) []byte { return bytes.Repeat([]byte{ byte('a' + idx % 26) }, 1024) }
+
+	t.Run("Test Ephemeral File", func(t *testing.T) {
+		if filepath.Dir(path) != ephemeralTestDir { t.Errorf("ephemeral file created outside of its directory: actual(%s), expected(%s)", filepath.Dir(path), ephemeralTestDir) }
+		if ! strings.HasPrefix(filepath.Base(path), "cache-") { t.Errorf("ephemeral file is not prefixed by the base name of Filepath: %s", path) }
+
+		_, statErr := os.Stat(path)
+		if statErr != nil { t.Fatalf("ephemeral file was not created: %s", statErr.Error()) }
+
+		fileSize, sizeErr := ephemeralTestMap.FileSize()
+		if sizeErr != nil { t.Fatalf("error getting file size: %s", sizeErr.Error()) }
+		if uint64(fileSize) > ephemeralTestSize { t.Errorf("file exceeds max file size: actual(%d), expected(%d)", fileSize, ephemeralTestSize) }
+	})
+
+	t.Run("Test Map Full", func(t *testing.T) {
+		var putErr error
+		var puts int
+
+		for puts = 0; puts < 10000; puts++ {
+			_, putErr = ephemeralTestMap.Put([]byte(fmt.Sprintf("ephemeral%05d", puts)), ephemeralValue(puts))
+			if putErr != nil { break }
+		}
+
+		if ! errors.Is(putErr, mmcmap.ErrMapFull) { t.Fatalf("expected ErrMapFull once the map is full, got: %v", putErr) }
+		if puts == 0 { t.Fatalf("map was full before any key was put") }
+
+		fileSize, sizeErr := ephemeralTestMap.FileSize()
+		if sizeErr != nil { t.Fatalf("error getting file size: %s", sizeErr.Error()) }
+		if uint64(fileSize) != ephemeralTestSize { t.Errorf("file size mismatch once full: actual(%d), expected(%d)", fileSize, ephemeralTestSize) }
+
+		for idx := range make([]int, puts) {
+			value, getErr := ephemeralTestMap.Get([]byte(fmt.Sprintf("ephemeral%05d", idx)))
+			if getErr != nil { t.Fatalf("error getting key from full map: %s", getErr.Error()) }
+			if ! bytes.Equal(value, ephemeralValue(idx)) { t.Fatalf("value mismatch for key %d: actual(%d bytes), expected(%d bytes)", idx, len(value), len(ephemeralValue(idx))) }
+		}
+
+		_, putErr = ephemeralTestMap.Put([]byte("another"), ephemeralValue(0))
+		if ! errors.Is(putErr, mmcmap.ErrMapFull) { t.Errorf("expected ErrMapFull on repeated put, got: %v", putErr) }
+	})
+
+	t.Run("Test Clear Full Map", func(t *testing.T) {
+		clearErr := ephemeralTestMap.Clear(mmcmap.ClearOpts{ Truncate: true })
+		if clearErr != nil { t.Fatalf("error on clear: %s", clearErr.Error()) }
+
+		fileSize, sizeErr := ephemeralTestMap.FileSize()
+		if sizeErr != nil { t.Fatalf("error getting file size: %s", sizeErr.Error()) }
+		if uint64(fileSize) > ephemeralTestSize { t.Errorf("file exceeds max file size after clear: actual(%d), expected(%d)", fileSize, ephemeralTestSize) }
+
+		_, putErr := ephemeralTestMap.Put([]byte("after"), []byte("clear"))
+		if putErr != nil { t.Fatalf("error putting key after clear: %s", putErr.Error()) }
+	})
+
+	t.Run("Test Remove On Close", func(t *testing.T) {
+		closeErr := ephemeralTestMap.Close()
+		if closeErr != nil { t.Fatalf("error on close: %s", closeErr.Error()) }
+
+		matches, globErr := filepath.Glob(path + "*")
+		if globErr != nil { t.Fatalf("error listing ephemeral files: %s", globErr.Error()) }
+		if len(matches) != 0 { t.Errorf("files remain after close: %v", matches) }
+
+		removeErr := ephemeralTestMap.Remove()
+		if removeErr != nil { t.Errorf("error on remove after close: %s", removeErr.Error()) }
+	})
+
+	t.Run("Test Default Ephemeral Dir", func(t *testing.T) {
+		mmcMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Ephemeral: true })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+
+		defaultPath := mmcMap.Opts.Filepath
+		if ! strings.HasPrefix(filepath.Base(defaultPath), "mmcmap-") { t.Errorf("ephemeral file without Filepath is not prefixed by mmcmap: %s", defaultPath) }
+
+		_, putErr := mmcMap.Put([]byte("key"), []byte("value"))
+		if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+
+		closeErr := mmcMap.Close()
+		if closeErr != nil { t.Fatalf("error on close: %s", closeErr.Error()) }
+
+		_, statErr := os.Stat(defaultPath)
+		if ! errors.Is(statErr, os.ErrNotExist) { t.Errorf("ephemeral file was not removed on close: stat error(%v)", statErr) }
+	})
+
+	t.Run("Test Max File Size", func(t *testing.T) {
+		cappedPath := filepath.Join(os.TempDir(), "testephemeralcapped")
+		mmcmap.Destroy(cappedPath)
+
+		cappedMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: cappedPath, MaxFileSize: ephemeralTestSize })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+		defer cappedMap.Remove()
+
+		var putErr error
+		for idx := range make([]int, 10000) {
+			_, putErr = cappedMap.Put([]byte(fmt.Sprintf("capped%05d", idx)), ephemeralValue(idx))
+			if putErr != nil { break }
+		}
+
+		if ! errors.Is(putErr, mmcmap.ErrMapFull) { t.Errorf("expected ErrMapFull from a durable map with MaxFileSize, got: %v", putErr) }
+
+		closeErr := cappedMap.Close()
+		if closeErr != nil { t.Fatalf("error on close: %s", closeErr.Error()) }
+
+		_, statErr := os.Stat(cappedPath)
+		if statErr != nil { t.Errorf("file of a map that is not ephemeral was removed on close: %s", statErr.Error()) }
+	})
+
+	t.Run("Test Invalid Max File Size", func(t *testing.T) {
+		_, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Ephemeral: true, EphemeralDir: ephemeralTestDir, MaxFileSize: 100 })
+		if ! errors.Is(openErr, mmcmap.ErrInvalidSizeLimit) { t.Errorf("expected ErrInvalidSizeLimit, got: %v", openErr) }
+
+		_, openErr = mmcmap.Open(mmcmap.MMCMapOpts{ Ephemeral: true, EphemeralDir: ephemeralTestDir, SegmentSize: mmcmap.MinSegmentSize, MaxFileSize: mmcmap.MinSegmentSize / 2 })
+		if ! errors.Is(openErr, mmcmap.ErrInvalidSizeLimit) { t.Errorf("expected ErrInvalidSizeLimit for a cap smaller than a segment, got: %v", openErr) }
+
+		matches, globErr := filepath.Glob(filepath.Join(ephemeralTestDir, "*"))
+		if globErr != nil { t.Fatalf("error listing ephemeral files: %s", globErr.Error()) }
+		if len(matches) != 0 { t.Errorf("ephemeral files remain after failed opens: %v", matches) }
+	})
+
+	t.Log("Done")
+}
@@ -0,0 +1,197 @@
+package mmcmaptests
+
+import "bytes"
+import "errors"
+import "fmt"
+import "os"
+import "path/filepath"
+import "sync"
+import "testing"
+import "time"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var errTestPath = filepath.Join(os.TempDir(), "testerrors")
+var errorsTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initErrMapErr error
+	os.Remove(errTestPath)
+
+	opts := mmcmap.MMCMapOpts{ Filepath: errTestPath, StrictGet: true }
+	errorsTestMap, initErrMapErr = mmcmap.Open(opts)
+	if initErrMapErr != nil { panic(initErrMapErr.Error()) }
+
+	fmt.Println("errors test mmcmap initialized")
+}
+
+
+func TestMMCMapErrors(t *testing.T) {
+	defer os.Remove(errTestPath)
+
+	t.Run("Test Key Too Large", func(t *testing.T) {
+		_, putErr := errorsTestMap.Put(make([]byte, mmcmap.MaxKeyLength + 1), []byte("value"))
+		if ! errors.Is(putErr, mmcmap.ErrKeyTooLarge) { t.Errorf("expected ErrKeyTooLarge, got: %v", putErr) }
+
+		_, putErr = errorsTestMap.Put(make([]byte, mmcmap.MaxKeyLength), []byte("value"))
+		if putErr != nil { t.Errorf("error on put of max length key: %s", putErr.Error()) }
+	})
+
+	t.Run("Test Configured Size Limits", func(t *testing.T) {
+		limitTestPath := filepath.Join(os.TempDir(), "testerrorslimits")
+		os.Remove(limitTestPath)
+
+		limitTestMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: limitTestPath, MaxKeySize: 16, MaxValueSize: 64 })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+		defer limitTestMap.Remove()
+
+		_, putErr := limitTestMap.Put(make([]byte, 17), []byte("value"))
+		if ! errors.Is(putErr, mmcmap.ErrKeyTooLarge) { t.Errorf("expected ErrKeyTooLarge, got: %v", putErr) }
+
+		_, putErr = limitTestMap.Put([]byte("key"), make([]byte, 65))
+		if ! errors.Is(putErr, mmcmap.ErrValueTooLarge) { t.Errorf("expected ErrValueTooLarge, got: %v", putErr) }
+
+		batch := limitTestMap.NewWriteBatch()
+		batch.Put([]byte("small"), []byte("value"))
+		batch.Put([]byte("large"), make([]byte, 65))
+
+		_, commitErr := batch.Commit()
+		if ! errors.Is(commitErr, mmcmap.ErrValueTooLarge) { t.Errorf("expected ErrValueTooLarge on batch, got: %v", commitErr) }
+
+		value, _ := limitTestMap.Get([]byte("small"))
+		if value != nil { t.Error("batch with an oversized value was partially committed") }
+
+		_, putErr = limitTestMap.PutReader([]byte("stream"), bytes.NewReader(make([]byte, 65)), 65)
+		if ! errors.Is(putErr, mmcmap.ErrValueTooLarge) { t.Errorf("expected ErrValueTooLarge on put reader, got: %v", putErr) }
+
+		_, putErr = limitTestMap.Put(make([]byte, 16), make([]byte, 64))
+		if putErr != nil { t.Errorf("error on put at the limits: %s", putErr.Error()) }
+
+		_, openErr = mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: limitTestPath + "invalid", MaxKeySize: mmcmap.MaxKeyLength + 1 })
+		if ! errors.Is(openErr, mmcmap.ErrInvalidSizeLimit) { t.Errorf("expected ErrInvalidSizeLimit, got: %v", openErr) }
+	})
+
+	t.Run("Test Corrupt Node", func(t *testing.T) {
+		_, readErr := errorsTestMap.ReadNodeFromMemMap(1 << 40)
+		if ! errors.Is(readErr, mmcmap.ErrCorruptNode) { t.Errorf("expected ErrCorruptNode, got: %v", readErr) }
+	})
+
+	t.Run("Test Node Out Of Range", func(t *testing.T) {
+		_, readErr := errorsTestMap.ReadNodeFromMemMap(1 << 40)
+		if ! errors.Is(readErr, mmcmap.ErrOutOfRange) { t.Errorf("expected ErrOutOfRange, got: %v", readErr) }
+
+		var rangeErr *mmcmap.OutOfRangeError
+		if ! errors.As(readErr, &rangeErr) { t.Fatalf("expected OutOfRangeError, got: %T", readErr) }
+		if rangeErr.Offset != 1 << 40 { t.Errorf("offset mismatch: actual(%d), expected(%d)", rangeErr.Offset, uint64(1 << 40)) }
+		if rangeErr.Offset + rangeErr.Length <= rangeErr.Size { t.Errorf("access reported in range: offset(%d), length(%d), size(%d)", rangeErr.Offset, rangeErr.Length, rangeErr.Size) }
+
+		_, writeErr := errorsTestMap.WriteNodeToMemMap(&mmcmap.MMCMapNode{ StartOffset: 1 << 40, IsLeaf: true, Key: []byte("key"), KeyLength: 3, Value: []byte("value") })
+		if ! errors.Is(writeErr, mmcmap.ErrOutOfRange) { t.Errorf("expected ErrOutOfRange on write, got: %v", writeErr) }
+	})
+
+	t.Run("Test Key Not Found", func(t *testing.T) {
+		_, getErr := errorsTestMap.Get([]byte("missing"))
+		if ! errors.Is(getErr, mmcmap.ErrKeyNotFound) { t.Errorf("expected ErrKeyNotFound, got: %v", getErr) }
+
+		_, putErr := errorsTestMap.Put([]byte("present"), []byte("value"))
+		if putErr != nil { t.Errorf("error on put: %s", putErr.Error()) }
+
+		val, getErr := errorsTestMap.Get([]byte("present"))
+		if getErr != nil { t.Errorf("error on get: %s", getErr.Error()) }
+		if string(val) != "value" { t.Errorf("value mismatch: actual(%s), expected(value)", val) }
+	})
+
+	t.Run("Test Delete Reports Removal", func(t *testing.T) {
+		prevVersion, _ := readVersion(errorsTestMap)
+
+		removed, delErr := errorsTestMap.Delete([]byte("missing"))
+		if delErr != nil { t.Errorf("error on delete: %s", delErr.Error()) }
+		if removed { t.Error("delete of missing key reported a removal") }
+
+		version, _ := readVersion(errorsTestMap)
+		if version != prevVersion { t.Errorf("delete of missing key committed a version: actual(%d), expected(%d)", version, prevVersion) }
+
+		removed, delErr = errorsTestMap.Delete([]byte("present"))
+		if delErr != nil { t.Errorf("error on delete: %s", delErr.Error()) }
+		if ! removed { t.Error("delete of present key did not report a removal") }
+
+		_, getErr := errorsTestMap.Get([]byte("present"))
+		if ! errors.Is(getErr, mmcmap.ErrKeyNotFound) { t.Errorf("expected ErrKeyNotFound after delete, got: %v", getErr) }
+	})
+
+	t.Run("Test Map Closed", func(t *testing.T) {
+		closeErr := errorsTestMap.Close()
+		if closeErr != nil { t.Fatalf("error on close: %s", closeErr.Error()) }
+
+		_, putErr := errorsTestMap.Put([]byte("key"), []byte("value"))
+		if ! errors.Is(putErr, mmcmap.ErrMapClosed) { t.Errorf("expected ErrMapClosed on put, got: %v", putErr) }
+
+		_, getErr := errorsTestMap.Get([]byte("key"))
+		if ! errors.Is(getErr, mmcmap.ErrMapClosed) { t.Errorf("expected ErrMapClosed on get, got: %v", getErr) }
+
+		_, readMetaErr := errorsTestMap.ReadMetaFromMemMap()
+		if ! errors.Is(readMetaErr, mmcmap.ErrMapClosed) { t.Errorf("expected ErrMapClosed reading metadata, got: %v", readMetaErr) }
+	})
+
+	t.Run("Test Close During Operations", func(t *testing.T) {
+		closeTestPath := filepath.Join(os.TempDir(), "testerrorsclose")
+		os.Remove(closeTestPath)
+		defer os.Remove(closeTestPath)
+
+		closeTestMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: closeTestPath })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+
+		for idx := 0; idx < 100; idx++ {
+			key := []byte(fmt.Sprintf("key%d", idx))
+			_, putErr := closeTestMap.Put(key, key)
+			if putErr != nil { t.Errorf("error on put: %s", putErr.Error()) }
+		}
+
+		var wg sync.WaitGroup
+		opErrs := make(chan error, 8)
+
+		for worker := 0; worker < 8; worker++ {
+			wg.Add(1)
+			go func(worker int) {
+				defer wg.Done()
+
+				for idx := 0; ; idx++ {
+					key := []byte(fmt.Sprintf("key%d", idx % 100))
+
+					var opErr error
+					switch worker % 4 {
+						case 0:
+							_, opErr = closeTestMap.Get(key)
+						case 1:
+							_, opErr = closeTestMap.Put(key, key)
+						case 2:
+							_, opErr = closeTestMap.Delete(key)
+						default:
+							_, opErr = closeTestMap.Range(nil, nil)
+					}
+
+					if opErr != nil {
+						opErrs <- opErr
+						return
+					}
+				}
+			}(worker)
+		}
+
+		time.Sleep(50 * time.Millisecond)
+
+		closeErr := closeTestMap.Close()
+		if closeErr != nil { t.Errorf("error on close: %s", closeErr.Error()) }
+
+		wg.Wait()
+		close(opErrs)
+
+		for opErr := range opErrs {
+			if ! errors.Is(opErr, mmcmap.ErrMapClosed) { t.Errorf("expected ErrMapClosed after close, got: %v", opErr) }
+		}
+	})
+
+	t.Log("Done")
+}
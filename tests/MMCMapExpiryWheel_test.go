@@ -0,0 +1,77 @@
+package mmcmaptests
+
+import "fmt"
+import "os"
+import "path/filepath"
+import "sort"
+import "testing"
+import "time"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var ewTestPath = filepath.Join(os.TempDir(), "testexpirywheel")
+
+
+func TestMMCMapExpiryWheel(t *testing.T) {
+	defer os.Remove(ewTestPath)
+
+	start := time.Unix(1700000000, 0)
+	wheel := mmcmap.NewExpiryWheel(time.Second, start)
+
+	delays := map[string]time.Duration{
+		"soon": 5 * time.Second,
+		"minute": 90 * time.Second,
+		"hour": 2 * time.Hour,
+		"week": 7 * 24 * time.Hour,
+		"year": 365 * 24 * time.Hour,
+		"cancelled": 10 * time.Second,
+		"moved": 20 * time.Second,
+	}
+
+	for key, delay := range delays { wheel.Schedule([]byte(key), start.Add(delay)) }
+
+	wheel.Cancel([]byte("cancelled"))
+	wheel.Schedule([]byte("moved"), start.Add(3 * time.Hour))
+
+	expiredAt := func(now time.Time) []string {
+		var keys []string
+		for _, key := range wheel.Advance(now) { keys = append(keys, string(key)) }
+		sort.Strings(keys)
+		return keys
+	}
+
+	t.Run("Test Advance Expires Due Keys", func(t *testing.T) {
+		expired := expiredAt(start.Add(4 * time.Second))
+		if len(expired) != 0 { t.Errorf("keys expired early: %v", expired) }
+
+		expired = expiredAt(start.Add(5 * time.Second))
+		if fmt.Sprint(expired) != "[soon]" { t.Errorf("unexpected expired keys: actual(%v), expected([soon])", expired) }
+
+		expired = expiredAt(start.Add(2 * time.Hour))
+		if fmt.Sprint(expired) != "[hour minute]" { t.Errorf("unexpected expired keys: actual(%v), expected([hour minute])", expired) }
+		if wheel.Len() != 3 { t.Errorf("unexpected number of scheduled keys: actual(%d), expected(3)", wheel.Len()) }
+	})
+
+	t.Run("Test Save And Load", func(t *testing.T) {
+		saveErr := wheel.Save(ewTestPath)
+		if saveErr != nil { t.Fatalf("error saving wheel: %s", saveErr.Error()) }
+
+		var loadErr error
+		wheel, loadErr = mmcmap.LoadExpiryWheel(ewTestPath)
+		if loadErr != nil { t.Fatalf("error loading wheel: %s", loadErr.Error()) }
+		if wheel.Len() != 3 { t.Errorf("unexpected number of scheduled keys after load: actual(%d), expected(3)", wheel.Len()) }
+
+		expired := expiredAt(start.Add(3 * time.Hour))
+		if fmt.Sprint(expired) != "[moved]" { t.Errorf("unexpected expired keys: actual(%v), expected([moved])", expired) }
+
+		expired = expiredAt(start.Add(8 * 24 * time.Hour))
+		if fmt.Sprint(expired) != "[week]" { t.Errorf("unexpected expired keys: actual(%v), expected([week])", expired) }
+
+		expired = expiredAt(start.Add(400 * 24 * time.Hour))
+		if fmt.Sprint(expired) != "[year]" { t.Errorf("unexpected expired keys: actual(%v), expected([year])", expired) }
+		if wheel.Len() != 0 { t.Errorf("keys remain scheduled: %d", wheel.Len()) }
+	})
+
+	t.Log("Done")
+}
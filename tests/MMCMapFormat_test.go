@@ -0,0 +1,163 @@
+package mmcmaptests
+
+import "bytes"
+import "encoding/binary"
+import "fmt"
+import "os"
+import "path/filepath"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var formatTestPath = filepath.Join(os.TempDir(), "testformat")
+
+
+func TestMMCMapFormat(t *testing.T) {
+	defer os.Remove(formatTestPath)
+
+	t.Run("Test Meta Layout", func(t *testing.T) {
+		meta := &mmcmap.MMCMapMetaData{ Version: 1, RootOffset: 64, EndMmapOffset: 0x0102030405060708 }
+		expected := []byte{
+			1, 0, 0, 0, 0, 0, 0, 0,
+			64, 0, 0, 0, 0, 0, 0, 0,
+			8, 7, 6, 5, 4, 3, 2, 1,
+		}
+
+		sMeta := meta.SerializeMetaData()
+		if ! bytes.Equal(sMeta, expected) { t.Errorf("serialized meta mismatch: actual(%v), expected(%v)", sMeta, expected) }
+
+		deserialized, decErr := mmcmap.DeserializeMetaData(sMeta)
+		if decErr != nil { t.Fatalf("error deserializing meta: %s", decErr.Error()) }
+		if *deserialized != *meta { t.Errorf("meta round trip mismatch: actual(%+v), expected(%+v)", deserialized, meta) }
+	})
+
+	t.Run("Test Header Layout", func(t *testing.T) {
+		header := &mmcmap.MMCMapHeader{ FormatVersion: 6, AllocatorID: mmcmap.AllocFreeList, NodeEncoding: mmcmap.NodeEncodingCompact }
+
+		expected := make([]byte, mmcmap.InitRootOffset - mmcmap.HeaderIdx)
+		copy(expected, []byte{ 'M', 'M', 'C', 'H', 6, 0, 1, 1 })
+
+		sHeader := header.SerializeHeader()
+		if ! bytes.Equal(sHeader, expected) { t.Errorf("serialized header mismatch: actual(%v), expected(%v)", sHeader, expected) }
+	})
+
+	t.Run("Test Node Layout", func(t *testing.T) {
+		leaf := &mmcmap.MMCMapNode{ Version: 2, StartOffset: 64, IsLeaf: true, KeyLength: 1, Key: []byte("k"), Value: []byte("v") }
+		expected := []byte{
+			2, 0, 0, 0, 0, 0, 0, 0,
+			64, 0, 0, 0, 0, 0, 0, 0,
+			96, 0, 0, 0, 0, 0, 0, 0,
+			0, 0, 0, 0,
+			1,
+			1, 0,
+			'k', 'v',
+		}
+
+		sLeaf, serializeErr := leaf.SerializeNode(leaf.StartOffset)
+		if serializeErr != nil { t.Fatalf("error serializing leaf: %s", serializeErr.Error()) }
+		if ! bytes.Equal(sLeaf, expected) { t.Errorf("serialized leaf mismatch: actual(%v), expected(%v)", sLeaf, expected) }
+
+		internal := &mmcmap.MMCMapNode{
+			Version: 3,
+			StartOffset: 128,
+			Bitmap: 0x80000001,
+			Children: []*mmcmap.MMCMapNode{ { StartOffset: 0x0201 }, { StartOffset: 64 } },
+		}
+
+		expected = []byte{
+			3, 0, 0, 0, 0, 0, 0, 0,
+			128, 0, 0, 0, 0, 0, 0, 0,
+			174, 0, 0, 0, 0, 0, 0, 0,
+			1, 0, 0, 0x80,
+			0,
+			0, 0,
+			1, 2, 0, 0, 0, 0, 0, 0,
+			64, 0, 0, 0, 0, 0, 0, 0,
+		}
+
+		sInternal, serializeErr := internal.SerializeNode(internal.StartOffset)
+		if serializeErr != nil { t.Fatalf("error serializing internal node: %s", serializeErr.Error()) }
+		if ! bytes.Equal(sInternal, expected) { t.Errorf("serialized internal node mismatch: actual(%v), expected(%v)", sInternal, expected) }
+	})
+
+	t.Run("Test Node Round Trip", func(t *testing.T) {
+		os.Remove(formatTestPath)
+
+		mmcMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: formatTestPath })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+		defer mmcMap.Close()
+
+		leaf := &mmcmap.MMCMapNode{ Version: 7, StartOffset: 1000, IsLeaf: true, KeyLength: 3, Key: []byte("key"), Value: []byte("value"), ExpiresAt: 1 << 40 }
+		sLeaf, _ := leaf.SerializeNode(leaf.StartOffset)
+
+		deserialized, decErr := mmcMap.DeserializeNode(sLeaf)
+		if decErr != nil { t.Fatalf("error deserializing leaf: %s", decErr.Error()) }
+
+		switch {
+			case deserialized.Version != leaf.Version || deserialized.StartOffset != leaf.StartOffset || deserialized.EndOffset != 1000 + uint64(len(sLeaf)) - 1:
+				t.Errorf("leaf header round trip mismatch: actual(%+v)", deserialized)
+			case string(deserialized.Key) != "key" || string(deserialized.Value) != "value" || deserialized.ExpiresAt != leaf.ExpiresAt:
+				t.Errorf("leaf contents round trip mismatch: actual(%+v)", deserialized)
+		}
+
+		internal := &mmcmap.MMCMapNode{ Version: 7, StartOffset: 2000, Bitmap: 0x11, Children: []*mmcmap.MMCMapNode{ { StartOffset: 1000 }, { StartOffset: 1 << 33 } } }
+		sInternal, _ := internal.SerializeNode(internal.StartOffset)
+
+		deserialized, decErr = mmcMap.DeserializeNode(sInternal)
+		if decErr != nil { t.Fatalf("error deserializing internal node: %s", decErr.Error()) }
+		if deserialized.Bitmap != 0x11 || len(deserialized.Children) != 2 || deserialized.Children[1].StartOffset != 1 << 33 {
+			t.Errorf("internal node round trip mismatch: actual(%+v)", deserialized)
+		}
+	})
+
+	t.Run("Test File Is Little Endian", func(t *testing.T) {
+		os.Remove(formatTestPath)
+
+		mmcMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: formatTestPath })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+
+		for idx := 0; idx < 300; idx++ {
+			key := []byte(fmt.Sprintf("key%d", idx))
+			_, putErr := mmcMap.Put(key, key)
+			if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+		}
+
+		meta, _ := mmcMap.ReadMetaFromMemMap()
+		closeErr := mmcMap.Close()
+		if closeErr != nil { t.Fatalf("error on close: %s", closeErr.Error()) }
+
+		raw, readErr := os.ReadFile(formatTestPath)
+		if readErr != nil { t.Fatalf("error reading file: %s", readErr.Error()) }
+
+		if version := binary.LittleEndian.Uint64(raw[mmcmap.MetaVersionIdx:]); version != meta.Version || version != 300 {
+			t.Errorf("version mismatch: actual(%d), expected(300)", version)
+		}
+
+		if rootOffset := binary.LittleEndian.Uint64(raw[mmcmap.MetaRootOffsetIdx:]); rootOffset != meta.RootOffset {
+			t.Errorf("root offset mismatch: actual(%d), expected(%d)", rootOffset, meta.RootOffset)
+		}
+
+		if keyCount := binary.LittleEndian.Uint64(raw[mmcmap.HeaderKeyCountIdx:]); keyCount != 300 {
+			t.Errorf("key count mismatch: actual(%d), expected(300)", keyCount)
+		}
+	})
+
+	t.Run("Test Byte Order Mismatch", func(t *testing.T) {
+		raw, readErr := os.ReadFile(formatTestPath)
+		if readErr != nil { t.Fatalf("error reading file: %s", readErr.Error()) }
+
+		for idx := 0; idx < mmcmap.HeaderIdx; idx += mmcmap.OffsetSize {
+			word := raw[idx:idx + mmcmap.OffsetSize]
+			binary.BigEndian.PutUint64(word, binary.LittleEndian.Uint64(word))
+		}
+
+		writeErr := os.WriteFile(formatTestPath, raw, 0600)
+		if writeErr != nil { t.Fatalf("error writing file: %s", writeErr.Error()) }
+
+		_, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: formatTestPath })
+		if openErr != mmcmap.ErrByteOrderMismatch { t.Errorf("byte order error mismatch: actual(%v), expected(%v)", openErr, mmcmap.ErrByteOrderMismatch) }
+	})
+
+	t.Log("Done")
+}
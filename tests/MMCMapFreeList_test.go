@@ -0,0 +1,176 @@
+package mmcmaptests
+
+import "bytes"
+import "errors"
+import "fmt"
+import "os"
+import "path/filepath"
+import "runtime"
+import "sync"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var flTestPath = filepath.Join(os.TempDir(), "testfreelist")
+var freeListTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initFlMapErr error
+	os.Remove(flTestPath)
+
+	opts := mmcmap.MMCMapOpts{ Filepath: flTestPath, Allocator: mmcmap.FreeListAllocator{} }
+	freeListTestMap, initFlMapErr = mmcmap.Open(opts)
+	if initFlMapErr != nil { panic(initFlMapErr.Error()) }
+
+	fmt.Println("free list test mmcmap initialized")
+}
+
+
+func TestMMCMapFreeList(t *testing.T) {
+	defer freeListTestMap.Remove()
+
+	putRound := func(t *testing.T, round int) {
+		for idx := 0; idx < 1000; idx++ {
+			_, putErr := freeListTestMap.Put([]byte(fmt.Sprintf("key%d", idx)), []byte(fmt.Sprintf("value%d-%d", idx, round)))
+			if putErr != nil { t.Errorf("error on put: %s", putErr.Error()) }
+		}
+	}
+
+	assertRound := func(t *testing.T, round int) {
+		for idx := 0; idx < 1000; idx++ {
+			key := []byte(fmt.Sprintf("key%d", idx))
+			expected := []byte(fmt.Sprintf("value%d-%d", idx, round))
+
+			val, getErr := freeListTestMap.Get(key)
+			if getErr != nil { t.Errorf("error on get: %s", getErr.Error()) }
+			if ! bytes.Equal(val, expected) { t.Errorf("value mismatch for %s: actual(%s), expected(%s)", key, val, expected) }
+		}
+
+		report, verifyErr := freeListTestMap.Verify()
+		if verifyErr != nil { t.Errorf("error on verify: %s", verifyErr.Error()) }
+		if report != nil && ! report.IsValid { t.Errorf("map invalid: %v", report.Findings) }
+	}
+
+	t.Run("Test Reclaim", func(t *testing.T) {
+		for round := 0; round < 3; round++ { putRound(t, round) }
+
+		report, reclaimErr := freeListTestMap.Reclaim()
+		if reclaimErr != nil { t.Fatalf("error on reclaim: %s", reclaimErr.Error()) }
+		if report.Regions == 0 || report.FreeBytes == 0 { t.Errorf("no regions reclaimed: regions(%d), bytes(%d)", report.Regions, report.FreeBytes) }
+
+		regions, regionsErr := freeListTestMap.FreeRegions()
+		if regionsErr != nil { t.Errorf("error reading free list: %s", regionsErr.Error()) }
+		if len(regions) != report.Regions { t.Errorf("free list length mismatch: actual(%d), expected(%d)", len(regions), report.Regions) }
+
+		assertRound(t, 2)
+	})
+
+	t.Run("Test Writes Reuse Reclaimed Regions", func(t *testing.T) {
+		prevMeta, _ := freeListTestMap.ReadMetaFromMemMap()
+		prevRegions, _ := freeListTestMap.FreeRegions()
+
+		for idx := 0; idx < 100; idx++ {
+			_, putErr := freeListTestMap.Put([]byte(fmt.Sprintf("key%d", idx)), []byte(fmt.Sprintf("value%d-3", idx)))
+			if putErr != nil { t.Errorf("error on put: %s", putErr.Error()) }
+		}
+
+		meta, _ := freeListTestMap.ReadMetaFromMemMap()
+		if meta.EndMmapOffset != prevMeta.EndMmapOffset { t.Errorf("writes appended instead of reusing regions: actual(%d), expected(%d)", meta.EndMmapOffset, prevMeta.EndMmapOffset) }
+		if meta.RootOffset >= prevMeta.EndMmapOffset { t.Errorf("root was appended: actual(%d), end(%d)", meta.RootOffset, prevMeta.EndMmapOffset) }
+
+		regions, _ := freeListTestMap.FreeRegions()
+		if totalFree(regions) >= totalFree(prevRegions) { t.Errorf("free list did not shrink: actual(%d), previous(%d)", totalFree(regions), totalFree(prevRegions)) }
+
+		for idx := 100; idx < 1000; idx++ {
+			_, putErr := freeListTestMap.Put([]byte(fmt.Sprintf("key%d", idx)), []byte(fmt.Sprintf("value%d-3", idx)))
+			if putErr != nil { t.Errorf("error on put: %s", putErr.Error()) }
+		}
+
+		assertRound(t, 3)
+	})
+
+	t.Run("Test Concurrent Writes Into Reclaimed Regions", func(t *testing.T) {
+		_, reclaimErr := freeListTestMap.Reclaim()
+		if reclaimErr != nil { t.Fatalf("error on reclaim: %s", reclaimErr.Error()) }
+
+		var wg sync.WaitGroup
+		for worker := 0; worker < 8; worker++ {
+			wg.Add(1)
+			go func(worker int) {
+				defer wg.Done()
+
+				for idx := worker; idx < 1000; idx += 8 {
+					_, putErr := freeListTestMap.Put([]byte(fmt.Sprintf("key%d", idx)), []byte(fmt.Sprintf("value%d-4", idx)))
+					if putErr != nil { t.Errorf("error on put: %s", putErr.Error()) }
+				}
+			}(worker)
+		}
+
+		wg.Wait()
+		assertRound(t, 4)
+	})
+
+	t.Run("Test Free List Persists Across Reopen", func(t *testing.T) {
+		prevRegions, _ := freeListTestMap.FreeRegions()
+
+		closeErr := freeListTestMap.Close()
+		if closeErr != nil { t.Errorf("error on close: %s", closeErr.Error()) }
+
+		var openErr error
+		freeListTestMap, openErr = mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: flTestPath })
+		if openErr != nil { t.Fatalf("error on reopen: %s", openErr.Error()) }
+
+		regions, _ := freeListTestMap.FreeRegions()
+		if len(regions) != len(prevRegions) || totalFree(regions) != totalFree(prevRegions) {
+			t.Errorf("free list changed across reopen: actual(%d), expected(%d)", totalFree(regions), totalFree(prevRegions))
+		}
+
+		assertRound(t, 4)
+	})
+
+	t.Run("Test Punch Holes", func(t *testing.T) {
+		for round := 5; round < 8; round++ { putRound(t, round) }
+
+		_, reclaimErr := freeListTestMap.Reclaim()
+		if reclaimErr != nil { t.Fatalf("error on reclaim: %s", reclaimErr.Error()) }
+
+		report, punchErr := freeListTestMap.PunchHoles()
+		if runtime.GOOS != "linux" {
+			if ! errors.Is(punchErr, mmcmap.ErrPunchHoleUnsupported) { t.Errorf("expected ErrPunchHoleUnsupported, got: %v", punchErr) }
+			return
+		}
+
+		if punchErr != nil { t.Fatalf("error on punch holes: %s", punchErr.Error()) }
+		if report.PunchedBytes == 0 { t.Error("no pages were punched") }
+
+		assertRound(t, 7)
+
+		putRound(t, 8)
+		assertRound(t, 8)
+	})
+
+	t.Run("Test Unsupported Operations", func(t *testing.T) {
+		_, historyErr := freeListTestMap.History([]byte("key1"), 0)
+		if ! errors.Is(historyErr, mmcmap.ErrAllocatorUnsupported) { t.Errorf("expected ErrAllocatorUnsupported from history, got: %v", historyErr) }
+
+		appendTestPath := filepath.Join(os.TempDir(), "testfreelistappend")
+		os.Remove(appendTestPath)
+
+		appendTestMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: appendTestPath })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+		defer appendTestMap.Remove()
+
+		_, reclaimErr := appendTestMap.Reclaim()
+		if ! errors.Is(reclaimErr, mmcmap.ErrAllocatorUnsupported) { t.Errorf("expected ErrAllocatorUnsupported from reclaim on an append map, got: %v", reclaimErr) }
+	})
+
+	t.Log("Done")
+}
+
+func totalFree(regions []mmcmap.FreeRegion) uint64 {
+	var total uint64
+	for _, region := range regions { total += region.Size }
+	return total
+}
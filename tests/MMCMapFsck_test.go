@@ -0,0 +1,117 @@
+package mmcmaptests
+
+import "bytes"
+import "fmt"
+import "os"
+import "path/filepath"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var fsckTestPath = filepath.Join(os.TempDir(), "testfsck")
+var fsckRecoverPath = filepath.Join(os.TempDir(), "testfsckrecovered")
+var fsckTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initFsckMapErr error
+	os.Remove(fsckTestPath)
+	os.Remove(fsckRecoverPath)
+
+	fsckTestMap, initFsckMapErr = mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: fsckTestPath })
+	if initFsckMapErr != nil { panic(initFsckMapErr.Error()) }
+
+	fmt.Println("fsck test mmcmap initialized")
+}
+
+
+func TestMMCMapFsck(t *testing.T) {
+	defer os.Remove(fsckTestPath)
+	defer os.Remove(fsckRecoverPath)
+
+	for idx := 0; idx < 200; idx++ {
+		key := []byte(fmt.Sprintf("key%d", idx))
+		_, putErr := fsckTestMap.Put(key, key)
+		if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+	}
+
+	meta, readMetaErr := fsckTestMap.ReadMetaFromMemMap()
+	if readMetaErr != nil { t.Fatalf("error reading metadata: %s", readMetaErr.Error()) }
+
+	root, readRootErr := fsckTestMap.ReadNodeFromMemMap(meta.RootOffset)
+	if readRootErr != nil { t.Fatalf("error reading root: %s", readRootErr.Error()) }
+
+	var corruptOffset uint64
+	for _, child := range root.Children {
+		node, readErr := fsckTestMap.ReadNodeFromMemMap(child.StartOffset)
+		if readErr != nil { t.Fatalf("error reading child: %s", readErr.Error()) }
+		if node.Version == meta.Version { corruptOffset = child.StartOffset }
+	}
+
+	if corruptOffset == 0 { t.Fatal("no node written by the latest commit") }
+
+	closeErr := fsckTestMap.Close()
+	if closeErr != nil { t.Fatalf("error on close: %s", closeErr.Error()) }
+
+	t.Run("Test Clean File", func(t *testing.T) {
+		before, readErr := os.ReadFile(fsckTestPath)
+		if readErr != nil { t.Fatalf("error reading file: %s", readErr.Error()) }
+
+		report, fsckErr := mmcmap.FsckFile(mmcmap.MMCMapOpts{ Filepath: fsckTestPath }, mmcmap.FsckOpts{})
+		if fsckErr != nil { t.Fatalf("error on fsck: %s", fsckErr.Error()) }
+
+		if ! report.IsClean { t.Errorf("clean file reported unclean: %v, %v", report.HeaderFindings, report.Versions[len(report.Versions) - 1].Findings) }
+		if report.CorruptVersions != 0 { t.Errorf("corrupt versions mismatch: actual(%d), expected(0)", report.CorruptVersions) }
+		if report.LatestValidVersion != meta.Version { t.Errorf("latest valid version mismatch: actual(%d), expected(%d)", report.LatestValidVersion, meta.Version) }
+		if report.ValidVersions < 200 { t.Errorf("too few versions found: actual(%d), expected(>= 200)", report.ValidVersions) }
+
+		latest := report.Versions[len(report.Versions) - 1]
+		if latest.KeysVisited != 200 { t.Errorf("keys mismatch: actual(%d), expected(200)", latest.KeysVisited) }
+		if report.ReachableBytes == 0 || report.ReachableBytes >= report.ScannedBytes {
+			t.Errorf("reachable bytes out of range: actual(%d), scanned(%d)", report.ReachableBytes, report.ScannedBytes)
+		}
+
+		after, readErr := os.ReadFile(fsckTestPath)
+		if readErr != nil { t.Fatalf("error reading file: %s", readErr.Error()) }
+		if ! bytes.Equal(before, after) { t.Error("fsck modified the file") }
+	})
+
+	t.Run("Test Corrupt File", func(t *testing.T) {
+		file, fileErr := os.OpenFile(fsckTestPath, os.O_RDWR, 0600)
+		if fileErr != nil { t.Fatalf("error opening file: %s", fileErr.Error()) }
+
+		_, writeErr := file.WriteAt([]byte{ 1, 2, 3, 4, 5, 6, 7, 8 }, int64(corruptOffset) + mmcmap.NodeStartOffsetIdx)
+		file.Close()
+		if writeErr != nil { t.Fatalf("error corrupting node: %s", writeErr.Error()) }
+
+		report, fsckErr := mmcmap.FsckFile(mmcmap.MMCMapOpts{ Filepath: fsckTestPath }, mmcmap.FsckOpts{ RecoverTo: fsckRecoverPath })
+		if fsckErr != nil { t.Fatalf("error on fsck: %s", fsckErr.Error()) }
+
+		if report.IsClean { t.Error("corrupt file reported clean") }
+		if report.CorruptVersions == 0 { t.Error("no corrupt versions reported") }
+		if report.LatestValidVersion >= meta.Version { t.Errorf("latest valid version not rolled back: actual(%d), expected(< %d)", report.LatestValidVersion, meta.Version) }
+		if report.RecoveredPath != fsckRecoverPath { t.Errorf("recovered path mismatch: actual(%s), expected(%s)", report.RecoveredPath, fsckRecoverPath) }
+
+		recovered, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: fsckRecoverPath })
+		if openErr != nil { t.Fatalf("error opening recovered file: %s", openErr.Error()) }
+		defer recovered.Close()
+
+		verifyReport, verifyErr := recovered.Verify()
+		if verifyErr != nil { t.Fatalf("error on verify: %s", verifyErr.Error()) }
+		if ! verifyReport.IsValid { t.Errorf("recovered file invalid: %v", verifyReport.Findings) }
+
+		count, lenErr := recovered.Len()
+		if lenErr != nil { t.Fatalf("error on len: %s", lenErr.Error()) }
+		if count != 199 { t.Errorf("recovered key count mismatch: actual(%d), expected(199)", count) }
+
+		value, getErr := recovered.Get([]byte("key0"))
+		if getErr != nil { t.Fatalf("error on get: %s", getErr.Error()) }
+		if ! bytes.Equal(value, []byte("key0")) { t.Errorf("recovered value mismatch: actual(%s), expected(key0)", value) }
+
+		_, fsckErr = mmcmap.FsckFile(mmcmap.MMCMapOpts{ Filepath: fsckTestPath }, mmcmap.FsckOpts{ RecoverTo: fsckRecoverPath })
+		if fsckErr == nil { t.Error("recovery overwrote an existing file") }
+	})
+
+	t.Log("Done")
+}
@@ -0,0 +1,94 @@
+package mmcmaptests
+
+import "bytes"
+import "fmt"
+import "os"
+import "path/filepath"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var fuzzTestPath = filepath.Join(os.TempDir(), "testfuzz")
+var fuzzTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initFuzzMapErr error
+	os.Remove(fuzzTestPath)
+
+	fuzzTestMap, initFuzzMapErr = mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: fuzzTestPath, OpenMode: mmcmap.OpenLazy })
+	if initFuzzMapErr != nil { panic(initFuzzMapErr.Error()) }
+
+	fmt.Println("fuzz test mmcmap initialized")
+}
+
+
+// fuzzSeedNodes
+//	Serialized nodes of every shape, used as the seed corpus of the node fuzz targets.
+func fuzzSeedNodes(f *testing.F) [][]byte {
+	nodes := []*mmcmap.MMCMapNode{
+		{ Version: 1, StartOffset: 64 },
+		{ Version: 2, StartOffset: 128, Bitmap: 0x80000011, Children: []*mmcmap.MMCMapNode{ { StartOffset: 64 }, { StartOffset: 1 << 33 }, { StartOffset: 512 } } },
+		{ Version: 3, StartOffset: 256, IsLeaf: true, KeyLength: 3, Key: []byte("key"), Value: []byte("value") },
+		{ Version: 4, StartOffset: 512, IsLeaf: true, KeyLength: 3, Key: []byte("key"), Value: []byte("value"), ExpiresAt: 1 << 40 },
+		{ Version: 5, StartOffset: 1024, IsLeaf: true, KeyLength: 3, Key: []byte("key"), Value: bytes.Repeat([]byte("v"), 256), Compression: mmcmap.CompressionSnappy },
+		{ Version: 6, StartOffset: 2048, IsLeaf: true, KeyLength: 3, Key: []byte("key"), Value: bytes.Repeat([]byte("v"), 64), IsOverflow: true, OverflowOffset: 64 },
+	}
+
+	var seeds [][]byte
+	for _, node := range nodes {
+		sNode, serializeErr := node.SerializeNode(node.StartOffset)
+		if serializeErr != nil { f.Fatalf("error serializing seed node: %s", serializeErr.Error()) }
+
+		seeds = append(seeds, sNode)
+	}
+
+	return seeds
+}
+
+func FuzzDeserializeINode(f *testing.F) {
+	for _, seed := range fuzzSeedNodes(f) { f.Add(seed) }
+
+	f.Fuzz(func(t *testing.T, snode []byte) {
+		node, decErr := mmcmap.DeserializeINode(snode)
+		if decErr != nil { return }
+
+		sNode, serializeErr := node.SerializeNode(node.StartOffset)
+		if serializeErr != nil { t.Fatalf("error serializing deserialized node: %s", serializeErr.Error()) }
+		if ! bytes.Equal(sNode, snode[:len(sNode)]) { t.Errorf("internal node round trip mismatch: actual(%v), expected(%v)", sNode, snode[:len(sNode)]) }
+	})
+}
+
+func FuzzDeserializeLNode(f *testing.F) {
+	for _, seed := range fuzzSeedNodes(f) { f.Add(seed) }
+
+	f.Fuzz(func(t *testing.T, snode []byte) {
+		node, decErr := mmcmap.DeserializeLNode(snode)
+		if decErr != nil { return }
+
+		if ! node.IsLeaf || int(node.KeyLength) != len(node.Key) { t.Errorf("invalid leaf: actual(%+v)", node) }
+		if node.EndOffset - node.StartOffset >= uint64(len(snode)) { t.Errorf("leaf exceeds input: actual(%d, %d), len(%d)", node.StartOffset, node.EndOffset, len(snode)) }
+	})
+}
+
+func FuzzDeserializeNode(f *testing.F) {
+	for _, seed := range fuzzSeedNodes(f) { f.Add(seed) }
+
+	f.Fuzz(func(t *testing.T, snode []byte) {
+		node, decErr := fuzzTestMap.DeserializeNode(snode)
+		if decErr != nil { return }
+		if node.IsLeaf && int(node.KeyLength) != len(node.Key) { t.Errorf("invalid leaf: actual(%+v)", node) }
+	})
+}
+
+func FuzzDeserializeMetaData(f *testing.F) {
+	f.Add((&mmcmap.MMCMapMetaData{ Version: 1, RootOffset: 64, EndMmapOffset: 96 }).SerializeMetaData())
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, smeta []byte) {
+		meta, decErr := mmcmap.DeserializeMetaData(smeta)
+		if decErr != nil { return }
+		if ! bytes.Equal(meta.SerializeMetaData(), smeta) { t.Errorf("meta data round trip mismatch: actual(%+v), expected(%v)", meta, smeta) }
+	})
+}
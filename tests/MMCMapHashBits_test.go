@@ -0,0 +1,133 @@
+package mmcmaptests
+
+import "fmt"
+import "os"
+import "path/filepath"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var hashBitsTestPath = filepath.Join(os.TempDir(), "testhashbits")
+var hashBitsTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initHashBitsMapErr error
+	os.Remove(hashBitsTestPath)
+
+	opts := mmcmap.MMCMapOpts{ Filepath: hashBitsTestPath, HashBits: mmcmap.HashBits64 }
+	hashBitsTestMap, initHashBitsMapErr = mmcmap.Open(opts)
+	if initHashBitsMapErr != nil { panic(initHashBitsMapErr.Error()) }
+
+	fmt.Println("hash bits test mmcmap initialized")
+}
+
+
+func TestMMCMapHashBits(t *testing.T) {
+	defer hashBitsTestMap.Remove()
+
+	keys := make([]string, 10000)
+	for idx := range keys { keys[idx] = fmt.Sprintf("key%d", idx) }
+
+	for _, key := range keys {
+		_, putErr := hashBitsTestMap.Put([]byte(key), []byte("v" + key))
+		if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+	}
+
+	checkValues := func(t *testing.T, mmcMap *mmcmap.MMCMap) {
+		for _, key := range keys {
+			value, getErr := mmcMap.Get([]byte(key))
+			if getErr != nil { t.Fatalf("error on get: %s", getErr.Error()) }
+			if string(value) != "v" + key { t.Fatalf("value mismatch for %s: actual(%s), expected(v%s)", key, value, key) }
+		}
+	}
+
+	t.Run("Test Reads", func(t *testing.T) {
+		checkValues(t, hashBitsTestMap)
+
+		if hashBitsTestMap.HashChunks != 12 { t.Errorf("hash chunks mismatch: actual(%d), expected(12)", hashBitsTestMap.HashChunks) }
+
+		report, verifyErr := hashBitsTestMap.Verify()
+		if verifyErr != nil { t.Fatalf("error on verify: %s", verifyErr.Error()) }
+		if ! report.IsValid { t.Errorf("verify found inconsistencies: %v", report.Findings) }
+	})
+
+	t.Run("Test Delete", func(t *testing.T) {
+		for _, key := range keys[:len(keys) / 2] {
+			_, delErr := hashBitsTestMap.Delete([]byte(key))
+			if delErr != nil { t.Fatalf("error on delete: %s", delErr.Error()) }
+		}
+
+		for _, key := range keys[:len(keys) / 2] {
+			value, _ := hashBitsTestMap.Get([]byte(key))
+			if value != nil { t.Fatalf("deleted key %s still present", key) }
+		}
+
+		for _, key := range keys[:len(keys) / 2] {
+			_, putErr := hashBitsTestMap.Put([]byte(key), []byte("v" + key))
+			if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+		}
+
+		checkValues(t, hashBitsTestMap)
+	})
+
+	t.Run("Test Persisted", func(t *testing.T) {
+		_, compactErr := hashBitsTestMap.Compact()
+		if compactErr != nil { t.Fatalf("error on compact: %s", compactErr.Error()) }
+
+		closeErr := hashBitsTestMap.Close()
+		if closeErr != nil { t.Fatalf("error on close: %s", closeErr.Error()) }
+
+		_, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: hashBitsTestPath, HashBits: mmcmap.HashBits32 })
+		if openErr != mmcmap.ErrHashBitsMismatch { t.Errorf("mismatch error mismatch: actual(%v), expected(%v)", openErr, mmcmap.ErrHashBitsMismatch) }
+
+		hashBitsTestMap, openErr = mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: hashBitsTestPath, OpenCheck: mmcmap.OpenCheckDeep })
+		if openErr != nil { t.Fatalf("error on reopen: %s", openErr.Error()) }
+
+		if hashBitsTestMap.Header.HashBits != mmcmap.HashBits64 { t.Errorf("hash bits were not persisted: actual(%d)", hashBitsTestMap.Header.HashBits) }
+		if len(hashBitsTestMap.OpenReport.Findings) != 0 { t.Errorf("open check found inconsistencies: %v", hashBitsTestMap.OpenReport.Findings) }
+
+		checkValues(t, hashBitsTestMap)
+	})
+
+	t.Run("Test Bulk Load And Truncate", func(t *testing.T) {
+		path := filepath.Join(os.TempDir(), "testhashbitsbulk")
+		os.Remove(path)
+
+		bulkMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: path, HashBits: mmcmap.HashBits64 })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+		defer bulkMap.Remove()
+
+		pairs := make([]mmcmap.KeyValuePair, len(keys))
+		for idx, key := range keys { pairs[idx] = mmcmap.KeyValuePair{ Key: []byte(key), Value: []byte("v" + key) } }
+
+		loadErr := bulkMap.BulkLoad(pairs)
+		if loadErr != nil { t.Fatalf("error on bulk load: %s", loadErr.Error()) }
+
+		checkValues(t, bulkMap)
+
+		clearErr := bulkMap.Clear(mmcmap.ClearOpts{ Truncate: true })
+		if clearErr != nil { t.Fatalf("error on clear: %s", clearErr.Error()) }
+		if bulkMap.Header.HashBits != mmcmap.HashBits64 { t.Errorf("hash bits were not kept by clear: actual(%d)", bulkMap.Header.HashBits) }
+	})
+
+	t.Run("Test Unsupported Options", func(t *testing.T) {
+		path := filepath.Join(os.TempDir(), "testhashbitserrors")
+		os.Remove(path)
+		defer os.Remove(path)
+
+		_, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: path, HashBits: 48 })
+		if openErr != mmcmap.ErrUnknownHashBits { t.Errorf("unknown error mismatch: actual(%v), expected(%v)", openErr, mmcmap.ErrUnknownHashBits) }
+
+		defaultMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: path })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+		if defaultMap.Header.HashBits != mmcmap.HashBits32 { t.Errorf("default hash bits mismatch: actual(%d), expected(%d)", defaultMap.Header.HashBits, mmcmap.HashBits32) }
+		defaultMap.Close()
+
+		_, openErr = mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: path, HashBits: mmcmap.HashBits64 })
+		if openErr != mmcmap.ErrHashBitsMismatch { t.Errorf("mismatch error mismatch: actual(%v), expected(%v)", openErr, mmcmap.ErrHashBitsMismatch) }
+	})
+
+	t.Log("Done")
+}
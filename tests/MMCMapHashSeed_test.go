@@ -0,0 +1,109 @@
+package mmcmaptests
+
+import "bytes"
+import "fmt"
+import "os"
+import "path/filepath"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var hashSeedTestPath = filepath.Join(os.TempDir(), "testhashseed")
+var hashSeedTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initHashSeedMapErr error
+	os.Remove(hashSeedTestPath)
+
+	hashSeedTestMap, initHashSeedMapErr = mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: hashSeedTestPath })
+	if initHashSeedMapErr != nil { panic(initHashSeedMapErr.Error()) }
+
+	fmt.Println("hash seed test mmcmap initialized")
+}
+
+
+func TestMMCMapHashSeed(t *testing.T) {
+	defer hashSeedTestMap.Remove()
+
+	keys := make([]string, 1000)
+	for idx := range keys { keys[idx] = fmt.Sprintf("key%d", idx) }
+
+	for _, key := range keys {
+		_, putErr := hashSeedTestMap.Put([]byte(key), []byte("v" + key))
+		if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+	}
+
+	checkValues := func(t *testing.T, mmcMap *mmcmap.MMCMap) {
+		for _, key := range keys {
+			value, getErr := mmcMap.Get([]byte(key))
+			if getErr != nil { t.Fatalf("error on get: %s", getErr.Error()) }
+			if string(value) != "v" + key { t.Fatalf("value mismatch for %s: actual(%s), expected(v%s)", key, value, key) }
+		}
+	}
+
+	seed := hashSeedTestMap.Header.HashSeed
+
+	t.Run("Test Seed Per File", func(t *testing.T) {
+		path := filepath.Join(os.TempDir(), "testhashseedother")
+		os.Remove(path)
+
+		otherMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: path, HashBits: mmcmap.HashBits64 })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+		defer otherMap.Remove()
+
+		if otherMap.Header.HashSeed == seed { t.Errorf("new files share a hash seed: %d", seed) }
+
+		for _, key := range keys {
+			_, putErr := otherMap.Put([]byte(key), []byte("v" + key))
+			if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+		}
+
+		checkValues(t, otherMap)
+	})
+
+	t.Run("Test Seed Persisted", func(t *testing.T) {
+		_, compactErr := hashSeedTestMap.Compact()
+		if compactErr != nil { t.Fatalf("error on compact: %s", compactErr.Error()) }
+
+		closeErr := hashSeedTestMap.Close()
+		if closeErr != nil { t.Fatalf("error on close: %s", closeErr.Error()) }
+
+		var openErr error
+		hashSeedTestMap, openErr = mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: hashSeedTestPath, OpenCheck: mmcmap.OpenCheckDeep })
+		if openErr != nil { t.Fatalf("error on reopen: %s", openErr.Error()) }
+
+		if hashSeedTestMap.Header.HashSeed != seed { t.Errorf("hash seed mismatch: actual(%d), expected(%d)", hashSeedTestMap.Header.HashSeed, seed) }
+		if len(hashSeedTestMap.OpenReport.Findings) != 0 { t.Errorf("open check found inconsistencies: %v", hashSeedTestMap.OpenReport.Findings) }
+
+		checkValues(t, hashSeedTestMap)
+	})
+
+	t.Run("Test Replica Adopts Seed", func(t *testing.T) {
+		path := filepath.Join(os.TempDir(), "testhashseedreplica")
+		os.Remove(path)
+
+		replica, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: path })
+		if openErr != nil { t.Fatalf("error opening replica: %s", openErr.Error()) }
+		defer replica.Remove()
+
+		var buf bytes.Buffer
+		exportErr := hashSeedTestMap.ExportDelta(&buf, 0)
+		if exportErr != nil { t.Fatalf("error exporting delta: %s", exportErr.Error()) }
+
+		catchUpErr := replica.CatchUp(&buf)
+		if catchUpErr != nil { t.Fatalf("error on catch up: %s", catchUpErr.Error()) }
+		if replica.Header.HashSeed != seed { t.Errorf("replica hash seed mismatch: actual(%d), expected(%d)", replica.Header.HashSeed, seed) }
+
+		checkValues(t, replica)
+	})
+
+	t.Run("Test Seed Kept By Clear", func(t *testing.T) {
+		clearErr := hashSeedTestMap.Clear(mmcmap.ClearOpts{ Truncate: true })
+		if clearErr != nil { t.Fatalf("error on clear: %s", clearErr.Error()) }
+		if hashSeedTestMap.Header.HashSeed != seed { t.Errorf("hash seed was not kept by clear: actual(%d), expected(%d)", hashSeedTestMap.Header.HashSeed, seed) }
+	})
+
+	t.Log("Done")
+}
@@ -0,0 +1,128 @@
+package mmcmaptests
+
+import "context"
+import "errors"
+import "fmt"
+import "os"
+import "path/filepath"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var healthTestPath = filepath.Join(os.TempDir(), "testhealth")
+var healthTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initHealthMapErr error
+	mmcmap.Destroy(healthTestPath)
+
+	opts := mmcmap.MMCMapOpts{ Filepath: healthTestPath }
+	healthTestMap, initHealthMapErr = mmcmap.OpenWithContext(context.Background(), opts)
+	if initHealthMapErr != nil { panic(initHealthMapErr.Error()) }
+
+	fmt.Println("health test mmcmap initialized")
+}
+
+
+func TestMMCMapHealth(t *testing.T) {
+	defer healthTestMap.Remove()
+
+	t.Run("Test Ping", func(t *testing.T) {
+		_, putErr := healthTestMap.Put([]byte("key"), []byte("value"))
+		if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+
+		pingErr := healthTestMap.Ping()
+		if pingErr != nil { t.Errorf("error on ping: %s", pingErr.Error()) }
+	})
+
+	t.Run("Test Ping Lazy", func(t *testing.T) {
+		path := filepath.Join(os.TempDir(), "testhealthlazy")
+		mmcmap.Destroy(path)
+
+		mmcMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: path, OpenMode: mmcmap.OpenLazy })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+		defer mmcMap.Remove()
+
+		pingErr := mmcMap.Ping()
+		if pingErr != nil { t.Errorf("error on ping of lazy map: %s", pingErr.Error()) }
+		if len(mmcMap.BackgroundTasks) == 0 { t.Error("ping did not start the background go routines") }
+	})
+
+	t.Run("Test Ping Closed", func(t *testing.T) {
+		path := filepath.Join(os.TempDir(), "testhealthclosed")
+		mmcmap.Destroy(path)
+
+		mmcMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: path })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+		defer mmcMap.Remove()
+
+		closeErr := mmcMap.Close()
+		if closeErr != nil { t.Fatalf("error on close: %s", closeErr.Error()) }
+
+		pingErr := mmcMap.Ping()
+		if ! errors.Is(pingErr, mmcmap.ErrMapClosed) { t.Errorf("ping error mismatch: actual(%v), expected(%v)", pingErr, mmcmap.ErrMapClosed) }
+	})
+
+	t.Run("Test Open With Cancelled Context", func(t *testing.T) {
+		path := filepath.Join(os.TempDir(), "testhealthcancel")
+		mmcmap.Destroy(path)
+		defer mmcmap.Destroy(path)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		mmcMap, openErr := mmcmap.OpenWithContext(ctx, mmcmap.MMCMapOpts{ Filepath: path })
+		if ! errors.Is(openErr, context.Canceled) { t.Errorf("open error mismatch: actual(%v), expected(%v)", openErr, context.Canceled) }
+		if mmcMap != nil { t.Error("map returned for cancelled open") }
+	})
+
+	t.Run("Test Open Stops During Open Check", func(t *testing.T) {
+		path := filepath.Join(os.TempDir(), "testhealthopencheck")
+		mmcmap.Destroy(path)
+		defer mmcmap.Destroy(path)
+
+		mmcMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: path })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+
+		for idx := 0; idx < 1000; idx++ {
+			_, putErr := mmcMap.Put([]byte(fmt.Sprintf("key%d", idx)), []byte("value"))
+			if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+		}
+
+		closeErr := mmcMap.Close()
+		if closeErr != nil { t.Fatalf("error on close: %s", closeErr.Error()) }
+
+		// done at the first check of the deep open check traversal, after the four checks made by the earlier steps of the open
+		ctx := &countdownContext{ Context: context.Background(), remaining: 5 }
+		opts := mmcmap.MMCMapOpts{ Filepath: path, OpenCheck: mmcmap.OpenCheckDeep }
+
+		mmcMap, openErr = mmcmap.OpenWithContext(ctx, opts)
+		if ! errors.Is(openErr, context.Canceled) { t.Fatalf("open error mismatch: actual(%v), expected(%v)", openErr, context.Canceled) }
+		if mmcMap != nil { t.Error("map returned for cancelled open") }
+		if ctx.remaining > 0 { t.Errorf("open check did not check the context: remaining(%d)", ctx.remaining) }
+
+		mmcMap, openErr = mmcmap.Open(opts)
+		if openErr != nil { t.Fatalf("error reopening after a cancelled open: %s", openErr.Error()) }
+		defer mmcMap.Close()
+
+		keyCount, _ := mmcMap.Len()
+		if keyCount != 1000 { t.Errorf("key count mismatch after a cancelled open: actual(%d), expected(1000)", keyCount) }
+	})
+
+	t.Log("Done")
+}
+
+
+// countdownContext is done once Err has been called remaining times, so a test can stop an open at a given check
+type countdownContext struct {
+	context.Context
+	remaining int
+}
+
+func (ctx *countdownContext) Err() error {
+	if ctx.remaining > 0 { ctx.remaining-- }
+	if ctx.remaining == 0 { return context.Canceled }
+	return nil
+}
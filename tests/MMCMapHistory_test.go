@@ -0,0 +1,88 @@
+package mmcmaptests
+
+import "fmt"
+import "os"
+import "path/filepath"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var hTestPath = filepath.Join(os.TempDir(), "testhistory")
+var historyTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initHMapErr error
+	os.Remove(hTestPath)
+
+	opts := mmcmap.MMCMapOpts{ Filepath: hTestPath }
+	historyTestMap, initHMapErr = mmcmap.Open(opts)
+	if initHMapErr != nil { panic(initHMapErr.Error()) }
+
+	fmt.Println("history test mmcmap initialized")
+}
+
+
+func TestMMCMapHistory(t *testing.T) {
+	defer historyTestMap.Remove()
+
+	key := []byte("tracked")
+	for idx := range make([]int, 5) {
+		_, putErr := historyTestMap.Put(key, []byte(fmt.Sprintf("value%d", idx)))
+		if putErr != nil { t.Errorf("error putting key in mmcmap: %s", putErr.Error()) }
+
+		_, putErr = historyTestMap.Put([]byte(fmt.Sprintf("other%d", idx)), []byte("other"))
+		if putErr != nil { t.Errorf("error putting key in mmcmap: %s", putErr.Error()) }
+	}
+
+	t.Run("Test Full History", func(t *testing.T) {
+		history, historyErr := historyTestMap.History(key, 0)
+		if historyErr != nil { t.Errorf("error getting history: %s", historyErr.Error()) }
+		if len(history) != 5 { t.Fatalf("history returned unexpected number of entries: actual(%d), expected(5)", len(history)) }
+
+		for idx, pair := range history {
+			expected := fmt.Sprintf("value%d", 4 - idx)
+			if string(pair.Value) != expected { t.Errorf("history value mismatch: actual(%s), expected(%s)", pair.Value, expected) }
+			if idx > 0 && pair.Version >= history[idx - 1].Version { t.Errorf("history not ordered newest first: %d >= %d", pair.Version, history[idx - 1].Version) }
+		}
+	})
+
+	t.Run("Test Limited History", func(t *testing.T) {
+		history, historyErr := historyTestMap.History(key, 2)
+		if historyErr != nil { t.Errorf("error getting history: %s", historyErr.Error()) }
+		if len(history) != 2 { t.Errorf("history returned unexpected number of entries: actual(%d), expected(2)", len(history)) }
+		if string(history[0].Value) != "value4" { t.Errorf("latest history value mismatch: actual(%s), expected(value4)", history[0].Value) }
+	})
+
+	t.Run("Test History Of Missing Key", func(t *testing.T) {
+		history, historyErr := historyTestMap.History([]byte("missing"), 0)
+		if historyErr != nil { t.Errorf("error getting history: %s", historyErr.Error()) }
+		if len(history) != 0 { t.Errorf("expected empty history for missing key, got %d entries", len(history)) }
+	})
+
+	t.Run("Test Write Versions Match History", func(t *testing.T) {
+		versioned := []byte("versioned")
+
+		putVersion, putErr := historyTestMap.PutVersioned(versioned, []byte("first"))
+		if putErr != nil { t.Fatalf("error on versioned put: %s", putErr.Error()) }
+
+		meta, readMetaErr := historyTestMap.ReadMetaFromMemMap()
+		if readMetaErr != nil { t.Fatalf("error reading metadata: %s", readMetaErr.Error()) }
+		if putVersion != meta.Version { t.Errorf("put version mismatch: actual(%d), expected(%d)", putVersion, meta.Version) }
+
+		history, historyErr := historyTestMap.History(versioned, 0)
+		if historyErr != nil { t.Fatalf("error getting history: %s", historyErr.Error()) }
+		if len(history) != 1 || history[0].Version != putVersion { t.Errorf("history version mismatch: actual(%v), expected(%d)", history, putVersion) }
+
+		delVersion, delErr := historyTestMap.DeleteVersioned(versioned)
+		if delErr != nil { t.Fatalf("error on versioned delete: %s", delErr.Error()) }
+		if delVersion != putVersion + 1 { t.Errorf("delete version mismatch: actual(%d), expected(%d)", delVersion, putVersion + 1) }
+
+		delVersion, delErr = historyTestMap.DeleteVersioned(versioned)
+		if delErr != nil { t.Fatalf("error on versioned delete: %s", delErr.Error()) }
+		if delVersion != 0 { t.Errorf("delete of missing key wrote a version: actual(%d), expected(0)", delVersion) }
+	})
+
+	t.Log("Done")
+}
@@ -0,0 +1,143 @@
+package mmcmaptests
+
+import "bytes"
+import "fmt"
+import "os"
+import "path/filepath"
+import "sync"
+import "testing"
+import "time"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var hooksTestPath = filepath.Join(os.TempDir(), "testhooks")
+var hooksTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initHooksMapErr error
+	os.Remove(hooksTestPath)
+
+	hooksTestMap, initHooksMapErr = mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: hooksTestPath })
+	if initHooksMapErr != nil { panic(initHooksMapErr.Error()) }
+
+	fmt.Println("hooks test mmcmap initialized")
+}
+
+
+func TestMMCMapHooks(t *testing.T) {
+	defer hooksTestMap.Remove()
+
+	t.Run("Test On Commit In Version Order", func(t *testing.T) {
+		var lock sync.Mutex
+		var versions []uint64
+		var rootOffsets []uint64
+
+		cancel := hooksTestMap.OnCommit(func(version, rootOffset uint64) {
+			lock.Lock()
+			defer lock.Unlock()
+
+			versions = append(versions, version)
+			rootOffsets = append(rootOffsets, rootOffset)
+		})
+
+		var wg sync.WaitGroup
+		for worker := 0; worker < 4; worker++ {
+			wg.Add(1)
+			go func(worker int) {
+				defer wg.Done()
+				for idx := 0; idx < 50; idx++ {
+					key := []byte(fmt.Sprintf("worker%d-%d", worker, idx))
+					_, putErr := hooksTestMap.Put(key, key)
+					if putErr != nil { t.Errorf("error on put: %s", putErr.Error()) }
+				}
+			}(worker)
+		}
+
+		wg.Wait()
+
+		lock.Lock()
+		if len(versions) != 200 { t.Fatalf("commit hook count mismatch: actual(%d), expected(200)", len(versions)) }
+		for idx := 1; idx < len(versions); idx++ {
+			if versions[idx] != versions[idx - 1] + 1 { t.Fatalf("commit hooks out of order: actual(%d after %d)", versions[idx], versions[idx - 1]) }
+		}
+
+		lastVersion, lastRoot := versions[len(versions) - 1], rootOffsets[len(rootOffsets) - 1]
+		lock.Unlock()
+
+		meta, readMetaErr := hooksTestMap.ReadMetaFromMemMap()
+		if readMetaErr != nil { t.Fatalf("error reading meta: %s", readMetaErr.Error()) }
+		if meta.Version != lastVersion || meta.RootOffset != lastRoot { t.Errorf("last commit mismatch: actual(%d %d), expected(%d %d)", lastVersion, lastRoot, meta.Version, meta.RootOffset) }
+
+		cancel()
+		cancel()
+
+		_, putErr := hooksTestMap.Put([]byte("after"), []byte("cancel"))
+		if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+
+		lock.Lock()
+		defer lock.Unlock()
+
+		if len(versions) != 200 { t.Errorf("commit hook called after cancel: actual(%d), expected(200)", len(versions)) }
+	})
+
+	t.Run("Test On Resize And Flush", func(t *testing.T) {
+		resized := make(chan uint64, 16)
+		cancelResize := hooksTestMap.OnResize(func(newSize uint64) {
+			select {
+				case resized <- newSize:
+				default:
+			}
+		})
+		defer cancelResize()
+
+		flushed := make(chan uint64, 1024)
+		cancelFlush := hooksTestMap.OnFlush(func(version uint64) {
+			select {
+				case flushed <- version:
+				default:
+			}
+		})
+		defer cancelFlush()
+
+		startSize, _ := hooksTestMap.FileSize()
+		value := bytes.Repeat([]byte("v"), 4096)
+		for idx := 0; ; idx++ {
+			_, putErr := hooksTestMap.Put([]byte(fmt.Sprintf("large%d", idx)), value)
+			if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+
+			size, _ := hooksTestMap.FileSize()
+			if size > startSize { break }
+		}
+
+		select {
+			case newSize := <-resized:
+				size, _ := hooksTestMap.FileSize()
+				if newSize <= uint64(startSize) || newSize > uint64(size) { t.Errorf("resize size mismatch: actual(%d), expected(%d-%d)", newSize, startSize, size) }
+			case <-time.After(5 * time.Second):
+				t.Errorf("resize hook was not called")
+		}
+
+		select {
+			case version := <-flushed:
+				meta, _ := hooksTestMap.ReadMetaFromMemMap()
+				if version == 0 || version > meta.Version { t.Errorf("flushed version out of range: actual(%d), latest(%d)", version, meta.Version) }
+			case <-time.After(5 * time.Second):
+				t.Errorf("flush hook was not called")
+		}
+	})
+
+	t.Run("Test On Compact", func(t *testing.T) {
+		var reports []*mmcmap.CompactReport
+		cancel := hooksTestMap.OnCompact(func(report *mmcmap.CompactReport) { reports = append(reports, report) })
+		defer cancel()
+
+		report, compactErr := hooksTestMap.Compact()
+		if compactErr != nil { t.Fatalf("error on compact: %s", compactErr.Error()) }
+
+		if len(reports) != 1 || reports[0] != report { t.Errorf("compact hook mismatch: actual(%v), expected([%v])", reports, report) }
+	})
+
+	t.Log("Done")
+}
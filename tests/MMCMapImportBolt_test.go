@@ -0,0 +1,82 @@
+//go:build bolt
+
+package mmcmaptests
+
+import "bytes"
+import "fmt"
+import "os"
+import "path/filepath"
+import "testing"
+
+import bolt "go.etcd.io/bbolt"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var ibTestPath = filepath.Join(os.TempDir(), "testimportbolt")
+var importBoltTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initIbMapErr error
+	os.Remove(ibTestPath)
+
+	importBoltTestMap, initIbMapErr = mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: ibTestPath })
+	if initIbMapErr != nil { panic(initIbMapErr.Error()) }
+
+	fmt.Println("import bolt test mmcmap initialized")
+}
+
+
+func TestMMCMapImportBolt(t *testing.T) {
+	defer importBoltTestMap.Remove()
+
+	boltTestPath := filepath.Join(os.TempDir(), "testimportbolt.db")
+	os.Remove(boltTestPath)
+	defer os.Remove(boltTestPath)
+
+	db, openErr := bolt.Open(boltTestPath, 0600, nil)
+	if openErr != nil { t.Fatalf("error opening bolt: %s", openErr.Error()) }
+	defer db.Close()
+
+	updateErr := db.Update(func(tx *bolt.Tx) error {
+		bucket, createErr := tx.CreateBucket([]byte("pairs"))
+		if createErr != nil { return createErr }
+
+		for idx := 0; idx < 25000; idx++ {
+			putErr := bucket.Put([]byte(fmt.Sprintf("key%d", idx)), []byte(fmt.Sprintf("value%d", idx)))
+			if putErr != nil { return putErr }
+		}
+
+		_, nestedErr := bucket.CreateBucket([]byte("nested"))
+		return nestedErr
+	})
+
+	if updateErr != nil { t.Fatalf("error seeding bolt: %s", updateErr.Error()) }
+
+	t.Run("Test Import From Bolt", func(t *testing.T) {
+		imported, importErr := importBoltTestMap.ImportFromBolt(db, []byte("pairs"))
+		if importErr != nil { t.Fatalf("error on import: %s", importErr.Error()) }
+		if imported != 25000 { t.Errorf("imported count mismatch: actual(%d), expected(25000)", imported) }
+
+		version, _ := readVersion(importBoltTestMap)
+		if version != 3 { t.Errorf("import batch versions mismatch: actual(%d), expected(3)", version) }
+
+		for idx := 0; idx < 25000; idx++ {
+			key := []byte(fmt.Sprintf("key%d", idx))
+			val, getErr := importBoltTestMap.Get(key)
+			if getErr != nil { t.Errorf("error on get: %s", getErr.Error()) }
+			if ! bytes.Equal(val, []byte(fmt.Sprintf("value%d", idx))) { t.Errorf("value mismatch for %s: actual(%s)", key, val) }
+		}
+
+		val, _ := importBoltTestMap.Get([]byte("nested"))
+		if val != nil { t.Errorf("nested bucket was imported: actual(%v), expected(nil)", val) }
+	})
+
+	t.Run("Test Import Missing Bucket", func(t *testing.T) {
+		_, importErr := importBoltTestMap.ImportFromBolt(db, []byte("missing"))
+		if importErr == nil { t.Error("expected an error importing a missing bucket") }
+	})
+
+	t.Log("Done")
+}
@@ -0,0 +1,70 @@
+//go:build leveldb
+
+package mmcmaptests
+
+import "bytes"
+import "fmt"
+import "os"
+import "path/filepath"
+import "testing"
+
+import "github.com/syndtr/goleveldb/leveldb"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var ilTestPath = filepath.Join(os.TempDir(), "testimportleveldb")
+var importLevelDBTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initIlMapErr error
+	os.Remove(ilTestPath)
+
+	importLevelDBTestMap, initIlMapErr = mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: ilTestPath })
+	if initIlMapErr != nil { panic(initIlMapErr.Error()) }
+
+	fmt.Println("import leveldb test mmcmap initialized")
+}
+
+
+func TestMMCMapImportLevelDB(t *testing.T) {
+	defer importLevelDBTestMap.Remove()
+
+	levelTestPath := filepath.Join(os.TempDir(), "testimportleveldb.db")
+	os.RemoveAll(levelTestPath)
+	defer os.RemoveAll(levelTestPath)
+
+	db, openErr := leveldb.OpenFile(levelTestPath, nil)
+	if openErr != nil { t.Fatalf("error opening leveldb: %s", openErr.Error()) }
+
+	batch := new(leveldb.Batch)
+	for idx := 0; idx < 25000; idx++ { batch.Put([]byte(fmt.Sprintf("key%d", idx)), []byte(fmt.Sprintf("value%d", idx))) }
+
+	writeErr := db.Write(batch, nil)
+	if writeErr != nil { t.Fatalf("error seeding leveldb: %s", writeErr.Error()) }
+	db.Close()
+
+	t.Run("Test Import From LevelDB", func(t *testing.T) {
+		imported, importErr := importLevelDBTestMap.ImportFromLevelDB(levelTestPath)
+		if importErr != nil { t.Fatalf("error on import: %s", importErr.Error()) }
+		if imported != 25000 { t.Errorf("imported count mismatch: actual(%d), expected(25000)", imported) }
+
+		version, _ := readVersion(importLevelDBTestMap)
+		if version != 3 { t.Errorf("import batch versions mismatch: actual(%d), expected(3)", version) }
+
+		for idx := 0; idx < 25000; idx++ {
+			key := []byte(fmt.Sprintf("key%d", idx))
+			val, getErr := importLevelDBTestMap.Get(key)
+			if getErr != nil { t.Errorf("error on get: %s", getErr.Error()) }
+			if ! bytes.Equal(val, []byte(fmt.Sprintf("value%d", idx))) { t.Errorf("value mismatch for %s: actual(%s)", key, val) }
+		}
+	})
+
+	t.Run("Test Import Missing Database", func(t *testing.T) {
+		_, importErr := importLevelDBTestMap.ImportFromLevelDB(filepath.Join(os.TempDir(), "testimportleveldbmissing"))
+		if importErr == nil { t.Error("expected an error importing a missing database") }
+	})
+
+	t.Log("Done")
+}
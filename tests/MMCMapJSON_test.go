@@ -0,0 +1,140 @@
+package mmcmaptests
+
+import "bytes"
+import "fmt"
+import "os"
+import "path/filepath"
+import "strings"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var jsTestPath = filepath.Join(os.TempDir(), "testjson")
+var jsonTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initJsMapErr error
+	os.Remove(jsTestPath)
+
+	jsonTestMap, initJsMapErr = mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: jsTestPath })
+	if initJsMapErr != nil { panic(initJsMapErr.Error()) }
+
+	fmt.Println("json test mmcmap initialized")
+}
+
+
+func TestMMCMapJSON(t *testing.T) {
+	defer jsonTestMap.Remove()
+
+	for idx := 0; idx < 500; idx++ {
+		_, putErr := jsonTestMap.Put([]byte(fmt.Sprintf("key%d", idx)), []byte(fmt.Sprintf("value%d", idx)))
+		if putErr != nil { t.Errorf("error on put: %s", putErr.Error()) }
+	}
+
+	_, putErr := jsonTestMap.Put([]byte("binary"), []byte{ 0, 1, 2, 255, '"', '\n' })
+	if putErr != nil { t.Errorf("error on put: %s", putErr.Error()) }
+
+	openImportMap := func(t *testing.T, name string) *mmcmap.MMCMap {
+		importTestPath := filepath.Join(os.TempDir(), name)
+		os.Remove(importTestPath)
+
+		importTestMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: importTestPath })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+
+		return importTestMap
+	}
+
+	assertImported := func(t *testing.T, importTestMap *mmcmap.MMCMap) {
+		expected, expectedErr := jsonTestMap.Range(nil, nil)
+		if expectedErr != nil { t.Fatalf("error ranging source: %s", expectedErr.Error()) }
+
+		actual, actualErr := importTestMap.Range(nil, nil)
+		if actualErr != nil { t.Fatalf("error ranging imported map: %s", actualErr.Error()) }
+
+		if len(actual) != len(expected) { t.Fatalf("imported key count mismatch: actual(%d), expected(%d)", len(actual), len(expected)) }
+
+		for idx := range expected {
+			if ! bytes.Equal(actual[idx].Key, expected[idx].Key) || ! bytes.Equal(actual[idx].Value, expected[idx].Value) {
+				t.Errorf("imported pair mismatch: actual(%q: %q), expected(%q: %q)", actual[idx].Key, actual[idx].Value, expected[idx].Key, expected[idx].Value)
+			}
+		}
+	}
+
+	t.Run("Test Export Import Base64", func(t *testing.T) {
+		var buf bytes.Buffer
+		exported, exportErr := jsonTestMap.ExportJSON(&buf)
+		if exportErr != nil { t.Fatalf("error on export: %s", exportErr.Error()) }
+		if exported != 501 { t.Errorf("exported count mismatch: actual(%d), expected(501)", exported) }
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(lines) != 501 { t.Errorf("export is not one pair per line: actual(%d), expected(501)", len(lines)) }
+
+		importTestMap := openImportMap(t, "testjsonimport")
+		defer importTestMap.Remove()
+
+		imported, importErr := importTestMap.ImportJSON(&buf)
+		if importErr != nil { t.Fatalf("error on import: %s", importErr.Error()) }
+		if imported != 501 { t.Errorf("imported count mismatch: actual(%d), expected(501)", imported) }
+
+		version, _ := readVersion(importTestMap)
+		if version != 1 { t.Errorf("import without a batch size did not commit a single version: actual(%d), expected(1)", version) }
+
+		assertImported(t, importTestMap)
+	})
+
+	t.Run("Test Export Import Hex In Batches", func(t *testing.T) {
+		opts := mmcmap.JSONOpts{ Encoding: mmcmap.EncodingHex, BatchSize: 100 }
+
+		var buf bytes.Buffer
+		_, exportErr := jsonTestMap.ExportJSON(&buf, opts)
+		if exportErr != nil { t.Fatalf("error on export: %s", exportErr.Error()) }
+		if ! strings.Contains(buf.String(), `"value":"000102ff220a"`) { t.Error("binary value was not hex encoded") }
+
+		importTestMap := openImportMap(t, "testjsonimporthex")
+		defer importTestMap.Remove()
+
+		imported, importErr := importTestMap.ImportJSON(&buf, opts)
+		if importErr != nil { t.Fatalf("error on import: %s", importErr.Error()) }
+		if imported != 501 { t.Errorf("imported count mismatch: actual(%d), expected(501)", imported) }
+
+		version, _ := readVersion(importTestMap)
+		if version != 6 { t.Errorf("import batch versions mismatch: actual(%d), expected(6)", version) }
+
+		assertImported(t, importTestMap)
+	})
+
+	t.Run("Test Import Array", func(t *testing.T) {
+		importTestMap := openImportMap(t, "testjsonimportarray")
+		defer importTestMap.Remove()
+
+		input := ` [ {"key": "6b31", "value": "7631"}, {"key": "6b32", "value": ""} ]`
+		imported, importErr := importTestMap.ImportJSON(strings.NewReader(input), mmcmap.JSONOpts{ Encoding: mmcmap.EncodingHex })
+		if importErr != nil { t.Fatalf("error on import: %s", importErr.Error()) }
+		if imported != 2 { t.Errorf("imported count mismatch: actual(%d), expected(2)", imported) }
+
+		val, getErr := importTestMap.Get([]byte("k1"))
+		if getErr != nil { t.Errorf("error on get: %s", getErr.Error()) }
+		if ! bytes.Equal(val, []byte("v1")) { t.Errorf("value mismatch: actual(%s), expected(v1)", val) }
+
+		val, getErr = importTestMap.Get([]byte("k2"))
+		if getErr != nil { t.Errorf("error on get: %s", getErr.Error()) }
+		if val == nil || len(val) != 0 { t.Errorf("empty value mismatch: actual(%v), expected([])", val) }
+	})
+
+	t.Run("Test Import Invalid", func(t *testing.T) {
+		importTestMap := openImportMap(t, "testjsonimportinvalid")
+		defer importTestMap.Remove()
+
+		input := "{\"key\": \"a2V5\", \"value\": \"dmFs\"}\n{\"key\": \"not base64!\", \"value\": \"\"}\n"
+		imported, importErr := importTestMap.ImportJSON(strings.NewReader(input))
+		if importErr == nil { t.Error("expected an error importing an invalid key") }
+		if imported != 0 { t.Errorf("pairs committed from a failed batch: actual(%d), expected(0)", imported) }
+
+		_, importErr = importTestMap.ImportJSON(strings.NewReader(`{"key": "a2V5", "value": `))
+		if importErr == nil { t.Error("expected an error importing truncated json") }
+	})
+
+	t.Log("Done")
+}
@@ -0,0 +1,136 @@
+package mmcmaptests
+
+import "bytes"
+import "fmt"
+import "os"
+import "path/filepath"
+import "strings"
+import "sync"
+import "testing"
+import "time"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var latencyTestPath = filepath.Join(os.TempDir(), "testlatency")
+var latencyTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initLatencyMapErr error
+	os.Remove(latencyTestPath)
+
+	opts := mmcmap.MMCMapOpts{ Filepath: latencyTestPath, Name: "latency", RecordLatency: true }
+	latencyTestMap, initLatencyMapErr = mmcmap.Open(opts)
+	if initLatencyMapErr != nil { panic(initLatencyMapErr.Error()) }
+
+	fmt.Println("latency test mmcmap initialized")
+}
+
+
+func TestMMCMapLatency(t *testing.T) {
+	defer latencyTestMap.Remove()
+
+	checkSummary := func(t *testing.T, op string, summary mmcmap.OpLatency, expectedCount uint64) {
+		if summary.Count != expectedCount { t.Errorf("%s count mismatch: actual(%d), expected(%d)", op, summary.Count, expectedCount) }
+		if summary.Mean <= 0 || summary.Total < summary.Mean { t.Errorf("%s mean or total not recorded: %+v", op, summary) }
+		if summary.P50 > summary.P99 || summary.P99 > summary.Max { t.Errorf("%s quantiles out of order: %+v", op, summary) }
+	}
+
+	t.Run("Test Records Operations", func(t *testing.T) {
+		for idx := 0; idx < 200; idx++ {
+			key := []byte(fmt.Sprintf("key%d", idx))
+			_, putErr := latencyTestMap.Put(key, key)
+			if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+
+			_, getErr := latencyTestMap.Get(key)
+			if getErr != nil { t.Fatalf("error on get: %s", getErr.Error()) }
+		}
+
+		for idx := 0; idx < 20; idx++ {
+			_, delErr := latencyTestMap.Delete([]byte(fmt.Sprintf("key%d", idx)))
+			if delErr != nil { t.Fatalf("error on delete: %s", delErr.Error()) }
+		}
+
+		_, rangeErr := latencyTestMap.Range([]byte("key"), []byte("key9"))
+		if rangeErr != nil { t.Fatalf("error on range: %s", rangeErr.Error()) }
+
+		stats := latencyTestMap.LatencyStats()
+		checkSummary(t, "put", stats.Put, 200)
+		checkSummary(t, "get", stats.Get, 200)
+		checkSummary(t, "delete", stats.Delete, 20)
+		checkSummary(t, "range", stats.Range, 1)
+	})
+
+	t.Run("Test Retries Per Op", func(t *testing.T) {
+		before, statsErr := latencyTestMap.Stats()
+		if statsErr != nil { t.Fatalf("error on stats: %s", statsErr.Error()) }
+
+		var wg sync.WaitGroup
+		for worker := 0; worker < 8; worker++ {
+			wg.Add(1)
+			go func(worker int) {
+				defer wg.Done()
+				for idx := 0; idx < 100; idx++ {
+					key := []byte(fmt.Sprintf("worker%d-%d", worker, idx))
+					_, putErr := latencyTestMap.Put(key, key)
+					if putErr != nil { t.Errorf("error on put: %s", putErr.Error()) }
+				}
+			}(worker)
+		}
+
+		wg.Wait()
+
+		after, statsErr := latencyTestMap.Stats()
+		if statsErr != nil { t.Fatalf("error on stats: %s", statsErr.Error()) }
+
+		putRetries := after.Latency.Put.Retries - before.Latency.Put.Retries
+		commitRetries := after.CommitRetries - before.CommitRetries
+		if putRetries != commitRetries { t.Errorf("put retries mismatch: actual(%d), expected(%d)", putRetries, commitRetries) }
+		if after.Latency.Put.Count - before.Latency.Put.Count != 800 { t.Errorf("put count mismatch: actual(%d), expected(800)", after.Latency.Put.Count - before.Latency.Put.Count) }
+	})
+
+	t.Run("Test Histogram Quantiles", func(t *testing.T) {
+		var histogram mmcmap.LatencyHistogram
+		for idx := 0; idx < 98; idx++ { histogram.Record(1000 * time.Nanosecond, 0) }
+		histogram.Record(time.Millisecond, 2)
+		histogram.Record(time.Hour, 0)
+
+		summary := histogram.Summary()
+		if summary.Count != 100 || summary.Retries != 2 { t.Errorf("count or retries mismatch: %+v", summary) }
+		if summary.P50 < 1 << 9 || summary.P50 > 1 << 10 { t.Errorf("p50 outside its bucket: actual(%s), expected(%s-%s)", summary.P50, time.Duration(1 << 9), time.Duration(1 << 10)) }
+		if summary.P99 < 1 << 19 || summary.P99 > 1 << 20 { t.Errorf("p99 outside its bucket: actual(%s), expected(%s-%s)", summary.P99, time.Duration(1 << 19), time.Duration(1 << 20)) }
+		if summary.Max != time.Hour { t.Errorf("max mismatch: actual(%s), expected(%s)", summary.Max, time.Hour) }
+
+		var empty mmcmap.LatencyHistogram
+		if empty.Summary() != (mmcmap.OpLatency{}) { t.Errorf("empty histogram summary is not empty: %+v", empty.Summary()) }
+	})
+
+	t.Run("Test Write Prometheus", func(t *testing.T) {
+		var buf bytes.Buffer
+		writeErr := latencyTestMap.WritePrometheus(&buf)
+		if writeErr != nil { t.Fatalf("error writing metrics: %s", writeErr.Error()) }
+
+		output := buf.String()
+		if strings.Count(output, "# TYPE mmcmap_op_latency_seconds summary") != 1 { t.Errorf("latency summary type missing or repeated: %s", output) }
+		for _, sample := range []string{ `mmcmap_op_latency_seconds{map="latency",op="put",quantile="0.99"} `, `mmcmap_op_latency_seconds_count{map="latency",op="get"} 200`, `mmcmap_op_retries_total{map="latency",op="delete"} ` } {
+			if ! strings.Contains(output, sample) { t.Errorf("sample missing: %s", sample) }
+		}
+	})
+
+	t.Run("Test Disabled By Default", func(t *testing.T) {
+		disabledPath := filepath.Join(os.TempDir(), "testlatencydisabled")
+		os.Remove(disabledPath)
+
+		mmcMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: disabledPath })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+		defer mmcMap.Remove()
+
+		_, putErr := mmcMap.Put([]byte("hello"), []byte("world"))
+		if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+
+		if mmcMap.LatencyStats() != (mmcmap.LatencyStats{}) { t.Errorf("latency recorded while disabled: %+v", mmcMap.LatencyStats()) }
+	})
+
+	t.Log("Done")
+}
@@ -0,0 +1,138 @@
+package mmcmaptests
+
+import "errors"
+import "fmt"
+import "os"
+import "path/filepath"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var leafLookupTestPath = filepath.Join(os.TempDir(), "testleaflookup")
+var leafLookupTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initLeafLookupMapErr error
+	os.Remove(leafLookupTestPath)
+
+	opts := mmcmap.MMCMapOpts{ Filepath: leafLookupTestPath, CopyOnRead: mmcmap.CopyNever }
+	leafLookupTestMap, initLeafLookupMapErr = mmcmap.Open(opts)
+	if initLeafLookupMapErr != nil { panic(initLeafLookupMapErr.Error()) }
+
+	fmt.Println("leaf lookup test mmcmap initialized")
+}
+
+
+func TestMMCMapLeafLookup(t *testing.T) {
+	defer leafLookupTestMap.Remove()
+
+	putValues := func(t *testing.T, mmcMap *mmcmap.MMCMap) {
+		for idx := 0; idx < 5000; idx++ {
+			_, putErr := mmcMap.Put([]byte(fmt.Sprintf("key%d", idx)), []byte(fmt.Sprintf("value%d", idx)))
+			if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+		}
+	}
+
+	checkValues := func(t *testing.T, mmcMap *mmcmap.MMCMap) {
+		for idx := 0; idx < 5000; idx++ {
+			value, getErr := mmcMap.Get([]byte(fmt.Sprintf("key%d", idx)))
+			if getErr != nil { t.Fatalf("error on get: %s", getErr.Error()) }
+			if string(value) != fmt.Sprintf("value%d", idx) { t.Fatalf("value mismatch: actual(%s), expected(value%d)", value, idx) }
+		}
+
+		for idx := 5000; idx < 5100; idx++ {
+			value, getErr := mmcMap.Get([]byte(fmt.Sprintf("key%d", idx)))
+			if getErr != nil || value != nil { t.Fatalf("found missing key%d: actual(%s), err(%v)", idx, value, getErr) }
+		}
+
+		keys := [][]byte{ []byte("key1"), []byte("missing"), []byte("key4999") }
+		pairs, getErr := mmcMap.GetMulti(keys)
+		if getErr != nil { t.Fatalf("error on get multi: %s", getErr.Error()) }
+		if string(pairs[0].Value) != "value1" || pairs[1].Value != nil || string(pairs[2].Value) != "value4999" { t.Errorf("get multi mismatch: %v", pairs) }
+	}
+
+	putValues(t, leafLookupTestMap)
+
+	t.Run("Test Lookup", func(t *testing.T) {
+		checkValues(t, leafLookupTestMap)
+	})
+
+	t.Run("Test Lookup Allocations", func(t *testing.T) {
+		key := []byte("key42")
+		allocs := testing.AllocsPerRun(100, func() { leafLookupTestMap.Get(key) })
+
+		// only the leaf is deserialized, so a lookup allocates the same regardless of the depth of the key and the fanout of the nodes above it
+		if allocs > 4 { t.Errorf("get allocated more than the leaf: actual(%v), expected at most(4)", allocs) }
+	})
+
+	t.Run("Test Lookup Ordered Keys", func(t *testing.T) {
+		path := filepath.Join(os.TempDir(), "testleaflookupordered")
+		os.Remove(path)
+
+		orderedMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: path, KeyMode: mmcmap.KeyModeOrdered })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+		defer orderedMap.Remove()
+
+		putValues(t, orderedMap)
+		checkValues(t, orderedMap)
+	})
+
+	t.Run("Test Lookup Compact Encoding", func(t *testing.T) {
+		path := filepath.Join(os.TempDir(), "testleaflookupcompact")
+		os.Remove(path)
+
+		compactMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: path, NodeEncoding: mmcmap.NodeEncodingCompact })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+		defer compactMap.Remove()
+
+		putValues(t, compactMap)
+		checkValues(t, compactMap)
+	})
+
+	t.Run("Test Lookup Pinned And Cached", func(t *testing.T) {
+		path := filepath.Join(os.TempDir(), "testleaflookupcached")
+		os.Remove(path)
+
+		cachedMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: path, NodeCacheSize: 1 << 24, PinnedLevels: 1 })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+		defer cachedMap.Remove()
+
+		putValues(t, cachedMap)
+
+		hits := cachedMap.NodeCacheStats().Hits
+		checkValues(t, cachedMap)
+
+		if cachedMap.NodeCacheStats().Hits <= hits { t.Errorf("lookups did not use the internal nodes cached by the puts") }
+	})
+
+	t.Run("Test Lookup Cyclic Child Offset", func(t *testing.T) {
+		path := filepath.Join(os.TempDir(), "testleaflookupcyclic")
+		os.Remove(path)
+
+		cyclicMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: path, KeyMode: mmcmap.KeyModeOrdered })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+		defer cyclicMap.Remove()
+
+		meta, readMetaErr := cyclicMap.ReadMetaFromMemMap()
+		if readMetaErr != nil { t.Fatalf("error reading metadata: %s", readMetaErr.Error()) }
+
+		// an internal node with every child pointing back at itself, published as the root
+		offset := meta.EndMmapOffset + 1
+		children := make([]*mmcmap.MMCMapNode, 32)
+		for idx := range children { children[idx] = &mmcmap.MMCMapNode{ StartOffset: offset } }
+
+		endOffset, writeErr := cyclicMap.WriteNodeToMemMap(&mmcmap.MMCMapNode{ StartOffset: offset, Bitmap: ^uint32(0), Children: children })
+		if writeErr != nil { t.Fatalf("error writing node: %s", writeErr.Error()) }
+
+		cyclicMeta := &mmcmap.MMCMapMetaData{ Version: meta.Version, RootOffset: offset, EndMmapOffset: endOffset }
+		_, writeMetaErr := cyclicMap.WriteMetaToMemMap(cyclicMeta.SerializeMetaData())
+		if writeMetaErr != nil { t.Fatalf("error writing metadata: %s", writeMetaErr.Error()) }
+
+		_, getErr := cyclicMap.Get([]byte("key"))
+		if ! errors.Is(getErr, mmcmap.ErrCorruptNode) { t.Errorf("get error mismatch: actual(%v), expected(%v)", getErr, mmcmap.ErrCorruptNode) }
+	})
+
+	t.Log("Done")
+}
@@ -0,0 +1,137 @@
+package mmcmaptests
+
+import "fmt"
+import "os"
+import "path/filepath"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var lenTestPath = filepath.Join(os.TempDir(), "testlen")
+var lenTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initLenMapErr error
+	os.Remove(lenTestPath)
+
+	opts := mmcmap.MMCMapOpts{ Filepath: lenTestPath }
+	lenTestMap, initLenMapErr = mmcmap.Open(opts)
+	if initLenMapErr != nil { panic(initLenMapErr.Error()) }
+
+	fmt.Println("len test mmcmap initialized")
+}
+
+
+func TestMMCMapLen(t *testing.T) {
+	defer lenTestMap.Remove()
+
+	assertLen := func(t *testing.T, expected uint64) {
+		keyCount, lenErr := lenTestMap.Len()
+		if lenErr != nil { t.Errorf("error on len: %s", lenErr.Error()) }
+		if keyCount != expected { t.Errorf("key count mismatch: actual(%d), expected(%d)", keyCount, expected) }
+
+		kvPairs, rangeErr := lenTestMap.Range(nil, nil)
+		if rangeErr != nil { t.Errorf("error on range: %s", rangeErr.Error()) }
+		if uint64(len(kvPairs)) != keyCount { t.Errorf("key count does not match range: actual(%d), expected(%d)", keyCount, len(kvPairs)) }
+	}
+
+	t.Run("Test Empty Len", func(t *testing.T) {
+		assertLen(t, 0)
+	})
+
+	t.Run("Test Len After Puts", func(t *testing.T) {
+		for idx := 0; idx < 1000; idx++ {
+			_, putErr := lenTestMap.Put([]byte(fmt.Sprintf("key%d", idx)), []byte("value"))
+			if putErr != nil { t.Errorf("error on put: %s", putErr.Error()) }
+		}
+
+		assertLen(t, 1000)
+	})
+
+	t.Run("Test Overwrite Does Not Change Len", func(t *testing.T) {
+		for idx := 0; idx < 100; idx++ {
+			_, putErr := lenTestMap.Put([]byte(fmt.Sprintf("key%d", idx)), []byte("updated"))
+			if putErr != nil { t.Errorf("error on put: %s", putErr.Error()) }
+		}
+
+		assertLen(t, 1000)
+	})
+
+	t.Run("Test Len After Deletes", func(t *testing.T) {
+		for idx := 0; idx < 250; idx++ {
+			_, delErr := lenTestMap.Delete([]byte(fmt.Sprintf("key%d", idx)))
+			if delErr != nil { t.Errorf("error on delete: %s", delErr.Error()) }
+		}
+
+		_, delErr := lenTestMap.Delete([]byte("missing"))
+		if delErr != nil { t.Errorf("error on delete: %s", delErr.Error()) }
+
+		assertLen(t, 750)
+	})
+
+	t.Run("Test Len After Batch", func(t *testing.T) {
+		batch := lenTestMap.NewWriteBatch()
+		batch.Put([]byte("batch1"), []byte("value"))
+		batch.Put([]byte("batch2"), []byte("value"))
+		batch.Put([]byte("key500"), []byte("updated"))
+		batch.Delete([]byte("key999"))
+		batch.Delete([]byte("key0"))
+
+		_, commitErr := batch.Commit()
+		if commitErr != nil { t.Errorf("error on commit: %s", commitErr.Error()) }
+
+		assertLen(t, 751)
+	})
+
+	t.Run("Test Len Matches Published Root", func(t *testing.T) {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for idx := 0; idx < 2000; idx++ {
+				key := []byte(fmt.Sprintf("published%d", idx))
+				_, putErr := lenTestMap.Put(key, key)
+				if putErr != nil { t.Errorf("error on put: %s", putErr.Error()); return }
+			}
+		}()
+
+		for isDone := false; ! isDone; {
+			select {
+				case <-done:
+					isDone = true
+				default:
+			}
+
+			keyCount, lenErr := lenTestMap.Len()
+			if lenErr != nil { t.Fatalf("error on len: %s", lenErr.Error()) }
+			if keyCount <= 751 { continue }
+
+			// keys are put in order, so the count of a published root covers every key up to the last one put
+			lastKey := []byte(fmt.Sprintf("published%d", keyCount - 752))
+			val, getErr := lenTestMap.Get(lastKey)
+			if getErr != nil { t.Fatalf("error on get: %s", getErr.Error()) }
+			if val == nil { t.Fatalf("key count is ahead of the published root: count(%d), missing(%s)", keyCount, lastKey) }
+		}
+
+		for idx := 0; idx < 2000; idx++ {
+			_, delErr := lenTestMap.Delete([]byte(fmt.Sprintf("published%d", idx)))
+			if delErr != nil { t.Fatalf("error on delete: %s", delErr.Error()) }
+		}
+
+		assertLen(t, 751)
+	})
+
+	t.Run("Test Len Persists Across Reopen", func(t *testing.T) {
+		closeErr := lenTestMap.Close()
+		if closeErr != nil { t.Errorf("error on close: %s", closeErr.Error()) }
+
+		var openErr error
+		lenTestMap, openErr = mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: lenTestPath })
+		if openErr != nil { t.Fatalf("error on reopen: %s", openErr.Error()) }
+
+		assertLen(t, 751)
+	})
+
+	t.Log("Done")
+}
@@ -0,0 +1,136 @@
+package mmcmaptests
+
+import "bytes"
+import "fmt"
+import "os"
+import "path/filepath"
+import "strings"
+import "sync"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var loggerTestPath = filepath.Join(os.TempDir(), "testlogger")
+
+
+type captureLogger struct {
+	lock sync.Mutex
+	events []captureEvent
+}
+
+type captureEvent struct {
+	level mmcmap.LogLevel
+	msg string
+	keyvals []interface{}
+}
+
+func (logger *captureLogger) Log(level mmcmap.LogLevel, msg string, keyvals ...interface{}) {
+	logger.lock.Lock()
+	defer logger.lock.Unlock()
+
+	logger.events = append(logger.events, captureEvent{ level: level, msg: msg, keyvals: keyvals })
+}
+
+func (logger *captureLogger) find(msgPrefix string) (captureEvent, bool) {
+	logger.lock.Lock()
+	defer logger.lock.Unlock()
+
+	for _, event := range logger.events {
+		if strings.HasPrefix(event.msg, msgPrefix) { return event, true }
+	}
+
+	return captureEvent{}, false
+}
+
+func (logger *captureLogger) count() int {
+	logger.lock.Lock()
+	defer logger.lock.Unlock()
+
+	return len(logger.events)
+}
+
+
+func TestMMCMapLogger(t *testing.T) {
+	defer os.Remove(loggerTestPath)
+
+	growMap := func(t *testing.T, mmcMap *mmcmap.MMCMap) {
+		startSize, _ := mmcMap.FileSize()
+		value := bytes.Repeat([]byte("v"), 1024)
+
+		for idx := 0; ; idx++ {
+			_, putErr := mmcMap.Put([]byte(fmt.Sprintf("key%d", idx)), value)
+			if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+
+			size, _ := mmcMap.FileSize()
+			if size > startSize { return }
+		}
+	}
+
+	t.Run("Test Resize Is Logged At Debug", func(t *testing.T) {
+		os.Remove(loggerTestPath)
+
+		logger := &captureLogger{}
+		mmcMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: loggerTestPath, Name: "resized", Logger: logger, LogLevel: mmcmap.LogDebug })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+		defer mmcMap.Close()
+
+		growMap(t, mmcMap)
+
+		event, ok := logger.find("grew memory map")
+		if ! ok { event, ok = logger.find("remapped memory map") }
+		if ! ok { t.Fatalf("resize was not logged: %+v", logger.events) }
+
+		if event.level != mmcmap.LogDebug { t.Errorf("level mismatch: actual(%s), expected(%s)", event.level, mmcmap.LogDebug) }
+		if len(event.keyvals) < 2 || event.keyvals[0] != "map" || event.keyvals[1] != "resized" {
+			t.Errorf("event was not tagged with the map name: actual(%v)", event.keyvals)
+		}
+	})
+
+	t.Run("Test Level Filtering", func(t *testing.T) {
+		os.Remove(loggerTestPath)
+
+		logger := &captureLogger{}
+		mmcMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: loggerTestPath, Logger: logger, LogLevel: mmcmap.LogWarn })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+		defer mmcMap.Close()
+
+		growMap(t, mmcMap)
+
+		printErr := mmcMap.PrintChildren()
+		if printErr != nil { t.Fatalf("error on print children: %s", printErr.Error()) }
+
+		if logger.count() != 0 { t.Errorf("events below the level were logged: %+v", logger.events) }
+	})
+
+	t.Run("Test Print Children Logs Nodes", func(t *testing.T) {
+		os.Remove(loggerTestPath)
+
+		logger := &captureLogger{}
+		mmcMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: loggerTestPath, Logger: logger, LogLevel: mmcmap.LogDebug })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+		defer mmcMap.Close()
+
+		_, putErr := mmcMap.Put([]byte("hello"), []byte("world"))
+		if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+
+		printErr := mmcMap.PrintChildren()
+		if printErr != nil { t.Fatalf("error on print children: %s", printErr.Error()) }
+
+		_, ok := logger.find("child")
+		if ! ok { t.Errorf("children were not logged: %+v", logger.events) }
+	})
+
+	t.Run("Test Writer Logger", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := mmcmap.NewWriterLogger(&buf)
+
+		logger.Log(mmcmap.LogWarn, "something happened", "key", 1, "dangling")
+
+		line := buf.String()
+		if ! strings.HasSuffix(line, " WARN something happened key=1 dangling\n") { t.Errorf("line mismatch: actual(%q)", line) }
+		if strings.Count(line, "\n") != 1 { t.Errorf("expected a single line: actual(%q)", line) }
+	})
+
+	t.Log("Done")
+}
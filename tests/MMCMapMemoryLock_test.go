@@ -0,0 +1,74 @@
+package mmcmaptests
+
+import "fmt"
+import "os"
+import "path/filepath"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var memoryLockTestPath = filepath.Join(os.TempDir(), "testmemorylock")
+
+
+func TestMMCMapMemoryLock(t *testing.T) {
+	defer os.Remove(memoryLockTestPath)
+
+	putValues := func(t *testing.T, mmcMap *mmcmap.MMCMap) {
+		for idx := 0; idx < 1000; idx++ {
+			key := []byte(fmt.Sprintf("key%d", idx))
+			_, putErr := mmcMap.Put(key, key)
+			if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+		}
+	}
+
+	t.Run("Test Lock Prefix", func(t *testing.T) {
+		os.Remove(memoryLockTestPath)
+
+		mmcMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: memoryLockTestPath, LockMemory: mmcmap.LockMemoryPrefix, LockMemoryPrefix: 1 << 20 })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+		defer mmcMap.Close()
+
+		stats := mmcMap.MemoryLockStats()
+		if stats.Mode != mmcmap.LockMemoryPrefix { t.Errorf("mode mismatch: actual(%d), expected(%d)", stats.Mode, mmcmap.LockMemoryPrefix) }
+		if stats.LockedBytes != 1 << 20 && stats.Fallbacks == 0 { t.Errorf("prefix was not locked or counted as a fallback: %+v", stats) }
+		if stats.LockedBytes > 1 << 20 { t.Errorf("more than the prefix was locked: actual(%d), expected(%d)", stats.LockedBytes, 1 << 20) }
+
+		putValues(t, mmcMap)
+	})
+
+	t.Run("Test Lock All Across Remaps", func(t *testing.T) {
+		os.Remove(memoryLockTestPath)
+
+		mmcMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: memoryLockTestPath, LockMemory: mmcmap.LockMemoryAll })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+		defer mmcMap.Close()
+
+		putValues(t, mmcMap)
+
+		_, compactErr := mmcMap.Compact()
+		if compactErr != nil { t.Fatalf("error on compact: %s", compactErr.Error()) }
+
+		size, _ := mmcMap.FileSize()
+		stats := mmcMap.MemoryLockStats()
+		if stats.LockedBytes != uint64(size) && stats.Fallbacks == 0 { t.Errorf("remapped file was not locked or counted as a fallback: size(%d), %+v", size, stats) }
+
+		value, getErr := mmcMap.Get([]byte("key1"))
+		if getErr != nil || string(value) != "key1" { t.Errorf("value mismatch after remap: actual(%s), expected(key1)", value) }
+	})
+
+	t.Run("Test Off By Default", func(t *testing.T) {
+		os.Remove(memoryLockTestPath)
+
+		mmcMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: memoryLockTestPath })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+		defer mmcMap.Close()
+
+		if stats := mmcMap.MemoryLockStats(); stats.LockedBytes != 0 || stats.Fallbacks != 0 { t.Errorf("memory was locked without LockMemory: %+v", stats) }
+
+		_, openErr = mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: memoryLockTestPath, LockMemory: mmcmap.LockMemoryPrefix + 1 })
+		if openErr != mmcmap.ErrUnknownLockMemoryMode { t.Errorf("open error mismatch: actual(%v), expected(%v)", openErr, mmcmap.ErrUnknownLockMemoryMode) }
+	})
+
+	t.Log("Done")
+}
@@ -0,0 +1,102 @@
+package mmcmaptests
+
+import "fmt"
+import "os"
+import "path/filepath"
+import "sync"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var mgTestPath = filepath.Join(os.TempDir(), "testmerge")
+var mergeTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initMgMapErr error
+	os.Remove(mgTestPath)
+
+	appendMerge := func(existing, operand []byte) []byte {
+		merged := make([]byte, 0, len(existing) + len(operand))
+		merged = append(merged, existing...)
+		return append(merged, operand...)
+	}
+
+	opts := mmcmap.MMCMapOpts{ Filepath: mgTestPath, MergeFunc: appendMerge }
+	mergeTestMap, initMgMapErr = mmcmap.Open(opts)
+	if initMgMapErr != nil { panic(initMgMapErr.Error()) }
+
+	fmt.Println("merge test mmcmap initialized")
+}
+
+
+func TestMMCMapMerge(t *testing.T) {
+	defer mergeTestMap.Remove()
+
+	t.Run("Test Merge", func(t *testing.T) {
+		for _, operand := range []string{ "a", "b", "c" } {
+			_, mergeErr := mergeTestMap.Merge([]byte("appended"), []byte(operand))
+			if mergeErr != nil { t.Errorf("error on merge: %s", mergeErr.Error()) }
+		}
+
+		val, getErr := mergeTestMap.Get([]byte("appended"))
+		if getErr != nil { t.Errorf("error getting from mmcmap: %s", getErr.Error()) }
+		if string(val) != "abc" { t.Errorf("merged value mismatch: actual(%s), expected(abc)", val) }
+	})
+
+	t.Run("Test Concurrent Merge", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for range make([]int, 8) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for range make([]int, 25) {
+					_, mergeErr := mergeTestMap.Merge([]byte("concurrent"), []byte("x"))
+					if mergeErr != nil { t.Errorf("error on merge: %s", mergeErr.Error()) }
+				}
+			}()
+		}
+
+		wg.Wait()
+
+		val, getErr := mergeTestMap.Get([]byte("concurrent"))
+		if getErr != nil { t.Errorf("error getting from mmcmap: %s", getErr.Error()) }
+		if len(val) != 200 { t.Errorf("lost merge operands: actual(%d), expected(200)", len(val)) }
+	})
+
+	t.Run("Test Append In Place", func(t *testing.T) {
+		path := filepath.Join(os.TempDir(), "testmergeappend")
+		os.Remove(path)
+
+		plainAppend := func(existing, operand []byte) []byte { return append(existing, operand...) }
+		appendMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: path, MergeFunc: plainAppend })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+		defer appendMap.Remove()
+
+		for idx := 0; idx < 50; idx++ {
+			key := []byte(fmt.Sprintf("key%d", idx))
+			_, putErr := appendMap.Put(key, key)
+			if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+		}
+
+		for round := 0; round < 3; round++ {
+			for idx := 0; idx < 50; idx++ {
+				_, mergeErr := appendMap.Merge([]byte(fmt.Sprintf("key%d", idx)), []byte("+"))
+				if mergeErr != nil { t.Fatalf("error on merge: %s", mergeErr.Error()) }
+			}
+		}
+
+		for idx := 0; idx < 50; idx++ {
+			val, getErr := appendMap.Get([]byte(fmt.Sprintf("key%d", idx)))
+			if getErr != nil { t.Errorf("error getting from mmcmap: %s", getErr.Error()) }
+			if expected := fmt.Sprintf("key%d+++", idx); string(val) != expected { t.Errorf("merged value mismatch: actual(%s), expected(%s)", val, expected) }
+		}
+
+		report, verifyErr := appendMap.Verify()
+		if verifyErr != nil { t.Fatalf("error on verify: %s", verifyErr.Error()) }
+		if ! report.IsValid { t.Errorf("verify found inconsistencies: %v", report.Findings) }
+	})
+
+	t.Log("Done")
+}
@@ -0,0 +1,135 @@
+package mmcmaptests
+
+import "errors"
+import "fmt"
+import "os"
+import "path/filepath"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var migrateTestPath = filepath.Join(os.TempDir(), "testmigrate")
+
+
+// writeLegacyFile writes an empty map laid out as before the header existed
+func writeLegacyFile(t *testing.T, path string) {
+	meta := &mmcmap.MMCMapMetaData{ Version: 0, RootOffset: mmcmap.LegacyInitRootOffset, EndMmapOffset: mmcmap.LegacyInitRootOffset + mmcmap.NodeChildrenIdx }
+	root := &mmcmap.MMCMapNode{ StartOffset: mmcmap.LegacyInitRootOffset, Children: []*mmcmap.MMCMapNode{} }
+
+	sRoot, serializeErr := root.SerializeNode(mmcmap.LegacyInitRootOffset)
+	if serializeErr != nil { t.Fatalf("error serializing root: %s", serializeErr.Error()) }
+
+	legacy := make([]byte, 1 << 20)
+	copy(legacy, meta.SerializeMetaData())
+	copy(legacy[mmcmap.LegacyInitRootOffset:], sRoot)
+
+	writeErr := os.WriteFile(path, legacy, 0600)
+	if writeErr != nil { t.Fatalf("error writing legacy file: %s", writeErr.Error()) }
+}
+
+// writeFormatVersion overwrites the format version in the header of a closed file
+func writeFormatVersion(t *testing.T, path string, formatVersion uint16) {
+	file, fileErr := os.OpenFile(path, os.O_RDWR, 0600)
+	if fileErr != nil { t.Fatalf("error opening file: %s", fileErr.Error()) }
+	defer file.Close()
+
+	_, writeErr := file.WriteAt([]byte{ byte(formatVersion), byte(formatVersion >> 8) }, mmcmap.HeaderFormatVersionIdx)
+	if writeErr != nil { t.Fatalf("error writing format version: %s", writeErr.Error()) }
+}
+
+
+func TestMMCMapMigrate(t *testing.T) {
+	defer os.Remove(migrateTestPath)
+
+	putKeys := func(t *testing.T, opts mmcmap.MMCMapOpts) {
+		mmcMap, openErr := mmcmap.Open(opts)
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+		defer mmcMap.Close()
+
+		for idx := 0; idx < 100; idx++ {
+			key := []byte(fmt.Sprintf("key%d", idx))
+			_, putErr := mmcMap.Put(key, key)
+			if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+		}
+	}
+
+	checkKeys := func(t *testing.T) *mmcmap.MMCMap {
+		mmcMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: migrateTestPath })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+
+		if mmcMap.Header.FormatVersion != mmcmap.HeaderFormatVersion {
+			t.Errorf("format version mismatch: actual(%d), expected(%d)", mmcMap.Header.FormatVersion, mmcmap.HeaderFormatVersion)
+		}
+
+		length, _ := mmcMap.Len()
+		if length != 100 { t.Errorf("length mismatch: actual(%d), expected(100)", length) }
+
+		for idx := 0; idx < 100; idx++ {
+			key := fmt.Sprintf("key%d", idx)
+			value, getErr := mmcMap.Get([]byte(key))
+			if getErr != nil { t.Fatalf("error on get: %s", getErr.Error()) }
+			if string(value) != key { t.Fatalf("value mismatch: actual(%s), expected(%s)", value, key) }
+		}
+
+		return mmcMap
+	}
+
+	t.Run("Test Migrate Legacy File", func(t *testing.T) {
+		os.Remove(migrateTestPath)
+		writeLegacyFile(t, migrateTestPath)
+		putKeys(t, mmcmap.MMCMapOpts{ Filepath: migrateTestPath })
+
+		report, migrateErr := mmcmap.Migrate(migrateTestPath)
+		if migrateErr != nil { t.Fatalf("error on migrate: %s", migrateErr.Error()) }
+		if report.PrevFormatVersion != 0 || ! report.IsRelocated { t.Errorf("report mismatch: actual(%+v)", report) }
+
+		migrated := checkKeys(t)
+		defer migrated.Close()
+
+		if migrated.HeaderSize != mmcmap.InitRootOffset { t.Errorf("header size mismatch: actual(%d), expected(%d)", migrated.HeaderSize, mmcmap.InitRootOffset) }
+
+		_, putErr := migrated.Put([]byte("after"), []byte("migrate"))
+		if putErr != nil { t.Errorf("error on put after migrate: %s", putErr.Error()) }
+
+		length, _ := migrated.Len()
+		if length != 101 { t.Errorf("length after put mismatch: actual(%d), expected(101)", length) }
+	})
+
+	t.Run("Test Migrate Untracked Key Count", func(t *testing.T) {
+		os.Remove(migrateTestPath)
+		putKeys(t, mmcmap.MMCMapOpts{ Filepath: migrateTestPath })
+
+		writeFormatVersion(t, migrateTestPath, 1)
+
+		file, fileErr := os.OpenFile(migrateTestPath, os.O_RDWR, 0600)
+		if fileErr != nil { t.Fatalf("error opening file: %s", fileErr.Error()) }
+		file.WriteAt(make([]byte, 8), mmcmap.HeaderKeyCountIdx)
+		file.Close()
+
+		report, migrateErr := mmcmap.Migrate(migrateTestPath)
+		if migrateErr != nil { t.Fatalf("error on migrate: %s", migrateErr.Error()) }
+		if report.PrevFormatVersion != 1 || report.IsRelocated { t.Errorf("report mismatch: actual(%+v)", report) }
+
+		checkKeys(t).Close()
+
+		report, migrateErr = mmcmap.Migrate(migrateTestPath)
+		if migrateErr != nil { t.Fatalf("error on second migrate: %s", migrateErr.Error()) }
+		if report.PrevFormatVersion != mmcmap.HeaderFormatVersion { t.Errorf("current file was migrated again: actual(%+v)", report) }
+	})
+
+	t.Run("Test Newer Format Rejected", func(t *testing.T) {
+		os.Remove(migrateTestPath)
+		putKeys(t, mmcmap.MMCMapOpts{ Filepath: migrateTestPath })
+
+		writeFormatVersion(t, migrateTestPath, mmcmap.HeaderFormatVersion + 1)
+
+		_, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: migrateTestPath })
+		if ! errors.Is(openErr, mmcmap.ErrUnsupportedFormatVersion) { t.Errorf("open error mismatch: actual(%v), expected(%v)", openErr, mmcmap.ErrUnsupportedFormatVersion) }
+
+		_, migrateErr := mmcmap.Migrate(migrateTestPath)
+		if ! errors.Is(migrateErr, mmcmap.ErrUnsupportedFormatVersion) { t.Errorf("migrate error mismatch: actual(%v), expected(%v)", migrateErr, mmcmap.ErrUnsupportedFormatVersion) }
+	})
+
+	t.Log("Done")
+}
@@ -0,0 +1,132 @@
+package mmcmaptests
+
+import "fmt"
+import "os"
+import "path/filepath"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var nodeCacheTestPath = filepath.Join(os.TempDir(), "testnodecache")
+var nodeCacheTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initNodeCacheMapErr error
+	os.Remove(nodeCacheTestPath)
+
+	opts := mmcmap.MMCMapOpts{ Filepath: nodeCacheTestPath, NodeCacheSize: 1 << 20 }
+	nodeCacheTestMap, initNodeCacheMapErr = mmcmap.Open(opts)
+	if initNodeCacheMapErr != nil { panic(initNodeCacheMapErr.Error()) }
+
+	fmt.Println("node cache test mmcmap initialized")
+}
+
+
+func TestMMCMapNodeCache(t *testing.T) {
+	defer nodeCacheTestMap.Remove()
+
+	putValues := func(t *testing.T, mmcMap *mmcmap.MMCMap, round int) {
+		for idx := 0; idx < 1000; idx++ {
+			_, putErr := mmcMap.Put([]byte(fmt.Sprintf("key%d", idx)), []byte(fmt.Sprintf("value%d-%d", idx, round)))
+			if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+		}
+	}
+
+	checkValues := func(t *testing.T, mmcMap *mmcmap.MMCMap, round int) {
+		for idx := 0; idx < 1000; idx++ {
+			key := fmt.Sprintf("key%d", idx)
+			expected := fmt.Sprintf("value%d-%d", idx, round)
+
+			value, getErr := mmcMap.Get([]byte(key))
+			if getErr != nil { t.Fatalf("error on get: %s", getErr.Error()) }
+			if string(value) != expected { t.Fatalf("value mismatch for %s: actual(%s), expected(%s)", key, value, expected) }
+		}
+	}
+
+	putValues(t, nodeCacheTestMap, 0)
+
+	t.Run("Test Cached Reads", func(t *testing.T) {
+		checkValues(t, nodeCacheTestMap, 0)
+		checkValues(t, nodeCacheTestMap, 0)
+
+		stats := nodeCacheTestMap.NodeCacheStats()
+		if stats.Hits == 0 { t.Errorf("no reads were served from the cache: %+v", stats) }
+		if stats.Entries == 0 || stats.Bytes > 1 << 20 { t.Errorf("cache size out of bounds: %+v", stats) }
+
+		putValues(t, nodeCacheTestMap, 1)
+		checkValues(t, nodeCacheTestMap, 1)
+	})
+
+	t.Run("Test Eviction", func(t *testing.T) {
+		path := filepath.Join(os.TempDir(), "testnodecachesmall")
+		os.Remove(path)
+
+		smallMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: path, NodeCacheSize: 4096 })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+		defer smallMap.Remove()
+
+		putValues(t, smallMap, 0)
+		checkValues(t, smallMap, 0)
+
+		stats := smallMap.NodeCacheStats()
+		if stats.Evictions == 0 { t.Errorf("no nodes were evicted: %+v", stats) }
+		if stats.Bytes > 4096 { t.Errorf("cache exceeded its budget: actual(%d), expected(<= 4096)", stats.Bytes) }
+	})
+
+	t.Run("Test Purged On Compact", func(t *testing.T) {
+		purges := nodeCacheTestMap.NodeCacheStats().Purges
+
+		_, compactErr := nodeCacheTestMap.Compact()
+		if compactErr != nil { t.Fatalf("error on compact: %s", compactErr.Error()) }
+
+		if nodeCacheTestMap.NodeCacheStats().Purges == purges { t.Errorf("cache was not purged by compact") }
+		checkValues(t, nodeCacheTestMap, 1)
+
+		putValues(t, nodeCacheTestMap, 2)
+		checkValues(t, nodeCacheTestMap, 2)
+	})
+
+	t.Run("Test Free List Reuse", func(t *testing.T) {
+		path := filepath.Join(os.TempDir(), "testnodecachefreelist")
+		os.Remove(path)
+
+		freeListMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: path, Allocator: mmcmap.FreeListAllocator{}, NodeCacheSize: 1 << 20 })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+		defer freeListMap.Remove()
+
+		for round := 0; round < 3; round++ {
+			putValues(t, freeListMap, round)
+			checkValues(t, freeListMap, round)
+		}
+
+		_, reclaimErr := freeListMap.Reclaim()
+		if reclaimErr != nil { t.Fatalf("error on reclaim: %s", reclaimErr.Error()) }
+
+		for round := 3; round < 6; round++ {
+			putValues(t, freeListMap, round)
+			checkValues(t, freeListMap, round)
+		}
+
+		report, verifyErr := freeListMap.Verify()
+		if verifyErr != nil { t.Fatalf("error on verify: %s", verifyErr.Error()) }
+		if ! report.IsValid { t.Errorf("map invalid: %v", report.Findings) }
+	})
+
+	t.Run("Test Disabled", func(t *testing.T) {
+		path := filepath.Join(os.TempDir(), "testnodecachedisabled")
+		os.Remove(path)
+
+		uncachedMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: path })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+		defer uncachedMap.Remove()
+
+		putValues(t, uncachedMap, 0)
+		checkValues(t, uncachedMap, 0)
+
+		if stats := uncachedMap.NodeCacheStats(); stats != (mmcmap.NodeCacheStats{}) { t.Errorf("disabled cache reported stats: %+v", stats) }
+	})
+
+	t.Log("Done")
+}
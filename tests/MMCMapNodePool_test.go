@@ -0,0 +1,46 @@
+package mmcmaptests
+
+import "errors"
+import "os"
+import "path/filepath"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+
+
+func TestMMCMapNodePool(t *testing.T) {
+	t.Run("Test Adaptive Pool Grows", func(t *testing.T) {
+		pool := mmcmap.NewAdaptiveNodePool(0, 4 * mmcmap.MinNodePoolSize)
+
+		// nodes are held by callers and never returned, so every request misses
+		for idx := 0; idx < 2 * mmcmap.NodePoolWindow; idx++ { pool.Get() }
+
+		if pool.Grows == 0 { t.Errorf("pool did not grow under misses: %+v", pool) }
+		if pool.MaxSize == 0 || pool.MaxSize > pool.Limit { t.Errorf("pool size out of range: actual(%d), limit(%d)", pool.MaxSize, pool.Limit) }
+	})
+
+	t.Run("Test Pool Size From Options", func(t *testing.T) {
+		path := filepath.Join(os.TempDir(), "testnodepool")
+		mmcmap.Destroy(path)
+
+		opts := mmcmap.MMCMapOpts{ Filepath: path, NodePoolSize: 2048, NodePoolAdaptive: true, NodePoolMaxSize: 8192 }
+		mmcMap, openErr := mmcmap.Open(opts)
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+		defer mmcMap.Remove()
+
+		_, putErr := mmcMap.Put([]byte("key"), []byte("value"))
+		if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+
+		stats, statsErr := mmcMap.Stats()
+		if statsErr != nil { t.Fatalf("error on stats: %s", statsErr.Error()) }
+		if stats.NodePoolSize != 2048 { t.Errorf("node pool size mismatch: actual(%d), expected(2048)", stats.NodePoolSize) }
+		if mmcMap.NodePool.Limit != 8192 { t.Errorf("node pool limit mismatch: actual(%d), expected(8192)", mmcMap.NodePool.Limit) }
+	})
+
+	t.Run("Test Negative Pool Size", func(t *testing.T) {
+		_, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: filepath.Join(os.TempDir(), "testnodepoolnegative"), NodePoolSize: -1 })
+		if ! errors.Is(openErr, mmcmap.ErrInvalidSizeLimit) { t.Errorf("open error mismatch: actual(%v), expected(%v)", openErr, mmcmap.ErrInvalidSizeLimit) }
+	})
+
+	t.Log("Done")
+}
@@ -0,0 +1,189 @@
+package mmcmaptests
+
+import "errors"
+import "fmt"
+import "os"
+import "path/filepath"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var oTestPath = filepath.Join(os.TempDir(), "testopen")
+
+
+func TestMMCMapLazyOpen(t *testing.T) {
+	os.Remove(oTestPath)
+	defer os.Remove(oTestPath)
+
+	eagerMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: oTestPath })
+	if openErr != nil { t.Fatalf("error opening mmcmap: %s", openErr.Error()) }
+
+	for idx := range make([]int, 10) {
+		key := []byte(fmt.Sprintf("lazy%d", idx))
+		_, putErr := eagerMap.Put(key, key)
+		if putErr != nil { t.Errorf("error putting key in mmcmap: %s", putErr.Error()) }
+	}
+
+	closeErr := eagerMap.Close()
+	if closeErr != nil { t.Errorf("error closing mmcmap: %s", closeErr.Error()) }
+
+	t.Run("Test Lazy Open Defers Work", func(t *testing.T) {
+		lazyMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: oTestPath, OpenMode: mmcmap.OpenLazy })
+		if openErr != nil { t.Fatalf("error opening mmcmap lazily: %s", openErr.Error()) }
+		defer lazyMap.Close()
+
+		if lazyMap.NodePool != nil { t.Error("node pool allocated before first use") }
+
+		val, getErr := lazyMap.Get([]byte("lazy3"))
+		if getErr != nil { t.Errorf("error getting key from lazily opened mmcmap: %s", getErr.Error()) }
+		if string(val) != "lazy3" { t.Errorf("value does not match: actual(%s), expected(lazy3)", val) }
+
+		if lazyMap.NodePool == nil { t.Error("node pool not allocated after first use") }
+
+		_, putErr := lazyMap.Put([]byte("lazy10"), []byte("lazy10"))
+		if putErr != nil { t.Errorf("error putting key in lazily opened mmcmap: %s", putErr.Error()) }
+	})
+
+	t.Run("Test Open Rejects Corrupt Metadata", func(t *testing.T) {
+		file, fileErr := os.OpenFile(oTestPath, os.O_RDWR, 0600)
+		if fileErr != nil { t.Fatalf("error opening file: %s", fileErr.Error()) }
+
+		_, writeErr := file.WriteAt([]byte{ 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f }, mmcmap.MetaRootOffsetIdx)
+		if writeErr != nil { t.Fatalf("error corrupting metadata: %s", writeErr.Error()) }
+		file.Close()
+
+		_, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: oTestPath, OpenMode: mmcmap.OpenLazy })
+		if openErr == nil { t.Error("expected error opening mmcmap with corrupt metadata") }
+	})
+
+	t.Log("Done")
+}
+
+func TestMMCMapHeader(t *testing.T) {
+	os.Remove(oTestPath)
+	defer os.Remove(oTestPath)
+
+	t.Run("Test Header Persists Allocator", func(t *testing.T) {
+		headerMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: oTestPath })
+		if openErr != nil { t.Fatalf("error opening mmcmap: %s", openErr.Error()) }
+
+		if headerMap.HeaderSize != mmcmap.InitRootOffset { t.Errorf("unexpected header size: actual(%d), expected(%d)", headerMap.HeaderSize, mmcmap.InitRootOffset) }
+		if headerMap.Header.AllocatorID != mmcmap.AllocAppend { t.Errorf("unexpected allocator: actual(%d), expected(%d)", headerMap.Header.AllocatorID, mmcmap.AllocAppend) }
+
+		closeErr := headerMap.Close()
+		if closeErr != nil { t.Errorf("error closing mmcmap: %s", closeErr.Error()) }
+
+		_, openErr = mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: oTestPath, Allocator: testAllocator{} })
+		if openErr != mmcmap.ErrAllocatorMismatch { t.Errorf("expected allocator mismatch error, got: %v", openErr) }
+	})
+
+	t.Run("Test Open Legacy File", func(t *testing.T) {
+		os.Remove(oTestPath)
+
+		meta := &mmcmap.MMCMapMetaData{ Version: 0, RootOffset: mmcmap.LegacyInitRootOffset, EndMmapOffset: mmcmap.LegacyInitRootOffset + mmcmap.NodeChildrenIdx }
+		root := &mmcmap.MMCMapNode{ StartOffset: mmcmap.LegacyInitRootOffset, Children: []*mmcmap.MMCMapNode{} }
+
+		sRoot, serializeErr := root.SerializeNode(mmcmap.LegacyInitRootOffset)
+		if serializeErr != nil { t.Fatalf("error serializing root: %s", serializeErr.Error()) }
+
+		legacy := make([]byte, 1 << 20)
+		copy(legacy, meta.SerializeMetaData())
+		copy(legacy[mmcmap.LegacyInitRootOffset:], sRoot)
+
+		writeErr := os.WriteFile(oTestPath, legacy, 0600)
+		if writeErr != nil { t.Fatalf("error writing legacy file: %s", writeErr.Error()) }
+
+		legacyMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: oTestPath })
+		if openErr != nil { t.Fatalf("error opening legacy file: %s", openErr.Error()) }
+		defer legacyMap.Close()
+
+		if legacyMap.HeaderSize != mmcmap.LegacyInitRootOffset { t.Errorf("unexpected header size: actual(%d), expected(%d)", legacyMap.HeaderSize, mmcmap.LegacyInitRootOffset) }
+
+		_, putErr := legacyMap.Put([]byte("legacy"), []byte("value"))
+		if putErr != nil { t.Errorf("error putting key in legacy mmcmap: %s", putErr.Error()) }
+
+		val, getErr := legacyMap.Get([]byte("legacy"))
+		if getErr != nil { t.Errorf("error getting key from legacy mmcmap: %s", getErr.Error()) }
+		if string(val) != "value" { t.Errorf("value does not match: actual(%s), expected(value)", val) }
+	})
+
+	t.Log("Done")
+}
+
+// testAllocator is an append allocator registered under a different id
+type testAllocator struct {
+	mmcmap.AppendAllocator
+}
+
+func (testAllocator) ID() mmcmap.AllocatorID {
+	return mmcmap.AllocatorID(200)
+}
+
+func TestMMCMapOpenCheck(t *testing.T) {
+	os.Remove(oTestPath)
+	defer os.Remove(oTestPath)
+
+	checkMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: oTestPath })
+	if openErr != nil { t.Fatalf("error opening mmcmap: %s", openErr.Error()) }
+
+	for idx := range make([]int, 200) {
+		key := []byte(fmt.Sprintf("check%d", idx))
+		_, putErr := checkMap.Put(key, key)
+		if putErr != nil { t.Errorf("error putting key in mmcmap: %s", putErr.Error()) }
+	}
+
+	meta, readMetaErr := checkMap.ReadMetaFromMemMap()
+	if readMetaErr != nil { t.Fatalf("error reading metadata: %s", readMetaErr.Error()) }
+
+	root, readRootErr := checkMap.ReadNodeFromMemMap(meta.RootOffset)
+	if readRootErr != nil { t.Fatalf("error reading root: %s", readRootErr.Error()) }
+
+	closeErr := checkMap.Close()
+	if closeErr != nil { t.Errorf("error closing mmcmap: %s", closeErr.Error()) }
+
+	t.Run("Test Deep Check On Valid File", func(t *testing.T) {
+		deepMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: oTestPath, OpenCheck: mmcmap.OpenCheckDeep })
+		if openErr != nil { t.Fatalf("error opening mmcmap with deep check: %s", openErr.Error()) }
+		defer deepMap.Close()
+
+		report := deepMap.OpenReport
+		if len(report.Findings) != 0 { t.Errorf("unexpected findings on valid file: %v", report.Findings) }
+		if ! report.IsComplete { t.Error("deep check did not complete on a small file") }
+		if report.NodesVisited < 200 { t.Errorf("deep check visited too few nodes: actual(%d), expected at least(200)", report.NodesVisited) }
+	})
+
+	t.Run("Test Deep Check Reports Corrupt Node", func(t *testing.T) {
+		file, fileErr := os.OpenFile(oTestPath, os.O_RDWR, 0600)
+		if fileErr != nil { t.Fatalf("error opening file: %s", fileErr.Error()) }
+
+		_, writeErr := file.WriteAt([]byte{ 1, 2, 3, 4, 5, 6, 7, 8 }, int64(root.Children[0].StartOffset) + mmcmap.NodeStartOffsetIdx)
+		if writeErr != nil { t.Fatalf("error corrupting node: %s", writeErr.Error()) }
+		file.Close()
+
+		quickMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: oTestPath, OpenCheck: mmcmap.OpenCheckQuick })
+		if openErr != nil { t.Fatalf("quick check failed on a file with a valid root: %s", openErr.Error()) }
+		quickMap.Close()
+
+		deepMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: oTestPath, OpenCheck: mmcmap.OpenCheckDeep })
+		if openErr != nil { t.Fatalf("error opening mmcmap with deep check: %s", openErr.Error()) }
+		defer deepMap.Close()
+
+		if len(deepMap.OpenReport.Findings) == 0 { t.Error("deep check did not report corrupt node") }
+	})
+
+	t.Run("Test Quick Check Rejects Corrupt Root", func(t *testing.T) {
+		file, fileErr := os.OpenFile(oTestPath, os.O_RDWR, 0600)
+		if fileErr != nil { t.Fatalf("error opening file: %s", fileErr.Error()) }
+
+		corruptMeta := &mmcmap.MMCMapMetaData{ Version: meta.Version, RootOffset: meta.RootOffset + 1, EndMmapOffset: meta.EndMmapOffset }
+		_, writeErr := file.WriteAt(corruptMeta.SerializeMetaData(), mmcmap.MetaVersionIdx)
+		if writeErr != nil { t.Fatalf("error corrupting metadata: %s", writeErr.Error()) }
+		file.Close()
+
+		_, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: oTestPath, OpenCheck: mmcmap.OpenCheckQuick })
+		if ! errors.Is(openErr, mmcmap.ErrOpenCheckFailed) { t.Errorf("expected open check error, got: %v", openErr) }
+	})
+
+	t.Log("Done")
+}
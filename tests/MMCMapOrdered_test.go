@@ -0,0 +1,253 @@
+package mmcmaptests
+
+import "bytes"
+import "fmt"
+import "math/rand"
+import "os"
+import "path/filepath"
+import "sort"
+import "testing"
+import "time"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var orderedTestPath = filepath.Join(os.TempDir(), "testordered")
+var orderedTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initOrderedMapErr error
+	os.Remove(orderedTestPath)
+
+	opts := mmcmap.MMCMapOpts{ Filepath: orderedTestPath, KeyMode: mmcmap.KeyModeOrdered }
+	orderedTestMap, initOrderedMapErr = mmcmap.Open(opts)
+	if initOrderedMapErr != nil { panic(initOrderedMapErr.Error()) }
+
+	fmt.Println("ordered test mmcmap initialized")
+}
+
+
+func TestMMCMapOrdered(t *testing.T) {
+	defer orderedTestMap.Remove()
+
+	random := rand.New(rand.NewSource(1))
+
+	keys := []string{ "a", "ab", "abc", "b", "\x00", "\x00\x00", "\xff", "\xff\xff" }
+	for idx := 0; idx < 5000; idx++ {
+		key := make([]byte, 1 + random.Intn(12))
+		random.Read(key)
+		keys = append(keys, string(key))
+	}
+
+	unique := make(map[string]bool)
+	for _, key := range keys { unique[key] = true }
+
+	sorted := make([]string, 0, len(unique))
+	for key := range unique { sorted = append(sorted, key) }
+	sort.Strings(sorted)
+
+	for _, key := range keys {
+		_, putErr := orderedTestMap.Put([]byte(key), []byte("v" + key))
+		if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+	}
+
+	checkValues := func(t *testing.T, mmcMap *mmcmap.MMCMap) {
+		for _, key := range sorted {
+			value, getErr := mmcMap.Get([]byte(key))
+			if getErr != nil { t.Fatalf("error on get: %s", getErr.Error()) }
+			if string(value) != "v" + key { t.Fatalf("value mismatch for %x: actual(%x), expected(v%x)", key, value, key) }
+		}
+	}
+
+	checkKeys := func(t *testing.T, actual [][]byte, expected []string) {
+		if len(actual) != len(expected) { t.Fatalf("length mismatch: actual(%d), expected(%d)", len(actual), len(expected)) }
+		for idx, key := range actual {
+			if string(key) != expected[idx] { t.Fatalf("key mismatch at %d: actual(%x), expected(%x)", idx, key, expected[idx]) }
+		}
+	}
+
+	t.Run("Test Reads", func(t *testing.T) {
+		checkValues(t, orderedTestMap)
+
+		value, _ := orderedTestMap.Get([]byte("abcd"))
+		if value != nil { t.Errorf("missing key sharing a prefix is present: %s", value) }
+
+		report, verifyErr := orderedTestMap.Verify()
+		if verifyErr != nil { t.Fatalf("error on verify: %s", verifyErr.Error()) }
+		if ! report.IsValid { t.Errorf("verify found inconsistencies: %v", report.Findings) }
+	})
+
+	t.Run("Test Range", func(t *testing.T) {
+		keys, rangeErr := orderedTestMap.Keys(nil, nil)
+		if rangeErr != nil { t.Fatalf("error on range: %s", rangeErr.Error()) }
+		checkKeys(t, keys, sorted)
+
+		startKey, endKey := []byte("a"), []byte("b")
+		lower := sort.SearchStrings(sorted, "a")
+		upper := sort.SearchStrings(sorted, "b\x00")
+
+		keys, rangeErr = orderedTestMap.Keys(startKey, endKey)
+		if rangeErr != nil { t.Fatalf("error on range: %s", rangeErr.Error()) }
+		checkKeys(t, keys, sorted[lower:upper])
+
+		pairs, streamErrs := orderedTestMap.RangeChan(nil, nil)
+
+		var streamed [][]byte
+		for pair := range pairs { streamed = append(streamed, pair.Key) }
+		for streamErr := range streamErrs { t.Fatalf("error on range chan: %s", streamErr.Error()) }
+		checkKeys(t, streamed, sorted)
+	})
+
+	t.Run("Test Range Pages", func(t *testing.T) {
+		for _, reverse := range []bool{ false, true } {
+			expected := append([]string{}, sorted...)
+			if reverse { sort.Sort(sort.Reverse(sort.StringSlice(expected))) }
+
+			var paged [][]byte
+			var after []byte
+			for {
+				page, next, pageErr := orderedTestMap.RangePage(nil, nil, mmcmap.RangeOpts{ Limit: 97, Reverse: reverse, After: after })
+				if pageErr != nil { t.Fatalf("error on range page: %s", pageErr.Error()) }
+
+				for _, pair := range page { paged = append(paged, pair.Key) }
+				if next == nil { break }
+				after = next
+			}
+
+			checkKeys(t, paged, expected)
+		}
+	})
+
+	t.Run("Test First And Last", func(t *testing.T) {
+		first, firstErr := orderedTestMap.First()
+		if firstErr != nil { t.Fatalf("error on first: %s", firstErr.Error()) }
+		if first == nil || string(first.Key) != sorted[0] || string(first.Value) != "v" + sorted[0] { t.Errorf("first mismatch: actual(%v), expected(%x)", first, sorted[0]) }
+
+		last, lastErr := orderedTestMap.Last()
+		if lastErr != nil { t.Fatalf("error on last: %s", lastErr.Error()) }
+		expected := sorted[len(sorted) - 1]
+		if last == nil || string(last.Key) != expected || string(last.Value) != "v" + expected { t.Errorf("last mismatch: actual(%v), expected(%x)", last, expected) }
+
+		path := filepath.Join(os.TempDir(), "testorderedbounds")
+		os.Remove(path)
+
+		boundsMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: path, KeyMode: mmcmap.KeyModeOrdered })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+		defer boundsMap.Remove()
+
+		for _, key := range []string{ "b", "ba", "c" } { boundsMap.Put([]byte(key), []byte(key)) }
+		for _, key := range []string{ "a", "ab", "c\x00", "d" } { boundsMap.PutWithTTL([]byte(key), []byte(key), time.Millisecond) }
+		time.Sleep(10 * time.Millisecond)
+
+		first, firstErr = boundsMap.First()
+		if firstErr != nil || first == nil || string(first.Key) != "b" { t.Errorf("first past expired keys mismatch: actual(%v %v), expected(b)", first, firstErr) }
+
+		last, lastErr = boundsMap.Last()
+		if lastErr != nil || last == nil || string(last.Key) != "c" { t.Errorf("last past expired keys mismatch: actual(%v %v), expected(c)", last, lastErr) }
+	})
+
+	t.Run("Test Approximate Size", func(t *testing.T) {
+		total, sizeErr := orderedTestMap.ApproximateSize(nil, nil)
+		if sizeErr != nil { t.Fatalf("error on approximate size: %s", sizeErr.Error()) }
+
+		inRange, sizeErr := orderedTestMap.ApproximateSize([]byte("a"), []byte("b"))
+		if sizeErr != nil { t.Fatalf("error on approximate size: %s", sizeErr.Error()) }
+		if inRange == 0 || inRange >= total { t.Errorf("range size out of bounds: actual(%d), total(%d)", inRange, total) }
+	})
+
+	t.Run("Test Delete", func(t *testing.T) {
+		for _, key := range sorted[:len(sorted) / 2] {
+			removed, delErr := orderedTestMap.Delete([]byte(key))
+			if delErr != nil { t.Fatalf("error on delete: %s", delErr.Error()) }
+			if ! removed { t.Errorf("delete of %x did not report a removal", key) }
+		}
+
+		keys, rangeErr := orderedTestMap.Keys(nil, nil)
+		if rangeErr != nil { t.Fatalf("error on range: %s", rangeErr.Error()) }
+		checkKeys(t, keys, sorted[len(sorted) / 2:])
+
+		for _, key := range sorted[:len(sorted) / 2] {
+			_, putErr := orderedTestMap.Put([]byte(key), []byte("v" + key))
+			if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+		}
+
+		checkValues(t, orderedTestMap)
+	})
+
+	t.Run("Test Persisted", func(t *testing.T) {
+		_, compactErr := orderedTestMap.Compact()
+		if compactErr != nil { t.Fatalf("error on compact: %s", compactErr.Error()) }
+
+		closeErr := orderedTestMap.Close()
+		if closeErr != nil { t.Fatalf("error on close: %s", closeErr.Error()) }
+
+		var openErr error
+		orderedTestMap, openErr = mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: orderedTestPath, OpenCheck: mmcmap.OpenCheckDeep })
+		if openErr != nil { t.Fatalf("error on reopen: %s", openErr.Error()) }
+
+		if orderedTestMap.Header.KeyMode != mmcmap.KeyModeOrdered { t.Errorf("key mode was not persisted: actual(%d)", orderedTestMap.Header.KeyMode) }
+		if len(orderedTestMap.OpenReport.Findings) != 0 { t.Errorf("open check found inconsistencies: %v", orderedTestMap.OpenReport.Findings) }
+
+		checkValues(t, orderedTestMap)
+	})
+
+	t.Run("Test Bulk Load Matches Puts", func(t *testing.T) {
+		path := filepath.Join(os.TempDir(), "testorderedbulk")
+		os.Remove(path)
+
+		bulkMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: path, KeyMode: mmcmap.KeyModeOrdered })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+		defer bulkMap.Remove()
+
+		pairs := make([]mmcmap.KeyValuePair, len(sorted))
+		for idx, key := range sorted { pairs[idx] = mmcmap.KeyValuePair{ Key: []byte(key), Value: []byte("v" + key) } }
+
+		loadErr := bulkMap.BulkLoad(pairs)
+		if loadErr != nil { t.Fatalf("error on bulk load: %s", loadErr.Error()) }
+
+		checkValues(t, bulkMap)
+
+		keys, rangeErr := bulkMap.Keys(nil, nil)
+		if rangeErr != nil { t.Fatalf("error on range: %s", rangeErr.Error()) }
+		checkKeys(t, keys, sorted)
+	})
+
+	t.Run("Test Replica Adopts Key Mode", func(t *testing.T) {
+		path := filepath.Join(os.TempDir(), "testorderedreplica")
+		os.Remove(path)
+
+		replica, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: path })
+		if openErr != nil { t.Fatalf("error opening replica: %s", openErr.Error()) }
+		defer replica.Remove()
+
+		var buf bytes.Buffer
+		exportErr := orderedTestMap.ExportDelta(&buf, 0)
+		if exportErr != nil { t.Fatalf("error exporting delta: %s", exportErr.Error()) }
+
+		catchUpErr := replica.CatchUp(&buf)
+		if catchUpErr != nil { t.Fatalf("error on catch up: %s", catchUpErr.Error()) }
+		if replica.Header.KeyMode != mmcmap.KeyModeOrdered { t.Errorf("replica key mode mismatch: actual(%d)", replica.Header.KeyMode) }
+
+		checkValues(t, replica)
+	})
+
+	t.Run("Test Mode Mismatch", func(t *testing.T) {
+		path := filepath.Join(os.TempDir(), "testorderedmismatch")
+		os.Remove(path)
+		defer os.Remove(path)
+
+		_, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: path, KeyMode: 2 })
+		if openErr != mmcmap.ErrUnknownKeyMode { t.Errorf("unknown error mismatch: actual(%v), expected(%v)", openErr, mmcmap.ErrUnknownKeyMode) }
+
+		hashedMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: path })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+		if hashedMap.Header.KeyMode != mmcmap.KeyModeHashed { t.Errorf("default key mode mismatch: actual(%d)", hashedMap.Header.KeyMode) }
+		hashedMap.Close()
+
+		_, openErr = mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: path, KeyMode: mmcmap.KeyModeOrdered })
+		if openErr != mmcmap.ErrKeyModeMismatch { t.Errorf("mismatch error mismatch: actual(%v), expected(%v)", openErr, mmcmap.ErrKeyModeMismatch) }
+	})
+
+	t.Log("Done")
+}
@@ -0,0 +1,178 @@
+package mmcmaptests
+
+import "bytes"
+import "fmt"
+import "io"
+import "os"
+import "path/filepath"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var overflowTestPath = filepath.Join(os.TempDir(), "testoverflow")
+var overflowTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initOverflowMapErr error
+	os.Remove(overflowTestPath)
+
+	overflowTestMap, initOverflowMapErr = mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: overflowTestPath, OverflowThreshold: 64 })
+	if initOverflowMapErr != nil { panic(initOverflowMapErr.Error()) }
+
+	fmt.Println("overflow test mmcmap initialized")
+}
+
+
+// overflowValue is a value for key long enough to be stored in an overflow extent
+func overflowValue(key string) []byte {
+	return bytes.Repeat([]byte(key), 4096 / len(key) + 1)
+}
+
+// findLeaf reads the leaf for key at the latest version by walking every node of the trie
+func findLeaf(t *testing.T, mmcMap *mmcmap.MMCMap, offset uint64, key []byte) *mmcmap.MMCMapNode {
+	node, readErr := mmcMap.ReadNodeFromMemMap(offset)
+	if readErr != nil { t.Fatalf("error reading node: %s", readErr.Error()) }
+
+	if node.IsLeaf {
+		if bytes.Equal(node.Key, key) { return node }
+		return nil
+	}
+
+	for _, child := range node.Children {
+		leaf := findLeaf(t, mmcMap, child.StartOffset, key)
+		if leaf != nil { return leaf }
+	}
+
+	return nil
+}
+
+
+func TestMMCMapOverflow(t *testing.T) {
+	defer overflowTestMap.Remove()
+
+	keys := make([]string, 2000)
+	for idx := range keys {
+		keys[idx] = fmt.Sprintf("key%d", idx)
+
+		value := []byte(keys[idx])
+		if idx % 3 == 0 { value = overflowValue(keys[idx]) }
+
+		_, putErr := overflowTestMap.Put([]byte(keys[idx]), value)
+		if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+	}
+
+	checkValues := func(t *testing.T, mmcMap *mmcmap.MMCMap) {
+		for idx, key := range keys {
+			expected := []byte(key)
+			if idx % 3 == 0 { expected = overflowValue(key) }
+
+			value, getErr := mmcMap.Get([]byte(key))
+			if getErr != nil { t.Fatalf("error on get: %s", getErr.Error()) }
+			if ! bytes.Equal(value, expected) { t.Fatalf("value mismatch for %s: actual(%d bytes), expected(%d bytes)", key, len(value), len(expected)) }
+		}
+	}
+
+	t.Run("Test Large Values Stored In Extents", func(t *testing.T) {
+		checkValues(t, overflowTestMap)
+
+		meta, _ := overflowTestMap.ReadMetaFromMemMap()
+
+		large := findLeaf(t, overflowTestMap, meta.RootOffset, []byte("key0"))
+		if large == nil || ! large.IsOverflow || large.OverflowOffset == 0 { t.Fatalf("large value is not stored in an extent: %+v", large) }
+		if size := large.EndOffset - large.StartOffset + 1; size > 64 { t.Errorf("overflow leaf is not small: actual(%d bytes)", size) }
+
+		small := findLeaf(t, overflowTestMap, meta.RootOffset, []byte("key1"))
+		if small == nil || small.IsOverflow { t.Fatalf("small value is stored in an extent: %+v", small) }
+
+		pairs, rangeErr := overflowTestMap.Range([]byte("key0"), []byte("key0"))
+		if rangeErr != nil { t.Fatalf("error on range: %s", rangeErr.Error()) }
+		if len(pairs) != 1 || ! bytes.Equal(pairs[0].Value, overflowValue("key0")) { t.Error("range did not return the overflow value") }
+
+		reader, getErr := overflowTestMap.GetReader([]byte("key0"))
+		if getErr != nil { t.Fatalf("error on get reader: %s", getErr.Error()) }
+		defer reader.Close()
+
+		streamed, _ := io.ReadAll(reader)
+		if ! bytes.Equal(streamed, overflowValue("key0")) { t.Error("get reader did not stream the overflow value") }
+	})
+
+	t.Run("Test Overwrite Moves Value Inline", func(t *testing.T) {
+		overflowTestMap.Put([]byte("key3"), []byte("small"))
+
+		meta, _ := overflowTestMap.ReadMetaFromMemMap()
+		leaf := findLeaf(t, overflowTestMap, meta.RootOffset, []byte("key3"))
+		if leaf == nil || leaf.IsOverflow { t.Errorf("overwritten value is still in an extent: %+v", leaf) }
+
+		overflowTestMap.Put([]byte("key3"), overflowValue("key3"))
+	})
+
+	t.Run("Test Verify History And Compact", func(t *testing.T) {
+		report, verifyErr := overflowTestMap.Verify()
+		if verifyErr != nil { t.Fatalf("error on verify: %s", verifyErr.Error()) }
+		if ! report.IsValid { t.Errorf("verify found inconsistencies: %v", report.Findings) }
+
+		history, historyErr := overflowTestMap.History([]byte("key3"), 0)
+		if historyErr != nil { t.Fatalf("error on history: %s", historyErr.Error()) }
+		if len(history) != 3 { t.Errorf("history length mismatch: actual(%d), expected(3)", len(history)) }
+
+		_, compactErr := overflowTestMap.Compact()
+		if compactErr != nil { t.Fatalf("error on compact: %s", compactErr.Error()) }
+
+		checkValues(t, overflowTestMap)
+	})
+
+	t.Run("Test Free List Keeps Extents", func(t *testing.T) {
+		path := filepath.Join(os.TempDir(), "testoverflowfreelist")
+		os.Remove(path)
+
+		freeListMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: path, Allocator: mmcmap.FreeListAllocator{}, OverflowThreshold: 64 })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+		defer freeListMap.Remove()
+
+		for idx, key := range keys {
+			value := []byte(key)
+			if idx % 3 == 0 { value = overflowValue(key) }
+			freeListMap.Put([]byte(key), value)
+		}
+
+		_, reclaimErr := freeListMap.Reclaim()
+		if reclaimErr != nil { t.Fatalf("error on reclaim: %s", reclaimErr.Error()) }
+
+		for idx := 0; idx < 500; idx++ { freeListMap.Put([]byte(fmt.Sprintf("filler%d", idx)), overflowValue("filler")) }
+
+		checkValues(t, freeListMap)
+	})
+
+	t.Run("Test Existing File Upgraded On Open", func(t *testing.T) {
+		path := filepath.Join(os.TempDir(), "testoverflowupgrade")
+		os.Remove(path)
+
+		plainMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: path })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+
+		plainMap.Put([]byte("inline"), overflowValue("inline"))
+		plainMap.Close()
+
+		file, _ := os.OpenFile(path, os.O_WRONLY, 0600)
+		file.WriteAt([]byte{ mmcmap.ExpiryFormatVersion, 0 }, mmcmap.HeaderFormatVersionIdx)
+		file.Close()
+
+		reopenMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: path, OverflowThreshold: 64 })
+		if openErr != nil { t.Fatalf("error on reopen: %s", openErr.Error()) }
+		defer reopenMap.Remove()
+
+		if reopenMap.Header.FormatVersion != mmcmap.OverflowFormatVersion { t.Errorf("format version mismatch: actual(%d), expected(%d)", reopenMap.Header.FormatVersion, mmcmap.OverflowFormatVersion) }
+
+		reopenMap.Put([]byte("extent"), overflowValue("extent"))
+
+		value, _ := reopenMap.Get([]byte("inline"))
+		if ! bytes.Equal(value, overflowValue("inline")) { t.Error("inline value mismatch after reopen") }
+
+		value, _ = reopenMap.Get([]byte("extent"))
+		if ! bytes.Equal(value, overflowValue("extent")) { t.Error("overflow value mismatch after reopen") }
+	})
+
+	t.Log("Done")
+}
@@ -0,0 +1,103 @@
+package mmcmaptests
+
+import "fmt"
+import "os"
+import "path/filepath"
+import "sync"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var pinnedTestPath = filepath.Join(os.TempDir(), "testpinned")
+var pinnedTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initPinnedMapErr error
+	os.Remove(pinnedTestPath)
+
+	opts := mmcmap.MMCMapOpts{ Filepath: pinnedTestPath, PinnedLevels: 2 }
+	pinnedTestMap, initPinnedMapErr = mmcmap.Open(opts)
+	if initPinnedMapErr != nil { panic(initPinnedMapErr.Error()) }
+
+	fmt.Println("pinned test mmcmap initialized")
+}
+
+
+func TestMMCMapPinned(t *testing.T) {
+	defer pinnedTestMap.Remove()
+
+	checkValues := func(t *testing.T, mmcMap *mmcmap.MMCMap, round int) {
+		for idx := 0; idx < 1000; idx++ {
+			key := fmt.Sprintf("key%d", idx)
+			expected := fmt.Sprintf("value%d-%d", idx, round)
+
+			value, getErr := mmcMap.Get([]byte(key))
+			if getErr != nil { t.Fatalf("error on get: %s", getErr.Error()) }
+			if string(value) != expected { t.Fatalf("value mismatch for %s: actual(%s), expected(%s)", key, value, expected) }
+		}
+	}
+
+	putValues := func(t *testing.T, round int) {
+		for idx := 0; idx < 1000; idx++ {
+			_, putErr := pinnedTestMap.Put([]byte(fmt.Sprintf("key%d", idx)), []byte(fmt.Sprintf("value%d-%d", idx, round)))
+			if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+		}
+	}
+
+	if pinned := pinnedTestMap.NodeCacheStats().Pinned; pinned != 1 { t.Errorf("root was not pinned on open: actual(%d), expected(1)", pinned) }
+
+	putValues(t, 0)
+
+	t.Run("Test Pinned Levels", func(t *testing.T) {
+		pinned := pinnedTestMap.NodeCacheStats().Pinned
+		if pinned <= 1 || pinned > 33 { t.Errorf("pinned node count out of bounds: actual(%d), expected(2 - 33)", pinned) }
+
+		checkValues(t, pinnedTestMap, 0)
+	})
+
+	t.Run("Test Concurrent Commits", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for worker := 0; worker < 4; worker++ {
+			wg.Add(1)
+			go func(worker int) {
+				defer wg.Done()
+
+				for idx := worker; idx < 1000; idx += 4 {
+					key := []byte(fmt.Sprintf("key%d", idx))
+					_, putErr := pinnedTestMap.Put(key, []byte(fmt.Sprintf("value%d-1", idx)))
+					if putErr != nil { t.Errorf("error on put: %s", putErr.Error()) }
+
+					_, getErr := pinnedTestMap.Get(key)
+					if getErr != nil { t.Errorf("error on get: %s", getErr.Error()) }
+				}
+			}(worker)
+		}
+
+		wg.Wait()
+		checkValues(t, pinnedTestMap, 1)
+	})
+
+	t.Run("Test Compact And Reopen", func(t *testing.T) {
+		_, compactErr := pinnedTestMap.Compact()
+		if compactErr != nil { t.Fatalf("error on compact: %s", compactErr.Error()) }
+
+		checkValues(t, pinnedTestMap, 1)
+
+		putValues(t, 2)
+		checkValues(t, pinnedTestMap, 2)
+
+		closeErr := pinnedTestMap.Close()
+		if closeErr != nil { t.Fatalf("error on close: %s", closeErr.Error()) }
+
+		var openErr error
+		pinnedTestMap, openErr = mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: pinnedTestPath, PinnedLevels: 2 })
+		if openErr != nil { t.Fatalf("error on reopen: %s", openErr.Error()) }
+
+		if pinned := pinnedTestMap.NodeCacheStats().Pinned; pinned <= 1 { t.Errorf("levels were not pinned on open: actual(%d)", pinned) }
+		checkValues(t, pinnedTestMap, 2)
+	})
+
+	t.Log("Done")
+}
@@ -0,0 +1,76 @@
+package mmcmaptests
+
+import "fmt"
+import "os"
+import "path/filepath"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var prefetchTestPath = filepath.Join(os.TempDir(), "testprefetch")
+var prefetchTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initPrefetchMapErr error
+	os.Remove(prefetchTestPath)
+
+	opts := mmcmap.MMCMapOpts{ Filepath: prefetchTestPath, PrefetchScans: true }
+	prefetchTestMap, initPrefetchMapErr = mmcmap.Open(opts)
+	if initPrefetchMapErr != nil { panic(initPrefetchMapErr.Error()) }
+
+	fmt.Println("prefetch test mmcmap initialized")
+}
+
+
+func TestMMCMapPrefetch(t *testing.T) {
+	defer prefetchTestMap.Remove()
+
+	putValues := func(t *testing.T, mmcMap *mmcmap.MMCMap) {
+		for idx := 0; idx < 2000; idx++ {
+			_, putErr := mmcMap.Put([]byte(fmt.Sprintf("key%d", idx)), []byte(fmt.Sprintf("value%d", idx)))
+			if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+		}
+	}
+
+	prefetches := func(t *testing.T, mmcMap *mmcmap.MMCMap) uint64 {
+		stats, statsErr := mmcMap.Stats()
+		if statsErr != nil { t.Fatalf("error on stats: %s", statsErr.Error()) }
+
+		return stats.Prefetches
+	}
+
+	putValues(t, prefetchTestMap)
+
+	t.Run("Test Range With Prefetch", func(t *testing.T) {
+		pairs, rangeErr := prefetchTestMap.Range(nil, nil)
+		if rangeErr != nil { t.Fatalf("error on range: %s", rangeErr.Error()) }
+		if len(pairs) != 2000 { t.Errorf("range length mismatch: actual(%d), expected(2000)", len(pairs)) }
+
+		for _, pair := range pairs {
+			if string(pair.Value) != "value" + string(pair.Key[len("key"):]) { t.Fatalf("value mismatch for %s: actual(%s)", pair.Key, pair.Value) }
+		}
+
+		if prefetches(t, prefetchTestMap) == 0 { t.Error("range issued no prefetch hints") }
+	})
+
+	t.Run("Test Range Without Prefetch", func(t *testing.T) {
+		path := filepath.Join(os.TempDir(), "testprefetchoff")
+		os.Remove(path)
+
+		mmcMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: path })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+		defer mmcMap.Remove()
+
+		putValues(t, mmcMap)
+
+		keys, keysErr := mmcMap.Keys(nil, nil)
+		if keysErr != nil { t.Fatalf("error on keys: %s", keysErr.Error()) }
+		if len(keys) != 2000 { t.Errorf("keys length mismatch: actual(%d), expected(2000)", len(keys)) }
+
+		if count := prefetches(t, mmcMap); count != 0 { t.Errorf("prefetch hints issued without PrefetchScans: actual(%d)", count) }
+	})
+
+	t.Log("Done")
+}
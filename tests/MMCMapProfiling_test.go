@@ -0,0 +1,97 @@
+package mmcmaptests
+
+import "bytes"
+import "encoding/json"
+import "expvar"
+import "fmt"
+import "os"
+import "path/filepath"
+import "runtime/pprof"
+import "strings"
+import "testing"
+import "time"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var profilingTestPath = filepath.Join(os.TempDir(), "testprofiling")
+var profilingTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initProfilingMapErr error
+	os.Remove(profilingTestPath)
+
+	opts := mmcmap.MMCMapOpts{ Filepath: profilingTestPath, Name: "profiling", CompactionThreshold: mmcmap.CompactionThreshold{ MinSize: 1 << 40 } }
+	profilingTestMap, initProfilingMapErr = mmcmap.Open(opts)
+	if initProfilingMapErr != nil { panic(initProfilingMapErr.Error()) }
+
+	fmt.Println("profiling test mmcmap initialized")
+}
+
+
+func TestMMCMapProfiling(t *testing.T) {
+	defer profilingTestMap.Remove()
+
+	for idx := 0; idx < 100; idx++ {
+		key := []byte(fmt.Sprintf("key%d", idx))
+		_, putErr := profilingTestMap.Put(key, key)
+		if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+	}
+
+	t.Run("Test Expvar Counters", func(t *testing.T) {
+		publishErr := mmcmap.PublishCounters()
+		if publishErr != nil { t.Fatalf("error publishing counters: %s", publishErr.Error()) }
+
+		publishErr = mmcmap.PublishCounters()
+		if publishErr != nil { t.Errorf("publishing twice should be a no op: %s", publishErr.Error()) }
+
+		published := expvar.Get(mmcmap.ExpvarName)
+		if published == nil { t.Fatalf("counters are not published under %s", mmcmap.ExpvarName) }
+
+		var counters []mmcmap.MMCMapCounters
+		decodeErr := json.Unmarshal([]byte(published.String()), &counters)
+		if decodeErr != nil { t.Fatalf("error decoding expvar: %s", decodeErr.Error()) }
+
+		var isFound bool
+		for _, mapCounters := range counters {
+			if mapCounters.Filepath != profilingTestPath { continue }
+
+			isFound = true
+			if mapCounters.Name != "profiling" { t.Errorf("name mismatch: actual(%s), expected(profiling)", mapCounters.Name) }
+			if mapCounters.Puts != 100 { t.Errorf("puts mismatch: actual(%d), expected(100)", mapCounters.Puts) }
+		}
+
+		if ! isFound { t.Errorf("open map missing from published counters: %+v", counters) }
+	})
+
+	t.Run("Test Go Routine Labels", func(t *testing.T) {
+		_, cancel := profilingTestMap.Watch(nil)
+		defer cancel()
+
+		labels := make([]string, 0, 5)
+		for _, task := range []string{ "flush", "resize", "sweep", "watch", "compaction" } {
+			labels = append(labels, fmt.Sprintf(`"%s":"%s"`, mmcmap.ProfileLabelTask, task))
+		}
+
+		// the labels are set once each go routine starts running
+		var dump string
+		for attempt := 0; attempt < 100; attempt++ {
+			var buf bytes.Buffer
+			writeErr := pprof.Lookup("goroutine").WriteTo(&buf, 1)
+			if writeErr != nil { t.Fatalf("error writing go routine profile: %s", writeErr.Error()) }
+
+			dump = buf.String()
+			if strings.Contains(dump, labels[3]) { break }
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		for _, label := range labels {
+			if ! strings.Contains(dump, label) { t.Errorf("go routine dump missing label %s", label) }
+		}
+
+		if ! strings.Contains(dump, fmt.Sprintf(`"%s":"profiling"`, mmcmap.ProfileLabelMap)) { t.Error("go routine dump missing the map label") }
+	})
+
+	t.Log("Done")
+}
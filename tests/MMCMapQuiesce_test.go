@@ -0,0 +1,74 @@
+package mmcmaptests
+
+import "context"
+import "errors"
+import "fmt"
+import "os"
+import "path/filepath"
+import "testing"
+import "time"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var qTestPath = filepath.Join(os.TempDir(), "testquiesce")
+var quiesceTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initQMapErr error
+	os.Remove(qTestPath)
+
+	opts := mmcmap.MMCMapOpts{ Filepath: qTestPath }
+	quiesceTestMap, initQMapErr = mmcmap.Open(opts)
+	if initQMapErr != nil { panic(initQMapErr.Error()) }
+
+	fmt.Println("quiesce test mmcmap initialized")
+}
+
+
+func TestMMCMapQuiesce(t *testing.T) {
+	defer quiesceTestMap.Remove()
+
+	_, putErr := quiesceTestMap.Put([]byte("hello"), []byte("world"))
+	if putErr != nil { t.Errorf("error putting key in mmcmap: %s", putErr.Error()) }
+
+	t.Run("Test Quiesce Blocks Commits", func(t *testing.T) {
+		release, quiesceErr := quiesceTestMap.Quiesce(context.Background())
+		if quiesceErr != nil { t.Fatalf("error quiescing mmcmap: %s", quiesceErr.Error()) }
+
+		val, getErr := quiesceTestMap.Get([]byte("hello"))
+		if getErr != nil { t.Errorf("error getting key while quiesced: %s", getErr.Error()) }
+		if string(val) != "world" { t.Errorf("value does not match while quiesced: actual(%s), expected(world)", val) }
+
+		committed := make(chan error, 1)
+		go func() {
+			_, putErr := quiesceTestMap.Put([]byte("blocked"), []byte("write"))
+			committed <- putErr
+		}()
+
+		select {
+			case <-committed:
+				t.Error("commit completed while map was quiesced")
+			case <-time.After(50 * time.Millisecond):
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20 * time.Millisecond)
+		defer cancel()
+
+		_, nestedErr := quiesceTestMap.Quiesce(ctx)
+		if ! errors.Is(nestedErr, context.DeadlineExceeded) { t.Errorf("expected deadline exceeded on nested quiesce, got: %v", nestedErr) }
+
+		release()
+		release()
+
+		putErr := <-committed
+		if putErr != nil { t.Errorf("error on blocked put after release: %s", putErr.Error()) }
+
+		stats := quiesceTestMap.QuiesceStats()
+		if stats.Count != 1 { t.Errorf("unexpected quiesce count: actual(%d), expected(1)", stats.Count) }
+		if stats.Total < 50 * time.Millisecond { t.Errorf("quiesced duration too short: %s", stats.Total) }
+	})
+
+	t.Log("Done")
+}
@@ -0,0 +1,159 @@
+package mmcmaptests
+
+import "bytes"
+import "context"
+import "errors"
+import "fmt"
+import "os"
+import "path/filepath"
+import "testing"
+import "time"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var rTestPath = filepath.Join(os.TempDir(), "testrange")
+var rangeTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initRMapErr error
+	os.Remove(rTestPath)
+
+	opts := mmcmap.MMCMapOpts{ Filepath: rTestPath }
+	rangeTestMap, initRMapErr = mmcmap.Open(opts)
+	if initRMapErr != nil { panic(initRMapErr.Error()) }
+
+	fmt.Println("range test mmcmap initialized")
+}
+
+
+func TestMMCMapRange(t *testing.T) {
+	defer rangeTestMap.Remove()
+
+	for idx := range make([]int, 100) {
+		key := []byte(fmt.Sprintf("key%03d", idx))
+		_, putErr := rangeTestMap.Put(key, key)
+		if putErr != nil { t.Errorf("error putting key in mmcmap: %s", putErr.Error()) }
+	}
+
+	t.Run("Test Range", func(t *testing.T) {
+		pairs, rangeErr := rangeTestMap.Range([]byte("key010"), []byte("key019"))
+		if rangeErr != nil { t.Errorf("error on range: %s", rangeErr.Error()) }
+		if len(pairs) != 10 { t.Errorf("range returned unexpected number of pairs: actual(%d), expected(10)", len(pairs)) }
+
+		for idx, pair := range pairs {
+			expected := []byte(fmt.Sprintf("key%03d", idx + 10))
+			if ! bytes.Equal(pair.Key, expected) { t.Errorf("range not sorted: actual(%s), expected(%s)", pair.Key, expected) }
+			if ! bytes.Equal(pair.Value, expected) { t.Errorf("range value mismatch: actual(%s), expected(%s)", pair.Value, expected) }
+		}
+
+		all, rangeErr := rangeTestMap.Range(nil, nil)
+		if rangeErr != nil { t.Errorf("error on unbounded range: %s", rangeErr.Error()) }
+		if len(all) != 100 { t.Errorf("unbounded range returned unexpected number of pairs: actual(%d), expected(100)", len(all)) }
+	})
+
+	t.Run("Test Range Pagination", func(t *testing.T) {
+		var collected [][]byte
+		var token []byte
+
+		for page := 0; ; page++ {
+			pairs, next, pageErr := rangeTestMap.RangePage(nil, nil, mmcmap.RangeOpts{ Limit: 30, After: token })
+			if pageErr != nil { t.Errorf("error on range page: %s", pageErr.Error()) }
+
+			for _, pair := range pairs { collected = append(collected, pair.Key) }
+			if next == nil { break }
+			if page > 4 { t.Fatalf("pagination did not terminate") }
+
+			token = next
+		}
+
+		if len(collected) != 100 { t.Errorf("pages returned unexpected number of keys: actual(%d), expected(100)", len(collected)) }
+		for idx, key := range collected {
+			expected := []byte(fmt.Sprintf("key%03d", idx))
+			if ! bytes.Equal(key, expected) { t.Errorf("pages out of order: actual(%s), expected(%s)", key, expected) }
+		}
+	})
+
+	t.Run("Test Range Reverse With Offset", func(t *testing.T) {
+		pairs, rangeErr := rangeTestMap.Range(nil, nil, mmcmap.RangeOpts{ Limit: 5, Offset: 2, Reverse: true })
+		if rangeErr != nil { t.Errorf("error on reverse range: %s", rangeErr.Error()) }
+		if len(pairs) != 5 { t.Errorf("reverse range returned unexpected number of pairs: actual(%d), expected(5)", len(pairs)) }
+
+		for idx, pair := range pairs {
+			expected := []byte(fmt.Sprintf("key%03d", 97 - idx))
+			if ! bytes.Equal(pair.Key, expected) { t.Errorf("reverse range mismatch: actual(%s), expected(%s)", pair.Key, expected) }
+		}
+	})
+
+	t.Run("Test Keys Only", func(t *testing.T) {
+		pairs, rangeErr := rangeTestMap.Range([]byte("key020"), []byte("key029"), mmcmap.RangeOpts{ KeysOnly: true })
+		if rangeErr != nil { t.Errorf("error on keys only range: %s", rangeErr.Error()) }
+		if len(pairs) != 10 { t.Errorf("keys only range returned unexpected number of pairs: actual(%d), expected(10)", len(pairs)) }
+
+		for idx, pair := range pairs {
+			expected := []byte(fmt.Sprintf("key%03d", idx + 20))
+			if ! bytes.Equal(pair.Key, expected) { t.Errorf("keys only range mismatch: actual(%s), expected(%s)", pair.Key, expected) }
+			if pair.Value != nil { t.Errorf("keys only range returned value for key %s", pair.Key) }
+		}
+
+		keys, keysErr := rangeTestMap.Keys([]byte("key090"), nil)
+		if keysErr != nil { t.Errorf("error on keys: %s", keysErr.Error()) }
+		if len(keys) != 10 { t.Errorf("keys returned unexpected number of keys: actual(%d), expected(10)", len(keys)) }
+
+		for idx, key := range keys {
+			expected := []byte(fmt.Sprintf("key%03d", idx + 90))
+			if ! bytes.Equal(key, expected) { t.Errorf("keys mismatch: actual(%s), expected(%s)", key, expected) }
+		}
+	})
+
+	t.Run("Test First And Last", func(t *testing.T) {
+		first, firstErr := rangeTestMap.First()
+		if firstErr != nil { t.Errorf("error on first: %s", firstErr.Error()) }
+		if first == nil || ! bytes.Equal(first.Key, []byte("key000")) || ! bytes.Equal(first.Value, []byte("key000")) { t.Errorf("first mismatch: actual(%v), expected(key000)", first) }
+
+		last, lastErr := rangeTestMap.Last()
+		if lastErr != nil { t.Errorf("error on last: %s", lastErr.Error()) }
+		if last == nil || ! bytes.Equal(last.Key, []byte("key099")) || ! bytes.Equal(last.Value, []byte("key099")) { t.Errorf("last mismatch: actual(%v), expected(key099)", last) }
+	})
+
+	t.Run("Test Range Chan", func(t *testing.T) {
+		pairs, errs := rangeTestMap.RangeChan([]byte("key050"), nil)
+
+		total := 0
+		for pair := range pairs {
+			if bytes.Compare(pair.Key, []byte("key050")) < 0 { t.Errorf("streamed key out of range: %s", pair.Key) }
+			total++
+		}
+
+		for rangeErr := range errs { t.Errorf("error on range chan: %s", rangeErr.Error()) }
+		if total != 50 { t.Errorf("range chan emitted unexpected number of pairs: actual(%d), expected(50)", total) }
+	})
+
+	t.Run("Test Range Chan Cancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		pairs, errs := rangeTestMap.RangeChanWithContext(ctx, nil, nil)
+
+		<-pairs
+		cancel()
+
+		// the traversal releases its pin on the root once cancelled, so compaction is not held up by the abandoned stream
+		compacted := make(chan error, 1)
+		go func() {
+			_, compactErr := rangeTestMap.Compact()
+			compacted <- compactErr
+		}()
+
+		select {
+			case compactErr := <-compacted:
+				if compactErr != nil { t.Errorf("error on compact: %s", compactErr.Error()) }
+			case <-time.After(10 * time.Second):
+				t.Fatalf("compact blocked by a cancelled range chan")
+		}
+
+		rangeErr := <-errs
+		if ! errors.Is(rangeErr, context.Canceled) { t.Errorf("range chan error mismatch: actual(%v), expected(%v)", rangeErr, context.Canceled) }
+	})
+
+	t.Log("Done")
+}
@@ -0,0 +1,108 @@
+package mmcmaptests
+
+import "fmt"
+import "os"
+import "path/filepath"
+import "sync"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var readEpochTestPath = filepath.Join(os.TempDir(), "testreadepoch")
+var readEpochTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initReadEpochMapErr error
+	os.Remove(readEpochTestPath)
+
+	opts := mmcmap.MMCMapOpts{ Filepath: readEpochTestPath }
+	readEpochTestMap, initReadEpochMapErr = mmcmap.Open(opts)
+	if initReadEpochMapErr != nil { panic(initReadEpochMapErr.Error()) }
+
+	fmt.Println("read epoch test mmcmap initialized")
+}
+
+
+func TestMMCMapReadEpoch(t *testing.T) {
+	defer readEpochTestMap.Remove()
+
+	for idx := 0; idx < 1000; idx++ {
+		key := []byte(fmt.Sprintf("key%d", idx))
+		_, putErr := readEpochTestMap.Put(key, key)
+		if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+	}
+
+	t.Run("Test Reads Without Resize Lock", func(t *testing.T) {
+		for idx := 0; idx < 1000; idx++ {
+			key := []byte(fmt.Sprintf("key%d", idx))
+			value, getErr := readEpochTestMap.Get(key)
+			if getErr != nil || string(value) != string(key) { t.Fatalf("value mismatch: actual(%s), expected(%s), err(%v)", value, key, getErr) }
+		}
+
+		stats, statsErr := readEpochTestMap.Stats()
+		if statsErr != nil { t.Fatalf("error on stats: %s", statsErr.Error()) }
+		if stats.LockedReads != 0 { t.Errorf("reads took the resize lock without an exclusive holder: actual(%d)", stats.LockedReads) }
+	})
+
+	t.Run("Test Reads During Compact", func(t *testing.T) {
+		stop := make(chan struct{})
+
+		var wg, started sync.WaitGroup
+		for reader := 0; reader < 4; reader++ {
+			wg.Add(1)
+			started.Add(1)
+			go func(reader int) {
+				defer wg.Done()
+				isStarted := false
+
+				for idx := reader; ; idx = (idx + 1) % 1000 {
+					select {
+						case <-stop:
+							return
+						default:
+							key := []byte(fmt.Sprintf("key%d", idx))
+							value, getErr := readEpochTestMap.Get(key)
+							if getErr != nil || string(value) != string(key) { t.Errorf("read mismatch during compact: actual(%s), expected(%s), err(%v)", value, key, getErr) }
+
+							if ! isStarted {
+								isStarted = true
+								started.Done()
+							}
+					}
+				}
+			}(reader)
+		}
+
+		started.Wait()
+
+		for compaction := 0; compaction < 3; compaction++ {
+			_, compactErr := readEpochTestMap.Compact()
+			if compactErr != nil { t.Fatalf("error on compact: %s", compactErr.Error()) }
+		}
+
+		close(stop)
+		wg.Wait()
+	})
+
+	t.Run("Test Reads After Close", func(t *testing.T) {
+		path := filepath.Join(os.TempDir(), "testreadepochclosed")
+		os.Remove(path)
+
+		mmcMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: path })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+		defer mmcMap.Remove()
+
+		_, putErr := mmcMap.Put([]byte("key"), []byte("value"))
+		if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+
+		closeErr := mmcMap.Close()
+		if closeErr != nil { t.Fatalf("error on close: %s", closeErr.Error()) }
+
+		_, getErr := mmcMap.Get([]byte("key"))
+		if getErr != mmcmap.ErrMapClosed { t.Errorf("get error mismatch: actual(%v), expected(%v)", getErr, mmcmap.ErrMapClosed) }
+	})
+
+	t.Log("Done")
+}
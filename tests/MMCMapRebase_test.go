@@ -0,0 +1,81 @@
+package mmcmaptests
+
+import "bytes"
+import "fmt"
+import "os"
+import "path/filepath"
+import "sync"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var rebaseTestPath = filepath.Join(os.TempDir(), "testrebase")
+var rebaseTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initRebaseMapErr error
+	mmcmap.Destroy(rebaseTestPath)
+
+	opts := mmcmap.MMCMapOpts{ Filepath: rebaseTestPath }
+	rebaseTestMap, initRebaseMapErr = mmcmap.Open(opts)
+	if initRebaseMapErr != nil { panic(initRebaseMapErr.Error()) }
+
+	fmt.Println("rebase test mmcmap initialized")
+}
+
+
+func TestMMCMapRebase(t *testing.T) {
+	defer rebaseTestMap.Remove()
+
+	writers, keysPerWriter := 16, 500
+
+	t.Run("Test Concurrent Puts And Deletes", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for writer := 0; writer < writers; writer++ {
+			wg.Add(1)
+			go func(writer int) {
+				defer wg.Done()
+
+				for idx := 0; idx < keysPerWriter; idx++ {
+					key := []byte(fmt.Sprintf("writer%d-key%d", writer, idx))
+					_, putErr := rebaseTestMap.Put(key, key)
+					if putErr != nil { t.Errorf("error on put: %s", putErr.Error()) }
+
+					if idx % 5 == 0 {
+						_, delErr := rebaseTestMap.Delete(key)
+						if delErr != nil { t.Errorf("error on delete: %s", delErr.Error()) }
+					}
+				}
+			}(writer)
+		}
+
+		wg.Wait()
+
+		for writer := 0; writer < writers; writer++ {
+			for idx := 0; idx < keysPerWriter; idx++ {
+				key := []byte(fmt.Sprintf("writer%d-key%d", writer, idx))
+				value, getErr := rebaseTestMap.Get(key)
+				if getErr != nil { t.Fatalf("error on get: %s", getErr.Error()) }
+
+				if idx % 5 == 0 {
+					if value != nil { t.Errorf("deleted key is present: %s", key) }
+				} else if ! bytes.Equal(value, key) { t.Errorf("value mismatch: actual(%s), expected(%s)", value, key) }
+			}
+		}
+
+		expected := uint64(writers * keysPerWriter * 4 / 5)
+		count, lenErr := rebaseTestMap.Len()
+		if lenErr != nil { t.Fatalf("error on len: %s", lenErr.Error()) }
+		if count != expected { t.Errorf("key count mismatch: actual(%d), expected(%d)", count, expected) }
+
+		report, verifyErr := rebaseTestMap.Verify()
+		if verifyErr != nil { t.Fatalf("error on verify: %s", verifyErr.Error()) }
+		if len(report.Findings) > 0 { t.Errorf("verify found inconsistencies: %v", report.Findings) }
+
+		t.Logf("commits rebased: %d, retried: %d", rebaseTestMap.Counters().CommitRebases, rebaseTestMap.Counters().CommitRetries)
+	})
+
+	t.Log("Done")
+}
@@ -0,0 +1,46 @@
+package mmcmaptests
+
+import "os"
+import "path/filepath"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var regTestPath = filepath.Join(os.TempDir(), "testregistry")
+
+
+func TestMMCMapRegistry(t *testing.T) {
+	os.Remove(regTestPath)
+
+	opts := mmcmap.MMCMapOpts{ Filepath: regTestPath, Name: "registry-test", Labels: map[string]string{ "role": "index" } }
+	regMap, openErr := mmcmap.Open(opts)
+	if openErr != nil { t.Fatalf("error opening mmcmap: %s", openErr.Error()) }
+
+	findInfo := func() *mmcmap.OpenMapInfo {
+		for _, info := range mmcmap.ListOpenMaps() {
+			if info.Name == "registry-test" { return &info }
+		}
+
+		return nil
+	}
+
+	t.Run("Test Open Map Listed", func(t *testing.T) {
+		_, putErr := regMap.Put([]byte("hello"), []byte("world"))
+		if putErr != nil { t.Errorf("error putting key in mmcmap: %s", putErr.Error()) }
+
+		info := findInfo()
+		if info == nil { t.Fatal("open map not found in registry") }
+		if info.Labels["role"] != "index" { t.Errorf("label not found on registered map: %v", info.Labels) }
+		if info.Version != 1 { t.Errorf("unexpected version for registered map: actual(%d), expected(1)", info.Version) }
+	})
+
+	t.Run("Test Closed Map Removed", func(t *testing.T) {
+		removeErr := regMap.Remove()
+		if removeErr != nil { t.Errorf("error removing mmcmap: %s", removeErr.Error()) }
+
+		if findInfo() != nil { t.Error("closed map still present in registry") }
+	})
+
+	t.Log("Done")
+}
@@ -0,0 +1,133 @@
+package mmcmaptests
+
+import "bytes"
+import "fmt"
+import "os"
+import "path/filepath"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var rpTestPath = filepath.Join(os.TempDir(), "testrepair")
+var repairTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initRpMapErr error
+	os.Remove(rpTestPath)
+
+	opts := mmcmap.MMCMapOpts{ Filepath: rpTestPath }
+	repairTestMap, initRpMapErr = mmcmap.Open(opts)
+	if initRpMapErr != nil { panic(initRpMapErr.Error()) }
+
+	fmt.Println("repair test mmcmap initialized")
+}
+
+
+func TestMMCMapRepair(t *testing.T) {
+	defer repairTestMap.Remove()
+
+	for idx := 0; idx < 200; idx++ {
+		key := []byte(fmt.Sprintf("key%d", idx))
+		_, putErr := repairTestMap.Put(key, key)
+		if putErr != nil { t.Errorf("error on put: %s", putErr.Error()) }
+	}
+
+	corruptLatestCommit := func(t *testing.T) *mmcmap.MMCMapMetaData {
+		meta, readMetaErr := repairTestMap.ReadMetaFromMemMap()
+		if readMetaErr != nil { t.Fatalf("error reading metadata: %s", readMetaErr.Error()) }
+
+		root, readRootErr := repairTestMap.ReadNodeFromMemMap(meta.RootOffset)
+		if readRootErr != nil { t.Fatalf("error reading root: %s", readRootErr.Error()) }
+
+		file, fileErr := os.OpenFile(rpTestPath, os.O_RDWR, 0600)
+		if fileErr != nil { t.Fatalf("error opening file: %s", fileErr.Error()) }
+		defer file.Close()
+
+		for _, child := range root.Children {
+			node, readErr := repairTestMap.ReadNodeFromMemMap(child.StartOffset)
+			if readErr != nil { t.Fatalf("error reading child: %s", readErr.Error()) }
+			if node.Version != meta.Version { continue }
+
+			_, writeErr := file.WriteAt([]byte{ 1, 2, 3, 4, 5, 6, 7, 8 }, int64(child.StartOffset) + mmcmap.NodeStartOffsetIdx)
+			if writeErr != nil { t.Fatalf("error corrupting node: %s", writeErr.Error()) }
+
+			return meta
+		}
+
+		t.Fatal("no node written by the latest commit")
+		return nil
+	}
+
+	t.Run("Test Repair Intact Map", func(t *testing.T) {
+		report, repairErr := repairTestMap.Repair()
+		if repairErr != nil { t.Fatalf("error on repair: %s", repairErr.Error()) }
+
+		if report.IsRepaired { t.Error("repair modified an intact map") }
+		if report.Version != report.PrevVersion { t.Errorf("repair changed version: actual(%d), expected(%d)", report.Version, report.PrevVersion) }
+	})
+
+	t.Run("Test Repair Rolls Back Damaged Commit", func(t *testing.T) {
+		_, putErr := repairTestMap.Put([]byte("damaged"), []byte("value"))
+		if putErr != nil { t.Errorf("error on put: %s", putErr.Error()) }
+
+		meta := corruptLatestCommit(t)
+
+		report, repairErr := repairTestMap.Repair()
+		if repairErr != nil { t.Fatalf("error on repair: %s", repairErr.Error()) }
+
+		if ! report.IsRepaired { t.Error("repair did not modify a damaged map") }
+		if report.Version != meta.Version - 1 { t.Errorf("repaired version mismatch: actual(%d), expected(%d)", report.Version, meta.Version - 1) }
+		if report.DiscardedVersions != 1 { t.Errorf("discarded versions mismatch: actual(%d), expected(1)", report.DiscardedVersions) }
+		if report.KeyCount != 200 { t.Errorf("key count mismatch: actual(%d), expected(200)", report.KeyCount) }
+
+		verifyReport, verifyErr := repairTestMap.Verify()
+		if verifyErr != nil { t.Fatalf("error on verify: %s", verifyErr.Error()) }
+		if ! verifyReport.IsValid { t.Errorf("repaired map is not valid: %v", verifyReport.Findings) }
+
+		val, getErr := repairTestMap.Get([]byte("damaged"))
+		if getErr != nil { t.Errorf("error on get: %s", getErr.Error()) }
+		if val != nil { t.Errorf("rolled back key still present: %s", val) }
+
+		_, putErr = repairTestMap.Put([]byte("after"), []byte("value"))
+		if putErr != nil { t.Errorf("error on put after repair: %s", putErr.Error()) }
+
+		val, getErr = repairTestMap.Get([]byte("key42"))
+		if getErr != nil { t.Errorf("error on get: %s", getErr.Error()) }
+		if ! bytes.Equal(val, []byte("key42")) { t.Errorf("value mismatch after repair: actual(%s), expected(key42)", val) }
+	})
+
+	t.Run("Test Repair File With Damaged Metadata", func(t *testing.T) {
+		meta, readMetaErr := repairTestMap.ReadMetaFromMemMap()
+		if readMetaErr != nil { t.Fatalf("error reading metadata: %s", readMetaErr.Error()) }
+
+		closeErr := repairTestMap.Close()
+		if closeErr != nil { t.Fatalf("error on close: %s", closeErr.Error()) }
+
+		file, fileErr := os.OpenFile(rpTestPath, os.O_RDWR, 0600)
+		if fileErr != nil { t.Fatalf("error opening file: %s", fileErr.Error()) }
+
+		corruptMeta := &mmcmap.MMCMapMetaData{ Version: meta.Version, RootOffset: meta.RootOffset, EndMmapOffset: 1 << 40 }
+		_, writeErr := file.WriteAt(corruptMeta.SerializeMetaData(), mmcmap.MetaVersionIdx)
+		if writeErr != nil { t.Fatalf("error corrupting metadata: %s", writeErr.Error()) }
+		file.Close()
+
+		_, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: rpTestPath })
+		if openErr == nil { t.Fatal("expected error opening mmcmap with damaged metadata") }
+
+		report, repairErr := mmcmap.RepairFile(mmcmap.MMCMapOpts{ Filepath: rpTestPath })
+		if repairErr != nil { t.Fatalf("error on repair file: %s", repairErr.Error()) }
+		if report.Version != meta.Version { t.Errorf("repaired version mismatch: actual(%d), expected(%d)", report.Version, meta.Version) }
+
+		var openAfterErr error
+		repairTestMap, openAfterErr = mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: rpTestPath })
+		if openAfterErr != nil { t.Fatalf("error opening repaired file: %s", openAfterErr.Error()) }
+
+		val, getErr := repairTestMap.Get([]byte("after"))
+		if getErr != nil { t.Errorf("error on get: %s", getErr.Error()) }
+		if ! bytes.Equal(val, []byte("value")) { t.Errorf("value mismatch after repair: actual(%s), expected(value)", val) }
+	})
+
+	t.Log("Done")
+}
@@ -0,0 +1,150 @@
+package mmcmaptests
+
+import "bytes"
+import "errors"
+import "fmt"
+import "net"
+import "os"
+import "path/filepath"
+import "testing"
+import "time"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var rtPrimaryTestPath = filepath.Join(os.TempDir(), "testreplicatcpprimary")
+var rtPrimaryTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initRtMapErr error
+	os.Remove(rtPrimaryTestPath)
+
+	rtPrimaryTestMap, initRtMapErr = mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: rtPrimaryTestPath })
+	if initRtMapErr != nil { panic(initRtMapErr.Error()) }
+
+	fmt.Println("replica tcp test mmcmap initialized")
+}
+
+
+func TestMMCMapReplicaTCP(t *testing.T) {
+	defer rtPrimaryTestMap.Remove()
+
+	listener, listenErr := net.Listen("tcp", "127.0.0.1:0")
+	if listenErr != nil { t.Fatalf("error on listen: %s", listenErr.Error()) }
+
+	served := make(chan error, 1)
+	go func() { served <- rtPrimaryTestMap.ServeReplication(listener, mmcmap.ReplicationOpts{ PollInterval: time.Millisecond }) }()
+
+	addr := listener.Addr().String()
+
+	putRange := func(start, end int) {
+		for idx := start; idx < end; idx++ {
+			key := []byte(fmt.Sprintf("tcpkey%d", idx))
+			_, putErr := rtPrimaryTestMap.Put(key, key)
+			if putErr != nil { t.Errorf("error on put: %s", putErr.Error()) }
+		}
+	}
+
+	verifyRange := func(replica *mmcmap.MMCMap, end int) {
+		for idx := 0; idx < end; idx++ {
+			key := []byte(fmt.Sprintf("tcpkey%d", idx))
+			val, getErr := replica.Get(key)
+			if getErr != nil { t.Errorf("error getting from replica: %s", getErr.Error()) }
+			if ! bytes.Equal(val, key) { t.Errorf("replica value mismatch: actual(%s), expected(%s)", val, key) }
+		}
+	}
+
+	awaitVersion := func(replica *mmcmap.MMCMap) {
+		expected, _ := readVersion(rtPrimaryTestMap)
+		deadline := time.Now().Add(10 * time.Second)
+
+		for time.Now().Before(deadline) {
+			version, _ := readVersion(replica)
+			if version == expected { return }
+
+			time.Sleep(5 * time.Millisecond)
+		}
+
+		t.Fatalf("follower did not reach the primary version %d", expected)
+	}
+
+	putRange(0, 100)
+
+	t.Run("Test TCP Replica Source", func(t *testing.T) {
+		replicaTestPath := filepath.Join(os.TempDir(), "testreplicatcpsource")
+		os.Remove(replicaTestPath)
+
+		replica, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: replicaTestPath, ReplicaSource: mmcmap.NewTCPReplicaSource(addr) })
+		if openErr != nil { t.Fatalf("error opening replica: %s", openErr.Error()) }
+		defer replica.Remove()
+
+		var buf bytes.Buffer
+		primaryVersion, _ := readVersion(rtPrimaryTestMap)
+		exportErr := rtPrimaryTestMap.ExportDelta(&buf, primaryVersion - 1)
+		if exportErr != nil { t.Fatalf("error exporting delta: %s", exportErr.Error()) }
+
+		catchUpErr := replica.CatchUp(&buf)
+		if catchUpErr != nil { t.Fatalf("error catching up: %s", catchUpErr.Error()) }
+
+		status, _ := replica.ReplicaStatus()
+		if status.Gaps != 1 { t.Errorf("gap was not detected: actual(%d), expected(1)", status.Gaps) }
+
+		verifyRange(replica, 100)
+	})
+
+	t.Run("Test Follower", func(t *testing.T) {
+		followerTestPath := filepath.Join(os.TempDir(), "testreplicatcpfollower")
+		os.Remove(followerTestPath)
+
+		follower, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: followerTestPath, FollowAddr: addr })
+		if openErr != nil { t.Fatalf("error opening follower: %s", openErr.Error()) }
+		defer follower.Remove()
+
+		awaitVersion(follower)
+		verifyRange(follower, 100)
+
+		putRange(100, 300)
+		_, delErr := rtPrimaryTestMap.Delete([]byte("tcpkey0"))
+		if delErr != nil { t.Errorf("error on delete: %s", delErr.Error()) }
+
+		awaitVersion(follower)
+
+		val, _ := follower.Get([]byte("tcpkey0"))
+		if val != nil { t.Errorf("delete was not replicated: actual(%s), expected(nil)", val) }
+
+		val, _ = follower.Get([]byte("tcpkey299"))
+		if ! bytes.Equal(val, []byte("tcpkey299")) { t.Errorf("put was not replicated: actual(%s), expected(tcpkey299)", val) }
+
+		keyCount, _ := follower.Len()
+		if keyCount != 299 { t.Errorf("follower key count mismatch: actual(%d), expected(299)", keyCount) }
+
+		_, putErr := follower.Put([]byte("local"), []byte("write"))
+		if ! errors.Is(putErr, mmcmap.ErrFollowerReadOnly) { t.Errorf("expected ErrFollowerReadOnly, got: %v", putErr) }
+
+		_, asyncErr := follower.PutAsync([]byte("local"), []byte("write")).Wait()
+		if ! errors.Is(asyncErr, mmcmap.ErrFollowerReadOnly) { t.Errorf("expected ErrFollowerReadOnly from PutAsync, got: %v", asyncErr) }
+
+		_, asyncErr = follower.DeleteAsync([]byte("tcpkey1")).Wait()
+		if ! errors.Is(asyncErr, mmcmap.ErrFollowerReadOnly) { t.Errorf("expected ErrFollowerReadOnly from DeleteAsync, got: %v", asyncErr) }
+
+		val, _ = follower.Get([]byte("local"))
+		if val != nil { t.Errorf("follower accepted a local write: actual(%s), expected(nil)", val) }
+
+		status, _ := follower.ReplicaStatus()
+		if status.FollowErrors != 0 { t.Errorf("follower lost its connection: actual(%d), expected(0)", status.FollowErrors) }
+	})
+
+	t.Run("Test Stop Serving", func(t *testing.T) {
+		listener.Close()
+
+		select {
+			case serveErr := <-served:
+				if serveErr != nil { t.Errorf("error serving replication: %s", serveErr.Error()) }
+			case <-time.After(5 * time.Second):
+				t.Error("ServeReplication did not return after the listener was closed")
+		}
+	})
+
+	t.Log("Done")
+}
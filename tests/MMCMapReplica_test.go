@@ -0,0 +1,122 @@
+package mmcmaptests
+
+import "bytes"
+import "errors"
+import "fmt"
+import "io"
+import "os"
+import "path/filepath"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var rpPrimaryTestPath = filepath.Join(os.TempDir(), "testreplicaprimary")
+var rpReplicaTestPath = filepath.Join(os.TempDir(), "testreplica")
+var rpPrimaryTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initRpMapErr error
+	os.Remove(rpPrimaryTestPath)
+
+	rpPrimaryTestMap, initRpMapErr = mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: rpPrimaryTestPath })
+	if initRpMapErr != nil { panic(initRpMapErr.Error()) }
+
+	fmt.Println("replica test mmcmap initialized")
+}
+
+// snapshotOnlySource fails every delta request so the replica must fall back to a snapshot
+type snapshotOnlySource struct {
+	mmcmap.ReplicaSource
+}
+
+func (source *snapshotOnlySource) DeltaSince(version uint64) (io.Reader, error) {
+	return nil, errors.New("delta unavailable")
+}
+
+
+func TestMMCMapReplica(t *testing.T) {
+	defer rpPrimaryTestMap.Remove()
+
+	putRange := func(start, end int) {
+		for idx := start; idx < end; idx++ {
+			key := []byte(fmt.Sprintf("replkey%d", idx))
+			_, putErr := rpPrimaryTestMap.Put(key, key)
+			if putErr != nil { t.Errorf("error putting key in mmcmap: %s", putErr.Error()) }
+		}
+	}
+
+	verifyRange := func(replica *mmcmap.MMCMap, end int) {
+		for idx := 0; idx < end; idx++ {
+			key := []byte(fmt.Sprintf("replkey%d", idx))
+			val, getErr := replica.Get(key)
+			if getErr != nil { t.Errorf("error getting from replica: %s", getErr.Error()) }
+			if ! bytes.Equal(val, key) { t.Errorf("replica value mismatch: actual(%s), expected(%s)", val, key) }
+		}
+	}
+
+	exportSince := func(version uint64) *bytes.Buffer {
+		var buf bytes.Buffer
+		exportErr := rpPrimaryTestMap.ExportDelta(&buf, version)
+		if exportErr != nil { t.Fatalf("error exporting delta: %s", exportErr.Error()) }
+		return &buf
+	}
+
+	t.Run("Test Catch Up With Gap Detection", func(t *testing.T) {
+		os.Remove(rpReplicaTestPath)
+		replica, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: rpReplicaTestPath, ReplicaSource: mmcmap.NewReplicaSource(rpPrimaryTestMap) })
+		if openErr != nil { t.Fatalf("error opening replica: %s", openErr.Error()) }
+		defer replica.Remove()
+
+		putRange(0, 50)
+
+		status, statusErr := replica.ReplicaStatus()
+		if statusErr != nil { t.Fatalf("error getting replica status: %s", statusErr.Error()) }
+
+		catchUpErr := replica.CatchUp(exportSince(status.Version))
+		if catchUpErr != nil { t.Errorf("error catching up: %s", catchUpErr.Error()) }
+		verifyRange(replica, 50)
+
+		putRange(50, 100)
+		catchUpErr = replica.CatchUp(exportSince(75))
+		if catchUpErr != nil { t.Errorf("error catching up across gap: %s", catchUpErr.Error()) }
+		verifyRange(replica, 100)
+
+		status, statusErr = replica.ReplicaStatus()
+		if statusErr != nil { t.Fatalf("error getting replica status: %s", statusErr.Error()) }
+		if status.Gaps != 1 { t.Errorf("unexpected number of gaps: actual(%d), expected(1)", status.Gaps) }
+		if status.Deltas != 2 { t.Errorf("unexpected number of deltas: actual(%d), expected(2)", status.Deltas) }
+
+		_, putErr := replica.Put([]byte("after"), []byte("catch up"))
+		if putErr != nil { t.Errorf("error putting key in replica: %s", putErr.Error()) }
+	})
+
+	t.Run("Test Gap Without Source", func(t *testing.T) {
+		os.Remove(rpReplicaTestPath)
+		replica, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: rpReplicaTestPath })
+		if openErr != nil { t.Fatalf("error opening replica: %s", openErr.Error()) }
+		defer replica.Remove()
+
+		catchUpErr := replica.CatchUp(exportSince(10))
+		if catchUpErr != mmcmap.ErrReplicaGap { t.Errorf("expected gap error, got: %v", catchUpErr) }
+	})
+
+	t.Run("Test Snapshot Fallback", func(t *testing.T) {
+		os.Remove(rpReplicaTestPath)
+		source := &snapshotOnlySource{ mmcmap.NewReplicaSource(rpPrimaryTestMap) }
+		replica, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: rpReplicaTestPath, ReplicaSource: source })
+		if openErr != nil { t.Fatalf("error opening replica: %s", openErr.Error()) }
+		defer replica.Remove()
+
+		catchUpErr := replica.CatchUp(exportSince(10))
+		if catchUpErr != nil { t.Errorf("error catching up from snapshot: %s", catchUpErr.Error()) }
+		verifyRange(replica, 100)
+
+		status, statusErr := replica.ReplicaStatus()
+		if statusErr != nil { t.Fatalf("error getting replica status: %s", statusErr.Error()) }
+		if status.Snapshots != 1 { t.Errorf("unexpected number of snapshots: actual(%d), expected(1)", status.Snapshots) }
+	})
+
+	t.Log("Done")
+}
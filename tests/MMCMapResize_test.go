@@ -0,0 +1,120 @@
+package mmcmaptests
+
+import "bytes"
+import "fmt"
+import "os"
+import "path/filepath"
+import "sync"
+import "sync/atomic"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+import "github.com/sirgallo/mmcmap/common/mmap"
+
+
+var resizeTestPath = filepath.Join(os.TempDir(), "testresize")
+var resizeTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initResizeMapErr error
+	os.Remove(resizeTestPath)
+
+	opts := mmcmap.MMCMapOpts{ Filepath: resizeTestPath, CopyOnRead: mmcmap.CopyNever }
+	resizeTestMap, initResizeMapErr = mmcmap.Open(opts)
+	if initResizeMapErr != nil { panic(initResizeMapErr.Error()) }
+
+	fmt.Println("resize test mmcmap initialized")
+}
+
+
+func TestMMCMapResize(t *testing.T) {
+	defer resizeTestMap.Remove()
+
+	for idx := 0; idx < 100; idx++ {
+		key := []byte(fmt.Sprintf("key%d", idx))
+		_, putErr := resizeTestMap.Put(key, key)
+		if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+	}
+
+	t.Run("Test Grow In Place", func(t *testing.T) {
+		before := resizeTestMap.Data.Load().(mmap.MMap)
+		value, _ := resizeTestMap.Get([]byte("key1"))
+
+		var reads int64
+		stop := make(chan struct{})
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for {
+				select {
+					case <-stop:
+						return
+					default:
+						readValue, getErr := resizeTestMap.Get([]byte("key2"))
+						if getErr != nil || string(readValue) != "key2" { t.Errorf("read mismatch during resize: actual(%s), err(%v)", readValue, getErr) }
+						atomic.AddInt64(&reads, 1)
+				}
+			}
+		}()
+
+		large := bytes.Repeat([]byte("x"), 1 << 20)
+		for idx := 0; idx < 80; idx++ {
+			_, putErr := resizeTestMap.Put([]byte(fmt.Sprintf("large%d", idx)), large)
+			if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+		}
+
+		close(stop)
+		wg.Wait()
+
+		after := resizeTestMap.Data.Load().(mmap.MMap)
+		if len(after) <= len(before) { t.Fatalf("memory map was not resized: actual(%d), before(%d)", len(after), len(before)) }
+		if &after[0] != &before[0] { t.Errorf("memory map moved on resize") }
+		if string(value) != "key1" { t.Errorf("value read before resize mismatch: actual(%s), expected(key1)", value) }
+		if atomic.LoadInt64(&reads) == 0 { t.Errorf("no reads completed during resize") }
+	})
+
+	t.Run("Test Reads After Grow", func(t *testing.T) {
+		for idx := 0; idx < 80; idx++ {
+			value, getErr := resizeTestMap.Get([]byte(fmt.Sprintf("large%d", idx)))
+			if getErr != nil { t.Fatalf("error on get: %s", getErr.Error()) }
+			if len(value) != 1 << 20 { t.Fatalf("value length mismatch: actual(%d), expected(%d)", len(value), 1 << 20) }
+		}
+
+		report, verifyErr := resizeTestMap.Verify()
+		if verifyErr != nil { t.Fatalf("error on verify: %s", verifyErr.Error()) }
+		if ! report.IsValid { t.Errorf("map invalid after resize: %v", report.Findings) }
+	})
+
+	t.Run("Test Remap After Compact", func(t *testing.T) {
+		_, compactErr := resizeTestMap.Compact()
+		if compactErr != nil { t.Fatalf("error on compact: %s", compactErr.Error()) }
+
+		value, getErr := resizeTestMap.Get([]byte("key1"))
+		if getErr != nil || string(value) != "key1" { t.Errorf("value mismatch after compact: actual(%s), err(%v)", value, getErr) }
+	})
+
+	t.Run("Test Reserve Address Space", func(t *testing.T) {
+		path := filepath.Join(os.TempDir(), "testresizereserved")
+		os.Remove(path)
+		defer os.Remove(path)
+
+		reservedMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: path, ReserveAddressSpace: 256 << 30 })
+		if openErr == mmap.ErrFixedMappingUnsupported { t.Skip("fixed mappings unsupported") }
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+
+		if len(reservedMap.Reservation) != 256 << 30 { t.Errorf("reservation size mismatch: actual(%d), expected(%d)", len(reservedMap.Reservation), 256 << 30) }
+
+		_, putErr := reservedMap.Put([]byte("key"), []byte("value"))
+		if putErr != nil { t.Errorf("error on put: %s", putErr.Error()) }
+		reservedMap.Close()
+
+		_, openErr = mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: path, ReserveAddressSpace: 1 << 62 })
+		if openErr == nil { t.Errorf("open succeeded without reserving the requested address space") }
+	})
+
+	t.Log("Done")
+}
@@ -0,0 +1,217 @@
+package mmcmaptests
+
+import "bytes"
+import "errors"
+import "fmt"
+import "os"
+import "path/filepath"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var segmentsTestPath = filepath.Join(os.TempDir(), "testsegments")
+var segmentsTestMap *mmcmap.MMCMap
+var segmentsTestSize = uint64(mmcmap.MinSegmentSize)
+
+
+func init() {
+	var initSegmentsMapErr error
+	mmcmap.Destroy(segmentsTestPath)
+
+	opts := mmcmap.MMCMapOpts{ Filepath: segmentsTestPath, SegmentSize: segmentsTestSize }
+	segmentsTestMap, initSegmentsMapErr = mmcmap.Open(opts)
+	if initSegmentsMapErr != nil { panic(initSegmentsMapErr.Error()) }
+
+	fmt.Println("segments test mmcmap initialized")
+}
+
+
+func TestMMCMapSegments(t *testing.T) {
+	defer segmentsTestMap.Remove()
+
+	segmentPath := func(id int) string { return fmt.Sprintf("%s.%06d", segmentsTestPath, id) }
+	segmentValue := func(round, idx int) []byte { return bytes.Repeat([]byte{ byte('a' + round) }, 2048 + idx % 7) }
+
+	putRound := func(t *testing.T, round int) uint64 {
+		var version uint64
+		for idx := range make([]int, 1000) {
+			var putErr error
+			version, putErr = segmentsTestMap.PutVersioned([]byte(fmt.Sprintf("segment%04d", idx)), segmentValue(round, idx))
+			if putErr != nil { t.Fatalf("error putting key in mmcmap: %s", putErr.Error()) }
+		}
+
+		return version
+	}
+
+	checkRound := func(t *testing.T, mmcMap *mmcmap.MMCMap, round int) {
+		for idx := range make([]int, 1000) {
+			key := []byte(fmt.Sprintf("segment%04d", idx))
+			value, getErr := mmcMap.Get(key)
+			if getErr != nil { t.Fatalf("error getting key from mmcmap: %s", getErr.Error()) }
+			if ! bytes.Equal(value, segmentValue(round, idx)) { t.Fatalf("value mismatch for key %s: actual(%d bytes), expected(%d bytes)", key, len(value), len(segmentValue(round, idx))) }
+		}
+	}
+
+	var firstVersion uint64
+
+	t.Run("Test Grow By Segments", func(t *testing.T) {
+		firstVersion = putRound(t, 0)
+		checkRound(t, segmentsTestMap, 0)
+
+		stat, statErr := os.Stat(segmentsTestPath)
+		if statErr != nil { t.Fatalf("error on stat of first segment: %s", statErr.Error()) }
+		if uint64(stat.Size()) != segmentsTestSize { t.Errorf("first segment size mismatch: actual(%d), expected(%d)", stat.Size(), segmentsTestSize) }
+
+		for _, id := range []int{ 1, 2 } {
+			stat, statErr = os.Stat(segmentPath(id))
+			if statErr != nil { t.Fatalf("segment %d was not created: %s", id, statErr.Error()) }
+			if uint64(stat.Size()) != segmentsTestSize { t.Errorf("segment %d size mismatch: actual(%d), expected(%d)", id, stat.Size(), segmentsTestSize) }
+		}
+
+		fileSize, sizeErr := segmentsTestMap.FileSize()
+		if sizeErr != nil { t.Fatalf("error getting file size: %s", sizeErr.Error()) }
+		if uint64(fileSize) <= 2 * segmentsTestSize { t.Errorf("file size does not include every segment: actual(%d)", fileSize) }
+	})
+
+	t.Run("Test Reopen Segments", func(t *testing.T) {
+		closeErr := segmentsTestMap.Close()
+		if closeErr != nil { t.Fatalf("error on close: %s", closeErr.Error()) }
+
+		var openErr error
+		segmentsTestMap, openErr = mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: segmentsTestPath })
+		if openErr != nil { t.Fatalf("error reopening segmented file without a segment size: %s", openErr.Error()) }
+		if segmentsTestMap.Header.SegmentSize != segmentsTestSize { t.Errorf("segment size mismatch: actual(%d), expected(%d)", segmentsTestMap.Header.SegmentSize, segmentsTestSize) }
+
+		checkRound(t, segmentsTestMap, 0)
+	})
+
+	t.Run("Test Reclaim Segments", func(t *testing.T) {
+		putRound(t, 1)
+
+		report, reclaimErr := segmentsTestMap.ReclaimSegments()
+		if reclaimErr != nil { t.Fatalf("error reclaiming segments: %s", reclaimErr.Error()) }
+		if report.RemovedSegments == 0 { t.Fatalf("no segments were reclaimed after every key was overwritten: %+v", report) }
+		if report.ReclaimedBytes != report.RemovedSegments * segmentsTestSize { t.Errorf("reclaimed bytes mismatch: actual(%d), expected(%d)", report.ReclaimedBytes, report.RemovedSegments * segmentsTestSize) }
+
+		for id := 1; uint64(id) < report.FirstSegment; id++ {
+			_, statErr := os.Stat(segmentPath(id))
+			if ! errors.Is(statErr, os.ErrNotExist) { t.Errorf("segment %d was not removed: stat error(%v)", id, statErr) }
+		}
+
+		checkRound(t, segmentsTestMap, 1)
+
+		_, diffErr := segmentsTestMap.Diff(firstVersion, report.MinVersion)
+		if ! errors.Is(diffErr, mmcmap.ErrVersionUnavailable) { t.Errorf("expected ErrVersionUnavailable diffing a reclaimed version, got: %v", diffErr) }
+
+		history, historyErr := segmentsTestMap.History([]byte("segment0000"), 0)
+		if historyErr != nil { t.Fatalf("error getting history: %s", historyErr.Error()) }
+		for _, pair := range history {
+			if ! bytes.Equal(pair.Value, segmentValue(1, 0)) { t.Errorf("history returned a value from a reclaimed segment: %d bytes", len(pair.Value)) }
+		}
+
+		again, reclaimErr := segmentsTestMap.ReclaimSegments()
+		if reclaimErr != nil { t.Fatalf("error on repeated reclaim: %s", reclaimErr.Error()) }
+		if again.RemovedSegments != 0 { t.Errorf("repeated reclaim removed segments: actual(%d), expected(0)", again.RemovedSegments) }
+	})
+
+	t.Run("Test Reopen After Reclaim", func(t *testing.T) {
+		closeErr := segmentsTestMap.Close()
+		if closeErr != nil { t.Fatalf("error on close: %s", closeErr.Error()) }
+
+		var openErr error
+		segmentsTestMap, openErr = mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: segmentsTestPath, SegmentSize: segmentsTestSize })
+		if openErr != nil { t.Fatalf("error reopening after reclaim: %s", openErr.Error()) }
+
+		checkRound(t, segmentsTestMap, 1)
+		putRound(t, 2)
+		checkRound(t, segmentsTestMap, 2)
+	})
+
+	t.Run("Test Unsupported Operations", func(t *testing.T) {
+		_, compactErr := segmentsTestMap.Compact()
+		if ! errors.Is(compactErr, mmcmap.ErrSegmentedUnsupported) { t.Errorf("expected ErrSegmentedUnsupported on compact, got: %v", compactErr) }
+
+		_, mismatchErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: segmentsTestPath, SegmentSize: 2 * segmentsTestSize })
+		if ! errors.Is(mismatchErr, mmcmap.ErrSegmentSizeMismatch) { t.Errorf("expected ErrSegmentSizeMismatch, got: %v", mismatchErr) }
+
+		invalidPath := filepath.Join(os.TempDir(), "testsegmentsinvalid")
+		defer mmcmap.Destroy(invalidPath)
+
+		_, invalidErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: invalidPath, SegmentSize: mmcmap.MinSegmentSize + 1 })
+		if ! errors.Is(invalidErr, mmcmap.ErrInvalidSegmentSize) { t.Errorf("expected ErrInvalidSegmentSize, got: %v", invalidErr) }
+
+		plainPath := filepath.Join(os.TempDir(), "testsegmentsplain")
+		mmcmap.Destroy(plainPath)
+
+		plainMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: plainPath })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+		defer plainMap.Remove()
+
+		_, reclaimErr := plainMap.ReclaimSegments()
+		if ! errors.Is(reclaimErr, mmcmap.ErrNotSegmented) { t.Errorf("expected ErrNotSegmented, got: %v", reclaimErr) }
+	})
+
+	t.Run("Test Segmented Replica", func(t *testing.T) {
+		var snapshot bytes.Buffer
+		exportErr := segmentsTestMap.ExportSnapshot(&snapshot)
+		if exportErr != nil { t.Fatalf("error exporting snapshot: %s", exportErr.Error()) }
+
+		replicaPath := filepath.Join(os.TempDir(), "testsegmentsreplica")
+		mmcmap.Destroy(replicaPath)
+
+		replicaMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: replicaPath, SegmentSize: segmentsTestSize })
+		if openErr != nil { t.Fatalf("error opening replica: %s", openErr.Error()) }
+		defer func() { replicaMap.Remove() }()
+
+		catchUpErr := replicaMap.CatchUp(bytes.NewReader(snapshot.Bytes()))
+		if catchUpErr != nil { t.Fatalf("error applying snapshot: %s", catchUpErr.Error()) }
+		checkRound(t, replicaMap, 2)
+
+		closeErr := replicaMap.Close()
+		if closeErr != nil { t.Fatalf("error closing replica: %s", closeErr.Error()) }
+
+		replicaMap, openErr = mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: replicaPath })
+		if openErr != nil { t.Fatalf("error reopening replica: %s", openErr.Error()) }
+		checkRound(t, replicaMap, 2)
+
+		plainPath := filepath.Join(os.TempDir(), "testsegmentsplainreplica")
+		mmcmap.Destroy(plainPath)
+
+		plainMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: plainPath })
+		if openErr != nil { t.Fatalf("error opening replica: %s", openErr.Error()) }
+		defer plainMap.Remove()
+
+		catchUpErr = plainMap.CatchUp(bytes.NewReader(snapshot.Bytes()))
+		if ! errors.Is(catchUpErr, mmcmap.ErrSegmentSizeMismatch) { t.Errorf("expected ErrSegmentSizeMismatch applying a segmented snapshot, got: %v", catchUpErr) }
+	})
+
+	t.Run("Test Truncating Clear", func(t *testing.T) {
+		clearErr := segmentsTestMap.Clear(mmcmap.ClearOpts{ Truncate: true })
+		if clearErr != nil { t.Fatalf("error on clear: %s", clearErr.Error()) }
+
+		matches, globErr := filepath.Glob(segmentsTestPath + ".[0-9]*")
+		if globErr != nil { t.Fatalf("error listing segments: %s", globErr.Error()) }
+		if len(matches) != 0 { t.Errorf("segments remain after truncating clear: %v", matches) }
+
+		_, putErr := segmentsTestMap.Put([]byte("after"), []byte("clear"))
+		if putErr != nil { t.Fatalf("error putting key after clear: %s", putErr.Error()) }
+
+		value, getErr := segmentsTestMap.Get([]byte("after"))
+		if getErr != nil { t.Fatalf("error getting key after clear: %s", getErr.Error()) }
+		if string(value) != "clear" { t.Errorf("value mismatch after clear: actual(%s), expected(clear)", value) }
+	})
+
+	t.Run("Test Destroy Segments", func(t *testing.T) {
+		putRound(t, 3)
+
+		removeErr := segmentsTestMap.Remove()
+		if removeErr != nil { t.Fatalf("error on remove: %s", removeErr.Error()) }
+
+		matches, globErr := filepath.Glob(segmentsTestPath + "*")
+		if globErr != nil { t.Fatalf("error listing segments: %s", globErr.Error()) }
+		if len(matches) != 0 { t.Errorf("files remain after remove: %v", matches) }
+	})
+
+	t.Log("Done")
+}
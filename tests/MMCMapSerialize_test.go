@@ -4,6 +4,7 @@ import "bytes"
 import "fmt"
 import "os"
 import "path/filepath"
+import "sync"
 import "testing"
 
 import "github.com/sirgallo/mmcmap"
@@ -32,8 +33,8 @@ func TestMMCMapSerialize(t *testing.T) {
 	t.Run("Test Put Meta From Mem Map", func(t *testing.T) {
 		expected := &mmcmap.MMCMapMetaData{
 			Version: 0,
-			RootOffset: 24,
-			EndMmapOffset: 55,
+			RootOffset: mmcmap.InitRootOffset,
+			EndMmapOffset: mmcmap.InitRootOffset + mmcmap.NodeChildrenIdx,
 		}
 
 		mMap := serializePcMap.Data.Load().(mmap.MMap)
@@ -57,8 +58,8 @@ func TestMMCMapSerialize(t *testing.T) {
 	t.Run("Test Get Meta From Mem Map", func(t *testing.T) {
 		expected := &mmcmap.MMCMapMetaData{
 			Version: 0,
-			RootOffset: 24,
-			EndMmapOffset: 55,
+			RootOffset: mmcmap.InitRootOffset,
+			EndMmapOffset: mmcmap.InitRootOffset + mmcmap.NodeChildrenIdx,
 		}
 
 		sMeta := expected.SerializeMetaData()
@@ -83,7 +84,7 @@ func TestMMCMapSerialize(t *testing.T) {
 	t.Run("Test Read Write LNode From Mem Map", func(t *testing.T) {
 		newNode := &mmcmap.MMCMapNode{
 			Version: 0,
-			StartOffset: 24,
+			StartOffset: mmcmap.InitRootOffset,
 			Bitmap: 0,
 			IsLeaf: true,
 			KeyLength: uint16(len([]byte("test"))),
@@ -94,7 +95,7 @@ func TestMMCMapSerialize(t *testing.T) {
 		_, writeErr := serializePcMap.WriteNodeToMemMap(newNode)
 		if writeErr != nil { t.Errorf("error writing node, (%s)", writeErr.Error()) }
 
-		deserialized, readErr := serializePcMap.ReadNodeFromMemMap(24)
+		deserialized, readErr := serializePcMap.ReadNodeFromMemMap(mmcmap.InitRootOffset)
 		if readErr != nil { t.Errorf("error reading node, (%s)", readErr.Error()) }
 
 		if deserialized.Version != newNode.Version {
@@ -105,7 +106,7 @@ func TestMMCMapSerialize(t *testing.T) {
 			t.Errorf("deserialized start not expected: actual(%d), expected(%d)", deserialized.StartOffset, newNode.StartOffset)
 		}
 
-		expectedEndOffset := 24 + uint64(mmcmap.NodeKeyIdx + 4 + 4 - 1)
+		expectedEndOffset := mmcmap.InitRootOffset + uint64(mmcmap.NodeKeyIdx + 4 + 4 - 1)
 		if deserialized.EndOffset != expectedEndOffset {
 			t.Errorf("deserialized end not expected: actual(%d), expected(%d)", deserialized.EndOffset, expectedEndOffset)
 		}
@@ -130,7 +131,7 @@ func TestMMCMapSerialize(t *testing.T) {
 	t.Run("Test Read Write INode From Mem Map", func(t *testing.T) {
 		newNode := &mmcmap.MMCMapNode{
 			Version: 1,
-			StartOffset: 24,
+			StartOffset: mmcmap.InitRootOffset,
 			Bitmap: 1,
 			IsLeaf: false,
 			KeyLength: uint16(0),
@@ -142,7 +143,7 @@ func TestMMCMapSerialize(t *testing.T) {
 		_, writeErr := serializePcMap.WriteNodeToMemMap(newNode)
 		if writeErr != nil { t.Errorf("error writing node, (%s)", writeErr.Error()) }
 
-		deserialized, readErr := serializePcMap.ReadNodeFromMemMap(24)
+		deserialized, readErr := serializePcMap.ReadNodeFromMemMap(mmcmap.InitRootOffset)
 		if readErr != nil { t.Errorf("error reading node, (%s)", readErr.Error()) }
 
 		if deserialized.Version != newNode.Version {
@@ -153,7 +154,7 @@ func TestMMCMapSerialize(t *testing.T) {
 			t.Errorf("deserialized start not expected: actual(%d), expected(%d)", deserialized.StartOffset, newNode.StartOffset)
 		}
 
-		expectedEndOffset := 24 + uint64(mmcmap.NodeChildrenIdx + 8 - 1)
+		expectedEndOffset := mmcmap.InitRootOffset + uint64(mmcmap.NodeChildrenIdx + 8 - 1)
 		if deserialized.EndOffset != expectedEndOffset {
 			t.Errorf("deserialized end not expected: actual(%d), expected(%d)", deserialized.EndOffset, expectedEndOffset)
 		}
@@ -167,5 +168,123 @@ func TestMMCMapSerialize(t *testing.T) {
 		}
 	})
 
+	t.Run("Test Leaf Round Trip", func(t *testing.T) {
+		leaf := &mmcmap.MMCMapNode{ Version: 7, StartOffset: 1024, IsLeaf: true, KeyLength: 5, Key: []byte("hello"), Value: []byte("world"), ExpiresAt: 42 }
+
+		sLeaf, serializeErr := leaf.SerializeNode(leaf.StartOffset)
+		if serializeErr != nil { t.Fatalf("error serializing leaf: %s", serializeErr.Error()) }
+
+		expectedSize := mmcmap.NodeKeyIdx + len(leaf.Key) + mmcmap.OffsetSize + len(leaf.Value)
+		if len(sLeaf) != expectedSize { t.Errorf("serialized leaf size mismatch: actual(%d), expected(%d)", len(sLeaf), expectedSize) }
+
+		node, deserializeErr := serializePcMap.DeserializeNode(sLeaf)
+		if deserializeErr != nil { t.Fatalf("error deserializing leaf: %s", deserializeErr.Error()) }
+
+		if node.Version != 7 || node.StartOffset != 1024 || node.EndOffset != 1024 + uint64(expectedSize) - 1 { t.Errorf("leaf header mismatch: %+v", node) }
+		if ! bytes.Equal(node.Key, leaf.Key) || ! bytes.Equal(node.Value, leaf.Value) || node.ExpiresAt != 42 { t.Errorf("leaf contents mismatch: %+v", node) }
+	})
+
+	t.Run("Test Internal Node Round Trip", func(t *testing.T) {
+		children := []*mmcmap.MMCMapNode{ { StartOffset: 100 }, { StartOffset: 200 }, { StartOffset: 300 } }
+		iNode := &mmcmap.MMCMapNode{ Version: 3, StartOffset: 2048, Bitmap: 0b1011, Children: children }
+
+		sINode, serializeErr := iNode.SerializeNode(iNode.StartOffset)
+		if serializeErr != nil { t.Fatalf("error serializing internal node: %s", serializeErr.Error()) }
+
+		expectedSize := mmcmap.NodeChildrenIdx + len(children) * mmcmap.NodeChildPtrSize
+		if len(sINode) != expectedSize { t.Errorf("serialized internal node size mismatch: actual(%d), expected(%d)", len(sINode), expectedSize) }
+
+		node, deserializeErr := serializePcMap.DeserializeNode(sINode)
+		if deserializeErr != nil { t.Fatalf("error deserializing internal node: %s", deserializeErr.Error()) }
+
+		if node.IsLeaf || node.Bitmap != 0b1011 || len(node.Children) != 3 { t.Fatalf("internal node mismatch: %+v", node) }
+		for idx, child := range node.Children {
+			if child.StartOffset != children[idx].StartOffset { t.Errorf("child offset mismatch: actual(%d), expected(%d)", child.StartOffset, children[idx].StartOffset) }
+		}
+	})
+
+	t.Run("Test Internal Node Allocations", func(t *testing.T) {
+		children := make([]*mmcmap.MMCMapNode, 32)
+		for idx := range children { children[idx] = &mmcmap.MMCMapNode{ StartOffset: uint64(idx + 1) * 100 } }
+
+		iNode := &mmcmap.MMCMapNode{ Version: 3, StartOffset: 4096, Bitmap: ^uint32(0), Children: children }
+		sINode, serializeErr := iNode.SerializeNode(iNode.StartOffset)
+		if serializeErr != nil { t.Fatalf("error serializing internal node: %s", serializeErr.Error()) }
+
+		allocs := testing.AllocsPerRun(100, func() { mmcmap.DeserializeINode(sINode) })
+		if allocs > 3 { t.Errorf("deserializing an internal node allocated per child: actual(%v), expected at most(3)", allocs) }
+	})
+
+	t.Run("Test Oversized Key", func(t *testing.T) {
+		leaf := &mmcmap.MMCMapNode{ IsLeaf: true, Key: make([]byte, mmcmap.MaxKeyLength + 1) }
+
+		_, serializeErr := leaf.SerializeNode(0)
+		if serializeErr != mmcmap.ErrKeyTooLarge { t.Errorf("oversized key error mismatch: actual(%v), expected(%v)", serializeErr, mmcmap.ErrKeyTooLarge) }
+	})
+
+	t.Run("Test Paths Written Across Commits", func(t *testing.T) {
+		path := filepath.Join(os.TempDir(), "testserializepaths")
+		os.Remove(path)
+
+		// the mem map tests above overwrite the root of the shared map, so the commits are made against a new one
+		pathsMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: path })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+		defer pathsMap.Remove()
+
+		for idx := 0; idx < 5000; idx++ {
+			key := []byte(fmt.Sprintf("key%d", idx))
+			_, putErr := pathsMap.Put(key, bytes.Repeat(key, idx % 7 + 1))
+			if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+		}
+
+		for idx := 0; idx < 5000; idx++ {
+			key := []byte(fmt.Sprintf("key%d", idx))
+			value, _ := pathsMap.Get(key)
+			if ! bytes.Equal(value, bytes.Repeat(key, idx % 7 + 1)) { t.Fatalf("value mismatch for %s: actual(%s)", key, value) }
+		}
+
+		report, verifyErr := pathsMap.Verify()
+		if verifyErr != nil { t.Fatalf("error on verify: %s", verifyErr.Error()) }
+		if ! report.IsValid { t.Errorf("verify found inconsistencies: %v", report.Findings) }
+	})
+
+	t.Run("Test Direct Serialize", func(t *testing.T) {
+		path := filepath.Join(os.TempDir(), "testserializedirect")
+		os.Remove(path)
+
+		directMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: path, DirectSerialize: true, OverflowThreshold: 64 })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+		defer directMap.Remove()
+
+		var wg sync.WaitGroup
+		for worker := 0; worker < 4; worker++ {
+			wg.Add(1)
+			go func(worker int) {
+				defer wg.Done()
+
+				for idx := worker; idx < 2000; idx += 4 {
+					key := []byte(fmt.Sprintf("key%d", idx))
+					_, putErr := directMap.Put(key, bytes.Repeat(key, idx % 20 + 1))
+					if putErr != nil { t.Errorf("error on put: %s", putErr.Error()) }
+				}
+			}(worker)
+		}
+
+		wg.Wait()
+
+		for idx := 0; idx < 2000; idx++ {
+			key := []byte(fmt.Sprintf("key%d", idx))
+			value, _ := directMap.Get(key)
+			if ! bytes.Equal(value, bytes.Repeat(key, idx % 20 + 1)) { t.Fatalf("value mismatch for %s: actual(%s)", key, value) }
+		}
+
+		length, _ := directMap.Len()
+		if length != 2000 { t.Errorf("length mismatch: actual(%d), expected(2000)", length) }
+
+		report, verifyErr := directMap.Verify()
+		if verifyErr != nil { t.Fatalf("error on verify: %s", verifyErr.Error()) }
+		if ! report.IsValid { t.Errorf("verify found inconsistencies: %v", report.Findings) }
+	})
+
 	t.Log("Done")
-}
\ No newline at end of file
+}
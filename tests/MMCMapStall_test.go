@@ -0,0 +1,62 @@
+package mmcmaptests
+
+import "errors"
+import "fmt"
+import "os"
+import "path/filepath"
+import "sync/atomic"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var wsTestPath = filepath.Join(os.TempDir(), "teststall")
+var stallTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initStMapErr error
+	os.Remove(wsTestPath)
+
+	opts := mmcmap.MMCMapOpts{ Filepath: wsTestPath, MaxUnflushedBytes: 1 }
+	stallTestMap, initStMapErr = mmcmap.Open(opts)
+	if initStMapErr != nil { panic(initStMapErr.Error()) }
+
+	fmt.Println("stall test mmcmap initialized")
+}
+
+
+func TestMMCMapWriteStall(t *testing.T) {
+	defer stallTestMap.Remove()
+
+	t.Run("Test Blocking Stall", func(t *testing.T) {
+		for idx := 0; idx < 200; idx++ {
+			key := []byte(fmt.Sprintf("block%d", idx))
+			_, putErr := stallTestMap.Put(key, key)
+			if putErr != nil { t.Errorf("error on put: %s", putErr.Error()) }
+		}
+
+		keyCount, lenErr := stallTestMap.Len()
+		if lenErr != nil { t.Errorf("error on len: %s", lenErr.Error()) }
+		if keyCount != 200 { t.Errorf("key count mismatch: actual(%d), expected(200)", keyCount) }
+		if atomic.LoadUint64(&stallTestMap.WriteStalls) == 0 { t.Error("writes were never stalled") }
+	})
+
+	t.Run("Test Error Stall", func(t *testing.T) {
+		stallTestMap.Opts.StallPolicy = mmcmap.StallError
+
+		stalled := false
+		for idx := 0; idx < 1000 && ! stalled; idx++ {
+			key := []byte(fmt.Sprintf("error%d", idx))
+			_, putErr := stallTestMap.Put(key, key)
+			if putErr != nil {
+				if ! errors.Is(putErr, mmcmap.ErrWriteStall) { t.Errorf("unexpected error on put: %s", putErr.Error()) }
+				stalled = true
+			}
+		}
+
+		if ! stalled { t.Error("put never returned ErrWriteStall") }
+	})
+
+	t.Log("Done")
+}
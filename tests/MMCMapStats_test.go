@@ -0,0 +1,134 @@
+package mmcmaptests
+
+import "bytes"
+import "encoding/json"
+import "fmt"
+import "os"
+import "path/filepath"
+import "strings"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var statsTestPath = filepath.Join(os.TempDir(), "teststats")
+var statsTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initStatsMapErr error
+	os.Remove(statsTestPath)
+
+	opts := mmcmap.MMCMapOpts{ Filepath: statsTestPath, Name: "stats", NodeCacheSize: 1 << 20 }
+	statsTestMap, initStatsMapErr = mmcmap.Open(opts)
+	if initStatsMapErr != nil { panic(initStatsMapErr.Error()) }
+
+	fmt.Println("stats test mmcmap initialized")
+}
+
+
+func TestMMCMapStats(t *testing.T) {
+	defer statsTestMap.Remove()
+
+	t.Run("Test Operation Counters", func(t *testing.T) {
+		for idx := 0; idx < 100; idx++ {
+			key := []byte(fmt.Sprintf("key%d", idx))
+			_, putErr := statsTestMap.Put(key, key)
+			if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+		}
+
+		batch := statsTestMap.NewWriteBatch()
+		batch.Put([]byte("batch1"), []byte("value"))
+		batch.Put([]byte("batch2"), []byte("value"))
+		batch.Delete([]byte("missing"))
+		_, commitErr := batch.Commit()
+		if commitErr != nil { t.Fatalf("error on batch commit: %s", commitErr.Error()) }
+
+		for idx := 0; idx < 10; idx++ {
+			_, delErr := statsTestMap.Delete([]byte(fmt.Sprintf("key%d", idx)))
+			if delErr != nil { t.Fatalf("error on delete: %s", delErr.Error()) }
+		}
+
+		_, delErr := statsTestMap.Delete([]byte("missing"))
+		if delErr != nil { t.Fatalf("error on delete: %s", delErr.Error()) }
+
+		for idx := 0; idx < 50; idx++ {
+			_, getErr := statsTestMap.Get([]byte(fmt.Sprintf("key%d", idx)))
+			if getErr != nil { t.Fatalf("error on get: %s", getErr.Error()) }
+		}
+
+		_, getErr := statsTestMap.GetMulti([][]byte{ []byte("key1"), []byte("key2") })
+		if getErr != nil { t.Fatalf("error on get multi: %s", getErr.Error()) }
+
+		stats, statsErr := statsTestMap.Stats()
+		if statsErr != nil { t.Fatalf("error on stats: %s", statsErr.Error()) }
+
+		if stats.Puts != 102 { t.Errorf("puts mismatch: actual(%d), expected(102)", stats.Puts) }
+		if stats.Deletes != 10 { t.Errorf("deletes mismatch: actual(%d), expected(10)", stats.Deletes) }
+		if stats.Gets != 52 { t.Errorf("gets mismatch: actual(%d), expected(52)", stats.Gets) }
+		if stats.LiveKeys != 92 { t.Errorf("live keys mismatch: actual(%d), expected(92)", stats.LiveKeys) }
+
+		size, _ := statsTestMap.FileSize()
+		if stats.FileSize != uint64(size) { t.Errorf("file size mismatch: actual(%d), expected(%d)", stats.FileSize, size) }
+
+		if stats.NodeCache.Hits == 0 || stats.NodeCacheHitRate <= 0 || stats.NodeCacheHitRate > 1 { t.Errorf("node cache hit rate not reported: %+v", stats) }
+		if stats.NodePoolHits + stats.NodePoolMisses == 0 { t.Errorf("node pool requests not counted: %+v", stats) }
+	})
+
+	t.Run("Test Dead Bytes", func(t *testing.T) {
+		stats, statsErr := statsTestMap.Stats()
+		if statsErr != nil { t.Fatalf("error on stats: %s", statsErr.Error()) }
+
+		if stats.DeadBytes == 0 || stats.DeadBytes >= stats.DataBytes { t.Fatalf("dead bytes out of range: dead(%d), data(%d)", stats.DeadBytes, stats.DataBytes) }
+
+		report, compactErr := statsTestMap.Compact()
+		if compactErr != nil { t.Fatalf("error on compact: %s", compactErr.Error()) }
+
+		compacted, statsErr := statsTestMap.Stats()
+		if statsErr != nil { t.Fatalf("error on stats: %s", statsErr.Error()) }
+
+		if compacted.DeadBytes >= stats.DeadBytes { t.Errorf("compaction did not reduce dead bytes: actual(%d), before(%d), reclaimed(%d)", compacted.DeadBytes, stats.DeadBytes, report.ReclaimedBytes) }
+		if compacted.LiveKeys != stats.LiveKeys { t.Errorf("live keys changed by compaction: actual(%d), expected(%d)", compacted.LiveKeys, stats.LiveKeys) }
+	})
+
+	t.Run("Test Resizes And Flushes", func(t *testing.T) {
+		startSize, _ := statsTestMap.FileSize()
+		value := bytes.Repeat([]byte("v"), 4096)
+
+		for idx := 0; ; idx++ {
+			_, putErr := statsTestMap.Put([]byte(fmt.Sprintf("large%d", idx)), value)
+			if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+
+			size, _ := statsTestMap.FileSize()
+			if size > startSize { break }
+		}
+
+		stats, statsErr := statsTestMap.Stats()
+		if statsErr != nil { t.Fatalf("error on stats: %s", statsErr.Error()) }
+
+		if stats.Resizes == 0 { t.Errorf("resize not counted: %+v", stats) }
+		if stats.Flushes == 0 { t.Errorf("flush not counted: %+v", stats) }
+	})
+
+	t.Run("Test Expvar", func(t *testing.T) {
+		var stats mmcmap.MMCMapStats
+		decodeErr := json.Unmarshal([]byte(statsTestMap.Expvar().String()), &stats)
+		if decodeErr != nil { t.Fatalf("error decoding expvar: %s", decodeErr.Error()) }
+
+		if stats.Puts == 0 || stats.LiveKeys == 0 { t.Errorf("expvar missing stats: %+v", stats) }
+	})
+
+	t.Run("Test Write Prometheus", func(t *testing.T) {
+		var buf bytes.Buffer
+		writeErr := statsTestMap.WritePrometheus(&buf)
+		if writeErr != nil { t.Fatalf("error writing metrics: %s", writeErr.Error()) }
+
+		output := buf.String()
+		for _, name := range []string{ "mmcmap_puts_total", "mmcmap_dead_bytes", "mmcmap_node_cache_hits_total" } {
+			if ! strings.Contains(output, "# TYPE " + name + " ") { t.Errorf("metric type missing: %s", name) }
+			if ! strings.Contains(output, name + "{map=\"stats\"} ") { t.Errorf("metric sample missing: %s", name) }
+		}
+	})
+
+	t.Log("Done")
+}
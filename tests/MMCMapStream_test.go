@@ -0,0 +1,162 @@
+package mmcmaptests
+
+import "bytes"
+import "crypto/rand"
+import "fmt"
+import "io"
+import "os"
+import "path/filepath"
+import "testing"
+import "testing/iotest"
+import "time"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var streamTestPath = filepath.Join(os.TempDir(), "teststream")
+var streamTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initStreamMapErr error
+	os.Remove(streamTestPath)
+
+	streamTestMap, initStreamMapErr = mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: streamTestPath })
+	if initStreamMapErr != nil { panic(initStreamMapErr.Error()) }
+
+	fmt.Println("stream test mmcmap initialized")
+}
+
+
+func TestMMCMapStream(t *testing.T) {
+	defer streamTestMap.Remove()
+
+	for idx := 0; idx < 1000; idx++ {
+		key := []byte(fmt.Sprintf("key%d", idx))
+		_, putErr := streamTestMap.Put(key, key)
+		if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+	}
+
+	large := make([]byte, 80 << 20)
+	rand.Read(large)
+
+	t.Run("Test Put And Get Large Value", func(t *testing.T) {
+		ok, putErr := streamTestMap.PutReader([]byte("large"), iotest.HalfReader(bytes.NewReader(large)), int64(len(large)))
+		if putErr != nil { t.Fatalf("error on put reader: %s", putErr.Error()) }
+		if ! ok { t.Fatal("put reader was not committed") }
+
+		reader, getErr := streamTestMap.GetReader([]byte("large"))
+		if getErr != nil { t.Fatalf("error on get reader: %s", getErr.Error()) }
+
+		if size := reader.(*mmcmap.ValueReader).Size(); size != int64(len(large)) { t.Errorf("size mismatch: actual(%d), expected(%d)", size, len(large)) }
+
+		var streamed bytes.Buffer
+		_, copyErr := io.CopyBuffer(&streamed, reader, make([]byte, 4096))
+		if copyErr != nil { t.Fatalf("error reading value: %s", copyErr.Error()) }
+		if ! bytes.Equal(streamed.Bytes(), large) { t.Error("streamed value does not match the value put") }
+
+		reader.Close()
+		_, readErr := reader.Read(make([]byte, 1))
+		if readErr != mmcmap.ErrReaderClosed { t.Errorf("read after close mismatch: actual(%v), expected(%v)", readErr, mmcmap.ErrReaderClosed) }
+
+		value, _ := streamTestMap.Get([]byte("key42"))
+		if string(value) != "key42" { t.Errorf("existing key mismatch after put reader: actual(%s), expected(key42)", value) }
+	})
+
+	t.Run("Test Overwrite Small Value", func(t *testing.T) {
+		ok, putErr := streamTestMap.PutReader([]byte("key7"), bytes.NewReader([]byte("streamed")), 8)
+		if putErr != nil || ! ok { t.Fatalf("error on put reader: %v", putErr) }
+
+		value, _ := streamTestMap.Get([]byte("key7"))
+		if string(value) != "streamed" { t.Errorf("value mismatch: actual(%s), expected(streamed)", value) }
+
+		length, _ := streamTestMap.Len()
+		if length != 1001 { t.Errorf("length mismatch: actual(%d), expected(1001)", length) }
+
+		_, putErr = streamTestMap.Put([]byte("key7"), []byte("again"))
+		if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+
+		reader, _ := streamTestMap.GetReader([]byte("key7"))
+		defer reader.Close()
+
+		streamed, _ := io.ReadAll(reader)
+		if string(streamed) != "again" { t.Errorf("streamed value mismatch: actual(%s), expected(again)", streamed) }
+	})
+
+	t.Run("Test Short Reader Commits Nothing", func(t *testing.T) {
+		_, putErr := streamTestMap.PutReader([]byte("short"), bytes.NewReader([]byte("abc")), 10)
+		if putErr != io.ErrUnexpectedEOF { t.Errorf("short reader error mismatch: actual(%v), expected(%v)", putErr, io.ErrUnexpectedEOF) }
+
+		value, _ := streamTestMap.Get([]byte("short"))
+		if value != nil { t.Errorf("short value was committed: %s", value) }
+
+		_, putErr = streamTestMap.PutReader([]byte("short"), bytes.NewReader(nil), -1)
+		if putErr != mmcmap.ErrInvalidValueSize { t.Errorf("negative size error mismatch: actual(%v), expected(%v)", putErr, mmcmap.ErrInvalidValueSize) }
+
+		reader, getErr := streamTestMap.GetReader([]byte("short"))
+		if getErr != nil || reader != nil { t.Errorf("missing key reader mismatch: actual(%v %v), expected(nil nil)", reader, getErr) }
+	})
+
+	t.Run("Test Verify And Compact", func(t *testing.T) {
+		report, verifyErr := streamTestMap.Verify()
+		if verifyErr != nil { t.Fatalf("error on verify: %s", verifyErr.Error()) }
+		if ! report.IsValid { t.Errorf("verify found inconsistencies: %v", report.Findings) }
+
+		_, compactErr := streamTestMap.Compact()
+		if compactErr != nil { t.Fatalf("error on compact: %s", compactErr.Error()) }
+
+		reader, getErr := streamTestMap.GetReader([]byte("large"))
+		if getErr != nil { t.Fatalf("error on get reader: %s", getErr.Error()) }
+		defer reader.Close()
+
+		streamed, _ := io.ReadAll(reader)
+		if ! bytes.Equal(streamed, large) { t.Error("streamed value does not match after compact") }
+	})
+
+	t.Run("Test Compact Waits For Reader Close", func(t *testing.T) {
+		reader, getErr := streamTestMap.GetReader([]byte("key0"))
+		if getErr != nil { t.Fatalf("error on get reader: %s", getErr.Error()) }
+
+		compacted := make(chan error, 1)
+		go func() {
+			_, compactErr := streamTestMap.Compact()
+			compacted <- compactErr
+		}()
+
+		time.Sleep(50 * time.Millisecond)
+
+		unblocked := make(chan error, 1)
+		go func() {
+			_, putErr := streamTestMap.Put([]byte("key1"), []byte("key1"))
+			if putErr != nil {
+				unblocked <- putErr
+				return
+			}
+
+			_, rangeErr := streamTestMap.Range([]byte("key0"), []byte("key1"))
+			unblocked <- rangeErr
+		}()
+
+		select {
+			case opErr := <-unblocked:
+				if opErr != nil { t.Errorf("error while compact is queued: %s", opErr.Error()) }
+			case <-time.After(2 * time.Second):
+				t.Fatal("put and range blocked behind a compact waiting for an open reader")
+		}
+
+		select {
+			case <-compacted:
+				t.Error("compact ran while a reader was open")
+			default:
+		}
+
+		streamed, _ := io.ReadAll(reader)
+		if string(streamed) != "key0" { t.Errorf("streamed value mismatch: actual(%s), expected(key0)", streamed) }
+		reader.Close()
+
+		compactErr := <-compacted
+		if compactErr != nil { t.Errorf("error on compact: %s", compactErr.Error()) }
+	})
+
+	t.Log("Done")
+}
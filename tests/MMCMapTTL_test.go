@@ -0,0 +1,164 @@
+package mmcmaptests
+
+import "fmt"
+import "os"
+import "path/filepath"
+import "sync/atomic"
+import "testing"
+import "time"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var ttlTestPath = filepath.Join(os.TempDir(), "testttl")
+var ttlTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initTTLMapErr error
+	os.Remove(ttlTestPath)
+	os.Remove(ttlTestPath + ".wheel")
+
+	ttlTestMap, initTTLMapErr = mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: ttlTestPath, ExpirySweepInterval: 50 * time.Millisecond })
+	if initTTLMapErr != nil { panic(initTTLMapErr.Error()) }
+
+	fmt.Println("ttl test mmcmap initialized")
+}
+
+
+// waitFor polls cond until it holds or the timeout passes
+func waitFor(timeout time.Duration, cond func() bool) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() { return true }
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	return cond()
+}
+
+
+func TestMMCMapTTL(t *testing.T) {
+	defer ttlTestMap.Remove()
+
+	t.Run("Test Expired Keys Are Missing", func(t *testing.T) {
+		_, putErr := ttlTestMap.PutWithTTL([]byte("session"), []byte("token"), 100 * time.Millisecond)
+		if putErr != nil { t.Fatalf("error on put with ttl: %s", putErr.Error()) }
+
+		ttlTestMap.Put([]byte("persistent"), []byte("value"))
+
+		value, _ := ttlTestMap.Get([]byte("session"))
+		if string(value) != "token" { t.Errorf("value before expiry mismatch: actual(%s), expected(token)", value) }
+
+		time.Sleep(150 * time.Millisecond)
+
+		value, _ = ttlTestMap.Get([]byte("session"))
+		if value != nil { t.Errorf("expired key was returned: actual(%s)", value) }
+
+		pairs, rangeErr := ttlTestMap.Range(nil, nil)
+		if rangeErr != nil { t.Fatalf("error on range: %s", rangeErr.Error()) }
+		if len(pairs) != 1 || string(pairs[0].Key) != "persistent" { t.Errorf("range returned expired key: actual(%d pairs)", len(pairs)) }
+
+		isPut, _ := ttlTestMap.PutIfAbsent([]byte("session"), []byte("fresh"))
+		if ! isPut { t.Error("put if absent did not treat the expired key as missing") }
+
+		value, _ = ttlTestMap.Get([]byte("session"))
+		if string(value) != "fresh" { t.Errorf("value after put mismatch: actual(%s), expected(fresh)", value) }
+
+		ttlTestMap.Delete([]byte("session"))
+		ttlTestMap.Delete([]byte("persistent"))
+	})
+
+	t.Run("Test Put Clears Expiration", func(t *testing.T) {
+		ttlTestMap.PutWithTTL([]byte("renewed"), []byte("old"), 50 * time.Millisecond)
+		ttlTestMap.Put([]byte("renewed"), []byte("new"))
+
+		time.Sleep(100 * time.Millisecond)
+
+		value, _ := ttlTestMap.Get([]byte("renewed"))
+		if string(value) != "new" { t.Errorf("value after put mismatch: actual(%s), expected(new)", value) }
+
+		ttlTestMap.Delete([]byte("renewed"))
+	})
+
+	t.Run("Test Sweeper Removes Expired Keys", func(t *testing.T) {
+		before := atomic.LoadUint64(&ttlTestMap.ExpiredKeys)
+
+		for idx := 0; idx < 100; idx++ {
+			_, putErr := ttlTestMap.PutWithTTL([]byte(fmt.Sprintf("temp%d", idx)), []byte("value"), 50 * time.Millisecond)
+			if putErr != nil { t.Fatalf("error on put with ttl: %s", putErr.Error()) }
+		}
+
+		ttlTestMap.PutWithTTL([]byte("long"), []byte("value"), time.Hour)
+
+		length, _ := ttlTestMap.Len()
+		if length != 101 { t.Fatalf("length before sweep mismatch: actual(%d), expected(101)", length) }
+
+		isSwept := waitFor(5 * time.Second, func() bool {
+			length, _ := ttlTestMap.Len()
+			return length == 1
+		})
+
+		if ! isSwept {
+			length, _ := ttlTestMap.Len()
+			t.Fatalf("length after sweep mismatch: actual(%d), expected(1)", length)
+		}
+
+		swept := atomic.LoadUint64(&ttlTestMap.ExpiredKeys) - before
+		if swept != 100 { t.Errorf("expired key count mismatch: actual(%d), expected(100)", swept) }
+
+		value, _ := ttlTestMap.Get([]byte("long"))
+		if string(value) != "value" { t.Errorf("unexpired key was removed: actual(%s)", value) }
+	})
+
+	t.Run("Test Expiration Survives Compact", func(t *testing.T) {
+		ttlTestMap.PutWithTTL([]byte("compacted"), []byte("value"), 200 * time.Millisecond)
+
+		_, compactErr := ttlTestMap.Compact()
+		if compactErr != nil { t.Fatalf("error on compact: %s", compactErr.Error()) }
+
+		value, _ := ttlTestMap.Get([]byte("compacted"))
+		if string(value) != "value" { t.Errorf("value after compact mismatch: actual(%s), expected(value)", value) }
+
+		time.Sleep(250 * time.Millisecond)
+
+		value, _ = ttlTestMap.Get([]byte("compacted"))
+		if value != nil { t.Errorf("expiration was lost by compact: actual(%s)", value) }
+	})
+
+	t.Run("Test Invalid TTL", func(t *testing.T) {
+		_, putErr := ttlTestMap.PutWithTTL([]byte("key"), []byte("value"), 0)
+		if putErr != mmcmap.ErrInvalidTTL { t.Errorf("invalid ttl error mismatch: actual(%v), expected(%v)", putErr, mmcmap.ErrInvalidTTL) }
+	})
+
+	t.Run("Test Wheel Persisted Across Close", func(t *testing.T) {
+		path := filepath.Join(os.TempDir(), "testttlreopen")
+		os.Remove(path)
+		os.Remove(path + ".wheel")
+
+		opts := mmcmap.MMCMapOpts{ Filepath: path, ExpirySweepInterval: 50 * time.Millisecond }
+		reopenMap, openErr := mmcmap.Open(opts)
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+
+		reopenMap.PutWithTTL([]byte("key"), []byte("value"), 100 * time.Millisecond)
+		reopenMap.Close()
+
+		_, statErr := os.Stat(path + ".wheel")
+		if statErr != nil { t.Fatalf("expiry wheel was not saved: %s", statErr.Error()) }
+
+		time.Sleep(150 * time.Millisecond)
+
+		reopenMap, openErr = mmcmap.Open(opts)
+		if openErr != nil { t.Fatalf("error on reopen: %s", openErr.Error()) }
+		defer reopenMap.Remove()
+
+		isSwept := waitFor(5 * time.Second, func() bool {
+			length, _ := reopenMap.Len()
+			return length == 0
+		})
+
+		if ! isSwept { t.Error("key expired while closed was not swept after reopen") }
+	})
+
+	t.Log("Done")
+}
@@ -0,0 +1,134 @@
+package mmcmaptests
+
+import "bytes"
+import "fmt"
+import "os"
+import "path/filepath"
+import "sync"
+import "testing"
+import "time"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var tracingTestPath = filepath.Join(os.TempDir(), "testtracing")
+
+
+type recordingTracer struct {
+	lock sync.Mutex
+	spans []*recordedSpan
+}
+
+type recordedSpan struct {
+	tracer *recordingTracer
+	parent *recordedSpan
+	name string
+	attributes map[string]interface{}
+	ended bool
+	err error
+}
+
+func (tracer *recordingTracer) Start(parent mmcmap.Span, name string) mmcmap.Span {
+	span := &recordedSpan{ tracer: tracer, name: name, attributes: make(map[string]interface{}) }
+	if parent != nil { span.parent = parent.(*recordedSpan) }
+
+	tracer.lock.Lock()
+	defer tracer.lock.Unlock()
+
+	tracer.spans = append(tracer.spans, span)
+	return span
+}
+
+func (span *recordedSpan) SetAttribute(key string, value interface{}) {
+	span.tracer.lock.Lock()
+	defer span.tracer.lock.Unlock()
+
+	span.attributes[key] = value
+}
+
+func (span *recordedSpan) End(err error) {
+	span.tracer.lock.Lock()
+	defer span.tracer.lock.Unlock()
+
+	span.ended, span.err = true, err
+}
+
+func (tracer *recordingTracer) named(name string) []recordedSpan {
+	tracer.lock.Lock()
+	defer tracer.lock.Unlock()
+
+	var spans []recordedSpan
+	for _, span := range tracer.spans {
+		if span.name == name { spans = append(spans, *span) }
+	}
+
+	return spans
+}
+
+
+func TestMMCMapTracing(t *testing.T) {
+	defer os.Remove(tracingTestPath)
+
+	t.Run("Test Commit And Serialize Spans", func(t *testing.T) {
+		os.Remove(tracingTestPath)
+
+		tracer := &recordingTracer{}
+		mmcMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: tracingTestPath, Tracer: tracer })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+		defer mmcMap.Close()
+
+		version, putErr := mmcMap.PutVersioned([]byte("hello"), []byte("world"))
+		if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+
+		commits := tracer.named(mmcmap.SpanCommit)
+		if len(commits) != 1 { t.Fatalf("commit span count mismatch: actual(%d), expected(1)", len(commits)) }
+		if ! commits[0].ended || commits[0].err != nil { t.Errorf("commit span not ended cleanly: %+v", commits[0]) }
+		if commits[0].attributes["version"] != version { t.Errorf("commit version mismatch: actual(%v), expected(%d)", commits[0].attributes["version"], version) }
+		if commits[0].attributes["ops"] != 1 { t.Errorf("commit ops mismatch: actual(%v), expected(1)", commits[0].attributes["ops"]) }
+
+		serializes := tracer.named(mmcmap.SpanSerialize)
+		if len(serializes) != 1 { t.Fatalf("serialize span count mismatch: actual(%d), expected(1)", len(serializes)) }
+		if serializes[0].parent == nil || serializes[0].parent.name != mmcmap.SpanCommit { t.Errorf("serialize span is not a child of the commit: %+v", serializes[0]) }
+		if ! serializes[0].ended { t.Errorf("serialize span not ended") }
+
+		_, delErr := mmcMap.Delete([]byte("missing"))
+		if delErr != nil { t.Fatalf("error on delete: %s", delErr.Error()) }
+
+		commits = tracer.named(mmcmap.SpanCommit)
+		aborted := commits[len(commits) - 1]
+		if aborted.attributes["aborted"] != true || aborted.err != nil { t.Errorf("aborted commit not traced as aborted: %+v", aborted) }
+	})
+
+	t.Run("Test Flush And Resize Spans", func(t *testing.T) {
+		os.Remove(tracingTestPath)
+
+		tracer := &recordingTracer{}
+		mmcMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: tracingTestPath, Tracer: tracer, DirectSerialize: true })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+		defer mmcMap.Close()
+
+		startSize, _ := mmcMap.FileSize()
+		value := bytes.Repeat([]byte("v"), 4096)
+		for idx := 0; ; idx++ {
+			_, putErr := mmcMap.Put([]byte(fmt.Sprintf("key%d", idx)), value)
+			if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+
+			size, _ := mmcMap.FileSize()
+			if size > startSize { break }
+		}
+
+		deadline := time.Now().Add(5 * time.Second)
+		for len(tracer.named(mmcmap.SpanFlush)) == 0 && time.Now().Before(deadline) { time.Sleep(10 * time.Millisecond) }
+
+		resizes := tracer.named(mmcmap.SpanResize)
+		if len(resizes) == 0 { t.Fatalf("resize was not traced") }
+		if _, ok := resizes[len(resizes) - 1].attributes["inPlace"]; ! ok { t.Errorf("resize span missing inPlace: %+v", resizes[len(resizes) - 1]) }
+
+		if len(tracer.named(mmcmap.SpanFlush)) == 0 { t.Errorf("flush was not traced") }
+
+		serializes := tracer.named(mmcmap.SpanSerialize)
+		if len(serializes) == 0 || serializes[0].attributes["direct"] != true { t.Errorf("direct serialization not traced") }
+	})
+
+	t.Log("Done")
+}
@@ -0,0 +1,98 @@
+package mmcmaptests
+
+import "errors"
+import "fmt"
+import "os"
+import "path/filepath"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var txTestPath = filepath.Join(os.TempDir(), "testtxn")
+var txnTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initTxMapErr error
+	os.Remove(txTestPath)
+
+	opts := mmcmap.MMCMapOpts{ Filepath: txTestPath }
+	txnTestMap, initTxMapErr = mmcmap.Open(opts)
+	if initTxMapErr != nil { panic(initTxMapErr.Error()) }
+
+	fmt.Println("txn test mmcmap initialized")
+}
+
+
+func TestMMCMapTxn(t *testing.T) {
+	defer txnTestMap.Remove()
+
+	_, putErr := txnTestMap.Put([]byte("balance-a"), []byte("100"))
+	if putErr != nil { t.Errorf("error putting key in mmcmap: %s", putErr.Error()) }
+
+	_, putErr = txnTestMap.Put([]byte("balance-b"), []byte("0"))
+	if putErr != nil { t.Errorf("error putting key in mmcmap: %s", putErr.Error()) }
+
+	t.Run("Test Txn Commit", func(t *testing.T) {
+		txn, beginErr := txnTestMap.BeginTxn()
+		if beginErr != nil { t.Fatalf("error beginning txn: %s", beginErr.Error()) }
+
+		val, getErr := txn.Get([]byte("balance-a"))
+		if getErr != nil { t.Errorf("error getting in txn: %s", getErr.Error()) }
+		if string(val) != "100" { t.Errorf("txn get does not match: actual(%s), expected(100)", val) }
+
+		txn.Put([]byte("balance-a"), []byte("50"))
+		txn.Put([]byte("balance-b"), []byte("50"))
+
+		val, getErr = txn.Get([]byte("balance-a"))
+		if getErr != nil { t.Errorf("error getting in txn: %s", getErr.Error()) }
+		if string(val) != "50" { t.Errorf("txn did not observe its own write: actual(%s), expected(50)", val) }
+
+		commitErr := txn.Commit()
+		if commitErr != nil { t.Errorf("error committing txn: %s", commitErr.Error()) }
+
+		for _, key := range []string{ "balance-a", "balance-b" } {
+			val, getErr = txnTestMap.Get([]byte(key))
+			if getErr != nil { t.Errorf("error getting from mmcmap: %s", getErr.Error()) }
+			if string(val) != "50" { t.Errorf("committed value does not match: actual(%s), expected(50)", val) }
+		}
+
+		commitErr = txn.Commit()
+		if ! errors.Is(commitErr, mmcmap.ErrTxnDone) { t.Errorf("expected done error on second commit, got: %v", commitErr) }
+	})
+
+	t.Run("Test Txn Conflict", func(t *testing.T) {
+		txn, beginErr := txnTestMap.BeginTxn()
+		if beginErr != nil { t.Fatalf("error beginning txn: %s", beginErr.Error()) }
+
+		_, getErr := txn.Get([]byte("balance-a"))
+		if getErr != nil { t.Errorf("error getting in txn: %s", getErr.Error()) }
+
+		_, putErr := txnTestMap.Put([]byte("balance-a"), []byte("75"))
+		if putErr != nil { t.Errorf("error putting key in mmcmap: %s", putErr.Error()) }
+
+		txn.Put([]byte("balance-b"), []byte("25"))
+
+		commitErr := txn.Commit()
+		if ! errors.Is(commitErr, mmcmap.ErrTxnConflict) { t.Errorf("expected conflict error, got: %v", commitErr) }
+
+		val, getErr := txnTestMap.Get([]byte("balance-b"))
+		if getErr != nil { t.Errorf("error getting from mmcmap: %s", getErr.Error()) }
+		if string(val) != "50" { t.Errorf("conflicting txn wrote a value: actual(%s), expected(50)", val) }
+	})
+
+	t.Run("Test Txn Rollback", func(t *testing.T) {
+		txn, beginErr := txnTestMap.BeginTxn()
+		if beginErr != nil { t.Fatalf("error beginning txn: %s", beginErr.Error()) }
+
+		txn.Put([]byte("rolled-back"), []byte("value"))
+		txn.Rollback()
+
+		val, getErr := txnTestMap.Get([]byte("rolled-back"))
+		if getErr != nil { t.Errorf("error getting from mmcmap: %s", getErr.Error()) }
+		if val != nil { t.Errorf("rolled back write is visible: %s", val) }
+	})
+
+	t.Log("Done")
+}
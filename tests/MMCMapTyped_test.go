@@ -0,0 +1,94 @@
+package mmcmaptests
+
+import "errors"
+import "fmt"
+import "os"
+import "path/filepath"
+import "testing"
+
+import "google.golang.org/protobuf/types/known/wrapperspb"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var typedTestPath = filepath.Join(os.TempDir(), "testtyped")
+var typedTestMap *mmcmap.MMCMap
+
+
+type typedTestValue struct {
+	Name string
+	Count int
+}
+
+
+func init() {
+	var initTypedMapErr error
+	os.Remove(typedTestPath)
+
+	opts := mmcmap.MMCMapOpts{ Filepath: typedTestPath, KeyMode: mmcmap.KeyModeOrdered, StrictGet: true }
+	typedTestMap, initTypedMapErr = mmcmap.Open(opts)
+	if initTypedMapErr != nil { panic(initTypedMapErr.Error()) }
+
+	fmt.Println("typed test mmcmap initialized")
+}
+
+
+func TestMMCMapTyped(t *testing.T) {
+	defer typedTestMap.Remove()
+
+	t.Run("Test Uint64 Keys And JSON Values", func(t *testing.T) {
+		typed := mmcmap.NewTyped[uint64, typedTestValue](typedTestMap, mmcmap.Uint64Codec{}, mmcmap.JSONCodec[typedTestValue]{})
+
+		for idx := uint64(0); idx < 1000; idx++ {
+			_, putErr := typed.Put(idx * 300, typedTestValue{ Name: fmt.Sprintf("value%d", idx), Count: int(idx) })
+			if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+		}
+
+		value, isFound, getErr := typed.Get(600)
+		if getErr != nil { t.Fatalf("error on get: %s", getErr.Error()) }
+		if ! isFound || value.Name != "value2" || value.Count != 2 { t.Errorf("value mismatch: actual(%v, %t), expected({value2 2}, true)", value, isFound) }
+
+		_, isFound, getErr = typed.Get(601)
+		if getErr != nil { t.Fatalf("error on get of missing key: %s", getErr.Error()) }
+		if isFound { t.Error("missing key was found") }
+
+		startKey, endKey := uint64(255), uint64(3000)
+		pairs, rangeErr := typed.Range(&startKey, &endKey)
+		if rangeErr != nil { t.Fatalf("error on range: %s", rangeErr.Error()) }
+		if len(pairs) != 10 { t.Fatalf("range length mismatch: actual(%d), expected(10)", len(pairs)) }
+
+		for idx, pair := range pairs {
+			expected := uint64(idx + 1) * 300
+			if pair.Key != expected || pair.Value.Count != idx + 1 { t.Errorf("pair mismatch at %d: actual(%d, %v), expected(%d)", idx, pair.Key, pair.Value, expected) }
+		}
+
+		removed, delErr := typed.Delete(600)
+		if delErr != nil { t.Fatalf("error on delete: %s", delErr.Error()) }
+		if ! removed { t.Error("delete did not report a removal") }
+
+		pairs, rangeErr = typed.Range(nil, &endKey, mmcmap.RangeOpts{ Reverse: true, Limit: 3 })
+		if rangeErr != nil { t.Fatalf("error on range: %s", rangeErr.Error()) }
+		if len(pairs) != 3 || pairs[0].Key != 3000 || pairs[2].Key != 2400 { t.Errorf("reverse range mismatch: actual(%d)", len(pairs)) }
+	})
+
+	t.Run("Test String Keys And Proto Values", func(t *testing.T) {
+		newValue := func() *wrapperspb.StringValue { return &wrapperspb.StringValue{} }
+		typed := mmcmap.NewTyped[string, *wrapperspb.StringValue](typedTestMap, mmcmap.StringCodec{}, mmcmap.ProtoCodec[*wrapperspb.StringValue]{ New: newValue })
+
+		_, putErr := typed.Put("proto", wrapperspb.String("message"))
+		if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+
+		value, isFound, getErr := typed.Get("proto")
+		if getErr != nil { t.Fatalf("error on get: %s", getErr.Error()) }
+		if ! isFound || value.GetValue() != "message" { t.Errorf("value mismatch: actual(%s), expected(message)", value.GetValue()) }
+	})
+
+	t.Run("Test Invalid Encoding", func(t *testing.T) {
+		typed := mmcmap.NewTyped[string, uint64](typedTestMap, mmcmap.StringCodec{}, mmcmap.Uint64Codec{})
+
+		_, _, getErr := typed.Get("proto")
+		if ! errors.Is(getErr, mmcmap.ErrInvalidEncoding) { t.Errorf("expected ErrInvalidEncoding, got: %v", getErr) }
+	})
+
+	t.Log("Done")
+}
@@ -59,6 +59,17 @@ func TestMMCMapUtils(t *testing.T) {
 		if ! isBitSet5 { t.Error("bit at index 5 is not set") }
 	})
 
+	t.Run("Test Clear Bitmap", func(t *testing.T) {
+		bitmap := mmcmap.SetBit(mmcmap.SetBit(uint32(0), 1), 5)
+
+		bitmap = mmcmap.ClearBit(bitmap, 1)
+		if mmcmap.IsBitSet(bitmap, 1) { t.Error("bit at index 1 is still set") }
+		if ! mmcmap.IsBitSet(bitmap, 5) { t.Error("bit at index 5 was cleared") }
+
+		bitmap = mmcmap.ClearBit(bitmap, 1)
+		if mmcmap.IsBitSet(bitmap, 1) { t.Error("clearing an unset bit set it") }
+	})
+
 	t.Log("Done")
 }
 
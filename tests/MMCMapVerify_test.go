@@ -0,0 +1,73 @@
+package mmcmaptests
+
+import "fmt"
+import "os"
+import "path/filepath"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var vTestPath = filepath.Join(os.TempDir(), "testverify")
+var verifyTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initVMapErr error
+	os.Remove(vTestPath)
+
+	opts := mmcmap.MMCMapOpts{ Filepath: vTestPath }
+	verifyTestMap, initVMapErr = mmcmap.Open(opts)
+	if initVMapErr != nil { panic(initVMapErr.Error()) }
+
+	fmt.Println("verify test mmcmap initialized")
+}
+
+
+func TestMMCMapVerify(t *testing.T) {
+	defer verifyTestMap.Remove()
+
+	for idx := 0; idx < 500; idx++ {
+		key := []byte(fmt.Sprintf("key%d", idx))
+		_, putErr := verifyTestMap.Put(key, key)
+		if putErr != nil { t.Errorf("error on put: %s", putErr.Error()) }
+	}
+
+	for idx := 0; idx < 100; idx++ {
+		_, delErr := verifyTestMap.Delete([]byte(fmt.Sprintf("key%d", idx)))
+		if delErr != nil { t.Errorf("error on delete: %s", delErr.Error()) }
+	}
+
+	t.Run("Test Verify Valid Map", func(t *testing.T) {
+		report, verifyErr := verifyTestMap.Verify()
+		if verifyErr != nil { t.Fatalf("error on verify: %s", verifyErr.Error()) }
+
+		if ! report.IsValid { t.Errorf("unexpected findings on valid map: %v", report.Findings) }
+		if report.KeysVisited != 400 { t.Errorf("keys visited mismatch: actual(%d), expected(400)", report.KeysVisited) }
+		if report.KeyCount != 400 { t.Errorf("key count mismatch: actual(%d), expected(400)", report.KeyCount) }
+		if report.NodesVisited <= report.KeysVisited { t.Errorf("verify did not visit internal nodes: actual(%d)", report.NodesVisited) }
+	})
+
+	t.Run("Test Verify Reports Corrupt Node", func(t *testing.T) {
+		meta, readMetaErr := verifyTestMap.ReadMetaFromMemMap()
+		if readMetaErr != nil { t.Fatalf("error reading metadata: %s", readMetaErr.Error()) }
+
+		root, readRootErr := verifyTestMap.ReadNodeFromMemMap(meta.RootOffset)
+		if readRootErr != nil { t.Fatalf("error reading root: %s", readRootErr.Error()) }
+
+		file, fileErr := os.OpenFile(vTestPath, os.O_RDWR, 0600)
+		if fileErr != nil { t.Fatalf("error opening file: %s", fileErr.Error()) }
+
+		_, writeErr := file.WriteAt([]byte{ 1, 2, 3, 4, 5, 6, 7, 8 }, int64(root.Children[0].StartOffset) + mmcmap.NodeStartOffsetIdx)
+		if writeErr != nil { t.Fatalf("error corrupting node: %s", writeErr.Error()) }
+		file.Close()
+
+		report, verifyErr := verifyTestMap.Verify()
+		if verifyErr != nil { t.Fatalf("error on verify: %s", verifyErr.Error()) }
+
+		if report.IsValid { t.Error("verify did not report corrupt node") }
+		if len(report.Findings) == 0 { t.Error("verify returned no findings for corrupt node") }
+	})
+
+	t.Log("Done")
+}
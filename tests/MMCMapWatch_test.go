@@ -0,0 +1,167 @@
+package mmcmaptests
+
+import "bytes"
+import "fmt"
+import "os"
+import "path/filepath"
+import "sync"
+import "testing"
+import "time"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var watchTestPath = filepath.Join(os.TempDir(), "testwatch")
+var watchTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initWatchMapErr error
+	os.Remove(watchTestPath)
+
+	watchTestMap, initWatchMapErr = mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: watchTestPath })
+	if initWatchMapErr != nil { panic(initWatchMapErr.Error()) }
+
+	fmt.Println("watch test mmcmap initialized")
+}
+
+
+func TestMMCMapWatch(t *testing.T) {
+	defer watchTestMap.Remove()
+
+	receive := func(t *testing.T, events <-chan mmcmap.ChangeEvent) mmcmap.ChangeEvent {
+		select {
+			case event := <-events:
+				return event
+			case <-time.After(5 * time.Second):
+				t.Fatal("timed out waiting for change event")
+		}
+
+		return mmcmap.ChangeEvent{}
+	}
+
+	t.Run("Test Watch Puts And Deletes", func(t *testing.T) {
+		events, cancel := watchTestMap.Watch([]byte("user/"))
+		defer cancel()
+
+		_, putErr := watchTestMap.Put([]byte("user/1"), []byte("alice"))
+		if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+
+		_, putErr = watchTestMap.Put([]byte("order/1"), []byte("ignored"))
+		if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+
+		_, delErr := watchTestMap.Delete([]byte("user/missing"))
+		if delErr != nil { t.Fatalf("error on delete: %s", delErr.Error()) }
+
+		_, delErr = watchTestMap.Delete([]byte("user/1"))
+		if delErr != nil { t.Fatalf("error on delete: %s", delErr.Error()) }
+
+		version, _ := readVersion(watchTestMap)
+
+		put := receive(t, events)
+		if put.Op != mmcmap.ChangePut || ! bytes.Equal(put.Key, []byte("user/1")) || ! bytes.Equal(put.Value, []byte("alice")) {
+			t.Errorf("put event mismatch: actual(%d %s: %s), expected(put user/1: alice)", put.Op, put.Key, put.Value)
+		}
+
+		if put.Version != version - 2 { t.Errorf("put event version mismatch: actual(%d), expected(%d)", put.Version, version - 2) }
+
+		del := receive(t, events)
+		if del.Op != mmcmap.ChangeDelete || ! bytes.Equal(del.Key, []byte("user/1")) || del.Value != nil {
+			t.Errorf("delete event mismatch: actual(%d %s: %v), expected(delete user/1: nil)", del.Op, del.Key, del.Value)
+		}
+
+		if del.Version != version { t.Errorf("delete event version mismatch: actual(%d), expected(%d)", del.Version, version) }
+	})
+
+	t.Run("Test Watch Batch", func(t *testing.T) {
+		events, cancel := watchTestMap.Watch(nil)
+		defer cancel()
+
+		batch := watchTestMap.NewWriteBatch()
+		batch.Put([]byte("batch1"), []byte("value1"))
+		batch.Put([]byte("batch2"), []byte("value2"))
+		batch.Delete([]byte("batch1"))
+
+		_, commitErr := batch.Commit()
+		if commitErr != nil { t.Fatalf("error on commit: %s", commitErr.Error()) }
+
+		version, _ := readVersion(watchTestMap)
+		expected := []struct { key string; op mmcmap.ChangeOp }{ { "batch1", mmcmap.ChangePut }, { "batch2", mmcmap.ChangePut }, { "batch1", mmcmap.ChangeDelete } }
+
+		for _, exp := range expected {
+			event := receive(t, events)
+			if string(event.Key) != exp.key || event.Op != exp.op || event.Version != version {
+				t.Errorf("batch event mismatch: actual(%d %s @ %d), expected(%d %s @ %d)", event.Op, event.Key, event.Version, exp.op, exp.key, version)
+			}
+		}
+	})
+
+	t.Run("Test Watch Concurrent Commits In Order", func(t *testing.T) {
+		events, cancel := watchTestMap.Watch([]byte("concurrent"))
+		defer cancel()
+
+		var wg sync.WaitGroup
+		for worker := 0; worker < 8; worker++ {
+			wg.Add(1)
+			go func(worker int) {
+				defer wg.Done()
+
+				for idx := 0; idx < 250; idx++ {
+					_, putErr := watchTestMap.Put([]byte(fmt.Sprintf("concurrent%d-%d", worker, idx)), []byte("value"))
+					if putErr != nil { t.Errorf("error on put: %s", putErr.Error()) }
+				}
+			}(worker)
+		}
+
+		wg.Wait()
+
+		var prevVersion uint64
+		for idx := 0; idx < 2000; idx++ {
+			event := receive(t, events)
+			if event.Version <= prevVersion { t.Fatalf("events out of version order: actual(%d), previous(%d)", event.Version, prevVersion) }
+			prevVersion = event.Version
+
+			val, getErr := watchTestMap.Get(event.Key)
+			if getErr != nil { t.Errorf("error on get: %s", getErr.Error()) }
+			if val == nil { t.Errorf("event published before the put was visible: %s", event.Key) }
+		}
+	})
+
+	t.Run("Test Watch Cancel", func(t *testing.T) {
+		events, cancel := watchTestMap.Watch(nil)
+
+		_, putErr := watchTestMap.Put([]byte("cancelled"), []byte("value"))
+		if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+
+		cancel()
+		cancel()
+
+		for range events {}
+
+		_, putErr = watchTestMap.Put([]byte("cancelled"), []byte("value"))
+		if putErr != nil { t.Errorf("error on put after cancel: %s", putErr.Error()) }
+	})
+
+	t.Run("Test Watch Closed With Map", func(t *testing.T) {
+		closeTestPath := filepath.Join(os.TempDir(), "testwatchclose")
+		os.Remove(closeTestPath)
+
+		closeTestMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: closeTestPath })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+
+		events, cancel := closeTestMap.Watch(nil)
+		defer cancel()
+
+		removeErr := closeTestMap.Remove()
+		if removeErr != nil { t.Fatalf("error on remove: %s", removeErr.Error()) }
+
+		select {
+			case _, isOpen := <-events:
+				if isOpen { t.Error("received an event from a closed map") }
+			case <-time.After(5 * time.Second):
+				t.Error("watch channel was not closed with the map")
+		}
+	})
+
+	t.Log("Done")
+}
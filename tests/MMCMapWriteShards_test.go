@@ -0,0 +1,194 @@
+package mmcmaptests
+
+import "bytes"
+import "errors"
+import "fmt"
+import "os"
+import "path/filepath"
+import "sync"
+import "sync/atomic"
+import "testing"
+import "time"
+
+import "github.com/sirgallo/mmcmap"
+import "github.com/sirgallo/mmcmap/common/mmap"
+
+
+var shardsTestPath = filepath.Join(os.TempDir(), "testwriteshards")
+var shardsTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initShardsMapErr error
+	mmcmap.Destroy(shardsTestPath)
+
+	opts := mmcmap.MMCMapOpts{ Filepath: shardsTestPath, WriteShards: 8 }
+	shardsTestMap, initShardsMapErr = mmcmap.Open(opts)
+	if initShardsMapErr != nil { panic(initShardsMapErr.Error()) }
+
+	fmt.Println("write shards test mmcmap initialized")
+}
+
+
+func TestMMCMapWriteShards(t *testing.T) {
+	defer shardsTestMap.Remove()
+
+	t.Run("Test Concurrent Puts", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for writer := 0; writer < 16; writer++ {
+			wg.Add(1)
+			go func(writer int) {
+				defer wg.Done()
+
+				for idx := 0; idx < 500; idx++ {
+					key := []byte(fmt.Sprintf("shard%d-key%d", writer, idx))
+					_, putErr := shardsTestMap.Put(key, key)
+					if putErr != nil { t.Errorf("error on put: %s", putErr.Error()) }
+				}
+			}(writer)
+		}
+
+		wg.Wait()
+
+		for writer := 0; writer < 16; writer++ {
+			for idx := 0; idx < 500; idx++ {
+				key := []byte(fmt.Sprintf("shard%d-key%d", writer, idx))
+				value, getErr := shardsTestMap.Get(key)
+				if getErr != nil { t.Fatalf("error on get: %s", getErr.Error()) }
+				if ! bytes.Equal(value, key) { t.Errorf("value mismatch: actual(%s), expected(%s)", value, key) }
+			}
+		}
+
+		count, lenErr := shardsTestMap.Len()
+		if lenErr != nil { t.Fatalf("error on len: %s", lenErr.Error()) }
+		if count != 8000 { t.Errorf("key count mismatch: actual(%d), expected(8000)", count) }
+
+		t.Logf("commits waiting on their shard: %d", shardsTestMap.ShardWaits)
+	})
+
+	t.Run("Test Disjoint Shards Do Not Retry", func(t *testing.T) {
+		// a resize makes every commit in flight retry, so the memory map is grown ahead of the writers
+		filler := make([]byte, 4 << 20)
+		for {
+			meta, readMetaErr := shardsTestMap.ReadMetaFromMemMap()
+			if readMetaErr != nil { t.Fatalf("error reading meta: %s", readMetaErr.Error()) }
+			if uint64(len(shardsTestMap.Data.Load().(mmap.MMap))) - meta.EndMmapOffset > 32 << 20 { break }
+
+			_, putErr := shardsTestMap.Put([]byte("filler"), filler)
+			if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+		}
+
+		_, delErr := shardsTestMap.Delete([]byte("filler"))
+		if delErr != nil { t.Fatalf("error on delete: %s", delErr.Error()) }
+
+		keys := make([][][]byte, len(shardsTestMap.ShardLanes))
+		for idx, full := 0, 0; full < len(keys); idx++ {
+			key := []byte(fmt.Sprintf("disjoint-key%d", idx))
+			shard := shardsTestMap.WriteShard(key)
+			if len(keys[shard]) == 300 { continue }
+
+			keys[shard] = append(keys[shard], key)
+			if len(keys[shard]) == 300 { full++ }
+		}
+
+		retries := atomic.LoadUint64(&shardsTestMap.CommitRetries)
+		resizes := atomic.LoadUint64(&shardsTestMap.Resizes)
+
+		var wg sync.WaitGroup
+		for shard := range keys {
+			wg.Add(1)
+			go func(shard int) {
+				defer wg.Done()
+
+				for _, key := range keys[shard] {
+					_, putErr := shardsTestMap.Put(key, key)
+					if putErr != nil { t.Errorf("error on put: %s", putErr.Error()) }
+				}
+			}(shard)
+		}
+
+		wg.Wait()
+
+		if atomic.LoadUint64(&shardsTestMap.Resizes) != resizes { t.Fatalf("memory map was resized during the writes") }
+		if retried := atomic.LoadUint64(&shardsTestMap.CommitRetries) - retries; retried != 0 {
+			t.Errorf("commits to disjoint shards retried: actual(%d), expected(0)", retried)
+		}
+
+		for shard := range keys {
+			for _, key := range keys[shard] {
+				value, getErr := shardsTestMap.Get(key)
+				if getErr != nil { t.Fatalf("error on get: %s", getErr.Error()) }
+				if ! bytes.Equal(value, key) { t.Errorf("value mismatch: actual(%s), expected(%s)", value, key) }
+			}
+		}
+
+		t.Logf("commits placed on a root published by another shard: %d", shardsTestMap.CommitRebases)
+	})
+
+	t.Run("Test Waiting Writer Holds No Locks", func(t *testing.T) {
+		waits := atomic.LoadUint64(&shardsTestMap.ShardWaits)
+		for idx := range shardsTestMap.ShardLanes { shardsTestMap.ShardLanes[idx].Lock() }
+
+		put := make(chan error, 1)
+		go func() {
+			_, putErr := shardsTestMap.Put([]byte("waiting"), []byte("waiting"))
+			put <- putErr
+		}()
+
+		for atomic.LoadUint64(&shardsTestMap.ShardWaits) == waits { time.Sleep(time.Millisecond) }
+
+		// a writer waiting on the lane of its shard has released the commit gate and resize lock, so compaction is not held up by it
+		compacted := make(chan error, 1)
+		go func() {
+			_, compactErr := shardsTestMap.Compact()
+			compacted <- compactErr
+		}()
+
+		select {
+			case compactErr := <-compacted:
+				if compactErr != nil { t.Errorf("error on compact: %s", compactErr.Error()) }
+			case <-time.After(10 * time.Second):
+				t.Errorf("compact blocked by a writer waiting on its shard")
+		}
+
+		for idx := range shardsTestMap.ShardLanes { shardsTestMap.ShardLanes[idx].Unlock() }
+
+		putErr := <-put
+		if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+
+		value, getErr := shardsTestMap.Get([]byte("waiting"))
+		if getErr != nil || ! bytes.Equal(value, []byte("waiting")) { t.Errorf("value mismatch: actual(%s), err(%v)", value, getErr) }
+	})
+
+	t.Run("Test Invalid Write Shards", func(t *testing.T) {
+		_, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: filepath.Join(os.TempDir(), "testwriteshardsinvalid"), WriteShards: 33 })
+		if ! errors.Is(openErr, mmcmap.ErrInvalidWriteShards) { t.Errorf("open error mismatch: actual(%v), expected(%v)", openErr, mmcmap.ErrInvalidWriteShards) }
+	})
+
+	t.Run("Test Write Shards Persisted", func(t *testing.T) {
+		path := filepath.Join(os.TempDir(), "testwriteshardspersisted")
+		mmcmap.Destroy(path)
+		defer mmcmap.Destroy(path)
+
+		mmcMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: path, WriteShards: 4 })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+
+		_, putErr := mmcMap.Put([]byte("key"), []byte("value"))
+		if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+		mmcMap.Close()
+
+		_, openErr = mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: path, WriteShards: 8 })
+		if ! errors.Is(openErr, mmcmap.ErrWriteShardsMismatch) { t.Errorf("open error mismatch: actual(%v), expected(%v)", openErr, mmcmap.ErrWriteShardsMismatch) }
+
+		mmcMap, openErr = mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: path })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+		defer mmcMap.Close()
+
+		if len(mmcMap.ShardLanes) != 4 { t.Errorf("write shards mismatch: actual(%d), expected(4)", len(mmcMap.ShardLanes)) }
+
+		value, getErr := mmcMap.Get([]byte("key"))
+		if getErr != nil || ! bytes.Equal(value, []byte("value")) { t.Errorf("value mismatch: actual(%s), err(%v)", value, getErr) }
+	})
+
+	t.Log("Done")
+}
@@ -0,0 +1,97 @@
+package mmcmaptests
+
+import "bytes"
+import "fmt"
+import "os"
+import "path/filepath"
+import "sync"
+import "testing"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var wTestPath = filepath.Join(os.TempDir(), "testwriter")
+var writerTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initWMapErr error
+	os.Remove(wTestPath)
+
+	opts := mmcmap.MMCMapOpts{ Filepath: wTestPath, SingleWriter: true, WriteQueueSize: 16 }
+	writerTestMap, initWMapErr = mmcmap.Open(opts)
+	if initWMapErr != nil { panic(initWMapErr.Error()) }
+
+	fmt.Println("writer test mmcmap initialized")
+}
+
+
+func TestMMCMapSingleWriter(t *testing.T) {
+	defer writerTestMap.Remove()
+
+	t.Run("Test Concurrent Puts Through Writer", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for worker := 0; worker < 8; worker++ {
+			wg.Add(1)
+			go func(worker int) {
+				defer wg.Done()
+
+				for idx := 0; idx < 250; idx++ {
+					key := []byte(fmt.Sprintf("worker%d-key%d", worker, idx))
+					_, putErr := writerTestMap.Put(key, key)
+					if putErr != nil { t.Errorf("error on put: %s", putErr.Error()) }
+				}
+			}(worker)
+		}
+
+		wg.Wait()
+
+		keyCount, lenErr := writerTestMap.Len()
+		if lenErr != nil { t.Errorf("error on len: %s", lenErr.Error()) }
+		if keyCount != 2000 { t.Errorf("key count mismatch: actual(%d), expected(2000)", keyCount) }
+
+		meta, readMetaErr := writerTestMap.ReadMetaFromMemMap()
+		if readMetaErr != nil { t.Errorf("error reading metadata: %s", readMetaErr.Error()) }
+		if meta.Version != 2000 { t.Errorf("unexpected number of versions: actual(%d), expected(2000)", meta.Version) }
+	})
+
+	t.Run("Test Async Futures", func(t *testing.T) {
+		var futures []*mmcmap.WriteFuture
+		for idx := 0; idx < 100; idx++ {
+			key := []byte(fmt.Sprintf("async%d", idx))
+			futures = append(futures, writerTestMap.PutAsync(key, key))
+		}
+
+		futures = append(futures, writerTestMap.DeleteAsync([]byte("async0")))
+
+		for _, future := range futures {
+			<-future.Done()
+			ok, waitErr := future.Wait()
+			if waitErr != nil { t.Errorf("error on async write: %s", waitErr.Error()) }
+			if ! ok { t.Error("async write was not committed") }
+		}
+
+		val, getErr := writerTestMap.Get([]byte("async42"))
+		if getErr != nil { t.Errorf("error on get: %s", getErr.Error()) }
+		if ! bytes.Equal(val, []byte("async42")) { t.Errorf("value mismatch: actual(%s), expected(async42)", val) }
+
+		val, getErr = writerTestMap.Get([]byte("async0"))
+		if getErr != nil { t.Errorf("error on get: %s", getErr.Error()) }
+		if val != nil { t.Errorf("deleted key still present: %s", val) }
+	})
+
+	t.Run("Test Versions Through Writer", func(t *testing.T) {
+		first, waitErr := writerTestMap.PutAsync([]byte("versioned"), []byte("value")).WaitVersion()
+		if waitErr != nil { t.Fatalf("error on async write: %s", waitErr.Error()) }
+
+		second, putErr := writerTestMap.PutVersioned([]byte("versioned"), []byte("again"))
+		if putErr != nil { t.Fatalf("error on versioned put: %s", putErr.Error()) }
+		if first == 0 || second != first + 1 { t.Errorf("version mismatch: actual(%d %d), expected consecutive versions", first, second) }
+
+		missing, waitErr := writerTestMap.DeleteAsync([]byte("missing")).WaitVersion()
+		if waitErr != nil { t.Fatalf("error on async delete: %s", waitErr.Error()) }
+		if missing != 0 { t.Errorf("delete of missing key wrote a version: actual(%d), expected(0)", missing) }
+	})
+
+	t.Log("Done")
+}
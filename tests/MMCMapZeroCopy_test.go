@@ -0,0 +1,148 @@
+package mmcmaptests
+
+import "bytes"
+import "fmt"
+import "os"
+import "path/filepath"
+import "testing"
+import "time"
+
+import "github.com/sirgallo/mmcmap"
+
+
+var zeroCopyTestPath = filepath.Join(os.TempDir(), "testzerocopy")
+var zeroCopyTestMap *mmcmap.MMCMap
+
+
+func init() {
+	var initZeroCopyMapErr error
+	os.Remove(zeroCopyTestPath)
+
+	zeroCopyTestMap, initZeroCopyMapErr = mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: zeroCopyTestPath })
+	if initZeroCopyMapErr != nil { panic(initZeroCopyMapErr.Error()) }
+
+	fmt.Println("zero copy test mmcmap initialized")
+}
+
+
+func TestMMCMapZeroCopy(t *testing.T) {
+	defer zeroCopyTestMap.Remove()
+
+	zeroCopyTestMap.Put([]byte("hello"), []byte("world"))
+
+	t.Run("Test Get Zero Copy", func(t *testing.T) {
+		value, release, getErr := zeroCopyTestMap.GetZeroCopy([]byte("hello"))
+		if getErr != nil { t.Fatalf("error on get zero copy: %s", getErr.Error()) }
+		if string(value) != "world" { t.Errorf("value mismatch: actual(%s), expected(world)", value) }
+
+		release()
+		release()
+
+		value, release, getErr = zeroCopyTestMap.GetZeroCopy([]byte("missing"))
+		if getErr != nil || value != nil { t.Errorf("missing key mismatch: actual(%s %v), expected(nil nil)", value, getErr) }
+		release()
+	})
+
+	t.Run("Test Value Survives Resize", func(t *testing.T) {
+		value, release, _ := zeroCopyTestMap.GetZeroCopy([]byte("hello"))
+
+		sizeBefore, _ := zeroCopyTestMap.FileSize()
+		large := bytes.Repeat([]byte("x"), 1 << 20)
+		for idx := 0; idx < 80; idx++ {
+			_, putErr := zeroCopyTestMap.Put([]byte(fmt.Sprintf("large%d", idx)), large)
+			if putErr != nil { t.Fatalf("error on put: %s", putErr.Error()) }
+		}
+
+		sizeAfter, _ := zeroCopyTestMap.FileSize()
+		if sizeAfter <= sizeBefore { t.Fatalf("memory map was not resized: actual(%d), before(%d)", sizeAfter, sizeBefore) }
+		if string(value) != "world" { t.Errorf("pinned value mismatch after resize: actual(%s), expected(world)", value) }
+
+		release()
+	})
+
+	t.Run("Test Compact Waits For Release", func(t *testing.T) {
+		value, release, _ := zeroCopyTestMap.GetZeroCopy([]byte("hello"))
+
+		compacted := make(chan error)
+		go func() {
+			_, compactErr := zeroCopyTestMap.Compact()
+			compacted <- compactErr
+		}()
+
+		select {
+			case <-compacted:
+				t.Fatal("compact did not wait for the pinned value")
+			case <-time.After(100 * time.Millisecond):
+		}
+
+		if string(value) != "world" { t.Errorf("pinned value mismatch: actual(%s), expected(world)", value) }
+
+		unblocked := make(chan error, 1)
+		go func() {
+			_, putErr := zeroCopyTestMap.Put([]byte("queued"), []byte("put"))
+			if putErr != nil {
+				unblocked <- putErr
+				return
+			}
+
+			_, rangeErr := zeroCopyTestMap.Range([]byte("hello"), []byte("hello"))
+			unblocked <- rangeErr
+		}()
+
+		select {
+			case opErr := <-unblocked:
+				if opErr != nil { t.Errorf("error while compact is queued: %s", opErr.Error()) }
+			case <-time.After(2 * time.Second):
+				t.Fatal("put and range from the pin holder blocked behind a queued compact")
+		}
+
+		release()
+
+		compactErr := <-compacted
+		if compactErr != nil { t.Fatalf("error on compact: %s", compactErr.Error()) }
+
+		value, _ = zeroCopyTestMap.Get([]byte("hello"))
+		if string(value) != "world" { t.Errorf("value mismatch after compact: actual(%s), expected(world)", value) }
+	})
+
+	t.Run("Test Value Survives Close", func(t *testing.T) {
+		path := filepath.Join(os.TempDir(), "testzerocopyclose")
+		os.Remove(path)
+		defer os.Remove(path)
+
+		closeMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: path })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+
+		closeMap.Put([]byte("key"), []byte("value"))
+		value, release, _ := closeMap.GetZeroCopy([]byte("key"))
+		copied, _ := closeMap.Get([]byte("key"))
+
+		closeErr := closeMap.Close()
+		if closeErr != nil { t.Fatalf("error on close: %s", closeErr.Error()) }
+		if string(value) != "value" { t.Errorf("pinned value mismatch after close: actual(%s), expected(value)", value) }
+
+		release()
+
+		if string(copied) != "value" { t.Errorf("copied value mismatch after unmap: actual(%s), expected(value)", copied) }
+	})
+
+	t.Run("Test Copy Never", func(t *testing.T) {
+		path := filepath.Join(os.TempDir(), "testzerocopynever")
+		os.Remove(path)
+
+		neverMap, openErr := mmcmap.Open(mmcmap.MMCMapOpts{ Filepath: path, CopyOnRead: mmcmap.CopyNever })
+		if openErr != nil { t.Fatalf("error on open: %s", openErr.Error()) }
+		defer neverMap.Remove()
+
+		neverMap.Put([]byte("key"), []byte("value"))
+
+		value, getErr := neverMap.Get([]byte("key"))
+		if getErr != nil { t.Fatalf("error on get: %s", getErr.Error()) }
+		if string(value) != "value" { t.Errorf("value mismatch: actual(%s), expected(value)", value) }
+
+		pairs, _ := neverMap.GetMulti([][]byte{ []byte("key"), []byte("missing") })
+		if string(pairs[0].Value) != "value" || pairs[1].Value != nil { t.Errorf("get multi mismatch: actual(%s %s)", pairs[0].Value, pairs[1].Value) }
+	})
+
+	t.Log("Done")
+}
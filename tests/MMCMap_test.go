@@ -19,7 +19,7 @@ func init() {
 	var initPCMapErr error
 	os.Remove(TestPath)
 	
-	opts := mmcmap.MMCMapOpts{ Filepath: TestPath }
+	opts := mmcmap.MMCMapOpts{ Filepath: TestPath, DeterministicHash: true }
 	mmcMap, initPCMapErr = mmcmap.Open(opts)
 	if initPCMapErr != nil { panic(initPCMapErr.Error()) }
 
@@ -140,6 +140,22 @@ func TestMMCMap(t *testing.T) {
 		if string(val4) != expVal4 { t.Errorf("val 4 does not match expected val 4: actual(%s), expected(%s)", val4, expVal4) }
 	})
 
+	t.Run("Test MMCMap Get Multi", func(t *testing.T) {
+		keys := [][]byte{ []byte("hello"), []byte("missing"), []byte("asdfasdf") }
+		expected := []string{ "world", "", "123123" }
+
+		pairs, getMultiErr := mmcMap.GetMulti(keys)
+		if getMultiErr != nil { t.Errorf("error on get multi: %s", getMultiErr.Error()) }
+		if len(pairs) != len(keys) { t.Fatalf("get multi returned unexpected number of pairs: actual(%d), expected(%d)", len(pairs), len(keys)) }
+
+		for idx, pair := range pairs {
+			if string(pair.Key) != string(keys[idx]) { t.Errorf("get multi out of order: actual(%s), expected(%s)", pair.Key, keys[idx]) }
+			if string(pair.Value) != expected[idx] { t.Errorf("get multi value mismatch: actual(%s), expected(%s)", pair.Value, expected[idx]) }
+		}
+
+		if pairs[1].Value != nil { t.Errorf("expected nil value for missing key, got: %s", pairs[1].Value) }
+	})
+
 	t.Run("Test MMCMap Delete", func(t *testing.T) {
 		_, delErr = mmcMap.Delete([]byte("hello"))
 		if delErr != nil { t.Errorf("error deleting key from mmcmap: %s", delErr.Error()) }